@@ -46,6 +46,7 @@ type InitRequest struct {
 	RecoveryThreshold int      `json:"recovery_threshold"`
 	RecoveryPGPKeys   []string `json:"recovery_pgp_keys"`
 	RootTokenPGPKey   string   `json:"root_token_pgp_key"`
+	SignedUnseal      bool     `json:"signed_unseal"`
 }
 
 type InitStatusResponse struct {
@@ -104,6 +104,71 @@ func (c *Sys) DeletePolicy(name string) error {
 	return err
 }
 
+// PolicyExplainInput is the request sent to ExplainPolicy. At most one of
+// Token, Accessor and EntityID can be set; if none is, the policies of the
+// client token are explained.
+type PolicyExplainInput struct {
+	Token      string                 `json:"token,omitempty"`
+	Accessor   string                 `json:"accessor,omitempty"`
+	EntityID   string                 `json:"entity_id,omitempty"`
+	Path       string                 `json:"path"`
+	Operation  string                 `json:"operation,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type PolicyExplainOutput struct {
+	Path         string               `json:"path" mapstructure:"path"`
+	Operation    string               `json:"operation" mapstructure:"operation"`
+	Allowed      bool                 `json:"allowed" mapstructure:"allowed"`
+	Rule         string               `json:"rule" mapstructure:"rule"`
+	Capabilities []string             `json:"capabilities" mapstructure:"capabilities"`
+	GrantedBy    map[string][]string  `json:"granted_by" mapstructure:"granted_by"`
+	DeniedBy     []string             `json:"denied_by" mapstructure:"denied_by"`
+	Reason       string               `json:"reason" mapstructure:"reason"`
+	Policies     []*PolicyExplainRule `json:"policies" mapstructure:"policies"`
+}
+
+type PolicyExplainRule struct {
+	Name         string   `json:"name" mapstructure:"name"`
+	Namespace    string   `json:"namespace" mapstructure:"namespace"`
+	Rule         string   `json:"rule" mapstructure:"rule"`
+	Capabilities []string `json:"capabilities" mapstructure:"capabilities"`
+	Effective    bool     `json:"effective" mapstructure:"effective"`
+}
+
+// ExplainPolicy returns which policies and path rules decide the described
+// request, without performing it.
+func (c *Sys) ExplainPolicy(input *PolicyExplainInput) (*PolicyExplainOutput, error) {
+	r := c.c.NewRequest("PUT", "/v1/sys/policy-explain")
+	if err := r.SetJSONBody(input); err != nil {
+		return nil, err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result PolicyExplainOutput
+	err = mapstructure.Decode(secret.Data, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 type getPoliciesResp struct {
 	Rules string `json:"rules"`
 }
@@ -42,6 +42,7 @@ type AutopilotConfig struct {
 	MaxTrailingLogs                uint64        `json:"max_trailing_logs" mapstructure:"max_trailing_logs"`
 	MinQuorum                      uint          `json:"min_quorum" mapstructure:"min_quorum"`
 	ServerStabilizationTime        time.Duration `json:"server_stabilization_time" mapstructure:"-"`
+	DisableUpgradeMigration        bool          `json:"disable_upgrade_migration" mapstructure:"disable_upgrade_migration"`
 }
 
 // MarshalJSON makes the autopilot config fields JSON compatible
@@ -53,6 +54,7 @@ func (ac *AutopilotConfig) MarshalJSON() ([]byte, error) {
 		"max_trailing_logs":                  ac.MaxTrailingLogs,
 		"min_quorum":                         ac.MinQuorum,
 		"server_stabilization_time":          ac.ServerStabilizationTime.String(),
+		"disable_upgrade_migration":          ac.DisableUpgradeMigration,
 	})
 }
 
@@ -88,6 +90,9 @@ type AutopilotState struct {
 	Leader           string                      `mapstructure:"leader"`
 	Voters           []string                    `mapstructure:"voters"`
 	NonVoters        []string                    `mapstructure:"non_voters"`
+	ReadReplicas     []string                    `mapstructure:"read_replicas"`
+	RedundancyZones  map[string]AutopilotZone    `mapstructure:"redundancy_zones"`
+	Upgrade          *AutopilotUpgrade           `mapstructure:"upgrade_info"`
 }
 
 // AutopilotServer represents the server blocks in the response of the raft
@@ -104,6 +109,30 @@ type AutopilotServer struct {
 	StableSince string            `mapstructure:"stable_since"`
 	Status      string            `mapstructure:"status"`
 	Meta        map[string]string `mapstructure:"meta"`
+	NodeType    string            `mapstructure:"node_type"`
+
+	Version        string `mapstructure:"version"`
+	UpgradeVersion string `mapstructure:"upgrade_version"`
+	RedundancyZone string `mapstructure:"redundancy_zone"`
+}
+
+// AutopilotZone represents a redundancy zone in the response of the raft
+// autopilot state API.
+type AutopilotZone struct {
+	Servers          []string `mapstructure:"servers"`
+	Voters           []string `mapstructure:"voters"`
+	FailureTolerance int      `mapstructure:"failure_tolerance"`
+}
+
+// AutopilotUpgrade represents the state of the automated upgrades in the
+// response of the raft autopilot state API.
+type AutopilotUpgrade struct {
+	Status                 string   `mapstructure:"status"`
+	TargetVersion          string   `mapstructure:"target_version"`
+	TargetVersionVoters    []string `mapstructure:"target_version_voters"`
+	TargetVersionNonVoters []string `mapstructure:"target_version_non_voters"`
+	OtherVersionVoters     []string `mapstructure:"other_version_voters"`
+	OtherVersionNonVoters  []string `mapstructure:"other_version_non_voters"`
 }
 
 // RaftJoin adds the node from which this call is invoked from to the raft
@@ -240,6 +269,30 @@ func (c *Sys) RaftSnapshotRestore(snapReader io.Reader, force bool) error {
 	return nil
 }
 
+// RaftSnapshotRestoreSubtree reads the snapshot from the io.Reader and
+// restores only the keys of the mount at mountPath, or of the mounts of the
+// namespace at nsPath if mountPath is empty.
+func (c *Sys) RaftSnapshotRestoreSubtree(snapReader io.Reader, mountPath, nsPath string) (*Secret, error) {
+	r := c.c.NewRequest("POST", "/v1/sys/storage/raft/snapshot-restore-subtree")
+	if mountPath != "" {
+		r.Params.Set("mount", mountPath)
+	} else {
+		r.Params.Set("namespace", nsPath)
+	}
+
+	r.Body = snapReader
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return ParseSecret(resp.Body)
+}
+
 // RaftAutopilotState returns the state of the raft cluster as seen by autopilot.
 func (c *Sys) RaftAutopilotState() (*AutopilotState, error) {
 	r := c.c.NewRequest("GET", "/v1/sys/storage/raft/autopilot/state")
@@ -342,6 +342,7 @@ type RekeyInitRequest struct {
 	PGPKeys             []string `json:"pgp_keys"`
 	Backup              bool
 	RequireVerification bool `json:"require_verification"`
+	SignedUnseal        bool `json:"signed_unseal"`
 }
 
 type RekeyStatusResponse struct {
@@ -355,6 +356,7 @@ type RekeyStatusResponse struct {
 	Backup               bool     `json:"backup"`
 	VerificationRequired bool     `json:"verification_required"`
 	VerificationNonce    string   `json:"verification_nonce"`
+	SignedUnseal         bool     `json:"signed_unseal"`
 }
 
 type RekeyUpdateResponse struct {
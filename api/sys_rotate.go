@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/mitchellh/mapstructure"
 )
 
 func (c *Sys) Rotate() error {
@@ -81,11 +83,75 @@ func (c *Sys) KeyStatus() (*KeyStatus, error) {
 		result.Encryptions = int(encryptions64)
 	}
 
+	if err := weakDecodeKeyStatus(secret.Data["keyring_terms"], &result.KeyringTerms); err != nil {
+		return nil, err
+	}
+	if rewrapRaw, ok := secret.Data["rewrap"]; ok && rewrapRaw != nil {
+		result.Rewrap = new(KeyRewrapStatus)
+		if err := weakDecodeKeyStatus(rewrapRaw, result.Rewrap); err != nil {
+			return nil, err
+		}
+	}
+
 	return &result, err
 }
 
+// RotatePrune removes the keys of the terms preceding the last complete
+// rewrap from the keyring, and returns the removed terms.
+func (c *Sys) RotatePrune() ([]int, error) {
+	r := c.c.NewRequest("POST", "/v1/sys/rotate/prune")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var removed []int
+	if err := weakDecodeKeyStatus(secret.Data["removed_terms"], &removed); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func weakDecodeKeyStatus(input, output interface{}) error {
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
+		WeaklyTypedInput: true,
+		Result:           output,
+	})
+	if err != nil {
+		return err
+	}
+	return d.Decode(input)
+}
+
 type KeyStatus struct {
-	Term        int       `json:"term"`
-	InstallTime time.Time `json:"install_time"`
-	Encryptions int       `json:"encryptions"`
+	Term         int              `json:"term"`
+	InstallTime  time.Time        `json:"install_time"`
+	Encryptions  int              `json:"encryptions"`
+	KeyringTerms []int            `json:"keyring_terms"`
+	Rewrap       *KeyRewrapStatus `json:"rewrap,omitempty"`
+}
+
+// KeyRewrapStatus is the progress of the re-encryption of the existing data
+// under the key of the given term.
+type KeyRewrapStatus struct {
+	Term             int       `json:"term" mapstructure:"term"`
+	Status           string    `json:"status" mapstructure:"status"`
+	StartTime        time.Time `json:"start_time" mapstructure:"start_time"`
+	CompleteTime     time.Time `json:"complete_time" mapstructure:"complete_time"`
+	EntriesChecked   int       `json:"entries_checked" mapstructure:"entries_checked"`
+	EntriesRewrapped int       `json:"entries_rewrapped" mapstructure:"entries_rewrapped"`
+	EntriesFailed    int       `json:"entries_failed" mapstructure:"entries_failed"`
 }
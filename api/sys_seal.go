@@ -78,10 +78,19 @@ type SealStatusResponse struct {
 	ClusterID    string `json:"cluster_id,omitempty"`
 	RecoverySeal bool   `json:"recovery_seal"`
 	StorageType  string `json:"storage_type,omitempty"`
+
+	SignedUnseal  bool     `json:"signed_unseal,omitempty"`
+	UnsealPGPKey  string   `json:"unseal_pgp_key,omitempty"`
+	UnsealHolders []string `json:"unseal_holders,omitempty"`
 }
 
 type UnsealOpts struct {
 	Key     string `json:"key"`
 	Reset   bool   `json:"reset"`
 	Migrate bool   `json:"migrate"`
+
+	// SignedKey is the base64-encoded PGP message holding the unseal key,
+	// signed by the key holder and encrypted with the unseal PGP key
+	// returned by SealStatus
+	SignedKey string `json:"signed_key,omitempty"`
 }
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// StorageMigrationInput is used to start an online storage migration.
+type StorageMigrationInput struct {
+	Type   string            `json:"type"`
+	Config map[string]string `json:"config"`
+}
+
+// StorageMigrationStatus is the progress of an online storage migration.
+type StorageMigrationStatus struct {
+	Status          string    `json:"status" mapstructure:"status"`
+	DestinationType string    `json:"destination_type" mapstructure:"destination_type"`
+	StartTime       time.Time `json:"start_time" mapstructure:"start_time"`
+	CompleteTime    time.Time `json:"complete_time" mapstructure:"complete_time"`
+	KeysCopied      int       `json:"keys_copied" mapstructure:"keys_copied"`
+	Error           string    `json:"error" mapstructure:"error"`
+}
+
+// StartStorageMigration starts migrating the storage of the active node to
+// another backend while it keeps serving requests.
+func (c *Sys) StartStorageMigration(input *StorageMigrationInput) error {
+	r := c.c.NewRequest("PUT", "/v1/sys/storage/migration")
+	if err := r.SetJSONBody(input); err != nil {
+		return err
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+// StorageMigrationStatus returns the progress of the online storage migration.
+func (c *Sys) StorageMigrationStatus() (*StorageMigrationStatus, error) {
+	r := c.c.NewRequest("GET", "/v1/sys/storage/migration")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result StorageMigrationStatus
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
+		WeaklyTypedInput: true,
+		Result:           &result,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Decode(secret.Data); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CancelStorageMigration cancels the online storage migration in progress.
+func (c *Sys) CancelStorageMigration() error {
+	r := c.c.NewRequest("DELETE", "/v1/sys/storage/migration")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
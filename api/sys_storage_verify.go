@@ -0,0 +1,76 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// StorageVerifyResult is the progress of the verification of the storage
+// entries, with the problems found so far.
+type StorageVerifyResult struct {
+	Status         string                  `json:"status" mapstructure:"status"`
+	StartTime      time.Time               `json:"start_time" mapstructure:"start_time"`
+	CompleteTime   time.Time               `json:"complete_time" mapstructure:"complete_time"`
+	Error          string                  `json:"error,omitempty" mapstructure:"error"`
+	EntriesChecked int                     `json:"entries_checked" mapstructure:"entries_checked"`
+	EntriesSkipped int                     `json:"entries_skipped" mapstructure:"entries_skipped"`
+	Problems       []*StorageVerifyProblem `json:"problems" mapstructure:"problems"`
+}
+
+// StorageVerifyProblem is a storage entry that is truncated, cannot be
+// decrypted, or belongs to a mount that does not exist anymore.
+type StorageVerifyProblem struct {
+	Path    string `json:"path" mapstructure:"path"`
+	Problem string `json:"problem" mapstructure:"problem"`
+	Error   string `json:"error,omitempty" mapstructure:"error"`
+}
+
+// StartStorageVerify starts walking all the storage entries of the active
+// node in the background, and returns the progress of the verification. If a
+// verification is already running, its progress is returned instead.
+func (c *Sys) StartStorageVerify() (*StorageVerifyResult, error) {
+	return c.storageVerify("PUT")
+}
+
+// StorageVerifyStatus returns the progress of the storage verification, and
+// the entries reported so far as corrupted or orphaned.
+func (c *Sys) StorageVerifyStatus() (*StorageVerifyResult, error) {
+	return c.storageVerify("GET")
+}
+
+func (c *Sys) storageVerify(method string) (*StorageVerifyResult, error) {
+	r := c.c.NewRequest(method, "/v1/sys/storage/verify")
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	defer cancelFunc()
+	resp, err := c.c.RawRequestWithContext(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result StorageVerifyResult
+	d, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeHookFunc(time.RFC3339Nano),
+		WeaklyTypedInput: true,
+		Result:           &result,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Decode(secret.Data); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
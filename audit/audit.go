@@ -3,6 +3,7 @@ package audit
 import (
 	"context"
 
+	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -42,6 +43,14 @@ type Backend interface {
 	Invalidate(context.Context)
 }
 
+// CloseableBackend is implemented by audit backends that run in the
+// background, e.g. to retry failed deliveries.
+type CloseableBackend interface {
+	// Close stops the background work of the backend. It is called once the
+	// device is disabled, or when Vault seals.
+	Close()
+}
+
 // BackendConfig contains configuration parameters used in the factory func to
 // instantiate audit backends
 type BackendConfig struct {
@@ -53,6 +62,10 @@ type BackendConfig struct {
 
 	// Config is the opaque user configuration provided when mounting
 	Config map[string]string
+
+	// Logger is used by the backend to report errors of background work. It
+	// may be nil.
+	Logger log.Logger
 }
 
 // Factory is the factory function to create an audit backend.
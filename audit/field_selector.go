@@ -0,0 +1,291 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// FieldAction is what is done to the fields matched by a FieldSelector
+type FieldAction string
+
+const (
+	// FieldActionHMAC hashes the field, even if it would otherwise be logged
+	// in plaintext
+	FieldActionHMAC FieldAction = "hmac"
+
+	// FieldActionPlaintext logs the field without hashing it
+	FieldActionPlaintext FieldAction = "plaintext"
+
+	// FieldActionDrop removes the field from the entry
+	FieldActionDrop FieldAction = "drop"
+)
+
+// Roots of the pointers of field selectors
+const (
+	fieldSelectorRequestData  = "/request/data"
+	fieldSelectorResponseData = "/response/data"
+)
+
+// FieldSelector selects fields of the request or response data of audit
+// entries with a JSON pointer (RFC 6901), and sets the action applied to
+// them. A "*" segment of the pointer matches any key or index.
+//
+// Selectors are written as "<pointer>=<action>", for instance
+// "/response/data/keys/*/password=drop".
+type FieldSelector struct {
+	Pointer string
+	Action  FieldAction
+
+	root     string
+	segments []string
+}
+
+// ParseFieldSelector parses a selector written as "<pointer>=<action>"
+func ParseFieldSelector(s string) (*FieldSelector, error) {
+	idx := strings.LastIndex(s, "=")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid field selector %q: expected <pointer>=<action>", s)
+	}
+
+	selector := &FieldSelector{
+		Pointer: strings.TrimSpace(s[:idx]),
+		Action:  FieldAction(strings.TrimSpace(s[idx+1:])),
+	}
+	switch selector.Action {
+	case FieldActionHMAC, FieldActionPlaintext, FieldActionDrop:
+	default:
+		return nil, fmt.Errorf("invalid field selector %q: unknown action %q", s, selector.Action)
+	}
+
+	for _, root := range []string{fieldSelectorRequestData, fieldSelectorResponseData} {
+		switch {
+		case selector.Pointer == root:
+			selector.root = root
+		case strings.HasPrefix(selector.Pointer, root+"/"):
+			selector.root = root
+			for _, segment := range strings.Split(selector.Pointer[len(root)+1:], "/") {
+				// Unescape as per RFC 6901
+				segment = strings.Replace(segment, "~1", "/", -1)
+				segment = strings.Replace(segment, "~0", "~", -1)
+				selector.segments = append(selector.segments, segment)
+			}
+		}
+	}
+	if selector.root == "" {
+		return nil, fmt.Errorf("invalid field selector %q: the pointer must start with %q or %q", s, fieldSelectorRequestData, fieldSelectorResponseData)
+	}
+
+	return selector, nil
+}
+
+// ParseFieldSelectors parses a list of selectors
+func ParseFieldSelectors(selectors []string) ([]*FieldSelector, error) {
+	var ret []*FieldSelector
+	for _, s := range selectors {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		selector, err := ParseFieldSelector(s)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, selector)
+	}
+	return ret, nil
+}
+
+// fieldSelectors returns the selectors applying to the entry, in the order
+// they are applied: those of the mount, then those of the audit device
+func fieldSelectors(in *logical.LogInput, config FormatterConfig) ([][]*FieldSelector, error) {
+	var ret [][]*FieldSelector
+	if in.AuditFieldSelectors != nil {
+		selectors, ok := in.AuditFieldSelectors.([]*FieldSelector)
+		if !ok {
+			return nil, fmt.Errorf("invalid audit field selectors of type %T", in.AuditFieldSelectors)
+		}
+		if len(selectors) > 0 {
+			ret = append(ret, selectors)
+		}
+	}
+	if len(config.FieldSelectors) > 0 {
+		ret = append(ret, config.FieldSelectors)
+	}
+	return ret, nil
+}
+
+// applyFieldSelectors returns the data to log for the root given its raw and
+// its hashed version. The groups of selectors are applied in order, so that
+// those of the audit device take precedence over those of the mount. Within a
+// group, the most specific selector matching a field applies, and for
+// selectors equally specific, the last one.
+func applyFieldSelectors(fn HashCallback, groups [][]*FieldSelector, root string, raw, hashed map[string]interface{}) (map[string]interface{}, error) {
+	var matching []*FieldSelector
+	for _, selectors := range groups {
+		start := len(matching)
+		for _, selector := range selectors {
+			if selector.root == root {
+				matching = append(matching, selector)
+			}
+		}
+		group := matching[start:]
+		sort.SliceStable(group, func(i, j int) bool {
+			return len(group[i].segments) < len(group[j].segments)
+		})
+	}
+	if len(matching) == 0 {
+		return hashed, nil
+	}
+
+	if b, ok := raw[logical.HTTPRawBody].([]byte); ok {
+		rawCopy := make(map[string]interface{}, len(raw))
+		for k, v := range raw {
+			rawCopy[k] = v
+		}
+		rawCopy[logical.HTTPRawBody] = string(b)
+		raw = rawCopy
+	}
+
+	rawTree, err := jsonTree(raw)
+	if err != nil {
+		return nil, err
+	}
+	out, err := jsonTree(hashed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, selector := range matching {
+		out = applyFieldAction(fn, out, rawTree, selector.segments, selector.Action)
+	}
+
+	ret, _ := out.(map[string]interface{})
+	return ret, nil
+}
+
+// droppedField is returned by applyFieldAction for dropped values
+type droppedField struct{}
+
+// applyFieldAction applies the action to the values matched by the segments
+// in out, which is the JSON tree of the data to log, and returns the new
+// value. raw is the JSON tree of the raw value at the same location.
+func applyFieldAction(fn HashCallback, out, raw interface{}, segments []string, action FieldAction) interface{} {
+	if len(segments) == 0 {
+		switch action {
+		case FieldActionDrop:
+			return droppedField{}
+		case FieldActionPlaintext:
+			return copyJSONTree(raw, nil)
+		default:
+			return copyJSONTree(raw, fn)
+		}
+	}
+
+	segment, rest := segments[0], segments[1:]
+	switch value := out.(type) {
+	case map[string]interface{}:
+		rawMap, _ := raw.(map[string]interface{})
+		keys := []string{segment}
+		if segment == "*" {
+			keys = keys[:0]
+			for k := range value {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			child, ok := value[k]
+			if !ok {
+				continue
+			}
+			rawChild, ok := rawMap[k]
+			if !ok && action != FieldActionDrop {
+				continue
+			}
+			child = applyFieldAction(fn, child, rawChild, rest, action)
+			if _, ok := child.(droppedField); ok {
+				delete(value, k)
+			} else {
+				value[k] = child
+			}
+		}
+		return value
+
+	case []interface{}:
+		rawSlice, _ := raw.([]interface{})
+		ret := value[:0:0]
+		for i, child := range value {
+			if segment == "*" || segment == strconv.Itoa(i) {
+				if i >= len(rawSlice) && action != FieldActionDrop {
+					ret = append(ret, child)
+					continue
+				}
+				var rawChild interface{}
+				if i < len(rawSlice) {
+					rawChild = rawSlice[i]
+				}
+				child = applyFieldAction(fn, child, rawChild, rest, action)
+				if _, ok := child.(droppedField); ok {
+					continue
+				}
+			}
+			ret = append(ret, child)
+		}
+		return ret
+
+	default:
+		return out
+	}
+}
+
+// copyJSONTree returns a copy of a JSON tree, with its strings hashed by fn if
+// set
+func copyJSONTree(v interface{}, fn HashCallback) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			ret[k] = copyJSONTree(child, fn)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(value))
+		for i, child := range value {
+			ret[i] = copyJSONTree(child, fn)
+		}
+		return ret
+	case string:
+		if fn != nil {
+			return fn(value)
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+// jsonTree returns the value as decoded from its JSON encoding, keeping
+// numbers as json.Number so that they are encoded back unchanged
+func jsonTree(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var ret interface{}
+	if err := dec.Decode(&ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
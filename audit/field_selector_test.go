@@ -0,0 +1,247 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestParseFieldSelector(t *testing.T) {
+	cases := map[string]struct {
+		selector string
+		segments []string
+		err      bool
+	}{
+		"nested":        {"/response/data/keys/*/password=drop", []string{"keys", "*", "password"}, false},
+		"root":          {"/request/data=plaintext", nil, false},
+		"escaped":       {"/request/data/a~1b/c~0d=hmac", []string{"a/b", "c~d"}, false},
+		"equals in key": {"/request/data/a=b=plaintext", []string{"a=b"}, false},
+		"no action":     {"/request/data/foo", nil, true},
+		"bad action":    {"/request/data/foo=hide", nil, true},
+		"bad root":      {"/request/headers/foo=drop", nil, true},
+		"prefix only":   {"/request/database=drop", nil, true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			selector, err := ParseFieldSelector(tc.selector)
+			if tc.err {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(selector.segments, tc.segments) {
+				t.Fatalf("expected %#v, got %#v", tc.segments, selector.segments)
+			}
+		})
+	}
+}
+
+func TestFormatJSON_fieldSelectors(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := salter.GetIdentifiedHMAC
+
+	mountSelectors, err := ParseFieldSelectors([]string{
+		"/request/data/user=plaintext",
+		"/response/data/keys/*/name=plaintext",
+		"/response/data/keys/*/password=drop",
+		"/response/data/count=hmac",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceSelectors, err := ParseFieldSelectors([]string{
+		"/request/data/config=plaintext",
+		"/request/data/config/secret=hmac",
+		"/request/data/user=drop",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			SaltFunc: func(context.Context) (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "secret/foo",
+			Data: map[string]interface{}{
+				"user":     "alice",
+				"password": "hunter2",
+				"config": map[string]interface{}{
+					"url":    "https://example.com",
+					"secret": "s3cr3t",
+					"port":   8200,
+				},
+			},
+		},
+		Response: &logical.Response{
+			Data: map[string]interface{}{
+				"count": "2",
+				"keys": []interface{}{
+					map[string]interface{}{"name": "a", "password": "pa"},
+					map[string]interface{}{"name": "b", "password": "pb"},
+				},
+			},
+		},
+		NonHMACRespDataKeys: []string{"count"},
+		AuditFieldSelectors: mountSelectors,
+	}
+	config := FormatterConfig{
+		HMACAccessor:   true,
+		FieldSelectors: deviceSelectors,
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatResponse(namespace.RootContext(nil), &buf, config, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var entry struct {
+		Request  AuditRequest  `json:"request"`
+		Response AuditResponse `json:"response"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// The device selector for user applies after the mount one
+	expectedReq := map[string]interface{}{
+		"password": hash("hunter2"),
+		"config": map[string]interface{}{
+			"url":    "https://example.com",
+			"secret": hash("s3cr3t"),
+			"port":   float64(8200),
+		},
+	}
+	if !reflect.DeepEqual(entry.Request.Data, expectedReq) {
+		t.Fatalf("expected %#v, got %#v", expectedReq, entry.Request.Data)
+	}
+
+	// The hmac action applies even though count is a non-HMAC key
+	expectedResp := map[string]interface{}{
+		"count": hash("2"),
+		"keys": []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "b"},
+		},
+	}
+	if !reflect.DeepEqual(entry.Response.Data, expectedResp) {
+		t.Fatalf("expected %#v, got %#v", expectedResp, entry.Response.Data)
+	}
+
+	// The input is not modified
+	if in.Request.Data["user"] != "alice" || len(in.Response.Data["keys"].([]interface{})[0].(map[string]interface{})) != 2 {
+		t.Fatalf("input was modified: %#v", in)
+	}
+
+	// With raw logging, only the hmac and drop actions change the entry
+	buf.Reset()
+	config.Raw = true
+	if err := formatter.FormatRequest(namespace.RootContext(nil), &buf, config, in); err != nil {
+		t.Fatal(err)
+	}
+	entry.Request = AuditRequest{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	expectedReq["password"] = "hunter2"
+	if !reflect.DeepEqual(entry.Request.Data, expectedReq) {
+		t.Fatalf("expected %#v, got %#v", expectedReq, entry.Request.Data)
+	}
+	if in.Request.Data["user"] != "alice" {
+		t.Fatalf("input was modified: %#v", in)
+	}
+}
+
+func TestFormatJSON_fieldSelectorsPrecedence(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := salter.GetIdentifiedHMAC
+
+	mountSelectors, err := ParseFieldSelectors([]string{
+		"/request/data/user=plaintext",
+		"/request/data/config/url=plaintext",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceSelectors, err := ParseFieldSelectors([]string{
+		"/request/data=hmac",
+		"/request/data/config=drop",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			SaltFunc: func(context.Context) (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "secret/foo",
+			Data: map[string]interface{}{
+				"user": "alice",
+				"config": map[string]interface{}{
+					"url": "https://example.com",
+				},
+			},
+		},
+		AuditFieldSelectors: mountSelectors,
+	}
+	config := FormatterConfig{
+		HMACAccessor:   true,
+		FieldSelectors: deviceSelectors,
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatRequest(namespace.RootContext(nil), &buf, config, in); err != nil {
+		t.Fatal(err)
+	}
+	var entry struct {
+		Request AuditRequest `json:"request"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	// The selectors of the device win over the more specific plaintext
+	// selectors of the mount
+	expected := map[string]interface{}{
+		"user": hash("alice"),
+	}
+	if !reflect.DeepEqual(entry.Request.Data, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, entry.Request.Data)
+	}
+
+	// Unparsed selectors are rejected
+	in.AuditFieldSelectors = []string{"/request/data/user=plaintext"}
+	if err := formatter.FormatRequest(namespace.RootContext(nil), &buf, config, in); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -28,6 +28,9 @@ type AuditFormatWriter interface {
 // marshaller to be swapped out
 type AuditFormatter struct {
 	AuditFormatWriter
+
+	// Chain, if set, links each entry to the previous one
+	Chain *HashChain
 }
 
 var _ Formatter = (*AuditFormatter)(nil)
@@ -74,6 +77,19 @@ func (f *AuditFormatter) FormatRequest(ctx context.Context, w io.Writer, config
 		}
 	}
 
+	selectors, err := fieldSelectors(in, config)
+	if err != nil {
+		return err
+	}
+	if len(selectors) > 0 {
+		reqCopy := *req
+		reqCopy.Data, err = applyFieldSelectors(salt.GetIdentifiedHMAC, selectors, fieldSelectorRequestData, in.Request.Data, req.Data)
+		if err != nil {
+			return err
+		}
+		req = &reqCopy
+	}
+
 	var errString string
 	if in.OuterErr != nil {
 		errString = in.OuterErr.Error()
@@ -140,6 +156,13 @@ func (f *AuditFormatter) FormatRequest(ctx context.Context, w io.Writer, config
 		reqEntry.Time = time.Now().UTC().Format(time.RFC3339Nano)
 	}
 
+	if f.Chain != nil {
+		return f.Chain.write(ctx, w, func(w io.Writer, sequence uint64, prevHMAC string) error {
+			reqEntry.Sequence, reqEntry.PrevHMAC = sequence, prevHMAC
+			return f.AuditFormatWriter.WriteRequest(w, reqEntry)
+		})
+	}
+
 	return f.AuditFormatWriter.WriteRequest(w, reqEntry)
 }
 
@@ -192,6 +215,30 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 		}
 	}
 
+	selectors, err := fieldSelectors(in, config)
+	if err != nil {
+		return err
+	}
+	if len(selectors) > 0 {
+		reqCopy := *req
+		reqCopy.Data, err = applyFieldSelectors(salt.GetIdentifiedHMAC, selectors, fieldSelectorRequestData, in.Request.Data, req.Data)
+		if err != nil {
+			return err
+		}
+		req = &reqCopy
+
+		var rawRespData map[string]interface{}
+		if in.Response != nil {
+			rawRespData = in.Response.Data
+		}
+		respCopy := *resp
+		respCopy.Data, err = applyFieldSelectors(salt.GetIdentifiedHMAC, selectors, fieldSelectorResponseData, rawRespData, resp.Data)
+		if err != nil {
+			return err
+		}
+		resp = &respCopy
+	}
+
 	var errString string
 	if in.OuterErr != nil {
 		errString = in.OuterErr.Error()
@@ -312,6 +359,13 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 		respEntry.Time = time.Now().UTC().Format(time.RFC3339Nano)
 	}
 
+	if f.Chain != nil {
+		return f.Chain.write(ctx, w, func(w io.Writer, sequence uint64, prevHMAC string) error {
+			respEntry.Sequence, respEntry.PrevHMAC = sequence, prevHMAC
+			return f.AuditFormatWriter.WriteResponse(w, respEntry)
+		})
+	}
+
 	return f.AuditFormatWriter.WriteResponse(w, respEntry)
 }
 
@@ -322,6 +376,10 @@ type AuditRequestEntry struct {
 	Auth    *AuditAuth    `json:"auth,omitempty"`
 	Request *AuditRequest `json:"request,omitempty"`
 	Error   string        `json:"error,omitempty"`
+
+	// Sequence and PrevHMAC are only set when the entries are chained
+	Sequence uint64 `json:"sequence,omitempty"`
+	PrevHMAC string `json:"prev_hmac,omitempty"`
 }
 
 // AuditResponseEntry is the structure of a response audit log entry in Audit.
@@ -332,6 +390,10 @@ type AuditResponseEntry struct {
 	Request  *AuditRequest  `json:"request,omitempty"`
 	Response *AuditResponse `json:"response,omitempty"`
 	Error    string         `json:"error,omitempty"`
+
+	// Sequence and PrevHMAC are only set when the entries are chained
+	Sequence uint64 `json:"sequence,omitempty"`
+	PrevHMAC string `json:"prev_hmac,omitempty"`
 }
 
 type AuditRequest struct {
@@ -441,17 +503,39 @@ func NewTemporaryFormatter(format, prefix string) *AuditFormatter {
 	}
 	ret := &AuditFormatter{}
 
+	writer, err := NewFormatWriter(format, prefix, temporarySalt)
+	if err != nil {
+		writer, _ = NewFormatWriter("json", prefix, temporarySalt)
+	}
+	ret.AuditFormatWriter = writer
+	return ret
+}
+
+// NewFormatWriter returns the AuditFormatWriter for the given format: "json",
+// "jsonx", "cef" or "ocsf".
+func NewFormatWriter(format, prefix string, saltFunc func(context.Context) (*salt.Salt, error)) (AuditFormatWriter, error) {
 	switch format {
+	case "json":
+		return &JSONFormatWriter{
+			Prefix:   prefix,
+			SaltFunc: saltFunc,
+		}, nil
 	case "jsonx":
-		ret.AuditFormatWriter = &JSONxFormatWriter{
+		return &JSONxFormatWriter{
 			Prefix:   prefix,
-			SaltFunc: temporarySalt,
-		}
-	default:
-		ret.AuditFormatWriter = &JSONFormatWriter{
+			SaltFunc: saltFunc,
+		}, nil
+	case "cef":
+		return &CEFFormatWriter{
 			Prefix:   prefix,
-			SaltFunc: temporarySalt,
-		}
+			SaltFunc: saltFunc,
+		}, nil
+	case "ocsf":
+		return &OCSFFormatWriter{
+			Prefix:   prefix,
+			SaltFunc: saltFunc,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format type %q", format)
 	}
-	return ret
 }
@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/version"
+)
+
+// CEFFormatWriter is an AuditFormatWriter implementation that structures data
+// into ArcSight Common Event Format (CEF) lines.
+type CEFFormatWriter struct {
+	Prefix   string
+	SaltFunc func(context.Context) (*salt.Salt, error)
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func (f *CEFFormatWriter) WriteRequest(w io.Writer, req *AuditRequestEntry) error {
+	if req == nil {
+		return fmt.Errorf("request entry was nil, cannot encode")
+	}
+
+	return f.write(w, req.Type, req.Time, req.Auth, req.Request, req.Error)
+}
+
+func (f *CEFFormatWriter) WriteResponse(w io.Writer, resp *AuditResponseEntry) error {
+	if resp == nil {
+		return fmt.Errorf("response entry was nil, cannot encode")
+	}
+
+	return f.write(w, resp.Type, resp.Time, resp.Auth, resp.Request, resp.Error)
+}
+
+func (f *CEFFormatWriter) write(w io.Writer, entryType, entryTime string, auth *AuditAuth, req *AuditRequest, errString string) error {
+	if req == nil {
+		req = &AuditRequest{}
+	}
+	if auth == nil {
+		auth = &AuditAuth{}
+	}
+
+	severity := 3
+	outcome := "success"
+	if errString != "" {
+		severity = 7
+		outcome = "failure"
+	}
+
+	header := []string{
+		"CEF:0",
+		"HashiCorp",
+		"Vault",
+		version.GetVersion().Version,
+		fmt.Sprintf("%s:%s", entryType, req.Operation),
+		fmt.Sprintf("%s %s", req.Operation, req.Path),
+		strconv.Itoa(severity),
+	}
+	for i, field := range header[1:] {
+		header[i+1] = cefHeaderEscaper.Replace(field)
+	}
+
+	var ext []string
+	add := func(key, value string) {
+		if value != "" {
+			ext = append(ext, key+"="+cefExtensionEscaper.Replace(value))
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, entryTime); err == nil {
+		add("rt", strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+	}
+	add("act", string(req.Operation))
+	add("request", req.Path)
+	add("src", req.RemoteAddr)
+	add("suser", auth.DisplayName)
+	add("suid", auth.EntityID)
+	add("externalId", req.ID)
+	add("outcome", outcome)
+	add("reason", errString)
+	custom := func(key, label, value string) {
+		if value != "" {
+			add(key+"Label", label)
+			add(key, value)
+		}
+	}
+	custom("cs1", "mountType", req.MountType)
+	if req.Namespace != nil {
+		custom("cs2", "namespace", req.Namespace.Path)
+	}
+	custom("cs3", "policies", strings.Join(auth.Policies, ","))
+	custom("cs4", "tokenType", auth.TokenType)
+	custom("cs5", "clientTokenAccessor", req.ClientTokenAccessor)
+	custom("cs6", "clientToken", req.ClientToken)
+
+	line := f.Prefix + strings.Join(header, "|") + "|" + strings.Join(ext, " ") + "\n"
+	_, err := w.Write([]byte(line))
+	return err
+}
+
+func (f *CEFFormatWriter) Salt(ctx context.Context) (*salt.Salt, error) {
+	return f.SaltFunc(ctx)
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/version"
+)
+
+func TestFormatCEF_formatRequest(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatter := AuditFormatter{
+		AuditFormatWriter: &CEFFormatWriter{
+			Prefix: "@cef: ",
+			SaltFunc: func(context.Context) (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+			Accessor:    "bar",
+			DisplayName: "test|token",
+			EntityID:    "foobarentity",
+			Policies:    []string{"default", "dev"},
+			TokenType:   logical.TokenTypeService,
+		},
+		Request: &logical.Request{
+			ID:                  "req-id",
+			Operation:           logical.UpdateOperation,
+			Path:                "secret/a=b",
+			ClientToken:         "foo",
+			ClientTokenAccessor: "bar",
+			MountType:           "kv",
+			Connection: &logical.Connection{
+				RemoteAddr: "127.0.0.1",
+			},
+		},
+		OuterErr: errors.New("permission denied"),
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatRequest(namespace.RootContext(nil), &buf, FormatterConfig{HMACAccessor: true}, in); err != nil {
+		t.Fatal(err)
+	}
+	line := buf.String()
+
+	header := "@cef: CEF:0|HashiCorp|Vault|" + version.GetVersion().Version + "|request:update|update secret/a=b|7|"
+	if !strings.HasPrefix(line, header) {
+		t.Fatalf("expected %q to start with %q", line, header)
+	}
+	if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+		t.Fatalf("expected a single line, got %q", line)
+	}
+
+	for _, expected := range []string{
+		"act=update",
+		`request=secret/a\=b`,
+		"src=127.0.0.1",
+		"suser=test|token",
+		"suid=foobarentity",
+		"externalId=req-id",
+		"outcome=failure",
+		"reason=permission denied",
+		"cs1Label=mountType cs1=kv",
+		"cs3Label=policies cs3=default,dev",
+		"cs4Label=tokenType cs4=service",
+		"cs5Label=clientTokenAccessor cs5=" + salter.GetIdentifiedHMAC("bar"),
+		"cs6Label=clientToken cs6=" + salter.GetIdentifiedHMAC("foo"),
+	} {
+		if !strings.Contains(line, expected) {
+			t.Fatalf("expected %q to contain %q", line, expected)
+		}
+	}
+	if strings.Contains(line, "=foo ") || strings.Contains(line, "=bar ") {
+		t.Fatalf("sensitive values were not salted: %q", line)
+	}
+}
@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/version"
+)
+
+// OCSFFormatWriter is an AuditFormatWriter implementation that structures data
+// into Open Cybersecurity Schema Framework (OCSF) API Activity events, one
+// JSON object per line.
+type OCSFFormatWriter struct {
+	Prefix   string
+	SaltFunc func(context.Context) (*salt.Salt, error)
+}
+
+const (
+	ocsfSchemaVersion = "1.0.0"
+
+	ocsfCategoryUID = 6
+	ocsfClassUID    = 6003
+)
+
+// OCSFEvent is the subset of the OCSF API Activity class written by Vault.
+// Fields without an equivalent in the schema are kept in Unmapped.
+type OCSFEvent struct {
+	ActivityID   int                    `json:"activity_id"`
+	ActivityName string                 `json:"activity_name"`
+	CategoryUID  int                    `json:"category_uid"`
+	CategoryName string                 `json:"category_name"`
+	ClassUID     int                    `json:"class_uid"`
+	ClassName    string                 `json:"class_name"`
+	TypeUID      int                    `json:"type_uid"`
+	Time         int64                  `json:"time,omitempty"`
+	SeverityID   int                    `json:"severity_id"`
+	Severity     string                 `json:"severity"`
+	StatusID     int                    `json:"status_id"`
+	Status       string                 `json:"status"`
+	StatusDetail string                 `json:"status_detail,omitempty"`
+	Metadata     OCSFMetadata           `json:"metadata"`
+	Actor        OCSFActor              `json:"actor"`
+	API          OCSFAPI                `json:"api"`
+	SrcEndpoint  *OCSFEndpoint          `json:"src_endpoint,omitempty"`
+	Resources    []OCSFResource         `json:"resources,omitempty"`
+	Unmapped     map[string]interface{} `json:"unmapped,omitempty"`
+}
+
+type OCSFMetadata struct {
+	Version string      `json:"version"`
+	LogName string      `json:"log_name"`
+	Product OCSFProduct `json:"product"`
+	UID     string      `json:"uid,omitempty"`
+}
+
+type OCSFProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version"`
+}
+
+type OCSFActor struct {
+	User    *OCSFUser    `json:"user,omitempty"`
+	Session *OCSFSession `json:"session,omitempty"`
+}
+
+type OCSFUser struct {
+	Name string `json:"name,omitempty"`
+	UID  string `json:"uid,omitempty"`
+}
+
+type OCSFSession struct {
+	UID string `json:"uid,omitempty"`
+}
+
+type OCSFAPI struct {
+	Operation string           `json:"operation"`
+	Request   *OCSFAPIRequest  `json:"request,omitempty"`
+	Response  *OCSFAPIResponse `json:"response,omitempty"`
+	Service   *OCSFService     `json:"service,omitempty"`
+}
+
+type OCSFAPIRequest struct {
+	UID string `json:"uid,omitempty"`
+}
+
+type OCSFAPIResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type OCSFService struct {
+	Name string `json:"name,omitempty"`
+}
+
+type OCSFEndpoint struct {
+	IP string `json:"ip,omitempty"`
+}
+
+type OCSFResource struct {
+	UID  string `json:"uid"`
+	Type string `json:"type,omitempty"`
+}
+
+func (f *OCSFFormatWriter) WriteRequest(w io.Writer, req *AuditRequestEntry) error {
+	if req == nil {
+		return fmt.Errorf("request entry was nil, cannot encode")
+	}
+
+	event := newOCSFEvent(req.Type, req.Time, req.Auth, req.Request, req.Error)
+	return f.write(w, event)
+}
+
+func (f *OCSFFormatWriter) WriteResponse(w io.Writer, resp *AuditResponseEntry) error {
+	if resp == nil {
+		return fmt.Errorf("response entry was nil, cannot encode")
+	}
+
+	event := newOCSFEvent(resp.Type, resp.Time, resp.Auth, resp.Request, resp.Error)
+	if resp.Response != nil {
+		event.Unmapped["response"] = resp.Response
+	}
+	return f.write(w, event)
+}
+
+func (f *OCSFFormatWriter) write(w io.Writer, event *OCSFEvent) error {
+	if len(f.Prefix) > 0 {
+		_, err := w.Write([]byte(f.Prefix))
+		if err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	return enc.Encode(event)
+}
+
+func (f *OCSFFormatWriter) Salt(ctx context.Context) (*salt.Salt, error) {
+	return f.SaltFunc(ctx)
+}
+
+// newOCSFEvent maps the fields common to request and response entries. The
+// entry is already salted, so sensitive values are only copied as HMACs.
+func newOCSFEvent(entryType, entryTime string, auth *AuditAuth, req *AuditRequest, errString string) *OCSFEvent {
+	if req == nil {
+		req = &AuditRequest{}
+	}
+
+	activityID, activityName := ocsfActivity(req.Operation)
+	event := &OCSFEvent{
+		ActivityID:   activityID,
+		ActivityName: activityName,
+		CategoryUID:  ocsfCategoryUID,
+		CategoryName: "Application Activity",
+		ClassUID:     ocsfClassUID,
+		ClassName:    "API Activity",
+		TypeUID:      ocsfClassUID*100 + activityID,
+		SeverityID:   1,
+		Severity:     "Informational",
+		StatusID:     1,
+		Status:       "Success",
+		Metadata: OCSFMetadata{
+			Version: ocsfSchemaVersion,
+			LogName: entryType,
+			Product: OCSFProduct{
+				Name:       "Vault",
+				VendorName: "HashiCorp",
+				Version:    version.GetVersion().Version,
+			},
+			UID: req.ID,
+		},
+		API: OCSFAPI{
+			Operation: string(req.Operation),
+		},
+		Unmapped: map[string]interface{}{},
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, entryTime); err == nil {
+		event.Time = t.UnixNano() / int64(time.Millisecond)
+	}
+	if errString != "" {
+		event.StatusID, event.Status, event.StatusDetail = 2, "Failure", errString
+		event.API.Response = &OCSFAPIResponse{Error: errString}
+	}
+	if req.ID != "" {
+		event.API.Request = &OCSFAPIRequest{UID: req.ID}
+	}
+	if req.MountType != "" {
+		event.API.Service = &OCSFService{Name: req.MountType}
+	}
+	if req.RemoteAddr != "" {
+		event.SrcEndpoint = &OCSFEndpoint{IP: req.RemoteAddr}
+	}
+	if req.Path != "" {
+		event.Resources = []OCSFResource{{UID: req.Path, Type: req.MountType}}
+	}
+
+	if auth != nil {
+		if auth.DisplayName != "" || auth.EntityID != "" {
+			event.Actor.User = &OCSFUser{Name: auth.DisplayName, UID: auth.EntityID}
+		}
+		event.Unmapped["auth"] = auth
+	}
+	if req.ClientTokenAccessor != "" {
+		event.Actor.Session = &OCSFSession{UID: req.ClientTokenAccessor}
+	}
+	if req.Namespace != nil {
+		event.Unmapped["namespace"] = req.Namespace
+	}
+	if req.ClientToken != "" {
+		event.Unmapped["client_token"] = req.ClientToken
+	}
+	if req.Data != nil {
+		event.Unmapped["request_data"] = req.Data
+	}
+
+	return event
+}
+
+// ocsfActivity maps a Vault operation to an API Activity activity
+func ocsfActivity(op logical.Operation) (int, string) {
+	switch op {
+	case logical.CreateOperation:
+		return 1, "Create"
+	case logical.ReadOperation, logical.ListOperation:
+		return 2, "Read"
+	case logical.UpdateOperation:
+		return 3, "Update"
+	case logical.DeleteOperation:
+		return 4, "Delete"
+	default:
+		return 99, "Other"
+	}
+}
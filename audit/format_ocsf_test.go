@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestFormatOCSF_formatResponse(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	formatter := AuditFormatter{
+		AuditFormatWriter: &OCSFFormatWriter{
+			SaltFunc: func(context.Context) (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+			Accessor:    "bar",
+			DisplayName: "testtoken",
+			EntityID:    "foobarentity",
+			Policies:    []string{"default"},
+		},
+		Request: &logical.Request{
+			ID:                  "req-id",
+			Operation:           logical.ReadOperation,
+			Path:                "secret/foo",
+			ClientTokenAccessor: "bar",
+			MountType:           "kv",
+			Connection: &logical.Connection{
+				RemoteAddr: "127.0.0.1",
+			},
+		},
+		Response: &logical.Response{
+			Data: map[string]interface{}{
+				"password": "hunter2",
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := formatter.FormatResponse(namespace.RootContext(nil), &buf, FormatterConfig{HMACAccessor: true}, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var event OCSFEvent
+	if err := json.Unmarshal(buf.Bytes(), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	if event.ClassUID != 6003 || event.ActivityID != 2 || event.TypeUID != 600302 || event.StatusID != 1 {
+		t.Fatalf("bad: %#v", event)
+	}
+	if event.Metadata.LogName != "response" || event.Metadata.UID != "req-id" || event.Metadata.Product.Name != "Vault" {
+		t.Fatalf("bad: %#v", event.Metadata)
+	}
+	if event.Time == 0 {
+		t.Fatal("expected a time")
+	}
+	if event.Actor.User == nil || event.Actor.User.Name != "testtoken" || event.Actor.User.UID != "foobarentity" {
+		t.Fatalf("bad: %#v", event.Actor)
+	}
+	if event.Actor.Session == nil || event.Actor.Session.UID != salter.GetIdentifiedHMAC("bar") {
+		t.Fatalf("bad: %#v", event.Actor)
+	}
+	if event.API.Operation != "read" || event.API.Service.Name != "kv" || event.SrcEndpoint.IP != "127.0.0.1" {
+		t.Fatalf("bad: %#v", event)
+	}
+	if len(event.Resources) != 1 || event.Resources[0].UID != "secret/foo" {
+		t.Fatalf("bad: %#v", event.Resources)
+	}
+
+	data := event.Unmapped["response"].(map[string]interface{})["data"].(map[string]interface{})
+	if data["password"] != salter.GetIdentifiedHMAC("hunter2") {
+		t.Fatalf("response data was not salted: %#v", data)
+	}
+}
@@ -21,6 +21,9 @@ type FormatterConfig struct {
 	Raw          bool
 	HMACAccessor bool
 
+	// FieldSelectors of the audit device, applied after those of the mount
+	FieldSelectors []*FieldSelector
+
 	// This should only ever be used in a testing context
 	OmitTime bool
 }
@@ -0,0 +1,430 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// HashChainKeysLocation is the storage key of the hash chain keys of an
+// audit device, within its barrier view.
+const HashChainKeysLocation = "hash-chain-keys"
+
+// HashChainKeys are the keys used to chain and sign the entries of an audit
+// device. The HMAC key links each entry to the previous one, the signing key
+// signs the checkpoints.
+type HashChainKeys struct {
+	HMACKey    []byte             `json:"hmac_key"`
+	SigningKey ed25519.PrivateKey `json:"signing_key"`
+}
+
+// PublicKey returns the key verifying the checkpoints
+func (k *HashChainKeys) PublicKey() ed25519.PublicKey {
+	return k.SigningKey.Public().(ed25519.PublicKey)
+}
+
+// LoadHashChainKeys reads the hash chain keys from the storage of an audit
+// device, generating them if they do not exist yet.
+func LoadHashChainKeys(ctx context.Context, view logical.Storage) (*HashChainKeys, error) {
+	entry, err := view.Get(ctx, HashChainKeysLocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hash chain keys: %w", err)
+	}
+	if entry != nil {
+		var keys HashChainKeys
+		if err := entry.DecodeJSON(&keys); err != nil {
+			return nil, fmt.Errorf("failed to decode hash chain keys: %w", err)
+		}
+		return &keys, nil
+	}
+
+	keys := &HashChainKeys{
+		HMACKey: make([]byte, 32),
+	}
+	if _, err := rand.Read(keys.HMACKey); err != nil {
+		return nil, err
+	}
+	if _, keys.SigningKey, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		return nil, err
+	}
+
+	entry, err = logical.StorageEntryJSON(HashChainKeysLocation, keys)
+	if err != nil {
+		return nil, err
+	}
+	if err := view.Put(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to persist hash chain keys: %w", err)
+	}
+	return keys, nil
+}
+
+// HashChainBackend is implemented by audit backends that can chain their
+// entries.
+type HashChainBackend interface {
+	// HashChainKeys returns the keys used to chain and sign the entries, or
+	// an error if chaining is not enabled.
+	HashChainKeys(context.Context) (*HashChainKeys, error)
+}
+
+// HashChain links every entry written by an AuditFormatter to the previous
+// one: each entry holds its sequence number and the HMAC of the previous
+// line, so that removing, inserting or editing lines breaks the chain. Every
+// CheckpointInterval lines of the chain, a checkpoint signing its head is also
+// written. A file continuing the chain from a previous one starts with a
+// signed anchor, so that its head cannot be removed unnoticed.
+//
+// Entries must be written out in the order they are formatted, so callers
+// have to serialize formatting and writing, and call Commit once an entry was
+// written out.
+type HashChain struct {
+	// Storage holds the keys of the chain
+	Storage            logical.Storage
+	CheckpointInterval uint64
+
+	lock     sync.Mutex
+	keys     *HashChainKeys
+	sequence uint64
+	prevHMAC string
+
+	// pending is the head of the chain once the last entry formatted is
+	// written out
+	pending *hashChainHead
+}
+
+type hashChainHead struct {
+	sequence uint64
+	hmac     string
+}
+
+// AuditCheckpointEntry is the structure of a checkpoint or of an anchor in a
+// chained audit log. A checkpoint is part of the chain and signs the HMAC of
+// the line before it, an anchor signs the sequence number and previous HMAC
+// of the entry following it.
+type AuditCheckpointEntry struct {
+	Time      string `json:"time,omitempty"`
+	Type      string `json:"type"`
+	Sequence  uint64 `json:"sequence"`
+	PrevHMAC  string `json:"prev_hmac"`
+	Signature string `json:"signature"`
+}
+
+// Keys returns the keys of the chain, loading them if needed
+func (c *HashChain) Keys(ctx context.Context) (*HashChainKeys, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.loadKeys(ctx)
+}
+
+func (c *HashChain) loadKeys(ctx context.Context) (*HashChainKeys, error) {
+	if c.keys != nil {
+		return c.keys, nil
+	}
+
+	keys, err := LoadHashChainKeys(ctx, c.Storage)
+	if err != nil {
+		return nil, err
+	}
+	c.keys = keys
+	return keys, nil
+}
+
+// write calls format to encode the next entry of the chain, given its
+// sequence number and the HMAC of the previous line, and writes it to w along
+// with a checkpoint if one is due. The chain only advances past the entry once
+// Commit is called.
+func (c *HashChain) write(ctx context.Context, w io.Writer, format func(w io.Writer, sequence uint64, prevHMAC string) error) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	keys, err := c.loadKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	sequence, prevHMAC := c.sequence+1, c.prevHMAC
+	if err := format(&buf, sequence, prevHMAC); err != nil {
+		return err
+	}
+	lineHMAC := hashChainHMAC(keys.HMACKey, buf.Bytes())
+
+	if c.CheckpointInterval > 0 && sequence%c.CheckpointInterval == 0 {
+		checkpoint := &AuditCheckpointEntry{
+			Time:     time.Now().UTC().Format(time.RFC3339Nano),
+			Type:     "checkpoint",
+			Sequence: sequence + 1,
+			PrevHMAC: lineHMAC,
+		}
+		checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(keys.SigningKey, checkpointMessage(checkpoint.Sequence, checkpoint.PrevHMAC)))
+
+		start := buf.Len()
+		if err := json.NewEncoder(&buf).Encode(checkpoint); err != nil {
+			return err
+		}
+		sequence, lineHMAC = checkpoint.Sequence, hashChainHMAC(keys.HMACKey, buf.Bytes()[start:])
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+
+	c.pending = &hashChainHead{sequence: sequence, hmac: lineHMAC}
+	return nil
+}
+
+// Commit advances the chain past the last entry formatted. It must be called
+// once the entry was written out, so that the next entry is not linked to an
+// entry that failed to be written.
+func (c *HashChain) Commit() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.pending != nil {
+		c.sequence, c.prevHMAC = c.pending.sequence, c.pending.hmac
+		c.pending = nil
+	}
+}
+
+// Anchor returns the anchor to write at the start of a new file, before the
+// next entry of the chain. It is nil if the next entry starts the chain.
+func (c *HashChain) Anchor(ctx context.Context) ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sequence == 0 {
+		return nil, nil
+	}
+	keys, err := c.loadKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor := &AuditCheckpointEntry{
+		Time:     time.Now().UTC().Format(time.RFC3339Nano),
+		Type:     "anchor",
+		Sequence: c.sequence + 1,
+		PrevHMAC: c.prevHMAC,
+	}
+	anchor.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(keys.SigningKey, anchorMessage(anchor.Sequence, anchor.PrevHMAC)))
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(anchor); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Resume continues the chain from the given line, the last line of the chain
+// written to the log before the chain was set up, e.g. before Vault restarted.
+// It reports whether the line is part of a chain. Other lines are ignored.
+func (c *HashChain) Resume(ctx context.Context, line []byte) (bool, error) {
+	var entry struct {
+		Type     string `json:"type"`
+		Sequence uint64 `json:"sequence"`
+	}
+	start := bytes.IndexByte(line, '{')
+	if start < 0 || json.Unmarshal(line[start:], &entry) != nil || entry.Sequence == 0 || entry.Type == "anchor" {
+		return false, nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.sequence != 0 {
+		return true, nil
+	}
+	keys, err := c.loadKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.sequence, c.prevHMAC = entry.Sequence, hashChainHMAC(keys.HMACKey, line)
+	return true, nil
+}
+
+// hashChainHMAC returns the HMAC of a line of the log, without its trailing
+// newline
+func hashChainHMAC(key, line []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(bytes.TrimRight(line, "\r\n"))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func checkpointMessage(sequence uint64, prevHMAC string) []byte {
+	return []byte(strconv.FormatUint(sequence, 10) + ":" + prevHMAC)
+}
+
+// anchorMessage differs from checkpointMessage so that a checkpoint cannot be
+// passed off as an anchor
+func anchorMessage(sequence uint64, prevHMAC string) []byte {
+	return append([]byte("anchor:"), checkpointMessage(sequence, prevHMAC)...)
+}
+
+// HashChainVerification is the result of verifying a chained audit log
+type HashChainVerification struct {
+	Entries     int `json:"entries"`
+	Checkpoints int `json:"checkpoints"`
+
+	// Segments is the number of chains in the log. A new chain starts when
+	// the audit device is set up without a previous chained entry in its log.
+	Segments int `json:"segments"`
+
+	// LastCheckpointLine is the last line covered by a valid checkpoint.
+	// Lines after it are chained but not signed yet.
+	LastCheckpointLine int `json:"last_checkpoint_line"`
+
+	Errors []string `json:"errors"`
+}
+
+// Valid returns whether no error was found in the log
+func (v *HashChainVerification) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// VerifyHashChain verifies the chain of the audit log read from r. Lines may
+// start with a prefix before the JSON entry. The only unchained entries
+// accepted are the test messages written when enabling the device. The log
+// must start with the start of a chain, or with a valid anchor if it
+// continues a chain from a previous file. Successive files of a log can be
+// verified together by concatenating them, in which case the anchor of each
+// file must also follow the last entry of the previous one.
+func VerifyHashChain(r io.Reader, hmacKey []byte, publicKey ed25519.PublicKey) (*HashChainVerification, error) {
+	ret := &HashChainVerification{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var (
+		lineNum      int
+		seen         bool
+		chained      bool
+		prevSequence uint64
+		prevHMAC     string
+
+		// anchored is set when the previous line is a valid anchor, which
+		// the next entry must match
+		anchored   bool
+		anchorSeq  uint64
+		anchorHMAC string
+	)
+	fail := func(format string, args ...interface{}) {
+		ret.Errors = append(ret.Errors, fmt.Sprintf("line %d: ", lineNum)+fmt.Sprintf(format, args...))
+	}
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry struct {
+			Type      string `json:"type"`
+			Sequence  uint64 `json:"sequence"`
+			PrevHMAC  string `json:"prev_hmac"`
+			Signature string `json:"signature"`
+			Request   *struct {
+				Path string `json:"path"`
+			} `json:"request"`
+		}
+		start := bytes.IndexByte(line, '{')
+		if start < 0 || json.Unmarshal(line[start:], &entry) != nil {
+			fail("not a JSON audit entry")
+			seen, chained, anchored = true, false, false
+			continue
+		}
+
+		first := !seen
+		seen = true
+
+		if entry.Type == "anchor" {
+			anchored = false
+			sig, err := base64.StdEncoding.DecodeString(entry.Signature)
+			if err != nil || !ed25519.Verify(publicKey, anchorMessage(entry.Sequence, entry.PrevHMAC), sig) {
+				fail("invalid anchor signature")
+				continue
+			}
+			// The files of the log are concatenated, so the anchor must
+			// follow the end of the previous file
+			if chained && (entry.Sequence != prevSequence+1 || !hmac.Equal([]byte(entry.PrevHMAC), []byte(prevHMAC))) {
+				fail("anchor of entry %d does not follow entry %d", entry.Sequence, prevSequence)
+			}
+			anchored, anchorSeq, anchorHMAC = true, entry.Sequence, entry.PrevHMAC
+			continue
+		}
+
+		switch {
+		case entry.Sequence == 0:
+			// Test messages are not part of the chain
+			if entry.Request == nil || entry.Request.Path != "sys/audit/test" {
+				fail("unchained entry")
+			}
+			continue
+
+		case anchored:
+			if entry.Sequence != anchorSeq || !hmac.Equal([]byte(entry.PrevHMAC), []byte(anchorHMAC)) {
+				fail("entry %d does not match the anchor preceding it", entry.Sequence)
+			}
+			if !chained {
+				ret.Segments++
+			}
+
+		case entry.Sequence == 1:
+			if entry.PrevHMAC != "" {
+				fail("chain start references a previous entry")
+			}
+			ret.Segments++
+
+		case first:
+			fail("log starts with entry %d, which neither starts a chain nor follows an anchor", entry.Sequence)
+			ret.Segments++
+
+		case !chained:
+			fail("entry %d does not follow a chained entry", entry.Sequence)
+			ret.Segments++
+
+		default:
+			if entry.Sequence != prevSequence+1 {
+				fail("expected entry %d, found entry %d", prevSequence+1, entry.Sequence)
+			}
+			if !hmac.Equal([]byte(entry.PrevHMAC), []byte(prevHMAC)) {
+				fail("entry %d does not match the HMAC of the previous line", entry.Sequence)
+			}
+		}
+		anchored = false
+
+		if entry.Type == "checkpoint" {
+			ret.Checkpoints++
+			sig, err := base64.StdEncoding.DecodeString(entry.Signature)
+			if err != nil || !ed25519.Verify(publicKey, checkpointMessage(entry.Sequence, entry.PrevHMAC), sig) {
+				fail("invalid checkpoint signature")
+			} else {
+				ret.LastCheckpointLine = lineNum
+			}
+		} else {
+			ret.Entries++
+		}
+
+		chained = true
+		prevSequence = entry.Sequence
+		prevHMAC = hashChainHMAC(hmacKey, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
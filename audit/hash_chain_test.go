@@ -0,0 +1,314 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// testChainedFormatter returns a formatter chaining its entries, along with
+// the storage of its chain
+func testChainedFormatter(t *testing.T, checkpointInterval uint64) (*AuditFormatter, logical.Storage) {
+	t.Helper()
+
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &logical.InmemStorage{}
+	formatter := &AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			Prefix: "vault: ",
+			SaltFunc: func(context.Context) (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+		Chain: &HashChain{
+			Storage:            storage,
+			CheckpointInterval: checkpointInterval,
+		},
+	}
+	return formatter, storage
+}
+
+// testWriteChained writes the given number of chained entries to buf
+func testWriteChained(t *testing.T, formatter *AuditFormatter, buf *bytes.Buffer, entries int) {
+	t.Helper()
+
+	ctx := namespace.RootContext(nil)
+	for i := 0; i < entries; i++ {
+		in := &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      "secret/foo",
+			},
+		}
+		format := formatter.FormatRequest
+		if i%2 == 1 {
+			format = formatter.FormatResponse
+		}
+		if err := format(ctx, buf, FormatterConfig{}, in); err != nil {
+			t.Fatal(err)
+		}
+		formatter.Chain.Commit()
+	}
+}
+
+// testChainedLog returns a log of the given number of chained entries, written
+// after a test message, along with the keys of its chain
+func testChainedLog(t *testing.T, entries int, checkpointInterval uint64) ([]string, *HashChainKeys) {
+	t.Helper()
+
+	formatter, storage := testChainedFormatter(t, checkpointInterval)
+
+	ctx := namespace.RootContext(nil)
+	var buf bytes.Buffer
+	testMessage := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "sys/audit/test",
+		},
+	}
+	if err := NewTemporaryFormatter("json", "").FormatRequest(ctx, &buf, FormatterConfig{}, testMessage); err != nil {
+		t.Fatal(err)
+	}
+	testWriteChained(t, formatter, &buf, entries)
+
+	keys, err := LoadHashChainKeys(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return testSplitLines(buf.String()), keys
+}
+
+func testSplitLines(log string) []string {
+	return strings.SplitAfter(strings.TrimSuffix(log, "\n"), "\n")
+}
+
+func testVerifyHashChain(t *testing.T, lines []string, keys *HashChainKeys) *HashChainVerification {
+	t.Helper()
+
+	result, err := VerifyHashChain(strings.NewReader(strings.Join(lines, "")), keys.HMACKey, keys.PublicKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestHashChain_verify(t *testing.T) {
+	lines, keys := testChainedLog(t, 10, 4)
+
+	// One test message, 10 entries and a checkpoint every 4 lines of the chain
+	if len(lines) != 14 {
+		t.Fatalf("expected 14 lines, got %d", len(lines))
+	}
+	if !strings.HasPrefix(lines[1], `vault: {"time"`) || !strings.Contains(lines[1], `"sequence":1`) {
+		t.Fatalf("bad: %s", lines[1])
+	}
+	if !strings.Contains(lines[5], `"type":"checkpoint"`) {
+		t.Fatalf("bad: %s", lines[5])
+	}
+
+	result := testVerifyHashChain(t, lines, keys)
+	if !result.Valid() || result.Entries != 10 || result.Checkpoints != 3 || result.Segments != 1 || result.LastCheckpointLine != 14 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// A log missing the start of its chain is not valid
+	result = testVerifyHashChain(t, lines[3:], keys)
+	if result.Valid() || result.Errors[0] != "line 1: log starts with entry 3, which neither starts a chain nor follows an anchor" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestHashChain_anchor(t *testing.T) {
+	formatter, storage := testChainedFormatter(t, 4)
+	ctx := namespace.RootContext(nil)
+
+	// The first file starts the chain, so it needs no anchor
+	anchor, err := formatter.Chain.Anchor(ctx)
+	if err != nil || anchor != nil {
+		t.Fatalf("expected no anchor, got %q, %v", anchor, err)
+	}
+
+	var files []string
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		anchor, err := formatter.Chain.Anchor(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Write(anchor)
+		testWriteChained(t, formatter, &buf, 3)
+		files = append(files, buf.String())
+	}
+	keys, err := LoadHashChainKeys(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each file verifies on its own, and so do the files concatenated
+	for i, file := range files {
+		result := testVerifyHashChain(t, testSplitLines(file), keys)
+		if !result.Valid() || result.Entries != 3 || result.Segments != 1 {
+			t.Fatalf("file %d: bad: %#v", i, result)
+		}
+	}
+	result := testVerifyHashChain(t, testSplitLines(strings.Join(files, "")), keys)
+	if !result.Valid() || result.Entries != 9 || result.Checkpoints != 2 || result.Segments != 1 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// A missing file breaks the chain at the anchor of the next one
+	result = testVerifyHashChain(t, testSplitLines(files[0]+files[2]), keys)
+	if result.Valid() || !strings.Contains(result.Errors[0], "anchor of entry 8 does not follow entry 3") {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// A checkpoint cannot be passed off as an anchor, so the files cannot be
+	// cut at a checkpoint
+	lines := testSplitLines(strings.Join(files, ""))
+	var forged []string
+	for i, line := range lines {
+		if strings.Contains(line, `"type":"checkpoint"`) {
+			forged = append([]string{strings.Replace(line, `"type":"checkpoint"`, `"type":"anchor"`, 1)}, lines[i+1:]...)
+			break
+		}
+	}
+	result = testVerifyHashChain(t, forged, keys)
+	if result.Valid() || result.Errors[0] != "line 1: invalid anchor signature" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestHashChain_commit(t *testing.T) {
+	formatter, storage := testChainedFormatter(t, 0)
+	ctx := namespace.RootContext(nil)
+
+	var buf bytes.Buffer
+	testWriteChained(t, formatter, &buf, 2)
+
+	// An entry which failed to be written is not linked to
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "secret/lost",
+		},
+	}
+	if err := formatter.FormatRequest(ctx, &bytes.Buffer{}, FormatterConfig{}, in); err != nil {
+		t.Fatal(err)
+	}
+	testWriteChained(t, formatter, &buf, 1)
+
+	keys, err := LoadHashChainKeys(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := testVerifyHashChain(t, testSplitLines(buf.String()), keys)
+	if !result.Valid() || result.Entries != 3 {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestHashChain_resume(t *testing.T) {
+	formatter, storage := testChainedFormatter(t, 2)
+	ctx := namespace.RootContext(nil)
+
+	var buf bytes.Buffer
+	testWriteChained(t, formatter, &buf, 2)
+	lines := testSplitLines(buf.String())
+
+	// A new chain, e.g. after a restart, continues from the last line of the
+	// log, here a checkpoint
+	resumed := &HashChain{
+		Storage:            storage,
+		CheckpointInterval: 2,
+	}
+	for _, line := range []string{"not an entry", `{"type":"anchor","sequence":1}`} {
+		if chained, err := resumed.Resume(ctx, []byte(line)); err != nil || chained {
+			t.Fatalf("%q: expected the line to be skipped, got %t, %v", line, chained, err)
+		}
+	}
+	last := strings.TrimSuffix(lines[len(lines)-1], "\n")
+	if chained, err := resumed.Resume(ctx, []byte(last)); err != nil || !chained {
+		t.Fatalf("expected the chain to resume, got %t, %v", chained, err)
+	}
+	formatter.Chain = resumed
+	testWriteChained(t, formatter, &buf, 2)
+
+	keys, err := LoadHashChainKeys(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := testVerifyHashChain(t, testSplitLines(buf.String()), keys)
+	if !result.Valid() || result.Entries != 4 || result.Checkpoints != 3 || result.Segments != 1 {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestHashChain_tampering(t *testing.T) {
+	lines, keys := testChainedLog(t, 10, 4)
+
+	tamper := func(f func([]string) []string) []string {
+		copied := make([]string, len(lines))
+		copy(copied, lines)
+		return f(copied)
+	}
+
+	cases := map[string]struct {
+		lines    []string
+		expected string
+	}{
+		"edited entry": {
+			tamper(func(l []string) []string {
+				l[2] = strings.Replace(l[2], "secret/foo", "secret/bar", 1)
+				return l
+			}),
+			"line 4: entry 3 does not match the HMAC of the previous line",
+		},
+		"removed entry": {
+			tamper(func(l []string) []string {
+				return append(l[:3], l[4:]...)
+			}),
+			"line 4: expected entry 3, found entry 4",
+		},
+		"inserted entry": {
+			tamper(func(l []string) []string {
+				return append(l[:3], append([]string{`{"type":"request","request":{"path":"secret/foo"}}` + "\n"}, l[3:]...)...)
+			}),
+			"line 4: unchained entry",
+		},
+		"forged checkpoint": {
+			tamper(func(l []string) []string {
+				l[5] = strings.Replace(l[5], `"signature":"`, `"signature":"AA`, 1)
+				return l
+			}),
+			"line 6: invalid checkpoint signature",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			result := testVerifyHashChain(t, tc.lines, keys)
+			if result.Valid() {
+				t.Fatal("expected verification to fail")
+			}
+			if result.Errors[0] != tc.expected {
+				t.Fatalf("expected %q, got %q", tc.expected, result.Errors)
+			}
+		})
+	}
+
+	// Keys of another chain fail every link
+	_, otherKeys := testChainedLog(t, 1, 0)
+	result := testVerifyHashChain(t, lines, otherKeys)
+	if result.Valid() {
+		t.Fatal("expected verification to fail")
+	}
+}
@@ -11,12 +11,20 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// chainResumeMaxBytes is how much of the end of the file is read to continue
+// the hash chain from the entries already in it
+const chainResumeMaxBytes = 1024 * 1024
+
 func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, error) {
 	if conf.SaltConfig == nil {
 		return nil, fmt.Errorf("nil salt config")
@@ -46,7 +54,7 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		format = "json"
 	}
 	switch format {
-	case "json", "jsonx":
+	case "json", "jsonx", "cef", "ocsf":
 	default:
 		return nil, fmt.Errorf("unknown format type %q", format)
 	}
@@ -71,6 +79,33 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	fieldSelectors, err := audit.ParseFieldSelectors(strutil.ParseStringSlice(conf.Config["field_selectors"], ","))
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if entries should be chained
+	hashChain := false
+	if raw, ok := conf.Config["hash_chain"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		hashChain = b
+	}
+	if hashChain && format != "json" {
+		return nil, fmt.Errorf("hash_chain is only supported with the json format")
+	}
+
+	checkpointInterval := uint64(1000)
+	if raw, ok := conf.Config["checkpoint_interval"]; ok {
+		i, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		checkpointInterval = i
+	}
+
 	// Check if mode is provided
 	mode := os.FileMode(0o600)
 	if modeRaw, ok := conf.Config["mode"]; ok {
@@ -83,15 +118,73 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		}
 	}
 
+	// Check if the file should be rotated
+	var maxBytes int64
+	if raw, ok := conf.Config["max_bytes"]; ok {
+		i, err := parseutil.ParseCapacityString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_bytes: %w", err)
+		}
+		maxBytes = int64(i)
+	}
+
+	var maxAge time.Duration
+	if raw, ok := conf.Config["max_age"]; ok {
+		d, err := parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_age: %w", err)
+		}
+		maxAge = d
+	}
+
+	var maxFiles int
+	if raw, ok := conf.Config["max_files"]; ok {
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_files: %w", err)
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("max_files cannot be negative")
+		}
+		maxFiles = i
+	}
+
+	compress := false
+	if raw, ok := conf.Config["compress"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		compress = b
+	}
+
+	if maxBytes > 0 || maxAge > 0 {
+		switch path {
+		case "stdout", "discard", "/dev/null":
+			return nil, fmt.Errorf("rotation is not supported when logging to %q", path)
+		}
+	}
+
+	logger := conf.Logger
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
 	b := &Backend{
 		path:       path,
+		logger:     logger,
 		mode:       mode,
+		maxBytes:   maxBytes,
+		maxAge:     maxAge,
+		maxFiles:   maxFiles,
+		compress:   compress,
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		salt:       new(atomic.Value),
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:            logRaw,
+			HMACAccessor:   hmacAccessor,
+			FieldSelectors: fieldSelectors,
 		},
 	}
 
@@ -99,16 +192,16 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 	// the right type
 	b.salt.Store((*salt.Salt)(nil))
 
-	switch format {
-	case "json":
-		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
-			Prefix:   conf.Config["prefix"],
-			SaltFunc: b.Salt,
-		}
-	case "jsonx":
-		b.formatter.AuditFormatWriter = &audit.JSONxFormatWriter{
-			Prefix:   conf.Config["prefix"],
-			SaltFunc: b.Salt,
+	writer, err := audit.NewFormatWriter(format, conf.Config["prefix"], b.Salt)
+	if err != nil {
+		return nil, err
+	}
+	b.formatter.AuditFormatWriter = writer
+
+	if hashChain {
+		b.formatter.Chain = &audit.HashChain{
+			Storage:            conf.SaltView,
+			CheckpointInterval: checkpointInterval,
 		}
 	}
 
@@ -129,11 +222,12 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 
 // Backend is the audit backend for the file-based audit store.
 //
-// NOTE: This audit backend is currently very simple: it appends to a file.
-// It doesn't do anything more at the moment to assist with rotation
-// or reset the write cursor, this should be done in the future.
+// The backend appends to a file. When max_bytes or max_age is set, the file
+// is rotated before the write that would exceed them, under the file lock, so
+// that every entry is written exactly once to either the old or the new file.
 type Backend struct {
-	path string
+	path   string
+	logger log.Logger
 
 	formatter    audit.AuditFormatter
 	formatConfig audit.FormatterConfig
@@ -142,13 +236,34 @@ type Backend struct {
 	f        *os.File
 	mode     os.FileMode
 
+	// size and startedAt describe the current file, for rotation
+	size      int64
+	startedAt time.Time
+	maxBytes  int64
+	maxAge    time.Duration
+	maxFiles  int
+	compress  bool
+
+	// rotateLock serializes compressing and pruning rotated files, which
+	// happens in the background
+	rotateLock sync.Mutex
+	rotateWG   sync.WaitGroup
+
+	// chainLock ensures chained entries are written in order. chainResumed
+	// is set once the chain continues from the entries already in the file.
+	chainLock    sync.Mutex
+	chainResumed bool
+
 	saltMutex  sync.RWMutex
 	salt       *atomic.Value
 	saltConfig *salt.Config
 	saltView   logical.Storage
 }
 
-var _ audit.Backend = (*Backend)(nil)
+var (
+	_ audit.Backend          = (*Backend)(nil)
+	_ audit.HashChainBackend = (*Backend)(nil)
+)
 
 func (b *Backend) Salt(ctx context.Context) (*salt.Salt, error) {
 	s := b.salt.Load().(*salt.Salt)
@@ -192,33 +307,65 @@ func (b *Backend) LogRequest(ctx context.Context, in *logical.LogInput) error {
 		return nil
 	}
 
+	if b.formatter.Chain != nil {
+		b.chainLock.Lock()
+		defer b.chainLock.Unlock()
+
+		if writer == nil {
+			if err := b.resumeChain(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
 	buf := bytes.NewBuffer(make([]byte, 0, 2000))
 	err := b.formatter.FormatRequest(ctx, buf, b.formatConfig, in)
 	if err != nil {
 		return err
 	}
 
-	return b.log(ctx, buf, writer)
+	if err := b.log(ctx, buf, writer); err != nil {
+		return err
+	}
+	if b.formatter.Chain != nil {
+		b.formatter.Chain.Commit()
+	}
+	return nil
 }
 
 func (b *Backend) log(ctx context.Context, buf *bytes.Buffer, writer io.Writer) error {
-	reader := bytes.NewReader(buf.Bytes())
+	data := buf.Bytes()
 
 	b.fileLock.Lock()
+	defer b.fileLock.Unlock()
 
 	if writer == nil {
+		if b.rotationDue(buf.Len()) {
+			if err := b.rotate(); err != nil {
+				return err
+			}
+		}
 		if err := b.open(); err != nil {
-			b.fileLock.Unlock()
 			return err
 		}
 		writer = b.f
+
+		// A new file continuing the chain starts with an anchor
+		if b.size == 0 && b.formatter.Chain != nil {
+			anchor, err := b.formatter.Chain.Anchor(ctx)
+			if err != nil {
+				return err
+			}
+			data = append(anchor, data...)
+		}
 	}
+	reader := bytes.NewReader(data)
 
-	if _, err := reader.WriteTo(writer); err == nil {
-		b.fileLock.Unlock()
+	n, err := reader.WriteTo(writer)
+	if err == nil {
+		b.size += n
 		return nil
 	} else if b.path == "stdout" {
-		b.fileLock.Unlock()
 		return err
 	}
 
@@ -229,13 +376,12 @@ func (b *Backend) log(ctx context.Context, buf *bytes.Buffer, writer io.Writer)
 	b.f = nil
 
 	if err := b.open(); err != nil {
-		b.fileLock.Unlock()
 		return err
 	}
 
 	reader.Seek(0, io.SeekStart)
-	_, err := reader.WriteTo(writer)
-	b.fileLock.Unlock()
+	n, err = reader.WriteTo(b.f)
+	b.size += n
 	return err
 }
 
@@ -248,13 +394,30 @@ func (b *Backend) LogResponse(ctx context.Context, in *logical.LogInput) error {
 		return nil
 	}
 
+	if b.formatter.Chain != nil {
+		b.chainLock.Lock()
+		defer b.chainLock.Unlock()
+
+		if writer == nil {
+			if err := b.resumeChain(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
 	buf := bytes.NewBuffer(make([]byte, 0, 6000))
 	err := b.formatter.FormatResponse(ctx, buf, b.formatConfig, in)
 	if err != nil {
 		return err
 	}
 
-	return b.log(ctx, buf, writer)
+	if err := b.log(ctx, buf, writer); err != nil {
+		return err
+	}
+	if b.formatter.Chain != nil {
+		b.formatter.Chain.Commit()
+	}
+	return nil
 }
 
 func (b *Backend) LogTestMessage(ctx context.Context, in *logical.LogInput, config map[string]string) error {
@@ -275,6 +438,71 @@ func (b *Backend) LogTestMessage(ctx context.Context, in *logical.LogInput, conf
 	return b.log(ctx, &buf, writer)
 }
 
+// resumeChain continues the hash chain from the last chained line of the
+// file, so that the entries written before the device was set up, e.g. before
+// Vault restarted, stay linked to the new ones. Only the end of the file is
+// read. The chain lock must be held before calling this.
+func (b *Backend) resumeChain(ctx context.Context) error {
+	if b.chainResumed {
+		return nil
+	}
+
+	b.fileLock.Lock()
+	defer b.fileLock.Unlock()
+
+	f, err := os.Open(b.path)
+	switch {
+	case os.IsNotExist(err):
+		b.chainResumed = true
+		return nil
+	case err != nil:
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size() - chainResumeMaxBytes
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return err
+	}
+
+	lines := bytes.Split(tail, []byte("\n"))
+	if offset > 0 {
+		// The first line may be truncated
+		lines = lines[1:]
+	}
+	for i := len(lines) - 1; i >= 0; i-- {
+		if len(bytes.TrimSpace(lines[i])) == 0 {
+			continue
+		}
+		chained, err := b.formatter.Chain.Resume(ctx, lines[i])
+		if err != nil {
+			return err
+		}
+		if chained {
+			break
+		}
+	}
+
+	b.chainResumed = true
+	return nil
+}
+
+// HashChainKeys returns the keys used to chain and sign the entries
+func (b *Backend) HashChainKeys(ctx context.Context) (*audit.HashChainKeys, error) {
+	if b.formatter.Chain == nil {
+		return nil, fmt.Errorf("hash chaining is not enabled")
+	}
+	return b.formatter.Chain.Keys(ctx)
+}
+
 // The file lock must be held before calling this
 func (b *Backend) open() error {
 	if b.f != nil {
@@ -290,6 +518,13 @@ func (b *Backend) open() error {
 		return err
 	}
 
+	info, err := b.f.Stat()
+	if err != nil {
+		return err
+	}
+	b.size = info.Size()
+	b.startedAt = b.fileStartTime(info)
+
 	// Change the file mode in case the log file already existed. We special
 	// case /dev/null since we can't chmod it and bypass if the mode is zero
 	switch b.path {
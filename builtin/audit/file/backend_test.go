@@ -1,11 +1,19 @@
 package file
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -93,6 +101,347 @@ func TestAuditFile_fileModeExisting(t *testing.T) {
 	}
 }
 
+func testRotatingBackend(t *testing.T, config map[string]string) *Backend {
+	t.Helper()
+
+	be, err := Factory(context.Background(), &audit.BackendConfig{
+		Config:     config,
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return be.(*Backend)
+}
+
+// readRotatedLog returns the request paths logged in the file and its rotated
+// files
+func readRotatedLog(t *testing.T, b *Backend) ([]string, []string) {
+	t.Helper()
+
+	b.rotateWG.Wait()
+
+	files, err := b.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, name := range append(files, b.path) {
+		f, err := os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if strings.HasSuffix(name, ".gz") {
+			if r, err = gzip.NewReader(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			var entry audit.AuditRequestEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			paths = append(paths, entry.Request.Path)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return paths, files
+}
+
+func TestAuditFile_rotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test_audit_file-rotation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := testRotatingBackend(t, map[string]string{
+		"path":      filepath.Join(dir, "audit.log"),
+		"max_bytes": "2048",
+		"compress":  "true",
+	})
+
+	ctx := namespace.RootContext(nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			in := &logical.LogInput{
+				Request: &logical.Request{
+					Operation: logical.ReadOperation,
+					Path:      fmt.Sprintf("secret/%d", i),
+				},
+			}
+			if err := b.LogRequest(ctx, in); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	paths, files := readRotatedLog(t, b)
+	if len(files) < 2 {
+		t.Fatalf("expected the log to be rotated, got %v", files)
+	}
+	for _, name := range files {
+		if !strings.HasSuffix(name, ".log.gz") {
+			t.Fatalf("expected rotated files to be compressed, got %q", name)
+		}
+	}
+
+	// Every entry is logged exactly once
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if seen[path] {
+			t.Fatalf("duplicate entry %q", path)
+		}
+		seen[path] = true
+	}
+	if len(seen) != 200 {
+		t.Fatalf("expected 200 entries, got %d", len(seen))
+	}
+
+	// No file exceeds max_bytes
+	info, err := os.Stat(b.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() > 2048 {
+		t.Fatalf("file is %d bytes", info.Size())
+	}
+}
+
+func TestAuditFile_rotationMaxFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test_audit_file-rotation_max_files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := testRotatingBackend(t, map[string]string{
+		"path":      filepath.Join(dir, "audit.log"),
+		"max_age":   "1s",
+		"max_files": "2",
+	})
+
+	ctx := namespace.RootContext(nil)
+	for i := 0; i < 4; i++ {
+		b.fileLock.Lock()
+		b.startedAt = b.startedAt.Add(-time.Second)
+		b.fileLock.Unlock()
+
+		in := &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      fmt.Sprintf("secret/%d", i),
+			},
+		}
+		if err := b.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first entry is written to the new file, every following one rotates
+	// the file, and only the 2 newest rotated files are kept
+	paths, files := readRotatedLog(t, b)
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", files)
+	}
+	if expected := []string{"secret/1", "secret/2", "secret/3"}; strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+}
+
+func TestAuditFile_rotationMaxAgeRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test_audit_file-rotation_max_age_restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := map[string]string{
+		"path":    filepath.Join(dir, "audit.log"),
+		"max_age": "1h",
+	}
+	ctx := namespace.RootContext(nil)
+	logRequest := func(b *Backend, path string) {
+		in := &logical.LogInput{
+			Request: &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      path,
+			},
+		}
+		if err := b.LogRequest(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := testRotatingBackend(t, config)
+	logRequest(b, "secret/0")
+
+	// Without a rotated file, the age of the file is taken from its
+	// modification time when the backend is created again
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(config["path"], old, old); err != nil {
+		t.Fatal(err)
+	}
+	b = testRotatingBackend(t, config)
+	logRequest(b, "secret/1")
+
+	paths, files := readRotatedLog(t, b)
+	if len(files) != 1 {
+		t.Fatalf("expected 1 rotated file, got %v", files)
+	}
+	if expected := []string{"secret/0", "secret/1"}; strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	// A recent write does not reset the age of the file, which is started
+	// when the previous file is rotated
+	if err := os.Rename(files[0], b.rotatedPath(old)); err != nil {
+		t.Fatal(err)
+	}
+	b = testRotatingBackend(t, config)
+	logRequest(b, "secret/2")
+
+	paths, files = readRotatedLog(t, b)
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", files)
+	}
+	if expected := []string{"secret/0", "secret/1", "secret/2"}; strings.Join(paths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	// A file started recently is not rotated
+	b = testRotatingBackend(t, config)
+	logRequest(b, "secret/3")
+
+	if _, files = readRotatedLog(t, b); len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", files)
+	}
+}
+
+func TestAuditFile_hashChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-test_audit_file-hash_chain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	saltView := &logical.InmemStorage{}
+	newBackend := func() *Backend {
+		be, err := Factory(context.Background(), &audit.BackendConfig{
+			Config: map[string]string{
+				"path":                filepath.Join(dir, "audit.log"),
+				"max_age":             "1h",
+				"hash_chain":          "true",
+				"checkpoint_interval": "2",
+			},
+			SaltConfig: &salt.Config{},
+			SaltView:   saltView,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return be.(*Backend)
+	}
+
+	ctx := namespace.RootContext(nil)
+	logRequests := func(b *Backend, rotate bool) {
+		for i := 0; i < 3; i++ {
+			if rotate {
+				b.fileLock.Lock()
+				b.startedAt = b.startedAt.Add(-time.Hour)
+				b.fileLock.Unlock()
+			}
+			in := &logical.LogInput{
+				Request: &logical.Request{
+					Operation: logical.ReadOperation,
+					Path:      fmt.Sprintf("secret/%d", i),
+				},
+			}
+			if err := b.LogRequest(ctx, in); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// Entries are written across rotated files, then by a new backend
+	// continuing the chain of the current file, e.g. after a restart
+	b := newBackend()
+	logRequests(b, true)
+	b.rotateWG.Wait()
+	b = newBackend()
+	logRequests(b, false)
+
+	keys, err := b.HashChainKeys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := b.rotatedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", files)
+	}
+	verify := func(files ...string) *audit.HashChainVerification {
+		t.Helper()
+
+		var buf bytes.Buffer
+		for _, name := range files {
+			raw, err := ioutil.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf.Write(raw)
+		}
+		result, err := audit.VerifyHashChain(&buf, keys.HMACKey, keys.PublicKey())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := verify(append(files, b.path)...)
+	if !result.Valid() || result.Entries != 6 || result.Segments != 1 {
+		t.Fatalf("bad: %#v", result)
+	}
+
+	// Each file starting with an anchor verifies on its own, but not without
+	// the file before it
+	if result := verify(b.path); !result.Valid() {
+		t.Fatalf("bad: %#v", result)
+	}
+	if result := verify(files[0], b.path); result.Valid() {
+		t.Fatal("expected verification to fail without the middle file")
+	}
+}
+
+func TestAuditFile_rotationStdout(t *testing.T) {
+	_, err := Factory(context.Background(), &audit.BackendConfig{
+		Config: map[string]string{
+			"path":      "stdout",
+			"max_bytes": "1MB",
+		},
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
 func BenchmarkAuditFile_request(b *testing.B) {
 	config := map[string]string{
 		"path": "/dev/null",
@@ -0,0 +1,211 @@
+package file
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rotatedTimeFormat is the format of the timestamp in the name of rotated
+// files. It sorts lexically in chronological order.
+const rotatedTimeFormat = "20060102T150405.000Z"
+
+// rotationDue returns whether the file must be rotated before writing n more
+// bytes to it. The file lock must be held before calling this.
+func (b *Backend) rotationDue(n int) bool {
+	if b.maxBytes > 0 && b.size > 0 && b.size+int64(n) > b.maxBytes {
+		return true
+	}
+	if b.maxAge > 0 && time.Since(b.startedAt) >= b.maxAge {
+		return true
+	}
+	return false
+}
+
+// rotate closes the current file and renames it after the current time, so
+// that the next write opens a new file. Rotated files are then compressed and
+// pruned in the background. The file lock must be held before calling this.
+func (b *Backend) rotate() error {
+	if b.f != nil {
+		err := b.f.Close()
+		b.f = nil
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rotating an empty file would only create empty files
+	if b.size == 0 {
+		return nil
+	}
+
+	rotated := b.rotatedPath(time.Now())
+	for i := 1; fileExists(rotated) || fileExists(rotated+".gz"); i++ {
+		rotated = b.rotatedPath(time.Now().Add(time.Duration(i) * time.Millisecond))
+	}
+	if err := os.Rename(b.path, rotated); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	// Compressing and pruning are best effort: a file that failed to be
+	// compressed is kept as is, and pruning is retried on the next rotation
+	b.rotateWG.Add(1)
+	go func() {
+		defer b.rotateWG.Done()
+
+		b.rotateLock.Lock()
+		defer b.rotateLock.Unlock()
+
+		if b.compress {
+			if err := compressFile(rotated); err != nil {
+				b.logger.Error("failed to compress rotated audit log", "file", rotated, "error", err)
+			}
+		}
+		if b.maxFiles > 0 {
+			if err := b.pruneRotated(); err != nil {
+				b.logger.Error("failed to prune rotated audit logs", "error", err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// fileStartTime returns when the current file was started, given its info at
+// open, so that max_age holds across restarts. A file is started when the
+// previous one is rotated, so the time in the name of the newest rotated file
+// is used. Without a rotated file, the modification time of a non-empty file
+// is the best estimate available.
+func (b *Backend) fileStartTime(info os.FileInfo) time.Time {
+	if info.Size() == 0 {
+		return time.Now()
+	}
+
+	start := info.ModTime()
+	files, err := b.rotatedFiles()
+	if err == nil && len(files) > 0 {
+		if t, ok := b.rotatedTime(files[len(files)-1]); ok && t.Before(start) {
+			start = t
+		}
+	}
+	return start
+}
+
+// rotatedPath returns the path a file rotated at the given time is renamed
+// to: the timestamp is inserted before the extension of the file.
+func (b *Backend) rotatedPath(t time.Time) string {
+	ext := filepath.Ext(b.path)
+	return strings.TrimSuffix(b.path, ext) + "-" + t.UTC().Format(rotatedTimeFormat) + ext
+}
+
+// rotatedFiles returns the rotated files of the log, oldest first
+func (b *Backend) rotatedFiles() ([]string, error) {
+	dir := filepath.Dir(b.path)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() {
+			continue
+		}
+		if _, ok := b.rotatedTime(name); !ok {
+			continue
+		}
+		files = append(files, filepath.Join(dir, name))
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// rotatedTime returns the time a file was rotated at, parsed from its name,
+// and whether the name is the one of a rotated file of the log
+func (b *Backend) rotatedTime(path string) (time.Time, bool) {
+	ext := filepath.Ext(b.path)
+	prefix := strings.TrimSuffix(filepath.Base(b.path), ext) + "-"
+
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, prefix) {
+		return time.Time{}, false
+	}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz")
+	if !strings.HasSuffix(stamp, ext) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(rotatedTimeFormat, strings.TrimSuffix(stamp, ext))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// pruneRotated removes the oldest rotated files beyond maxFiles
+func (b *Backend) pruneRotated() error {
+	files, err := b.rotatedFiles()
+	if err != nil {
+		return err
+	}
+
+	for len(files) > b.maxFiles {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// compressFile replaces the file with a gzip compressed copy. The copy is
+// only put in place once fully written, so that an interrupted compression
+// leaves the original file.
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".gz.tmp"
+	out, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+
+	return os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,582 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, error) {
+	if conf.SaltConfig == nil {
+		return nil, fmt.Errorf("nil salt config")
+	}
+	if conf.SaltView == nil {
+		return nil, fmt.Errorf("nil salt view")
+	}
+
+	address, ok := conf.Config["address"]
+	if !ok {
+		return nil, fmt.Errorf("address is required")
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("address must be an http or https URL")
+	}
+
+	format, ok := conf.Config["format"]
+	if !ok {
+		format = "json"
+	}
+	// Entries are delivered as a JSON array, so only JSON formats are supported
+	switch format {
+	case "json", "ocsf":
+	default:
+		return nil, fmt.Errorf("unknown format type %q", format)
+	}
+
+	batchSize := 100
+	if raw, ok := conf.Config["batch_size"]; ok {
+		batchSize, err = strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid batch_size: %w", err)
+		}
+		if batchSize < 1 {
+			return nil, fmt.Errorf("batch_size must be at least 1")
+		}
+	}
+
+	durations := map[string]string{
+		"flush_interval":     "100ms",
+		"timeout":            "5s",
+		"retry_interval":     "10s",
+		"max_retry_interval": "5m",
+	}
+	parsed := make(map[string]time.Duration, len(durations))
+	for name, def := range durations {
+		raw, ok := conf.Config[name]
+		if !ok {
+			raw = def
+		}
+		d, err := parseutil.ParseDurationSecond(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("%s must be positive", name)
+		}
+		parsed[name] = d
+	}
+	if parsed["max_retry_interval"] < parsed["retry_interval"] {
+		return nil, fmt.Errorf("max_retry_interval must not be less than retry_interval")
+	}
+
+	// Check if hashing of accessor is disabled
+	hmacAccessor := true
+	if hmacAccessorRaw, ok := conf.Config["hmac_accessor"]; ok {
+		value, err := strconv.ParseBool(hmacAccessorRaw)
+		if err != nil {
+			return nil, err
+		}
+		hmacAccessor = value
+	}
+
+	// Check if raw logging is enabled
+	logRaw := false
+	if raw, ok := conf.Config["log_raw"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logRaw = b
+	}
+
+	fieldSelectors, err := audit.ParseFieldSelectors(strutil.ParseStringSlice(conf.Config["field_selectors"], ","))
+	if err != nil {
+		return nil, err
+	}
+
+	tlsSkipVerify := false
+	if raw, ok := conf.Config["tls_skip_verify"]; ok {
+		tlsSkipVerify, err = strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := &Backend{
+		saltConfig: conf.SaltConfig,
+		saltView:   conf.SaltView,
+		formatConfig: audit.FormatterConfig{
+			Raw:            logRaw,
+			HMACAccessor:   hmacAccessor,
+			FieldSelectors: fieldSelectors,
+		},
+
+		address:          address,
+		batchSize:        batchSize,
+		flushInterval:    parsed["flush_interval"],
+		timeout:          parsed["timeout"],
+		retryInterval:    parsed["retry_interval"],
+		maxRetryInterval: parsed["max_retry_interval"],
+		retryBackoff:     parsed["retry_interval"],
+		stopCh:           make(chan struct{}),
+
+		tlsCACert:     conf.Config["tls_ca_cert"],
+		tlsClientCert: conf.Config["tls_client_cert"],
+		tlsClientKey:  conf.Config["tls_client_key"],
+		tlsServerName: conf.Config["tls_server_name"],
+		tlsSkipVerify: tlsSkipVerify,
+	}
+
+	if b.formatter.AuditFormatWriter, err = audit.NewFormatWriter(format, "", b.Salt); err != nil {
+		return nil, err
+	}
+
+	if b.client, err = b.newClient(); err != nil {
+		return nil, err
+	}
+
+	if path, ok := conf.Config["spool_path"]; ok && path != "" {
+		maxSize, err := parseutil.ParseCapacityString(conf.Config["spool_max_size"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid spool_max_size: %w", err)
+		}
+		if maxSize == 0 {
+			maxSize = 100 * 1024 * 1024
+		}
+		logger := conf.Logger
+		if logger == nil {
+			logger = log.NewNullLogger()
+		}
+		if b.spool, err = newSpool(path, int64(maxSize), logger); err != nil {
+			return nil, err
+		}
+
+		// Batches spooled before a restart are retried right away
+		if !b.spool.empty() {
+			b.startRetry()
+		}
+	}
+
+	return b, nil
+}
+
+// Backend is the audit backend that sends batches of entries to an HTTP
+// collector. A request or response is only reported as logged once the batch
+// holding it has been accepted by the collector, or stored in the spool to be
+// retried later, which gives the same blocking semantics as the other audit
+// backends. Spooled batches are retried in the background, backing off
+// exponentially from retryInterval up to maxRetryInterval while the collector
+// fails.
+type Backend struct {
+	formatter    audit.AuditFormatter
+	formatConfig audit.FormatterConfig
+
+	address          string
+	batchSize        int
+	flushInterval    time.Duration
+	timeout          time.Duration
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+
+	tlsCACert     string
+	tlsClientCert string
+	tlsClientKey  string
+	tlsServerName string
+	tlsSkipVerify bool
+
+	// batchLock protects the batch being filled and its flush timer
+	batchLock sync.Mutex
+	current   *batch
+	timer     *time.Timer
+
+	// sendLock serializes deliveries, so that batches reach the collector in
+	// order, and protects the client, the spool and the retry state
+	sendLock     sync.Mutex
+	client       *http.Client
+	spool        *spool
+	retrying     bool
+	retryBackoff time.Duration
+	nextRetry    time.Time
+
+	// stopCh is closed to stop retrying the spool
+	stopCh    chan struct{}
+	closeOnce sync.Once
+
+	saltMutex  sync.RWMutex
+	salt       *salt.Salt
+	saltConfig *salt.Config
+	saltView   logical.Storage
+}
+
+var (
+	_ audit.Backend          = (*Backend)(nil)
+	_ audit.CloseableBackend = (*Backend)(nil)
+)
+
+// batch is a set of formatted entries delivered in a single request. Callers
+// that added an entry wait for done, after which err holds the outcome of the
+// delivery.
+type batch struct {
+	entries [][]byte
+	done    chan struct{}
+	err     error
+}
+
+func (b *Backend) GetHash(ctx context.Context, data string) (string, error) {
+	salt, err := b.Salt(ctx)
+	if err != nil {
+		return "", err
+	}
+	return audit.HashString(salt, data), nil
+}
+
+func (b *Backend) LogRequest(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatRequest(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.enqueue(ctx, buf.Bytes())
+}
+
+func (b *Backend) LogResponse(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatResponse(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.enqueue(ctx, buf.Bytes())
+}
+
+func (b *Backend) LogTestMessage(ctx context.Context, in *logical.LogInput, config map[string]string) error {
+	var buf bytes.Buffer
+	temporaryFormatter := audit.NewTemporaryFormatter(config["format"], "")
+	if err := temporaryFormatter.FormatRequest(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	// The test message bypasses the spool, the collector has to be reachable
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+
+	return b.send(ctx, encodeBatch([][]byte{buf.Bytes()}))
+}
+
+// enqueue adds the entry to the current batch and waits for the batch to be
+// delivered. The batch is flushed once it holds batchSize entries, or after
+// flushInterval.
+func (b *Backend) enqueue(ctx context.Context, entry []byte) error {
+	b.batchLock.Lock()
+	if b.current == nil {
+		current := &batch{
+			done: make(chan struct{}),
+		}
+		b.current = current
+		b.timer = time.AfterFunc(b.flushInterval, func() {
+			b.flush(current)
+		})
+	}
+	current := b.current
+	current.entries = append(current.entries, entry)
+	full := len(current.entries) >= b.batchSize
+	b.batchLock.Unlock()
+
+	if full {
+		b.flush(current)
+	}
+
+	select {
+	case <-current.done:
+		return current.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush delivers the batch, unless it was already flushed
+func (b *Backend) flush(current *batch) {
+	b.batchLock.Lock()
+	if b.current != current {
+		b.batchLock.Unlock()
+		return
+	}
+	b.current = nil
+	b.timer.Stop()
+	b.batchLock.Unlock()
+
+	current.err = b.deliver(encodeBatch(current.entries))
+	close(current.done)
+}
+
+// deliver sends the batch to the collector. If a spool is configured, failed
+// batches are stored in it and retried, oldest first, before newer batches are
+// sent. Delivery only fails if the batch could neither be sent nor spooled.
+func (b *Backend) deliver(body []byte) error {
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+
+	// Each request is bounded by the client timeout
+	ctx := context.Background()
+
+	if b.spool == nil {
+		return b.send(ctx, body)
+	}
+
+	var retErr *multierror.Error
+	if !b.spool.empty() && !time.Now().Before(b.nextRetry) {
+		if err := b.retrySpool(ctx); err != nil {
+			retErr = multierror.Append(retErr, err)
+		}
+	}
+
+	// Keep batches in order while older ones are waiting to be retried
+	if b.spool.empty() {
+		err := b.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		retErr = multierror.Append(retErr, err)
+	}
+
+	if err := b.spool.push(body); err != nil {
+		return multierror.Append(retErr, err)
+	}
+	if b.nextRetry.IsZero() {
+		b.nextRetry = time.Now().Add(b.retryBackoff)
+	}
+	b.startRetry()
+	return nil
+}
+
+// retrySpool drains the spool, and schedules the next retry. The delay between
+// retries doubles on every failure, up to maxRetryInterval, and is reset once
+// the spool is drained. The send lock must be held before calling this.
+func (b *Backend) retrySpool(ctx context.Context) error {
+	err := b.drainSpool(ctx)
+	if err != nil {
+		b.retryBackoff *= 2
+		if b.retryBackoff > b.maxRetryInterval {
+			b.retryBackoff = b.maxRetryInterval
+		}
+	} else {
+		b.retryBackoff = b.retryInterval
+	}
+	b.nextRetry = time.Now().Add(b.retryBackoff)
+	return err
+}
+
+// startRetry starts retrying the spool in the background, unless it is
+// already being retried. The send lock must be held before calling this,
+// except from the factory.
+func (b *Backend) startRetry() {
+	if b.retrying {
+		return
+	}
+	b.retrying = true
+	go b.retryLoop()
+}
+
+// retryLoop retries the spool until it is drained or the backend is closed,
+// so that spooled batches are delivered even when no new entries are logged
+func (b *Backend) retryLoop() {
+	// Each request is bounded by the client timeout
+	ctx := context.Background()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		b.sendLock.Lock()
+		if b.spool.empty() {
+			b.retrying = false
+			b.sendLock.Unlock()
+			return
+		}
+		wait := time.Until(b.nextRetry)
+		b.sendLock.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+		select {
+		case <-b.stopCh:
+			return
+		case <-timer.C:
+		}
+
+		// A delivery may have retried the spool in the meantime
+		b.sendLock.Lock()
+		if !b.spool.empty() && !time.Now().Before(b.nextRetry) {
+			b.retrySpool(ctx)
+		}
+		b.sendLock.Unlock()
+	}
+}
+
+// Close stops retrying the spool. Spooled batches are kept, and retried once
+// a backend is set up again for the same spool.
+func (b *Backend) Close() {
+	b.closeOnce.Do(func() {
+		close(b.stopCh)
+	})
+}
+
+// drainSpool sends the spooled batches until the spool is empty or a
+// delivery fails
+func (b *Backend) drainSpool(ctx context.Context) error {
+	for !b.spool.empty() {
+		body, err := b.spool.peek()
+		if err != nil {
+			return err
+		}
+		if body == nil {
+			return nil
+		}
+		if err := b.send(ctx, body); err != nil {
+			return err
+		}
+		if err := b.spool.pop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Backend) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.address, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// encodeBatch returns the JSON array of the given JSON entries
+func encodeBatch(entries [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, entry := range entries {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(bytes.TrimSpace(entry))
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+func (b *Backend) newClient() (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         b.tlsServerName,
+		InsecureSkipVerify: b.tlsSkipVerify,
+	}
+
+	if b.tlsCACert != "" {
+		pem, err := ioutil.ReadFile(b.tlsCACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", b.tlsCACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if b.tlsClientCert != "" || b.tlsClientKey != "" {
+		if b.tlsClientCert == "" || b.tlsClientKey == "" {
+			return nil, fmt.Errorf("both tls_client_cert and tls_client_key must be set")
+		}
+		cert, err := tls.LoadX509KeyPair(b.tlsClientCert, b.tlsClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := cleanhttp.DefaultPooledTransport()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   b.timeout,
+	}, nil
+}
+
+// Reload reloads the TLS certificates
+func (b *Backend) Reload(_ context.Context) error {
+	client, err := b.newClient()
+	if err != nil {
+		return err
+	}
+
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+
+	b.client = client
+
+	return nil
+}
+
+func (b *Backend) Salt(ctx context.Context) (*salt.Salt, error) {
+	b.saltMutex.RLock()
+	if b.salt != nil {
+		defer b.saltMutex.RUnlock()
+		return b.salt, nil
+	}
+	b.saltMutex.RUnlock()
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	if b.salt != nil {
+		return b.salt, nil
+	}
+	salt, err := salt.NewSalt(ctx, b.saltView, b.saltConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.salt = salt
+	return salt, nil
+}
+
+func (b *Backend) Invalidate(_ context.Context) {
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	b.salt = nil
+}
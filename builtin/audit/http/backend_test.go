@@ -0,0 +1,336 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// testCollector is an HTTP audit collector recording the batches it accepts
+type testCollector struct {
+	sync.Mutex
+	batches [][]map[string]interface{}
+	fail    bool
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var batch []map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.batches = append(c.batches, batch)
+}
+
+func (c *testCollector) setFail(fail bool) {
+	c.Lock()
+	defer c.Unlock()
+	c.fail = fail
+}
+
+func (c *testCollector) batchSizes() []int {
+	c.Lock()
+	defer c.Unlock()
+	sizes := make([]int, 0, len(c.batches))
+	for _, batch := range c.batches {
+		sizes = append(sizes, len(batch))
+	}
+	return sizes
+}
+
+func testBackend(t *testing.T, config map[string]string) *Backend {
+	t.Helper()
+	b, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.(*Backend)
+}
+
+func testLogRequest(b *Backend, path string) error {
+	return b.LogRequest(namespace.RootContext(nil), &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      path,
+		},
+	})
+}
+
+func TestAuditHTTP_batching(t *testing.T) {
+	collector := &testCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	b := testBackend(t, map[string]string{
+		"address":        server.URL,
+		"batch_size":     "3",
+		"flush_interval": "50ms",
+	})
+
+	// A full batch is sent at once, the remaining entries after the flush
+	// interval
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := testLogRequest(b, "secret/foo"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sizes := collector.batchSizes()
+	if len(sizes) != 2 || sizes[0]+sizes[1] != 4 {
+		t.Fatalf("bad: %v", sizes)
+	}
+
+	collector.Lock()
+	entry := collector.batches[0][0]
+	collector.Unlock()
+	if entry["type"] != "request" || entry["request"].(map[string]interface{})["path"] != "secret/foo" {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func TestAuditHTTP_failure(t *testing.T) {
+	collector := &testCollector{fail: true}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	b := testBackend(t, map[string]string{
+		"address":    server.URL,
+		"batch_size": "1",
+	})
+
+	// Without a spool, failing to deliver fails the request
+	err := testLogRequest(b, "secret/foo")
+	if err == nil || !strings.Contains(err.Error(), "status 503") {
+		t.Fatalf("expected error, got: %v", err)
+	}
+}
+
+func TestAuditHTTP_spool(t *testing.T) {
+	collector := &testCollector{fail: true}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault-test_audit_http-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := map[string]string{
+		"address":        server.URL,
+		"batch_size":     "1",
+		"retry_interval": "1ms",
+		"spool_path":     dir,
+		"spool_max_size": "1kb",
+	}
+	b := testBackend(t, config)
+
+	// Failed batches are spooled
+	for _, path := range []string{"secret/a", "secret/b"} {
+		if err := testLogRequest(b, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 spooled batches, got %d", len(files))
+	}
+
+	// The spool is bounded
+	for {
+		err := testLogRequest(b, "secret/c")
+		if err != nil {
+			if !strings.Contains(err.Error(), "audit spool is full") {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			break
+		}
+	}
+
+	// The spool survives restarts and is retried in order before new batches
+	b.Close()
+	collector.setFail(false)
+	b = testBackend(t, config)
+	defer b.Close()
+	if err := testLogRequest(b, "secret/d"); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err = ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected an empty spool, got %d batches", len(files))
+	}
+
+	collector.Lock()
+	defer collector.Unlock()
+	var paths []string
+	for _, batch := range collector.batches {
+		paths = append(paths, batch[0]["request"].(map[string]interface{})["path"].(string))
+	}
+	if paths[0] != "secret/a" || paths[1] != "secret/b" || paths[len(paths)-1] != "secret/d" {
+		t.Fatalf("bad: %v", paths)
+	}
+}
+
+func TestAuditHTTP_spoolCorrupt(t *testing.T) {
+	collector := &testCollector{fail: true}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault-test_audit_http-spool_corrupt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := map[string]string{
+		"address":        server.URL,
+		"batch_size":     "1",
+		"retry_interval": "1ms",
+		"spool_path":     dir,
+	}
+	b := testBackend(t, config)
+	if err := testLogRequest(b, "secret/a"); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	// An empty and a truncated batch, as left by a crash, around the valid one
+	for name, contents := range map[string]string{
+		"00000000000000000000.json": "",
+		"00000000000000000002.json": `[{"type":"requ`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The corrupt batches are skipped and set aside, the others are delivered
+	collector.setFail(false)
+	b = testBackend(t, config)
+	defer b.Close()
+	if err := testLogRequest(b, "secret/b"); err != nil {
+		t.Fatal(err)
+	}
+
+	collector.Lock()
+	var paths []string
+	for _, batch := range collector.batches {
+		paths = append(paths, batch[0]["request"].(map[string]interface{})["path"].(string))
+	}
+	collector.Unlock()
+	if strings.Join(paths, ",") != "secret/a,secret/b" {
+		t.Fatalf("bad: %v", paths)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	expected := "00000000000000000000.json.corrupt,00000000000000000002.json.corrupt"
+	if strings.Join(names, ",") != expected {
+		t.Fatalf("expected %s, got %v", expected, names)
+	}
+
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+	if !b.spool.empty() || b.spool.size != 0 {
+		t.Fatalf("expected an empty spool, got %d bytes in %v", b.spool.size, b.spool.files)
+	}
+}
+
+func TestAuditHTTP_spoolRetry(t *testing.T) {
+	collector := &testCollector{fail: true}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault-test_audit_http-spool_retry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := testBackend(t, map[string]string{
+		"address":            server.URL,
+		"batch_size":         "1",
+		"retry_interval":     "10ms",
+		"max_retry_interval": "40ms",
+		"spool_path":         dir,
+	})
+	defer b.Close()
+
+	if err := testLogRequest(b, "secret/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The retries back off while the collector fails
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.sendLock.Lock()
+		backoff := b.retryBackoff
+		b.sendLock.Unlock()
+		if backoff == 40*time.Millisecond {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the retries to back off, got %s", backoff)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The spool is delivered in the background, without new entries
+	collector.setFail(false)
+	for len(collector.batchSizes()) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the spooled batch to be delivered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	b.sendLock.Lock()
+	defer b.sendLock.Unlock()
+	if !b.spool.empty() || b.retryBackoff != 10*time.Millisecond {
+		t.Fatalf("expected the spool to be drained and the backoff reset, got %s", b.retryBackoff)
+	}
+}
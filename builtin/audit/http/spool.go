@@ -0,0 +1,185 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/hashicorp/go-hclog"
+)
+
+const (
+	spoolFileSuffix = ".json"
+
+	// spoolCorruptSuffix is appended to the name of batches that cannot be
+	// replayed, which are kept for inspection but no longer retried
+	spoolCorruptSuffix = ".corrupt"
+)
+
+// spool is a bounded directory of batches that could not be delivered,
+// waiting to be retried. Batches are stored one per file, named after a
+// sequence number so that they are retried in order.
+type spool struct {
+	path    string
+	maxSize int64
+	logger  log.Logger
+
+	size  int64
+	next  uint64
+	files []string
+}
+
+// newSpool opens the spool in the given directory, creating it if needed and
+// picking up the batches left over by a previous run.
+func newSpool(path string, maxSize int64, logger log.Logger) (*spool, error) {
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	s := &spool{
+		path:    path,
+		maxSize: maxSize,
+		logger:  logger,
+		next:    1,
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, spoolFileSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		if seq >= s.next {
+			s.next = seq + 1
+		}
+		s.files = append(s.files, name)
+		s.size += info.Size()
+	}
+	sort.Strings(s.files)
+
+	return s, nil
+}
+
+// empty returns whether no batch is waiting to be retried
+func (s *spool) empty() bool {
+	return len(s.files) == 0
+}
+
+// push stores a batch at the end of the spool. It fails if the spool would
+// exceed its maximum size. The batch is synced to disk before push returns,
+// so that it survives a crash.
+func (s *spool) push(batch []byte) error {
+	if s.size+int64(len(batch)) > s.maxSize {
+		return fmt.Errorf("audit spool is full (%d of %d bytes used)", s.size, s.maxSize)
+	}
+
+	name := fmt.Sprintf("%020d%s", s.next, spoolFileSuffix)
+	path := filepath.Join(s.path, name)
+	tmpPath := path + ".tmp"
+	if err := writeFileSync(tmpPath, batch); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write to audit spool: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write to audit spool: %w", err)
+	}
+	if err := syncDir(s.path); err != nil {
+		return fmt.Errorf("failed to write to audit spool: %w", err)
+	}
+
+	s.next++
+	s.files = append(s.files, name)
+	s.size += int64(len(batch))
+	return nil
+}
+
+// peek returns the oldest batch of the spool, or nil if it is empty. Batches
+// that are empty or not valid JSON, for instance because they were written
+// before a crash, are set aside and skipped.
+func (s *spool) peek() ([]byte, error) {
+	for !s.empty() {
+		path := filepath.Join(s.path, s.files[0])
+		batch, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch) > 0 && json.Valid(batch) {
+			return batch, nil
+		}
+
+		s.logger.Warn("skipping corrupt audit spool batch", "file", path, "size", len(batch))
+		if err := os.Rename(path, path+spoolCorruptSuffix); err != nil {
+			return nil, err
+		}
+		s.files = s.files[1:]
+		s.size -= int64(len(batch))
+	}
+	return nil, nil
+}
+
+// pop removes the oldest batch of the spool, once it has been delivered
+func (s *spool) pop() error {
+	if s.empty() {
+		return nil
+	}
+
+	path := filepath.Join(s.path, s.files[0])
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	s.files = s.files[1:]
+	s.size -= info.Size()
+	return nil
+}
+
+// writeFileSync writes the data to a new file and syncs it to disk
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// syncDir syncs the directory, so that the files renamed into it survive a
+// crash. Directories cannot be synced on Windows.
+func syncDir(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -48,7 +49,7 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		format = "json"
 	}
 	switch format {
-	case "json", "jsonx":
+	case "json", "jsonx", "cef", "ocsf":
 	default:
 		return nil, fmt.Errorf("unknown format type %q", format)
 	}
@@ -73,12 +74,18 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	fieldSelectors, err := audit.ParseFieldSelectors(strutil.ParseStringSlice(conf.Config["field_selectors"], ","))
+	if err != nil {
+		return nil, err
+	}
+
 	b := &Backend{
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:            logRaw,
+			HMACAccessor:   hmacAccessor,
+			FieldSelectors: fieldSelectors,
 		},
 
 		writeDuration: writeDuration,
@@ -86,18 +93,11 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		socketType:    socketType,
 	}
 
-	switch format {
-	case "json":
-		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
-			Prefix:   conf.Config["prefix"],
-			SaltFunc: b.Salt,
-		}
-	case "jsonx":
-		b.formatter.AuditFormatWriter = &audit.JSONxFormatWriter{
-			Prefix:   conf.Config["prefix"],
-			SaltFunc: b.Salt,
-		}
+	writer, err := audit.NewFormatWriter(format, conf.Config["prefix"], b.Salt)
+	if err != nil {
+		return nil, err
 	}
+	b.formatter.AuditFormatWriter = writer
 
 	return b, nil
 }
@@ -10,6 +10,7 @@ import (
 	gsyslog "github.com/hashicorp/go-syslog"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
@@ -38,7 +39,7 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		format = "json"
 	}
 	switch format {
-	case "json", "jsonx":
+	case "json", "jsonx", "cef", "ocsf":
 	default:
 		return nil, fmt.Errorf("unknown format type %q", format)
 	}
@@ -63,6 +64,11 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	fieldSelectors, err := audit.ParseFieldSelectors(strutil.ParseStringSlice(conf.Config["field_selectors"], ","))
+	if err != nil {
+		return nil, err
+	}
+
 	// Get the logger
 	logger, err := gsyslog.NewLogger(gsyslog.LOG_INFO, facility, tag)
 	if err != nil {
@@ -74,23 +80,17 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
-			Raw:          logRaw,
-			HMACAccessor: hmacAccessor,
+			Raw:            logRaw,
+			HMACAccessor:   hmacAccessor,
+			FieldSelectors: fieldSelectors,
 		},
 	}
 
-	switch format {
-	case "json":
-		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
-			Prefix:   conf.Config["prefix"],
-			SaltFunc: b.Salt,
-		}
-	case "jsonx":
-		b.formatter.AuditFormatWriter = &audit.JSONxFormatWriter{
-			Prefix:   conf.Config["prefix"],
-			SaltFunc: b.Salt,
-		}
+	writer, err := audit.NewFormatWriter(format, conf.Config["prefix"], b.Salt)
+	if err != nil {
+		return nil, err
 	}
+	b.formatter.AuditFormatWriter = writer
 
 	return b, nil
 }
@@ -191,6 +191,9 @@ func TestAuditEnableCommand_Run(t *testing.T) {
 			case "socket":
 				args = append(args, "address=127.0.0.1:8888",
 					"skip_test=true")
+			case "http":
+				args = append(args, "address=http://127.0.0.1:8888",
+					"skip_test=true")
 			case "syslog":
 				if _, exists := os.LookupEnv("WSLENV"); exists {
 					t.Log("skipping syslog test on WSL")
@@ -0,0 +1,181 @@
+package command
+
+import (
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/mitchellh/cli"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*AuditVerifyCommand)(nil)
+	_ cli.CommandAutocomplete = (*AuditVerifyCommand)(nil)
+)
+
+type AuditVerifyCommand struct {
+	*BaseCommand
+
+	flagHMACKey   string
+	flagPublicKey string
+}
+
+func (c *AuditVerifyCommand) Synopsis() string {
+	return "Verifies the hash chain of an audit log"
+}
+
+func (c *AuditVerifyCommand) Help() string {
+	helpText := `
+Usage: vault audit verify [options] FILE...
+
+  Verifies offline that an audit log written by a file audit device with
+  "hash_chain" enabled was not edited: that no entry was modified, removed or
+  inserted, and that the checkpoints were signed by Vault. The command exits
+  with status 2 if the log fails verification.
+
+  The rotated files of a log are verified together by listing them oldest
+  first, followed by the current file, so that the anchor at the start of
+  each file is checked against the end of the previous one. Files ending in
+  ".gz" are decompressed. Line numbers count the lines of all the files.
+
+  The keys of an audit device are read with:
+
+      $ vault read sys/audit-chain/file
+
+  Verify the log of that device:
+
+      $ vault audit verify -hmac-key=... -public-key=... /var/log/vault_audit.log
+
+  Verify the log along with its rotated files:
+
+      $ vault audit verify -hmac-key=... -public-key=... \
+          /var/log/vault_audit-20210601T000000.000Z.log.gz \
+          /var/log/vault_audit.log
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *AuditVerifyCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetOutputFormat)
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "hmac-key",
+		Target:     &c.flagHMACKey,
+		Default:    "",
+		Completion: complete.PredictAnything,
+		Usage:      "Base64-encoded HMAC key linking the entries of the log.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "public-key",
+		Target:     &c.flagPublicKey,
+		Default:    "",
+		Completion: complete.PredictAnything,
+		Usage:      "Base64-encoded public key verifying the checkpoints of the log.",
+	})
+
+	return set
+}
+
+func (c *AuditVerifyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *AuditVerifyCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *AuditVerifyCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 1 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 1, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagHMACKey == "" || c.flagPublicKey == "" {
+		c.UI.Error("Both -hmac-key and -public-key must be specified")
+		return 1
+	}
+	hmacKey, err := base64.StdEncoding.DecodeString(c.flagHMACKey)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Invalid HMAC key: %s", err))
+		return 1
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(c.flagPublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		c.UI.Error("Invalid public key: expected a base64-encoded Ed25519 public key")
+		return 1
+	}
+
+	// The files are verified as a single log
+	readers := make([]io.Reader, 0, len(args))
+	for _, arg := range args {
+		path, err := homedir.Expand(strings.TrimSpace(arg))
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to expand path: %s", err))
+			return 1
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error opening audit log: %s", err))
+			return 1
+		}
+		defer file.Close()
+
+		var r io.Reader = file
+		if strings.HasSuffix(path, ".gz") {
+			if r, err = gzip.NewReader(file); err != nil {
+				c.UI.Error(fmt.Sprintf("Error decompressing audit log %q: %s", path, err))
+				return 1
+			}
+		}
+		readers = append(readers, r)
+	}
+
+	result, err := audit.VerifyHashChain(io.MultiReader(readers...), hmacKey, ed25519.PublicKey(publicKey))
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading audit log: %s", err))
+		return 1
+	}
+
+	if Format(c.UI) != "table" {
+		if code := OutputData(c.UI, result); code != 0 {
+			return code
+		}
+	} else {
+		out := []string{
+			"Key | Value",
+			fmt.Sprintf("Valid | %t", result.Valid()),
+			fmt.Sprintf("Entries | %d", result.Entries),
+			fmt.Sprintf("Checkpoints | %d", result.Checkpoints),
+			fmt.Sprintf("Chains | %d", result.Segments),
+			fmt.Sprintf("Last Checkpoint Line | %d", result.LastCheckpointLine),
+		}
+		c.UI.Output(tableOutput(out, nil))
+		for _, e := range result.Errors {
+			c.UI.Error(e)
+		}
+	}
+
+	if !result.Valid() {
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package command
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testAuditVerifyCommand(tb testing.TB) (*cli.MockUi, *AuditVerifyCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &AuditVerifyCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestAuditVerifyCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			nil,
+			"Not enough arguments",
+			1,
+		},
+		{
+			"missing_file",
+			[]string{"-hmac-key=Zm9v", "-public-key=" + base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize)), "audit.log", "missing.log"},
+			"Error opening audit log",
+			1,
+		},
+		{
+			"missing_keys",
+			[]string{"audit.log"},
+			"Both -hmac-key and -public-key must be specified",
+			1,
+		},
+		{
+			"invalid_public_key",
+			[]string{"-hmac-key=Zm9v", "-public-key=Zm9v", "audit.log"},
+			"Invalid public key",
+			1,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ui, cmd := testAuditVerifyCommand(t)
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if !strings.Contains(combined, tc.out) {
+				t.Errorf("expected %q to contain %q", combined, tc.out)
+			}
+		})
+	}
+
+	t.Run("integration", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerAllBackends(t)
+		defer closer()
+
+		dir, err := ioutil.TempDir("", "vault-test_audit_verify")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		logPath := filepath.Join(dir, "audit.log")
+
+		if err := client.Sys().EnableAuditWithOptions("file", &api.EnableAuditOptions{
+			Type: "file",
+			Options: map[string]string{
+				"file_path":           logPath,
+				"hash_chain":          "true",
+				"checkpoint_interval": "3",
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 5; i++ {
+			if _, err := client.Sys().ListMounts(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		secret, err := client.Logical().Read("sys/audit-chain/file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hmacKey := "-hmac-key=" + secret.Data["hmac_key"].(string)
+		publicKey := "-public-key=" + secret.Data["public_key"].(string)
+
+		ui, cmd := testAuditVerifyCommand(t)
+		code := cmd.Run([]string{hmacKey, publicKey, logPath})
+		if exp := 0; code != exp {
+			t.Fatalf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+		}
+		if out := ui.OutputWriter.String(); !strings.Contains(out, "Valid                   true") {
+			t.Fatalf("bad output: %s", out)
+		}
+
+		// Tamper with the log
+		raw, err := ioutil.ReadFile(logPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tampered := strings.Replace(string(raw), "sys/mounts", "sys/mount5", 1)
+		if err := ioutil.WriteFile(logPath, []byte(tampered), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd = testAuditVerifyCommand(t)
+		code = cmd.Run([]string{hmacKey, publicKey, logPath})
+		if exp := 2; code != exp {
+			t.Fatalf("expected %d to be %d", code, exp)
+		}
+		if out := ui.ErrorWriter.String(); !strings.Contains(out, "does not match the HMAC of the previous line") {
+			t.Fatalf("bad output: %s", out)
+		}
+	})
+}
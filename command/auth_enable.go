@@ -27,6 +27,7 @@ type AuthEnableCommand struct {
 	flagMaxLeaseTTL               time.Duration
 	flagAuditNonHMACRequestKeys   []string
 	flagAuditNonHMACResponseKeys  []string
+	flagAuditFieldSelectors       []string
 	flagListingVisibility         string
 	flagPluginName                string
 	flagPassthroughRequestHeaders []string
@@ -128,6 +129,15 @@ func (c *AuthEnableCommand) Flags() *FlagSets {
 			"devices in the response data object.",
 	})
 
+	f.StringSliceVar(&StringSliceVar{
+		Name:   flagNameAuditFieldSelectors,
+		Target: &c.flagAuditFieldSelectors,
+		Usage: "Comma-separated string or list of selectors of fields in the request " +
+			"and response data, written as \"<pointer>=<action>\", where the pointer is " +
+			"a JSON pointer starting with /request/data or /response/data and the " +
+			"action is \"hmac\", \"plaintext\" or \"drop\".",
+	})
+
 	f.StringVar(&StringVar{
 		Name:   flagNameListingVisibility,
 		Target: &c.flagListingVisibility,
@@ -283,6 +293,10 @@ func (c *AuthEnableCommand) Run(args []string) int {
 			authOpts.Config.AuditNonHMACResponseKeys = c.flagAuditNonHMACResponseKeys
 		}
 
+		if fl.Name == flagNameAuditFieldSelectors {
+			authOpts.Config.AuditFieldSelectors = c.flagAuditFieldSelectors
+		}
+
 		if fl.Name == flagNameListingVisibility {
 			authOpts.Config.ListingVisibility = c.flagListingVisibility
 		}
@@ -22,6 +22,7 @@ type AuthTuneCommand struct {
 
 	flagAuditNonHMACRequestKeys  []string
 	flagAuditNonHMACResponseKeys []string
+	flagAuditFieldSelectors      []string
 	flagDefaultLeaseTTL          time.Duration
 	flagDescription              string
 	flagListingVisibility        string
@@ -71,6 +72,15 @@ func (c *AuthTuneCommand) Flags() *FlagSets {
 			"devices in the response data object.",
 	})
 
+	f.StringSliceVar(&StringSliceVar{
+		Name:   flagNameAuditFieldSelectors,
+		Target: &c.flagAuditFieldSelectors,
+		Usage: "Comma-separated string or list of selectors of fields in the request " +
+			"and response data, written as \"<pointer>=<action>\", where the pointer is " +
+			"a JSON pointer starting with /request/data or /response/data and the " +
+			"action is \"hmac\", \"plaintext\" or \"drop\".",
+	})
+
 	f.DurationVar(&DurationVar{
 		Name:       "default-lease-ttl",
 		Target:     &c.flagDefaultLeaseTTL,
@@ -186,6 +196,10 @@ func (c *AuthTuneCommand) Run(args []string) int {
 			mountConfigInput.AuditNonHMACResponseKeys = c.flagAuditNonHMACResponseKeys
 		}
 
+		if fl.Name == flagNameAuditFieldSelectors {
+			mountConfigInput.AuditFieldSelectors = c.flagAuditFieldSelectors
+		}
+
 		if fl.Name == flagNameDescription {
 			mountConfigInput.Description = &c.flagDescription
 		}
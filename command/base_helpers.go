@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 	"strings"
 	"time"
 
@@ -190,11 +191,28 @@ func truncateToSeconds(d time.Duration) int {
 
 // printKeyStatus prints the KeyStatus response from the API.
 func printKeyStatus(ks *api.KeyStatus) string {
-	return columnOutput([]string{
+	out := []string{
 		fmt.Sprintf("Key Term | %d", ks.Term),
 		fmt.Sprintf("Install Time | %s", ks.InstallTime.UTC().Format(time.RFC822)),
 		fmt.Sprintf("Encryption Count | %d", ks.Encryptions),
-	}, nil)
+	}
+	if len(ks.KeyringTerms) > 0 {
+		terms := make([]string, 0, len(ks.KeyringTerms))
+		for _, term := range ks.KeyringTerms {
+			terms = append(terms, strconv.Itoa(term))
+		}
+		out = append(out, fmt.Sprintf("Keyring Terms | %s", strings.Join(terms, ", ")))
+	}
+	if rw := ks.Rewrap; rw != nil {
+		out = append(out,
+			fmt.Sprintf("Rewrap Term | %d", rw.Term),
+			fmt.Sprintf("Rewrap Status | %s", rw.Status),
+			fmt.Sprintf("Rewrap Entries Checked | %d", rw.EntriesChecked),
+			fmt.Sprintf("Rewrap Entries Rewrapped | %d", rw.EntriesRewrapped),
+			fmt.Sprintf("Rewrap Entries Failed | %d", rw.EntriesFailed),
+		)
+	}
+	return columnOutput(out, nil)
 }
 
 // expandPath takes a filepath and returns the full expanded path, accounting
@@ -20,6 +20,7 @@ import (
 	_ "github.com/hashicorp/vault/helper/builtinplugins"
 
 	auditFile "github.com/hashicorp/vault/builtin/audit/file"
+	auditHTTP "github.com/hashicorp/vault/builtin/audit/http"
 	auditSocket "github.com/hashicorp/vault/builtin/audit/socket"
 	auditSyslog "github.com/hashicorp/vault/builtin/audit/syslog"
 
@@ -60,6 +61,7 @@ import (
 	physRaft "github.com/hashicorp/vault/physical/raft"
 	physS3 "github.com/hashicorp/vault/physical/s3"
 	physSpanner "github.com/hashicorp/vault/physical/spanner"
+	physSQLite "github.com/hashicorp/vault/physical/sqlite"
 	physSwift "github.com/hashicorp/vault/physical/swift"
 	physZooKeeper "github.com/hashicorp/vault/physical/zookeeper"
 	physFile "github.com/hashicorp/vault/sdk/physical/file"
@@ -101,6 +103,8 @@ const (
 	flagNameAuditNonHMACRequestKeys = "audit-non-hmac-request-keys"
 	// flagNameAuditNonHMACResponseKeys is the flag name used for auth/secrets enable
 	flagNameAuditNonHMACResponseKeys = "audit-non-hmac-response-keys"
+	// flagNameAuditFieldSelectors is the flag name used for auth/secrets enable and tune
+	flagNameAuditFieldSelectors = "audit-field-selectors"
 	// flagNameDescription is the flag name used for tuning the secret and auth mount description parameter
 	flagNameDescription = "description"
 	// flagListingVisibility is the flag to toggle whether to show the mount in the UI-specific listing endpoint
@@ -116,6 +120,7 @@ const (
 var (
 	auditBackends = map[string]audit.Factory{
 		"file":   auditFile.Factory,
+		"http":   auditHTTP.Factory,
 		"socket": auditSocket.Factory,
 		"syslog": auditSyslog.Factory,
 	}
@@ -158,6 +163,7 @@ var (
 		"postgresql":             physPostgreSQL.NewPostgreSQLBackend,
 		"s3":                     physS3.NewS3Backend,
 		"spanner":                physSpanner.NewBackend,
+		"sqlite":                 physSQLite.NewSQLiteBackend,
 		"swift":                  physSwift.NewSwiftBackend,
 		"raft":                   physRaft.NewRaftBackend,
 		"zookeeper":              physZooKeeper.NewZooKeeperBackend,
@@ -234,6 +240,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"audit verify": func() (cli.Command, error) {
+			return &AuditVerifyCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"auth tune": func() (cli.Command, error) {
 			return &AuthTuneCommand{
 				BaseCommand: getBaseCommand(),
@@ -414,6 +425,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator raft snapshot inspect": func() (cli.Command, error) {
+			return &OperatorRaftSnapshotInspectCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"operator raft snapshot restore": func() (cli.Command, error) {
 			return &OperatorRaftSnapshotRestoreCommand{
 				BaseCommand: getBaseCommand(),
@@ -439,6 +455,17 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"operator storage": func() (cli.Command, error) {
+			return &OperatorStorageCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
+		"operator storage verify": func() (cli.Command, error) {
+			return &OperatorStorageVerifyCommand{
+				BaseCommand: getBaseCommand(),
+				ShutdownCh:  MakeShutdownCh(),
+			}, nil
+		},
 		"operator step-down": func() (cli.Command, error) {
 			return &OperatorStepDownCommand{
 				BaseCommand: getBaseCommand(),
@@ -504,6 +531,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"policy explain": func() (cli.Command, error) {
+			return &PolicyExplainCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"policy fmt": func() (cli.Command, error) {
 			return &PolicyFmtCommand{
 				BaseCommand: getBaseCommand(),
@@ -519,6 +551,11 @@ func initCommands(ui, serverCmdUi cli.Ui, runOpts *RunOptions) {
 				BaseCommand: getBaseCommand(),
 			}, nil
 		},
+		"policy test": func() (cli.Command, error) {
+			return &PolicyTestCommand{
+				BaseCommand: getBaseCommand(),
+			}, nil
+		},
 		"policy write": func() (cli.Command, error) {
 			return &PolicyWriteCommand{
 				BaseCommand: getBaseCommand(),
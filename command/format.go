@@ -152,10 +152,22 @@ func formatServer(srv *api.AutopilotServer) string {
 	buffer.WriteString(fmt.Sprintf("      Address:         %s\n", srv.Address))
 	buffer.WriteString(fmt.Sprintf("      Status:          %s\n", srv.Status))
 	buffer.WriteString(fmt.Sprintf("      Node Status:     %s\n", srv.NodeStatus))
+	if srv.NodeType != "" {
+		buffer.WriteString(fmt.Sprintf("      Node Type:       %s\n", srv.NodeType))
+	}
 	buffer.WriteString(fmt.Sprintf("      Healthy:         %t\n", srv.Healthy))
 	buffer.WriteString(fmt.Sprintf("      Last Contact:    %s\n", srv.LastContact))
 	buffer.WriteString(fmt.Sprintf("      Last Term:       %d\n", srv.LastTerm))
 	buffer.WriteString(fmt.Sprintf("      Last Index:      %d\n", srv.LastIndex))
+	if srv.Version != "" {
+		buffer.WriteString(fmt.Sprintf("      Version:         %s\n", srv.Version))
+	}
+	if srv.UpgradeVersion != "" {
+		buffer.WriteString(fmt.Sprintf("      Upgrade Version: %s\n", srv.UpgradeVersion))
+	}
+	if srv.RedundancyZone != "" {
+		buffer.WriteString(fmt.Sprintf("      Redundancy Zone: %s\n", srv.RedundancyZone))
+	}
 
 	if len(srv.Meta) > 0 {
 		buffer.WriteString(fmt.Sprintf("      Meta\n"))
@@ -191,6 +203,37 @@ func (p PrettyFormatter) OutputAutopilotState(ui cli.Ui, data interface{}) {
 		outputStringSlice(&buffer, "   ", state.NonVoters)
 	}
 
+	if len(state.ReadReplicas) > 0 {
+		buffer.WriteString("Read Replicas:\n")
+		outputStringSlice(&buffer, "   ", state.ReadReplicas)
+	}
+
+	if len(state.RedundancyZones) > 0 {
+		buffer.WriteString("Redundancy Zones:\n")
+		zoneList := make([]string, 0, len(state.RedundancyZones))
+		for z := range state.RedundancyZones {
+			zoneList = append(zoneList, z)
+		}
+		sort.Strings(zoneList)
+		for _, zoneName := range zoneList {
+			zone := state.RedundancyZones[zoneName]
+			buffer.WriteString(fmt.Sprintf("   %s\n", zoneName))
+			buffer.WriteString(fmt.Sprintf("      Servers:           %s\n", strings.Join(zone.Servers, ", ")))
+			buffer.WriteString(fmt.Sprintf("      Voters:            %s\n", strings.Join(zone.Voters, ", ")))
+			buffer.WriteString(fmt.Sprintf("      Failure Tolerance: %d\n", zone.FailureTolerance))
+		}
+	}
+
+	if state.Upgrade != nil {
+		buffer.WriteString("Upgrade Info:\n")
+		buffer.WriteString(fmt.Sprintf("   Status:                    %s\n", state.Upgrade.Status))
+		buffer.WriteString(fmt.Sprintf("   Target Version:            %s\n", state.Upgrade.TargetVersion))
+		buffer.WriteString(fmt.Sprintf("   Target Version Voters:     %s\n", strings.Join(state.Upgrade.TargetVersionVoters, ", ")))
+		buffer.WriteString(fmt.Sprintf("   Target Version Non-Voters: %s\n", strings.Join(state.Upgrade.TargetVersionNonVoters, ", ")))
+		buffer.WriteString(fmt.Sprintf("   Other Version Voters:      %s\n", strings.Join(state.Upgrade.OtherVersionVoters, ", ")))
+		buffer.WriteString(fmt.Sprintf("   Other Version Non-Voters:  %s\n", strings.Join(state.Upgrade.OtherVersionNonVoters, ", ")))
+	}
+
 	buffer.WriteString("Servers:\n")
 	var outputs []mapOutput
 	for id, srv := range state.Servers {
@@ -258,6 +301,13 @@ func (t TableFormatter) OutputSealStatusStruct(ui cli.Ui, secret *api.Secret, da
 		out = append(out, fmt.Sprintf("Seal Migration in Progress | %t", status.Migration))
 	}
 
+	if status.SignedUnseal {
+		out = append(out, fmt.Sprintf("Signed Unseal | %t", status.SignedUnseal))
+		if len(status.UnsealHolders) > 0 {
+			out = append(out, fmt.Sprintf("Unseal Key Holders | %s", strings.Join(status.UnsealHolders, ", ")))
+		}
+	}
+
 	out = append(out, fmt.Sprintf("Version | %s", status.Version))
 	out = append(out, fmt.Sprintf("Storage Type | %s", status.StorageType))
 
@@ -27,6 +27,7 @@ type OperatorInitCommand struct {
 	flagKeyThreshold    int
 	flagPGPKeys         []string
 	flagRootTokenPGPKey string
+	flagSignedUnseal    bool
 
 	// Auto Unseal
 	flagRecoveryShares    int
@@ -76,6 +77,15 @@ Usage: vault operator init [options]
           -key-threshold=2 \
           -pgp-keys="keybase:hashicorp,keybase:jefferai,keybase:sethvargo"
 
+  Require the unseal keys to be submitted signed by the holders of the pgp
+  keys, so that they are never entered in plaintext:
+
+      $ vault operator init \
+          -key-shares=3 \
+          -key-threshold=2 \
+          -pgp-keys="alice.asc,bob.asc,carol.asc" \
+          -signed-unseal
+
   Encrypt the initial root token using a pgp key:
 
       $ vault operator init -root-token-pgp-key="keybase:hashicorp"
@@ -131,6 +141,16 @@ func (c *OperatorInitCommand) Flags() *FlagSets {
 			"unless -stored-shares are used.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    "signed-unseal",
+		Target:  &c.flagSignedUnseal,
+		Default: false,
+		Usage: "Require the unseal keys to be signed by the holders of the keys " +
+			"given by -pgp-keys when unsealing. The holders unseal with " +
+			"\"vault operator unseal -holder-key\", and the unseal keys are never " +
+			"entered in plaintext.",
+	})
+
 	f.VarFlag(&VarFlag{
 		Name:       "root-token-pgp-key",
 		Value:      (*pgpkeys.PubKeyFileFlag)(&c.flagRootTokenPGPKey),
@@ -240,6 +260,7 @@ func (c *OperatorInitCommand) Run(args []string) int {
 		SecretThreshold: c.flagKeyThreshold,
 		PGPKeys:         c.flagPGPKeys,
 		RootTokenPGPKey: c.flagRootTokenPGPKey,
+		SignedUnseal:    c.flagSignedUnseal,
 
 		RecoveryShares:    c.flagRecoveryShares,
 		RecoveryThreshold: c.flagRecoveryThreshold,
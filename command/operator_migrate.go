@@ -14,6 +14,7 @@ import (
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/hcl"
 	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/command/server"
 	"github.com/hashicorp/vault/physical/raft"
 	"github.com/hashicorp/vault/sdk/helper/logging"
@@ -31,6 +32,10 @@ var (
 
 var errAbort = errors.New("Migration aborted")
 
+// onlineMigrationPollInterval is how often the progress of an online
+// migration is checked
+const onlineMigrationPollInterval = 2 * time.Second
+
 type OperatorMigrateCommand struct {
 	*BaseCommand
 
@@ -38,6 +43,7 @@ type OperatorMigrateCommand struct {
 	flagConfig       string
 	flagStart        string
 	flagReset        bool
+	flagOnline       bool
 	logger           log.Logger
 	ShutdownCh       chan struct{}
 }
@@ -64,6 +70,13 @@ Usage: vault operator migrate [options]
 
       $ vault operator migrate -config=migrate.hcl
 
+  With -online, the storage of the running Vault server is migrated instead,
+  without downtime. The active node writes to both storage backends while it
+  copies the existing data, then switches to the destination. Only the
+  storage_destination stanza is needed:
+
+      $ vault operator migrate -online -config=migrate.hcl
+
   For more information, please see the documentation.
 
 ` + c.Flags().Help()
@@ -72,7 +85,7 @@ Usage: vault operator migrate [options]
 }
 
 func (c *OperatorMigrateCommand) Flags() *FlagSets {
-	set := NewFlagSets(c.UI)
+	set := c.flagSet(FlagSetHTTP)
 	f := set.NewFlagSet("Command Options")
 
 	f.StringVar(&StringVar{
@@ -97,6 +110,13 @@ func (c *OperatorMigrateCommand) Flags() *FlagSets {
 		Usage:  "Reset the migration lock. No migration will occur.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:   "online",
+		Target: &c.flagOnline,
+		Usage: "Migrate the storage of the running Vault server to the " +
+			"destination, and wait for the migration to complete.",
+	})
+
 	return set
 }
 
@@ -122,12 +142,30 @@ func (c *OperatorMigrateCommand) Run(args []string) int {
 		return 1
 	}
 
+	if c.flagOnline && c.flagReset {
+		c.UI.Error("The -online and -reset flags are mutually exclusive")
+		return 1
+	}
+
 	config, err := c.loadMigratorConfig(c.flagConfig)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error loading configuration from %s: %s", c.flagConfig, err))
 		return 1
 	}
 
+	if c.flagOnline {
+		if err := c.migrateOnline(config); err != nil {
+			if err == errAbort {
+				return 0
+			}
+			c.UI.Error(fmt.Sprintf("Error migrating: %s", err))
+			return 2
+		}
+		c.UI.Output("Success! The storage has been migrated. Update the storage " +
+			"configuration of all the servers to use the destination.")
+		return 0
+	}
+
 	if err := c.migrate(config); err != nil {
 		if err == errAbort {
 			return 0
@@ -206,6 +244,52 @@ func (c *OperatorMigrateCommand) migrate(config *migratorConfig) error {
 	}
 }
 
+// migrateOnline starts the migration of the storage of the running server and
+// waits for it to complete.
+func (c *OperatorMigrateCommand) migrateOnline(config *migratorConfig) error {
+	if config.StorageDestination.Type == "raft" {
+		return errors.New("integrated storage cannot be the destination of an online migration")
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Sys().StartStorageMigration(&api.StorageMigrationInput{
+		Type:   config.StorageDestination.Type,
+		Config: config.StorageDestination.Config,
+	}); err != nil {
+		return errwrap.Wrapf("error starting online migration: {{err}}", err)
+	}
+	c.UI.Output("==> Online migration started")
+
+	for {
+		status, err := client.Sys().StorageMigrationStatus()
+		if err != nil {
+			return errwrap.Wrapf("error reading migration status: {{err}}", err)
+		}
+
+		switch status.Status {
+		case "complete":
+			return nil
+		case "failed":
+			return fmt.Errorf("online migration failed: %s", status.Error)
+		case "canceled":
+			return errors.New("online migration canceled")
+		}
+		c.logger.Info("online migration in progress", "keys_copied", status.KeysCopied)
+
+		select {
+		case <-time.After(onlineMigrationPollInterval):
+		case <-c.ShutdownCh:
+			c.UI.Output("==> Online migration continues on the server, its progress " +
+				"is available at sys/storage/migration\n")
+			return errAbort
+		}
+	}
+}
+
 // migrateAll copies all keys in lexicographic order.
 func (c *OperatorMigrateCommand) migrateAll(ctx context.Context, from physical.Backend, to physical.Backend) error {
 	return dfsScan(ctx, from, func(ctx context.Context, path string) error {
@@ -313,6 +397,10 @@ func (c *OperatorMigrateCommand) loadMigratorConfig(path string) (*migratorConfi
 	// Look for storage_* stanzas
 	for _, stanza := range []string{"storage_source", "storage_destination"} {
 		o := list.Filter(stanza)
+		if c.flagOnline && stanza == "storage_source" && len(o.Items) == 0 {
+			// The running server migrates its own storage
+			continue
+		}
 		if len(o.Items) != 1 {
 			return nil, fmt.Errorf("exactly one '%s' block is required", stanza)
 		}
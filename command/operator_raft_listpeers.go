@@ -105,7 +105,7 @@ func (c *OperatorRaftListPeersCommand) Run(args []string) int {
 	config := secret.Data["config"].(map[string]interface{})
 
 	servers := config["servers"].([]interface{})
-	out := []string{"Node | Address | State | Voter"}
+	out := []string{"Node | Address | State | Voter | Read Replica"}
 	for _, serverRaw := range servers {
 		server := serverRaw.(map[string]interface{})
 		state := "follower"
@@ -113,7 +113,9 @@ func (c *OperatorRaftListPeersCommand) Run(args []string) int {
 			state = "leader"
 		}
 
-		out = append(out, fmt.Sprintf("%s | %s | %s | %t", server["node_id"].(string), server["address"].(string), state, server["voter"].(bool)))
+		readReplica, _ := server["read_replica"].(bool)
+
+		out = append(out, fmt.Sprintf("%s | %s | %s | %t | %t", server["node_id"].(string), server["address"].(string), state, server["voter"].(bool), readReplica))
 	}
 
 	c.UI.Output(tableOutput(out, nil))
@@ -0,0 +1,218 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	hcraft "github.com/hashicorp/raft"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/plugin/pb"
+	"github.com/mitchellh/cli"
+)
+
+func testOperatorRaftSnapshotInspectCommand(tb testing.TB) (*cli.MockUi, *OperatorRaftSnapshotInspectCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &OperatorRaftSnapshotInspectCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+// testSnapshotFile writes a snapshot archive of the entries, in the format
+// saved by the snapshot API, and returns its path
+func testSnapshotFile(tb testing.TB, dir string, entries map[string]string, keys ...string) string {
+	tb.Helper()
+
+	var state bytes.Buffer
+	writer := raft.NewDelimitedWriter(&state)
+	for _, key := range keys {
+		if err := writer.WriteMsg(&pb.StorageEntry{Key: key, Value: []byte(entries[key])}); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	meta, err := json.Marshal(&hcraft.SnapshotMeta{
+		Version: 1,
+		ID:      "bolt-snapshot",
+		Index:   42,
+		Term:    3,
+		Size:    int64(state.Len()),
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var sums bytes.Buffer
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"meta.json", meta},
+		{"state.bin", state.Bytes()},
+	}
+	for _, file := range files {
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256(file.data), file.name)
+	}
+	files = append(files, struct {
+		name string
+		data []byte
+	}{"SHA256SUMS", sums.Bytes()})
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0o600, Size: int64(len(file.data))}); err != nil {
+			tb.Fatal(err)
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		tb.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "raft.snap")
+	if err := ioutil.WriteFile(path, archive.Bytes(), 0o600); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestOperatorRaftSnapshotInspectCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "vault-snapshot-inspect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	entries := map[string]string{
+		"core/mounts":              "mounts",
+		"logical/uuid1/a":          "12345",
+		"logical/uuid1/b/c":        "123",
+		"logical/uuid2/a":          "1",
+		"sys/token/id/h1234567890": "1234567890",
+	}
+	path := testSnapshotFile(t, dir, entries, "core/mounts", "logical/uuid1/a", "logical/uuid1/b/c", "logical/uuid2/a", "sys/token/id/h1234567890")
+
+	corrupt := filepath.Join(dir, "corrupt.snap")
+	if err := ioutil.WriteFile(corrupt, []byte("not a snapshot"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name string
+		args []string
+		out  []string
+		code int
+	}{
+		{
+			"not_enough_args",
+			nil,
+			[]string{"Incorrect arguments"},
+			1,
+		},
+		{
+			"bad_depth",
+			[]string{"-depth=0", path},
+			[]string{"Depth must be at least 1"},
+			1,
+		},
+		{
+			"missing_file",
+			[]string{filepath.Join(dir, "missing.snap")},
+			[]string{"Error opening snapshot file"},
+			2,
+		},
+		{
+			"corrupt_file",
+			[]string{corrupt},
+			[]string{"Error inspecting the snapshot"},
+			2,
+		},
+		{
+			"default",
+			[]string{path},
+			[]string{
+				"Index         42",
+				"Total Keys    5",
+				"Total Size    25",
+				"core/mounts       1       6",
+				"logical/uuid1/    2       8",
+				"logical/uuid2/    1       1",
+				"sys/token/        1       10",
+			},
+			0,
+		},
+		{
+			"prefix_depth",
+			[]string{"-prefix=logical/uuid1/", "-depth=3", path},
+			[]string{
+				"Total Keys    2",
+				"logical/uuid1/a     1       5",
+				"logical/uuid1/b/    1       3",
+			},
+			0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ui, cmd := testOperatorRaftSnapshotInspectCommand(t)
+
+			code := cmd.Run(tc.args)
+			if code != tc.code {
+				t.Errorf("expected %d to be %d", code, tc.code)
+			}
+
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			for _, out := range tc.out {
+				if !strings.Contains(combined, out) {
+					t.Errorf("expected %q to contain %q", combined, out)
+				}
+			}
+		})
+	}
+
+	t.Run("inspect", func(t *testing.T) {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		inspection, err := inspectSnapshot(f, "", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inspection.Term != 3 || inspection.TotalKeys != 5 || len(inspection.Prefixes) != 3 {
+			t.Fatalf("bad: %#v", inspection)
+		}
+		if p := inspection.Prefixes[1]; p.Prefix != "logical/" || p.Keys != 3 || p.Size != 9 {
+			t.Fatalf("bad: %#v", p)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		_, cmd := testOperatorRaftSnapshotInspectCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -2,9 +2,11 @@ package command
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 )
@@ -15,7 +17,9 @@ var (
 )
 
 type OperatorRaftSnapshotRestoreCommand struct {
-	flagForce bool
+	flagForce            bool
+	flagSubtreeMount     string
+	flagSubtreeNamespace string
 	*BaseCommand
 }
 
@@ -31,6 +35,15 @@ Usage: vault operator raft snapshot restore <snapshot_file>
 
 	  $ vault operator raft snapshot restore raft.snap
 
+  Restore only the keys of the mount at "secret/", leaving the rest of the
+  cluster unchanged:
+
+	  $ vault operator raft snapshot restore -subtree-mount=secret/ raft.snap
+
+  Restore only the keys of the mounts of the root namespace:
+
+	  $ vault operator raft snapshot restore -subtree-namespace=root raft.snap
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
@@ -48,6 +61,23 @@ func (c *OperatorRaftSnapshotRestoreCommand) Flags() *FlagSets {
 		Usage:   "This bypasses checks ensuring the Autounseal or shamir keys are consistent with the snapshot data.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "subtree-mount",
+		Target:     &c.flagSubtreeMount,
+		Completion: complete.PredictAnything,
+		Usage: "Restore only the keys of the mount at this path, such as " +
+			"\"secret/\" or \"auth/userpass/\". The mount must exist both in the " +
+			"snapshot and currently.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "subtree-namespace",
+		Target:     &c.flagSubtreeNamespace,
+		Completion: complete.PredictAnything,
+		Usage: "Restore only the keys of the mounts of the namespace at this " +
+			"path. Use \"root\" for the root namespace.",
+	})
+
 	return set
 }
 
@@ -83,6 +113,16 @@ func (c *OperatorRaftSnapshotRestoreCommand) Run(args []string) int {
 		return 1
 	}
 
+	subtree := c.flagSubtreeMount != "" || c.flagSubtreeNamespace != ""
+	switch {
+	case c.flagSubtreeMount != "" && c.flagSubtreeNamespace != "":
+		c.UI.Error("Only one of -subtree-mount and -subtree-namespace can be set")
+		return 1
+	case subtree && c.flagForce:
+		c.UI.Error("-force cannot be used with -subtree-mount or -subtree-namespace")
+		return 1
+	}
+
 	snapReader, err := os.Open(snapFile)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error opening policy file: %s", err))
@@ -96,6 +136,10 @@ func (c *OperatorRaftSnapshotRestoreCommand) Run(args []string) int {
 		return 2
 	}
 
+	if subtree {
+		return c.restoreSubtree(client, snapReader)
+	}
+
 	err = client.Sys().RaftSnapshotRestore(snapReader, c.flagForce)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error installing the snapshot: %s", err))
@@ -104,3 +148,38 @@ func (c *OperatorRaftSnapshotRestoreCommand) Run(args []string) int {
 
 	return 0
 }
+
+func (c *OperatorRaftSnapshotRestoreCommand) restoreSubtree(client *api.Client, snapReader io.Reader) int {
+	secret, err := client.Sys().RaftSnapshotRestoreSubtree(snapReader, c.flagSubtreeMount, c.flagSubtreeNamespace)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error restoring the snapshot: %s", err))
+		return 2
+	}
+
+	if Format(c.UI) != "table" {
+		return OutputSecret(c.UI, secret)
+	}
+
+	if secret != nil && len(secret.Warnings) > 0 {
+		tf := TableFormatter{}
+		tf.printWarnings(c.UI, secret)
+	}
+
+	var mounts []interface{}
+	if secret != nil && secret.Data != nil {
+		mounts, _ = secret.Data["mounts"].([]interface{})
+	}
+	if len(mounts) == 0 {
+		c.UI.Warn("No mount was restored")
+		return 0
+	}
+
+	out := []string{"Path | Type | Keys Restored | Keys Deleted"}
+	for _, mountRaw := range mounts {
+		mount := mountRaw.(map[string]interface{})
+		out = append(out, fmt.Sprintf("%s | %s | %s | %s", mount["path"], mount["type"], mount["keys_restored"], mount["keys_deleted"]))
+	}
+
+	c.UI.Output(tableOutput(out, nil))
+	return 0
+}
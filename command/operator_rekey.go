@@ -29,6 +29,7 @@ type OperatorRekeyCommand struct {
 	flagKeyThreshold int
 	flagNonce        string
 	flagPGPKeys      []string
+	flagSignedUnseal bool
 	flagStatus       bool
 	flagTarget       string
 	flagVerify       bool
@@ -183,6 +184,15 @@ func (c *OperatorRekeyCommand) Flags() *FlagSets {
 			"specified in this list.",
 	})
 
+	f.BoolVar(&BoolVar{
+		Name:    "signed-unseal",
+		Target:  &c.flagSignedUnseal,
+		Default: false,
+		Usage: "Require the new unseal keys to be signed by the holders of the " +
+			"keys given by -pgp-keys when unsealing. This must be given on every " +
+			"rekey to keep signed unseal enabled.",
+	})
+
 	f = set.NewFlagSet("Backup Options")
 
 	f.BoolVar(&BoolVar{
@@ -285,6 +295,7 @@ func (c *OperatorRekeyCommand) init(client *api.Client) int {
 		PGPKeys:             c.flagPGPKeys,
 		Backup:              c.flagBackup,
 		RequireVerification: c.flagVerify,
+		SignedUnseal:        c.flagSignedUnseal,
 	})
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error initializing rekey: %s", err))
@@ -0,0 +1,39 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+var _ cli.Command = (*OperatorStorageCommand)(nil)
+
+type OperatorStorageCommand struct {
+	*BaseCommand
+}
+
+func (c *OperatorStorageCommand) Synopsis() string {
+	return "Interact with Vault's storage backend"
+}
+
+func (c *OperatorStorageCommand) Help() string {
+	helpText := `
+Usage: vault operator storage <subcommand> [options] [args]
+
+  This command groups subcommands for operators interacting with the storage
+  backend of Vault, whatever its type. Most users will not need to interact
+  with these commands. Here are a few examples of the storage operator commands:
+
+  Verifies that every storage entry can be decrypted:
+
+      $ vault operator storage verify
+
+  Please see the individual subcommand help for detailed usage information.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorStorageCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
@@ -0,0 +1,355 @@
+package command
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	aeadwrapper "github.com/hashicorp/go-kms-wrapping/wrappers/aead"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/command/server"
+	"github.com/hashicorp/vault/internalshared/configutil"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/helper/password"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+// storageVerifyPollInterval is how often the progress of the verification
+// done by the active node is polled
+const storageVerifyPollInterval = 2 * time.Second
+
+// errStorageVerifyInterrupted is returned when the command is interrupted
+// while waiting for the active node
+var errStorageVerifyInterrupted = errors.New("storage verification interrupted")
+
+var (
+	_ cli.Command             = (*OperatorStorageVerifyCommand)(nil)
+	_ cli.CommandAutocomplete = (*OperatorStorageVerifyCommand)(nil)
+)
+
+type OperatorStorageVerifyCommand struct {
+	*BaseCommand
+
+	flagConfig string
+	ShutdownCh chan struct{}
+}
+
+func (c *OperatorStorageVerifyCommand) Synopsis() string {
+	return "Verifies that every storage entry can be decrypted"
+}
+
+func (c *OperatorStorageVerifyCommand) Help() string {
+	helpText := `
+Usage: vault operator storage verify [options]
+
+  Walks every entry of the storage backend and attempts to decrypt it with the
+  keyring. Entries too short to hold an encrypted value are reported as
+  truncated, and entries that fail to decrypt as undecryptable. Entries of
+  secrets engines, auth methods and audit devices that are not in the mount
+  tables anymore are reported as orphaned. The command exits with code 2 if
+  any entry is reported.
+
+  By default, the verification is done in the background by the active node,
+  which must be unsealed, and the command waits for it to complete. If the
+  command is interrupted, the verification continues on the server. This
+  requires sudo capability on sys/storage/verify:
+
+      $ vault operator storage verify
+
+  With integrated storage, the data directory of a server that is not running
+  can be verified offline, using its configuration file. The unseal keys are
+  prompted for when the server uses a Shamir seal:
+
+      $ vault operator storage verify -config=/etc/vault/config.hcl
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *OperatorStorageVerifyCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "config",
+		Target:     &c.flagConfig,
+		Completion: complete.PredictOr(complete.PredictFiles("*.hcl"), complete.PredictFiles("*.json")),
+		Usage: "Path to the configuration file of a server using integrated " +
+			"storage. When set, its data directory is verified offline, so the " +
+			"server must not be running.",
+	})
+
+	return set
+}
+
+func (c *OperatorStorageVerifyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (c *OperatorStorageVerifyCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *OperatorStorageVerifyCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+
+	var result *api.StorageVerifyResult
+	if c.flagConfig != "" {
+		var err error
+		result, err = c.verifyOffline(context.Background())
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error verifying storage: %s", err))
+			return 2
+		}
+	} else {
+		client, err := c.Client()
+		if err != nil {
+			c.UI.Error(err.Error())
+			return 2
+		}
+
+		result, err = c.verifyOnline(client)
+		if err == errStorageVerifyInterrupted {
+			return 1
+		}
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error verifying storage: %s", err))
+			return 2
+		}
+	}
+
+	code := 0
+	if len(result.Problems) > 0 {
+		code = 2
+	}
+
+	if Format(c.UI) != "table" {
+		if ret := OutputData(c.UI, result); ret != 0 {
+			return ret
+		}
+		return code
+	}
+
+	c.UI.Output(tableOutput([]string{
+		fmt.Sprintf("Entries Checked | %d", result.EntriesChecked),
+		fmt.Sprintf("Entries Skipped | %d", result.EntriesSkipped),
+		fmt.Sprintf("Problems | %d", len(result.Problems)),
+	}, nil))
+
+	if len(result.Problems) == 0 {
+		return code
+	}
+
+	out := []string{"Path | Problem | Error"}
+	for _, problem := range result.Problems {
+		out = append(out, fmt.Sprintf("%s | %s | %s", problem.Path, problem.Problem, problem.Error))
+	}
+	c.UI.Output("")
+	c.UI.Output(tableOutput(out, nil))
+	return code
+}
+
+// verifyOnline starts the verification on the active node and waits for it to
+// complete.
+func (c *OperatorStorageVerifyCommand) verifyOnline(client *api.Client) (*api.StorageVerifyResult, error) {
+	result, err := client.Sys().StartStorageVerify()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch result.Status {
+		case "complete":
+			return result, nil
+		case "failed":
+			return nil, errors.New(result.Error)
+		}
+
+		select {
+		case <-time.After(storageVerifyPollInterval):
+		case <-c.ShutdownCh:
+			c.UI.Output("==> Storage verification continues on the server, its " +
+				"progress is available at sys/storage/verify")
+			return nil, errStorageVerifyInterrupted
+		}
+
+		result, err = client.Sys().StorageVerifyStatus()
+		if err != nil {
+			return nil, errwrap.Wrapf("error reading verification status: {{err}}", err)
+		}
+	}
+}
+
+// verifyOffline opens the raft data directory of the configured server, and
+// verifies its entries after unlocking the barrier with the configured seal.
+func (c *OperatorStorageVerifyCommand) verifyOffline(ctx context.Context) (*api.StorageVerifyResult, error) {
+	config, err := server.LoadConfig(c.flagConfig)
+	if err != nil {
+		return nil, errwrap.Wrapf("error loading configuration: {{err}}", err)
+	}
+	if config.Storage == nil {
+		return nil, errors.New("a storage backend must be specified")
+	}
+	if config.Storage.Type != "raft" {
+		return nil, fmt.Errorf("offline verification is only supported with integrated storage, not %q: "+
+			"verify the storage through the active node instead", config.Storage.Type)
+	}
+
+	logger := logging.NewVaultLogger(log.Warn)
+
+	raftPath := config.Storage.Config["path"]
+	if envPath := os.Getenv(raft.EnvVaultRaftPath); envPath != "" {
+		raftPath = envPath
+	}
+	if raftPath == "" {
+		return nil, errors.New("the path of the raft storage must be specified")
+	}
+	fsm, err := raft.NewFSM(raftPath, "", logger.Named("storage.raft.fsm"))
+	if err != nil {
+		return nil, errwrap.Wrapf("error opening the raft storage: {{err}}", err)
+	}
+	defer fsm.Close()
+
+	seal, err := c.offlineSeal(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer seal.Finalize(ctx)
+
+	core, err := vault.NewCore(&vault.CoreConfig{
+		Physical:     fsm,
+		StorageType:  config.Storage.Type,
+		Seal:         seal,
+		Logger:       logger,
+		DisableMlock: config.DisableMlock,
+	})
+	if err != nil && vault.IsFatalError(err) {
+		return nil, errwrap.Wrapf("error initializing core: {{err}}", err)
+	}
+
+	var unsealKeys [][]byte
+	if core.SealAccess().StoredKeysSupported() != vaultseal.StoredKeysSupportedGeneric {
+		unsealKeys, err = c.unsealKeys(ctx, core)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report, err := core.VerifyStorageOffline(ctx, unsealKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &api.StorageVerifyResult{
+		Status:         "complete",
+		EntriesChecked: int(report.EntriesChecked),
+		EntriesSkipped: int(report.EntriesSkipped),
+		Problems:       make([]*api.StorageVerifyProblem, 0, len(report.Problems)),
+	}
+	for _, problem := range report.Problems {
+		result.Problems = append(result.Problems, &api.StorageVerifyProblem{
+			Path:    problem.Path,
+			Problem: problem.Problem,
+			Error:   problem.Error,
+		})
+	}
+	return result, nil
+}
+
+// offlineSeal creates the seal of the given server configuration, like the
+// server does in recovery mode
+func (c *OperatorStorageVerifyCommand) offlineSeal(config *server.Config, logger log.Logger) (vault.Seal, error) {
+	var configSeal *configutil.KMS
+	for _, s := range config.Seals {
+		if !s.Disabled {
+			configSeal = s
+		}
+	}
+	if configSeal == nil {
+		configSeal = &configutil.KMS{Type: wrapping.Shamir}
+	}
+	if os.Getenv("VAULT_SEAL_TYPE") != "" {
+		configSeal.Type = os.Getenv("VAULT_SEAL_TYPE")
+	}
+
+	var infoKeys []string
+	info := make(map[string]string)
+	wrapper, err := configutil.ConfigureWrapper(configSeal, &infoKeys, &info, logger.Named("seal."+configSeal.Type))
+	if err != nil && !errwrap.ContainsType(err, new(logical.KeyNotFoundError)) {
+		return nil, errwrap.Wrapf("error parsing seal configuration: {{err}}", err)
+	}
+	if wrapper == nil {
+		return vault.NewDefaultSeal(&vaultseal.Access{
+			Wrapper: aeadwrapper.NewShamirWrapper(&wrapping.WrapperOptions{
+				Logger: logger.Named("shamir"),
+			}),
+		}), nil
+	}
+	return vault.NewAutoSeal(&vaultseal.Access{
+		Wrapper: wrapper,
+	}), nil
+}
+
+// unsealKeys prompts for as many unseal keys as the threshold of the barrier
+func (c *OperatorStorageVerifyCommand) unsealKeys(ctx context.Context, core *vault.Core) ([][]byte, error) {
+	sealConfig, err := core.SealAccess().BarrierConfig(ctx)
+	if err != nil {
+		return nil, errwrap.Wrapf("error reading seal configuration: {{err}}", err)
+	}
+	if sealConfig == nil {
+		return nil, errors.New("vault is not initialized")
+	}
+
+	var encodedKeys []string
+	writer := getWriterFromUI(c.UI)
+	for i := 1; i <= sealConfig.SecretThreshold; i++ {
+		fmt.Fprintf(writer, "Unseal Key %d of %d (will be hidden): ", i, sealConfig.SecretThreshold)
+		value, err := password.Read(os.Stdin)
+		fmt.Fprintf(writer, "\n")
+		if err != nil {
+			return nil, errwrap.Wrapf("error reading unseal key, the command must be run from a terminal: {{err}}", err)
+		}
+		encodedKeys = append(encodedKeys, strings.TrimSpace(value))
+	}
+
+	// Keys are base64 or hex encoded, like when unsealing
+	min, max := core.BarrierKeyLength()
+	keys := make([][]byte, 0, len(encodedKeys))
+	for _, encoded := range encodedKeys {
+		key, err := hex.DecodeString(encoded)
+		if err != nil || len(key) < min || len(key) > max {
+			key, err = base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, errors.New("unseal keys must be valid hex or base64 strings")
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
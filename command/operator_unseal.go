@@ -1,13 +1,19 @@
 package command
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"strings"
 
 	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/pgpkeys"
 	"github.com/hashicorp/vault/sdk/helper/password"
+	"github.com/keybase/go-crypto/openpgp"
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 )
@@ -20,8 +26,9 @@ var (
 type OperatorUnsealCommand struct {
 	*BaseCommand
 
-	flagReset   bool
-	flagMigrate bool
+	flagReset     bool
+	flagMigrate   bool
+	flagHolderKey string
 
 	testOutput io.Writer // for tests
 }
@@ -48,6 +55,13 @@ Usage: vault operator unseal [options] [KEY]
       $ vault operator unseal
       Key (will be hidden): IXyR0OJnSFobekZMMCKCoVEpT7wI6l+USMzE3IcyDyo=
 
+  When signed unseal is enabled, provide the PGP-encrypted unseal key received
+  at initialization along with your private PGP key. The unseal key is
+  decrypted locally, signed with your key and encrypted for the server, so it
+  is never displayed or entered in plaintext:
+
+      $ vault operator unseal -holder-key=alice.key wcBMA37rwGt6FS1VAQgAk1q8XQh6yc...
+
 ` + c.Flags().Help()
 
 	return strings.TrimSpace(helpText)
@@ -78,6 +92,19 @@ func (c *OperatorUnsealCommand) Flags() *FlagSets {
 		Usage:      "Indicate that this share is provided with the intent that it is part of a seal migration process.",
 	})
 
+	f.StringVar(&StringVar{
+		Name:       "holder-key",
+		Target:     &c.flagHolderKey,
+		Default:    "",
+		EnvVar:     "",
+		Completion: complete.PredictFiles("*"),
+		Usage: "Path to a file on disk containing the armored or binary private " +
+			"PGP key of the key holder. When supplied, the key is the " +
+			"PGP-encrypted unseal key, which is decrypted with the private key " +
+			"and submitted signed by it. This is required when signed unseal is " +
+			"enabled.",
+	})
+
 	return set
 }
 
@@ -126,15 +153,11 @@ func (c *OperatorUnsealCommand) Run(args []string) int {
 	}
 
 	if unsealKey == "" {
-		// Override the output
-		writer := (io.Writer)(os.Stdout)
-		if c.testOutput != nil {
-			writer = c.testOutput
+		prompt := "Unseal Key (will be hidden): "
+		if c.flagHolderKey != "" {
+			prompt = "Encrypted Unseal Key (will be hidden): "
 		}
-
-		fmt.Fprintf(writer, "Unseal Key (will be hidden): ")
-		value, err := password.Read(os.Stdin)
-		fmt.Fprintf(writer, "\n")
+		value, err := c.readHidden(prompt)
 		if err != nil {
 			c.UI.Error(wrapAtLength(fmt.Sprintf("An error occurred attempting to "+
 				"ask for an unseal key. The raw error message is shown below, but "+
@@ -149,6 +172,10 @@ func (c *OperatorUnsealCommand) Run(args []string) int {
 		unsealKey = strings.TrimSpace(value)
 	}
 
+	if c.flagHolderKey != "" {
+		return c.unsealSigned(client, unsealKey)
+	}
+
 	status, err := client.Sys().UnsealWithOptions(&api.UnsealOpts{
 		Key:     unsealKey,
 		Migrate: c.flagMigrate,
@@ -160,3 +187,116 @@ func (c *OperatorUnsealCommand) Run(args []string) int {
 
 	return OutputSealStatus(c.UI, client, status)
 }
+
+// unsealSigned decrypts the PGP-encrypted unseal key with the private key of
+// the key holder, and submits it signed by the key holder and encrypted with
+// the unseal PGP key of the server.
+func (c *OperatorUnsealCommand) unsealSigned(client *api.Client, encryptedKey string) int {
+	holder, err := c.readHolderKey(c.flagHolderKey)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error reading holder key: %s", err))
+		return 1
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		encrypted, err = hex.DecodeString(encryptedKey)
+		if err != nil {
+			c.UI.Error("Encrypted unseal key must be a valid base64 or hex string")
+			return 1
+		}
+	}
+	md, err := openpgp.ReadMessage(bytes.NewBuffer(encrypted), openpgp.EntityList{holder}, nil, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error decrypting unseal key: %s", err))
+		return 1
+	}
+	unsealKey, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error decrypting unseal key: %s", err))
+		return 1
+	}
+
+	status, err := client.Sys().SealStatus()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error checking seal status: %s", err))
+		return 2
+	}
+	if !status.Sealed {
+		return OutputSealStatus(c.UI, client, status)
+	}
+	if !status.SignedUnseal || status.UnsealPGPKey == "" {
+		c.UI.Error("Signed unseal is not enabled on this Vault server")
+		return 2
+	}
+
+	signedKey, err := pgpkeys.SignAndEncrypt(unsealKey, status.UnsealPGPKey, holder)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error signing unseal key: %s", err))
+		return 2
+	}
+
+	status, err = client.Sys().UnsealWithOptions(&api.UnsealOpts{
+		SignedKey: base64.StdEncoding.EncodeToString(signedKey),
+		Migrate:   c.flagMigrate,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error unsealing: %s", err))
+		return 2
+	}
+
+	return OutputSealStatus(c.UI, client, status)
+}
+
+// readHolderKey reads the private PGP key of the key holder, prompting for
+// its passphrase if it is encrypted.
+func (c *OperatorUnsealCommand) readHolderKey(path string) (*openpgp.Entity, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewBuffer(data))
+	if err != nil {
+		entities, err = openpgp.ReadKeyRing(bytes.NewBuffer(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse PGP key: %w", err)
+		}
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, fmt.Errorf("no private PGP key found in %q", path)
+	}
+	entity := entities[0]
+
+	if entity.PrivateKey.Encrypted {
+		passphrase, err := c.readHidden("Holder Key Passphrase (will be hidden): ")
+		if err != nil {
+			return nil, err
+		}
+		if err := entity.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt PGP key: %w", err)
+		}
+		for _, subkey := range entity.Subkeys {
+			if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+				if err := subkey.PrivateKey.Decrypt([]byte(passphrase)); err != nil {
+					return nil, fmt.Errorf("failed to decrypt PGP subkey: %w", err)
+				}
+			}
+		}
+	}
+	return entity, nil
+}
+
+// readHidden prompts for a value without echoing it
+func (c *OperatorUnsealCommand) readHidden(prompt string) (string, error) {
+	// Override the output
+	writer := (io.Writer)(os.Stdout)
+	if c.testOutput != nil {
+		writer = c.testOutput
+	}
+
+	fmt.Fprint(writer, prompt)
+	value, err := password.Read(os.Stdin)
+	fmt.Fprintf(writer, "\n")
+	return value, err
+}
@@ -2,12 +2,17 @@ package command
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/pgpkeys"
 	"github.com/mitchellh/cli"
 )
 
@@ -115,6 +120,76 @@ func TestOperatorUnsealCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("signed", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerUninit(t)
+		defer closer()
+
+		init, err := client.Sys().Init(&api.InitRequest{
+			SecretShares:    2,
+			SecretThreshold: 2,
+			PGPKeys:         []string{pgpkeys.TestPubKey1, pgpkeys.TestPubKey2},
+			SignedUnseal:    true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir, err := ioutil.TempDir("", "vault-unseal")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		// Unsigned unseal keys are refused
+		ui, cmd := testOperatorUnsealCommand(t)
+		cmd.client = client
+		if code := cmd.Run([]string{init.KeysB64[0]}); code != 2 {
+			t.Errorf("expected %d to be %d: %s", code, 2, ui.OutputWriter.String())
+		}
+
+		for i, privKey := range []string{pgpkeys.TestPrivKey1, pgpkeys.TestPrivKey2} {
+			keyPath := filepath.Join(dir, fmt.Sprintf("holder%d.key", i))
+			data, err := base64.StdEncoding.DecodeString(privKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(keyPath, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			ui, cmd = testOperatorUnsealCommand(t)
+			cmd.client = client
+			cmd.testOutput = ioutil.Discard
+
+			code := cmd.Run([]string{
+				"-holder-key", keyPath,
+				init.KeysB64[i],
+			})
+			if exp := 0; code != exp {
+				t.Errorf("expected %d to be %d: %s", code, exp, ui.ErrorWriter.String())
+			}
+		}
+
+		expected := "Unseal Key Holders"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		status, err := client.Sys().SealStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Sealed {
+			t.Error("expected unsealed")
+		}
+		if len(status.UnsealHolders) != 2 {
+			t.Errorf("bad key holders: %v", status.UnsealHolders)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
@@ -0,0 +1,216 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*PolicyExplainCommand)(nil)
+	_ cli.CommandAutocomplete = (*PolicyExplainCommand)(nil)
+)
+
+type PolicyExplainCommand struct {
+	*BaseCommand
+
+	flagOperation string
+	flagAccessor  string
+	flagEntityID  string
+
+	testStdin io.Reader // for tests
+}
+
+func (c *PolicyExplainCommand) Synopsis() string {
+	return "Explains which policies allow or deny a request"
+}
+
+func (c *PolicyExplainCommand) Help() string {
+	helpText := `
+Usage: vault policy explain [options] PATH [DATA K=V...]
+
+  Explains how the policies of a token or entity decide a request to PATH,
+  without performing it. The output shows whether the request would be
+  allowed, the path rule that applies to it, which policies grant or deny
+  each capability of that rule, and the reason for the decision.
+
+  Request parameters are specified as "key=value" pairs, as with "vault write".
+  By default the policies of the locally authenticated token are explained.
+
+  Explain why the local token can or cannot read "secret/foo":
+
+      $ vault policy explain secret/foo
+
+  Explain an update with parameters for the token with a given accessor:
+
+      $ vault policy explain -operation=update -accessor=8609694a-cdbc-db9b-d345-e782dbb562ed \
+          auth/userpass/users/bob password=s3cr3t
+
+  Explain a request made by an entity:
+
+      $ vault policy explain -entity-id=6cd4b7d6-8d12-0b22-1d2c-1df5d4e1e4a2 -operation=list secret/
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PolicyExplainCommand) Flags() *FlagSets {
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+	f := set.NewFlagSet("Command Options")
+
+	f.StringVar(&StringVar{
+		Name:       "operation",
+		Target:     &c.flagOperation,
+		Default:    "read",
+		Completion: complete.PredictSet("create", "read", "update", "delete", "list"),
+		Usage: "Operation of the request to explain. This is one of \"create\", " +
+			"\"read\", \"update\", \"delete\" or \"list\".",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "accessor",
+		Target:     &c.flagAccessor,
+		Default:    "",
+		Completion: complete.PredictAnything,
+		Usage:      "Accessor of the token whose policies are explained.",
+	})
+
+	f.StringVar(&StringVar{
+		Name:       "entity-id",
+		Target:     &c.flagEntityID,
+		Default:    "",
+		Completion: complete.PredictAnything,
+		Usage:      "ID of the entity whose policies are explained.",
+	})
+
+	return set
+}
+
+func (c *PolicyExplainCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictAnything
+}
+
+func (c *PolicyExplainCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *PolicyExplainCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 1 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 1, got %d)", len(args)))
+		return 1
+	}
+
+	if c.flagAccessor != "" && c.flagEntityID != "" {
+		c.UI.Error("Only one of -accessor and -entity-id can be specified")
+		return 1
+	}
+
+	// Pull our fake stdin if needed
+	stdin := (io.Reader)(os.Stdin)
+	if c.testStdin != nil {
+		stdin = c.testStdin
+	}
+
+	data, err := parseArgsData(stdin, args[1:])
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Failed to parse K=V data: %s", err))
+		return 1
+	}
+
+	client, err := c.Client()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 2
+	}
+
+	path := sanitizePath(args[0])
+	explanation, err := client.Sys().ExplainPolicy(&api.PolicyExplainInput{
+		Accessor:   c.flagAccessor,
+		EntityID:   c.flagEntityID,
+		Path:       path,
+		Operation:  c.flagOperation,
+		Parameters: data,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error explaining policies for %s: %s", path, err))
+		return 2
+	}
+
+	return outputPolicyExplanation(c.UI, explanation)
+}
+
+// outputPolicyExplanation prints the explanation of a policy decision in the
+// configured format.
+func outputPolicyExplanation(ui cli.Ui, explanation *api.PolicyExplainOutput) int {
+	if Format(ui) != "table" {
+		return OutputData(ui, explanation)
+	}
+
+	decision := "denied"
+	if explanation.Allowed {
+		decision = "allowed"
+	}
+	rule := explanation.Rule
+	if rule == "" {
+		rule = "n/a"
+	}
+
+	out := []string{
+		"Key | Value",
+		fmt.Sprintf("Path | %s", explanation.Path),
+		fmt.Sprintf("Operation | %s", explanation.Operation),
+		fmt.Sprintf("Decision | %s", decision),
+		fmt.Sprintf("Rule | %s", rule),
+		fmt.Sprintf("Capabilities | %s", strings.Join(explanation.Capabilities, ", ")),
+		fmt.Sprintf("Reason | %s", explanation.Reason),
+	}
+
+	capabilities := make([]string, 0, len(explanation.GrantedBy))
+	for capability := range explanation.GrantedBy {
+		capabilities = append(capabilities, capability)
+	}
+	sort.Strings(capabilities)
+	for _, capability := range capabilities {
+		out = append(out, fmt.Sprintf("Granted %s by | %s", capability, strings.Join(explanation.GrantedBy[capability], ", ")))
+	}
+	if len(explanation.DeniedBy) > 0 {
+		out = append(out, fmt.Sprintf("Denied by | %s", strings.Join(explanation.DeniedBy, ", ")))
+	}
+	ui.Output(tableOutput(out, nil))
+
+	if len(explanation.Policies) == 0 {
+		return 0
+	}
+
+	ui.Output("")
+	policies := []string{"Policy | Namespace | Rule | Capabilities | Effective"}
+	for _, p := range explanation.Policies {
+		rule, capabilities := p.Rule, strings.Join(p.Capabilities, ", ")
+		if rule == "" {
+			rule, capabilities = "n/a", "n/a"
+		}
+		namespace := p.Namespace
+		if namespace == "" {
+			namespace = "root"
+		}
+		policies = append(policies, fmt.Sprintf("%s | %s | %s | %s | %t", p.Name, namespace, rule, capabilities, p.Effective))
+	}
+	ui.Output(tableOutput(policies, nil))
+
+	return 0
+}
@@ -0,0 +1,150 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/mitchellh/cli"
+)
+
+func testPolicyExplainCommand(tb testing.TB) (*cli.MockUi, *PolicyExplainCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &PolicyExplainCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func TestPolicyExplainCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name string
+		args []string
+		out  string
+		code int
+	}{
+		{
+			"not_enough_args",
+			[]string{},
+			"Not enough arguments",
+			1,
+		},
+		{
+			"accessor_and_entity",
+			[]string{"-accessor", "foo", "-entity-id", "bar", "secret/foo"},
+			"Only one of -accessor and -entity-id",
+			1,
+		},
+		{
+			"bad_operation",
+			[]string{"-operation", "sudo", "secret/foo"},
+			"unsupported operation",
+			2,
+		},
+	}
+
+	t.Run("validations", func(t *testing.T) {
+		t.Parallel()
+
+		for _, tc := range cases {
+			tc := tc
+
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				client, closer := testVaultServer(t)
+				defer closer()
+
+				ui, cmd := testPolicyExplainCommand(t)
+				cmd.client = client
+
+				code := cmd.Run(tc.args)
+				if code != tc.code {
+					t.Errorf("expected %d to be %d", code, tc.code)
+				}
+
+				combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+				if !strings.Contains(combined, tc.out) {
+					t.Errorf("expected %q to contain %q", combined, tc.out)
+				}
+			})
+		}
+	})
+
+	t.Run("default", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		policy := `path "secret/*" { capabilities = ["read"] }`
+		if err := client.Sys().PutPolicy("my-policy", policy); err != nil {
+			t.Fatal(err)
+		}
+
+		secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+			Policies: []string{"my-policy"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ui, cmd := testPolicyExplainCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"-operation", "update",
+			"-accessor", secret.Auth.Accessor,
+			"secret/foo", "bar=baz",
+		})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		for _, expected := range []string{
+			"denied",
+			`no policy grants the "update" capability on path rule "secret/*"`,
+			"Granted read by    my-policy",
+		} {
+			if !strings.Contains(combined, expected) {
+				t.Errorf("expected %q to contain %q", combined, expected)
+			}
+		}
+	})
+
+	t.Run("communication_failure", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServerBad(t)
+		defer closer()
+
+		ui, cmd := testPolicyExplainCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{
+			"secret/foo",
+		})
+		if exp := 2; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Error explaining policies for secret/foo: "
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+	})
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testPolicyExplainCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -0,0 +1,382 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+	"github.com/mitchellh/cli"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/posener/complete"
+)
+
+var (
+	_ cli.Command             = (*PolicyTestCommand)(nil)
+	_ cli.CommandAutocomplete = (*PolicyTestCommand)(nil)
+)
+
+type PolicyTestCommand struct {
+	*BaseCommand
+}
+
+// policyTestFile is the contents of a policy test file.
+type policyTestFile struct {
+	Tests []*policyTestCase `hcl:"test" json:"tests"`
+}
+
+// policyTestCase is a request that the tested policies are expected to allow
+// or deny.
+type policyTestCase struct {
+	Name       string                 `hcl:",key" json:"name"`
+	Path       string                 `hcl:"path" json:"path"`
+	Operation  string                 `hcl:"operation" json:"operation"`
+	Parameters map[string]interface{} `hcl:"parameters" json:"parameters"`
+	Policies   []string               `hcl:"policies" json:"policies"`
+	RemoteAddr string                 `hcl:"remote_addr" json:"remote_addr"`
+	Entity     *policyTestEntity      `hcl:"entity" json:"entity"`
+	Expect     string                 `hcl:"expect" json:"expect"`
+}
+
+// policyTestEntity is the identity a test case is run as, used to render
+// templated policies and to evaluate policy conditions.
+type policyTestEntity struct {
+	ID       string             `hcl:"id" json:"id"`
+	Name     string             `hcl:"name" json:"name"`
+	Metadata map[string]string  `hcl:"metadata" json:"metadata"`
+	Groups   []*policyTestGroup `hcl:"group" json:"groups"`
+}
+
+type policyTestGroup struct {
+	Name     string            `hcl:",key" json:"name"`
+	ID       string            `hcl:"id" json:"id"`
+	Metadata map[string]string `hcl:"metadata" json:"metadata"`
+}
+
+type policyTestResult struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Operation string `json:"operation"`
+	Expect    string `json:"expect"`
+	Allowed   bool   `json:"allowed"`
+	Passed    bool   `json:"passed"`
+	Reason    string `json:"reason"`
+}
+
+func (c *PolicyTestCommand) Synopsis() string {
+	return "Tests policies on disk against expected decisions"
+}
+
+func (c *PolicyTestCommand) Help() string {
+	helpText := `
+Usage: vault policy test [options] TESTS POLICY...
+
+  Tests local policy files against a file of test cases, fully offline and
+  without a Vault server. Each test case describes a request and whether the
+  policies are expected to allow or deny it.
+
+  Each POLICY is a policy file or a directory of ".hcl" policy files. A policy
+  is named after its file name without extension, unless given as NAME=PATH.
+
+  The TESTS file is written in HCL, or in YAML or JSON if it has a ".yaml",
+  ".yml" or ".json" extension:
+
+      test "developers can read app secrets" {
+        path      = "secret/data/app/config"
+        operation = "read"
+        policies  = ["developer"]
+        expect    = "allow"
+      }
+
+      test "developers cannot write the production config" {
+        path       = "secret/data/prod/config"
+        operation  = "update"
+        parameters = { data = "value" }
+        expect     = "deny"
+      }
+
+  Test cases without "policies" are run against every loaded policy. A test
+  case can set "remote_addr" and an "entity" block with "id", "name",
+  "metadata" and "group" blocks, used by templated policies and conditions.
+  The command exits with status 2 if any test case fails.
+
+  Run the tests in "policy_tests.hcl" against the policies in "./policies":
+
+      $ vault policy test policy_tests.hcl ./policies
+
+` + c.Flags().Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *PolicyTestCommand) Flags() *FlagSets {
+	return c.flagSet(FlagSetOutputFormat)
+}
+
+func (c *PolicyTestCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictFiles("*")
+}
+
+func (c *PolicyTestCommand) AutocompleteFlags() complete.Flags {
+	return c.Flags().Completions()
+}
+
+func (c *PolicyTestCommand) Run(args []string) int {
+	f := c.Flags()
+
+	if err := f.Parse(args); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) < 2 {
+		c.UI.Error(fmt.Sprintf("Not enough arguments (expected at least 2, got %d)", len(args)))
+		return 1
+	}
+
+	tests, err := parsePolicyTestFile(args[0])
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading tests: %s", err))
+		return 1
+	}
+
+	policies := make(map[string]*vault.Policy)
+	for _, arg := range args[1:] {
+		if err := loadTestPolicies(policies, arg); err != nil {
+			c.UI.Error(fmt.Sprintf("Error loading policies: %s", err))
+			return 1
+		}
+	}
+
+	results := make([]*policyTestResult, 0, len(tests.Tests))
+	var failed int
+	for _, tc := range tests.Tests {
+		result, err := runPolicyTest(policies, tc)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error running test %q: %s", tc.Name, err))
+			return 1
+		}
+		if !result.Passed {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if Format(c.UI) != "table" {
+		if code := OutputData(c.UI, results); code != 0 {
+			return code
+		}
+	} else {
+		out := []string{"Result | Test | Operation | Path | Reason"}
+		for _, r := range results {
+			status := "PASS"
+			if !r.Passed {
+				status = "FAIL"
+			}
+			out = append(out, fmt.Sprintf("%s | %s | %s | %s | %s", status, r.Name, r.Operation, r.Path, r.Reason))
+		}
+		c.UI.Output(tableOutput(out, nil))
+		c.UI.Output("")
+		c.UI.Output(fmt.Sprintf("%d passed, %d failed", len(results)-failed, failed))
+	}
+
+	if failed > 0 {
+		return 2
+	}
+	return 0
+}
+
+// parsePolicyTestFile reads a test file, decoding it as YAML or JSON based on
+// its extension and as HCL otherwise.
+func parsePolicyTestFile(path string) (*policyTestFile, error) {
+	path, err := homedir.Expand(strings.TrimSpace(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand path: %w", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tests policyTestFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		jsonBytes, err := yaml.YAMLToJSON(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if err := json.Unmarshal(jsonBytes, &tests); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	default:
+		if err := hcl.Decode(&tests, string(b)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	if len(tests.Tests) == 0 {
+		return nil, fmt.Errorf("no tests found in %s", path)
+	}
+	for i, tc := range tests.Tests {
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("#%d", i+1)
+		}
+		if tc.Path == "" {
+			return nil, fmt.Errorf("test %q: missing path", tc.Name)
+		}
+		if tc.Operation == "" {
+			tc.Operation = string(logical.ReadOperation)
+		}
+		switch logical.Operation(strings.ToLower(tc.Operation)) {
+		case logical.CreateOperation, logical.ReadOperation, logical.UpdateOperation, logical.DeleteOperation, logical.ListOperation:
+			tc.Operation = strings.ToLower(tc.Operation)
+		default:
+			return nil, fmt.Errorf("test %q: unsupported operation %q", tc.Name, tc.Operation)
+		}
+		switch tc.Expect {
+		case "allow", "deny":
+		default:
+			return nil, fmt.Errorf("test %q: expect must be \"allow\" or \"deny\", got %q", tc.Name, tc.Expect)
+		}
+	}
+
+	return &tests, nil
+}
+
+// loadTestPolicies parses the policy file, or the policy files of the
+// directory, at the given path into policies. The path can be prefixed with
+// "NAME=" to name a single policy.
+func loadTestPolicies(policies map[string]*vault.Policy, arg string) error {
+	name, path := "", arg
+	if idx := strings.Index(arg, "="); idx > 0 {
+		name, path = arg[:idx], arg[idx+1:]
+	}
+
+	path, err := homedir.Expand(strings.TrimSpace(path))
+	if err != nil {
+		return fmt.Errorf("failed to expand path: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if name != "" {
+			return fmt.Errorf("cannot name the directory %s", path)
+		}
+		files, err = filepath.Glob(filepath.Join(path, "*.hcl"))
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			return fmt.Errorf("no policy files found in %s", path)
+		}
+	}
+
+	for _, file := range files {
+		policyName := name
+		if policyName == "" {
+			policyName = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		policyName = strings.ToLower(policyName)
+		if _, ok := policies[policyName]; ok {
+			return fmt.Errorf("duplicate policy %q", policyName)
+		}
+
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		// Policies are always tested in the root namespace
+		policy, err := vault.ParseACLPolicy(namespace.RootNamespace, string(b))
+		if err != nil {
+			return fmt.Errorf("failed to parse policy %q: %w", policyName, err)
+		}
+		policy.Name = policyName
+		policies[policyName] = policy
+	}
+
+	return nil
+}
+
+// runPolicyTest evaluates the request of the test case against the policies
+// it selects.
+func runPolicyTest(policies map[string]*vault.Policy, tc *policyTestCase) (*policyTestResult, error) {
+	names := tc.Policies
+	if len(names) == 0 {
+		for name := range policies {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	selected := make([]*vault.Policy, 0, len(names))
+	for _, name := range names {
+		policy, ok := policies[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown policy %q", name)
+		}
+		selected = append(selected, policy)
+	}
+
+	req := &logical.Request{
+		Operation: logical.Operation(tc.Operation),
+		Path:      sanitizePath(tc.Path),
+		Data:      tc.Parameters,
+	}
+	if tc.RemoteAddr != "" {
+		req.Connection = &logical.Connection{
+			RemoteAddr: tc.RemoteAddr,
+		}
+	}
+
+	var entity *identity.Entity
+	var groups []*identity.Group
+	if tc.Entity != nil {
+		entity = &identity.Entity{
+			ID:          tc.Entity.ID,
+			Name:        tc.Entity.Name,
+			Metadata:    tc.Entity.Metadata,
+			NamespaceID: namespace.RootNamespaceID,
+		}
+		for _, g := range tc.Entity.Groups {
+			groups = append(groups, &identity.Group{
+				ID:          g.ID,
+				Name:        g.Name,
+				Metadata:    g.Metadata,
+				NamespaceID: namespace.RootNamespaceID,
+			})
+		}
+	}
+
+	ctx := namespace.RootContext(context.Background())
+	explanation, err := vault.ExplainPolicies(ctx, selected, entity, groups, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &policyTestResult{
+		Name:      tc.Name,
+		Path:      req.Path,
+		Operation: tc.Operation,
+		Expect:    tc.Expect,
+		Allowed:   explanation.Allowed,
+		Passed:    explanation.Allowed == (tc.Expect == "allow"),
+		Reason:    explanation.Reason,
+	}, nil
+}
@@ -0,0 +1,238 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/cli"
+)
+
+func testPolicyTestCommand(tb testing.TB) (*cli.MockUi, *PolicyTestCommand) {
+	tb.Helper()
+
+	ui := cli.NewMockUi()
+	return ui, &PolicyTestCommand{
+		BaseCommand: &BaseCommand{
+			UI: ui,
+		},
+	}
+}
+
+func testPolicyTestDir(tb testing.TB, files map[string]string) string {
+	tb.Helper()
+
+	dir, err := ioutil.TempDir("", "vault-policy-test")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestPolicyTestCommand_Run(t *testing.T) {
+	t.Parallel()
+
+	policies := map[string]string{
+		"policies/developer.hcl": `
+path "secret/data/app/*" {
+  capabilities = ["read", "update"]
+  allowed_parameters = {
+    "data" = []
+  }
+}`,
+		"policies/auditor.hcl": `
+path "secret/data/*" {
+  capabilities = ["read", "list"]
+}
+path "secret/data/prod/*" {
+  capabilities = ["deny"]
+}`,
+		"policies/self.hcl": `
+path "secret/data/users/{{identity.entity.name}}/*" {
+  capabilities = ["read"]
+}
+path "transit/encrypt/office" {
+  capabilities = ["update"]
+  condition "network" {
+    expression = "cidr_match(request.remote_addr, \"10.0.0.0/8\") && \"ops\" in identity.groups.names"
+  }
+}`,
+	}
+
+	cases := []struct {
+		name  string
+		files map[string]string
+		args  []string
+		out   []string
+		code  int
+	}{
+		{
+			"not_enough_args",
+			nil,
+			[]string{"tests.hcl"},
+			[]string{"Not enough arguments"},
+			1,
+		},
+		{
+			"bad_expect",
+			map[string]string{
+				"tests.hcl": `test "a" {
+  path   = "secret/data/foo"
+  expect = "maybe"
+}`,
+			},
+			[]string{"tests.hcl", "policies"},
+			[]string{`expect must be "allow" or "deny"`},
+			1,
+		},
+		{
+			"unknown_policy",
+			map[string]string{
+				"tests.hcl": `test "a" {
+  path     = "secret/data/foo"
+  policies = ["nope"]
+  expect   = "deny"
+}`,
+			},
+			[]string{"tests.hcl", "policies"},
+			[]string{`unknown policy "nope"`},
+			1,
+		},
+		{
+			"hcl_passing",
+			map[string]string{
+				"tests.hcl": `
+test "developer can update app secrets" {
+  path       = "secret/data/app/config"
+  operation  = "update"
+  parameters = { data = "foo" }
+  policies   = ["developer"]
+  expect     = "allow"
+}
+
+test "developer cannot set other parameters" {
+  path       = "secret/data/app/config"
+  operation  = "update"
+  parameters = { options = "foo" }
+  policies   = ["developer"]
+  expect     = "deny"
+}
+
+test "prod is denied" {
+  path   = "secret/data/prod/db"
+  expect = "deny"
+}
+
+test "users read their own secrets" {
+  path     = "secret/data/users/bob/key"
+  policies = ["self"]
+  entity {
+    name = "bob"
+  }
+  expect = "allow"
+}
+
+test "office network" {
+  path        = "transit/encrypt/office"
+  operation   = "update"
+  policies    = ["self"]
+  remote_addr = "10.1.2.3"
+  entity {
+    name = "bob"
+    group "ops" {
+      id = "1234"
+    }
+  }
+  expect = "allow"
+}`,
+			},
+			[]string{"tests.hcl", "policies"},
+			[]string{"5 passed, 0 failed"},
+			0,
+		},
+		{
+			"yaml_failing",
+			map[string]string{
+				"tests.yaml": `
+tests:
+  - name: auditor can write
+    path: secret/data/app/config
+    operation: update
+    policies: [auditor]
+    expect: allow
+  - name: auditor can list
+    path: secret/data/app/
+    operation: list
+    policies: [audit-renamed]
+    expect: allow
+`,
+			},
+			[]string{"tests.yaml", "audit-renamed=policies/auditor.hcl", "policies"},
+			[]string{
+				"FAIL",
+				`no policy grants the "update" capability on path rule "secret/data/*"`,
+				"1 passed, 1 failed",
+			},
+			2,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := map[string]string{}
+			for k, v := range policies {
+				files[k] = v
+			}
+			for k, v := range tc.files {
+				files[k] = v
+			}
+			dir := testPolicyTestDir(t, files)
+			defer os.RemoveAll(dir)
+
+			args := make([]string, 0, len(tc.args))
+			for _, arg := range tc.args {
+				if idx := strings.Index(arg, "="); idx > 0 {
+					args = append(args, arg[:idx+1]+filepath.Join(dir, arg[idx+1:]))
+					continue
+				}
+				args = append(args, filepath.Join(dir, arg))
+			}
+
+			ui, cmd := testPolicyTestCommand(t)
+
+			code := cmd.Run(args)
+			combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+			if code != tc.code {
+				t.Errorf("expected %d to be %d: %s", code, tc.code, combined)
+			}
+
+			for _, out := range tc.out {
+				if !strings.Contains(combined, out) {
+					t.Errorf("expected %q to contain %q", combined, out)
+				}
+			}
+		})
+	}
+
+	t.Run("no_tabs", func(t *testing.T) {
+		t.Parallel()
+
+		_, cmd := testPolicyTestCommand(t)
+		assertNoTabs(t, cmd)
+	})
+}
@@ -2,8 +2,10 @@ package command
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
+	"github.com/hashicorp/vault/api"
 	"github.com/mitchellh/cli"
 	"github.com/posener/complete"
 )
@@ -15,6 +17,8 @@ var (
 
 type OperatorRotateCommand struct {
 	*BaseCommand
+
+	flagPrune bool
 }
 
 func (c *OperatorRotateCommand) Synopsis() string {
@@ -34,10 +38,18 @@ Usage: vault operator rotate [options]
   per-cluster (not per-server), since Vault servers in HA mode share the same
   storage backend.
 
+  The data written under the older keys is re-encrypted with the new key in
+  the background. Its progress is reported by "vault operator key-status".
+  Once it is complete, the older keys can be removed from the key ring.
+
   Rotate Vault's encryption key:
 
       $ vault operator rotate
 
+  Remove the keys that are no longer in use from the key ring:
+
+      $ vault operator rotate -prune
+
   For a full list of examples, please see the documentation.
 
 ` + c.Flags().Help()
@@ -46,7 +58,21 @@ Usage: vault operator rotate [options]
 }
 
 func (c *OperatorRotateCommand) Flags() *FlagSets {
-	return c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+	set := c.flagSet(FlagSetHTTP | FlagSetOutputFormat)
+
+	f := set.NewFlagSet("Command Options")
+
+	f.BoolVar(&BoolVar{
+		Name:       "prune",
+		Target:     &c.flagPrune,
+		Default:    false,
+		EnvVar:     "",
+		Completion: complete.PredictNothing,
+		Usage: "Instead of rotating the key, remove the keys of the terms " +
+			"preceding the last complete re-encryption from the key ring.",
+	})
+
+	return set
 }
 
 func (c *OperatorRotateCommand) AutocompleteArgs() complete.Predictor {
@@ -77,6 +103,10 @@ func (c *OperatorRotateCommand) Run(args []string) int {
 		return 2
 	}
 
+	if c.flagPrune {
+		return c.prune(client)
+	}
+
 	// Rotate the key
 	err = client.Sys().Rotate()
 	if err != nil {
@@ -101,3 +131,29 @@ func (c *OperatorRotateCommand) Run(args []string) int {
 		return OutputData(c.UI, status)
 	}
 }
+
+func (c *OperatorRotateCommand) prune(client *api.Client) int {
+	removed, err := client.Sys().RotatePrune()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error pruning keys: %s", err))
+		return 2
+	}
+
+	switch Format(c.UI) {
+	case "table":
+		if len(removed) == 0 {
+			c.UI.Output("No keys to prune")
+			return 0
+		}
+		terms := make([]string, 0, len(removed))
+		for _, term := range removed {
+			terms = append(terms, strconv.Itoa(term))
+		}
+		c.UI.Output(fmt.Sprintf("Success! Pruned keys for terms: %s", strings.Join(terms, ", ")))
+		return 0
+	default:
+		return OutputData(c.UI, map[string]interface{}{
+			"removed_terms": removed,
+		})
+	}
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mitchellh/cli"
 )
@@ -92,6 +93,55 @@ func TestOperatorRotateCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("prune", func(t *testing.T) {
+		t.Parallel()
+
+		client, closer := testVaultServer(t)
+		defer closer()
+
+		if err := client.Sys().Rotate(); err != nil {
+			t.Fatal(err)
+		}
+
+		// Wait for the existing data to be rewrapped under the new key
+		deadline := time.Now().Add(10 * time.Second)
+		for {
+			status, err := client.Sys().KeyStatus()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status.Rewrap != nil && status.Rewrap.Term == status.Term && status.Rewrap.Status == "complete" {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("rewrap did not complete: %#v", status.Rewrap)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		ui, cmd := testOperatorRotateCommand(t)
+		cmd.client = client
+
+		code := cmd.Run([]string{"-prune"})
+		if exp := 0; code != exp {
+			t.Errorf("expected %d to be %d", code, exp)
+		}
+
+		expected := "Success! Pruned keys for terms: 1"
+		combined := ui.OutputWriter.String() + ui.ErrorWriter.String()
+		if !strings.Contains(combined, expected) {
+			t.Errorf("expected %q to contain %q", combined, expected)
+		}
+
+		status, err := client.Sys().KeyStatus()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status.KeyringTerms) != 1 || status.KeyringTerms[0] != status.Term {
+			t.Errorf("expected only the active term in the keyring, got %v", status.KeyringTerms)
+		}
+	})
+
 	t.Run("communication_failure", func(t *testing.T) {
 		t.Parallel()
 
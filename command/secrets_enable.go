@@ -27,6 +27,7 @@ type SecretsEnableCommand struct {
 	flagMaxLeaseTTL               time.Duration
 	flagAuditNonHMACRequestKeys   []string
 	flagAuditNonHMACResponseKeys  []string
+	flagAuditFieldSelectors       []string
 	flagListingVisibility         string
 	flagPassthroughRequestHeaders []string
 	flagAllowedResponseHeaders    []string
@@ -135,6 +136,15 @@ func (c *SecretsEnableCommand) Flags() *FlagSets {
 			"devices in the response data object.",
 	})
 
+	f.StringSliceVar(&StringSliceVar{
+		Name:   flagNameAuditFieldSelectors,
+		Target: &c.flagAuditFieldSelectors,
+		Usage: "Comma-separated string or list of selectors of fields in the request " +
+			"and response data, written as \"<pointer>=<action>\", where the pointer is " +
+			"a JSON pointer starting with /request/data or /response/data and the " +
+			"action is \"hmac\", \"plaintext\" or \"drop\".",
+	})
+
 	f.StringVar(&StringVar{
 		Name:   flagNameListingVisibility,
 		Target: &c.flagListingVisibility,
@@ -296,6 +306,10 @@ func (c *SecretsEnableCommand) Run(args []string) int {
 			mountInput.Config.AuditNonHMACResponseKeys = c.flagAuditNonHMACResponseKeys
 		}
 
+		if fl.Name == flagNameAuditFieldSelectors {
+			mountInput.Config.AuditFieldSelectors = c.flagAuditFieldSelectors
+		}
+
 		if fl.Name == flagNameListingVisibility {
 			mountInput.Config.ListingVisibility = c.flagListingVisibility
 		}
@@ -33,6 +33,7 @@ import (
 	"github.com/hashicorp/vault/helper/builtinplugins"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/helper/tracing"
 	vaulthttp "github.com/hashicorp/vault/http"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/internalshared/gatedwriter"
@@ -521,7 +522,7 @@ func (c *ServerCommand) runRecoveryMode() int {
 	}
 
 	if len(config.Seals) > 1 {
-		c.UI.Error("Only one seal block is accepted in recovery mode")
+		c.UI.Error("Only one seal block is accepted in recovery mode, when multiple seals are enabled any one of them can be used")
 		return 1
 	}
 
@@ -1154,6 +1155,28 @@ func (c *ServerCommand) Run(args []string) int {
 	}
 	metricsHelper := metricsutil.NewMetricsHelper(inmemMetrics, prometheusEnabled)
 
+	// Export the traces of the requests if a collector is configured
+	if config.Telemetry != nil && config.Telemetry.OTLPTraceEndpoint != "" {
+		tracingLogger := c.logger.Named("tracing")
+		c.allLoggers = append(c.allLoggers, tracingLogger)
+		shutdownTracing, err := tracing.Setup(&tracing.Config{
+			Endpoint:   config.Telemetry.OTLPTraceEndpoint,
+			SampleRate: config.Telemetry.TraceSampleRate,
+			Logger:     tracingLogger,
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error initializing tracing: %s", err))
+			return 1
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(ctx); err != nil {
+				tracingLogger.Warn("failed to flush traces", "error", err)
+			}
+		}()
+	}
+
 	// Initialize the storage backend
 	backend, err := c.setupStorage(config)
 	if err != nil {
@@ -1220,9 +1243,19 @@ func (c *ServerCommand) Run(args []string) int {
 				config.Seals = append(config.Seals, &configutil.KMS{Type: wrapping.Shamir})
 			}
 		}
+		// With more than one enabled seal, the seals are combined so that
+		// any of them can unseal
+		var enabledSeals int
+		for _, configSeal := range config.Seals {
+			if !configSeal.Disabled {
+				enabledSeals++
+			}
+		}
+		var multiSealWrappers []*vaultseal.SealWrapper
+
 		for _, configSeal := range config.Seals {
 			sealType := wrapping.Shamir
-			if !configSeal.Disabled && os.Getenv("VAULT_SEAL_TYPE") != "" {
+			if !configSeal.Disabled && enabledSeals == 1 && os.Getenv("VAULT_SEAL_TYPE") != "" {
 				sealType = os.Getenv("VAULT_SEAL_TYPE")
 				configSeal.Type = sealType
 			} else {
@@ -1247,6 +1280,23 @@ func (c *ServerCommand) Run(args []string) int {
 					return 1
 				}
 			}
+			if !configSeal.Disabled && enabledSeals > 1 {
+				if wrapper == nil {
+					c.UI.Error(fmt.Sprintf("Error parsing Seal configuration: seal %q cannot be enabled with other seals", configSeal.SealName()))
+					return 1
+				}
+				multiSealWrappers = append(multiSealWrappers, &vaultseal.SealWrapper{
+					Wrapper:  wrapper,
+					Name:     configSeal.SealName(),
+					Priority: configSeal.Priority,
+				})
+				for _, k := range sealInfoKeys {
+					infoKeys = append(infoKeys, configSeal.SealName()+" "+k)
+					info[configSeal.SealName()+" "+k] = sealInfoMap[k]
+				}
+				continue
+			}
+
 			if wrapper == nil {
 				seal = defaultSeal
 			} else {
@@ -1277,6 +1327,23 @@ func (c *ServerCommand) Run(args []string) int {
 			}()
 
 		}
+
+		if len(multiSealWrappers) > 0 {
+			multiLogger := c.logger.ResetNamed(fmt.Sprintf("seal.%s", vaultseal.MultiSealType))
+			c.allLoggers = append(c.allLoggers, multiLogger)
+			barrierWrapper = vaultseal.NewMultiWrapper(multiLogger, multiSealWrappers...)
+			barrierSeal = vault.NewAutoSeal(&vaultseal.Access{
+				Wrapper: barrierWrapper,
+			})
+
+			// Finalizing the seal finalizes all the combined seals
+			defer func() {
+				err = barrierSeal.Finalize(context.Background())
+				if err != nil {
+					c.UI.Error(fmt.Sprintf("Error finalizing seals: %v", err))
+				}
+			}()
+		}
 	}
 
 	if barrierSeal == nil {
@@ -1303,6 +1370,7 @@ func (c *ServerCommand) Run(args []string) int {
 		AuditBackends:                  c.AuditBackends,
 		CredentialBackends:             c.CredentialBackends,
 		LogicalBackends:                c.LogicalBackends,
+		PhysicalBackends:               c.PhysicalBackends,
 		Logger:                         c.logger,
 		DisableSentinelTrace:           config.DisableSentinelTrace,
 		DisableCache:                   config.DisableCache,
@@ -1312,6 +1380,7 @@ func (c *ServerCommand) Run(args []string) int {
 		ClusterName:                    config.ClusterName,
 		CacheSize:                      config.CacheSize,
 		PluginDirectory:                config.PluginDirectory,
+		RaftSnapshotDirectory:          config.RaftSnapshotDirectory,
 		EnableUI:                       config.EnableUI,
 		EnableRaw:                      config.EnableRawEndpoint,
 		DisableSealWrap:                config.DisableSealWrap,
@@ -2491,6 +2560,15 @@ func (c *ServerCommand) Reload(lock *sync.RWMutex, reloadFuncs *map[string][]rel
 					}
 				}
 			}
+
+		case strings.HasPrefix(k, "audit_http|"):
+			for _, relFunc := range relFuncs {
+				if relFunc != nil {
+					if err := relFunc(); err != nil {
+						reloadErrors = multierror.Append(reloadErrors, errwrap.Wrapf(fmt.Sprintf("error encountered reloading http audit device at path %q: {{err}}", strings.TrimPrefix(k, "audit_http|")), err))
+					}
+				}
+			}
 		}
 	}
 
@@ -2543,6 +2621,12 @@ func (c *ServerCommand) storageMigrationActive(backend physical.Backend) bool {
 	for {
 		migrationStatus, err := CheckStorageMigration(backend)
 		if err == nil {
+			if migrationStatus != nil && migrationStatus.Destination != "" {
+				c.UI.Error(wrapAtLength(fmt.Sprintf("ERROR! Storage was migrated online to the %q storage "+
+					"backend (started: %s). Update the storage configuration to use it.",
+					migrationStatus.Destination, migrationStatus.Start.Format(time.RFC3339))))
+				return true
+			}
 			if migrationStatus != nil {
 				startTime := migrationStatus.Start.Format(time.RFC3339)
 				c.UI.Error(wrapAtLength(fmt.Sprintf("ERROR! Storage migration in progress (started: %s). "+
@@ -2571,6 +2655,10 @@ func (c *ServerCommand) storageMigrationActive(backend physical.Backend) bool {
 
 type StorageMigrationStatus struct {
 	Start time.Time `json:"start"`
+
+	// Destination is set once an online migration switched to another
+	// storage backend
+	Destination string `json:"destination,omitempty"`
 }
 
 func CheckStorageMigration(b physical.Backend) (*StorageMigrationStatus, error) {
@@ -50,6 +50,8 @@ type Config struct {
 
 	PluginDirectory string `hcl:"plugin_directory"`
 
+	RaftSnapshotDirectory string `hcl:"raft_snapshot_directory"`
+
 	EnableRawEndpoint    bool        `hcl:"-"`
 	EnableRawEndpointRaw interface{} `hcl:"raw_storage_endpoint,alias:EnableRawEndpoint"`
 
@@ -253,6 +255,11 @@ func (c *Config) Merge(c2 *Config) *Config {
 		result.PluginDirectory = c2.PluginDirectory
 	}
 
+	result.RaftSnapshotDirectory = c.RaftSnapshotDirectory
+	if c2.RaftSnapshotDirectory != "" {
+		result.RaftSnapshotDirectory = c2.RaftSnapshotDirectory
+	}
+
 	result.DisablePerformanceStandby = c.DisablePerformanceStandby
 	if c2.DisablePerformanceStandby {
 		result.DisablePerformanceStandby = c2.DisablePerformanceStandby
@@ -332,12 +339,32 @@ func CheckConfig(c *Config, e error) (*Config, error) {
 		return c, e
 	}
 
-	if len(c.Seals) == 2 {
+	if len(c.Seals) > 1 {
+		var enabled, disabled int
+		var shamir bool
+		names := make(map[string]bool)
+		for _, s := range c.Seals {
+			if s.Disabled {
+				disabled++
+				continue
+			}
+			enabled++
+			if s.Type == "shamir" {
+				shamir = true
+			}
+			if names[s.SealName()] {
+				return nil, fmt.Errorf("seals: more than one enabled seal named %q, each seal must have a unique name", s.SealName())
+			}
+			names[s.SealName()] = true
+		}
+
 		switch {
-		case c.Seals[0].Disabled && c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but both are disabled")
-		case !c.Seals[0].Disabled && !c.Seals[1].Disabled:
-			return nil, errors.New("seals: two seals provided but neither is disabled")
+		case enabled == 0:
+			return nil, errors.New("seals: multiple seals provided but all are disabled")
+		case disabled > 1:
+			return nil, errors.New("seals: multiple seals provided but more than one is disabled")
+		case enabled > 1 && shamir:
+			return nil, errors.New("seals: a shamir seal cannot be enabled with other seals")
 		}
 	}
 
@@ -790,6 +817,8 @@ func (c *Config) Sanitized() map[string]interface{} {
 
 		"plugin_directory": c.PluginDirectory,
 
+		"raft_snapshot_directory": c.RaftSnapshotDirectory,
+
 		"raw_storage_endpoint": c.EnableRawEndpoint,
 
 		"api_addr":           c.APIAddr,
@@ -2,6 +2,8 @@ package server
 
 import (
 	"testing"
+
+	"github.com/hashicorp/vault/internalshared/configutil"
 )
 
 func TestLoadConfigFile(t *testing.T) {
@@ -44,6 +46,64 @@ func TestParseSeals(t *testing.T) {
 	testParseSeals(t)
 }
 
+func TestParseMultiSeals(t *testing.T) {
+	testParseMultiSeals(t)
+}
+
+func TestCheckConfig_Seals(t *testing.T) {
+	cases := map[string]struct {
+		seals []*configutil.KMS
+		err   bool
+	}{
+		"migration": {
+			seals: []*configutil.KMS{{Type: "awskms"}, {Type: "transit", Disabled: true}},
+		},
+		"multiple enabled": {
+			seals: []*configutil.KMS{{Type: "awskms", Name: "east"}, {Type: "awskms", Name: "west"}, {Type: "transit"}},
+		},
+		"multiple enabled and migration": {
+			seals: []*configutil.KMS{{Type: "awskms"}, {Type: "gcpckms"}, {Type: "transit", Disabled: true}},
+		},
+		"all disabled": {
+			seals: []*configutil.KMS{{Type: "awskms", Disabled: true}, {Type: "transit", Disabled: true}},
+			err:   true,
+		},
+		"multiple disabled": {
+			seals: []*configutil.KMS{{Type: "awskms"}, {Type: "gcpckms", Disabled: true}, {Type: "transit", Disabled: true}},
+			err:   true,
+		},
+		"duplicate names": {
+			seals: []*configutil.KMS{{Type: "awskms"}, {Type: "awskms"}},
+			err:   true,
+		},
+		"duplicate explicit names": {
+			seals: []*configutil.KMS{{Type: "awskms", Name: "primary"}, {Type: "transit", Name: "primary"}},
+			err:   true,
+		},
+		"shamir with other seals": {
+			seals: []*configutil.KMS{{Type: "shamir"}, {Type: "transit"}},
+			err:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := &Config{
+				SharedConfig: &configutil.SharedConfig{
+					Seals: tc.seals,
+				},
+			}
+			_, err := CheckConfig(config, nil)
+			if tc.err && err == nil {
+				t.Fatal("expected error")
+			}
+			if !tc.err && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestUnknownFieldValidation(t *testing.T) {
 	testUnknownFieldValidation(t)
 }
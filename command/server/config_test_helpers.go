@@ -64,17 +64,19 @@ func testLoadConfigFile_topLevel(t *testing.T, entropy *configutil.Entropy) {
 			},
 
 			Telemetry: &configutil.Telemetry{
-				StatsdAddr:                  "bar",
-				StatsiteAddr:                "foo",
-				DisableHostname:             false,
-				DogStatsDAddr:               "127.0.0.1:7254",
-				DogStatsDTags:               []string{"tag_1:val_1", "tag_2:val_2"},
-				PrometheusRetentionTime:     30 * time.Second,
-				UsageGaugePeriod:            5 * time.Minute,
-				MaximumGaugeCardinality:     125,
-				LeaseMetricsEpsilon:         time.Hour,
-				NumLeaseMetricsTimeBuckets:  168,
-				LeaseMetricsNameSpaceLabels: false,
+				StatsdAddr:                     "bar",
+				StatsiteAddr:                   "foo",
+				DisableHostname:                false,
+				DogStatsDAddr:                  "127.0.0.1:7254",
+				DogStatsDTags:                  []string{"tag_1:val_1", "tag_2:val_2"},
+				PrometheusRetentionTime:        30 * time.Second,
+				UsageGaugePeriod:               5 * time.Minute,
+				MaximumGaugeCardinality:        125,
+				MaximumRequestMountCardinality: 100,
+				LeaseMetricsEpsilon:            time.Hour,
+				NumLeaseMetricsTimeBuckets:     168,
+				TraceSampleRate:                1,
+				LeaseMetricsNameSpaceLabels:    false,
 			},
 
 			DisableMlock: true,
@@ -183,6 +185,7 @@ func testLoadConfigFile_json2(t *testing.T, entropy *configutil.Entropy) {
 				DisableHostname:                    true,
 				UsageGaugePeriod:                   5 * time.Minute,
 				MaximumGaugeCardinality:            125,
+				MaximumRequestMountCardinality:     100,
 				CirconusAPIToken:                   "0",
 				CirconusAPIApp:                     "vault",
 				CirconusAPIURL:                     "http://api.circonus.com/v2",
@@ -199,6 +202,7 @@ func testLoadConfigFile_json2(t *testing.T, entropy *configutil.Entropy) {
 				PrometheusRetentionTime:            30 * time.Second,
 				LeaseMetricsEpsilon:                time.Hour,
 				NumLeaseMetricsTimeBuckets:         168,
+				TraceSampleRate:                    1,
 				LeaseMetricsNameSpaceLabels:        false,
 			},
 		},
@@ -379,18 +383,20 @@ func testLoadConfigFile(t *testing.T) {
 			},
 
 			Telemetry: &configutil.Telemetry{
-				StatsdAddr:                  "bar",
-				StatsiteAddr:                "foo",
-				DisableHostname:             false,
-				UsageGaugePeriod:            5 * time.Minute,
-				MaximumGaugeCardinality:     100,
-				DogStatsDAddr:               "127.0.0.1:7254",
-				DogStatsDTags:               []string{"tag_1:val_1", "tag_2:val_2"},
-				PrometheusRetentionTime:     configutil.PrometheusDefaultRetentionTime,
-				MetricsPrefix:               "myprefix",
-				LeaseMetricsEpsilon:         time.Hour,
-				NumLeaseMetricsTimeBuckets:  168,
-				LeaseMetricsNameSpaceLabels: false,
+				StatsdAddr:                     "bar",
+				StatsiteAddr:                   "foo",
+				DisableHostname:                false,
+				UsageGaugePeriod:               5 * time.Minute,
+				MaximumGaugeCardinality:        100,
+				MaximumRequestMountCardinality: 100,
+				DogStatsDAddr:                  "127.0.0.1:7254",
+				DogStatsDTags:                  []string{"tag_1:val_1", "tag_2:val_2"},
+				PrometheusRetentionTime:        configutil.PrometheusDefaultRetentionTime,
+				MetricsPrefix:                  "myprefix",
+				LeaseMetricsEpsilon:            time.Hour,
+				NumLeaseMetricsTimeBuckets:     168,
+				TraceSampleRate:                1,
+				LeaseMetricsNameSpaceLabels:    false,
 			},
 
 			DisableMlock: true,
@@ -526,6 +532,7 @@ func testLoadConfigFile_json(t *testing.T) {
 				DisableHostname:                    false,
 				UsageGaugePeriod:                   5 * time.Minute,
 				MaximumGaugeCardinality:            100,
+				MaximumRequestMountCardinality:     100,
 				CirconusAPIToken:                   "",
 				CirconusAPIApp:                     "",
 				CirconusAPIURL:                     "",
@@ -542,6 +549,7 @@ func testLoadConfigFile_json(t *testing.T) {
 				PrometheusRetentionTime:            configutil.PrometheusDefaultRetentionTime,
 				LeaseMetricsEpsilon:                time.Hour,
 				NumLeaseMetricsTimeBuckets:         168,
+				TraceSampleRate:                    1,
 				LeaseMetricsNameSpaceLabels:        false,
 			},
 
@@ -606,15 +614,17 @@ func testLoadConfigDir(t *testing.T) {
 			},
 
 			Telemetry: &configutil.Telemetry{
-				StatsiteAddr:                "qux",
-				StatsdAddr:                  "baz",
-				DisableHostname:             true,
-				UsageGaugePeriod:            5 * time.Minute,
-				MaximumGaugeCardinality:     100,
-				PrometheusRetentionTime:     configutil.PrometheusDefaultRetentionTime,
-				LeaseMetricsEpsilon:         time.Hour,
-				NumLeaseMetricsTimeBuckets:  168,
-				LeaseMetricsNameSpaceLabels: false,
+				StatsiteAddr:                   "qux",
+				StatsdAddr:                     "baz",
+				DisableHostname:                true,
+				UsageGaugePeriod:               5 * time.Minute,
+				MaximumGaugeCardinality:        100,
+				MaximumRequestMountCardinality: 100,
+				PrometheusRetentionTime:        configutil.PrometheusDefaultRetentionTime,
+				LeaseMetricsEpsilon:            time.Hour,
+				NumLeaseMetricsTimeBuckets:     168,
+				TraceSampleRate:                1,
+				LeaseMetricsNameSpaceLabels:    false,
 			},
 			ClusterName: "testcluster",
 		},
@@ -693,11 +703,12 @@ func testConfig_Sanitized(t *testing.T) {
 				"type": "tcp",
 			},
 		},
-		"log_format":       "",
-		"log_level":        "",
-		"max_lease_ttl":    10 * time.Hour,
-		"pid_file":         "./pidfile",
-		"plugin_directory": "",
+		"log_format":              "",
+		"log_level":               "",
+		"max_lease_ttl":           10 * time.Hour,
+		"pid_file":                "./pidfile",
+		"plugin_directory":        "",
+		"raft_snapshot_directory": "",
 		"seals": []interface{}{
 			map[string]interface{}{
 				"disabled": false,
@@ -716,6 +727,7 @@ func testConfig_Sanitized(t *testing.T) {
 		"telemetry": map[string]interface{}{
 			"usage_gauge_period":                     5 * time.Minute,
 			"maximum_gauge_cardinality":              100,
+			"maximum_request_mount_cardinality":      100,
 			"circonus_api_app":                       "",
 			"circonus_api_token":                     "",
 			"circonus_api_url":                       "",
@@ -743,6 +755,8 @@ func testConfig_Sanitized(t *testing.T) {
 			"lease_metrics_epsilon":                  time.Hour,
 			"num_lease_metrics_buckets":              168,
 			"add_lease_metrics_namespace_labels":     false,
+			"otlp_trace_endpoint":                    "",
+			"trace_sample_rate":                      1.0,
 		},
 	}
 
@@ -894,18 +908,20 @@ func testLoadConfigFileLeaseMetrics(t *testing.T) {
 			},
 
 			Telemetry: &configutil.Telemetry{
-				StatsdAddr:                  "bar",
-				StatsiteAddr:                "foo",
-				DisableHostname:             false,
-				UsageGaugePeriod:            5 * time.Minute,
-				MaximumGaugeCardinality:     100,
-				DogStatsDAddr:               "127.0.0.1:7254",
-				DogStatsDTags:               []string{"tag_1:val_1", "tag_2:val_2"},
-				PrometheusRetentionTime:     configutil.PrometheusDefaultRetentionTime,
-				MetricsPrefix:               "myprefix",
-				LeaseMetricsEpsilon:         time.Hour,
-				NumLeaseMetricsTimeBuckets:  2,
-				LeaseMetricsNameSpaceLabels: true,
+				StatsdAddr:                     "bar",
+				StatsiteAddr:                   "foo",
+				DisableHostname:                false,
+				UsageGaugePeriod:               5 * time.Minute,
+				MaximumGaugeCardinality:        100,
+				MaximumRequestMountCardinality: 100,
+				DogStatsDAddr:                  "127.0.0.1:7254",
+				DogStatsDTags:                  []string{"tag_1:val_1", "tag_2:val_2"},
+				PrometheusRetentionTime:        configutil.PrometheusDefaultRetentionTime,
+				MetricsPrefix:                  "myprefix",
+				LeaseMetricsEpsilon:            time.Hour,
+				NumLeaseMetricsTimeBuckets:     2,
+				TraceSampleRate:                1,
+				LeaseMetricsNameSpaceLabels:    true,
 			},
 
 			DisableMlock: true,
@@ -1000,3 +1016,56 @@ func testConfigRaftAutopilot(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+func testParseMultiSeals(t *testing.T) {
+	config, err := CheckConfig(LoadConfigFile("./test-fixtures/config_multi_seals.hcl"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	config.Listeners[0].RawConfig = nil
+
+	expected := &Config{
+		Storage: &Storage{
+			Type:   "consul",
+			Config: map[string]string{},
+		},
+		SharedConfig: &configutil.SharedConfig{
+			Listeners: []*configutil.Listener{
+				{
+					Type:    "tcp",
+					Address: "127.0.0.1:443",
+				},
+			},
+			Seals: []*configutil.KMS{
+				{
+					Type:     "awskms",
+					Name:     "aws-east",
+					Priority: 1,
+					Config: map[string]string{
+						"region":     "us-east-1",
+						"kms_key_id": "alias/vault-east",
+					},
+				},
+				{
+					Type:     "awskms",
+					Name:     "aws-west",
+					Priority: 2,
+					Config: map[string]string{
+						"region":     "us-west-2",
+						"kms_key_id": "alias/vault-west",
+					},
+				},
+				{
+					Type:     "transit",
+					Disabled: true,
+					Config: map[string]string{
+						"address":  "https://vault:8200",
+						"key_name": "autounseal",
+					},
+				},
+			},
+		},
+	}
+	config.Prune()
+	require.Equal(t, config, expected)
+}
@@ -0,0 +1,26 @@
+listener "tcp" {
+  address = "127.0.0.1:443"
+}
+
+backend "consul" {
+}
+
+seal "awskms" {
+  name = "aws-east"
+  priority = 1
+  region = "us-east-1"
+  kms_key_id = "alias/vault-east"
+}
+
+seal "awskms" {
+  name = "aws-west"
+  priority = "2"
+  region = "us-west-2"
+  kms_key_id = "alias/vault-west"
+}
+
+seal "transit" {
+  disabled = "true"
+  address = "https://vault:8200"
+  key_name = "autounseal"
+}
@@ -0,0 +1,177 @@
+package metricsutil
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HistogramDefinition declares a sample metric which is reported to
+// Prometheus as a histogram with the given buckets. The name does not include
+// the service name.
+type HistogramDefinition struct {
+	Name    []string
+	Buckets []float64
+}
+
+// RequestDurationBuckets are the buckets, in milliseconds, of the request
+// duration histograms
+var RequestDurationBuckets = []float64{1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// PrometheusHistograms are the sample metrics reported to Prometheus as
+// histograms by default. The other samples are reported as summaries.
+var PrometheusHistograms = []HistogramDefinition{
+	// The duration of the requests to each mount, which should be
+	// aggregated across the nodes of a cluster
+	{
+		Name:    []string{"core", "mount", "request"},
+		Buckets: RequestDurationBuckets,
+	},
+}
+
+// PrometheusHistogramOpts configures a PrometheusHistogramSink
+type PrometheusHistogramOpts struct {
+	// ServiceName is the prefix go-metrics adds to the metric names
+	ServiceName string
+
+	// Expiration is how long a histogram which is not observed is kept. It
+	// is never removed if zero.
+	Expiration time.Duration
+
+	// Registerer is where the histograms are registered, the default
+	// registerer if not set
+	Registerer prometheus.Registerer
+
+	// Histograms are the metrics reported as histograms,
+	// PrometheusHistograms if not set
+	Histograms []HistogramDefinition
+}
+
+// PrometheusHistogramSink wraps the go-metrics Prometheus sink, which reports
+// every sample as a summary. The samples of the metrics declared as histograms
+// are observed in Prometheus histograms instead, which unlike summaries can be
+// aggregated across nodes. All the other metrics are passed on to the wrapped
+// sink.
+type PrometheusHistogramSink struct {
+	metrics.MetricSink
+
+	buckets    map[string][]float64
+	expiration time.Duration
+
+	// histograms holds a *histogram for each name and set of labels
+	histograms sync.Map
+}
+
+type histogram struct {
+	prometheus.Histogram
+
+	lock      sync.Mutex
+	updatedAt time.Time
+}
+
+var _ metrics.MetricSink = (*PrometheusHistogramSink)(nil)
+
+// NewPrometheusHistogramSink wraps the Prometheus sink and registers the
+// histograms in the registerer
+func NewPrometheusHistogramSink(sink metrics.MetricSink, opts PrometheusHistogramOpts) (*PrometheusHistogramSink, error) {
+	defs := opts.Histograms
+	if defs == nil {
+		defs = PrometheusHistograms
+	}
+
+	s := &PrometheusHistogramSink{
+		MetricSink: sink,
+		buckets:    make(map[string][]float64, len(defs)),
+		expiration: opts.Expiration,
+	}
+	for _, def := range defs {
+		name := def.Name
+		if opts.ServiceName != "" {
+			name = append([]string{opts.ServiceName}, name...)
+		}
+		s.buckets[prometheusName(name)] = def.Buckets
+	}
+
+	reg := opts.Registerer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	return s, reg.Register(s)
+}
+
+var prometheusForbiddenChars = regexp.MustCompile("[ .=\\-/]")
+
+// prometheusName returns the Prometheus name of a metric, as named by the
+// go-metrics Prometheus sink
+func prometheusName(parts []string) string {
+	return prometheusForbiddenChars.ReplaceAllString(strings.Join(parts, "_"), "_")
+}
+
+func (s *PrometheusHistogramSink) AddSample(key []string, val float32) {
+	s.AddSampleWithLabels(key, val, nil)
+}
+
+func (s *PrometheusHistogramSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	name := prometheusName(key)
+	buckets, ok := s.buckets[name]
+	if !ok {
+		s.MetricSink.AddSampleWithLabels(key, val, labels)
+		return
+	}
+
+	id := name
+	constLabels := make(prometheus.Labels, len(labels))
+	for _, label := range labels {
+		id += fmt.Sprintf(";%s=%s", label.Name, label.Value)
+		constLabels[label.Name] = label.Value
+	}
+
+	v, ok := s.histograms.Load(id)
+	if !ok {
+		v, _ = s.histograms.LoadOrStore(id, &histogram{
+			Histogram: prometheus.NewHistogram(prometheus.HistogramOpts{
+				Name:        name,
+				Help:        name,
+				Buckets:     buckets,
+				ConstLabels: constLabels,
+			}),
+			updatedAt: time.Now(),
+		})
+	}
+	h := v.(*histogram)
+	h.Observe(float64(val))
+	h.lock.Lock()
+	h.updatedAt = time.Now()
+	h.lock.Unlock()
+}
+
+// Describe implements prometheus.Collector. The histograms are created as
+// samples are added, so the sink is registered as an unchecked collector by
+// not describing any metric.
+func (s *PrometheusHistogramSink) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector. Histograms which have not been
+// observed for longer than the expiration are removed.
+func (s *PrometheusHistogramSink) Collect(c chan<- prometheus.Metric) {
+	s.collectAtTime(c, time.Now())
+}
+
+func (s *PrometheusHistogramSink) collectAtTime(c chan<- prometheus.Metric, t time.Time) {
+	s.histograms.Range(func(k, v interface{}) bool {
+		h := v.(*histogram)
+		h.lock.Lock()
+		updatedAt := h.updatedAt
+		h.lock.Unlock()
+		if s.expiration != 0 && updatedAt.Add(s.expiration).Before(t) {
+			s.histograms.Delete(k)
+			return true
+		}
+		h.Collect(c)
+		return true
+	})
+}
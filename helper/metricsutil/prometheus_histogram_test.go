@@ -0,0 +1,77 @@
+package metricsutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/armon/go-metrics"
+	promsink "github.com/armon/go-metrics/prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestPrometheusHistogramSink(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	summarySink, err := promsink.NewPrometheusSinkFrom(promsink.PrometheusOpts{
+		Registerer: reg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink, err := NewPrometheusHistogramSink(summarySink, PrometheusHistogramOpts{
+		ServiceName: "vault",
+		Expiration:  time.Minute,
+		Registerer:  reg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := defaultMetrics(sink)
+
+	labels := []metrics.Label{{"mount_accessor", "kv_1234"}}
+	m.AddSampleWithLabels([]string{"vault", "core", "mount", "request"}, 3, labels)
+	m.AddSampleWithLabels([]string{"vault", "core", "mount", "request"}, 700, labels)
+	m.AddSampleWithLabels([]string{"vault", "core", "handle_request"}, 3, nil)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]string)
+	for _, family := range families {
+		types[family.GetName()] = family.GetType().String()
+		if family.GetName() != "vault_core_mount_request" {
+			continue
+		}
+
+		h := family.GetMetric()[0].GetHistogram()
+		if h.GetSampleCount() != 2 || h.GetSampleSum() != 703 {
+			t.Fatalf("bad histogram: %v", h)
+		}
+		for _, bucket := range h.GetBucket() {
+			var expected uint64
+			switch {
+			case bucket.GetUpperBound() >= 1000:
+				expected = 2
+			case bucket.GetUpperBound() >= 5:
+				expected = 1
+			}
+			if bucket.GetCumulativeCount() != expected {
+				t.Fatalf("expected %d samples up to %v, got %d", expected, bucket.GetUpperBound(), bucket.GetCumulativeCount())
+			}
+		}
+	}
+	if types["vault_core_mount_request"] != "HISTOGRAM" {
+		t.Fatalf("expected a histogram, got %v", types)
+	}
+	if types["vault_core_handle_request"] != "SUMMARY" {
+		t.Fatalf("expected a summary, got %v", types)
+	}
+
+	// Histograms which are no longer observed expire
+	c := make(chan prometheus.Metric, 10)
+	sink.collectAtTime(c, time.Now().Add(2*time.Minute))
+	close(c)
+	if len(c) != 0 {
+		t.Fatalf("expected the histogram to expire, got %d metrics", len(c))
+	}
+}
@@ -2,6 +2,7 @@ package metricsutil
 
 import (
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -26,6 +27,14 @@ type ClusterMetricSink struct {
 	MaxGaugeCardinality int
 	GaugeInterval       time.Duration
 
+	// MaxRequestMountCardinality is the maximum number of distinct mounts
+	// labelled in the request metrics; requests to other mounts are labelled
+	// with OverflowMountLabel. There is no limit if it is not set.
+	MaxRequestMountCardinality int
+
+	mountLabelsLock sync.Mutex
+	mountLabels     map[string]struct{}
+
 	// Sink is the go-metrics instance to send to.
 	Sink metrics.MetricSink
 
@@ -119,6 +128,49 @@ func (m *ClusterMetricSink) SetDefaultClusterName(clusterName string) {
 	}
 }
 
+// OverflowMountLabel is the mount label of the requests to the mounts beyond
+// the maximum request mount cardinality
+const OverflowMountLabel = "other"
+
+// MountAccessorLabel creates a "mount_accessor" metrics label for the given
+// mount accessor. Once MaxRequestMountCardinality distinct mount accessors
+// have been labelled, the label of any other mount is OverflowMountLabel,
+// until a labelled mount is removed with RemoveMountAccessorLabel.
+func (m *ClusterMetricSink) MountAccessorLabel(accessor string) metrics.Label {
+	if m.MaxRequestMountCardinality <= 0 {
+		return metrics.Label{"mount_accessor", accessor}
+	}
+
+	m.mountLabelsLock.Lock()
+	defer m.mountLabelsLock.Unlock()
+	if m.mountLabels == nil {
+		m.mountLabels = make(map[string]struct{})
+	}
+	if _, ok := m.mountLabels[accessor]; !ok {
+		if len(m.mountLabels) >= m.MaxRequestMountCardinality {
+			return metrics.Label{"mount_accessor", OverflowMountLabel}
+		}
+		m.mountLabels[accessor] = struct{}{}
+	}
+	return metrics.Label{"mount_accessor", accessor}
+}
+
+// RemoveMountAccessorLabel frees the label of the given mount accessor once
+// the mount is removed, so that another mount can be labelled in its place.
+func (m *ClusterMetricSink) RemoveMountAccessorLabel(accessor string) {
+	m.mountLabelsLock.Lock()
+	defer m.mountLabelsLock.Unlock()
+	delete(m.mountLabels, accessor)
+}
+
+// ResetMountAccessorLabels frees the labels of all the mount accessors, for
+// when the mount tables are unloaded.
+func (m *ClusterMetricSink) ResetMountAccessorLabels() {
+	m.mountLabelsLock.Lock()
+	defer m.mountLabelsLock.Unlock()
+	m.mountLabels = nil
+}
+
 // NamespaceLabel creates a metrics label for the given
 // Namespace: root is "root"; others are path with the
 // final '/' removed.
@@ -108,3 +108,39 @@ func TestClusterLabelPresent(t *testing.T) {
 		t.Error("Sample label", s.Labels, "does not include", clusterLabel)
 	}
 }
+
+func TestMountAccessorLabel_Cardinality(t *testing.T) {
+	clusterSink := BlackholeSink()
+	clusterSink.MaxRequestMountCardinality = 2
+
+	for _, accessor := range []string{"kv_1", "kv_2", "kv_1"} {
+		if l := clusterSink.MountAccessorLabel(accessor); l.Value != accessor {
+			t.Errorf("Label value %v, expected %v", l.Value, accessor)
+		}
+	}
+	if l := clusterSink.MountAccessorLabel("kv_3"); l.Value != OverflowMountLabel {
+		t.Errorf("Label value %v, expected %v", l.Value, OverflowMountLabel)
+	}
+	// Mounts which have already been labelled keep their label
+	if l := clusterSink.MountAccessorLabel("kv_2"); l.Value != "kv_2" {
+		t.Errorf("Label value %v, expected %v", l.Value, "kv_2")
+	}
+
+	// Removed mounts free their label for another mount
+	clusterSink.RemoveMountAccessorLabel("kv_1")
+	if l := clusterSink.MountAccessorLabel("kv_3"); l.Value != "kv_3" {
+		t.Errorf("Label value %v, expected %v", l.Value, "kv_3")
+	}
+	if l := clusterSink.MountAccessorLabel("kv_1"); l.Value != OverflowMountLabel {
+		t.Errorf("Label value %v, expected %v", l.Value, OverflowMountLabel)
+	}
+	clusterSink.ResetMountAccessorLabels()
+	if l := clusterSink.MountAccessorLabel("kv_1"); l.Value != "kv_1" {
+		t.Errorf("Label value %v, expected %v", l.Value, "kv_1")
+	}
+
+	clusterSink.MaxRequestMountCardinality = 0
+	if l := clusterSink.MountAccessorLabel("kv_3"); l.Name != "mount_accessor" || l.Value != "kv_3" {
+		t.Errorf("Label %v, expected mount_accessor=kv_3", l)
+	}
+}
@@ -145,5 +145,8 @@ func duoHandler(duoConfig *DuoConfig, duoAuthClient AuthClient, request *duoAuth
 		return logical.ErrorResponse(result.Response.Status_Msg), nil
 	}
 
+	// Users allowed by the preauth, e.g. through a bypass, did not verify a
+	// second factor
+	request.successResp.Auth.MFAValidated = true
 	return request.successResp, nil
 }
@@ -86,6 +86,45 @@ func TestDuoHandlerSuccess(t *testing.T) {
 	if resp != successResp {
 		t.Fatalf("Testing Duo authentication gave incorrect response (expected success, got: %v)", resp)
 	}
+	if !resp.Auth.MFAValidated {
+		t.Fatalf("Testing Duo authentication did not mark the login as MFA validated")
+	}
+}
+
+func TestDuoHandlerPreauthAllow(t *testing.T) {
+	PreauthData := &authapi.PreauthResult{}
+	preauthAllowJSON := `
+	{
+	  "Stat": "OK",
+	  "Response": {
+	    "Result": "allow",
+	    "Status_Msg": "Allowing unknown user"
+	  }
+	}`
+	jsonutil.DecodeJSON([]byte(preauthAllowJSON), PreauthData)
+	successResp := &logical.Response{
+		Auth: &logical.Auth{},
+	}
+	duoConfig := &DuoConfig{
+		UsernameFormat: "%s",
+	}
+	duoAuthClient := getDuoAuthClient(&MockClientData{
+		PreauthData: PreauthData,
+	})
+	resp, err := duoHandler(duoConfig, duoAuthClient, &duoAuthRequest{
+		successResp: successResp,
+		username:    "user",
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if resp != successResp {
+		t.Fatalf("Testing Duo authentication gave incorrect response (expected success, got: %v)", resp)
+	}
+	// Users allowed without a second factor are not MFA validated
+	if resp.Auth.MFAValidated {
+		t.Fatalf("Testing Duo authentication marked a bypassed login as MFA validated")
+	}
 }
 
 func TestDuoHandlerReject(t *testing.T) {
@@ -115,3 +115,58 @@ func DecryptBytes(encodedCrypt, privKey string) (*bytes.Buffer, error) {
 
 	return ptBuf, nil
 }
+
+// SignAndEncrypt signs the plaintext with the signer's private key, and
+// encrypts it with the base64-encoded public key of the recipient.
+func SignAndEncrypt(plaintext []byte, recipientKey string, signer *openpgp.Entity) ([]byte, error) {
+	entities, err := GetEntities([]string{recipientKey})
+	if err != nil {
+		return nil, err
+	}
+
+	ctBuf := bytes.NewBuffer(nil)
+	pt, err := openpgp.Encrypt(ctBuf, entities, signer, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up encryption for PGP message: %w", err)
+	}
+	if _, err := pt.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("error encrypting PGP message: %w", err)
+	}
+	if err := pt.Close(); err != nil {
+		return nil, fmt.Errorf("error encrypting PGP message: %w", err)
+	}
+	return ctBuf.Bytes(), nil
+}
+
+// DecryptAndVerify decrypts the message with the private key, and verifies
+// that it was signed by one of the signers. It returns the plaintext and the
+// fingerprint of the signer.
+func DecryptAndVerify(ciphertext []byte, privKey *openpgp.Entity, signers []*openpgp.Entity) ([]byte, string, error) {
+	keyring := openpgp.EntityList{privKey}
+	keyring = append(keyring, signers...)
+
+	md, err := openpgp.ReadMessage(bytes.NewBuffer(ciphertext), keyring, nil, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error decrypting the message: %w", err)
+	}
+	if !md.IsEncrypted || md.DecryptedWith.Entity != privKey {
+		return nil, "", fmt.Errorf("message is not encrypted with the expected key")
+	}
+	if !md.IsSigned {
+		return nil, "", fmt.Errorf("message is not signed")
+	}
+	if md.SignedBy == nil || md.SignedBy.Entity == privKey {
+		return nil, "", fmt.Errorf("message is not signed by a known key")
+	}
+
+	ptBuf := bytes.NewBuffer(nil)
+	if _, err := ptBuf.ReadFrom(md.UnverifiedBody); err != nil {
+		return nil, "", fmt.Errorf("error reading the message: %w", err)
+	}
+	// The signature is only checked once the whole body has been read
+	if md.SignatureError != nil {
+		return nil, "", fmt.Errorf("invalid message signature: %w", md.SignatureError)
+	}
+
+	return ptBuf.Bytes(), fmt.Sprintf("%x", md.SignedBy.Entity.PrimaryKey.Fingerprint), nil
+}
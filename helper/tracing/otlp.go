@@ -0,0 +1,257 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	cleanhttp "github.com/hashicorp/go-cleanhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// otlpExporter exports spans to an OpenTelemetry collector using the OTLP/HTTP
+// protocol with JSON encoding.
+type otlpExporter struct {
+	url    string
+	client *http.Client
+}
+
+var _ sdktrace.SpanExporter = (*otlpExporter)(nil)
+
+func newOTLPExporter(endpoint string) *otlpExporter {
+	client := cleanhttp.DefaultPooledClient()
+	client.Timeout = 10 * time.Second
+	return &otlpExporter{
+		url:    strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		client: client,
+	}
+}
+
+// ExportSpans sends the spans to the collector
+func (e *otlpExporter) ExportSpans(ctx context.Context, spans []*sdktrace.SpanSnapshot) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(otlpRequestFromSpans(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to export spans: collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Shutdown releases the idle connections to the collector
+func (e *otlpExporter) Shutdown(context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// The types below are the JSON mapping of the OTLP trace protobuf messages.
+
+type otlpRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+// OTLP status codes, which differ from the ones of the codes package
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+// otlpRequestFromSpans groups the spans by resource and instrumentation
+// library, as expected by the collector.
+func otlpRequestFromSpans(spans []*sdktrace.SpanSnapshot) *otlpRequest {
+	req := &otlpRequest{}
+	resources := make(map[attribute.Distinct]*otlpResourceSpans)
+	scopes := make(map[attribute.Distinct]map[string]*otlpScopeSpans)
+
+	for _, s := range spans {
+		var key attribute.Distinct
+		var resAttrs []attribute.KeyValue
+		if s.Resource != nil {
+			key = s.Resource.Equivalent()
+			resAttrs = s.Resource.Attributes()
+		}
+		rs, ok := resources[key]
+		if !ok {
+			rs = &otlpResourceSpans{
+				Resource: otlpResource{Attributes: otlpAttributes(resAttrs)},
+			}
+			resources[key] = rs
+			scopes[key] = make(map[string]*otlpScopeSpans)
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+		}
+
+		lib := s.InstrumentationLibrary
+		ss, ok := scopes[key][lib.Name+"@"+lib.Version]
+		if !ok {
+			ss = &otlpScopeSpans{
+				Scope: otlpScope{Name: lib.Name, Version: lib.Version},
+			}
+			scopes[key][lib.Name+"@"+lib.Version] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, otlpSpanFromSnapshot(s))
+	}
+
+	return req
+}
+
+func otlpSpanFromSnapshot(s *sdktrace.SpanSnapshot) *otlpSpan {
+	span := &otlpSpan{
+		TraceID:           s.SpanContext.TraceID().String(),
+		SpanID:            s.SpanContext.SpanID().String(),
+		Name:              s.Name,
+		Kind:              int(s.SpanKind),
+		StartTimeUnixNano: strconv.FormatInt(s.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.EndTime.UnixNano(), 10),
+		Attributes:        otlpAttributes(s.Attributes),
+		Status:            otlpStatus{Code: otlpStatusUnset},
+	}
+	if s.Parent.SpanID().IsValid() {
+		span.ParentSpanID = s.Parent.SpanID().String()
+	}
+
+	switch s.StatusCode {
+	case codes.Error:
+		span.Status = otlpStatus{Code: otlpStatusError, Message: s.StatusMessage}
+	case codes.Ok:
+		span.Status = otlpStatus{Code: otlpStatusOk}
+	}
+
+	for _, e := range s.MessageEvents {
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: strconv.FormatInt(e.Time.UnixNano(), 10),
+			Name:         e.Name,
+			Attributes:   otlpAttributes(e.Attributes),
+		})
+	}
+
+	return span
+}
+
+func otlpAttributes(attrs []attribute.KeyValue) []otlpKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	ret := make([]otlpKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		ret = append(ret, otlpKeyValue{
+			Key:   string(kv.Key),
+			Value: otlpValue(kv.Value),
+		})
+	}
+	return ret
+}
+
+func otlpValue(v attribute.Value) otlpAnyValue {
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		return otlpAnyValue{BoolValue: &b}
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		return otlpAnyValue{IntValue: &i}
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		return otlpAnyValue{DoubleValue: &f}
+	case attribute.ARRAY:
+		arr := &otlpArrayValue{}
+		rv := reflect.ValueOf(v.AsArray())
+		for i := 0; rv.Kind() == reflect.Array && i < rv.Len(); i++ {
+			arr.Values = append(arr.Values, otlpValue(attribute.Any("", rv.Index(i).Interface()).Value))
+		}
+		return otlpAnyValue{ArrayValue: arr}
+	default:
+		s := v.Emit()
+		return otlpAnyValue{StringValue: &s}
+	}
+}
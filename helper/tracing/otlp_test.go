@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan *otlpRequest, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("bad content type: %q", ct)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Error(err)
+		}
+		requests <- &req
+	}))
+	defer ts.Close()
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(newOTLPExporter(ts.URL + "/")))
+	defer tp.Shutdown(context.Background())
+	tracer := tp.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := tracer.Start(ctx, "child", trace.WithAttributes(
+		attribute.String("string", "value"),
+		attribute.Int("int", 42),
+		attribute.Bool("bool", true),
+		attribute.Array("array", []string{"a b", "c"}),
+	))
+	EndSpan(child, errors.New("child failed"))
+
+	req := <-requests
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("bad request: %#v", req)
+	}
+	scope := req.ResourceSpans[0].ScopeSpans[0]
+	if scope.Scope.Name != "test" || len(scope.Spans) != 1 {
+		t.Fatalf("bad scope spans: %#v", scope)
+	}
+	span := scope.Spans[0]
+	if span.Name != "child" || span.Kind != int(trace.SpanKindInternal) {
+		t.Fatalf("bad span: %#v", span)
+	}
+	if span.TraceID != parent.SpanContext().TraceID().String() || span.ParentSpanID != parent.SpanContext().SpanID().String() {
+		t.Fatalf("bad span parent: %#v", span)
+	}
+	if span.Status.Code != otlpStatusError || span.Status.Message != "child failed" {
+		t.Fatalf("bad status: %#v", span.Status)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+		t.Fatalf("bad events: %#v", span.Events)
+	}
+
+	attrs := make(map[string]otlpAnyValue)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if v := attrs["string"].StringValue; v == nil || *v != "value" {
+		t.Fatalf("bad string attribute: %#v", attrs["string"])
+	}
+	if v := attrs["int"].IntValue; v == nil || *v != "42" {
+		t.Fatalf("bad int attribute: %#v", attrs["int"])
+	}
+	if v := attrs["bool"].BoolValue; v == nil || !*v {
+		t.Fatalf("bad bool attribute: %#v", attrs["bool"])
+	}
+	if v := attrs["array"].ArrayValue; v == nil || len(v.Values) != 2 || *v.Values[0].StringValue != "a b" {
+		t.Fatalf("bad array attribute: %#v", attrs["array"])
+	}
+
+	parent.End()
+	req = <-requests
+	span = req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.Name != "parent" || span.ParentSpanID != "" || span.Kind != int(trace.SpanKindServer) || span.Status.Code != otlpStatusUnset {
+		t.Fatalf("bad span: %#v", span)
+	}
+}
+
+func TestOTLPExporter_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	e := newOTLPExporter(ts.URL)
+	if err := e.ExportSpans(context.Background(), []*sdktrace.SpanSnapshot{{Name: "span"}}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestSetup_InvalidConfig(t *testing.T) {
+	for _, conf := range []*Config{
+		{Endpoint: "127.0.0.1:4318", SampleRate: 1},
+		{Endpoint: "grpc://127.0.0.1:4317", SampleRate: 1},
+		{Endpoint: "http://127.0.0.1:4318", SampleRate: 2},
+	} {
+		if _, err := Setup(conf); err == nil {
+			t.Fatalf("expected error for %#v", conf)
+		}
+	}
+}
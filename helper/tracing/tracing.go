@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/hashicorp/vault"
+
+// Config configures the export of the traces
+type Config struct {
+	// Endpoint is the base URL of the OTLP/HTTP receiver of the collector,
+	// e.g. http://127.0.0.1:4318
+	Endpoint string
+
+	// SampleRate is the fraction of the traces started by Vault that are
+	// sampled. Requests which are part of a trace started by the client
+	// follow the sampling decision of the client.
+	SampleRate float64
+
+	Logger log.Logger
+}
+
+type errorHandler struct {
+	logger log.Logger
+}
+
+func (h *errorHandler) Handle(err error) {
+	h.logger.Warn("tracing error", "error", err)
+}
+
+// Setup installs the global tracer provider exporting the traces to the
+// collector, and the propagator of the W3C trace context. The returned
+// function flushes the pending spans and shuts the tracer provider down.
+func Setup(conf *Config) (func(context.Context) error, error) {
+	u, err := url.Parse(conf.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP trace endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid OTLP trace endpoint %q: scheme must be http or https", conf.Endpoint)
+	}
+	if conf.SampleRate < 0 || conf.SampleRate > 1 {
+		return nil, fmt.Errorf("invalid trace sample rate %v: must be between 0 and 1", conf.SampleRate)
+	}
+
+	logger := conf.Logger
+	if logger == nil {
+		logger = log.NewNullLogger()
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(newOTLPExporter(conf.Endpoint)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRate))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.ServiceNameKey.String("vault"),
+			semconv.ServiceVersionKey.String(version.GetVersion().VersionNumber()),
+		)),
+	)
+
+	otel.SetErrorHandler(&errorHandler{logger: logger})
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// StartSpan starts a span with the global tracer provider. It is a no-op
+// unless tracing has been set up.
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// EndSpan records the error, if any, and ends the span
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
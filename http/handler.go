@@ -24,6 +24,7 @@ import (
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-sockaddr"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/helper/tracing"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
@@ -31,6 +32,10 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/pathmanager"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/semconv"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -106,9 +111,14 @@ var (
 )
 
 func init() {
+	perfStandbyAlwaysForwardPaths.AddPaths([]string{
+		"sys/storage/raft/*",
+		"sys/internal/counters/*",
+	})
 	alwaysRedirectPaths.AddPaths([]string{
 		"sys/storage/raft/snapshot",
 		"sys/storage/raft/snapshot-force",
+		"sys/storage/raft/snapshot-restore-subtree",
 	})
 }
 
@@ -140,6 +150,7 @@ func Handler(props *vault.HandlerProperties) http.Handler {
 		mux.Handle("/v1/sys/leader", handleSysLeader(core))
 		mux.Handle("/v1/sys/health", handleSysHealth(core))
 		mux.Handle("/v1/sys/monitor", handleLogicalNoForward(core))
+		mux.Handle("/v1/sys/events/subscribe", handleLogicalNoForward(core))
 		mux.Handle("/v1/sys/generate-root/attempt", handleRequestForwarding(core,
 			handleAuditNonLogical(core, handleSysGenerateRootAttempt(core, vault.GenerateStandardRootTokenStrategy))))
 		mux.Handle("/v1/sys/generate-root/update", handleRequestForwarding(core,
@@ -239,6 +250,24 @@ func (w *copyResponseWriter) WriteHeader(code int) {
 	w.wrapped.WriteHeader(code)
 }
 
+// statusResponseWriter records the status code of the response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush allows streaming responses through the writer
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func handleAuditNonLogical(core *vault.Core, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origBody := new(bytes.Buffer)
@@ -306,8 +335,9 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 		// Start with the request context
 		ctx := r.Context()
 		var cancelFunc context.CancelFunc
-		// Add our timeout, but not for the monitor endpoint, as it's streaming
-		if strings.HasSuffix(r.URL.Path, "sys/monitor") {
+		// Add our timeout, but not for the monitor and events endpoints, as
+		// they're streaming
+		if strings.HasSuffix(r.URL.Path, "sys/monitor") || strings.HasSuffix(r.URL.Path, "sys/events/subscribe") {
 			ctx, cancelFunc = context.WithCancel(ctx)
 		} else {
 			ctx, cancelFunc = context.WithTimeout(ctx, maxRequestDuration)
@@ -318,6 +348,24 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 			ctx = context.WithValue(ctx, "max_request_size", maxRequestSize)
 		}
 		ctx = context.WithValue(ctx, "original_request_path", r.URL.Path)
+
+		// Continue the trace of the client, if any
+		ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.StartSpan(ctx, "http.request",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPMethodKey.String(r.Method)))
+		defer span.End()
+		if span.IsRecording() {
+			span.SetAttributes(semconv.HTTPRouteKey.String(traceRoute(core, r)))
+
+			sw := &statusResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() {
+				span.SetAttributes(semconv.HTTPStatusCodeKey.Int(sw.statusCode))
+				span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(sw.statusCode))
+			}()
+			w = sw
+		}
+
 		r = r.WithContext(ctx)
 		r = r.WithContext(namespace.ContextWithNamespace(r.Context(), namespace.RootNamespace))
 
@@ -357,6 +405,22 @@ func wrapGenericHandler(core *vault.Core, h http.Handler, props *vault.HandlerPr
 	})
 }
 
+// traceRoute returns the route of the request recorded in its span: the mount
+// handling it rather than its path, which may hold sensitive data such as the
+// name of a secret or a token, and which the audit log only records HMAC-ed.
+func traceRoute(core *vault.Core, r *http.Request) string {
+	path, ok := stripPrefix("/v1/", r.URL.Path)
+	if !ok {
+		// UI and other non-API paths are reduced to their first segment
+		segment := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+		if segment == "" {
+			return "/"
+		}
+		return "/" + segment + "/"
+	}
+	return "/v1/" + core.MatchingMount(namespace.RootContext(r.Context()), path)
+}
+
 func WrapForwardedForHandler(h http.Handler, l *configutil.Listener) http.Handler {
 	rejectNotPresent := l.XForwardedForRejectNotPresent
 	hopSkips := l.XForwardedForHopSkips
@@ -572,6 +572,25 @@ func TestHandler_sealed(t *testing.T) {
 	testResponseStatus(t, resp, 503)
 }
 
+func TestHandler_traceRoute(t *testing.T) {
+	core, _, _ := vault.TestCoreUnsealed(t)
+
+	cases := map[string]string{
+		"/v1/secret/foo/bar":                "/v1/secret/",
+		"/v1/auth/token/lookup/s.abcdef":    "/v1/auth/token/",
+		"/v1/sys/leases/lookup/secret/foo":  "/v1/sys/",
+		"/v1/nomount/foo":                   "/v1/",
+		"/ui/vault/secrets/secret/show/foo": "/ui/",
+		"/":                                 "/",
+	}
+	for path, expected := range cases {
+		r := httptest.NewRequest("GET", path, nil)
+		if route := traceRoute(core, r); route != expected {
+			t.Fatalf("%s: expected %q, got %q", path, expected, route)
+		}
+	}
+}
+
 func TestHandler_ui_default(t *testing.T) {
 	core := vault.TestCoreUI(t, false)
 	ln, addr := TestServer(t, core)
@@ -89,6 +89,19 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 		case path == "sys/monitor":
 			passHTTPReq = true
 			responseWriter = w
+		case path == "sys/events/subscribe":
+			passHTTPReq = true
+			responseWriter = w
+			// Reconnecting server-sent event clients send the ID of the last
+			// event they received as a header
+			if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+				if data == nil {
+					data = make(map[string]interface{})
+				}
+				if _, ok := data["last_event_id"]; !ok {
+					data["last_event_id"] = lastEventID
+				}
+			}
 		}
 
 	case "POST", "PUT":
@@ -102,7 +115,7 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 		// If we are uploading a snapshot we don't want to parse it. Instead
 		// we will simply add the HTTP request to the logical request object
 		// for later consumption.
-		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" {
+		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || path == "sys/storage/raft/snapshot-restore-subtree" {
 			passHTTPReq = true
 			origBody = r.Body
 		} else {
@@ -171,6 +171,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 			"expire_time":      nil,
 			"entity_id":        "",
 			"type":             "service",
+			"use_count":        json.Number("1"),
 		},
 		"warnings":  nilWarnings,
 		"wrap_info": nil,
@@ -182,6 +183,7 @@ func TestLogical_StandbyRedirect(t *testing.T) {
 	actualDataMap := actual["data"].(map[string]interface{})
 	delete(actualDataMap, "creation_time")
 	delete(actualDataMap, "accessor")
+	delete(actualDataMap, "last_used_time")
 	actual["data"] = actualDataMap
 	expected["request_id"] = actual["request_id"]
 	delete(actual, "lease_id")
@@ -46,6 +46,7 @@ func TestSysConfigState_Sanitized(t *testing.T) {
 		"max_lease_ttl":                       json.Number("0"),
 		"pid_file":                            "",
 		"plugin_directory":                    "",
+		"raft_snapshot_directory":             "",
 		"enable_response_header_hostname":     false,
 		"enable_response_header_raft_node_id": false,
 	}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestSysEventsSubscribe(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{HandlerFunc: Handler})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	if err := client.Sys().Mount("kv", &api.MountInput{Type: "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	err := client.Sys().PutPolicy("events", `
+path "sys/events/subscribe" {
+	capabilities = ["read"]
+}
+path "kv/allowed/*" {
+	capabilities = ["read"]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"events"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subClient.SetToken(secret.Auth.ClientToken)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	request := subClient.NewRequest("GET", "/v1/sys/events/subscribe")
+	request.Params.Add("types", "secret.*")
+	resp, err := subClient.RawRequestWithContext(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	// The token cannot read the first secret, so only the second one is
+	// delivered
+	if _, err := client.Logical().Write("kv/denied/foo", map[string]interface{}{"bar": "baz"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("kv/allowed/foo", map[string]interface{}{"bar": "baz"}); err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var eventType, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" && data != "" {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if eventType != string(vault.EventSecretWritten) {
+		t.Fatalf("unexpected event type %q", eventType)
+	}
+	var ev vault.Event
+	if err := json.Unmarshal([]byte(data), &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.Path != "kv/allowed/foo" || ev.Data["mount_path"] != "kv/" {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+}
+
+func TestSysEventsSubscribe_PermissionDenied(t *testing.T) {
+	cluster := vault.NewTestCluster(t, nil, &vault.TestClusterOptions{HandlerFunc: Handler})
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	secret, err := client.Auth().Token().Create(&api.TokenCreateRequest{
+		Policies: []string{"default"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SetToken(secret.Auth.ClientToken)
+
+	_, err = client.RawRequest(client.NewRequest("GET", "/v1/sys/events/subscribe"))
+	if err == nil || !strings.Contains(err.Error(), "Code: 403") {
+		t.Fatalf("expected a permission denied error, got %v", err)
+	}
+}
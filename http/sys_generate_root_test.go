@@ -350,6 +350,7 @@ func TestSysGenerateRoot_Update_OTP(t *testing.T) {
 		"expire_time":      nil,
 		"entity_id":        "",
 		"type":             "service",
+		"use_count":        json.Number("1"),
 	}
 
 	resp = testHttpGet(t, newRootToken, addr+"/v1/auth/token/lookup-self")
@@ -358,6 +359,7 @@ func TestSysGenerateRoot_Update_OTP(t *testing.T) {
 
 	expected["creation_time"] = actual["data"].(map[string]interface{})["creation_time"]
 	expected["accessor"] = actual["data"].(map[string]interface{})["accessor"]
+	expected["last_used_time"] = actual["data"].(map[string]interface{})["last_used_time"]
 
 	if !reflect.DeepEqual(actual["data"], expected) {
 		t.Fatalf("\nexpected: %#v\nactual: %#v", expected, actual["data"])
@@ -453,6 +455,7 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 		"expire_time":      nil,
 		"entity_id":        "",
 		"type":             "service",
+		"use_count":        json.Number("1"),
 	}
 
 	resp = testHttpGet(t, newRootToken, addr+"/v1/auth/token/lookup-self")
@@ -461,6 +464,7 @@ func TestSysGenerateRoot_Update_PGP(t *testing.T) {
 
 	expected["creation_time"] = actual["data"].(map[string]interface{})["creation_time"]
 	expected["accessor"] = actual["data"].(map[string]interface{})["accessor"]
+	expected["last_used_time"] = actual["data"].(map[string]interface{})["last_used_time"]
 
 	if diff := deep.Equal(actual["data"], expected); diff != nil {
 		t.Fatal(diff)
@@ -50,6 +50,7 @@ func handleSysInitPut(core *vault.Core, w http.ResponseWriter, r *http.Request)
 		SecretThreshold: req.SecretThreshold,
 		StoredShares:    req.StoredShares,
 		PGPKeys:         req.PGPKeys,
+		SignedUnseal:    req.SignedUnseal,
 	}
 
 	recoveryConfig := &vault.SealConfig{
@@ -115,6 +116,7 @@ type InitRequest struct {
 	RecoveryThreshold int      `json:"recovery_threshold"`
 	RecoveryPGPKeys   []string `json:"recovery_pgp_keys"`
 	RootTokenPGPKey   string   `json:"root_token_pgp_key"`
+	SignedUnseal      bool     `json:"signed_unseal"`
 }
 
 type InitResponse struct {
@@ -1378,6 +1378,49 @@ func TestSysTuneMount_nonHMACKeys(t *testing.T) {
 	}
 }
 
+func TestSysTuneMount_auditFieldSelectors(t *testing.T) {
+	core, _, token := vault.TestCoreUnsealed(t)
+	ln, addr := TestServer(t, core)
+	defer ln.Close()
+	TestServerAuth(t, addr, token)
+
+	// Invalid selectors are rejected
+	resp := testHttpPost(t, token, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"audit_field_selectors": []string{"/response/data/password=hide"},
+	})
+	testResponseStatus(t, resp, 400)
+
+	resp = testHttpPost(t, token, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"audit_field_selectors": []string{"/request/data/user=plaintext", "/response/data/password=drop"},
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/mounts/secret/tune")
+	testResponseStatus(t, resp, 200)
+
+	var actual map[string]interface{}
+	testResponseBody(t, resp, &actual)
+	expected := []interface{}{"/request/data/user=plaintext", "/response/data/password=drop"}
+	if !reflect.DeepEqual(actual["audit_field_selectors"], expected) {
+		t.Fatalf("bad:\nExpected: %#v\nActual:%#v", expected, actual["audit_field_selectors"])
+	}
+
+	// Unset the selectors
+	resp = testHttpPost(t, token, addr+"/v1/sys/mounts/secret/tune", map[string]interface{}{
+		"audit_field_selectors": "",
+	})
+	testResponseStatus(t, resp, 204)
+
+	resp = testHttpGet(t, token, addr+"/v1/sys/mounts/secret/tune")
+	testResponseStatus(t, resp, 200)
+
+	actual = nil
+	testResponseBody(t, resp, &actual)
+	if _, ok := actual["audit_field_selectors"]; ok {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
 func TestSysTuneMount_listingVisibility(t *testing.T) {
 	core, _, token := vault.TestCoreUnsealed(t)
 	ln, addr := TestServer(t, core)
@@ -92,6 +92,7 @@ func handleSysRekeyInitGet(ctx context.Context, core *vault.Core, recovery bool,
 		status.Progress = progress
 		status.VerificationRequired = rekeyConf.VerificationRequired
 		status.VerificationNonce = rekeyConf.VerificationNonce
+		status.SignedUnseal = rekeyConf.SignedUnseal
 		if rekeyConf.PGPKeys != nil && len(rekeyConf.PGPKeys) != 0 {
 			pgpFingerprints, err := pgpkeys.GetFingerprints(rekeyConf.PGPKeys, nil)
 			if err != nil {
@@ -131,6 +132,7 @@ func handleSysRekeyInitPut(ctx context.Context, core *vault.Core, recovery bool,
 		PGPKeys:              req.PGPKeys,
 		Backup:               req.Backup,
 		VerificationRequired: req.RequireVerification,
+		SignedUnseal:         req.SignedUnseal,
 	}, recovery)
 	if err != nil {
 		respondError(w, err.Code(), err)
@@ -361,6 +363,7 @@ type RekeyRequest struct {
 	PGPKeys             []string `json:"pgp_keys"`
 	Backup              bool     `json:"backup"`
 	RequireVerification bool     `json:"require_verification"`
+	SignedUnseal        bool     `json:"signed_unseal"`
 }
 
 type RekeyStatusResponse struct {
@@ -374,6 +377,7 @@ type RekeyStatusResponse struct {
 	Backup               bool     `json:"backup"`
 	VerificationRequired bool     `json:"verification_required"`
 	VerificationNonce    string   `json:"verification_nonce,omitempty"`
+	SignedUnseal         bool     `json:"signed_unseal,omitempty"`
 }
 
 type RekeyUpdateRequest struct {
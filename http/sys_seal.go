@@ -99,35 +99,63 @@ func handleSysUnseal(core *vault.Core) http.Handler {
 			return
 		}
 
-		if req.Key == "" {
-			respondError(
-				w, http.StatusBadRequest,
-				errors.New("'key' must be specified in request body as JSON, or 'reset' set to true"))
-			return
-		}
+		var err error
+		switch {
+		case req.SignedKey != "":
+			if req.Key != "" {
+				respondError(
+					w, http.StatusBadRequest,
+					errors.New("only one of 'key' and 'signed_key' can be specified"))
+				return
+			}
 
-		// Decode the key, which is base64 or hex encoded
-		min, max := core.BarrierKeyLength()
-		key, err := hex.DecodeString(req.Key)
-		// We check min and max here to ensure that a string that is base64
-		// encoded but also valid hex will not be valid and we instead base64
-		// decode it
-		if err != nil || len(key) < min || len(key) > max {
-			key, err = base64.StdEncoding.DecodeString(req.Key)
+			// The signed key is a base64 encoded PGP message
+			var signedKey []byte
+			signedKey, err = base64.StdEncoding.DecodeString(req.SignedKey)
 			if err != nil {
 				respondError(
 					w, http.StatusBadRequest,
-					errors.New("'key' must be a valid hex or base64 string"))
+					errors.New("'signed_key' must be a valid base64 string"))
 				return
 			}
-		}
 
-		// Attempt the unseal.  If migrate was specified, the key should correspond
-		// to the old seal.
-		if req.Migrate {
-			_, err = core.UnsealMigrate(key)
-		} else {
-			_, err = core.Unseal(key)
+			if req.Migrate {
+				_, err = core.UnsealSignedMigrate(signedKey)
+			} else {
+				_, err = core.UnsealSigned(signedKey)
+			}
+
+		case req.Key != "":
+			// Decode the key, which is base64 or hex encoded
+			min, max := core.BarrierKeyLength()
+			var key []byte
+			key, err = hex.DecodeString(req.Key)
+			// We check min and max here to ensure that a string that is base64
+			// encoded but also valid hex will not be valid and we instead base64
+			// decode it
+			if err != nil || len(key) < min || len(key) > max {
+				key, err = base64.StdEncoding.DecodeString(req.Key)
+				if err != nil {
+					respondError(
+						w, http.StatusBadRequest,
+						errors.New("'key' must be a valid hex or base64 string"))
+					return
+				}
+			}
+
+			// Attempt the unseal.  If migrate was specified, the key should correspond
+			// to the old seal.
+			if req.Migrate {
+				_, err = core.UnsealMigrate(key)
+			} else {
+				_, err = core.Unseal(key)
+			}
+
+		default:
+			respondError(
+				w, http.StatusBadRequest,
+				errors.New("'key' or 'signed_key' must be specified in request body as JSON, or 'reset' set to true"))
+			return
 		}
 		if err != nil {
 			switch {
@@ -175,7 +203,8 @@ func handleSysSealStatusRaw(core *vault.Core, w http.ResponseWriter, r *http.Req
 // now because if it then no longer accepts capitalized versions it could break
 // clients
 type UnsealRequest struct {
-	Key     string
-	Reset   bool
-	Migrate bool
+	Key       string
+	SignedKey string `json:"signed_key"`
+	Reset     bool
+	Migrate   bool
 }
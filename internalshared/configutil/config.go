@@ -104,7 +104,7 @@ func ParseConfig(d string) (*SharedConfig, error) {
 	}
 
 	if o := list.Filter("seal"); len(o.Items) > 0 {
-		if err := parseKMS(&result.Seals, o, "seal", 3); err != nil {
+		if err := parseKMS(&result.Seals, o, "seal", maxSeals); err != nil {
 			return nil, fmt.Errorf("error parsing 'seal': %w", err)
 		}
 	}
@@ -201,6 +201,7 @@ func (c *SharedConfig) Sanitized() map[string]interface{} {
 			"metrics_prefix":                         c.Telemetry.MetricsPrefix,
 			"usage_gauge_period":                     c.Telemetry.UsageGaugePeriod,
 			"maximum_gauge_cardinality":              c.Telemetry.MaximumGaugeCardinality,
+			"maximum_request_mount_cardinality":      c.Telemetry.MaximumRequestMountCardinality,
 			"circonus_api_token":                     "",
 			"circonus_api_app":                       c.Telemetry.CirconusAPIApp,
 			"circonus_api_url":                       c.Telemetry.CirconusAPIURL,
@@ -224,6 +225,8 @@ func (c *SharedConfig) Sanitized() map[string]interface{} {
 			"lease_metrics_epsilon":                  c.Telemetry.LeaseMetricsEpsilon,
 			"num_lease_metrics_buckets":              c.Telemetry.NumLeaseMetricsTimeBuckets,
 			"add_lease_metrics_namespace_labels":     c.Telemetry.LeaseMetricsNameSpaceLabels,
+			"otlp_trace_endpoint":                    c.Telemetry.OTLPTraceEndpoint,
+			"trace_sample_rate":                      c.Telemetry.TraceSampleRate,
 		}
 		result["telemetry"] = sanitizedTelemetry
 	}
@@ -21,6 +21,7 @@ import (
 	"github.com/hashicorp/hcl/hcl/ast"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/seal/pkcs11"
 )
 
 var (
@@ -28,6 +29,10 @@ var (
 	CreateSecureRandomReaderFunc = createSecureRandomReader
 )
 
+// maxSeals is the maximum number of seal blocks, which allows several enabled
+// seals and a disabled seal to migrate from
+const maxSeals = 8
+
 // Entropy contains Entropy configuration for the server
 type EntropyMode int
 
@@ -50,7 +55,21 @@ type KMS struct {
 	Purpose []string `hcl:"-"`
 
 	Disabled bool
-	Config   map[string]string
+
+	// Name and Priority identify and order the seals when more than one
+	// enabled seal is configured
+	Name     string
+	Priority int
+
+	Config map[string]string
+}
+
+// SealName returns the name of the seal, which defaults to its type
+func (k *KMS) SealName() string {
+	if k.Name != "" {
+		return k.Name
+	}
+	return k.Type
 }
 
 func (k *KMS) GoString() string {
@@ -59,7 +78,7 @@ func (k *KMS) GoString() string {
 
 func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int) error {
 	if len(list.Items) > maxKMS {
-		return fmt.Errorf("only two or less %q blocks are permitted", blockName)
+		return fmt.Errorf("only %d or less %q blocks are permitted", maxKMS, blockName)
 	}
 
 	seals := make([]*KMS, 0, len(list.Items))
@@ -98,6 +117,25 @@ func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int
 			delete(m, "disabled")
 		}
 
+		var name string
+		if v, ok := m["name"]; ok {
+			name, err = parseutil.ParseString(v)
+			if err != nil {
+				return multierror.Prefix(err, fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			delete(m, "name")
+		}
+
+		var priority int
+		if v, ok := m["priority"]; ok {
+			p, err := parseutil.ParseInt(v)
+			if err != nil {
+				return multierror.Prefix(fmt.Errorf("unable to parse 'priority' in kms type %q: %w", key, err), fmt.Sprintf("%s.%s:", blockName, key))
+			}
+			priority = int(p)
+			delete(m, "priority")
+		}
+
 		strMap := make(map[string]string, len(m))
 		for k, v := range m {
 			s, err := parseutil.ParseString(v)
@@ -111,6 +149,8 @@ func parseKMS(result *[]*KMS, list *ast.ObjectList, blockName string, maxKMS int
 			Type:     strings.ToLower(key),
 			Purpose:  purpose,
 			Disabled: disabled,
+			Name:     name,
+			Priority: priority,
 		}
 		if len(strMap) > 0 {
 			seal.Config = strMap
@@ -145,7 +185,7 @@ func ParseKMSes(d string) ([]*KMS, error) {
 	}
 
 	if o := list.Filter("seal"); len(o.Items) > 0 {
-		if err := parseKMS(&result.Seals, o, "seal", 3); err != nil {
+		if err := parseKMS(&result.Seals, o, "seal", maxSeals); err != nil {
 			return nil, fmt.Errorf("error parsing 'seal': %w", err)
 		}
 	}
@@ -194,7 +234,7 @@ func configureWrapper(configKMS *KMS, infoKeys *[]string, info *map[string]strin
 		wrapper, kmsInfo, err = GetTransitKMSFunc(opts, configKMS)
 
 	case wrapping.PKCS11:
-		return nil, fmt.Errorf("KMS type 'pkcs11' requires the Vault Enterprise HSM binary")
+		wrapper, kmsInfo, err = GetPKCS11KMSFunc(opts, configKMS)
 
 	default:
 		return nil, fmt.Errorf("Unknown KMS type %q", configKMS.Type)
@@ -324,6 +364,28 @@ func GetOCIKMSKMSFunc(opts *wrapping.WrapperOptions, kms *KMS) (wrapping.Wrapper
 	return wrapper, info, nil
 }
 
+func GetPKCS11KMSFunc(opts *wrapping.WrapperOptions, kms *KMS) (wrapping.Wrapper, map[string]string, error) {
+	wrapper := pkcs11.NewWrapper(opts)
+	wrapperInfo, err := wrapper.SetConfig(kms.Config)
+	if err != nil {
+		return nil, nil, err
+	}
+	info := make(map[string]string)
+	if wrapperInfo != nil {
+		info["PKCS#11 Library"] = wrapperInfo["lib"]
+		info["PKCS#11 Slot"] = wrapperInfo["slot"]
+		if tokenLabel, ok := wrapperInfo["token_label"]; ok {
+			info["PKCS#11 Token Label"] = tokenLabel
+		}
+		info["PKCS#11 Key Label"] = wrapperInfo["key_label"]
+		info["PKCS#11 Mechanism"] = wrapperInfo["mechanism"]
+		if hash, ok := wrapperInfo["rsa_oaep_hash"]; ok {
+			info["PKCS#11 RSA OAEP Hash"] = hash
+		}
+	}
+	return wrapper, info, nil
+}
+
 var GetTransitKMSFunc = func(opts *wrapping.WrapperOptions, kms *KMS) (wrapping.Wrapper, map[string]string, error) {
 	wrapper := transit.NewWrapper(opts)
 	wrapperInfo, err := wrapper.SetConfig(kms.Config)
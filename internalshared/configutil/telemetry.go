@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	"strconv"
 	"time"
 
 	monitoring "cloud.google.com/go/monitoring/apiv3"
@@ -23,11 +24,13 @@ import (
 )
 
 const (
-	PrometheusDefaultRetentionTime    = 24 * time.Hour
-	UsageGaugeDefaultPeriod           = 10 * time.Minute
-	MaximumGaugeCardinalityDefault    = 500
-	LeaseMetricsEpsilonDefault        = time.Hour
-	NumLeaseMetricsTimeBucketsDefault = 168
+	PrometheusDefaultRetentionTime        = 24 * time.Hour
+	UsageGaugeDefaultPeriod               = 10 * time.Minute
+	MaximumGaugeCardinalityDefault        = 500
+	MaximumRequestMountCardinalityDefault = 100
+	LeaseMetricsEpsilonDefault            = time.Hour
+	NumLeaseMetricsTimeBucketsDefault     = 168
+	TraceSampleRateDefault                = 1.0
 )
 
 // Telemetry is the telemetry configuration for the server
@@ -45,6 +48,10 @@ type Telemetry struct {
 
 	MaximumGaugeCardinality int `hcl:"maximum_gauge_cardinality"`
 
+	// MaximumRequestMountCardinality is the maximum number of distinct mounts
+	// labelled in the per-mount request metrics
+	MaximumRequestMountCardinality int `hcl:"maximum_request_mount_cardinality"`
+
 	// Circonus: see https://github.com/circonus-labs/circonus-gometrics
 	// for more details on the various configuration options.
 	// Valid configuration combinations:
@@ -150,6 +157,18 @@ type Telemetry struct {
 
 	// Whether or not telemetry should add labels for namespaces
 	LeaseMetricsNameSpaceLabels bool `hcl:"add_lease_metrics_namespace_labels"`
+
+	// OpenTelemetry:
+	// OTLPTraceEndpoint is the URL of the OpenTelemetry collector that request
+	// traces are exported to with OTLP over HTTP, e.g. http://127.0.0.1:4318.
+	// Tracing is disabled when it is not set.
+	OTLPTraceEndpoint string `hcl:"otlp_trace_endpoint"`
+
+	// TraceSampleRate is the fraction of the traces started by Vault that are
+	// sampled. Requests carrying the trace context of the client follow the
+	// sampling decision of the client. Default: 1.0
+	TraceSampleRate    float64     `hcl:"-"`
+	TraceSampleRateRaw interface{} `hcl:"trace_sample_rate"`
 }
 
 func (t *Telemetry) Validate(source string) []ConfigError {
@@ -204,6 +223,10 @@ func parseTelemetry(result *SharedConfig, list *ast.ObjectList) error {
 		result.Telemetry.MaximumGaugeCardinality = MaximumGaugeCardinalityDefault
 	}
 
+	if result.Telemetry.MaximumRequestMountCardinality == 0 {
+		result.Telemetry.MaximumRequestMountCardinality = MaximumRequestMountCardinalityDefault
+	}
+
 	if result.Telemetry.LeaseMetricsEpsilonRaw != nil {
 		if result.Telemetry.LeaseMetricsEpsilonRaw == "none" {
 			result.Telemetry.LeaseMetricsEpsilonRaw = 0
@@ -222,9 +245,41 @@ func parseTelemetry(result *SharedConfig, list *ast.ObjectList) error {
 		result.Telemetry.NumLeaseMetricsTimeBuckets = NumLeaseMetricsTimeBucketsDefault
 	}
 
+	if result.Telemetry.TraceSampleRateRaw != nil {
+		var err error
+		if result.Telemetry.TraceSampleRate, err = parseSampleRate(result.Telemetry.TraceSampleRateRaw); err != nil {
+			return fmt.Errorf("invalid trace_sample_rate: %w", err)
+		}
+		result.Telemetry.TraceSampleRateRaw = nil
+	} else {
+		result.Telemetry.TraceSampleRate = TraceSampleRateDefault
+	}
+
 	return nil
 }
 
+// parseSampleRate parses a fraction between 0 and 1
+func parseSampleRate(in interface{}) (float64, error) {
+	var rate float64
+	switch v := in.(type) {
+	case float64:
+		rate = v
+	case int:
+		rate = float64(v)
+	case string:
+		var err error
+		if rate, err = strconv.ParseFloat(v, 64); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unexpected type %T", in)
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("%v is not between 0 and 1", rate)
+	}
+	return rate, nil
+}
+
 type SetupTelemetryOpts struct {
 	Config      *Telemetry
 	Ui          cli.Ui
@@ -275,7 +330,17 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 		if err != nil {
 			return nil, nil, false, err
 		}
-		fanout = append(fanout, sink)
+
+		// Report the request durations as histograms, so that they can be
+		// aggregated across the cluster
+		histogramSink, err := metricsutil.NewPrometheusHistogramSink(sink, metricsutil.PrometheusHistogramOpts{
+			ServiceName: opts.ServiceName,
+			Expiration:  opts.Config.PrometheusRetentionTime,
+		})
+		if err != nil {
+			return nil, nil, false, err
+		}
+		fanout = append(fanout, histogramSink)
 	}
 
 	if opts.Config.StatsiteAddr != "" {
@@ -383,6 +448,7 @@ func SetupTelemetry(opts *SetupTelemetryOpts) (*metrics.InmemSink, *metricsutil.
 	// and to any backend.
 	wrapper := metricsutil.NewClusterMetricSink(opts.ClusterName, globalMetrics)
 	wrapper.MaxGaugeCardinality = opts.Config.MaximumGaugeCardinality
+	wrapper.MaxRequestMountCardinality = opts.Config.MaximumRequestMountCardinality
 	wrapper.GaugeInterval = opts.Config.UsageGaugePeriod
 	wrapper.TelemetryConsts.LeaseMetricsEpsilon = opts.Config.LeaseMetricsEpsilon
 	wrapper.TelemetryConsts.LeaseMetricsNameSpaceLabels = opts.Config.LeaseMetricsNameSpaceLabels
@@ -55,6 +55,10 @@ var (
 
 type restoreCallback func(context.Context) error
 
+// invalidateCallback is called with the keys changed by applied logs. The keys
+// are nil when a snapshot was installed, since any key may have changed.
+type invalidateCallback func(keys []string)
+
 // FSMApplyResponse is returned from an FSM apply. It indicates if the apply was
 // successful or not.
 type FSMApplyResponse struct {
@@ -88,6 +92,10 @@ type FSM struct {
 	// retoreCb is called after we've restored a snapshot
 	restoreCb restoreCallback
 
+	// invalidateCb is called after logs were applied or a snapshot was
+	// installed
+	invalidateCb invalidateCallback
+
 	chunker *raftchunking.ChunkingBatchingFSM
 
 	localID         string
@@ -604,6 +612,7 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 		f.applyCallback()
 	}
 
+	var keys []string
 	err = f.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(dataBucketName)
 		for _, commandRaw := range commands {
@@ -614,8 +623,10 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 					switch op.OpType {
 					case putOp:
 						err = b.Put([]byte(op.Key), op.Value)
+						keys = append(keys, op.Key)
 					case deleteOp:
 						err = b.Delete([]byte(op.Key))
+						keys = append(keys, op.Key)
 					case restoreCallbackOp:
 						if f.restoreCb != nil {
 							// Kick off the restore callback function in a go routine
@@ -667,6 +678,10 @@ func (f *FSM) ApplyBatch(logs []*raft.Log) []interface{} {
 		f.latestConfig.Store(latestConfiguration)
 	}
 
+	if f.invalidateCb != nil && len(keys) > 0 {
+		f.invalidateCb(keys)
+	}
+
 	// Build the responses. The logs array is used here to ensure we reply to
 	// all command values; even if they are not of the types we expect. This
 	// should future proof this function from more log types being provided.
@@ -814,6 +829,10 @@ func (f *FSM) Restore(r io.ReadCloser) error {
 		}
 	}
 
+	if f.invalidateCb != nil {
+		f.invalidateCb(nil)
+	}
+
 	return retErr.ErrorOrNil()
 }
 
@@ -20,6 +20,7 @@ import (
 	wrapping "github.com/hashicorp/go-kms-wrapping"
 	"github.com/hashicorp/go-raftchunking"
 	"github.com/hashicorp/go-uuid"
+	goversion "github.com/hashicorp/go-version"
 	"github.com/hashicorp/raft"
 	autopilot "github.com/hashicorp/raft-autopilot"
 	"github.com/hashicorp/raft-boltdb/v2"
@@ -30,6 +31,7 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/tlsutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/version"
 	"github.com/hashicorp/vault/vault/cluster"
 	"github.com/hashicorp/vault/vault/seal"
 	bolt "go.etcd.io/bbolt"
@@ -151,6 +153,19 @@ type RaftBackend struct {
 	disableAutopilot bool
 
 	autopilotReconcileInterval time.Duration
+
+	// nonVoter is set if this node joins raft clusters as a non-voter, which
+	// autopilot never promotes. Such nodes serve as read replicas.
+	nonVoter bool
+
+	// redundancyZone is the autopilot redundancy zone of this node. Autopilot
+	// keeps a single voter per zone and the other nodes of the zone as
+	// non-voters, ready to take over if the voter fails.
+	redundancyZone string
+
+	// upgradeVersion overrides the Vault version this node reports to
+	// autopilot for automated upgrades.
+	upgradeVersion string
 }
 
 // LeaderJoinInfo contains information required by a node to join itself as a
@@ -413,6 +428,22 @@ func NewRaftBackend(conf map[string]string, logger log.Logger) (physical.Backend
 		reconcileInterval = interval
 	}
 
+	var nonVoter bool
+	if nonVoterRaw := conf["retry_join_as_non_voter"]; nonVoterRaw != "" {
+		var err error
+		nonVoter, err = strconv.ParseBool(nonVoterRaw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse 'retry_join_as_non_voter': %w", err)
+		}
+	}
+
+	upgradeVersion := conf["autopilot_upgrade_version"]
+	if upgradeVersion != "" {
+		if _, err := goversion.NewVersion(upgradeVersion); err != nil {
+			return nil, fmt.Errorf("failed to parse 'autopilot_upgrade_version': %w", err)
+		}
+	}
+
 	return &RaftBackend{
 		logger:                     logger,
 		fsm:                        fsm,
@@ -427,6 +458,9 @@ func NewRaftBackend(conf map[string]string, logger log.Logger) (physical.Backend
 		maxEntrySize:               maxEntrySize,
 		followerHeartbeatTicker:    time.NewTicker(time.Second),
 		autopilotReconcileInterval: reconcileInterval,
+		nonVoter:                   nonVoter,
+		redundancyZone:             conf["autopilot_redundancy_zone"],
+		upgradeVersion:             upgradeVersion,
 	}, nil
 }
 
@@ -527,6 +561,10 @@ type RaftServer struct {
 	// Voter is true if this server has a vote in the cluster. This might
 	// be false if the server is staging and still coming online.
 	Voter bool `json:"voter"`
+
+	// ReadReplica is true if this server joined the cluster as a non-voter,
+	// in which case it is never promoted.
+	ReadReplica bool `json:"read_replica"`
 }
 
 // RaftConfigurationResponse is returned when querying for the current Raft
@@ -609,6 +647,15 @@ func (b *RaftBackend) Bootstrap(peers []Peer) error {
 	return nil
 }
 
+// SetInvalidateCallback sets the callback called with the keys changed by the
+// logs applied to the FSM, or with nil keys when a snapshot was installed. The
+// callback must not block, and must not read from the FSM.
+func (b *RaftBackend) SetInvalidateCallback(invalidateCb invalidateCallback) {
+	b.fsm.l.Lock()
+	b.fsm.invalidateCb = invalidateCb
+	b.fsm.l.Unlock()
+}
+
 // SetRestoreCallback sets the callback to be used when a restoreCallbackOp is
 // processed through the FSM.
 func (b *RaftBackend) SetRestoreCallback(restoreCb restoreCallback) {
@@ -807,14 +854,6 @@ func (b *RaftBackend) SetupCluster(ctx context.Context, opts SetupOpts) error {
 			return errwrap.Wrapf("raft recovery failed to parse peers.json: {{err}}", err)
 		}
 
-		// Non-voting servers are only allowed in enterprise. If Suffrage is disabled,
-		// error out to indicate that it isn't allowed.
-		for idx := range recoveryConfig.Servers {
-			if !nonVotersAllowed && recoveryConfig.Servers[idx].Suffrage == raft.Nonvoter {
-				return fmt.Errorf("raft recovery failed to parse configuration for node %q: setting `non_voter` is only supported in enterprise", recoveryConfig.Servers[idx].ID)
-			}
-		}
-
 		b.logger.Info("raft recovery found new config", "config", recoveryConfig)
 
 		err = raft.RecoverCluster(raftConfig, b.fsm, b.logStore, b.stableStore, b.snapStore, b.raftTransport, recoveryConfig)
@@ -1008,6 +1047,7 @@ func (b *RaftBackend) GetConfiguration(ctx context.Context) (*RaftConfigurationR
 			// denotes the raft leader.
 			Leader:          string(server.ID) == b.NodeID(),
 			Voter:           server.Suffrage == raft.Voter,
+			ReadReplica:     server.Suffrage == raft.Nonvoter && b.followerDesiredSuffrage(string(server.ID)) == "non-voter",
 			ProtocolVersion: strconv.Itoa(raft.ProtocolVersionMax),
 		}
 		config.Servers = append(config.Servers, entry)
@@ -1044,6 +1084,21 @@ func (b *RaftBackend) AddPeer(ctx context.Context, peerID, clusterAddr string) e
 	})
 }
 
+// AddNonVotingPeer adds a new server to the raft cluster as a non-voter.
+// Autopilot never promotes it, since its desired suffrage is non-voter.
+func (b *RaftBackend) AddNonVotingPeer(ctx context.Context, peerID, clusterAddr string) error {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	if b.raft == nil {
+		return errors.New("raft storage is not initialized")
+	}
+
+	b.logger.Trace("adding non-voting server to raft", "id", peerID)
+	future := b.raft.AddNonvoter(raft.ServerID(peerID), raft.ServerAddress(clusterAddr), 0, 0)
+	return future.Error()
+}
+
 // Peers returns all the servers present in the raft cluster
 func (b *RaftBackend) Peers(ctx context.Context) ([]Peer, error) {
 	b.l.RLock()
@@ -1306,6 +1361,11 @@ func (b *RaftBackend) applyLog(ctx context.Context, command *LogData) error {
 	}
 
 	if err := applyFuture.Error(); err != nil {
+		// A read replica serving a request cannot write, so the request is
+		// forwarded to the active node
+		if err == raft.ErrNotLeader && b.DesiredSuffrage() == "non-voter" {
+			return logical.ErrReadOnly
+		}
 		return err
 	}
 
@@ -1378,6 +1438,26 @@ func (b *RaftBackend) DesiredSuffrage() string {
 	return b.fsm.DesiredSuffrage()
 }
 
+// NonVoter returns true if this node is configured to join raft clusters as a
+// non-voter
+func (b *RaftBackend) NonVoter() bool {
+	return b.nonVoter
+}
+
+// RedundancyZone returns the autopilot redundancy zone of this node, if any
+func (b *RaftBackend) RedundancyZone() string {
+	return b.redundancyZone
+}
+
+// EffectiveVersion returns the version this node reports to autopilot: the
+// configured upgrade version if set, the Vault version otherwise.
+func (b *RaftBackend) EffectiveVersion() string {
+	if b.upgradeVersion != "" {
+		return b.upgradeVersion
+	}
+	return version.GetVersion().Version
+}
+
 // RaftLock implements the physical Lock interface and enables HA for this
 // backend. The Lock uses the raftNotifyCh for receiving leadership edge
 // triggers. Vault's active duty matches raft's leadership.
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -70,6 +71,11 @@ type AutopilotConfig struct {
 	// stable, healthy state before it can be added to the cluster. Only applicable
 	// with Raft protocol version 3 or higher.
 	ServerStabilizationTime time.Duration `mapstructure:"-"`
+
+	// DisableUpgradeMigration disables the automated upgrades, which promote
+	// the servers running a newer version once there are enough of them,
+	// transfer leadership to one of them and demote the older servers.
+	DisableUpgradeMigration bool `mapstructure:"disable_upgrade_migration"`
 }
 
 // Merge combines the supplied config with the receiver. Supplied ones take
@@ -96,6 +102,7 @@ func (to *AutopilotConfig) Merge(from *AutopilotConfig) {
 	if from.ServerStabilizationTime != 0 {
 		to.ServerStabilizationTime = from.ServerStabilizationTime
 	}
+	to.DisableUpgradeMigration = from.DisableUpgradeMigration
 }
 
 // Clone returns a duplicate instance of AutopilotConfig with the exact same values.
@@ -110,6 +117,7 @@ func (ac *AutopilotConfig) Clone() *AutopilotConfig {
 		MaxTrailingLogs:                ac.MaxTrailingLogs,
 		MinQuorum:                      ac.MinQuorum,
 		ServerStabilizationTime:        ac.ServerStabilizationTime,
+		DisableUpgradeMigration:        ac.DisableUpgradeMigration,
 	}
 }
 
@@ -123,6 +131,7 @@ func (ac *AutopilotConfig) MarshalJSON() ([]byte, error) {
 		"max_trailing_logs":                  ac.MaxTrailingLogs,
 		"min_quorum":                         ac.MinQuorum,
 		"server_stabilization_time":          ac.ServerStabilizationTime.String(),
+		"disable_upgrade_migration":          ac.DisableUpgradeMigration,
 	})
 }
 
@@ -158,6 +167,19 @@ type FollowerState struct {
 	LastTerm        uint64
 	IsDead          *atomic.Bool
 	DesiredSuffrage string
+	UpgradeVersion  string
+	RedundancyZone  string
+}
+
+// EchoRequestUpdate is here to avoid 1) the list of arguments to Update()
+// getting huge 2) an import cycle on the vault package.
+type EchoRequestUpdate struct {
+	NodeID          string
+	AppliedIndex    uint64
+	Term            uint64
+	DesiredSuffrage string
+	UpgradeVersion  string
+	RedundancyZone  string
 }
 
 // FollowerStates holds information about all the followers in the raft cluster
@@ -175,22 +197,24 @@ func NewFollowerStates() *FollowerStates {
 }
 
 // Update the peer information in the follower states
-func (s *FollowerStates) Update(nodeID string, appliedIndex uint64, term uint64, desiredSuffrage string) {
+func (s *FollowerStates) Update(req *EchoRequestUpdate) {
 	s.l.Lock()
 	defer s.l.Unlock()
 
-	state, ok := s.followers[nodeID]
+	state, ok := s.followers[req.NodeID]
 	if !ok {
 		state = &FollowerState{
 			IsDead: atomic.NewBool(false),
 		}
-		s.followers[nodeID] = state
+		s.followers[req.NodeID] = state
 	}
 
 	state.IsDead.Store(false)
-	state.AppliedIndex = appliedIndex
-	state.LastTerm = term
-	state.DesiredSuffrage = desiredSuffrage
+	state.AppliedIndex = req.AppliedIndex
+	state.LastTerm = req.Term
+	state.DesiredSuffrage = req.DesiredSuffrage
+	state.UpgradeVersion = req.UpgradeVersion
+	state.RedundancyZone = req.RedundancyZone
 	state.LastHeartbeat = time.Now()
 }
 
@@ -233,6 +257,23 @@ func (s *FollowerStates) MinIndex() uint64 {
 	return min
 }
 
+// followerDesiredSuffrage returns the desired suffrage last reported by the
+// follower, or an empty string if it is unknown.
+func (b *RaftBackend) followerDesiredSuffrage(nodeID string) string {
+	if b.followerStates == nil {
+		return ""
+	}
+
+	b.followerStates.l.RLock()
+	defer b.followerStates.l.RUnlock()
+
+	state, ok := b.followerStates.followers[nodeID]
+	if !ok {
+		return ""
+	}
+	return state.DesiredSuffrage
+}
+
 // Ensure that the Delegate implements the ApplicationIntegration interface
 var _ autopilot.ApplicationIntegration = (*Delegate)(nil)
 
@@ -360,7 +401,8 @@ func (d *Delegate) KnownServers() map[raft.ServerID]*autopilot.Server {
 			ID:          raft.ServerID(id),
 			Name:        id,
 			RaftVersion: raft.ProtocolVersionMax,
-			Ext:         d.autopilotServerExt(state.DesiredSuffrage),
+			Version:     state.UpgradeVersion,
+			Ext:         d.autopilotServerExt(state),
 		}
 
 		switch state.IsDead.Load() {
@@ -380,8 +422,13 @@ func (d *Delegate) KnownServers() map[raft.ServerID]*autopilot.Server {
 		Name:        d.localID,
 		RaftVersion: raft.ProtocolVersionMax,
 		NodeStatus:  autopilot.NodeAlive,
-		Ext:         d.autopilotServerExt("voter"),
-		IsLeader:    true,
+		Version:     d.EffectiveVersion(),
+		Ext: d.autopilotServerExt(&FollowerState{
+			DesiredSuffrage: "voter",
+			UpgradeVersion:  d.EffectiveVersion(),
+			RedundancyZone:  d.RedundancyZone(),
+		}),
+		IsLeader: true,
 	}
 
 	return ret
@@ -507,10 +554,17 @@ type AutopilotState struct {
 	Healthy          bool `json:"healthy"`
 	FailureTolerance int  `json:"failure_tolerance"`
 
-	Servers   map[string]*AutopilotServer `json:"servers"`
-	Leader    string                      `json:"leader"`
-	Voters    []string                    `json:"voters"`
-	NonVoters []string                    `json:"non_voters,omitempty"`
+	Servers map[string]*AutopilotServer `json:"servers"`
+	Leader  string                      `json:"leader"`
+	Voters  []string                    `json:"voters"`
+
+	// NonVoters are the non-voters that autopilot may still promote; read
+	// replicas are never promoted and listed on their own
+	NonVoters    []string `json:"non_voters,omitempty"`
+	ReadReplicas []string `json:"read_replicas,omitempty"`
+
+	RedundancyZones map[string]AutopilotZone `json:"redundancy_zones,omitempty"`
+	Upgrade         *AutopilotUpgrade        `json:"upgrade_info,omitempty"`
 }
 
 // AutopilotServer represents the health information of individual server node
@@ -527,6 +581,11 @@ type AutopilotServer struct {
 	StableSince time.Time         `json:"stable_since"`
 	Status      string            `json:"status"`
 	Meta        map[string]string `json:"meta"`
+	NodeType    string            `json:"node_type"`
+
+	Version        string `json:"version"`
+	UpgradeVersion string `json:"upgrade_version,omitempty"`
+	RedundancyZone string `json:"redundancy_zone,omitempty"`
 }
 
 // ReadableDuration is a duration type that is serialized to JSON in human readable format.
@@ -597,6 +656,20 @@ func autopilotToAPIState(state *autopilot.State) (*AutopilotState, error) {
 
 	for id, srv := range state.Servers {
 		out.Servers[string(id)] = autopilotToAPIServer(srv)
+
+		switch {
+		case srv.Server.NodeType == autopilotNodeReadReplica:
+			out.ReadReplicas = append(out.ReadReplicas, string(id))
+		case srv.State == autopilot.RaftNonVoter:
+			out.NonVoters = append(out.NonVoters, string(id))
+		}
+	}
+	sort.Strings(out.ReadReplicas)
+	sort.Strings(out.NonVoters)
+
+	if ext, ok := state.Ext.(*autopilotStateInfo); ok {
+		out.RedundancyZones = ext.RedundancyZones
+		out.Upgrade = ext.Upgrade
 	}
 
 	return out, nil
@@ -615,6 +688,12 @@ func autopilotToAPIServer(srv *autopilot.ServerState) *AutopilotServer {
 		StableSince: srv.Health.StableSince,
 		Status:      string(srv.State),
 		Meta:        srv.Server.Meta,
+		NodeType:    string(srv.Server.NodeType),
+		Version:     srv.Server.Version,
+	}
+	if info, ok := srv.Server.Ext.(*autopilotServerInfo); ok {
+		apiSrv.UpgradeVersion = info.UpgradeVersion
+		apiSrv.RedundancyZone = info.RedundancyZone
 	}
 
 	autopilotToAPIServerEnterprise(srv, apiSrv)
@@ -0,0 +1,403 @@
+package raft
+
+import (
+	"sort"
+	"time"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+)
+
+const (
+	// autopilotNodeReadReplica is the autopilot node type of the servers that
+	// joined as non-voters, which are never promoted.
+	autopilotNodeReadReplica autopilot.NodeType = "read-replica"
+
+	// autopilotNodeZoneVoter is the autopilot node type of the server that
+	// votes on behalf of its redundancy zone.
+	autopilotNodeZoneVoter autopilot.NodeType = "zone-voter"
+
+	// autopilotNodeZoneStandby is the autopilot node type of the servers of a
+	// redundancy zone that are kept as non-voters, ready to be promoted if the
+	// voter of the zone fails.
+	autopilotNodeZoneStandby autopilot.NodeType = "zone-standby"
+)
+
+// Statuses of the automated upgrades reported in AutopilotUpgrade
+const (
+	AutopilotUpgradeIdle               = "idle"
+	AutopilotUpgradeDisabled           = "disabled"
+	AutopilotUpgradeAwaitNewServers    = "await-new-servers"
+	AutopilotUpgradePromoting          = "promoting"
+	AutopilotUpgradeLeaderTransfer     = "leader-transfer"
+	AutopilotUpgradeDemoting           = "demoting"
+	AutopilotUpgradeAwaitServerRemoval = "await-server-removal"
+)
+
+// AutopilotZone is the state of a redundancy zone reported by autopilot.
+type AutopilotZone struct {
+	Servers          []string `json:"servers"`
+	Voters           []string `json:"voters"`
+	FailureTolerance int      `json:"failure_tolerance"`
+}
+
+// AutopilotUpgrade is the state of the automated upgrades reported by
+// autopilot. The target version is the highest version run by a server of the
+// cluster; the other version servers are the ones to replace.
+type AutopilotUpgrade struct {
+	Status                 string   `json:"status"`
+	TargetVersion          string   `json:"target_version,omitempty"`
+	TargetVersionVoters    []string `json:"target_version_voters,omitempty"`
+	TargetVersionNonVoters []string `json:"target_version_non_voters,omitempty"`
+	OtherVersionVoters     []string `json:"other_version_voters,omitempty"`
+	OtherVersionNonVoters  []string `json:"other_version_non_voters,omitempty"`
+}
+
+// autopilotServerInfo is the Vault specific information autopilot keeps about
+// each server, in the Ext field of autopilot.Server.
+type autopilotServerInfo struct {
+	DesiredSuffrage string
+	UpgradeVersion  string
+	RedundancyZone  string
+}
+
+func (d *Delegate) autopilotServerExt(state *FollowerState) interface{} {
+	return &autopilotServerInfo{
+		DesiredSuffrage: state.DesiredSuffrage,
+		UpgradeVersion:  state.UpgradeVersion,
+		RedundancyZone:  state.RedundancyZone,
+	}
+}
+
+// autopilotConfigInfo is the Vault specific autopilot configuration, in the
+// Ext field of autopilot.Config.
+type autopilotConfigInfo struct {
+	DisableUpgradeMigration bool
+}
+
+// autopilotConfigExt must be called with the backend lock held
+func (d *Delegate) autopilotConfigExt() interface{} {
+	return &autopilotConfigInfo{
+		DisableUpgradeMigration: d.autopilotConfig.DisableUpgradeMigration,
+	}
+}
+
+// autopilotStateInfo is the Vault specific state autopilot reports, in the
+// Ext field of autopilot.State.
+type autopilotStateInfo struct {
+	RedundancyZones map[string]AutopilotZone
+	Upgrade         *AutopilotUpgrade
+}
+
+func serverInfo(srv *autopilot.Server) *autopilotServerInfo {
+	if info, ok := srv.Ext.(*autopilotServerInfo); ok {
+		return info
+	}
+	return &autopilotServerInfo{}
+}
+
+// isReadReplica returns true if the server joined as a non-voter
+func isReadReplica(srv *autopilot.Server) bool {
+	return serverInfo(srv).DesiredSuffrage == "non-voter"
+}
+
+// autopilotPromoter decides which servers vote. Read replicas never do. The
+// servers of a redundancy zone elect a single voter, the servers without a
+// zone are all voters once stable. During an upgrade only the servers that run
+// the newest version vote, once there are enough of them to replace the older
+// voters, and the leadership moves to one of them.
+type autopilotPromoter struct {
+	autopilot.StablePromoter
+}
+
+var _ autopilot.Promoter = (*autopilotPromoter)(nil)
+
+// autopilotPlan is the outcome of the promoter's calculations for a given
+// autopilot state.
+type autopilotPlan struct {
+	voters    map[raft.ServerID]bool
+	nodeTypes map[raft.ServerID]autopilot.NodeType
+	zones     map[string]AutopilotZone
+	upgrade   *AutopilotUpgrade
+	changes   autopilot.RaftChanges
+}
+
+func (p *autopilotPromoter) GetStateExt(c *autopilot.Config, s *autopilot.State) interface{} {
+	plan := p.plan(c, s)
+	return &autopilotStateInfo{
+		RedundancyZones: plan.zones,
+		Upgrade:         plan.upgrade,
+	}
+}
+
+func (p *autopilotPromoter) GetNodeTypes(c *autopilot.Config, s *autopilot.State) map[raft.ServerID]autopilot.NodeType {
+	return p.plan(c, s).nodeTypes
+}
+
+func (p *autopilotPromoter) CalculatePromotionsAndDemotions(c *autopilot.Config, s *autopilot.State) autopilot.RaftChanges {
+	return p.plan(c, s).changes
+}
+
+func (p *autopilotPromoter) plan(c *autopilot.Config, s *autopilot.State) *autopilotPlan {
+	plan := &autopilotPlan{
+		voters:    make(map[raft.ServerID]bool),
+		nodeTypes: make(map[raft.ServerID]autopilot.NodeType),
+	}
+
+	now := time.Now()
+	minStableDuration := s.ServerStabilizationTime(c)
+	stable := func(srv *autopilot.ServerState) bool {
+		return srv.Health.IsStable(now, minStableDuration)
+	}
+
+	var ids []raft.ServerID
+	for id, srv := range s.Servers {
+		if isReadReplica(&srv.Server) {
+			plan.nodeTypes[id] = autopilotNodeReadReplica
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	plan.zones = autopilotZones(s, ids)
+	upgrade, pool := autopilotUpgradePool(c, s, ids, stable)
+
+	// Pick the voters among the servers of the pool: one per redundancy zone,
+	// keeping the current one while it is healthy, and all the stable servers
+	// without a zone. The leader keeps voting until it hands over leadership.
+	zoneVoters := make(map[string]raft.ServerID)
+	for _, id := range pool {
+		srv := s.Servers[id]
+		zone := serverInfo(&srv.Server).RedundancyZone
+		switch {
+		case zone == "":
+			if srv.HasVotingRights() || stable(srv) {
+				plan.voters[id] = true
+			}
+		case srv.HasVotingRights() && srv.Health.Healthy:
+			current, ok := zoneVoters[zone]
+			if !ok || id == s.Leader || !s.Servers[current].HasVotingRights() || !s.Servers[current].Health.Healthy {
+				zoneVoters[zone] = id
+			}
+		case stable(srv):
+			if _, ok := zoneVoters[zone]; !ok {
+				zoneVoters[zone] = id
+			}
+		}
+	}
+	for zone, info := range plan.zones {
+		if _, ok := zoneVoters[zone]; ok {
+			continue
+		}
+		// Without a healthy server to replace it, the zone keeps its voter
+		for _, id := range info.Voters {
+			zoneVoters[zone] = raft.ServerID(id)
+			break
+		}
+	}
+	for _, id := range zoneVoters {
+		plan.voters[id] = true
+	}
+	plan.voters[s.Leader] = true
+
+	// Nothing is demoted while waiting for new servers: the voters that were
+	// upgraded in place keep voting alongside the older ones
+	if upgrade != nil && upgrade.Status == AutopilotUpgradeAwaitNewServers {
+		for _, id := range ids {
+			if s.Servers[id].HasVotingRights() {
+				plan.voters[id] = true
+			}
+		}
+	}
+
+	for _, id := range ids {
+		switch {
+		case serverInfo(&s.Servers[id].Server).RedundancyZone == "":
+			plan.nodeTypes[id] = autopilot.NodeVoter
+		case plan.voters[id]:
+			plan.nodeTypes[id] = autopilotNodeZoneVoter
+		default:
+			plan.nodeTypes[id] = autopilotNodeZoneStandby
+		}
+	}
+
+	// Promote the desired voters first. Once they all vote, demote the other
+	// voters, and then hand over leadership if the leader has to be replaced.
+	allVoting := true
+	for _, id := range ids {
+		srv := s.Servers[id]
+		if plan.voters[id] && !srv.HasVotingRights() {
+			allVoting = false
+			if srv.State == autopilot.RaftNonVoter {
+				plan.changes.Promotions = append(plan.changes.Promotions, id)
+			}
+		}
+	}
+	if allVoting {
+		for _, id := range ids {
+			if !plan.voters[id] && s.Servers[id].HasVotingRights() && id != s.Leader {
+				plan.changes.Demotions = append(plan.changes.Demotions, id)
+			}
+		}
+	}
+
+	if upgrade != nil {
+		plan.upgrade = upgrade
+		if upgrade.Status == AutopilotUpgradePromoting {
+			switch {
+			case !allVoting:
+			case len(plan.changes.Demotions) > 0:
+				upgrade.Status = AutopilotUpgradeDemoting
+			case !strutil.StrListContains(upgrade.TargetVersionVoters, string(s.Leader)):
+				// The former leader is demoted once it stepped down
+				upgrade.Status = AutopilotUpgradeLeaderTransfer
+				plan.changes.Leader = autopilotUpgradeLeader(s, upgrade)
+			default:
+				upgrade.Status = AutopilotUpgradeAwaitServerRemoval
+			}
+		}
+	}
+
+	return plan
+}
+
+// autopilotZones returns the state of the redundancy zones of the given
+// servers
+func autopilotZones(s *autopilot.State, ids []raft.ServerID) map[string]AutopilotZone {
+	var zones map[string]AutopilotZone
+	healthy := make(map[string]int)
+	for _, id := range ids {
+		srv := s.Servers[id]
+		name := serverInfo(&srv.Server).RedundancyZone
+		if name == "" {
+			continue
+		}
+		if zones == nil {
+			zones = make(map[string]AutopilotZone)
+		}
+
+		zone := zones[name]
+		zone.Servers = append(zone.Servers, string(id))
+		if srv.HasVotingRights() {
+			zone.Voters = append(zone.Voters, string(id))
+		}
+		if srv.Health.Healthy {
+			healthy[name]++
+		}
+		zones[name] = zone
+	}
+
+	for name, zone := range zones {
+		if healthy[name] > 1 {
+			zone.FailureTolerance = healthy[name] - 1
+		}
+		zones[name] = zone
+	}
+	return zones
+}
+
+// autopilotUpgradePool returns the state of the automated upgrades and the
+// servers among which the voters are picked. Those are the servers running
+// the target version once there are enough stable ones to replace the other
+// voters, and all the servers otherwise. While waiting for new servers, the
+// current voters keep voting whatever their version. The upgrade state is nil
+// if a server did not report a valid version.
+func autopilotUpgradePool(c *autopilot.Config, s *autopilot.State, ids []raft.ServerID, stable func(*autopilot.ServerState) bool) (*AutopilotUpgrade, []raft.ServerID) {
+	var target *goversion.Version
+	versions := make(map[raft.ServerID]*goversion.Version, len(ids))
+	for _, id := range ids {
+		v, err := goversion.NewVersion(s.Servers[id].Server.Version)
+		if err != nil {
+			return nil, ids
+		}
+		versions[id] = v
+		if target == nil || v.GreaterThan(target) {
+			target = v
+		}
+	}
+	if target == nil {
+		return nil, ids
+	}
+
+	upgrade := &AutopilotUpgrade{
+		Status:        AutopilotUpgradeIdle,
+		TargetVersion: target.String(),
+	}
+	var targetServers, otherServers []raft.ServerID
+	for _, id := range ids {
+		onTarget := versions[id].Equal(target)
+		voter := s.Servers[id].HasVotingRights()
+		switch {
+		case onTarget && voter:
+			upgrade.TargetVersionVoters = append(upgrade.TargetVersionVoters, string(id))
+		case onTarget:
+			upgrade.TargetVersionNonVoters = append(upgrade.TargetVersionNonVoters, string(id))
+		case voter:
+			upgrade.OtherVersionVoters = append(upgrade.OtherVersionVoters, string(id))
+		default:
+			upgrade.OtherVersionNonVoters = append(upgrade.OtherVersionNonVoters, string(id))
+		}
+		if onTarget {
+			targetServers = append(targetServers, id)
+		} else {
+			otherServers = append(otherServers, id)
+		}
+	}
+
+	if info, ok := c.Ext.(*autopilotConfigInfo); ok && info.DisableUpgradeMigration {
+		upgrade.Status = AutopilotUpgradeDisabled
+		return upgrade, ids
+	}
+	if len(otherServers) == 0 {
+		return upgrade, ids
+	}
+	if len(upgrade.OtherVersionVoters) == 0 {
+		upgrade.Status = AutopilotUpgradeAwaitServerRemoval
+		return upgrade, targetServers
+	}
+
+	// Every redundancy zone with an older voter needs a stable server running
+	// the target version to take over, and the older voters without a zone
+	// need as many stable target version servers without a zone.
+	needZones := make(map[string]bool)
+	var needUnzoned int
+	for _, id := range upgrade.OtherVersionVoters {
+		if zone := serverInfo(&s.Servers[raft.ServerID(id)].Server).RedundancyZone; zone != "" {
+			needZones[zone] = true
+		} else {
+			needUnzoned++
+		}
+	}
+	for _, id := range targetServers {
+		srv := s.Servers[id]
+		if !stable(srv) && !srv.HasVotingRights() {
+			continue
+		}
+		if zone := serverInfo(&srv.Server).RedundancyZone; zone != "" {
+			delete(needZones, zone)
+		} else {
+			needUnzoned--
+		}
+	}
+	if len(needZones) > 0 || needUnzoned > 0 {
+		upgrade.Status = AutopilotUpgradeAwaitNewServers
+		return upgrade, otherServers
+	}
+
+	upgrade.Status = AutopilotUpgradePromoting
+	return upgrade, targetServers
+}
+
+// autopilotUpgradeLeader returns the healthy voter running the target version
+// to hand over leadership to, or an empty ID if there is none.
+func autopilotUpgradeLeader(s *autopilot.State, upgrade *AutopilotUpgrade) raft.ServerID {
+	for _, id := range upgrade.TargetVersionVoters {
+		if srv := s.Servers[raft.ServerID(id)]; srv.Health.Healthy {
+			return srv.Server.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,277 @@
+package raft
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/stretchr/testify/require"
+)
+
+type testPromoterServer struct {
+	id      string
+	version string
+	zone    string
+	state   autopilot.RaftState
+	stable  bool
+	replica bool
+}
+
+func testPromoterState(leader string, servers ...testPromoterServer) *autopilot.State {
+	s := &autopilot.State{
+		Leader:  raft.ServerID(leader),
+		Servers: make(map[raft.ServerID]*autopilot.ServerState),
+	}
+	for _, srv := range servers {
+		suffrage := "voter"
+		if srv.replica {
+			suffrage = "non-voter"
+		}
+		health := autopilot.ServerHealth{Healthy: true, StableSince: time.Now()}
+		if srv.stable {
+			health.StableSince = time.Now().Add(-time.Hour)
+		}
+		s.Servers[raft.ServerID(srv.id)] = &autopilot.ServerState{
+			Server: autopilot.Server{
+				ID:      raft.ServerID(srv.id),
+				Version: srv.version,
+				Ext: &autopilotServerInfo{
+					DesiredSuffrage: suffrage,
+					UpgradeVersion:  srv.version,
+					RedundancyZone:  srv.zone,
+				},
+			},
+			State:  srv.state,
+			Health: health,
+		}
+	}
+	return s
+}
+
+func TestAutopilotPromoter_RedundancyZones(t *testing.T) {
+	p := &autopilotPromoter{}
+	c := &autopilot.Config{
+		ServerStabilizationTime: time.Minute,
+		Ext:                     &autopilotConfigInfo{},
+	}
+
+	// A single voter per zone; the other servers of the zone stay non-voters
+	s := testPromoterState("a1",
+		testPromoterServer{id: "a1", version: "1.9.0", zone: "a", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "a2", version: "1.9.0", zone: "a", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "b1", version: "1.9.0", zone: "b", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "b2", version: "1.9.0", zone: "b", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "c1", version: "1.9.0", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "r1", version: "1.9.0", state: autopilot.RaftNonVoter, stable: true, replica: true},
+	)
+	changes := p.CalculatePromotionsAndDemotions(c, s)
+	require.ElementsMatch(t, []raft.ServerID{"b1", "c1"}, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Empty(t, changes.Leader)
+
+	types := p.GetNodeTypes(c, s)
+	require.Equal(t, autopilotNodeZoneVoter, types["a1"])
+	require.Equal(t, autopilotNodeZoneStandby, types["a2"])
+	require.Equal(t, autopilotNodeZoneVoter, types["b1"])
+	require.Equal(t, autopilotNodeZoneStandby, types["b2"])
+	require.Equal(t, autopilot.NodeVoter, types["c1"])
+	require.Equal(t, autopilotNodeReadReplica, types["r1"])
+
+	ext := p.GetStateExt(c, s).(*autopilotStateInfo)
+	require.Equal(t, map[string]AutopilotZone{
+		"a": {Servers: []string{"a1", "a2"}, Voters: []string{"a1"}, FailureTolerance: 1},
+		"b": {Servers: []string{"b1", "b2"}, FailureTolerance: 1},
+	}, ext.RedundancyZones)
+	require.Equal(t, AutopilotUpgradeIdle, ext.Upgrade.Status)
+
+	// When the voter of a zone fails, a stable standby takes over and the
+	// failed voter is demoted once the standby votes
+	s = testPromoterState("a1",
+		testPromoterServer{id: "a1", version: "1.9.0", zone: "a", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "b1", version: "1.9.0", zone: "b", state: autopilot.RaftVoter, stable: true},
+		testPromoterServer{id: "b2", version: "1.9.0", zone: "b", state: autopilot.RaftNonVoter, stable: true},
+	)
+	s.Servers["b1"].Health.Healthy = false
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Equal(t, []raft.ServerID{"b2"}, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+
+	s.Servers["b2"].State = autopilot.RaftVoter
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"b1"}, changes.Demotions)
+}
+
+func TestAutopilotPromoter_Upgrade(t *testing.T) {
+	p := &autopilotPromoter{}
+	c := &autopilot.Config{
+		ServerStabilizationTime: time.Minute,
+		Ext:                     &autopilotConfigInfo{},
+	}
+
+	upgradeStatus := func(s *autopilot.State) string {
+		t.Helper()
+		return p.GetStateExt(c, s).(*autopilotStateInfo).Upgrade.Status
+	}
+
+	// The new servers stay non-voters until there are enough of them
+	s := testPromoterState("o1",
+		testPromoterServer{id: "o1", version: "1.9.0", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "o2", version: "1.9.0", state: autopilot.RaftVoter, stable: true},
+		testPromoterServer{id: "o3", version: "1.9.0", state: autopilot.RaftVoter, stable: true},
+		testPromoterServer{id: "n1", version: "1.10.0", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "n2", version: "1.10.0", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "n3", version: "1.10.0", state: autopilot.RaftNonVoter},
+	)
+	changes := p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, AutopilotUpgradeAwaitNewServers, upgradeStatus(s))
+
+	ext := p.GetStateExt(c, s).(*autopilotStateInfo)
+	require.Equal(t, &AutopilotUpgrade{
+		Status:                 AutopilotUpgradeAwaitNewServers,
+		TargetVersion:          "1.10.0",
+		TargetVersionNonVoters: []string{"n1", "n2", "n3"},
+		OtherVersionVoters:     []string{"o1", "o2", "o3"},
+	}, ext.Upgrade)
+
+	// Once they are all stable, they are promoted
+	s.Servers["n3"].Health.StableSince = time.Now().Add(-time.Hour)
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Equal(t, []raft.ServerID{"n1", "n2", "n3"}, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, AutopilotUpgradePromoting, upgradeStatus(s))
+
+	// Then the older voters are demoted, except for the leader
+	for _, id := range []raft.ServerID{"n1", "n2", "n3"} {
+		s.Servers[id].State = autopilot.RaftVoter
+	}
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Equal(t, []raft.ServerID{"o2", "o3"}, changes.Demotions)
+	require.Empty(t, changes.Leader)
+	require.Equal(t, AutopilotUpgradeDemoting, upgradeStatus(s))
+
+	// Then leadership moves to a new server
+	for _, id := range []raft.ServerID{"o2", "o3"} {
+		s.Servers[id].State = autopilot.RaftNonVoter
+	}
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, raft.ServerID("n1"), changes.Leader)
+	require.Equal(t, AutopilotUpgradeLeaderTransfer, upgradeStatus(s))
+
+	// And the former leader is demoted
+	s.Leader = "n1"
+	s.Servers["n1"].State = autopilot.RaftLeader
+	s.Servers["o1"].State = autopilot.RaftVoter
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Equal(t, []raft.ServerID{"o1"}, changes.Demotions)
+	require.Empty(t, changes.Leader)
+	require.Equal(t, AutopilotUpgradeDemoting, upgradeStatus(s))
+
+	s.Servers["o1"].State = autopilot.RaftNonVoter
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, AutopilotUpgradeAwaitServerRemoval, upgradeStatus(s))
+
+	// Nothing moves when the migration is disabled
+	c.Ext = &autopilotConfigInfo{DisableUpgradeMigration: true}
+	require.Equal(t, AutopilotUpgradeDisabled, upgradeStatus(s))
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.ElementsMatch(t, []raft.ServerID{"o1", "o2", "o3"}, changes.Promotions)
+}
+
+func TestAutopilotPromoter_UpgradeInPlace(t *testing.T) {
+	p := &autopilotPromoter{}
+	c := &autopilot.Config{
+		ServerStabilizationTime: time.Minute,
+		Ext:                     &autopilotConfigInfo{},
+	}
+
+	upgradeStatus := func(s *autopilot.State) string {
+		t.Helper()
+		return p.GetStateExt(c, s).(*autopilotStateInfo).Upgrade.Status
+	}
+
+	// A voter upgraded in place keeps voting while the others are upgraded,
+	// so that restarting the next one does not lose the quorum
+	s := testPromoterState("o1",
+		testPromoterServer{id: "o1", version: "1.9.0", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "o2", version: "1.10.0", state: autopilot.RaftVoter, stable: true},
+		testPromoterServer{id: "o3", version: "1.9.0", state: autopilot.RaftVoter, stable: true},
+	)
+	changes := p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Empty(t, changes.Leader)
+	require.Equal(t, AutopilotUpgradeAwaitNewServers, upgradeStatus(s))
+	require.Equal(t, autopilot.NodeVoter, p.GetNodeTypes(c, s)["o2"])
+
+	// The same goes for a voter upgraded in place while still restarting
+	s.Servers["o2"].Health.StableSince = time.Now()
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Demotions)
+
+	// Once enough voters run the target version, leadership moves to them
+	s.Servers["o2"].Health.StableSince = time.Now().Add(-time.Hour)
+	s.Servers["o3"].Server.Version = "1.10.0"
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+	require.Empty(t, changes.Demotions)
+	require.Equal(t, raft.ServerID("o2"), changes.Leader)
+	require.Equal(t, AutopilotUpgradeLeaderTransfer, upgradeStatus(s))
+}
+
+func TestAutopilotPromoter_UpgradeRedundancyZones(t *testing.T) {
+	p := &autopilotPromoter{}
+	c := &autopilot.Config{
+		ServerStabilizationTime: time.Minute,
+		Ext:                     &autopilotConfigInfo{},
+	}
+
+	// A single new server per zone is enough to replace the zone's voter
+	s := testPromoterState("a1",
+		testPromoterServer{id: "a1", version: "1.9.0", zone: "a", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "b1", version: "1.9.0", zone: "b", state: autopilot.RaftVoter, stable: true},
+		testPromoterServer{id: "a2", version: "1.10.0", zone: "a", state: autopilot.RaftNonVoter, stable: true},
+	)
+	changes := p.CalculatePromotionsAndDemotions(c, s)
+	require.Empty(t, changes.Promotions)
+
+	s = testPromoterState("a1",
+		testPromoterServer{id: "a1", version: "1.9.0", zone: "a", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "b1", version: "1.9.0", zone: "b", state: autopilot.RaftVoter, stable: true},
+		testPromoterServer{id: "a2", version: "1.10.0", zone: "a", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "a3", version: "1.10.0", zone: "a", state: autopilot.RaftNonVoter, stable: true},
+		testPromoterServer{id: "b2", version: "1.10.0", zone: "b", state: autopilot.RaftNonVoter, stable: true},
+	)
+	changes = p.CalculatePromotionsAndDemotions(c, s)
+	require.Equal(t, []raft.ServerID{"a2", "b2"}, changes.Promotions)
+
+	types := p.GetNodeTypes(c, s)
+	require.Equal(t, autopilotNodeZoneVoter, types["a2"])
+	require.Equal(t, autopilotNodeZoneStandby, types["a3"])
+	require.Equal(t, autopilotNodeZoneVoter, types["b2"])
+}
+
+func TestAutopilotPromoter_UnknownVersions(t *testing.T) {
+	p := &autopilotPromoter{}
+	c := &autopilot.Config{
+		ServerStabilizationTime: time.Minute,
+		Ext:                     &autopilotConfigInfo{},
+	}
+
+	// Servers that did not report a version yet leave the upgrades alone
+	s := testPromoterState("n1",
+		testPromoterServer{id: "n1", version: "1.10.0", state: autopilot.RaftLeader, stable: true},
+		testPromoterServer{id: "o1", state: autopilot.RaftNonVoter, stable: true},
+	)
+	require.Nil(t, p.GetStateExt(c, s).(*autopilotStateInfo).Upgrade)
+	changes := p.CalculatePromotionsAndDemotions(c, s)
+	require.Equal(t, []raft.ServerID{"o1"}, changes.Promotions)
+}
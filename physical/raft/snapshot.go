@@ -21,6 +21,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/hashicorp/raft"
+	snapshot "github.com/hashicorp/raft-snapshot"
 )
 
 const (
@@ -520,3 +521,54 @@ func snapshotName(term, index uint64) string {
 	msec := now.UnixNano() / int64(time.Millisecond)
 	return fmt.Sprintf("%d-%d-%d", term, index, msec)
 }
+
+// ReadSnapshot reads the FSM data of a snapshot, as extracted from a snapshot
+// archive, and calls fn with each of its entries in key order. The entry is
+// reused between calls.
+func ReadSnapshot(in io.Reader, fn func(*pb.StorageEntry) error) error {
+	protoReader := NewDelimitedReader(in, math.MaxInt32)
+
+	entry := new(pb.StorageEntry)
+	for {
+		if err := protoReader.ReadMsg(entry); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+}
+
+// ParseSnapshot reads a snapshot archive, as saved by the snapshot API, and
+// calls fn with each of its entries in key order. It does not check that the
+// snapshot was taken with the same seal. The entry is reused between calls.
+func ParseSnapshot(in io.Reader, fn func(*pb.StorageEntry) error) (*raft.SnapshotMeta, error) {
+	reader, writer := io.Pipe()
+
+	var metadata *raft.SnapshotMeta
+	var parseErr error
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		metadata, parseErr = snapshot.Parse(in, writer)
+		writer.CloseWithError(parseErr)
+	}()
+
+	err := ReadSnapshot(reader, fn)
+
+	// Unblock the parsing if the entries were not all read
+	reader.CloseWithError(errors.New("snapshot reading stopped"))
+	<-doneCh
+
+	switch {
+	case err != nil:
+		return nil, err
+	case parseErr != nil:
+		return nil, parseErr
+	}
+	return metadata, nil
+}
@@ -3,6 +3,7 @@ package raft
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"hash/crc64"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"testing"
 	"time"
 
@@ -529,6 +531,66 @@ func TestRaft_Snapshot_Take_Restore(t *testing.T) {
 	compareFSMs(t, raft1.fsm, raft2.fsm)
 }
 
+func TestRaft_ParseSnapshot(t *testing.T) {
+	raft, dir := getRaft(t, true, false)
+	defer os.RemoveAll(dir)
+
+	expected := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key, value := fmt.Sprintf("key-%d", i), fmt.Sprintf("value-%d", i)
+		err := raft.Put(context.Background(), &physical.Entry{
+			Key:   key,
+			Value: []byte(value),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected[key] = value
+	}
+
+	var snap bytes.Buffer
+	if err := raft.Snapshot(&snap, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := make(map[string]string)
+	var keys []string
+	metadata, err := ParseSnapshot(bytes.NewReader(snap.Bytes()), func(entry *pb.StorageEntry) error {
+		actual[entry.Key] = string(entry.Value)
+		keys = append(keys, entry.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.Index == 0 {
+		t.Fatalf("bad metadata: %#v", metadata)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad entries: %v", actual)
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Fatal("expected the entries in key order")
+	}
+
+	// Errors of the callback stop the parsing
+	stopErr := errors.New("stop")
+	var read int
+	_, err = ParseSnapshot(bytes.NewReader(snap.Bytes()), func(*pb.StorageEntry) error {
+		read++
+		return stopErr
+	})
+	if err != stopErr || read != 1 {
+		t.Fatalf("expected the parsing to stop, got %v after %d entries", err, read)
+	}
+
+	// Corrupt snapshots are rejected
+	corrupt := snap.Bytes()[:snap.Len()/2]
+	if _, err := ParseSnapshot(bytes.NewReader(corrupt), func(*pb.StorageEntry) error { return nil }); err == nil {
+		t.Fatal("expected an error parsing a truncated snapshot")
+	}
+}
+
 func TestBoltSnapshotStore_CreateSnapshotMissingParentDir(t *testing.T) {
 	parent, err := ioutil.TempDir("", "raft")
 	if err != nil {
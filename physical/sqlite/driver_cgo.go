@@ -0,0 +1,11 @@
+// +build cgo
+
+package sqlite
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// driverCompiled is set when the SQLite driver, which requires cgo, is
+// compiled in
+const driverCompiled = true
@@ -0,0 +1,7 @@
+// +build !cgo
+
+package sqlite
+
+// driverCompiled is set when the SQLite driver, which requires cgo, is
+// compiled in
+const driverCompiled = false
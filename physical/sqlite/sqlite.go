@@ -0,0 +1,513 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/errwrap"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+const (
+	defaultTableName   = "vault_kv_store"
+	defaultHATableName = "vault_ha_locks"
+
+	// defaultBusyTimeout is how long, in milliseconds, a connection waits
+	// for the database to be unlocked by another connection or process
+	defaultBusyTimeout = 5000
+
+	// SQLiteLockTTL is how long a lock is held without being renewed
+	SQLiteLockTTL = 15 * time.Second
+
+	// SQLiteLockRenewInterval is the amount of time to wait between the lock
+	// renewals
+	SQLiteLockRenewInterval = 5 * time.Second
+
+	// SQLiteLockRetryInterval is the amount of time to wait if a lock fails
+	// before trying again
+	SQLiteLockRetryInterval = time.Second
+)
+
+// Verify SQLiteBackend satisfies the correct interfaces
+var (
+	_ physical.Backend       = (*SQLiteBackend)(nil)
+	_ physical.Transactional = (*SQLiteBackend)(nil)
+	_ physical.HABackend     = (*SQLiteBackend)(nil)
+	_ physical.Lock          = (*SQLiteLock)(nil)
+)
+
+// SQLiteBackend is a physical backend that stores data in a local SQLite
+// database file. The database is used in WAL mode, so that reads are not
+// blocked while a write is in progress.
+type SQLiteBackend struct {
+	table      string
+	haTable    string
+	client     *sql.DB
+	statements map[string]*sql.Stmt
+	haEnabled  bool
+	logger     log.Logger
+	permitPool *physical.PermitPool
+}
+
+// SQLiteLock implements a lock using a row of the HA table. The lock is held
+// until it expires, and is renewed periodically by its holder.
+type SQLiteLock struct {
+	backend    *SQLiteBackend
+	key, value string
+	identity   string
+	lock       sync.Mutex
+
+	// renewStopCh is closed to stop renewing the lock once it is released
+	renewStopCh chan struct{}
+
+	// ttl is how long a lock is valid for
+	ttl time.Duration
+
+	// renewInterval is how much time to wait between lock renewals. must be << ttl
+	renewInterval time.Duration
+
+	// retryInterval is how much time to wait between attempts to grab the lock
+	retryInterval time.Duration
+}
+
+// NewSQLiteBackend constructs a SQLite backend storing the data in the
+// database file at the configured path, which is created if needed.
+func NewSQLiteBackend(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+	if !driverCompiled {
+		return nil, fmt.Errorf("the sqlite storage backend is not compiled in: Vault must be built with CGO_ENABLED=1 to use it")
+	}
+
+	path := conf["path"]
+	if path == "" {
+		return nil, fmt.Errorf("'path' must be set")
+	}
+
+	table := conf["table"]
+	if table == "" {
+		table = defaultTableName
+	}
+	if err := validateDBTable(table); err != nil {
+		return nil, errwrap.Wrapf("invalid table: {{err}}", err)
+	}
+	table = strconv.Quote(table)
+	haTable := conf["ha_table"]
+	if haTable == "" {
+		haTable = defaultHATableName
+	}
+	if err := validateDBTable(haTable); err != nil {
+		return nil, errwrap.Wrapf("invalid ha_table: {{err}}", err)
+	}
+	haTable = strconv.Quote(haTable)
+
+	var err error
+	haEnabled := false
+	if haEnabledStr, ok := conf["ha_enabled"]; ok {
+		haEnabled, err = strconv.ParseBool(haEnabledStr)
+		if err != nil {
+			return nil, fmt.Errorf("value [%v] of 'ha_enabled' could not be understood", haEnabledStr)
+		}
+	}
+
+	maxParInt := physical.DefaultParallelOperations
+	if maxParStr, ok := conf["max_parallel"]; ok {
+		maxParInt, err = strconv.Atoi(maxParStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing max_parallel parameter: {{err}}", err)
+		}
+		if logger.IsDebug() {
+			logger.Debug("max_parallel set", "max_parallel", maxParInt)
+		}
+	}
+
+	busyTimeout := defaultBusyTimeout
+	if busyTimeoutStr, ok := conf["busy_timeout"]; ok {
+		d, err := time.ParseDuration(busyTimeoutStr)
+		if err != nil {
+			return nil, errwrap.Wrapf("failed parsing busy_timeout parameter: {{err}}", err)
+		}
+		busyTimeout = int(d / time.Millisecond)
+	}
+
+	// Transactions take the write lock when they begin, rather than on their
+	// first write, so that concurrent transactions wait for each other instead
+	// of failing to upgrade their lock
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.Itoa(busyTimeout))
+	params.Set("_journal_mode", "WAL")
+	params.Set("_synchronous", "FULL")
+	params.Set("_txlock", "immediate")
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + params.Encode()
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, errwrap.Wrapf("failed to open sqlite database: {{err}}", err)
+	}
+	db.SetMaxOpenConns(maxParInt)
+
+	var journalMode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		db.Close()
+		return nil, errwrap.Wrapf("failed to open sqlite database: {{err}}", err)
+	}
+	if !strings.EqualFold(journalMode, "wal") {
+		db.Close()
+		return nil, fmt.Errorf("failed to enable WAL mode on the sqlite database, journal mode is %q", journalMode)
+	}
+
+	for _, query := range []string{
+		"CREATE TABLE IF NOT EXISTS " + table + " (path TEXT NOT NULL PRIMARY KEY, value BLOB)",
+		"CREATE TABLE IF NOT EXISTS " + haTable + " (ha_key TEXT NOT NULL PRIMARY KEY," +
+			" ha_identity TEXT NOT NULL, ha_value TEXT, valid_until INTEGER NOT NULL)",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			db.Close()
+			return nil, errwrap.Wrapf("failed to create sqlite table: {{err}}", err)
+		}
+	}
+
+	s := &SQLiteBackend{
+		table:      table,
+		haTable:    haTable,
+		client:     db,
+		statements: make(map[string]*sql.Stmt),
+		haEnabled:  haEnabled,
+		logger:     logger,
+		permitPool: physical.NewPermitPool(maxParInt),
+	}
+
+	statements := map[string]string{
+		"put":    "INSERT OR REPLACE INTO " + table + " (path, value) VALUES (?, ?)",
+		"get":    "SELECT value FROM " + table + " WHERE path = ?",
+		"delete": "DELETE FROM " + table + " WHERE path = ?",
+		"list":   "SELECT path FROM " + table + " WHERE path >= ? ORDER BY path",
+
+		// Only read locks that have not expired
+		"ha_get": "SELECT ha_value FROM " + haTable + " WHERE ha_key = ? AND valid_until >= ?",
+		// Either take an expired lock, or extend the lock owned by this identity
+		"ha_upsert": "INSERT INTO " + haTable + " (ha_key, ha_identity, ha_value, valid_until) VALUES (?, ?, ?, ?)" +
+			" ON CONFLICT (ha_key) DO UPDATE SET ha_identity = excluded.ha_identity," +
+			" ha_value = excluded.ha_value, valid_until = excluded.valid_until" +
+			" WHERE " + haTable + ".valid_until < ? OR " + haTable + ".ha_identity = excluded.ha_identity",
+		"ha_delete": "DELETE FROM " + haTable + " WHERE ha_key = ? AND ha_identity = ?",
+	}
+	for name, query := range statements {
+		stmt, err := db.Prepare(query)
+		if err != nil {
+			db.Close()
+			return nil, errwrap.Wrapf(fmt.Sprintf("failed to prepare %q: {{err}}", name), err)
+		}
+		s.statements[name] = stmt
+	}
+
+	return s, nil
+}
+
+// Put is used to insert or update an entry.
+func (s *SQLiteBackend) Put(ctx context.Context, entry *physical.Entry) error {
+	defer metrics.MeasureSince([]string{"sqlite", "put"}, time.Now())
+
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	_, err := s.statements["put"].ExecContext(ctx, entry.Key, entry.Value)
+	return err
+}
+
+// Get is used to fetch an entry.
+func (s *SQLiteBackend) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	defer metrics.MeasureSince([]string{"sqlite", "get"}, time.Now())
+
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	var value []byte
+	err := s.statements["get"].QueryRowContext(ctx, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &physical.Entry{
+		Key:   key,
+		Value: value,
+	}, nil
+}
+
+// Delete is used to permanently delete an entry
+func (s *SQLiteBackend) Delete(ctx context.Context, key string) error {
+	defer metrics.MeasureSince([]string{"sqlite", "delete"}, time.Now())
+
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	_, err := s.statements["delete"].ExecContext(ctx, key)
+	return err
+}
+
+// List is used to list all the keys under a given
+// prefix, up to the next prefix.
+func (s *SQLiteBackend) List(ctx context.Context, prefix string) ([]string, error) {
+	defer metrics.MeasureSince([]string{"sqlite", "list"}, time.Now())
+
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	// The paths are sorted, so the ones with the prefix are all contiguous
+	// and start at the prefix itself
+	rows, err := s.statements["list"].QueryContext(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, errwrap.Wrapf("failed to scan rows: {{err}}", err)
+		}
+		if !strings.HasPrefix(path, prefix) {
+			break
+		}
+
+		key := strings.TrimPrefix(path, prefix)
+		if i := strings.Index(key, "/"); i != -1 {
+			// Add the folder once, all its entries are next to each other
+			key = key[:i+1]
+			if len(keys) > 0 && keys[len(keys)-1] == key {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Transaction is used to run multiple entries via a transaction
+func (s *SQLiteBackend) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	defer metrics.MeasureSince([]string{"sqlite", "transaction"}, time.Now())
+	if len(txns) == 0 {
+		return nil
+	}
+
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	tx, err := s.client.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := s.transaction(ctx, tx, txns); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteBackend) transaction(ctx context.Context, tx *sql.Tx, txns []*physical.TxnEntry) error {
+	putStmt := tx.StmtContext(ctx, s.statements["put"])
+	deleteStmt := tx.StmtContext(ctx, s.statements["delete"])
+
+	for _, op := range txns {
+		var err error
+		switch op.Operation {
+		case physical.PutOperation:
+			_, err = putStmt.ExecContext(ctx, op.Entry.Key, op.Entry.Value)
+		case physical.DeleteOperation:
+			_, err = deleteStmt.ExecContext(ctx, op.Entry.Key)
+		default:
+			return fmt.Errorf("%q is not a supported transaction operation", op.Operation)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HAEnabled indicates whether the HA functionality should be exposed.
+func (s *SQLiteBackend) HAEnabled() bool {
+	return s.haEnabled
+}
+
+// LockWith is used for mutual exclusion based on the given key.
+func (s *SQLiteBackend) LockWith(key, value string) (physical.Lock, error) {
+	identity, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteLock{
+		backend:       s,
+		key:           key,
+		value:         value,
+		identity:      identity,
+		ttl:           SQLiteLockTTL,
+		renewInterval: SQLiteLockRenewInterval,
+		retryInterval: SQLiteLockRetryInterval,
+	}, nil
+}
+
+// Lock tries to acquire the lock by repeatedly trying to write its row in
+// the HA table. It will block until either the stop channel is closed or the
+// lock could be acquired successfully. The returned channel will be closed
+// once the lock cannot be renewed, either due to an error or because someone
+// else has taken it.
+func (l *SQLiteLock) Lock(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var (
+		success = make(chan struct{})
+		errors  = make(chan error, 1)
+		leader  = make(chan struct{})
+	)
+	// try to acquire the lock asynchronously
+	go l.tryToLock(stopCh, success, errors)
+
+	select {
+	case <-success:
+		// after acquiring it successfully, we must renew the lock periodically
+		l.renewStopCh = make(chan struct{})
+		go l.periodicallyRenewLock(l.renewStopCh, leader)
+	case err := <-errors:
+		return nil, err
+	case <-stopCh:
+		return nil, nil
+	}
+
+	return leader, nil
+}
+
+// Unlock releases the lock by deleting its row from the HA table.
+func (l *SQLiteLock) Unlock() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.renewStopCh != nil {
+		close(l.renewStopCh)
+		l.renewStopCh = nil
+	}
+
+	s := l.backend
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	_, err := s.statements["ha_delete"].Exec(l.key, l.identity)
+	return err
+}
+
+// Value checks whether or not the lock is held by any instance of SQLiteLock,
+// including this one, and returns the current value.
+func (l *SQLiteLock) Value() (bool, string, error) {
+	s := l.backend
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	var value string
+	err := s.statements["ha_get"].QueryRow(l.key, time.Now().UnixNano()).Scan(&value)
+	switch err {
+	case nil:
+		return true, value, nil
+	case sql.ErrNoRows:
+		return false, "", nil
+	default:
+		return false, "", err
+	}
+}
+
+// tryToLock tries to write the lock row every `retryInterval`. As long as the
+// row is held by someone else, it will be retried. If the operation fails due
+// to an error, it is sent to the errors channel. When the lock could be
+// acquired successfully, the success channel is closed.
+func (l *SQLiteLock) tryToLock(stop <-chan struct{}, success chan struct{}, errors chan error) {
+	ticker := time.NewTicker(l.retryInterval)
+	defer ticker.Stop()
+
+	for {
+		gotLock, err := l.writeItem()
+		switch {
+		case err != nil:
+			errors <- err
+			return
+		case gotLock:
+			close(success)
+			return
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *SQLiteLock) periodicallyRenewLock(stopCh, done chan struct{}) {
+	ticker := time.NewTicker(l.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		gotLock, err := l.writeItem()
+		if err != nil || !gotLock {
+			close(done)
+			return
+		}
+	}
+}
+
+// writeItem takes the lock if it expired, or extends it if it is held by this
+// identity. Returns true if the lock was obtained, false if someone else holds
+// it.
+func (l *SQLiteLock) writeItem() (bool, error) {
+	s := l.backend
+	s.permitPool.Acquire()
+	defer s.permitPool.Release()
+
+	now := time.Now()
+	result, err := s.statements["ha_upsert"].Exec(l.key, l.identity, l.value, now.Add(l.ttl).UnixNano(), now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// validateDBTable only allows table names made of letters, digits and
+// underscores, so that quoting them is enough to use them in queries.
+func validateDBTable(table string) error {
+	for i, r := range table {
+		if i == 0 && !unicode.IsLetter(r) && r != '_' {
+			return fmt.Errorf("must use a letter or an underscore as the first character")
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return fmt.Errorf("must only contain letters, underscores and digits")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// +build cgo
+
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+func testSQLiteBackend(t *testing.T, conf map[string]string) physical.Backend {
+	t.Helper()
+
+	logger := logging.NewVaultLogger(log.Debug)
+	b, err := NewSQLiteBackend(conf, logger)
+	if err != nil {
+		t.Fatalf("Failed to create new backend: %v", err)
+	}
+	return b
+}
+
+func truncate(t *testing.T, b physical.Backend) {
+	s := b.(*SQLiteBackend)
+	if _, err := s.client.Exec("DELETE FROM " + s.table); err != nil {
+		t.Fatalf("Failed to truncate table: %v", err)
+	}
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := testSQLiteBackend(t, map[string]string{
+		"path": filepath.Join(dir, "vault.db"),
+	})
+
+	physical.ExerciseBackend(t, b)
+	truncate(t, b)
+	physical.ExerciseBackend_ListPrefix(t, b)
+	truncate(t, b)
+	physical.ExerciseTransactionalBackend(t, b)
+}
+
+func TestSQLiteBackend_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := map[string]string{
+		"path":  filepath.Join(dir, "vault.db"),
+		"table": "select",
+	}
+	b := testSQLiteBackend(t, conf)
+	if err := b.Put(context.Background(), &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	b.(*SQLiteBackend).client.Close()
+
+	b = testSQLiteBackend(t, conf)
+	entry, err := b.Get(context.Background(), "foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry == nil || string(entry.Value) != "bar" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	var journalMode string
+	if err := b.(*SQLiteBackend).client.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Fatalf("bad journal mode: %q", journalMode)
+	}
+}
+
+func TestSQLiteBackend_InvalidConfig(t *testing.T) {
+	logger := logging.NewVaultLogger(log.Debug)
+	for _, conf := range []map[string]string{
+		{},
+		{"path": "vault.db", "table": "vault\"; DROP TABLE vault"},
+		{"path": "vault.db", "ha_table": "1vault"},
+		{"path": "vault.db", "ha_enabled": "maybe"},
+	} {
+		if _, err := NewSQLiteBackend(conf, logger); err == nil {
+			t.Fatalf("expected error for %v", conf)
+		}
+	}
+}
+
+func TestSQLiteHABackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := map[string]string{
+		"path":       filepath.Join(dir, "vault.db"),
+		"ha_enabled": "true",
+	}
+	b := testSQLiteBackend(t, conf)
+	b2 := testSQLiteBackend(t, conf)
+
+	ha1, ok := b.(physical.HABackend)
+	if !ok || !ha1.HAEnabled() {
+		t.Fatalf("SQLite does not implement HABackend")
+	}
+	ha2 := b2.(physical.HABackend)
+
+	physical.ExerciseHABackend(t, ha1, ha2)
+}
+
+func TestSQLiteLock_Expiry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := testSQLiteBackend(t, map[string]string{
+		"path":       filepath.Join(dir, "vault.db"),
+		"ha_enabled": "true",
+	}).(*SQLiteBackend)
+
+	lock, err := b.LockWith("foo", "bar")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l := lock.(*SQLiteLock)
+	l.ttl = 500 * time.Millisecond
+	l.renewInterval = 100 * time.Millisecond
+
+	leaderCh, err := l.Lock(nil)
+	if err != nil || leaderCh == nil {
+		t.Fatalf("failed to get leader ch: %v", err)
+	}
+
+	// The lock outlives its TTL while it is renewed
+	time.Sleep(time.Second)
+	held, value, err := l.Value()
+	if err != nil || !held || value != "bar" {
+		t.Fatalf("bad: %v %q %v", held, value, err)
+	}
+
+	// Someone else takes the lock once it expires
+	lock2, err := b.LockWith("foo", "baz")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	l2 := lock2.(*SQLiteLock)
+	l2.retryInterval = 50 * time.Millisecond
+	if _, err := b.client.Exec("UPDATE "+b.haTable+" SET valid_until = 0 WHERE ha_key = ?", "foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := l2.Lock(nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	select {
+	case <-leaderCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("leadership was not lost")
+	}
+	if err := l2.Unlock(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -8,6 +8,10 @@ type LogInput struct {
 	OuterErr            error
 	NonHMACReqDataKeys  []string
 	NonHMACRespDataKeys []string
+
+	// AuditFieldSelectors are the field selectors of the mount, as parsed by
+	// audit.ParseFieldSelectors when the mount is tuned
+	AuditFieldSelectors interface{}
 }
 
 type MarshalOptions struct {
@@ -100,6 +100,10 @@ type Auth struct {
 
 	// Orphan is set if the token does not have a parent
 	Orphan bool `json:"orphan"`
+
+	// MFAValidated is set by the auth method when the login was verified with
+	// a second factor
+	MFAValidated bool `json:"mfa_validated"`
 }
 
 func (a *Auth) GoString() string {
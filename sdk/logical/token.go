@@ -141,6 +141,21 @@ type TokenEntry struct {
 	// CubbyholeID is the identifier of the cubbyhole storage belonging to this
 	// token
 	CubbyholeID string `json:"cubbyhole_id" mapstructure:"cubbyhole_id" structs:"cubbyhole_id" sentinel:""`
+
+	// Time the token was last used to authenticate a request. This is
+	// sampled, so it may lag behind actual use by a bounded interval.
+	LastUsedTime int64 `json:"last_used_time" mapstructure:"last_used_time" structs:"last_used_time" sentinel:""`
+
+	// Number of requests the token has been used for, as of LastUsedTime
+	UseCount int64 `json:"use_count" mapstructure:"use_count" structs:"use_count" sentinel:""`
+
+	// If set, the token is revoked once it has gone unused for longer than
+	// this duration
+	MaxIdleTTL time.Duration `json:"max_idle_ttl" mapstructure:"max_idle_ttl" structs:"max_idle_ttl" sentinel:""`
+
+	// MFAValidated is set if the token was issued by a login that was verified
+	// with a second factor. It is not carried over to child tokens.
+	MFAValidated bool `json:"mfa_validated" mapstructure:"mfa_validated" structs:"mfa_validated" sentinel:""`
 }
 
 func (te *TokenEntry) SentinelGet(key string) (interface{}, error) {
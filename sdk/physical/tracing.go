@@ -0,0 +1,106 @@
+package physical
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/hashicorp/vault/sdk/physical"
+
+// Tracing is used to trace the underlying physical requests with the global
+// OpenTelemetry tracer provider
+type Tracing struct {
+	backend Backend
+	tracer  trace.Tracer
+}
+
+// TransactionalTracing is the transactional version of Tracing
+type TransactionalTracing struct {
+	*Tracing
+	Transactional
+}
+
+// Verify Tracing satisfies the correct interfaces
+var (
+	_ Backend       = (*Tracing)(nil)
+	_ Transactional = (*TransactionalTracing)(nil)
+)
+
+// NewTracing returns a wrapped physical backend that traces its requests
+func NewTracing(b Backend) *Tracing {
+	return &Tracing{
+		backend: b,
+		tracer:  otel.Tracer(tracerName),
+	}
+}
+
+// NewTransactionalTracing creates a new transactional Tracing
+func NewTransactionalTracing(b Backend) *TransactionalTracing {
+	return &TransactionalTracing{
+		Tracing:       NewTracing(b),
+		Transactional: b.(Transactional),
+	}
+}
+
+func (t *Tracing) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, "storage."+op, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+}
+
+// keyAttribute returns the attribute recording a storage key or prefix, cut
+// after its first two segments, e.g. "logical/<mount uuid>/": the rest of the
+// key may be a request path holding sensitive data, such as the name of a
+// secret, which the audit log only records HMAC-ed.
+func keyAttribute(name, key string) attribute.KeyValue {
+	if parts := strings.SplitN(key, "/", 3); len(parts) == 3 {
+		key = parts[0] + "/" + parts[1] + "/"
+	}
+	return attribute.String(name, key)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Put is a traced put request
+func (t *Tracing) Put(ctx context.Context, entry *Entry) (err error) {
+	ctx, span := t.start(ctx, "put", keyAttribute("storage.key", entry.Key), attribute.Int("storage.value_size", len(entry.Value)))
+	defer func() { endSpan(span, err) }()
+	return t.backend.Put(ctx, entry)
+}
+
+// Get is a traced get request
+func (t *Tracing) Get(ctx context.Context, key string) (_ *Entry, err error) {
+	ctx, span := t.start(ctx, "get", keyAttribute("storage.key", key))
+	defer func() { endSpan(span, err) }()
+	return t.backend.Get(ctx, key)
+}
+
+// Delete is a traced delete request
+func (t *Tracing) Delete(ctx context.Context, key string) (err error) {
+	ctx, span := t.start(ctx, "delete", keyAttribute("storage.key", key))
+	defer func() { endSpan(span, err) }()
+	return t.backend.Delete(ctx, key)
+}
+
+// List is a traced list request
+func (t *Tracing) List(ctx context.Context, prefix string) (_ []string, err error) {
+	ctx, span := t.start(ctx, "list", keyAttribute("storage.prefix", prefix))
+	defer func() { endSpan(span, err) }()
+	return t.backend.List(ctx, prefix)
+}
+
+// Transaction is a traced transaction request
+func (t *TransactionalTracing) Transaction(ctx context.Context, txns []*TxnEntry) (err error) {
+	ctx, span := t.start(ctx, "transaction", attribute.Int("storage.operations", len(txns)))
+	defer func() { endSpan(span, err) }()
+	return t.Transactional.Transaction(ctx, txns)
+}
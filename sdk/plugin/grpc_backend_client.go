@@ -80,11 +80,14 @@ func (b *backendGRPCPluginClient) Initialize(ctx context.Context, _ *logical.Ini
 	return nil
 }
 
-func (b *backendGRPCPluginClient) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+func (b *backendGRPCPluginClient) HandleRequest(ctx context.Context, req *logical.Request) (_ *logical.Response, retErr error) {
 	if b.metadataMode {
 		return nil, ErrClientInMetadataMode
 	}
 
+	ctx, span := startClientSpan(ctx, "plugin.HandleRequest", req)
+	defer func() { endSpan(span, retErr) }()
+
 	ctx, cancel := context.WithCancel(ctx)
 	quitCh := pluginutil.CtxCancelIfCanceled(cancel, b.doneCtx)
 	defer close(quitCh)
@@ -71,7 +71,9 @@ func (b *backendGRPCPluginServer) HandleRequest(ctx context.Context, args *pb.Ha
 
 	logicalReq.Storage = newGRPCStorageClient(b.brokeredClient)
 
+	ctx, span := startServerSpan(ctx, "plugin.HandleRequest", logicalReq)
 	resp, respErr := b.backend.HandleRequest(ctx, logicalReq)
+	endSpan(span, respErr)
 
 	pbResp, err := pb.LogicalResponseToProtoResponse(resp)
 	if err != nil {
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const tracerName = "github.com/hashicorp/vault/sdk/plugin"
+
+// tracePropagator is used to propagate the trace context between Vault and
+// the plugins over gRPC. It is set explicitly rather than taken from the
+// global propagator, since plugins do not configure one.
+var tracePropagator = propagation.TraceContext{}
+
+// metadataCarrier adapts gRPC metadata to a propagation.TextMapCarrier
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// startClientSpan starts a span for a gRPC call to the plugin, and injects
+// its context into the outgoing gRPC metadata.
+func startClientSpan(ctx context.Context, name string, req *logical.Request) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(requestAttributes(req)...))
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	tracePropagator.Inject(ctx, metadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md), span
+}
+
+// startServerSpan extracts the trace context from the incoming gRPC metadata,
+// and starts a span for the gRPC call handled by the plugin.
+func startServerSpan(ctx context.Context, name string, req *logical.Request) (context.Context, trace.Span) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = tracePropagator.Extract(ctx, metadataCarrier(md))
+	}
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(requestAttributes(req)...))
+}
+
+// requestAttributes returns the attributes of the span of a request. The
+// request path is not recorded, as it may hold sensitive data which the audit
+// log only records HMAC-ed.
+func requestAttributes(req *logical.Request) []attribute.KeyValue {
+	if req == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		attribute.String("vault.operation", string(req.Operation)),
+		attribute.String("vault.mount_point", req.MountPoint),
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/armon/go-radix"
 	"github.com/hashicorp/go-multierror"
@@ -32,6 +33,12 @@ type ACL struct {
 
 	// Stores policies that are actually RGPs for later fetching
 	rgpPolicies []*Policy
+
+	// hasConditions is set if any path rule carries conditions, in which case
+	// the identity of the caller is attached for their evaluation
+	hasConditions bool
+	entity        *identity.Entity
+	groups        []*identity.Group
 }
 
 type PolicyCheckOpts struct {
@@ -54,6 +61,10 @@ type ACLResults struct {
 	MFAMethods         []string
 	ControlGroup       *ControlGroup
 	CapabilitiesBitmap uint32
+
+	// ConditionError is set when the operation was denied because a policy
+	// condition on the matching path did not hold
+	ConditionError error
 }
 
 // NewACL is used to construct a policy based ACL from a set of policies.
@@ -121,11 +132,22 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 				raw, ok = tree.Get(pc.Path)
 			}
 
+			grant := policyConditionalGrant(policy, pc)
+			if grant != nil {
+				a.hasConditions = true
+			}
+
 			if !ok {
 				clonedPerms, err := pc.Permissions.Clone()
 				if err != nil {
 					return nil, fmt.Errorf("error cloning ACL permissions: %w", err)
 				}
+				clonedPerms.Conditions = nil
+				if grant != nil {
+					clonedPerms.ConditionalGrants = []*ConditionalGrant{grant}
+				} else {
+					clonedPerms.UnconditionalCapabilitiesBitmap = clonedPerms.CapabilitiesBitmap
+				}
 				switch {
 				case pc.HasSegmentWildcards:
 					a.segmentWildcardPaths[pc.Path] = clonedPerms
@@ -147,14 +169,22 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 			case pc.Permissions.CapabilitiesBitmap&DenyCapabilityInt > 0:
 				// If this new policy explicitly denies, only save the deny value
 				existingPerms.CapabilitiesBitmap = DenyCapabilityInt
+				existingPerms.UnconditionalCapabilitiesBitmap = DenyCapabilityInt
 				existingPerms.AllowedParameters = nil
 				existingPerms.DeniedParameters = nil
+				existingPerms.ConditionalGrants = nil
 				goto INSERT
 
 			default:
 				// Insert the capabilities in this new policy into the existing
-				// value
+				// value. The capabilities of a rule with conditions are only
+				// usable when its own conditions hold.
 				existingPerms.CapabilitiesBitmap = existingPerms.CapabilitiesBitmap | pc.Permissions.CapabilitiesBitmap
+				if grant != nil {
+					existingPerms.ConditionalGrants = append(existingPerms.ConditionalGrants, grant)
+				} else {
+					existingPerms.UnconditionalCapabilitiesBitmap = existingPerms.UnconditionalCapabilitiesBitmap | pc.Permissions.CapabilitiesBitmap
+				}
 			}
 
 			// Note: In these stanzas, we're preferring minimum lifetimes. So
@@ -271,6 +301,56 @@ func NewACL(ctx context.Context, policies []*Policy) (*ACL, error) {
 	return a, nil
 }
 
+// policyConditionalGrant returns the capabilities of the path rule along with
+// copies of its conditions attributed to the given policy, or nil if the rule
+// has no conditions. Denials are never conditional.
+func policyConditionalGrant(policy *Policy, pc *PathRules) *ConditionalGrant {
+	if len(pc.Permissions.Conditions) == 0 || pc.Permissions.CapabilitiesBitmap&DenyCapabilityInt > 0 {
+		return nil
+	}
+
+	conditions := make([]*PolicyCondition, 0, len(pc.Permissions.Conditions))
+	for _, c := range pc.Permissions.Conditions {
+		attributed := *c
+		attributed.Policy = policy.Name
+		conditions = append(conditions, &attributed)
+	}
+	return &ConditionalGrant{
+		CapabilitiesBitmap: pc.Permissions.CapabilitiesBitmap,
+		Conditions:         conditions,
+	}
+}
+
+// conditionalCapabilities returns the capabilities usable on the path for the
+// request: the ones granted unconditionally, and the ones of the conditional
+// grants whose conditions hold. If the required capability is only granted by
+// grants whose conditions do not hold, the reason of the first of them is
+// returned.
+func (a *ACL) conditionalCapabilities(req *logical.Request, permissions *ACLPermissions, required uint32) (uint32, error) {
+	in := &conditionInput{
+		now:    time.Now(),
+		req:    req,
+		entity: a.entity,
+		groups: a.groups,
+	}
+
+	capabilities := permissions.UnconditionalCapabilitiesBitmap
+	var conditionErr error
+	for _, grant := range permissions.ConditionalGrants {
+		if err := grant.evaluate(in); err != nil {
+			if conditionErr == nil && grant.CapabilitiesBitmap&required > 0 {
+				conditionErr = err
+			}
+			continue
+		}
+		capabilities |= grant.CapabilitiesBitmap
+	}
+	if capabilities&required > 0 {
+		conditionErr = nil
+	}
+	return capabilities, conditionErr
+}
+
 func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities []string) {
 	req := &logical.Request{
 		Path: path,
@@ -284,8 +364,12 @@ func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities [
 		return []string{RootCapability}
 	}
 
-	capabilities := res.CapabilitiesBitmap
+	return capabilitiesFromBitmap(res.CapabilitiesBitmap)
+}
 
+// capabilitiesFromBitmap returns the names of the capabilities set in the
+// given bitmap.
+func capabilitiesFromBitmap(capabilities uint32) (pathCapabilities []string) {
 	if capabilities&SudoCapabilityInt > 0 {
 		pathCapabilities = append(pathCapabilities, SudoCapability)
 	}
@@ -313,30 +397,13 @@ func (a *ACL) Capabilities(ctx context.Context, path string) (pathCapabilities [
 	return
 }
 
-// AllowOperation is used to check if the given operation is permitted.
-func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheckOnly bool) (ret *ACLResults) {
-	ret = new(ACLResults)
-
-	// Fast-path root
-	if a.root {
-		ret.Allowed = true
-		ret.RootPrivs = true
-		ret.IsRoot = true
-		return
-	}
-	op := req.Operation
-
-	// Help is always allowed
-	if op == logical.HelpOperation {
-		ret.Allowed = true
-		return
-	}
-
-	var permissions *ACLPermissions
-
+// matchingPermissions returns the permissions of the most specific path rule
+// matching the request, along with the path of that rule as written in the
+// policies. A nil set of permissions is returned if no rule matches.
+func (a *ACL) matchingPermissions(ctx context.Context, req *logical.Request) (*ACLPermissions, string, error) {
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
-		return
+		return nil, "", err
 	}
 	path := ns.Path + req.Path
 
@@ -351,33 +418,56 @@ func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheck
 	}
 
 	// Find an exact matching rule, look for prefix if no match
-	var capabilities uint32
 	raw, ok := a.exactRules.Get(path)
 	if ok {
-		permissions = raw.(*ACLPermissions)
-		capabilities = permissions.CapabilitiesBitmap
-		goto CHECK
+		return raw.(*ACLPermissions), path, nil
 	}
-	if op == logical.ListOperation {
-		raw, ok = a.exactRules.Get(strings.TrimSuffix(path, "/"))
+	if req.Operation == logical.ListOperation {
+		listPath := strings.TrimSuffix(path, "/")
+		raw, ok = a.exactRules.Get(listPath)
 		if ok {
-			permissions = raw.(*ACLPermissions)
-			capabilities = permissions.CapabilitiesBitmap
-			goto CHECK
+			return raw.(*ACLPermissions), listPath, nil
 		}
 	}
 
-	permissions = a.CheckAllowedFromNonExactPaths(path, false)
-	if permissions != nil {
-		capabilities = permissions.CapabilitiesBitmap
-		goto CHECK
+	permissions, rule := a.checkNonExactPaths(path, false)
+	return permissions, rule, nil
+}
+
+// AllowOperation is used to check if the given operation is permitted.
+func (a *ACL) AllowOperation(ctx context.Context, req *logical.Request, capCheckOnly bool) (ret *ACLResults) {
+	ret = new(ACLResults)
+
+	// Fast-path root
+	if a.root {
+		ret.Allowed = true
+		ret.RootPrivs = true
+		ret.IsRoot = true
+		return
 	}
+	op := req.Operation
 
-	// No exact, prefix, or segment wildcard paths found, return without
-	// setting allowed
-	return
+	// Help is always allowed
+	if op == logical.HelpOperation {
+		ret.Allowed = true
+		return
+	}
+
+	permissions, _, err := a.matchingPermissions(ctx, req)
+	if err != nil || permissions == nil {
+		// No exact, prefix, or segment wildcard paths found, return without
+		// setting allowed
+		return
+	}
+	capabilities := permissions.CapabilitiesBitmap
+
+	// The capabilities of the rules with conditions are reported as granted
+	// on the path, but only usable when their conditions hold
+	var conditionErr error
+	if !capCheckOnly && len(permissions.ConditionalGrants) > 0 {
+		capabilities, conditionErr = a.conditionalCapabilities(req, permissions, cap2Int[operationCapability(op)])
+	}
 
-CHECK:
 	// Check if the minimum permissions are met
 	// If "deny" has been explicitly set, only deny will be in the map, so we
 	// only need to check for the existence of other values
@@ -416,6 +506,7 @@ CHECK:
 	}
 
 	if !operationAllowed {
+		ret.ConditionError = conditionErr
 		return
 	}
 
@@ -517,6 +608,13 @@ type wcPathDescr struct {
 // of permissions from some allowed path underneath the mount (for use in mount
 // access checks), or nil indicating no non-deny permissions were found.
 func (a *ACL) CheckAllowedFromNonExactPaths(path string, bareMount bool) *ACLPermissions {
+	permissions, _ := a.checkNonExactPaths(path, bareMount)
+	return permissions
+}
+
+// checkNonExactPaths implements CheckAllowedFromNonExactPaths, additionally
+// returning the path of the matching rule as written in the policies.
+func (a *ACL) checkNonExactPaths(path string, bareMount bool) (*ACLPermissions, string) {
 	wcPathDescrs := make([]wcPathDescr, 0, len(a.segmentWildcardPaths)+1)
 
 	less := func(i, j int) bool {
@@ -580,7 +678,7 @@ func (a *ACL) CheckAllowedFromNonExactPaths(path string, bareMount bool) *ACLPer
 		prefix, raw, ok := a.prefixRules.LongestPrefix(path)
 		if ok {
 			if len(a.segmentWildcardPaths) == 0 {
-				return raw.(*ACLPermissions)
+				return raw.(*ACLPermissions), prefix + "*"
 			}
 			wcPathDescrs = append(wcPathDescrs, wcPathDescr{
 				firstWCOrGlob: len(prefix),
@@ -592,7 +690,7 @@ func (a *ACL) CheckAllowedFromNonExactPaths(path string, bareMount bool) *ACLPer
 	}
 
 	if len(a.segmentWildcardPaths) == 0 {
-		return nil
+		return nil, ""
 	}
 
 	pathParts := strings.Split(path, "/")
@@ -648,7 +746,7 @@ SWCPATH:
 				if strings.HasPrefix(joinedPath, path) {
 					permissions := a.segmentWildcardPaths[fullWCPath].(*ACLPermissions)
 					if permissions.CapabilitiesBitmap&DenyCapabilityInt == 0 && permissions.CapabilitiesBitmap > 0 {
-						return permissions
+						return permissions, fullWCPath
 					}
 				}
 				continue SWCPATH
@@ -659,14 +757,18 @@ SWCPATH:
 	}
 
 	if bareMount || len(wcPathDescrs) == 0 {
-		return nil
+		return nil, ""
 	}
 
 	// We don't do this in the bare mount check because we don't care about
 	// priority, we only care about any capability at all.
 	sort.Slice(wcPathDescrs, less)
 
-	return wcPathDescrs[len(wcPathDescrs)-1].perms
+	match := wcPathDescrs[len(wcPathDescrs)-1]
+	if match.isPrefix {
+		return match.perms, match.wcPath + "*"
+	}
+	return match.perms, match.wcPath
 }
 
 func (c *Core) performPolicyChecks(ctx context.Context, acl *ACL, te *logical.TokenEntry, req *logical.Request, inEntity *identity.Entity, opts *PolicyCheckOpts) *AuthResults {
@@ -685,6 +787,12 @@ func (c *Core) performPolicyChecks(ctx context.Context, acl *ACL, te *logical.To
 			return ret
 		}
 		if !ret.ACLResults.Allowed {
+			// The reason of the denial is not returned to the client, as
+			// it would disclose the policies of the token
+			if ret.ACLResults.ConditionError != nil {
+				ret.Error = multierror.Append(ret.Error, errPolicyConditionNotSatisfied)
+				ret.DeniedError = true
+			}
 			return ret
 		}
 		if !ret.RootPrivs && opts.RootPrivsRequired {
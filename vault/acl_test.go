@@ -519,6 +519,95 @@ func testACLAllowOperation(t *testing.T, ns *namespace.Namespace) {
 	}
 }
 
+func TestACL_Conditions(t *testing.T) {
+	t.Run("root-ns", func(t *testing.T) {
+		t.Parallel()
+		testACLConditions(t, namespace.RootNamespace)
+	})
+}
+
+func testACLConditions(t *testing.T, ns *namespace.Namespace) {
+	ctx := namespace.ContextWithNamespace(context.Background(), ns)
+
+	policy, err := ParseACLPolicy(ns, conditionsPolicy)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy.Name = "conditions"
+	policy2, err := ParseACLPolicy(ns, conditionsPolicy2)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	policy2.Name = "conditions2"
+
+	acl, err := NewACL(ctx, []*Policy{policy, policy2})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	type tcase struct {
+		op         logical.Operation
+		path       string
+		remoteAddr string
+		allowed    bool
+		reason     string
+	}
+	tcases := []tcase{
+		{logical.ReadOperation, "secret/internal", "10.0.0.1", true, ""},
+		{logical.ReadOperation, "secret/internal", "192.168.0.1", false, `condition "internal" of policy "conditions" on path "secret/*" was not satisfied`},
+		{logical.ReadOperation, "secret/internal", "", false, `condition "internal" of policy "conditions" on path "secret/*" was not satisfied`},
+		// Any rule on the path whose conditions hold grants its capabilities
+		{logical.ReadOperation, "secret/sensitive", "10.0.0.1", true, ""},
+		{logical.ReadOperation, "secret/sensitive", "192.168.0.1", true, ""},
+		{logical.ReadOperation, "secret/sensitive", "172.16.0.1", false, `condition "office" of policy "conditions2" on path "secret/sensitive" was not satisfied`},
+		// Conditions only gate the capabilities of the rule declaring them
+		{logical.UpdateOperation, "secret/shared", "172.16.0.1", true, ""},
+		{logical.ReadOperation, "secret/shared", "172.16.0.1", false, `condition "internal" of policy "conditions2" on path "secret/shared" was not satisfied`},
+		{logical.ReadOperation, "secret/shared", "10.0.0.1", true, ""},
+		// Unconditional paths are unaffected
+		{logical.ReadOperation, "public/foo", "192.168.0.1", true, ""},
+	}
+
+	for _, tc := range tcases {
+		req := &logical.Request{
+			Operation: tc.op,
+			Path:      tc.path,
+		}
+		if tc.remoteAddr != "" {
+			req.Connection = &logical.Connection{RemoteAddr: tc.remoteAddr}
+		}
+
+		authResults := acl.AllowOperation(ctx, req, false)
+		if authResults.Allowed != tc.allowed {
+			t.Fatalf("bad: case %#v: %v", tc, authResults.Allowed)
+		}
+		switch {
+		case tc.reason == "" && authResults.ConditionError != nil:
+			t.Fatalf("bad: case %#v: unexpected condition error %v", tc, authResults.ConditionError)
+		case tc.reason != "" && (authResults.ConditionError == nil || authResults.ConditionError.Error() != tc.reason):
+			t.Fatalf("bad: case %#v: expected reason %q, got %v", tc, tc.reason, authResults.ConditionError)
+		}
+	}
+
+	// Deny overrides any condition
+	denyPolicy, err := ParseACLPolicy(ns, `path "secret/*" { capabilities = ["deny"] }`)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	acl, err = NewACL(ctx, []*Policy{policy, denyPolicy})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	authResults := acl.AllowOperation(ctx, &logical.Request{
+		Operation:  logical.ReadOperation,
+		Path:       "secret/internal",
+		Connection: &logical.Connection{RemoteAddr: "10.0.0.1"},
+	}, false)
+	if authResults.Allowed || authResults.ConditionError != nil {
+		t.Fatalf("bad: %#v", authResults)
+	}
+}
+
 func TestACL_ValuePermissions(t *testing.T) {
 	t.Run("root-ns", func(t *testing.T) {
 		t.Parallel()
@@ -1182,3 +1271,39 @@ path "test/star" {
 	}
 }
 `
+
+var conditionsPolicy = `
+path "secret/*" {
+	capabilities = ["read"]
+	condition "internal" {
+		expression = "cidr_match(request.remote_addr, \"10.0.0.0/8\")"
+	}
+}
+path "public/*" {
+	capabilities = ["read"]
+}
+`
+
+var conditionsPolicy2 = `
+path "secret/sensitive" {
+	capabilities = ["read"]
+	condition "office" {
+		expression = "cidr_match(request.remote_addr, \"192.168.0.0/16\")"
+	}
+}
+path "secret/sensitive" {
+	capabilities = ["read"]
+	condition "internal" {
+		expression = "cidr_match(request.remote_addr, \"10.0.0.0/8\")"
+	}
+}
+path "secret/shared" {
+	capabilities = ["update"]
+}
+path "secret/shared" {
+	capabilities = ["read"]
+	condition "internal" {
+		expression = "cidr_match(request.remote_addr, \"10.0.0.0/8\")"
+	}
+}
+`
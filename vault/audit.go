@@ -84,6 +84,14 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 		}
 	}
 
+	filter, err := parseAuditFilter(entry.Options[auditFilterOption])
+	if err != nil {
+		return err
+	}
+	if err := validateAuditFilters(append(c.audit.shallowClone().Entries, entry)); err != nil {
+		return err
+	}
+
 	// Generate a new UUID and view
 	if entry.UUID == "" {
 		entryUUID, err := uuid.GenerateUUID()
@@ -118,6 +126,12 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	if backend == nil {
 		return fmt.Errorf("nil audit backend of type %q returned from factory", entry.Type)
 	}
+	registered := false
+	defer func() {
+		if !registered {
+			closeAuditBackend(backend)
+		}
+	}()
 
 	if entry.Options["skip_test"] != "true" {
 		// Test the new audit device and report failure if it doesn't work.
@@ -152,7 +166,8 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view, entry.Local)
+	c.auditBroker.Register(entry.Path, backend, view, entry.Local, filter)
+	registered = true
 	if c.logger.IsInfo() {
 		c.logger.Info("enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -187,6 +202,10 @@ func (c *Core) disableAudit(ctx context.Context, path string, updateStorage bool
 		return false, fmt.Errorf("no matching backend")
 	}
 
+	if err := validateAuditFilters(newTable.Entries); err != nil {
+		return false, fmt.Errorf("cannot disable the audit device: %w", err)
+	}
+
 	c.removeAuditReloadFunc(entry)
 
 	// When unmounting all entries the JSON code will load back up from storage
@@ -414,8 +433,16 @@ func (c *Core) setupAudits(ctx context.Context) error {
 			continue
 		}
 
+		// Unlike the backend, the filter was validated when the device was
+		// enabled. Skipping the device would silently change what is audited.
+		filter, err := parseAuditFilter(entry.Options[auditFilterOption])
+		if err != nil {
+			c.logger.Error("failed to parse audit filter", "path", entry.Path, "error", err)
+			return fmt.Errorf("failed to parse the audit filter of %q: %w", entry.Path, err)
+		}
+
 		// Mount the backend
-		broker.Register(entry.Path, backend, view, entry.Local)
+		broker.Register(entry.Path, backend, view, entry.Local, filter)
 
 		successCount++
 	}
@@ -441,17 +468,28 @@ func (c *Core) teardownAudits() error {
 		}
 	}
 
+	if c.auditBroker != nil {
+		c.auditBroker.closeBackends()
+	}
+
 	c.audit = nil
 	c.auditBroker = nil
 	return nil
 }
 
+// closeAuditBackend stops the background work of the backend, if any
+func closeAuditBackend(b audit.Backend) {
+	if closeable, ok := b.(audit.CloseableBackend); ok {
+		closeable.Close()
+	}
+}
+
 // removeAuditReloadFunc removes the reload func from the working set. The
 // audit lock needs to be held before calling this.
 func (c *Core) removeAuditReloadFunc(entry *MountEntry) {
 	switch entry.Type {
-	case "file":
-		key := "audit_file|" + entry.Path
+	case "file", "http":
+		key := "audit_" + entry.Type + "|" + entry.Path
 		c.reloadFuncsLock.Lock()
 
 		if c.logger.IsDebug() {
@@ -476,10 +514,14 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		Location: salt.DefaultLocation,
 	}
 
+	auditLogger := c.baseLogger.Named("audit")
+	c.AddLogger(auditLogger)
+
 	be, err := f(ctx, &audit.BackendConfig{
 		SaltView:   view,
 		SaltConfig: saltConfig,
 		Config:     conf,
+		Logger:     auditLogger.With("path", entry.Path),
 	})
 	if err != nil {
 		return nil, err
@@ -488,9 +530,6 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		return nil, fmt.Errorf("nil backend returned from %q factory function", entry.Type)
 	}
 
-	auditLogger := c.baseLogger.Named("audit")
-	c.AddLogger(auditLogger)
-
 	switch entry.Type {
 	case "file":
 		key := "audit_file|" + entry.Path
@@ -511,6 +550,26 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 			return be.Reload(ctx)
 		})
 
+		c.reloadFuncsLock.Unlock()
+	case "http":
+		key := "audit_http|" + entry.Path
+
+		c.reloadFuncsLock.Lock()
+
+		if auditLogger.IsDebug() {
+			auditLogger.Debug("adding reload function", "path", entry.Path)
+			if entry.Options != nil {
+				auditLogger.Debug("http backend options", "path", entry.Path, "address", entry.Options["address"])
+			}
+		}
+
+		c.reloadFuncs[key] = append(c.reloadFuncs[key], func() error {
+			if auditLogger.IsInfo() {
+				auditLogger.Info("reloading http audit backend", "path", entry.Path)
+			}
+			return be.Reload(ctx)
+		})
+
 		c.reloadFuncsLock.Unlock()
 	case "socket":
 		if auditLogger.IsDebug() {
@@ -17,6 +17,7 @@ type backendEntry struct {
 	backend audit.Backend
 	view    *BarrierView
 	local   bool
+	filter  *auditFilter
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -36,14 +37,16 @@ func NewAuditBroker(log log.Logger) *AuditBroker {
 	return b
 }
 
-// Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool) {
+// Register is used to add new audit backend to the broker. If filter is not
+// nil, the backend only logs the requests and responses it matches.
+func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool, filter *auditFilter) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
 		backend: b,
 		view:    v,
 		local:   local,
+		filter:  filter,
 	}
 }
 
@@ -51,9 +54,22 @@ func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, loc
 func (a *AuditBroker) Deregister(name string) {
 	a.Lock()
 	defer a.Unlock()
+	if be, ok := a.backends[name]; ok {
+		closeAuditBackend(be.backend)
+	}
 	delete(a.backends, name)
 }
 
+// closeBackends stops the background work of all the backends, before the
+// broker is discarded
+func (a *AuditBroker) closeBackends() {
+	a.RLock()
+	defer a.RUnlock()
+	for _, be := range a.backends {
+		closeAuditBackend(be.backend)
+	}
+}
+
 // IsRegistered is used to check if a given audit backend is registered
 func (a *AuditBroker) IsRegistered(name string) bool {
 	a.RLock()
@@ -85,6 +101,45 @@ func (a *AuditBroker) GetHash(ctx context.Context, name string, input string) (s
 	return be.backend.GetHash(ctx, input)
 }
 
+// HashChainKeys returns the hash chain keys of the given backend
+func (a *AuditBroker) HashChainKeys(ctx context.Context, name string) (*audit.HashChainKeys, error) {
+	a.RLock()
+	defer a.RUnlock()
+	be, ok := a.backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown audit backend %q", name)
+	}
+
+	chained, ok := be.backend.(audit.HashChainBackend)
+	if !ok {
+		return nil, fmt.Errorf("audit backend %q does not support hash chaining", name)
+	}
+	return chained.HashChainKeys(ctx)
+}
+
+// filterBackends returns the backends whose filter matches the given input,
+// logged as an entry of the given type.
+// If the filters of all backends exclude it, every backend is returned so that
+// nothing goes unaudited. A filter that fails to evaluate is treated as
+// matching. It must be called with the lock held.
+func (a *AuditBroker) filterBackends(ctx context.Context, entryType string, in *logical.LogInput) map[string]backendEntry {
+	ret := make(map[string]backendEntry, len(a.backends))
+	for name, be := range a.backends {
+		match, err := be.filter.matches(ctx, entryType, in)
+		if err != nil {
+			a.logger.Error("failed to evaluate audit filter", "backend", name, "filter", be.filter.expression, "error", err)
+			match = true
+		}
+		if match {
+			ret[name] = be
+		}
+	}
+	if len(ret) == 0 {
+		return a.backends
+	}
+	return ret
+}
+
 // LogRequest is used to ensure all the audit backends have an opportunity to
 // log the given request and that *at least one* succeeds.
 func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, headersConfig *AuditedHeadersConfig) (ret error) {
@@ -122,7 +177,7 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 
 	// Ensure at least one backend logs
 	anyLogged := false
-	for name, be := range a.backends {
+	for name, be := range a.filterBackends(ctx, "request", in) {
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
@@ -178,7 +233,7 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 
 	// Ensure at least one backend logs
 	anyLogged := false
-	for name, be := range a.backends {
+	for name, be := range a.filterBackends(ctx, "response", in) {
 		in.Request.Headers = nil
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
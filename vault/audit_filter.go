@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// auditFilterOption is the audit device option holding its filter expression
+const auditFilterOption = "filter"
+
+// auditFilterSelectors are the selectors that can be used in the filter
+// expression of an audit device. auditFilterSelectorPrefixes are selectors
+// that are followed by an arbitrary map key.
+var (
+	auditFilterSelectors = map[string]struct{}{
+		"type":                {},
+		"error":               {},
+		"request.operation":   {},
+		"request.path":        {},
+		"request.namespace":   {},
+		"request.remote_addr": {},
+		"mount.type":          {},
+		"mount.path":          {},
+		"auth.path":           {},
+		"auth.display_name":   {},
+		"auth.token_type":     {},
+		"auth.policies":       {},
+	}
+
+	auditFilterSelectorPrefixes = []string{
+		"auth.metadata.",
+	}
+)
+
+// auditFilter decides which entries an audit device records. It uses the
+// same expression language as policy conditions.
+type auditFilter struct {
+	expression string
+	expr       conditionNode
+}
+
+// parseAuditFilter compiles the filter expression of an audit device. A nil
+// filter is returned for an empty expression, meaning every entry is
+// recorded.
+func parseAuditFilter(expression string) (*auditFilter, error) {
+	if expression == "" {
+		return nil, nil
+	}
+
+	expr, err := parseExpression(expression, auditFilterSelectors, auditFilterSelectorPrefixes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit filter: %w", err)
+	}
+
+	return &auditFilter{
+		expression: expression,
+		expr:       expr,
+	}, nil
+}
+
+// matches returns whether the entry of the given type, "request" or
+// "response", should be recorded. A nil filter matches every entry.
+func (f *auditFilter) matches(ctx context.Context, entryType string, in *logical.LogInput) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+
+	v, err := f.expr.eval(&conditionInput{values: auditFilterValues(ctx, entryType, in)})
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v rather than a boolean", v)
+	}
+	return b, nil
+}
+
+// auditFilterValues returns the value of every filter selector for the entry
+func auditFilterValues(ctx context.Context, entryType string, in *logical.LogInput) map[string]interface{} {
+	values := map[string]interface{}{
+		"type":  entryType,
+		"error": in.OuterErr != nil || (in.Response != nil && in.Response.IsError()),
+	}
+
+	if ns, err := namespace.FromContext(ctx); err == nil {
+		values["request.namespace"] = ns.Path
+	}
+
+	if req := in.Request; req != nil {
+		values["request.operation"] = string(req.Operation)
+		values["request.path"] = req.Path
+		values["mount.type"] = req.MountType
+		values["mount.path"] = req.MountPoint
+		if req.Connection != nil {
+			values["request.remote_addr"] = req.Connection.RemoteAddr
+		}
+		if te := req.TokenEntry(); te != nil {
+			values["auth.path"] = te.Path
+		}
+	}
+
+	if auth := in.Auth; auth != nil {
+		values["auth.display_name"] = auth.DisplayName
+		values["auth.token_type"] = auth.TokenType.String()
+		values["auth.policies"] = auth.Policies
+		for k, v := range auth.Metadata {
+			values["auth.metadata."+k] = v
+		}
+	}
+
+	return values
+}
+
+// validateAuditFilters ensures that at least one of the given audit devices
+// has no filter, so that every request is recorded by some device.
+func validateAuditFilters(entries []*MountEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.Options[auditFilterOption] == "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("at least one audit device must be enabled without a filter")
+}
@@ -247,6 +247,93 @@ func TestCore_EnableAudit_Local(t *testing.T) {
 	}
 }
 
+func TestCore_EnableAudit_Filter(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	filtered := func(path, filter string) *MountEntry {
+		return &MountEntry{
+			Table: auditTableType,
+			Path:  path,
+			Type:  "noop",
+			Options: map[string]string{
+				"filter": filter,
+			},
+		}
+	}
+
+	// A filtered device cannot be the only one
+	err := c.enableAudit(namespace.RootContext(nil), filtered("filtered", `mount.type == "kv"`), true)
+	if err == nil || !strings.Contains(err.Error(), "without a filter") {
+		t.Fatalf("expected error, got: %v", err)
+	}
+
+	me := &MountEntry{
+		Table: auditTableType,
+		Path:  "all",
+		Type:  "noop",
+	}
+	if err := c.enableAudit(namespace.RootContext(nil), me, true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Invalid expressions are rejected
+	err = c.enableAudit(namespace.RootContext(nil), filtered("filtered", `mount.kind == "kv"`), true)
+	if err == nil || !strings.Contains(err.Error(), `unknown selector "mount.kind"`) {
+		t.Fatalf("expected error, got: %v", err)
+	}
+
+	if err := c.enableAudit(namespace.RootContext(nil), filtered("filtered", `mount.type == "kv"`), true); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !c.auditBroker.IsRegistered("filtered/") {
+		t.Fatalf("missing audit backend")
+	}
+
+	// The last device without a filter cannot be disabled
+	existed, err := c.disableAudit(namespace.RootContext(nil), "all", true)
+	if err == nil || !strings.Contains(err.Error(), "without a filter") {
+		t.Fatalf("expected error, got: %v", err)
+	}
+	if existed && !c.auditBroker.IsRegistered("all/") {
+		t.Fatalf("audit backend removed")
+	}
+
+	existed, err = c.disableAudit(namespace.RootContext(nil), "filtered", true)
+	if !existed || err != nil {
+		t.Fatalf("existed: %v; err: %v", existed, err)
+	}
+	existed, err = c.disableAudit(namespace.RootContext(nil), "all", true)
+	if !existed || err != nil {
+		t.Fatalf("existed: %v; err: %v", existed, err)
+	}
+
+	// A stored filter that does not parse fails the setup rather than
+	// silently dropping the device
+	invalid := filtered("invalid/", `mount.kind == "kv"`)
+	invalid.UUID = "bcde"
+	c.audit = &MountTable{
+		Type: auditTableType,
+		Entries: []*MountEntry{
+			{
+				Table: auditTableType,
+				Path:  "all/",
+				Type:  "noop",
+				UUID:  "abcd",
+			},
+			invalid,
+		},
+	}
+	err = c.setupAudits(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "audit filter") {
+		t.Fatalf("expected error, got: %v", err)
+	}
+}
+
 func TestCore_DisableAudit(t *testing.T) {
 	c, keys, _ := TestCoreUnsealed(t)
 	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
@@ -359,8 +446,8 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -445,8 +532,8 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -544,6 +631,107 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	}
 }
 
+func TestAuditBroker_Filter(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	a1 := &NoopAudit{}
+	a2 := &NoopAudit{}
+
+	f1, err := parseAuditFilter(`request.path != "sys/health" && !("default" in auth.policies)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f2, err := parseAuditFilter(`mount.type == "kv" || error`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Register("foo", a1, nil, false, f1)
+	b.Register("bar", a2, nil, false, f2)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+
+	logRequest := func(path, mountType string, policies []string, outerErr error) {
+		t.Helper()
+		in := &logical.LogInput{
+			Auth: &logical.Auth{
+				Policies: policies,
+			},
+			Request: &logical.Request{
+				Operation: logical.ReadOperation,
+				Path:      path,
+				MountType: mountType,
+			},
+			OuterErr: outerErr,
+		}
+		if err := b.LogRequest(namespace.RootContext(nil), in, headersConf); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	cases := []struct {
+		path      string
+		mountType string
+		policies  []string
+		outerErr  error
+		foo, bar  int
+	}{
+		{"sys/mounts", "system", []string{"admin"}, nil, 1, 0},
+		{"secret/foo", "kv", []string{"admin"}, nil, 2, 1},
+		{"secret/foo", "kv", []string{"default"}, nil, 2, 2},
+		{"sys/health", "system", []string{"admin"}, errors.New("failed"), 2, 3},
+		// Excluded by every filter, so logged everywhere
+		{"sys/health", "system", []string{"admin"}, nil, 3, 4},
+	}
+
+	for i, tc := range cases {
+		logRequest(tc.path, tc.mountType, tc.policies, tc.outerErr)
+		if len(a1.Req) != tc.foo || len(a2.Req) != tc.bar {
+			t.Fatalf("%d: bad: foo logged %d requests, bar logged %d", i, len(a1.Req), len(a2.Req))
+		}
+	}
+}
+
+func TestAuditBroker_FilterType(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+	a1 := &NoopAudit{}
+	a2 := &NoopAudit{}
+
+	f, err := parseAuditFilter(`type == "response"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Register("responses", a1, nil, false, f)
+	b.Register("all", a2, nil, false, nil)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	in := &logical.LogInput{
+		Request: &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "sys/mounts",
+		},
+		Response: &logical.Response{},
+	}
+
+	// The type is that of the entry being logged
+	if err := b.LogRequest(namespace.RootContext(nil), in, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.LogResponse(namespace.RootContext(nil), in, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 0 || len(a1.Resp) != 1 {
+		t.Fatalf("bad: responses logged %d requests and %d responses", len(a1.Req), len(a1.Resp))
+	}
+	if len(a2.Req) != 1 || len(a2.Resp) != 1 {
+		t.Fatalf("bad: all logged %d requests and %d responses", len(a2.Req), len(a2.Resp))
+	}
+}
+
 func TestAuditBroker_AuditHeaders(t *testing.T) {
 	logger := logging.NewVaultLogger(log.Trace)
 	b := NewAuditBroker(logger)
@@ -551,8 +739,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
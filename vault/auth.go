@@ -63,6 +63,8 @@ func (c *Core) enableCredential(ctx context.Context, entry *MountEntry) error {
 		}
 		return err
 	}
+
+	c.publishMountEvent(EventMountEnabled, entry)
 	return nil
 }
 
@@ -235,6 +237,8 @@ func (c *Core) disableCredential(ctx context.Context, path string) error {
 		return fmt.Errorf("token credential backend cannot be disabled")
 	}
 
+	entry := c.router.MatchingMountEntry(ctx, credentialRoutePrefix+path)
+
 	// Disable credential internally
 	if err := c.disableCredentialInternal(ctx, path, MountTableUpdateStorage); err != nil {
 		return err
@@ -245,6 +249,10 @@ func (c *Core) disableCredential(ctx context.Context, path string) error {
 		// Even we failed to evaluate filtered paths, the unmount operation was still successful
 		c.logger.Error("failed to evaluate filtered paths", "error", err)
 	}
+
+	if entry != nil {
+		c.publishMountEvent(EventMountDisabled, entry)
+	}
 	return nil
 }
 
@@ -381,6 +389,7 @@ func (c *Core) removeCredEntry(ctx context.Context, path string, updateStorage b
 	}
 
 	c.auth = newTable
+	c.metricSink.RemoveMountAccessorLabel(entry.Accessor)
 
 	return nil
 }
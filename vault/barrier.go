@@ -28,6 +28,18 @@ var (
 	// ErrPlaintextTooLarge is returned if a plaintext is offered for encryption
 	// that is too large to encrypt in memory
 	ErrPlaintextTooLarge = errors.New("plaintext value too large")
+
+	// ErrBarrierRewrapFailed is returned if an entry encrypted under an older
+	// term cannot be decrypted in order to be re-encrypted
+	ErrBarrierRewrapFailed = errors.New("failed to decrypt entry for rewrap")
+
+	// ErrBarrierEntryTruncated is returned if an entry is too short to hold
+	// an encrypted value
+	ErrBarrierEntryTruncated = errors.New("entry is truncated")
+
+	// ErrBarrierEntryUndecryptable is returned if an entry cannot be
+	// decrypted with the keyring
+	ErrBarrierEntryUndecryptable = errors.New("entry cannot be decrypted")
 )
 
 const (
@@ -158,6 +170,18 @@ type SecurityBarrier interface {
 	// Check whether an automatic rotation is due
 	CheckBarrierAutoRotate(ctx context.Context) (string, error)
 
+	// Rewrap re-encrypts the entry at the given key under the active term if
+	// it is encrypted under an older one, and reports whether it did so.
+	Rewrap(ctx context.Context, key string) (bool, error)
+
+	// PruneKeys removes the keys of all the terms older than the given one
+	// from the keyring, and returns the removed terms.
+	PruneKeys(ctx context.Context, term uint32) ([]uint32, error)
+
+	// Verify checks that the entry at the given key can be decrypted, without
+	// returning its value.
+	Verify(ctx context.Context, key string) error
+
 	// SecurityBarrier must provide the storage APIs
 	logical.Storage
 
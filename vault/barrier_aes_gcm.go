@@ -17,6 +17,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/physical"
@@ -74,6 +75,10 @@ type AESGCMBarrier struct {
 	cache     map[uint32]cipher.AEAD
 	cacheLock sync.RWMutex
 
+	// rewrapLocks prevent a rewrap from overwriting an entry that is
+	// concurrently updated or deleted
+	rewrapLocks []*locksutil.LockEntry
+
 	// currentAESGCMVersionByte is prefixed to a message to allow for
 	// future versioning of barrier implementations. It's var instead
 	// of const to allow for testing
@@ -113,6 +118,7 @@ func NewAESGCMBarrier(physical physical.Backend) (*AESGCMBarrier, error) {
 		backend:                  physical,
 		sealed:                   true,
 		cache:                    make(map[uint32]cipher.AEAD),
+		rewrapLocks:              locksutil.CreateLocks(),
 		currentAESGCMVersionByte: byte(AESGCMVersion2),
 		UnaccountedEncryptions:   atomic.NewInt64(0),
 		RemoteEncryptions:        atomic.NewInt64(0),
@@ -778,6 +784,10 @@ func (b *AESGCMBarrier) updateMasterKeyCommon(key []byte) (*Keyring, error) {
 // Put is used to insert or update an entry
 func (b *AESGCMBarrier) Put(ctx context.Context, entry *logical.StorageEntry) error {
 	defer metrics.MeasureSince([]string{"barrier", "put"}, time.Now())
+	lock := locksutil.LockForKey(b.rewrapLocks, entry.Key)
+	lock.RLock()
+	defer lock.RUnlock()
+
 	b.l.RLock()
 	if b.sealed {
 		b.l.RUnlock()
@@ -887,6 +897,10 @@ func (b *AESGCMBarrier) Delete(ctx context.Context, key string) error {
 		return ErrBarrierSealed
 	}
 
+	lock := locksutil.LockForKey(b.rewrapLocks, key)
+	lock.RLock()
+	defer lock.RUnlock()
+
 	return b.backend.Delete(ctx, key)
 }
 
@@ -904,6 +918,172 @@ func (b *AESGCMBarrier) List(ctx context.Context, prefix string) ([]string, erro
 	return b.backend.List(ctx, prefix)
 }
 
+// Rewrap re-encrypts the entry at the given key under the active term if it
+// is encrypted under an older one. Entries that are not encrypted with the
+// keyring, like the keyring itself and its upgrade keys, are left untouched.
+func (b *AESGCMBarrier) Rewrap(ctx context.Context, key string) (bool, error) {
+	defer metrics.MeasureSince([]string{"barrier", "rewrap"}, time.Now())
+	if key == keyringPath || key == masterKeyPath || strings.HasPrefix(key, keyringUpgradePrefix) {
+		return false, nil
+	}
+
+	lock := locksutil.LockForKey(b.rewrapLocks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	b.l.RLock()
+	if b.sealed {
+		b.l.RUnlock()
+		return false, ErrBarrierSealed
+	}
+
+	pe, err := b.backend.Get(ctx, key)
+	if err != nil {
+		b.l.RUnlock()
+		return false, err
+	}
+	if pe == nil || len(pe.Value) < termSize {
+		b.l.RUnlock()
+		return false, nil
+	}
+
+	// Only entries encrypted with an older key of the keyring need to be
+	// rewrapped, anything else is stored outside of the barrier
+	term := binary.BigEndian.Uint32(pe.Value[:termSize])
+	activeTerm := b.keyring.ActiveTerm()
+	if term >= activeTerm || b.keyring.TermKey(term) == nil {
+		b.l.RUnlock()
+		return false, nil
+	}
+
+	gcm, err := b.aeadForTerm(term)
+	if err != nil {
+		b.l.RUnlock()
+		return false, err
+	}
+	primary, err := b.aeadForTerm(activeTerm)
+	b.l.RUnlock()
+	if err != nil {
+		return false, err
+	}
+
+	if len(pe.Value) < termSize+1+gcm.NonceSize() {
+		return false, fmt.Errorf("%w: invalid value", ErrBarrierRewrapFailed)
+	}
+	plain, err := b.decrypt(key, gcm, pe.Value)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrBarrierRewrapFailed, err)
+	}
+
+	if err := b.putInternal(ctx, activeTerm, primary, &logical.StorageEntry{
+		Key:      key,
+		Value:    plain,
+		SealWrap: pe.SealWrap,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Verify checks that the entry at the given key can be decrypted. The
+// returned error wraps ErrBarrierEntryTruncated or ErrBarrierEntryUndecryptable
+// if the entry is corrupted, other errors come from the storage backend.
+func (b *AESGCMBarrier) Verify(ctx context.Context, key string) error {
+	defer metrics.MeasureSince([]string{"barrier", "verify"}, time.Now())
+	b.l.RLock()
+	if b.sealed {
+		b.l.RUnlock()
+		return ErrBarrierSealed
+	}
+
+	pe, err := b.backend.Get(ctx, key)
+	if err != nil {
+		b.l.RUnlock()
+		return err
+	}
+	if pe == nil {
+		b.l.RUnlock()
+		return nil
+	}
+	if len(pe.Value) < termSize+1 {
+		b.l.RUnlock()
+		return ErrBarrierEntryTruncated
+	}
+
+	// The keyring itself is encrypted by the master key rather than a key of
+	// the keyring
+	term := binary.BigEndian.Uint32(pe.Value[:termSize])
+	var gcm cipher.AEAD
+	if key == keyringPath {
+		gcm, err = b.aeadFromKey(b.keyring.MasterKey())
+	} else {
+		gcm, err = b.aeadForTerm(term)
+	}
+	b.l.RUnlock()
+	if err != nil {
+		return err
+	}
+	if gcm == nil {
+		return fmt.Errorf("%w: no decryption key available for term %d", ErrBarrierEntryUndecryptable, term)
+	}
+
+	if len(pe.Value) < termSize+1+gcm.NonceSize()+gcm.Overhead() {
+		return ErrBarrierEntryTruncated
+	}
+	plain, err := b.decrypt(key, gcm, pe.Value)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBarrierEntryUndecryptable, err)
+	}
+	memzero(plain)
+	return nil
+}
+
+// PruneKeys removes the keys of all the terms older than the given one from
+// the keyring. This must only be done once no entry is encrypted under those
+// terms anymore, as they can no longer be decrypted afterwards.
+func (b *AESGCMBarrier) PruneKeys(ctx context.Context, term uint32) ([]uint32, error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if b.sealed {
+		return nil, ErrBarrierSealed
+	}
+	if term > b.keyring.ActiveTerm() {
+		return nil, fmt.Errorf("term %d is newer than the active term", term)
+	}
+
+	var removed []uint32
+	newKeyring := b.keyring
+	for _, t := range b.keyring.Terms() {
+		if t >= term {
+			continue
+		}
+		var err error
+		newKeyring, err = newKeyring.RemoveKey(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove key for term %d: %w", t, err)
+		}
+		removed = append(removed, t)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	// Persist the new keyring
+	if err := b.persistKeyring(ctx, newKeyring); err != nil {
+		return nil, err
+	}
+
+	// Swap the keyrings and drop the removed keys from the cache
+	b.keyring = newKeyring
+	b.cacheLock.Lock()
+	for _, t := range removed {
+		delete(b.cache, t)
+	}
+	b.cacheLock.Unlock()
+
+	return removed, nil
+}
+
 // aeadForTerm returns the AES-GCM AEAD for the given term
 func (b *AESGCMBarrier) aeadForTerm(term uint32) (cipher.AEAD, error) {
 	// Check for the keyring
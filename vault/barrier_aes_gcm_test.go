@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
+	"reflect"
 	"testing"
 	"time"
 
@@ -655,3 +658,201 @@ func TestBarrier_LegacyRotate(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestAESGCMBarrier_Rewrap(t *testing.T) {
+	inm, b, _ := mockBarrier(t)
+	ctx := context.Background()
+
+	entryTerm := func(key string) uint32 {
+		t.Helper()
+		pe, err := inm.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return binary.BigEndian.Uint32(pe.Value[:termSize])
+	}
+
+	if err := b.Put(ctx, &logical.StorageEntry{Key: "foo", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// Entries stored outside of the barrier are left alone
+	if err := inm.Put(ctx, &physical.Entry{Key: "plain", Value: []byte(`{"plain":true}`)}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Nothing to do while there is a single term
+	rewrapped, err := b.Rewrap(ctx, "foo")
+	if err != nil || rewrapped {
+		t.Fatalf("bad: %v %v", rewrapped, err)
+	}
+
+	if _, err := b.Rotate(ctx, rand.Reader); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if term := entryTerm("foo"); term != 1 {
+		t.Fatalf("bad term: %d", term)
+	}
+
+	rewrapped, err = b.Rewrap(ctx, "foo")
+	if err != nil || !rewrapped {
+		t.Fatalf("bad: %v %v", rewrapped, err)
+	}
+	if term := entryTerm("foo"); term != 2 {
+		t.Fatalf("bad term: %d", term)
+	}
+	out, err := b.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(out.Value) != "test" {
+		t.Fatalf("bad: %#v", out)
+	}
+
+	// Rewrapping again is a no-op
+	rewrapped, err = b.Rewrap(ctx, "foo")
+	if err != nil || rewrapped {
+		t.Fatalf("bad: %v %v", rewrapped, err)
+	}
+
+	for _, key := range []string{"plain", "missing", keyringPath, masterKeyPath} {
+		rewrapped, err = b.Rewrap(ctx, key)
+		if err != nil || rewrapped {
+			t.Fatalf("bad: %s %v %v", key, rewrapped, err)
+		}
+	}
+
+	// A corrupted entry cannot be rewrapped
+	pe, err := inm.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	binary.BigEndian.PutUint32(pe.Value[:termSize], 1)
+	if err := inm.Put(ctx, pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = b.Rewrap(ctx, "foo")
+	if !errors.Is(err, ErrBarrierRewrapFailed) {
+		t.Fatalf("expected rewrap failure, got: %v", err)
+	}
+
+	b.Seal()
+	if _, err := b.Rewrap(ctx, "foo"); err != ErrBarrierSealed {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAESGCMBarrier_PruneKeys(t *testing.T) {
+	inm, b, key := mockBarrier(t)
+	ctx := context.Background()
+
+	if err := b.Put(ctx, &logical.StorageEntry{Key: "foo", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := b.Rotate(ctx, rand.Reader); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if _, err := b.Rewrap(ctx, "foo"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if _, err := b.PruneKeys(ctx, 4); err == nil {
+		t.Fatal("expected error pruning past the active term")
+	}
+
+	removed, err := b.PruneKeys(ctx, 3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(removed, []uint32{1, 2}) {
+		t.Fatalf("bad: %v", removed)
+	}
+	keyring, err := b.Keyring()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(keyring.Terms(), []uint32{3}) {
+		t.Fatalf("bad: %v", keyring.Terms())
+	}
+
+	// Pruning again is a no-op
+	removed, err = b.PruneKeys(ctx, 3)
+	if err != nil || len(removed) != 0 {
+		t.Fatalf("bad: %v %v", removed, err)
+	}
+
+	// The pruned keyring is persisted and the data is still readable
+	b2, err := NewAESGCMBarrier(inm)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b2.Unseal(ctx, key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyring, err = b2.Keyring()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(keyring.Terms(), []uint32{3}) {
+		t.Fatalf("bad: %v", keyring.Terms())
+	}
+	out, err := b2.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(out.Value) != "test" {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestAESGCMBarrier_Verify(t *testing.T) {
+	inm, b, _ := mockBarrier(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"good", "truncated", "corrupted", "unknown-term"} {
+		if err := b.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte("test")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Valid entries, including the keyring, and missing entries pass
+	for _, key := range []string{"good", keyringPath, "missing"} {
+		if err := b.Verify(ctx, key); err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+	}
+
+	pe, _ := inm.Get(ctx, "truncated")
+	pe.Value = pe.Value[:termSize+3]
+	if err := inm.Put(ctx, pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.Verify(ctx, "truncated"); !errors.Is(err, ErrBarrierEntryTruncated) {
+		t.Fatalf("bad: %v", err)
+	}
+
+	pe, _ = inm.Get(ctx, "corrupted")
+	pe.Value[len(pe.Value)-1]++
+	if err := inm.Put(ctx, pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.Verify(ctx, "corrupted"); !errors.Is(err, ErrBarrierEntryUndecryptable) {
+		t.Fatalf("bad: %v", err)
+	}
+
+	pe, _ = inm.Get(ctx, "unknown-term")
+	binary.BigEndian.PutUint32(pe.Value[:termSize], 99)
+	if err := inm.Put(ctx, pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.Verify(ctx, "unknown-term"); !errors.Is(err, ErrBarrierEntryUndecryptable) {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Verifying requires the barrier to be unsealed
+	b.Seal()
+	if err := b.Verify(ctx, "good"); err != ErrBarrierSealed {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// coreBarrierRewrapPath is used to persist the progress of the
+	// re-encryption of the storage entries after a key rotation
+	coreBarrierRewrapPath = "core/barrier-rewrap"
+
+	// barrierRewrapBatchSize is the number of entries checked before the
+	// rewrap pauses for barrierRewrapBatchInterval and persists its progress,
+	// so that it does not starve the storage backend
+	barrierRewrapBatchSize     = 256
+	barrierRewrapBatchInterval = 250 * time.Millisecond
+
+	barrierRewrapStatusInProgress = "in-progress"
+	barrierRewrapStatusComplete   = "complete"
+)
+
+// barrierRewrapStatus is the progress of the re-encryption of all the
+// storage entries under the key of the given term
+type barrierRewrapStatus struct {
+	Term             uint32    `json:"term"`
+	StartTime        time.Time `json:"start_time"`
+	CompleteTime     time.Time `json:"complete_time"`
+	EntriesChecked   int64     `json:"entries_checked"`
+	EntriesRewrapped int64     `json:"entries_rewrapped"`
+	EntriesFailed    int64     `json:"entries_failed"`
+}
+
+// Complete returns whether all the entries have been checked
+func (s *barrierRewrapStatus) Complete() bool {
+	return !s.CompleteTime.IsZero()
+}
+
+// barrierRewrapLoop re-encrypts the entries written under older key terms
+// whenever the barrier key is rotated, and keeps retrying periodically if
+// a previous run was interrupted.
+func (c *Core) barrierRewrapLoop(ctx context.Context) {
+	t := time.NewTicker(autoRotateCheckInterval)
+	defer t.Stop()
+	for {
+		if c.isPrimary() {
+			if err := c.runBarrierRewrap(ctx); err != nil {
+				lf := c.logger.Error
+				if ctx.Err() != nil || errors.Is(err, ErrBarrierSealed) {
+					lf = c.logger.Debug
+				}
+				lf("error rewrapping barrier entries", "error", err)
+			}
+		}
+
+		select {
+		case <-c.barrierRewrapTrigger:
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// triggerBarrierRewrap wakes up the rewrap loop without waiting for it
+func (c *Core) triggerBarrierRewrap() {
+	select {
+	case c.barrierRewrapTrigger <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Core) runBarrierRewrap(ctx context.Context) error {
+	keyring, err := c.barrier.Keyring()
+	if err != nil {
+		return err
+	}
+	term := keyring.ActiveTerm()
+
+	status, err := c.loadBarrierRewrapStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if status != nil && status.Term == term && status.Complete() {
+		c.setBarrierRewrapStatus(status)
+		return nil
+	}
+
+	// An interrupted rewrap for the same term starts over, the entries that
+	// were already rewrapped are simply skipped
+	startTime := time.Now()
+	if status != nil && status.Term == term {
+		startTime = status.StartTime
+	}
+	status = &barrierRewrapStatus{
+		Term:      term,
+		StartTime: startTime,
+	}
+	c.setBarrierRewrapStatus(status)
+
+	// With a single key in the keyring, there is nothing to rewrap
+	if len(keyring.Terms()) > 1 {
+		c.logger.Info("rewrapping barrier entries", "term", term)
+		if err := c.walkBarrierRewrap(ctx, status); err != nil {
+			return err
+		}
+	}
+
+	// If the key was rotated again in the meantime, the rewrap has to run for
+	// the new term
+	keyring, err = c.barrier.Keyring()
+	if err != nil {
+		return err
+	}
+	if keyring.ActiveTerm() != term {
+		c.triggerBarrierRewrap()
+		return c.persistBarrierRewrapStatus(ctx, status)
+	}
+
+	status.CompleteTime = time.Now()
+	c.setBarrierRewrapStatus(status)
+	if err := c.persistBarrierRewrapStatus(ctx, status); err != nil {
+		return err
+	}
+	c.logger.Info("barrier rewrap complete", "term", term, "checked", status.EntriesChecked,
+		"rewrapped", status.EntriesRewrapped, "failed", status.EntriesFailed)
+	return nil
+}
+
+// walkBarrierRewrap rewraps every entry in storage, pausing between batches
+func (c *Core) walkBarrierRewrap(ctx context.Context, status *barrierRewrapStatus) error {
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var walkErr error
+	err := logical.ScanView(walkCtx, c.barrier, func(path string) {
+		rewrapped, err := c.barrier.Rewrap(walkCtx, path)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrBarrierRewrapFailed):
+			c.logger.Warn("failed to rewrap barrier entry", "path", path, "error", err)
+			status.EntriesFailed++
+		default:
+			walkErr = fmt.Errorf("failed to rewrap %q: %w", path, err)
+			cancel()
+			return
+		}
+
+		status.EntriesChecked++
+		if rewrapped {
+			status.EntriesRewrapped++
+		}
+		if status.EntriesChecked%barrierRewrapBatchSize != 0 {
+			return
+		}
+
+		c.setBarrierRewrapStatus(status)
+		if err := c.persistBarrierRewrapStatus(walkCtx, status); err != nil {
+			walkErr = err
+			cancel()
+			return
+		}
+		select {
+		case <-time.After(barrierRewrapBatchInterval):
+		case <-walkCtx.Done():
+		}
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return err
+}
+
+func (c *Core) setBarrierRewrapStatus(status *barrierRewrapStatus) {
+	c.barrierRewrapLock.Lock()
+	defer c.barrierRewrapLock.Unlock()
+	if status == nil {
+		c.barrierRewrapStatus = nil
+		return
+	}
+	s := *status
+	c.barrierRewrapStatus = &s
+}
+
+// currentBarrierRewrapStatus returns the progress of the running rewrap, or
+// the last persisted one on nodes that are not running it.
+func (c *Core) currentBarrierRewrapStatus(ctx context.Context) (*barrierRewrapStatus, error) {
+	c.barrierRewrapLock.RLock()
+	status := c.barrierRewrapStatus
+	c.barrierRewrapLock.RUnlock()
+	if status != nil {
+		s := *status
+		return &s, nil
+	}
+	return c.loadBarrierRewrapStatus(ctx)
+}
+
+func (c *Core) loadBarrierRewrapStatus(ctx context.Context) (*barrierRewrapStatus, error) {
+	entry, err := c.barrier.Get(ctx, coreBarrierRewrapPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read barrier rewrap status: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var status barrierRewrapStatus
+	if err := jsonutil.DecodeJSON(entry.Value, &status); err != nil {
+		return nil, fmt.Errorf("failed to decode barrier rewrap status: %w", err)
+	}
+	return &status, nil
+}
+
+func (c *Core) persistBarrierRewrapStatus(ctx context.Context, status *barrierRewrapStatus) error {
+	entry, err := logical.StorageEntryJSON(coreBarrierRewrapPath, status)
+	if err != nil {
+		return fmt.Errorf("failed to encode barrier rewrap status: %w", err)
+	}
+	if err := c.barrier.Put(ctx, entry); err != nil {
+		return fmt.Errorf("failed to persist barrier rewrap status: %w", err)
+	}
+	return nil
+}
+
+// pruneBarrierKeys removes the keys older than the term of the last complete
+// rewrap from the keyring
+func (c *Core) pruneBarrierKeys(ctx context.Context) ([]uint32, error) {
+	status, err := c.loadBarrierRewrapStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if status == nil || !status.Complete() {
+		return nil, errors.New("barrier rewrap has not completed yet")
+	}
+	if status.EntriesFailed > 0 {
+		return nil, fmt.Errorf("barrier rewrap failed for %d entries", status.EntriesFailed)
+	}
+
+	keyring, err := c.barrier.Keyring()
+	if err != nil {
+		return nil, err
+	}
+	if keyring.ActiveTerm() != status.Term {
+		return nil, fmt.Errorf("barrier rewrap has not completed yet for term %d", keyring.ActiveTerm())
+	}
+
+	removed, err := c.barrier.PruneKeys(ctx, status.Term)
+	if err != nil {
+		return nil, err
+	}
+	if len(removed) > 0 {
+		c.logger.Info("pruned barrier keys", "terms", removed)
+	}
+	return removed, nil
+}
@@ -0,0 +1,154 @@
+package vault
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+func testWaitBarrierRewrap(t *testing.T, c *Core, term uint32) *barrierRewrapStatus {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := c.currentBarrierRewrapStatus(context.Background())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if status != nil && status.Term == term && status.Complete() {
+			return status
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("barrier rewrap for term %d did not complete", term)
+	return nil
+}
+
+func TestCore_BarrierRewrap(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	// The initial term has nothing to rewrap
+	testWaitBarrierRewrap(t, c, 1)
+
+	// Write more entries than a single batch
+	for i := 0; i < barrierRewrapBatchSize+10; i++ {
+		if err := c.barrier.Put(ctx, &logical.StorageEntry{
+			Key:   fmt.Sprintf("test/rewrap/%d", i),
+			Value: []byte("test"),
+		}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Add an entry that looks like it was encrypted under the first term
+	// but cannot be decrypted
+	corrupt := make([]byte, 64)
+	binary.BigEndian.PutUint32(corrupt, 1)
+	corrupt[4] = AESGCMVersion2
+	if err := c.physical.Put(ctx, &physical.Entry{Key: "test/corrupt", Value: corrupt}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	rotate := func() {
+		t.Helper()
+		resp, err := c.HandleRequest(ctx, &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "sys/rotate",
+			ClientToken: root,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %v %v", resp, err)
+		}
+	}
+	prune := func() (*logical.Response, error) {
+		return c.HandleRequest(ctx, &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "sys/rotate/prune",
+			ClientToken: root,
+		})
+	}
+
+	rotate()
+	status := testWaitBarrierRewrap(t, c, 2)
+	if status.EntriesRewrapped < barrierRewrapBatchSize+10 {
+		t.Fatalf("bad: %#v", status)
+	}
+	if status.EntriesFailed != 1 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// All the readable entries are now encrypted under the active term
+	for i := 0; i < barrierRewrapBatchSize+10; i++ {
+		pe, err := c.physical.Get(ctx, fmt.Sprintf("test/rewrap/%d", i))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if term := binary.BigEndian.Uint32(pe.Value[:termSize]); term != 2 {
+			t.Fatalf("bad term for entry %d: %d", i, term)
+		}
+	}
+
+	// The keys cannot be pruned while an entry failed to be rewrapped
+	resp, err := prune()
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error, got: %v", resp)
+	}
+
+	if err := c.physical.Delete(ctx, "test/corrupt"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	rotate()
+	status = testWaitBarrierRewrap(t, c, 3)
+	if status.EntriesFailed != 0 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	resp, err = c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/key-status",
+		ClientToken: root,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["keyring_terms"], []uint32{1, 2, 3}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	rewrap := resp.Data["rewrap"].(map[string]interface{})
+	if rewrap["status"] != barrierRewrapStatusComplete || rewrap["term"] != uint32(3) {
+		t.Fatalf("bad: %#v", rewrap)
+	}
+
+	resp, err = prune()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(resp.Data["removed_terms"], []uint32{1, 2}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Everything is still readable with the remaining key
+	for i := 0; i < barrierRewrapBatchSize+10; i++ {
+		entry, err := c.barrier.Get(ctx, fmt.Sprintf("test/rewrap/%d", i))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(entry.Value) != "test" {
+			t.Fatalf("bad: %#v", entry)
+		}
+	}
+	keyring, err := c.barrier.Keyring()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(keyring.Terms(), []uint32{3}) {
+		t.Fatalf("bad: %v", keyring.Terms())
+	}
+}
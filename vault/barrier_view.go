@@ -3,6 +3,7 @@ package vault
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/vault/sdk/logical"
@@ -20,16 +21,67 @@ type BarrierView struct {
 	readOnlyErr     error
 	readOnlyErrLock sync.RWMutex
 	iCheck          interface{}
+
+	// sealWrap is shared with the sub views
+	sealWrap *barrierViewSealWrap
+}
+
+// barrierViewSealWrap holds the storage paths of a mount whose entries are
+// flagged for seal wrapping. The paths are expanded to the full key in the
+// barrier.
+type barrierViewSealWrap struct {
+	l        sync.RWMutex
+	all      bool
+	exact    map[string]bool
+	prefixes []string
 }
 
 // NewBarrierView takes an underlying security barrier and returns
 // a view of it that can only operate with the given prefix.
 func NewBarrierView(barrier logical.Storage, prefix string) *BarrierView {
 	return &BarrierView{
-		storage: logical.NewStorageView(barrier, prefix),
+		storage:  logical.NewStorageView(barrier, prefix),
+		sealWrap: &barrierViewSealWrap{},
 	}
 }
 
+// setSealWrap flags for seal wrapping all the entries of the view, or the
+// ones matching the given paths, which are exact unless they end with '/'.
+func (v *BarrierView) setSealWrap(all bool, paths []string) {
+	exact := make(map[string]bool)
+	var prefixes []string
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			prefixes = append(prefixes, v.storage.ExpandKey(path))
+		} else {
+			exact[v.storage.ExpandKey(path)] = true
+		}
+	}
+
+	v.sealWrap.l.Lock()
+	defer v.sealWrap.l.Unlock()
+	v.sealWrap.all = all
+	v.sealWrap.exact = exact
+	v.sealWrap.prefixes = prefixes
+}
+
+// sealWrapped returns whether the entry at the expanded key is flagged for
+// seal wrapping
+func (v *BarrierView) sealWrapped(expandedKey string) bool {
+	v.sealWrap.l.RLock()
+	defer v.sealWrap.l.RUnlock()
+
+	if v.sealWrap.all || v.sealWrap.exact[expandedKey] {
+		return true
+	}
+	for _, prefix := range v.sealWrap.prefixes {
+		if strings.HasPrefix(expandedKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *BarrierView) setICheck(iCheck interface{}) {
 	v.iCheck = iCheck
 }
@@ -73,6 +125,14 @@ func (v *BarrierView) Put(ctx context.Context, entry *logical.StorageEntry) erro
 		}
 	}
 
+	if !entry.SealWrap && v.sealWrapped(expandedKey) {
+		entry = &logical.StorageEntry{
+			Key:      entry.Key,
+			Value:    entry.Value,
+			SealWrap: true,
+		}
+	}
+
 	return v.storage.Put(ctx, entry)
 }
 
@@ -96,5 +156,6 @@ func (v *BarrierView) SubView(prefix string) *BarrierView {
 		storage:     v.storage.SubView(prefix),
 		readOnlyErr: v.getReadOnlyErr(),
 		iCheck:      v.iCheck,
+		sealWrap:    v.sealWrap,
 	}
 }
@@ -316,3 +316,57 @@ func TestBarrierView_Readonly(t *testing.T) {
 		t.Fatalf("key test missing")
 	}
 }
+
+// sealWrapStorage records the seal wrap flag of the entries, which is not
+// kept by the inmem backend
+type sealWrapStorage struct {
+	logical.InmemStorage
+	sealWrap map[string]bool
+}
+
+func (s *sealWrapStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	s.sealWrap[entry.Key] = entry.SealWrap
+	return s.InmemStorage.Put(ctx, entry)
+}
+
+func TestBarrierView_SealWrap(t *testing.T) {
+	storage := &sealWrapStorage{sealWrap: make(map[string]bool)}
+	view := NewBarrierView(storage, "foo/")
+	sub := view.SubView("config/")
+	ctx := context.Background()
+
+	view.setSealWrap(false, []string{"keys/", "config/ca"})
+
+	for key, sealWrap := range map[string]bool{
+		"keys/a":     true,
+		"keys/b/c":   true,
+		"config/ca":  true,
+		"config/cab": false,
+		"other":      false,
+	} {
+		if err := view.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte("test")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if storage.sealWrap["foo/"+key] != sealWrap {
+			t.Fatalf("%s: expected seal wrap to be %t", key, sealWrap)
+		}
+	}
+
+	// Sub views share the paths of the mount
+	storage.sealWrap["foo/config/ca"] = false
+	if err := sub.Put(ctx, &logical.StorageEntry{Key: "ca", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !storage.sealWrap["foo/config/ca"] {
+		t.Fatal("expected seal wrap")
+	}
+
+	// The whole mount can be seal wrapped
+	view.setSealWrap(true, nil)
+	if err := view.Put(ctx, &logical.StorageEntry{Key: "other", Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !storage.sealWrap["foo/other"] {
+		t.Fatal("expected seal wrap")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"sort"
 
+	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -27,34 +28,13 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 		return nil, &logical.StatusBadRequest{Err: "invalid token"}
 	}
 
-	tokenNS, err := NamespaceByID(ctx, te.NamespaceID, c)
+	tokenNS, entity, policyNames, err := c.tokenPolicyNames(ctx, te)
 	if err != nil {
 		return nil, err
 	}
-	if tokenNS == nil {
-		return nil, namespace.ErrNoNamespace
-	}
 
 	var policyCount int
-	policyNames := make(map[string][]string)
-	policyNames[tokenNS.ID] = te.Policies
-	policyCount += len(te.Policies)
-
-	entity, identityPolicies, err := c.fetchEntityAndDerivedPolicies(ctx, tokenNS, te.EntityID)
-	if err != nil {
-		return nil, err
-	}
-	if entity != nil && entity.Disabled {
-		c.logger.Warn("permission denied as the entity on the token is disabled")
-		return nil, logical.ErrPermissionDenied
-	}
-	if te.EntityID != "" && entity == nil {
-		c.logger.Warn("permission denied as the entity on the token is invalid")
-		return nil, logical.ErrPermissionDenied
-	}
-
-	for nsID, nsPolicies := range identityPolicies {
-		policyNames[nsID] = append(policyNames[nsID], nsPolicies...)
+	for _, nsPolicies := range policyNames {
 		policyCount += len(nsPolicies)
 	}
 
@@ -74,3 +54,37 @@ func (c *Core) Capabilities(ctx context.Context, token, path string) ([]string,
 	sort.Strings(capabilities)
 	return capabilities, nil
 }
+
+// tokenPolicyNames returns the namespace and entity of the given token along
+// with the names of the policies that apply to it, keyed by namespace ID.
+func (c *Core) tokenPolicyNames(ctx context.Context, te *logical.TokenEntry) (*namespace.Namespace, *identity.Entity, map[string][]string, error) {
+	tokenNS, err := NamespaceByID(ctx, te.NamespaceID, c)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if tokenNS == nil {
+		return nil, nil, nil, namespace.ErrNoNamespace
+	}
+
+	policyNames := make(map[string][]string)
+	policyNames[tokenNS.ID] = te.Policies
+
+	entity, identityPolicies, err := c.fetchEntityAndDerivedPolicies(ctx, tokenNS, te.EntityID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if entity != nil && entity.Disabled {
+		c.logger.Warn("permission denied as the entity on the token is disabled")
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+	if te.EntityID != "" && entity == nil {
+		c.logger.Warn("permission denied as the entity on the token is invalid")
+		return nil, nil, nil, logical.ErrPermissionDenied
+	}
+
+	for nsID, nsPolicies := range identityPolicies {
+		policyNames[nsID] = append(policyNames[nsID], nsPolicies...)
+	}
+
+	return tokenNS, entity, policyNames, nil
+}
@@ -54,6 +54,7 @@ import (
 	"github.com/hashicorp/vault/vault/cluster"
 	"github.com/hashicorp/vault/vault/quotas"
 	vaultseal "github.com/hashicorp/vault/vault/seal"
+	"github.com/keybase/go-crypto/openpgp"
 	"github.com/patrickmn/go-cache"
 	uberAtomic "go.uber.org/atomic"
 	"google.golang.org/grpc"
@@ -173,6 +174,10 @@ type activeAdvertisement struct {
 type unlockInformation struct {
 	Parts [][]byte
 	Nonce string
+
+	// Holders has the fingerprints of the PGP keys of the key holders who
+	// provided signed unseal keys
+	Holders []string
 }
 
 type raftInformation struct {
@@ -257,6 +262,10 @@ type Core struct {
 	migrationInfo     *migrationInformation
 	sealMigrationDone *uint32
 
+	// disableSealWrap stops seal wrapping new entries, existing seal wrapped
+	// entries are still unwrapped when read
+	disableSealWrap bool
+
 	// barrier is the security barrier wrapping the physical backend
 	barrier SecurityBarrier
 
@@ -290,6 +299,15 @@ type Core struct {
 	// unlockInfo has the keys provided to Unseal until the threshold number of parts is available, as well as the operation nonce
 	unlockInfo *unlockInformation
 
+	// unsealPGPKey is the ephemeral key pair that signed unseal keys are
+	// encrypted with, generated while sealed
+	unsealPGPKey     *openpgp.Entity
+	unsealPGPKeyLock sync.Mutex
+
+	// unsealHolders has the key holders who provided the unseal keys of the
+	// last unseal
+	unsealHolders []string
+
 	// generateRootProgress holds the shares until we reach enough
 	// to verify the master key
 	generateRootConfig   *GenerateRootConfig
@@ -374,6 +392,10 @@ type Core struct {
 	// a cluster label.
 	metricSink *metricsutil.ClusterMetricSink
 
+	// events publishes the system events to the subscribers of
+	// sys/events/subscribe
+	events *eventBus
+
 	defaultLeaseTTL time.Duration
 	maxLeaseTTL     time.Duration
 
@@ -467,6 +489,10 @@ type Core struct {
 	// pluginDirectory is the location vault will look for plugin binaries
 	pluginDirectory string
 
+	// raftSnapshotDirectory is the directory scheduled raft snapshots can be
+	// saved to on the local filesystem
+	raftSnapshotDirectory string
+
 	// pluginCatalog is used to manage plugin configurations
 	pluginCatalog *PluginCatalog
 
@@ -528,6 +554,8 @@ type Core struct {
 	raftTLSRotationStopCh chan struct{}
 	// Stores the pending peers we are waiting to give answers
 	pendingRaftPeers *sync.Map
+	// raftAutoSnapshots runs the scheduled raft snapshots on the active node
+	raftAutoSnapshots *raftAutoSnapshots
 
 	// rawConfig stores the config as-is from the provided server configuration.
 	rawConfig *atomic.Value
@@ -563,6 +591,27 @@ type Core struct {
 
 	autoRotateCancel context.CancelFunc
 
+	// barrierRewrap tracks the background re-encryption of the entries
+	// written under older barrier key terms
+	barrierRewrapCancel  context.CancelFunc
+	barrierRewrapTrigger chan struct{}
+	barrierRewrapLock    sync.RWMutex
+	barrierRewrapStatus  *barrierRewrapStatus
+
+	// storageVerify tracks the background verification of the storage
+	// entries started through sys/storage/verify
+	storageVerifyCancel context.CancelFunc
+	storageVerifyLock   sync.RWMutex
+	storageVerifyStatus *storageVerifyStatus
+
+	// storageMigrator wraps the physical backend to allow migrating it to
+	// another backend while running
+	storageMigrator        *storageMigrator
+	physicalBackends       map[string]physical.Factory
+	storageMigrationLock   sync.Mutex
+	storageMigrationStatus *storageMigrationStatus
+	storageMigrationCancel context.CancelFunc
+
 	// number of workers to use for lease revocation in the expiration manager
 	numExpirationWorkers int
 
@@ -590,6 +639,10 @@ type CoreConfig struct {
 
 	AuditBackends map[string]audit.Factory
 
+	// PhysicalBackends are the storage backends available as destinations of
+	// online storage migrations
+	PhysicalBackends map[string]physical.Factory
+
 	Physical physical.Backend
 
 	StorageType string
@@ -644,6 +697,8 @@ type CoreConfig struct {
 
 	PluginDirectory string
 
+	RaftSnapshotDirectory string
+
 	DisableSealWrap bool
 
 	RawConfig *server.Config
@@ -786,6 +841,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		router:              NewRouter(),
 		sealed:              new(uint32),
 		sealMigrationDone:   new(uint32),
+		disableSealWrap:     conf.DisableSealWrap,
 		standby:             true,
 		standbyStopCh:       new(atomic.Value),
 		baseLogger:          conf.Logger,
@@ -818,6 +874,7 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterLeaderParams:          new(atomic.Value),
 		metricsHelper:                conf.MetricsHelper,
 		metricSink:                   conf.MetricSink,
+		events:                       newEventBus(),
 		secureRandomReader:           conf.SecureRandomReader,
 		rawConfig:                    new(atomic.Value),
 		counters: counters{
@@ -830,6 +887,8 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		clusterHeartbeatInterval:       clusterHeartbeatInterval,
 		activityLogConfig:              conf.ActivityLogConfig,
 		keyRotateGracePeriod:           new(int64),
+		barrierRewrapTrigger:           make(chan struct{}, 1),
+		physicalBackends:               conf.PhysicalBackends,
 		numExpirationWorkers:           conf.NumExpirationWorkers,
 		raftFollowerStates:             raft.NewFollowerStates(),
 		disableAutopilot:               conf.DisableAutopilot,
@@ -949,6 +1008,13 @@ func NewCore(conf *CoreConfig) (*Core, error) {
 		}
 	}
 
+	if conf.RaftSnapshotDirectory != "" {
+		c.raftSnapshotDirectory, err = filepath.Abs(conf.RaftSnapshotDirectory)
+		if err != nil {
+			return nil, fmt.Errorf("core setup failed, could not verify raft snapshot directory: %w", err)
+		}
+	}
+
 	createSecondaries(c, conf)
 
 	if conf.HAPhysical != nil && conf.HAPhysical.HAEnabled() {
@@ -1093,13 +1159,13 @@ func (c *Core) ResetUnsealProcess() {
 }
 
 func (c *Core) UnsealMigrate(key []byte) (bool, error) {
-	err := c.unsealFragment(key, true)
+	err := c.unsealFragment(key, nil, true)
 	return !c.Sealed(), err
 }
 
 // Unseal is used to provide one of the key parts to unseal the Vault.
 func (c *Core) Unseal(key []byte) (bool, error) {
-	err := c.unsealFragment(key, false)
+	err := c.unsealFragment(key, nil, false)
 	return !c.Sealed(), err
 }
 
@@ -1125,7 +1191,10 @@ func (c *Core) Unseal(key []byte) (bool, error) {
 // In migration scenarios a side-effect of unsealing is that
 // the members of c.migrationInfo are populated (excluding
 // .seal, which must already be populated before unseal is called.)
-func (c *Core) unsealFragment(key []byte, migrate bool) error {
+//
+// When signed unseal is enabled, the key fragment is instead provided
+// as signedKey, a PGP message signed by one of the key holders.
+func (c *Core) unsealFragment(key, signedKey []byte, migrate bool) error {
 	defer metrics.MeasureSince([]string{"core", "unseal"}, time.Now())
 
 	c.stateLock.Lock()
@@ -1161,6 +1230,30 @@ func (c *Core) unsealFragment(key []byte, migrate bool) error {
 		return ErrNotInit
 	}
 
+	sealToUse := c.seal
+	if migrate {
+		c.logger.Info("unsealing using migration seal")
+		sealToUse = c.migrationInfo.seal
+	}
+
+	config, err := c.unsealKeyConfig(ctx, sealToUse)
+	if err != nil {
+		return err
+	}
+	var holder string
+	switch {
+	case signedKey != nil:
+		if config == nil || !config.SignedUnseal {
+			return &ErrInvalidKey{"signed unseal is not enabled"}
+		}
+		key, holder, err = c.verifySignedUnsealKey(config, signedKey)
+		if err != nil {
+			return &ErrInvalidKey{err.Error()}
+		}
+	case config != nil && config.SignedUnseal:
+		return &ErrInvalidKey{"unseal keys must be signed by a key holder"}
+	}
+
 	// Verify the key length
 	min, max := c.barrier.KeyLength()
 	max += shamir.ShareOverhead
@@ -1176,16 +1269,17 @@ func (c *Core) unsealFragment(key []byte, migrate bool) error {
 		return nil
 	}
 
-	sealToUse := c.seal
-	if migrate {
-		c.logger.Info("unsealing using migration seal")
-		sealToUse = c.migrationInfo.seal
+	if holder != "" && c.unlockInfo != nil {
+		if err := checkHolderShares(config, c.unlockInfo.Holders, holder); err != nil {
+			return err
+		}
 	}
 
-	newKey, err := c.recordUnsealPart(key)
+	newKey, err := c.recordUnsealPart(key, holder)
 	if !newKey || err != nil {
 		return err
 	}
+	holders := c.unlockInfo.Holders
 
 	// getUnsealKey returns either a recovery key (in the case of an autoseal)
 	// or a master key (legacy shamir) or an unseal key (new-style shamir).
@@ -1196,6 +1290,11 @@ func (c *Core) unsealFragment(key []byte, migrate bool) error {
 	if migrate {
 		c.migrationInfo.unsealKey = combinedKey
 	}
+	if len(holders) > 0 {
+		c.logger.Info("unseal keys provided by key holders", "holders", holders)
+		c.unsealHolders = holders
+		c.resetUnsealPGPKey()
+	}
 
 	if c.isRaftUnseal() {
 		return c.unsealWithRaft(combinedKey)
@@ -1280,7 +1379,8 @@ func (c *Core) unsealWithRaft(combinedKey []byte) error {
 }
 
 // recordUnsealPart takes in a key fragment, and returns true if it's a new fragment.
-func (c *Core) recordUnsealPart(key []byte) (bool, error) {
+// The holder is the key holder who signed the fragment, if any.
+func (c *Core) recordUnsealPart(key []byte, holder string) (bool, error) {
 	// Check if we already have this piece
 	if c.unlockInfo != nil {
 		for _, existing := range c.unlockInfo.Parts {
@@ -1300,6 +1400,9 @@ func (c *Core) recordUnsealPart(key []byte) (bool, error) {
 
 	// Store this key
 	c.unlockInfo.Parts = append(c.unlockInfo.Parts, key)
+	if holder != "" {
+		c.unlockInfo.Holders = append(c.unlockInfo.Holders, holder)
+	}
 	return true, nil
 }
 
@@ -1308,19 +1411,7 @@ func (c *Core) recordUnsealPart(key []byte) (bool, error) {
 // If the key fragments are part of a recovery key, also verify that
 // it matches the stored recovery key on disk.
 func (c *Core) getUnsealKey(ctx context.Context, seal Seal) ([]byte, error) {
-	var config *SealConfig
-	var err error
-
-	switch {
-	case seal.RecoveryKeySupported():
-		config, err = seal.RecoveryConfig(ctx)
-	case c.isRaftUnseal():
-		// Ignore follower's seal config and refer to leader's barrier
-		// configuration.
-		config = c.raftInfo.leaderBarrierConfig
-	default:
-		config, err = seal.BarrierConfig(ctx)
-	}
+	config, err := c.unsealKeyConfig(ctx, seal)
 	if err != nil {
 		return nil, err
 	}
@@ -1360,6 +1451,21 @@ func (c *Core) getUnsealKey(ctx context.Context, seal Seal) ([]byte, error) {
 	return unsealKey, nil
 }
 
+// unsealKeyConfig returns the configuration of the key fragments provided
+// to unseal with the given seal.
+func (c *Core) unsealKeyConfig(ctx context.Context, seal Seal) (*SealConfig, error) {
+	switch {
+	case seal.RecoveryKeySupported():
+		return seal.RecoveryConfig(ctx)
+	case c.isRaftUnseal():
+		// Ignore follower's seal config and refer to leader's barrier
+		// configuration.
+		return c.raftInfo.leaderBarrierConfig, nil
+	default:
+		return seal.BarrierConfig(ctx)
+	}
+}
+
 // sealMigrated must be called with the stateLock held.  It returns true if
 // the seal configured in HCL and the seal configured in storage match.
 // For the auto->auto same seal migration scenario, it will return false even
@@ -1583,6 +1689,7 @@ func (c *Core) unsealInternal(ctx context.Context, masterKey []byte) error {
 	if c.logger.IsInfo() {
 		c.logger.Info("vault is unsealed")
 	}
+	c.publishSealEvent(EventUnsealed)
 
 	if c.serviceRegistration != nil {
 		if err := c.serviceRegistration.NotifySealedStateChange(false); err != nil {
@@ -1801,6 +1908,11 @@ func (c *Core) sealInternalWithOptions(grabStateLock, keepHALock, performCleanup
 
 	c.logger.Info("marked as sealed")
 
+	// Notify the event subscribers, and end their subscriptions so that they
+	// release the state lock
+	c.publishSealEvent(EventSealed)
+	c.events.closeSubscriptions()
+
 	// Clear forwarding clients
 	c.requestForwardingConnectionLock.Lock()
 	c.clearForwardingClients()
@@ -1952,6 +2064,12 @@ func (s standardUnsealStrategy) unseal(ctx context.Context, logger log.Logger, c
 		go c.autoRotateBarrierLoop(autoRotateCtx)
 	}
 
+	if c.barrierRewrapCancel == nil {
+		var barrierRewrapCtx context.Context
+		barrierRewrapCtx, c.barrierRewrapCancel = context.WithCancel(c.activeContext)
+		go c.barrierRewrapLoop(barrierRewrapCtx)
+	}
+
 	if !c.IsDRSecondary() {
 		if err := c.ensureWrappingKey(ctx); err != nil {
 			return err
@@ -2089,6 +2207,10 @@ func (c *Core) postUnseal(ctx context.Context, ctxCancelFunc context.CancelFunc,
 		c.seal.SetRecoveryConfig(ctx, nil)
 	}
 
+	if err := c.checkStorageMigrated(ctx); err != nil {
+		return err
+	}
+
 	if err := unsealer.unseal(ctx, c.logger, c); err != nil {
 		return err
 	}
@@ -2183,6 +2305,15 @@ func (c *Core) preSeal() error {
 		c.autoRotateCancel = nil
 	}
 
+	if c.barrierRewrapCancel != nil {
+		c.barrierRewrapCancel()
+		c.barrierRewrapCancel = nil
+	}
+	c.setBarrierRewrapStatus(nil)
+	c.stopStorageVerify()
+
+	c.cancelStorageMigration()
+
 	preSealPhysical(c)
 
 	c.logger.Info("pre-seal teardown complete")
@@ -2356,6 +2487,10 @@ func (c *Core) adjustForSealMigration(unwrapSeal Seal) error {
 			// We have the same barrier type and the unwrap seal is nil so we're not
 			// migrating from same to same, IOW we assume it's not a migration.
 			return nil
+		case sealTypesCompatible(existBarrierSealConfig.Type, c.seal.BarrierType()):
+			// Seals were added to or removed from a multi seal, the stored keys
+			// are encrypted again with the configured seals once unsealed.
+			return nil
 		case c.seal.BarrierType() == wrapping.Shamir:
 			// The stored barrier config is not shamir, there is no disabled seal
 			// in config, and either no configured seal (which equates to Shamir)
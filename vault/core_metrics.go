@@ -3,6 +3,7 @@ package vault
 import (
 	"context"
 	"errors"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -525,3 +526,61 @@ func (c *Core) cachedGaugeMetricsEmitter() {
 
 	loopMetrics.Range(emit)
 }
+
+// requestErrorClass classifies the error of a request by the HTTP status code
+// it is responded with. It returns an empty string if the request succeeded.
+func requestErrorClass(req *logical.Request, resp *logical.Response, err error) string {
+	if err == nil && !resp.IsError() {
+		return ""
+	}
+
+	// Error responses are responded with a bad request, unless the error is
+	// more specific
+	status := http.StatusBadRequest
+	if err != nil {
+		status, _ = logical.RespondErrorCommon(req, nil, err)
+		if resp != nil && status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		logical.AdjustErrorStatusCode(&status, err)
+	}
+
+	switch {
+	case status == http.StatusBadRequest:
+		return "invalid_request"
+	case status == http.StatusForbidden:
+		return "permission_denied"
+	case status == http.StatusNotFound:
+		return "unsupported_path"
+	case status == http.StatusMethodNotAllowed:
+		return "unsupported_operation"
+	case status == http.StatusTooManyRequests:
+		return "quota_exceeded"
+	case status == http.StatusBadGateway:
+		return "upstream"
+	case status == http.StatusServiceUnavailable:
+		return "unavailable"
+	case status < http.StatusInternalServerError:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// emitMountRequestMetrics emits the duration of a request handled by a mount
+// and, if it failed, counts its error class. The mounts are labelled by their
+// accessor, up to the maximum request mount cardinality. The duration is
+// reported to Prometheus as a histogram, see metricsutil.PrometheusHistograms.
+func (c *Core) emitMountRequestMetrics(entry *MountEntry, req *logical.Request, duration time.Duration, errClass string) {
+	labels := []metrics.Label{
+		c.MetricSink().MountAccessorLabel(entry.Accessor),
+		{"operation", string(req.Operation)},
+		metricsutil.NamespaceLabel(entry.Namespace()),
+	}
+	c.MetricSink().AddDurationWithLabels([]string{"core", "mount", "request"}, duration, labels)
+
+	if errClass != "" {
+		c.MetricSink().IncrCounterWithLabels([]string{"core", "mount", "request_error"}, 1,
+			append(labels, metrics.Label{"error_class", errClass}))
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	logicalKv "github.com/hashicorp/vault-plugin-secrets-kv"
+	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -234,6 +235,105 @@ func TestCoreMetrics_KvSecretGaugeError(t *testing.T) {
 	}
 }
 
+func TestCoreMetrics_MountRequests(t *testing.T) {
+	core, _, root, sink := TestCoreUnsealedWithMetrics(t)
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "secret/foo")
+	req.Data["foo"] = "bar"
+	req.ClientToken = root
+	if _, err := core.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	req.ClientToken = "invalid"
+	if _, err := core.HandleRequest(ctx, req); err == nil {
+		t.Fatal("expected error")
+	}
+
+	intervals := sink.Data()
+	// Test crossed an interval boundary, don't try to deal with it.
+	if len(intervals) > 1 {
+		t.Skip("Detected interval crossing.")
+	}
+
+	accessor := core.router.MatchingMountEntry(ctx, "secret/").Accessor
+	// The update is handled as a create, since the secret does not exist
+	for _, op := range []logical.Operation{logical.CreateOperation, logical.ReadOperation} {
+		key := "core.mount.request;mount_accessor=" + accessor + ";operation=" + string(op) + ";namespace=root;cluster=test-cluster"
+		if s, ok := intervals[0].Samples[key]; !ok || s.Count != 1 {
+			t.Errorf("No single sample %v found in %v", key, intervals[0].Samples)
+		}
+	}
+
+	key := "core.mount.request_error;mount_accessor=" + accessor + ";operation=read;namespace=root;error_class=permission_denied;cluster=test-cluster"
+	if c, ok := intervals[0].Counters[key]; !ok || c.Count != 1 {
+		t.Errorf("No single counter %v found in %v", key, intervals[0].Counters)
+	}
+	for name := range intervals[0].Counters {
+		if strings.HasPrefix(name, "core.mount.request_error") && name != key {
+			t.Errorf("Unexpected counter %v", name)
+		}
+	}
+}
+
+func TestCoreMetrics_MountRequestsUnmount(t *testing.T) {
+	core, _, root, _ := TestCoreUnsealedWithMetrics(t)
+	ctx := namespace.RootContext(nil)
+	core.MetricSink().MaxRequestMountCardinality = 1
+
+	secretAccessor := core.router.MatchingMountEntry(ctx, "secret/").Accessor
+	if l := core.MetricSink().MountAccessorLabel(secretAccessor); l.Value != secretAccessor {
+		t.Fatalf("Label value %v, expected %v", l.Value, secretAccessor)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/mounts/kv")
+	req.Data["type"] = "kv"
+	req.ClientToken = root
+	if resp, err := core.HandleRequest(ctx, req); err != nil || resp.IsError() {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	kvAccessor := core.router.MatchingMountEntry(ctx, "kv/").Accessor
+	if l := core.MetricSink().MountAccessorLabel(kvAccessor); l.Value != metricsutil.OverflowMountLabel {
+		t.Fatalf("Label value %v, expected %v", l.Value, metricsutil.OverflowMountLabel)
+	}
+
+	// Unmounting frees the label of the mount. The mount is removed directly,
+	// since the request to sys/mounts would take the freed label.
+	if err := core.unmount(ctx, "secret/"); err != nil {
+		t.Fatal(err)
+	}
+	if l := core.MetricSink().MountAccessorLabel(kvAccessor); l.Value != kvAccessor {
+		t.Fatalf("Label value %v, expected %v", l.Value, kvAccessor)
+	}
+}
+
+func TestCoreMetrics_RequestErrorClass(t *testing.T) {
+	req := logical.TestRequest(t, logical.ReadOperation, "secret/foo")
+	cases := []struct {
+		resp     *logical.Response
+		err      error
+		expected string
+	}{
+		{nil, nil, ""},
+		{&logical.Response{Data: map[string]interface{}{"foo": "bar"}}, nil, ""},
+		{logical.ErrorResponse("bad input"), nil, "invalid_request"},
+		{logical.ErrorResponse("bad input"), logical.ErrInvalidRequest, "invalid_request"},
+		{nil, logical.ErrPermissionDenied, "permission_denied"},
+		{nil, logical.ErrUnsupportedPath, "unsupported_path"},
+		{nil, logical.ErrUnsupportedOperation, "unsupported_operation"},
+		{nil, logical.ErrRateLimitQuotaExceeded, "quota_exceeded"},
+		{nil, logical.CodedError(409, "conflict"), "client"},
+		{nil, errors.New("failure"), "internal"},
+	}
+	for _, tc := range cases {
+		if class := requestErrorClass(req, tc.resp, tc.err); class != tc.expected {
+			t.Errorf("Error class %q for %v %v, expected %q", class, tc.resp, tc.err, tc.expected)
+		}
+	}
+}
+
 func metricLabelsMatch(t *testing.T, actual []metrics.Label, expected map[string]string) {
 	t.Helper()
 
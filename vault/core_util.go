@@ -33,11 +33,20 @@ type LicensingConfig struct {
 }
 
 func coreInit(c *Core, conf *CoreConfig) error {
-	phys := conf.Physical
+	storageMigratorLogger := conf.Logger.Named("storage.migrator")
+	c.allLoggers = append(c.allLoggers, storageMigratorLogger)
+	c.storageMigrator = newStorageMigrator(conf.Physical, storageMigratorLogger)
+	phys := c.storageMigrator.backend()
 	_, txnOK := phys.(physical.Transactional)
+	// Trace the storage operations, which is a no-op unless tracing is set up
+	if txnOK {
+		phys = physical.NewTransactionalTracing(phys)
+	} else {
+		phys = physical.NewTracing(phys)
+	}
 	sealUnwrapperLogger := conf.Logger.Named("storage.sealunwrapper")
 	c.allLoggers = append(c.allLoggers, sealUnwrapperLogger)
-	c.sealUnwrapper = NewSealUnwrapper(phys, sealUnwrapperLogger)
+	c.sealUnwrapper = NewSealUnwrapper(phys, sealUnwrapperLogger, c.sealWrapSeals)
 	// Wrap the physical backend in a cache layer if enabled
 	cacheLogger := c.baseLogger.Named("storage.cache")
 	c.allLoggers = append(c.allLoggers, cacheLogger)
@@ -151,8 +160,6 @@ func (c *Core) perfStandbyClusterHandler() (*replication.Cluster, chan struct{},
 
 func (c *Core) initSealsForMigration() {}
 
-func (c *Core) postSealMigration(ctx context.Context) error { return nil }
-
 func (c *Core) applyLeaseCountQuota(in *quotas.Request) (*quotas.Response, error) {
 	return &quotas.Response{Allowed: true}, nil
 }
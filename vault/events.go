@@ -0,0 +1,306 @@
+package vault
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// EventType is the type of a Vault system event
+type EventType string
+
+const (
+	EventSecretWritten EventType = "secret.written"
+	EventSecretDeleted EventType = "secret.deleted"
+	EventMountEnabled  EventType = "mount.enabled"
+	EventMountTuned    EventType = "mount.tuned"
+	EventMountDisabled EventType = "mount.disabled"
+	EventPolicyWritten EventType = "policy.written"
+	EventPolicyDeleted EventType = "policy.deleted"
+	EventLeaseRevoked  EventType = "lease.revoked"
+	EventSealed        EventType = "seal.sealed"
+	EventUnsealed      EventType = "seal.unsealed"
+)
+
+const (
+	// eventHistorySize is the number of past events kept in memory, so that
+	// subscribers can resume their subscription after reconnecting
+	eventHistorySize = 512
+
+	// eventSubscriberBufferSize is the number of events buffered for each
+	// subscriber; events are dropped for the subscribers that fall behind
+	eventSubscriberBufferSize = 128
+)
+
+// Event is a Vault system event. Events never carry secret material: the data
+// only describes the change.
+type Event struct {
+	// ID is the sequence number of the event on this node
+	ID        uint64                 `json:"id"`
+	Type      EventType              `json:"type"`
+	Time      time.Time              `json:"time"`
+	Namespace string                 `json:"namespace"`
+	Path      string                 `json:"path"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+
+	namespace *namespace.Namespace
+	// unauthenticated events are delivered to every subscriber regardless of
+	// the ACL of their token
+	unauthenticated bool
+}
+
+// EventFilter selects the events delivered to a subscriber. Empty lists match
+// everything; the entries may contain a leading or trailing glob.
+type EventFilter struct {
+	Types []string
+	Paths []string
+}
+
+func (f *EventFilter) matches(ev *Event) bool {
+	if len(f.Types) > 0 && !globbedStringsMatchAny(f.Types, string(ev.Type)) {
+		return false
+	}
+	if len(f.Paths) > 0 && !globbedStringsMatchAny(f.Paths, ev.Path) {
+		return false
+	}
+	return true
+}
+
+func globbedStringsMatchAny(items []string, val string) bool {
+	for _, item := range items {
+		if strutil.GlobbedStringsMatch(item, val) {
+			return true
+		}
+	}
+	return false
+}
+
+// EventSubscription receives the events matching its filter
+type EventSubscription struct {
+	bus    *eventBus
+	filter *EventFilter
+
+	ch        chan *Event
+	doneCh    chan struct{}
+	closeOnce sync.Once
+	dropped   *uint64
+}
+
+// Events returns the channel the events are delivered on
+func (s *EventSubscription) Events() <-chan *Event {
+	return s.ch
+}
+
+// Done is closed when the subscription ends, either because it was closed or
+// because the node sealed
+func (s *EventSubscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+// Dropped returns the number of events that were dropped because the
+// subscriber fell behind
+func (s *EventSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(s.dropped)
+}
+
+// Close ends the subscription
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+func (s *EventSubscription) close() {
+	s.closeOnce.Do(func() {
+		close(s.doneCh)
+	})
+}
+
+// eventBus fans the events published by core out to the subscribers
+type eventBus struct {
+	l           sync.RWMutex
+	lastID      uint64
+	history     []*Event
+	subscribers map[*EventSubscription]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		history:     make([]*Event, 0, eventHistorySize),
+		subscribers: make(map[*EventSubscription]struct{}),
+	}
+}
+
+func (b *eventBus) publish(ev *Event) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.lastID++
+	ev.ID = b.lastID
+	if len(b.history) == eventHistorySize {
+		copy(b.history, b.history[1:])
+		b.history = b.history[:eventHistorySize-1]
+	}
+	b.history = append(b.history, ev)
+
+	for sub := range b.subscribers {
+		if !sub.filter.matches(ev) {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			atomic.AddUint64(sub.dropped, 1)
+		}
+	}
+}
+
+// subscribe registers a subscription. If lastID is set, the events kept in
+// memory which were published after it are delivered first.
+func (b *eventBus) subscribe(filter *EventFilter, lastID uint64) *EventSubscription {
+	if filter == nil {
+		filter = &EventFilter{}
+	}
+	sub := &EventSubscription{
+		bus:     b,
+		filter:  filter,
+		ch:      make(chan *Event, eventSubscriberBufferSize+eventHistorySize),
+		doneCh:  make(chan struct{}),
+		dropped: new(uint64),
+	}
+
+	b.l.Lock()
+	defer b.l.Unlock()
+	if lastID != 0 {
+		for _, ev := range b.history {
+			if ev.ID > lastID && filter.matches(ev) {
+				sub.ch <- ev
+			}
+		}
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (b *eventBus) unsubscribe(sub *EventSubscription) {
+	b.l.Lock()
+	delete(b.subscribers, sub)
+	b.l.Unlock()
+	sub.close()
+}
+
+// closeSubscriptions ends all the subscriptions
+func (b *eventBus) closeSubscriptions() {
+	b.l.Lock()
+	defer b.l.Unlock()
+	for sub := range b.subscribers {
+		delete(b.subscribers, sub)
+		sub.close()
+	}
+}
+
+// SubscribeEvents subscribes to the events published on this node. Events
+// are not ACL checked: callers are responsible for filtering them.
+func (c *Core) SubscribeEvents(filter *EventFilter, lastID uint64) *EventSubscription {
+	return c.events.subscribe(filter, lastID)
+}
+
+// publishEvent publishes an event to the subscribers. It never blocks.
+func (c *Core) publishEvent(ns *namespace.Namespace, eventType EventType, path string, data map[string]interface{}) {
+	if c == nil || c.events == nil {
+		return
+	}
+	if ns == nil {
+		ns = namespace.RootNamespace
+	}
+	c.events.publish(&Event{
+		Type:      eventType,
+		Time:      time.Now().UTC(),
+		Namespace: ns.Path,
+		Path:      path,
+		Data:      data,
+		namespace: ns,
+	})
+}
+
+// publishSealEvent publishes a change of the seal state. The seal state is
+// not sensitive, so these events are delivered to all subscribers.
+func (c *Core) publishSealEvent(eventType EventType) {
+	if c.events == nil {
+		return
+	}
+	c.events.publish(&Event{
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		Path:            "sys/seal-status",
+		namespace:       namespace.RootNamespace,
+		unauthenticated: true,
+	})
+}
+
+// publishMountEvent publishes an event for the mount entry. The path of the
+// event is the path of the mount configuration endpoint.
+func (c *Core) publishMountEvent(eventType EventType, entry *MountEntry) {
+	path := "sys/mounts/"
+	table := "secrets"
+	if entry.Table == credentialTableType {
+		path = "sys/auth/"
+		table = "auth"
+	}
+	c.publishEvent(entry.Namespace(), eventType, path+strings.TrimSuffix(entry.Path, "/"), map[string]interface{}{
+		"mount_path":     entry.Path,
+		"mount_type":     entry.Type,
+		"mount_accessor": entry.Accessor,
+		"table":          table,
+	})
+}
+
+// publishPolicyEvent publishes an event for the named policy. The path of the
+// event is the path of the policy endpoint.
+func (c *Core) publishPolicyEvent(ns *namespace.Namespace, eventType EventType, name string, policyType PolicyType) {
+	c.publishEvent(ns, eventType, "sys/policies/"+policyType.String()+"/"+name, map[string]interface{}{
+		"name": name,
+		"type": policyType.String(),
+	})
+}
+
+// publishSecretEvent publishes an event for a request which succeeded on a
+// KV mount
+func (c *Core) publishSecretEvent(entry *MountEntry, req *logical.Request) {
+	if entry.Table != mountTableType || (entry.Type != "kv" && entry.Type != "generic") {
+		return
+	}
+
+	var eventType EventType
+	switch req.Operation {
+	case logical.CreateOperation, logical.UpdateOperation:
+		eventType = EventSecretWritten
+		// The soft deletion and destruction of versions of KV v2 secrets are
+		// updates of their own endpoints
+		relPath := strings.TrimPrefix(req.Path, entry.Path)
+		if strings.HasPrefix(relPath, "delete/") || strings.HasPrefix(relPath, "destroy/") {
+			eventType = EventSecretDeleted
+		}
+	case logical.DeleteOperation:
+		eventType = EventSecretDeleted
+	default:
+		return
+	}
+
+	c.publishEvent(entry.Namespace(), eventType, req.Path, map[string]interface{}{
+		"operation":      string(req.Operation),
+		"mount_path":     entry.Path,
+		"mount_accessor": entry.Accessor,
+	})
+}
+
+func parseEventID(id string) (uint64, error) {
+	if id == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(id, 10, 64)
+}
@@ -0,0 +1,191 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func nextEvent(t *testing.T, sub *EventSubscription) *Event {
+	t.Helper()
+	select {
+	case ev := <-sub.Events():
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return nil
+}
+
+func TestEventBus_Filter(t *testing.T) {
+	b := newEventBus()
+	sub := b.subscribe(&EventFilter{
+		Types: []string{"secret.*"},
+		Paths: []string{"kv/app/*"},
+	}, 0)
+	defer sub.Close()
+
+	b.publish(&Event{Type: EventSecretWritten, Path: "kv/other/foo"})
+	b.publish(&Event{Type: EventMountEnabled, Path: "kv/app/foo"})
+	b.publish(&Event{Type: EventSecretDeleted, Path: "kv/app/foo"})
+
+	ev := nextEvent(t, sub)
+	if ev.Type != EventSecretDeleted || ev.Path != "kv/app/foo" || ev.ID != 3 {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+	select {
+	case ev := <-sub.Events():
+		t.Fatalf("unexpected event: %#v", ev)
+	default:
+	}
+}
+
+func TestEventBus_Replay(t *testing.T) {
+	b := newEventBus()
+	for i := 0; i < eventHistorySize+10; i++ {
+		b.publish(&Event{Type: EventSecretWritten, Path: "kv/foo"})
+	}
+
+	// Only the events kept in memory can be replayed
+	sub := b.subscribe(nil, 1)
+	defer sub.Close()
+	if ev := nextEvent(t, sub); ev.ID != 11 {
+		t.Fatalf("expected the replay to start at the oldest event kept, got %d", ev.ID)
+	}
+	if n := len(sub.Events()); n != eventHistorySize-1 {
+		t.Fatalf("expected %d more events, got %d", eventHistorySize-1, n)
+	}
+
+	// Events are not replayed without a last ID
+	sub = b.subscribe(nil, 0)
+	defer sub.Close()
+	if n := len(sub.Events()); n != 0 {
+		t.Fatalf("expected no events, got %d", n)
+	}
+}
+
+func TestEventBus_Dropped(t *testing.T) {
+	b := newEventBus()
+	sub := b.subscribe(nil, 0)
+	defer sub.Close()
+
+	total := eventSubscriberBufferSize + eventHistorySize + 5
+	for i := 0; i < total; i++ {
+		b.publish(&Event{Type: EventSecretWritten, Path: "kv/foo"})
+	}
+	if d := sub.Dropped(); d != 5 {
+		t.Fatalf("expected 5 dropped events, got %d", d)
+	}
+}
+
+func TestEventBus_Close(t *testing.T) {
+	b := newEventBus()
+	sub1 := b.subscribe(nil, 0)
+	sub2 := b.subscribe(nil, 0)
+
+	sub1.Close()
+	b.publish(&Event{Type: EventSecretWritten, Path: "kv/foo"})
+	if n := len(sub1.Events()); n != 0 {
+		t.Fatalf("expected no events on a closed subscription, got %d", n)
+	}
+
+	b.closeSubscriptions()
+	select {
+	case <-sub2.Done():
+	default:
+		t.Fatal("expected the subscription to be closed")
+	}
+	// Closing again is a no-op
+	sub2.Close()
+}
+
+func TestCore_Events(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	sub := c.SubscribeEvents(nil, 0)
+
+	me := &MountEntry{
+		Table: mountTableType,
+		Path:  "foo/",
+		Type:  "kv",
+	}
+	if err := c.mount(ctx, me); err != nil {
+		t.Fatal(err)
+	}
+	ev := nextEvent(t, sub)
+	if ev.Type != EventMountEnabled || ev.Path != "sys/mounts/foo" || ev.Data["mount_type"] != "kv" {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "foo/bar")
+	req.ClientToken = root
+	req.Data["zip"] = "zap"
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	ev = nextEvent(t, sub)
+	if ev.Type != EventSecretWritten || ev.Path != "foo/bar" {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+	// Events describe the change without the secret material
+	if _, ok := ev.Data["zip"]; ok {
+		t.Fatalf("unexpected secret data in event: %#v", ev.Data)
+	}
+
+	// Reads do not publish events
+	req = logical.TestRequest(t, logical.ReadOperation, "foo/bar")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+
+	req = logical.TestRequest(t, logical.DeleteOperation, "foo/bar")
+	req.ClientToken = root
+	if _, err := c.HandleRequest(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	ev = nextEvent(t, sub)
+	if ev.Type != EventSecretDeleted || ev.Path != "foo/bar" {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+
+	// Sealing publishes an event and ends the subscriptions
+	if err := c.Seal(root); err != nil {
+		t.Fatal(err)
+	}
+	ev = nextEvent(t, sub)
+	if ev.Type != EventSealed || !ev.unauthenticated {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the subscription to end on seal")
+	}
+
+	sub = c.SubscribeEvents(&EventFilter{Types: []string{"seal.*"}}, 0)
+	defer sub.Close()
+	for _, key := range keys {
+		if _, err := TestCoreUnseal(c, TestKeyCopy(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ev := nextEvent(t, sub); ev.Type != EventUnsealed {
+		t.Fatalf("unexpected event: %#v", ev)
+	}
+}
+
+func TestParseEventID(t *testing.T) {
+	if id, err := parseEventID(""); err != nil || id != 0 {
+		t.Fatalf("bad: %d, %v", id, err)
+	}
+	if id, err := parseEventID("42"); err != nil || id != 42 {
+		t.Fatalf("bad: %d, %v", id, err)
+	}
+	if _, err := parseEventID("foo"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -1010,6 +1010,12 @@ func (m *ExpirationManager) revokeCommon(ctx context.Context, leaseID string, fo
 		}
 		m.logger.Warn("finished revoking incorrectly non-expiring lease", "leaseID", le.LeaseID, "accessor", accessor)
 	}
+
+	if le.Secret != nil {
+		m.core.publishEvent(le.namespace, EventLeaseRevoked, le.Path, map[string]interface{}{
+			"lease_id": leaseID,
+		})
+	}
 	return nil
 }
 
@@ -171,6 +171,7 @@ func TestRaft_Autopilot_Configuration(t *testing.T) {
 		"max_trailing_logs":                  100,
 		"min_quorum":                         100,
 		"server_stabilization_time":          "100s",
+		"disable_upgrade_migration":          true,
 	}
 	writeConfigFunc(writableConfig, false)
 
@@ -181,6 +182,7 @@ func TestRaft_Autopilot_Configuration(t *testing.T) {
 	config.MaxTrailingLogs = 100
 	config.MinQuorum = 100
 	config.ServerStabilizationTime = 100 * time.Second
+	config.DisableUpgradeMigration = true
 	configCheckFunc(config)
 
 	// Update some fields and leave the rest as it is.
@@ -331,6 +333,101 @@ func TestRaft_Autopilot_Stabilization_Delay(t *testing.T) {
 	require.Equal(t, []string{"core-0", "core-1", "core-2"}, state.Voters)
 }
 
+// TestRaft_Autopilot_RedundancyZones_Upgrade verifies that autopilot keeps a
+// single voter per redundancy zone, and that a server running a newer version
+// is not promoted until there are enough of them to replace the older voters.
+func TestRaft_Autopilot_RedundancyZones_Upgrade(t *testing.T) {
+	conf, opts := teststorage.ClusterSetup(nil, nil, teststorage.RaftBackendSetup)
+	conf.DisableAutopilot = false
+	opts.InmemClusterLayers = true
+	opts.KeepStandbysSealed = true
+	opts.SetupFunc = nil
+	opts.PhysicalFactory = func(t testingintf.T, coreIdx int, logger hclog.Logger, conf map[string]interface{}) *vault.PhysicalBackendBundle {
+		config := map[string]interface{}{
+			"autopilot_reconcile_interval": "1s",
+			"autopilot_redundancy_zone":    "zone-b",
+		}
+		switch coreIdx {
+		case 0:
+			config["autopilot_redundancy_zone"] = "zone-a"
+		case 2:
+			config["autopilot_upgrade_version"] = "99.0.0"
+		}
+		return teststorage.MakeRaftBackend(t, coreIdx, logger, config)
+	}
+
+	cluster := vault.NewTestCluster(t, conf, opts)
+	cluster.Start()
+	defer cluster.Cleanup()
+	testhelpers.WaitForActiveNode(t, cluster)
+
+	client := cluster.Cores[0].Client
+	_, err := client.Logical().Write("sys/storage/raft/autopilot/configuration", map[string]interface{}{
+		"server_stabilization_time": "5s",
+	})
+	require.NoError(t, err)
+
+	waitForState := func(check func(*api.AutopilotState) bool) *api.AutopilotState {
+		t.Helper()
+		deadline := time.Now().Add(time.Minute)
+		for {
+			state, err := client.Sys().RaftAutopilotState()
+			require.NoError(t, err)
+			if check(state) {
+				return state
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("autopilot did not reach the expected state: %# v", pretty.Formatter(state))
+			}
+			time.Sleep(1 * time.Second)
+		}
+	}
+
+	joinFunc := func(core *vault.TestClusterCore) {
+		_, err := core.JoinRaftCluster(namespace.RootContext(context.Background()), []*raft.LeaderJoinInfo{
+			{
+				LeaderAPIAddr: client.Address(),
+				TLSConfig:     cluster.Cores[0].TLSConfig,
+				Retry:         true,
+			},
+		}, false)
+		require.NoError(t, err)
+		time.Sleep(1 * time.Second)
+		cluster.UnsealCore(t, core)
+	}
+
+	joinFunc(cluster.Cores[1])
+	waitForState(func(state *api.AutopilotState) bool {
+		return len(state.Voters) == 2
+	})
+
+	joinFunc(cluster.Cores[2])
+	state := waitForState(func(state *api.AutopilotState) bool {
+		srv := state.Servers["core-2"]
+		return srv != nil && srv.Healthy && srv.Version == "99.0.0" && state.Upgrade != nil
+	})
+	time.Sleep(3 * time.Second)
+	state, err = client.Sys().RaftAutopilotState()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"core-0", "core-1"}, state.Voters)
+	require.Equal(t, []string{"core-2"}, state.NonVoters)
+	require.Equal(t, "zone-voter", state.Servers["core-1"].NodeType)
+	require.Equal(t, "zone-standby", state.Servers["core-2"].NodeType)
+	require.Equal(t, "zone-b", state.Servers["core-2"].RedundancyZone)
+	require.Equal(t, "99.0.0", state.Servers["core-2"].UpgradeVersion)
+
+	require.Equal(t, []string{"core-0"}, state.RedundancyZones["zone-a"].Voters)
+	require.Equal(t, []string{"core-1", "core-2"}, state.RedundancyZones["zone-b"].Servers)
+	require.Equal(t, []string{"core-1"}, state.RedundancyZones["zone-b"].Voters)
+	require.Equal(t, 1, state.RedundancyZones["zone-b"].FailureTolerance)
+
+	require.Equal(t, "await-new-servers", state.Upgrade.Status)
+	require.Equal(t, "99.0.0", state.Upgrade.TargetVersion)
+	require.Equal(t, []string{"core-2"}, state.Upgrade.TargetVersionNonVoters)
+	require.Equal(t, []string{"core-0", "core-1"}, state.Upgrade.OtherVersionVoters)
+}
+
 func TestRaft_AutoPilot_Peersets_Equivalent(t *testing.T) {
 	cluster := raftCluster(t, &RaftClusterOpts{
 		InmemCluster:         true,
@@ -0,0 +1,148 @@
+package rafttests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	autopilot "github.com/hashicorp/raft-autopilot"
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRaft_ReadReplica(t *testing.T) {
+	cluster := raftCluster(t, &RaftClusterOpts{
+		DisableFollowerJoins: true,
+		InmemCluster:         true,
+		EnableAutopilot:      true,
+	})
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	require.NoError(t, client.Sys().Mount("kv", &api.MountInput{Type: "kv"}))
+	_, err := client.Logical().Write("kv/foo", map[string]interface{}{"value": "bar"})
+	require.NoError(t, err)
+
+	replica := cluster.Cores[1]
+	_, err = replica.JoinRaftCluster(namespace.RootContext(context.Background()), []*raft.LeaderJoinInfo{
+		{
+			LeaderAPIAddr: client.Address(),
+			TLSConfig:     cluster.Cores[0].TLSConfig,
+			Retry:         true,
+		},
+	}, true)
+	require.NoError(t, err)
+	time.Sleep(1 * time.Second)
+	cluster.UnsealCore(t, replica)
+
+	deadline := time.Now().Add(30 * time.Second)
+	for !replica.PerfStandby() {
+		if time.Now().After(deadline) {
+			t.Fatal("read replica did not start serving requests")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// waitForRead polls the replica until its local copy of the data holds the
+	// expected value
+	waitForRead := func(path, expected string) {
+		t.Helper()
+		deadline := time.Now().Add(30 * time.Second)
+		for {
+			secret, err := replica.Client.Logical().Read(path)
+			if err == nil && secret != nil && secret.Data["value"] == expected {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("bad: %#v, %v", secret, err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		waitForRead("kv/foo", "bar")
+
+		keys, err := replica.Client.Logical().List("kv/")
+		require.NoError(t, err)
+		require.Equal(t, []interface{}{"foo"}, keys.Data["keys"])
+	})
+
+	t.Run("forwarded_writes", func(t *testing.T) {
+		_, err := replica.Client.Logical().Write("kv/foo", map[string]interface{}{"value": "baz"})
+		require.NoError(t, err)
+
+		secret, err := client.Logical().Read("kv/foo")
+		require.NoError(t, err)
+		require.Equal(t, "baz", secret.Data["value"])
+		waitForRead("kv/foo", "baz")
+
+		// New mounts are picked up by the replica
+		require.NoError(t, replica.Client.Sys().Mount("kv2", &api.MountInput{Type: "kv"}))
+		_, err = replica.Client.Logical().Write("kv2/foo", map[string]interface{}{"value": "qux"})
+		require.NoError(t, err)
+		waitForRead("kv2/foo", "qux")
+
+		// So are policy changes
+		require.NoError(t, client.Sys().PutPolicy("kv-read", `path "kv/*" { capabilities = ["read"] }`))
+		secret, err = client.Auth().Token().Create(&api.TokenCreateRequest{Policies: []string{"kv-read"}})
+		require.NoError(t, err)
+		limited, err := replica.Client.Clone()
+		require.NoError(t, err)
+		limited.SetToken(secret.Auth.ClientToken)
+		read, err := limited.Logical().Read("kv/foo")
+		require.NoError(t, err)
+		require.Equal(t, "baz", read.Data["value"])
+
+		require.NoError(t, client.Sys().PutPolicy("kv-read", `path "kv2/*" { capabilities = ["read"] }`))
+		deadline := time.Now().Add(30 * time.Second)
+		for {
+			_, err := limited.Logical().Read("kv/foo")
+			if err != nil {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("policy change did not reach the read replica")
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	})
+
+	t.Run("membership", func(t *testing.T) {
+		secret, err := client.Logical().Read("sys/storage/raft/configuration")
+		require.NoError(t, err)
+		servers := secret.Data["config"].(map[string]interface{})["servers"].([]interface{})
+		require.Len(t, servers, 2)
+		for _, raw := range servers {
+			server := raw.(map[string]interface{})
+			readReplica := server["node_id"] == "core-1"
+			require.Equal(t, !readReplica, server["voter"], server)
+			require.Equal(t, readReplica, server["read_replica"], server)
+		}
+
+		// Autopilot never promotes the replica, even once it is stable
+		deadline := time.Now().Add(2 * time.Minute)
+		for {
+			state, err := client.Sys().RaftAutopilotState()
+			require.NoError(t, err)
+			if state.Servers["core-1"] != nil && state.Servers["core-1"].Healthy {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("read replica did not become healthy")
+			}
+			time.Sleep(1 * time.Second)
+		}
+		time.Sleep(autopilot.DefaultReconcileInterval + time.Second)
+
+		state, err := client.Sys().RaftAutopilotState()
+		require.NoError(t, err)
+		require.Equal(t, []string{"core-0"}, state.Voters)
+		require.Empty(t, state.NonVoters)
+		require.Equal(t, []string{"core-1"}, state.ReadReplicas)
+		require.Equal(t, "non-voter", state.Servers["core-1"].Status)
+		require.Equal(t, "read-replica", state.Servers["core-1"].NodeType)
+	})
+}
@@ -0,0 +1,368 @@
+package rafttests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// waitForSnapshotStatus polls the status of the snapshot configuration until
+// cond returns true
+func waitForSnapshotStatus(t *testing.T, client *api.Client, name string, cond func(map[string]interface{}) bool) map[string]interface{} {
+	t.Helper()
+
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		secret, err := client.Logical().Read("sys/storage/raft/snapshot-auto/status/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if secret != nil && cond(secret.Data) {
+			return secret.Data
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for the status of snapshot configuration %q", name)
+	return nil
+}
+
+// successfulSnapshots returns a condition that is true once count distinct
+// snapshots were saved
+func successfulSnapshots(count int) func(map[string]interface{}) bool {
+	seen := make(map[string]bool)
+	return func(data map[string]interface{}) bool {
+		if url, _ := data["last_success_url"].(string); url != "" {
+			seen[url] = true
+		}
+		return len(seen) >= count
+	}
+}
+
+func TestRaft_AutoSnapshot_Local(t *testing.T) {
+	t.Parallel()
+	base, err := ioutil.TempDir("", "vault-raft-snapshot-auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	cluster := raftCluster(t, &RaftClusterOpts{RaftSnapshotDirectory: base})
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+	dir := filepath.Join(base, "hourly")
+
+	// Invalid configurations are rejected
+	_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/hourly", map[string]interface{}{
+		"interval":     "1h",
+		"storage_type": "local",
+	})
+	if err == nil || !strings.Contains(err.Error(), "path_prefix must be set") {
+		t.Fatalf("expected missing path_prefix error, got: %v", err)
+	}
+	for _, pathPrefix := range []string{os.TempDir(), "../hourly"} {
+		_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/hourly", map[string]interface{}{
+			"interval":     "1h",
+			"storage_type": "local",
+			"path_prefix":  pathPrefix,
+		})
+		if err == nil || !strings.Contains(err.Error(), "must be within the raft snapshot directory") {
+			t.Fatalf("expected path_prefix %q to be rejected, got: %v", pathPrefix, err)
+		}
+	}
+
+	_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/hourly", map[string]interface{}{
+		"interval":     "1s",
+		"retain":       2,
+		"storage_type": "local",
+		"path_prefix":  "hourly",
+		"file_prefix":  "test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := client.Logical().List("sys/storage/raft/snapshot-auto/config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := list.Data["keys"].([]interface{}); len(keys) != 1 || keys[0] != "hourly" {
+		t.Fatalf("bad: %#v", list.Data["keys"])
+	}
+
+	status := waitForSnapshotStatus(t, client, "hourly", successfulSnapshots(3))
+	if status["consecutive_errors"].(json.Number).String() != "0" || status["last_snapshot_error"] != "" {
+		t.Fatalf("bad: %#v", status)
+	}
+	if status["next_snapshot_start"] == "" {
+		t.Fatalf("expected the next snapshot to be scheduled: %#v", status)
+	}
+
+	// Only the retained snapshots are kept
+	var snapshots []string
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		snapshots, err = filepath.Glob(filepath.Join(dir, "test-*.snap"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("expected 2 snapshots, got: %v", snapshots)
+	}
+	for _, path := range snapshots {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) < 2 || b[0] != 0x1f || b[1] != 0x8b {
+			t.Fatalf("expected %q to be a gzipped snapshot archive", path)
+		}
+	}
+
+	// Deleting the configuration stops the snapshots and removes the status
+	if _, err := client.Logical().Delete("sys/storage/raft/snapshot-auto/config/hourly"); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := client.Logical().Read("sys/storage/raft/snapshot-auto/status/hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != nil {
+		t.Fatalf("expected no status, got: %#v", secret.Data)
+	}
+}
+
+func TestRaft_AutoSnapshot_S3(t *testing.T) {
+	t.Parallel()
+	cluster := raftCluster(t, nil)
+	defer cluster.Cleanup()
+
+	s3 := newFakeS3("snapshots")
+	defer s3.Close()
+
+	client := cluster.Cores[0].Client
+	_, err := client.Logical().Write("sys/storage/raft/snapshot-auto/config/s3", map[string]interface{}{
+		"interval":                "1s",
+		"retain":                  2,
+		"storage_type":            "aws-s3",
+		"path_prefix":             "vault/cluster1",
+		"aws_s3_bucket":           "snapshots",
+		"aws_s3_endpoint":         s3.URL,
+		"aws_s3_force_path_style": true,
+		"aws_s3_disable_tls":      true,
+		"aws_access_key_id":       "minio",
+		"aws_secret_access_key":   "minio-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret access key is not returned
+	secret, err := client.Logical().Read("sys/storage/raft/snapshot-auto/config/s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := secret.Data["aws_secret_access_key"]; ok {
+		t.Fatalf("secret access key was returned: %#v", secret.Data)
+	}
+	if secret.Data["aws_access_key_id"] != "minio" || secret.Data["file_prefix"] != "vault-snapshot" {
+		t.Fatalf("bad: %#v", secret.Data)
+	}
+
+	status := waitForSnapshotStatus(t, client, "s3", successfulSnapshots(3))
+	if url := status["last_success_url"].(string); !strings.HasPrefix(url, "s3://snapshots/vault/cluster1/vault-snapshot-") {
+		t.Fatalf("bad url: %q", url)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	var keys []string
+	for {
+		keys = s3.Keys()
+		if len(keys) == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 snapshots, got: %v", keys)
+	}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "vault/cluster1/vault-snapshot-") || !strings.HasSuffix(key, ".snap") {
+			t.Fatalf("bad key: %q", key)
+		}
+	}
+}
+
+func TestRaft_AutoSnapshot_Failure(t *testing.T) {
+	t.Parallel()
+	dir, err := ioutil.TempDir("", "vault-raft-snapshot-auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cluster := raftCluster(t, &RaftClusterOpts{RaftSnapshotDirectory: dir})
+	defer cluster.Cleanup()
+
+	// Nothing listens on the endpoint once the server is closed
+	s3 := newFakeS3("snapshots")
+	s3.Close()
+
+	client := cluster.Cores[0].Client
+	_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/broken", map[string]interface{}{
+		"interval":                "1s",
+		"storage_type":            "aws-s3",
+		"aws_s3_bucket":           "snapshots",
+		"aws_s3_endpoint":         s3.URL,
+		"aws_s3_force_path_style": true,
+		"aws_s3_disable_tls":      true,
+		"aws_access_key_id":       "minio",
+		"aws_secret_access_key":   "minio-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status := waitForSnapshotStatus(t, client, "broken", func(data map[string]interface{}) bool {
+		errors, _ := data["consecutive_errors"].(json.Number).Int64()
+		return errors >= 2
+	})
+	if !strings.Contains(status["last_snapshot_error"].(string), "failed to save snapshot") {
+		t.Fatalf("bad: %#v", status)
+	}
+	if status["last_success_time"] != "" || status["last_snapshot_start"] == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// Snapshots that do not fit in the local space allowance fail
+	_, err = client.Logical().Write("sys/storage/raft/snapshot-auto/config/full", map[string]interface{}{
+		"interval":        "1s",
+		"storage_type":    "local",
+		"path_prefix":     dir,
+		"local_max_space": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status = waitForSnapshotStatus(t, client, "full", func(data map[string]interface{}) bool {
+		return data["last_snapshot_error"] != ""
+	})
+	if !strings.Contains(status["last_snapshot_error"].(string), "exceeds local_max_space") {
+		t.Fatalf("bad: %#v", status)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Fatalf("expected no snapshot to be saved, got %d files", len(files))
+	}
+}
+
+// fakeS3 is a minimal server compatible with the S3 API, as used with
+// path-style addressing for a single bucket
+type fakeS3 struct {
+	*httptest.Server
+
+	bucket  string
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3(bucket string) *fakeS3 {
+	s := &fakeS3{
+		bucket:  bucket,
+		objects: make(map[string][]byte),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Keys returns the keys of the objects of the bucket
+func (s *fakeS3) Keys() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *fakeS3) handle(w http.ResponseWriter, r *http.Request) {
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if parts[0] != s.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	var key string
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.objects[key] = b
+
+	case r.Method == http.MethodDelete && key != "":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+
+	case r.Method == http.MethodGet && key == "" && r.URL.Query().Get("list-type") == "2":
+		type object struct {
+			Key  string
+			Size int
+		}
+		result := struct {
+			XMLName     xml.Name `xml:"ListBucketResult"`
+			Name        string
+			Prefix      string
+			KeyCount    int
+			IsTruncated bool
+			Contents    []object
+		}{
+			Name:   s.bucket,
+			Prefix: r.URL.Query().Get("prefix"),
+		}
+		for k, v := range s.objects {
+			if strings.HasPrefix(k, result.Prefix) {
+				result.Contents = append(result.Contents, object{Key: k, Size: len(v)})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool {
+			return result.Contents[i].Key < result.Contents[j].Key
+		})
+		result.KeyCount = len(result.Contents)
+
+		w.Header().Set("Content-Type", "application/xml")
+		fmt.Fprint(w, xml.Header)
+		xml.NewEncoder(w).Encode(result)
+
+	default:
+		http.Error(w, "NotImplemented", http.StatusNotImplemented)
+	}
+}
@@ -0,0 +1,207 @@
+package rafttests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func writeSubtreeKeys(t *testing.T, client *api.Client, mount string, keys ...string) {
+	t.Helper()
+
+	for _, key := range keys {
+		_, err := client.Logical().Write(mount+key, map[string]interface{}{
+			"value": key,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func listSubtreeKeys(t *testing.T, client *api.Client, mount string) []interface{} {
+	t.Helper()
+
+	secret, err := client.Logical().List(mount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == nil {
+		return nil
+	}
+	return secret.Data["keys"].([]interface{})
+}
+
+func TestRaft_SnapshotRestoreSubtree(t *testing.T) {
+	t.Parallel()
+	cluster := raftCluster(t, nil)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	for _, mount := range []string{"kv1", "kv2"} {
+		if err := client.Sys().Mount(mount, &api.MountInput{Type: "kv"}); err != nil {
+			t.Fatal(err)
+		}
+		writeSubtreeKeys(t, client, mount+"/", "a", "b", "c")
+	}
+
+	var snap bytes.Buffer
+	if err := client.Sys().RaftSnapshot(&snap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Change both mounts after the snapshot
+	for _, mount := range []string{"kv1/", "kv2/"} {
+		if _, err := client.Logical().Write(mount+"a", map[string]interface{}{"value": "changed"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.Logical().Delete(mount + "b"); err != nil {
+			t.Fatal(err)
+		}
+		writeSubtreeKeys(t, client, mount, "d", "e")
+	}
+
+	t.Run("mount", func(t *testing.T) {
+		secret, err := client.Sys().RaftSnapshotRestoreSubtree(bytes.NewReader(snap.Bytes()), "kv1", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mounts := secret.Data["mounts"].([]interface{})
+		if len(mounts) != 1 {
+			t.Fatalf("bad: %#v", mounts)
+		}
+		restore := mounts[0].(map[string]interface{})
+		if restore["path"] != "kv1/" || restore["type"] != "kv" || restore["keys_restored"].(json.Number).String() != "3" || restore["keys_deleted"].(json.Number).String() != "2" {
+			t.Fatalf("bad: %#v", restore)
+		}
+
+		if keys := listSubtreeKeys(t, client, "kv1/"); fmt.Sprint(keys) != "[a b c]" {
+			t.Fatalf("bad: %v", keys)
+		}
+		read, err := client.Logical().Read("kv1/a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read.Data["value"] != "a" {
+			t.Fatalf("bad: %#v", read.Data)
+		}
+
+		// The other mount is left untouched
+		if keys := listSubtreeKeys(t, client, "kv2/"); fmt.Sprint(keys) != "[a c d e]" {
+			t.Fatalf("bad: %v", keys)
+		}
+	})
+
+	t.Run("missing_mount", func(t *testing.T) {
+		if err := client.Sys().Mount("kv3", &api.MountInput{Type: "kv"}); err != nil {
+			t.Fatal(err)
+		}
+		defer client.Sys().Unmount("kv3")
+
+		_, err := client.Sys().RaftSnapshotRestoreSubtree(bytes.NewReader(snap.Bytes()), "kv3", "")
+		if err == nil {
+			t.Fatal("expected an error for a mount missing from the snapshot")
+		}
+		_, err = client.Sys().RaftSnapshotRestoreSubtree(bytes.NewReader(snap.Bytes()), "sys", "")
+		if err == nil {
+			t.Fatal("expected an error for the system mount")
+		}
+	})
+
+	t.Run("namespace", func(t *testing.T) {
+		// Enable the mount again, so that its storage prefix differs from
+		// the one of the snapshot
+		if err := client.Sys().Unmount("kv2"); err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Sys().Mount("kv2", &api.MountInput{Type: "kv"}); err != nil {
+			t.Fatal(err)
+		}
+		writeSubtreeKeys(t, client, "kv2/", "f")
+
+		secret, err := client.Sys().RaftSnapshotRestoreSubtree(bytes.NewReader(snap.Bytes()), "", "root")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		paths := make(map[string]bool)
+		for _, raw := range secret.Data["mounts"].([]interface{}) {
+			paths[raw.(map[string]interface{})["path"].(string)] = true
+		}
+		if !paths["kv1/"] || !paths["kv2/"] {
+			t.Fatalf("bad: %#v", secret.Data["mounts"])
+		}
+
+		if keys := listSubtreeKeys(t, client, "kv2/"); fmt.Sprint(keys) != "[a b c]" {
+			t.Fatalf("bad: %v", keys)
+		}
+		read, err := client.Logical().Read("kv2/b")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read == nil || read.Data["value"] != "b" {
+			t.Fatalf("bad: %#v", read)
+		}
+	})
+}
+
+func TestRaft_SnapshotRestoreSubtree_PrunedTerm(t *testing.T) {
+	t.Parallel()
+	cluster := raftCluster(t, nil)
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	if err := client.Sys().Mount("kv", &api.MountInput{Type: "kv"}); err != nil {
+		t.Fatal(err)
+	}
+	writeSubtreeKeys(t, client, "kv/", "a")
+
+	var snap bytes.Buffer
+	if err := client.Sys().RaftSnapshot(&snap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate, and remove the term of the snapshot once everything has been
+	// re-encrypted
+	if err := client.Sys().Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		secret, err := client.Logical().Read("sys/key-status")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rewrap, _ := secret.Data["rewrap"].(map[string]interface{})
+		if rewrap != nil && rewrap["status"] == "complete" && rewrap["term"].(json.Number).String() == "2" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rewrap did not complete: %#v", secret.Data)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if _, err := client.Logical().Write("sys/rotate/prune", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	writeSubtreeKeys(t, client, "kv/", "b")
+	_, err := client.Sys().RaftSnapshotRestoreSubtree(bytes.NewReader(snap.Bytes()), "kv", "")
+	if err == nil || !strings.Contains(err.Error(), "sys/rotate/prune") {
+		t.Fatalf("expected an error for the pruned term, got: %v", err)
+	}
+
+	// The mount is left untouched, and writable
+	writeSubtreeKeys(t, client, "kv/", "c")
+	if keys := listSubtreeKeys(t, client, "kv/"); fmt.Sprint(keys) != "[a b c]" {
+		t.Fatalf("bad: %v", keys)
+	}
+}
@@ -35,6 +35,7 @@ type RaftClusterOpts struct {
 	PhysicalFactoryConfig          map[string]interface{}
 	DisablePerfStandby             bool
 	EnableResponseHeaderRaftNodeID bool
+	RaftSnapshotDirectory          string
 }
 
 func raftCluster(t testing.TB, ropts *RaftClusterOpts) *vault.TestCluster {
@@ -48,6 +49,7 @@ func raftCluster(t testing.TB, ropts *RaftClusterOpts) *vault.TestCluster {
 		},
 		DisableAutopilot:               !ropts.EnableAutopilot,
 		EnableResponseHeaderRaftNodeID: ropts.EnableResponseHeaderRaftNodeID,
+		RaftSnapshotDirectory:          ropts.RaftSnapshotDirectory,
 	}
 
 	opts := vault.TestClusterOptions{
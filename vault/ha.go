@@ -378,6 +378,18 @@ func (c *Core) runStandby(doneCh, manualStepDownCh, stopCh chan struct{}) {
 			c.logger.Debug("shutting down periodic leader refresh")
 		})
 	}
+	if c.isRaftReadReplica() {
+		// Serve reads from the local copy of the data
+		readReplicaStop := make(chan struct{})
+
+		g.Add(func() error {
+			c.runRaftReadReplica(readReplicaStop)
+			return nil
+		}, func(error) {
+			close(readReplicaStop)
+			c.logger.Debug("shutting down read replica")
+		})
+	}
 	{
 		// Wait for leadership
 		leaderStopCh := make(chan struct{})
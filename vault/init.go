@@ -181,6 +181,9 @@ func (c *Core) Initialize(ctx context.Context, initParams *InitParams) (*InitRes
 		if len(barrierConfig.PGPKeys) > 0 {
 			return nil, fmt.Errorf("PGP keys not supported when storing shares")
 		}
+		if barrierConfig.SignedUnseal {
+			return nil, fmt.Errorf("signed unseal not supported when storing shares")
+		}
 		barrierConfig.SecretShares = 1
 		barrierConfig.SecretThreshold = 1
 		if barrierConfig.StoredShares != 1 {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/helper/jsonutil"
@@ -170,6 +171,16 @@ func (k *Keyring) TermKey(term uint32) *Key {
 	return k.keys[term]
 }
 
+// Terms returns the terms of all the keys in the keyring, in ascending order
+func (k *Keyring) Terms() []uint32 {
+	terms := make([]uint32, 0, len(k.keys))
+	for term := range k.keys {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool { return terms[i] < terms[j] })
+	return terms
+}
+
 // SetMasterKey is used to update the master key
 func (k *Keyring) SetMasterKey(val []byte) *Keyring {
 	valCopy := make([]byte, len(val))
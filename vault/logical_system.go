@@ -25,6 +25,7 @@ import (
 	memdb "github.com/hashicorp/go-memdb"
 	"github.com/hashicorp/go-multierror"
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/hostutil"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/metricsutil"
@@ -40,6 +41,7 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/version"
 	"github.com/mitchellh/mapstructure"
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 const (
@@ -87,6 +89,7 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"remount",
 				"audit",
 				"audit/*",
+				"audit-chain/*",
 				"raw",
 				"raw/*",
 				"replication/primary/secondary-token",
@@ -96,6 +99,7 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"replication/dr/reindex",
 				"replication/performance/reindex",
 				"rotate",
+				"rotate/prune",
 				"config/cors",
 				"config/auditing/*",
 				"config/ui/headers/*",
@@ -106,6 +110,8 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 				"leases/revoke-force/*",
 				"leases/lookup/*",
 				"storage/raft/snapshot-auto/config/*",
+				"storage/migration",
+				"storage/verify",
 			},
 
 			Unauthenticated: []string{
@@ -170,9 +176,12 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.remountPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.metricsPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.monitorPath())
+	b.Backend.Paths = append(b.Backend.Paths, b.eventsSubscribePath())
 	b.Backend.Paths = append(b.Backend.Paths, b.hostInfoPath())
 	b.Backend.Paths = append(b.Backend.Paths, b.quotasPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.rootActivityPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.storageMigrationPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.storageVerifyPaths()...)
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, b.rawPaths()...)
@@ -624,6 +633,123 @@ func (b *SystemBackend) handleCapabilities(ctx context.Context, req *logical.Req
 	return ret, nil
 }
 
+// handlePolicyExplain explains which policies and path rules decide a request
+// made by a token or entity
+func (b *SystemBackend) handlePolicyExplain(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	path := d.Get("path").(string)
+	if path == "" {
+		return logical.ErrorResponse("missing path"), nil
+	}
+
+	op := logical.Operation(strings.ToLower(d.Get("operation").(string)))
+	switch op {
+	case logical.CreateOperation, logical.ReadOperation, logical.UpdateOperation, logical.DeleteOperation, logical.ListOperation:
+	default:
+		return logical.ErrorResponse(fmt.Sprintf("unsupported operation %q", op)), nil
+	}
+
+	token := d.Get("token").(string)
+	accessor := d.Get("accessor").(string)
+	entityID := d.Get("entity_id").(string)
+
+	var set int
+	for _, v := range []string{token, accessor, entityID} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return logical.ErrorResponse("only one of token, accessor and entity_id can be specified"), nil
+	}
+
+	explainReq := &logical.Request{
+		Operation:  op,
+		Path:       path,
+		Data:       d.Get("parameters").(map[string]interface{}),
+		Connection: req.Connection,
+	}
+
+	var policyNS *namespace.Namespace
+	var entity *identity.Entity
+	var policyNames map[string][]string
+	switch {
+	case entityID != "":
+		ns, err := namespace.FromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var identityPolicies map[string][]string
+		entity, identityPolicies, err = b.Core.fetchEntityAndDerivedPolicies(ctx, ns, entityID)
+		if err != nil {
+			return nil, err
+		}
+		if entity == nil {
+			return logical.ErrorResponse("entity not found"), nil
+		}
+		policyNS = ns
+		policyNames = identityPolicies
+
+	default:
+		if accessor != "" {
+			aEntry, err := b.Core.tokenStore.lookupByAccessor(ctx, accessor, false, false)
+			if err != nil {
+				return nil, err
+			}
+			token = aEntry.TokenID
+		}
+		if token == "" {
+			token = req.ClientToken
+		}
+
+		te, err := b.Core.tokenStore.Lookup(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		if te == nil {
+			return logical.ErrorResponse("invalid token"), nil
+		}
+
+		policyNS, entity, policyNames, err = b.Core.tokenPolicyNames(ctx, te)
+		if err != nil {
+			if errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+				return logical.ErrorResponse("invalid token"), nil
+			}
+			return nil, err
+		}
+		explainReq.SetTokenEntry(te)
+	}
+
+	explanation, err := b.Core.explainPolicies(ctx, policyNS, entity, policyNames, explainReq)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]map[string]interface{}, 0, len(explanation.Policies))
+	for _, p := range explanation.Policies {
+		policies = append(policies, map[string]interface{}{
+			"name":         p.Policy,
+			"namespace":    p.Namespace,
+			"rule":         p.Rule,
+			"capabilities": p.Capabilities,
+			"effective":    p.Effective,
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"path":         path,
+			"operation":    string(op),
+			"allowed":      explanation.Allowed,
+			"rule":         explanation.Rule,
+			"capabilities": explanation.Capabilities,
+			"granted_by":   explanation.GrantedBy,
+			"denied_by":    explanation.DeniedBy,
+			"reason":       explanation.Reason,
+			"policies":     policies,
+		},
+	}, nil
+}
+
 // handleRekeyRetrieve returns backed-up, PGP-encrypted unseal keys from a
 // rekey operation
 func (b *SystemBackend) handleRekeyRetrieve(
@@ -720,6 +846,9 @@ func mountInfo(entry *MountEntry) map[string]interface{} {
 	if rawVal, ok := entry.synthesizedConfigCache.Load("audit_non_hmac_response_keys"); ok {
 		entryConfig["audit_non_hmac_response_keys"] = rawVal.([]string)
 	}
+	if rawVal, ok := entry.synthesizedConfigCache.Load("audit_field_selectors"); ok {
+		entryConfig["audit_field_selectors"] = rawVal.([]string)
+	}
 	// Even though empty value is valid for ListingVisibility, we can ignore
 	// this case during mount since there's nothing to unset/hide.
 	if len(entry.Config.ListingVisibility) > 0 {
@@ -912,6 +1041,12 @@ func (b *SystemBackend) handleMount(ctx context.Context, req *logical.Request, d
 	if len(apiConfig.AuditNonHMACResponseKeys) > 0 {
 		config.AuditNonHMACResponseKeys = apiConfig.AuditNonHMACResponseKeys
 	}
+	if len(apiConfig.AuditFieldSelectors) > 0 {
+		if _, err := audit.ParseFieldSelectors(apiConfig.AuditFieldSelectors); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.AuditFieldSelectors = apiConfig.AuditFieldSelectors
+	}
 	if len(apiConfig.PassthroughRequestHeaders) > 0 {
 		config.PassthroughRequestHeaders = apiConfig.PassthroughRequestHeaders
 	}
@@ -1172,6 +1307,10 @@ func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (
 		resp.Data["audit_non_hmac_response_keys"] = rawVal.([]string)
 	}
 
+	if rawVal, ok := mountEntry.synthesizedConfigCache.Load("audit_field_selectors"); ok {
+		resp.Data["audit_field_selectors"] = rawVal.([]string)
+	}
+
 	if len(mountEntry.Config.ListingVisibility) > 0 {
 		resp.Data["listing_visibility"] = mountEntry.Config.ListingVisibility
 	}
@@ -1374,6 +1513,35 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if rawVal, ok := data.GetOk("audit_field_selectors"); ok {
+		auditFieldSelectors := rawVal.([]string)
+		if _, err := audit.ParseFieldSelectors(auditFieldSelectors); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.AuditFieldSelectors
+		mountEntry.Config.AuditFieldSelectors = auditFieldSelectors
+
+		// Update the mount table
+		var err error
+		switch {
+		case strings.HasPrefix(path, "auth/"):
+			err = b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local)
+		default:
+			err = b.Core.persistMounts(ctx, b.Core.mounts, &mountEntry.Local)
+		}
+		if err != nil {
+			mountEntry.Config.AuditFieldSelectors = oldVal
+			return handleError(err)
+		}
+
+		mountEntry.SyncCache()
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of audit_field_selectors successful", "path", path)
+		}
+	}
+
 	if rawVal, ok := data.GetOk("listing_visibility"); ok {
 		lvString := rawVal.(string)
 		listingVisibility := ListingVisibilityType(lvString)
@@ -1578,6 +1746,7 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	b.Core.publishMountEvent(EventMountTuned, mountEntry)
 	return resp, nil
 }
 
@@ -1898,6 +2067,12 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 	if len(apiConfig.AuditNonHMACResponseKeys) > 0 {
 		config.AuditNonHMACResponseKeys = apiConfig.AuditNonHMACResponseKeys
 	}
+	if len(apiConfig.AuditFieldSelectors) > 0 {
+		if _, err := audit.ParseFieldSelectors(apiConfig.AuditFieldSelectors); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.AuditFieldSelectors = apiConfig.AuditFieldSelectors
+	}
 	if len(apiConfig.PassthroughRequestHeaders) > 0 {
 		config.PassthroughRequestHeaders = apiConfig.PassthroughRequestHeaders
 	}
@@ -2080,6 +2255,9 @@ func (b *SystemBackend) handlePoliciesSet(policyType PolicyType) framework.Opera
 		if policy.Name == "" {
 			return logical.ErrorResponse("policy name must be provided in the URL"), nil
 		}
+		if policyType == PolicyTypeACL && policyHistoryPathRe.MatchString(policy.Name) {
+			return logical.ErrorResponse("policy names ending in /versions, /diff or /rollback are reserved for the policy history"), nil
+		}
 
 		policy.Raw = data.Get("policy").(string)
 		if policy.Raw == "" && policyType == PolicyTypeACL && strings.HasPrefix(req.Path, "policy") {
@@ -2119,7 +2297,7 @@ func (b *SystemBackend) handlePoliciesSet(policyType PolicyType) framework.Opera
 		}
 
 		// Update the policy
-		if err := b.Core.policyStore.SetPolicy(ctx, policy); err != nil {
+		if err := b.Core.policyStore.SetPolicyWithAuthor(ctx, policy, req.ClientTokenAccessor); err != nil {
 			return handleError(err)
 		}
 		return resp, nil
@@ -2137,6 +2315,182 @@ func (b *SystemBackend) handlePoliciesDelete(policyType PolicyType) framework.Op
 	}
 }
 
+// handlePoliciesACLVersionsList handles the
+// "/sys/policies/acl/<name>/versions" endpoint to list the history of
+// an ACL policy
+func (b *SystemBackend) handlePoliciesACLVersionsList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+
+	versions, err := b.Core.policyStore.ListPolicyVersions(ctx, name)
+	if err != nil {
+		return handleError(err)
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(versions))
+	keyInfo := make(map[string]interface{}, len(versions))
+	for _, pv := range versions {
+		key := strconv.Itoa(pv.Version)
+		keys = append(keys, key)
+		keyInfo[key] = map[string]interface{}{
+			"author_accessor": pv.AuthorAccessor,
+			"created_time":    pv.CreatedTime,
+		}
+	}
+
+	resp := logical.ListResponseWithInfo(keys, keyInfo)
+	resp.Data["current_version"] = versions[len(versions)-1].Version
+	return resp, nil
+}
+
+// handlePoliciesACLVersionRead handles the
+// "/sys/policies/acl/<name>/versions/<version>" endpoint to read a
+// previous version of an ACL policy
+func (b *SystemBackend) handlePoliciesACLVersionRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := data.Get("name").(string)
+	version := data.Get("version").(int)
+
+	pv, err := b.Core.policyStore.GetPolicyVersion(ctx, name, version)
+	if err != nil {
+		return handleError(err)
+	}
+	if pv == nil {
+		return nil, nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":            strings.ToLower(name),
+			"version":         pv.Version,
+			"policy":          pv.Raw,
+			"author_accessor": pv.AuthorAccessor,
+			"created_time":    pv.CreatedTime,
+		},
+	}, nil
+}
+
+// handlePoliciesACLDiff handles the "/sys/policies/acl/<name>/diff"
+// endpoint to compare two versions of an ACL policy
+func (b *SystemBackend) handlePoliciesACLDiff(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	name := strings.ToLower(data.Get("name").(string))
+
+	versions, err := b.Core.policyStore.ListPolicyVersions(ctx, name)
+	if err != nil {
+		return handleError(err)
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	find := func(version int) (int, *PolicyVersion) {
+		for i, pv := range versions {
+			if pv.Version == version {
+				return i, pv
+			}
+		}
+		return -1, nil
+	}
+
+	toIndex := len(versions) - 1
+	if to, ok := data.GetOk("to"); ok {
+		toIndex, _ = find(to.(int))
+		if toIndex < 0 {
+			return logical.ErrorResponse(fmt.Sprintf("version %d of policy %q not found", to.(int), name)), nil
+		}
+	}
+	toVersion := versions[toIndex]
+
+	var fromVersion *PolicyVersion
+	if from, ok := data.GetOk("from"); ok {
+		_, fromVersion = find(from.(int))
+		if fromVersion == nil {
+			return logical.ErrorResponse(fmt.Sprintf("version %d of policy %q not found", from.(int), name)), nil
+		}
+	} else {
+		if toIndex == 0 {
+			return logical.ErrorResponse(fmt.Sprintf("no version of policy %q precedes version %d", name, toVersion.Version)), nil
+		}
+		fromVersion = versions[toIndex-1]
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(strings.TrimSuffix(fromVersion.Raw, "\n")),
+		B:        difflib.SplitLines(strings.TrimSuffix(toVersion.Raw, "\n")),
+		FromFile: fmt.Sprintf("%s (version %d)", name, fromVersion.Version),
+		ToFile:   fmt.Sprintf("%s (version %d)", name, toVersion.Version),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":         name,
+			"from_version": fromVersion.Version,
+			"to_version":   toVersion.Version,
+			"diff":         diff,
+		},
+	}, nil
+}
+
+// handlePoliciesACLRollback handles the "/sys/policies/acl/<name>/rollback"
+// endpoint to restore a previous version of an ACL policy. The restored
+// contents are recorded as a new version. Since it replaces the policy, the
+// token must also be allowed to update "/sys/policies/acl/<name>".
+func (b *SystemBackend) handlePoliciesACLRollback(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(data.Get("name").(string))
+	versionRaw, ok := data.GetOk("version")
+	if !ok {
+		return logical.ErrorResponse("version must be provided"), nil
+	}
+	version := versionRaw.(int)
+
+	acl, _, _, _, err := b.Core.fetchACLTokenEntryAndEntity(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	checkReq := *req
+	checkReq.Operation = logical.UpdateOperation
+	checkReq.Path = "sys/policies/acl/" + name
+	if !acl.AllowOperation(ctx, &checkReq, false).Allowed {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	pv, err := b.Core.policyStore.GetPolicyVersion(ctx, name, version)
+	if err != nil {
+		return handleError(err)
+	}
+	if pv == nil {
+		return logical.ErrorResponse(fmt.Sprintf("version %d of policy %q not found", version, name)), nil
+	}
+
+	p, err := ParseACLPolicy(ns, pv.Raw)
+	if err != nil {
+		return handleError(err)
+	}
+	policy := &Policy{
+		Name:      name,
+		Type:      PolicyTypeACL,
+		Raw:       pv.Raw,
+		Paths:     p.Paths,
+		Templated: p.Templated,
+		namespace: ns,
+	}
+
+	if err := b.Core.policyStore.SetPolicyWithAuthor(ctx, policy, req.ClientTokenAccessor); err != nil {
+		return handleError(err)
+	}
+	return nil, nil
+}
+
 type passwordPolicyConfig struct {
 	HCLPolicy string `json:"policy"`
 }
@@ -2358,6 +2712,24 @@ func (b *SystemBackend) handleAuditHash(ctx context.Context, req *logical.Reques
 	}, nil
 }
 
+// handleAuditChain returns the keys verifying the hash chain of an audit
+// device
+func (b *SystemBackend) handleAuditChain(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	path := sanitizePath(data.Get("path").(string))
+
+	keys, err := b.Core.auditBroker.HashChainKeys(ctx, path)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"hmac_key":   base64.StdEncoding.EncodeToString(keys.HMACKey),
+			"public_key": base64.StdEncoding.EncodeToString(keys.PublicKey()),
+		},
+	}, nil
+}
+
 // handleEnableAudit is used to enable a new audit backend
 func (b *SystemBackend) handleEnableAudit(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	repState := b.Core.ReplicationState()
@@ -2523,13 +2895,39 @@ func (b *SystemBackend) handleKeyStatus(ctx context.Context, req *logical.Reques
 		return nil, err
 	}
 
+	keyring, err := b.Core.barrier.Keyring()
+	if err != nil {
+		return nil, err
+	}
+
 	resp := &logical.Response{
 		Data: map[string]interface{}{
-			"term":         info.Term,
-			"install_time": info.InstallTime.Format(time.RFC3339Nano),
-			"encryptions":  info.Encryptions,
+			"term":          info.Term,
+			"install_time":  info.InstallTime.Format(time.RFC3339Nano),
+			"encryptions":   info.Encryptions,
+			"keyring_terms": keyring.Terms(),
 		},
 	}
+
+	rewrap, err := b.Core.currentBarrierRewrapStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rewrap != nil {
+		rewrapData := map[string]interface{}{
+			"term":              rewrap.Term,
+			"status":            barrierRewrapStatusInProgress,
+			"start_time":        rewrap.StartTime.Format(time.RFC3339Nano),
+			"entries_checked":   rewrap.EntriesChecked,
+			"entries_rewrapped": rewrap.EntriesRewrapped,
+			"entries_failed":    rewrap.EntriesFailed,
+		}
+		if rewrap.Complete() {
+			rewrapData["status"] = barrierRewrapStatusComplete
+			rewrapData["complete_time"] = rewrap.CompleteTime.Format(time.RFC3339Nano)
+		}
+		resp.Data["rewrap"] = rewrapData
+	}
 	return resp, nil
 }
 
@@ -2616,6 +3014,29 @@ func (b *SystemBackend) handleRotate(ctx context.Context, _ *logical.Request, _
 	return nil, nil
 }
 
+// handleRotatePrune is used to remove the keys that are no longer needed
+// from the keyring once all the entries have been rewrapped
+func (b *SystemBackend) handleRotatePrune(ctx context.Context, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	repState := b.Core.ReplicationState()
+	if repState.HasState(consts.ReplicationPerformanceSecondary) {
+		return logical.ErrorResponse("cannot prune keys on a replication secondary"), nil
+	}
+
+	removed, err := b.Core.pruneBarrierKeys(ctx)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	if removed == nil {
+		removed = []uint32{}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"removed_terms": removed,
+		},
+	}, nil
+}
+
 func (b *SystemBackend) handleWrappingPubkey(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	x, _ := b.Core.wrappingJWTKey.X.MarshalText()
 	y, _ := b.Core.wrappingJWTKey.Y.MarshalText()
@@ -2896,6 +3317,134 @@ func (b *SystemBackend) handleMonitor(ctx context.Context, req *logical.Request,
 	}
 }
 
+// eventsHeartbeatInterval is the interval at which comments are written to
+// the event streams to keep them open, and at which the token of the
+// subscriber is checked again
+const eventsHeartbeatInterval = 30 * time.Second
+
+// handleEventsSubscribe streams the system events matching the filters of the
+// request as server-sent events. Events are only delivered if the token of the
+// subscriber has the read capability on their path.
+func (b *SystemBackend) handleEventsSubscribe(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	w := req.ResponseWriter
+	if w == nil {
+		return logical.ErrorResponse("streaming not supported"), nil
+	}
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return logical.ErrorResponse("streaming not supported"), nil
+	}
+
+	lastID, err := parseEventID(data.Get("last_event_id").(string))
+	if err != nil {
+		return logical.ErrorResponse("invalid last_event_id"), logical.ErrInvalidRequest
+	}
+
+	acl, _, _, _, err := b.Core.fetchACLTokenEntryAndEntity(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := b.Core.SubscribeEvents(&EventFilter{
+		Types: data.Get("types").([]string),
+		Paths: data.Get("paths").([]string),
+	}, lastID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	// 0 byte write is needed before the Flush call so that if we are using
+	// a gzip stream it will go ahead and write out the HTTP response header
+	_, err = w.Write([]byte(""))
+	if err != nil {
+		return nil, fmt.Errorf("error seeding flusher: %w", err)
+	}
+
+	flusher.Flush()
+
+	// Errors are still returned below, but they are ignored upstream since a
+	// response was already sent by writing the header and flushing the
+	// writer above.
+	writeEvent := func(ev *Event) error {
+		if !ev.unauthenticated {
+			checkReq := &logical.Request{
+				Operation:  logical.ReadOperation,
+				Path:       ev.Path,
+				Connection: req.Connection,
+			}
+			if !acl.AllowOperation(namespace.ContextWithNamespace(ctx, ev.namespace), checkReq, false).Allowed {
+				return nil
+			}
+		}
+
+		evJSON, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, evJSON)
+		return err
+	}
+
+	ticker := time.NewTicker(eventsHeartbeatInterval)
+	defer ticker.Stop()
+
+	var dropped uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-sub.Done():
+			// The node sealed: deliver the events that were published before
+			// it did, including the seal event, and end the stream
+			for {
+				select {
+				case ev := <-sub.Events():
+					if err := writeEvent(ev); err != nil {
+						return nil, fmt.Errorf("error streaming events: %w", err)
+					}
+				default:
+					flusher.Flush()
+					return nil, nil
+				}
+			}
+		case ev := <-sub.Events():
+			if err := writeEvent(ev); err != nil {
+				return nil, fmt.Errorf("error streaming events: %w", err)
+			}
+			flusher.Flush()
+
+			// Changes of the policies may change which events the token can
+			// read
+			if ev.Type == EventPolicyWritten || ev.Type == EventPolicyDeleted {
+				req.SetTokenEntry(nil)
+				acl, _, _, _, err = b.Core.fetchACLTokenEntryAndEntity(ctx, req)
+				if err != nil {
+					return nil, nil
+				}
+			}
+		case <-ticker.C:
+			// End the stream once the token is revoked or expired
+			req.SetTokenEntry(nil)
+			acl, _, _, _, err = b.Core.fetchACLTokenEntryAndEntity(ctx, req)
+			if err != nil {
+				return nil, nil
+			}
+
+			if d := sub.Dropped(); d != dropped {
+				_, err = fmt.Fprintf(w, ": %d events dropped\n\n", d-dropped)
+				dropped = d
+			} else {
+				_, err = fmt.Fprint(w, ": keepalive\n\n")
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error streaming events: %w", err)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
 // handleHostInfo collects and returns host-related information, which includes
 // system information, cpu, disk, and memory usage. Any capture-related errors
 // returned by the collection method will be returned as response warnings.
@@ -3728,6 +4277,12 @@ type SealStatusResponse struct {
 	ClusterID    string `json:"cluster_id,omitempty"`
 	RecoverySeal bool   `json:"recovery_seal"`
 	StorageType  string `json:"storage_type,omitempty"`
+
+	// SignedUnseal is set when the unseal keys must be signed by the key
+	// holders, and encrypted with UnsealPGPKey while sealed
+	SignedUnseal  bool     `json:"signed_unseal,omitempty"`
+	UnsealPGPKey  string   `json:"unseal_pgp_key,omitempty"`
+	UnsealHolders []string `json:"unseal_holders,omitempty"`
 }
 
 func (core *Core) GetSealStatus(ctx context.Context) (*SealStatusResponse, error) {
@@ -3775,6 +4330,18 @@ func (core *Core) GetSealStatus(ctx context.Context) (*SealStatusResponse, error
 
 	progress, nonce := core.SecretProgress()
 
+	var unsealPGPKey string
+	var unsealHolders []string
+	if sealConfig.SignedUnseal {
+		if sealed {
+			unsealPGPKey, err = core.UnsealPGPKey()
+			if err != nil {
+				return nil, err
+			}
+		}
+		unsealHolders = core.UnsealHolders()
+	}
+
 	return &SealStatusResponse{
 		Type:         sealConfig.Type,
 		Initialized:  initialized,
@@ -3789,6 +4356,10 @@ func (core *Core) GetSealStatus(ctx context.Context) (*SealStatusResponse, error
 		ClusterID:    clusterID,
 		RecoverySeal: core.SealAccess().RecoveryKeySupported(),
 		StorageType:  core.StorageType(),
+
+		SignedUnseal:  sealConfig.SignedUnseal,
+		UnsealPGPKey:  unsealPGPKey,
+		UnsealHolders: unsealHolders,
 	}, nil
 }
 
@@ -3917,6 +4488,9 @@ func (b *SystemBackend) rotateBarrierKey(ctx context.Context) error {
 		return errwrap.Wrap(errors.New("failed to save keyring canary"), err)
 	}
 
+	// Re-encrypt the existing entries under the new term in the background
+	b.Core.triggerBarrierRewrap()
+
 	return nil
 }
 
@@ -4154,6 +4728,10 @@ in the plugin catalog.`,
 		`The list of keys in the response data object that will not be HMAC'ed by audit devices.`,
 	},
 
+	"tune_audit_field_selectors": {
+		`The list of selectors of fields in the request and response data, written as "<pointer>=<action>", where the pointer is a JSON pointer starting with /request/data or /response/data and the action is hmac, plaintext or drop.`,
+	},
+
 	"tune_mount_options": {
 		`The options to pass into the backend. Should be a json object with string keys and values.`,
 	},
@@ -4342,6 +4920,26 @@ or delete a policy.
 		"",
 	},
 
+	"policy-versions": {
+		"List or read the versions kept in the history of an ACL policy.",
+		`Every write to an ACL policy is recorded as a new version, along with the
+accessor of the token making the change and the time of the change. A bounded
+number of versions is kept for each policy; the history is removed along with
+the policy.`,
+	},
+
+	"policy-diff": {
+		"Compare two versions of an ACL policy.",
+		`Returns a unified diff between two versions kept in the history of an ACL
+policy. By default the current version is compared to the one preceding it.`,
+	},
+
+	"policy-rollback": {
+		"Restore a previous version of an ACL policy.",
+		`Replaces the contents of the ACL policy with those of the given version from
+its history. The restored contents are recorded as a new version.`,
+	},
+
 	"policy-rules": {
 		`The rules of the policy.`,
 		"",
@@ -4367,6 +4965,15 @@ or delete a policy.
 		"",
 	},
 
+	"audit-chain": {
+		"The keys verifying the hash chain of the given audit backend",
+		`
+Returns the HMAC key linking the entries of the audit log and the public key
+verifying its checkpoints, for use with "vault audit verify". The HMAC key
+allows recomputing the chain and should only be given to auditors.
+		`,
+	},
+
 	"audit-table": {
 		"List the currently enabled audit backends.",
 		`
@@ -4413,7 +5020,9 @@ Enable a new audit backend or disable an existing backend.
 	"key-status": {
 		"Provides information about the backend encryption key.",
 		`
-		Provides the current backend encryption key term and installation time.
+		Provides the current backend encryption key term and installation time,
+		the terms of the keys in the keyring, and the progress of the
+		re-encryption of the existing data under the current term.
 		`,
 	},
 
@@ -4441,7 +5050,18 @@ Enable a new audit backend or disable an existing backend.
 		`
 		Rotate generates a new encryption key which is used to encrypt all
 		data going to the storage backend. The old encryption keys are kept so
-		that data encrypted using those keys can still be decrypted.
+		that data encrypted using those keys can still be decrypted. The
+		existing data is then re-encrypted using the new key in the background.
+		`,
+	},
+
+	"rotate-prune": {
+		"Removes the backend encryption keys that are no longer in use.",
+		`
+		Once all the existing data has been re-encrypted using the current key,
+		the keys of the previous terms are no longer needed to decrypt anything
+		and can be removed from the keyring, so that compromising them does not
+		expose any data.
 		`,
 	},
 
@@ -4462,6 +5082,16 @@ Enable a new audit backend or disable an existing backend.
 		The path will be searched for a path match in all the policies associated with the client token.`,
 	},
 
+	"policy-explain": {
+		"Explains which policies and path rules decide a request.",
+		`Evaluates a request for the given path, operation and parameters against
+		the policies of a token or entity, without performing it. Returns whether the
+		request would be allowed, the path rule applying to it, the policies granting
+		or denying each capability of that rule, and the reason for the decision.
+		If neither a token, an accessor nor an entity ID is provided, the policies of
+		the client token are explained.`,
+	},
+
 	"capabilities_accessor": {
 		"Fetches the capabilities of the token associated with the given token, on the given path.",
 		`When there is no access to the token, token accessor can be used to fetch the token's capabilities
@@ -4670,6 +5300,12 @@ This path responds to the following HTTP methods.
 		"Count of active entities in this Vault cluster.",
 		"Count of active entities in this Vault cluster.",
 	},
+	"events-subscribe": {
+		"Stream the system events of this Vault server.",
+		`Stream the system events of this Vault server, as server-sent events.
+		The events are filtered by type and path, and only the events on paths
+		the token can read are delivered.`,
+	},
 	"host-info": {
 		"Information about the host instance that this Vault server is running on.",
 		`Information about the host instance that this Vault server is running on.
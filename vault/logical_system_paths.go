@@ -232,6 +232,10 @@ func (b *SystemBackend) configPaths() []*framework.Path {
 					Type:        framework.TypeInt,
 					Description: "Specifies the number of shares that should be encrypted by the HSM and stored for auto-unsealing. Currently must be the same as `secret_shares`.",
 				},
+				"signed_unseal": {
+					Type:        framework.TypeBool,
+					Description: "Specifies if the unseal keys must be submitted signed by the holders of `pgp_keys`, and encrypted with the unseal PGP key of the node.",
+				},
 				"recovery_shares": {
 					Type:        framework.TypeInt,
 					Description: "Specifies the number of shares to split the recovery key into.",
@@ -300,6 +304,10 @@ func (b *SystemBackend) rekeyPaths() []*framework.Path {
 					Type:        framework.TypeBool,
 					Description: "Turns on verification functionality",
 				},
+				"signed_unseal": {
+					Type:        framework.TypeBool,
+					Description: "Specifies if the new unseal keys must be submitted signed by the holders of pgp_keys, and encrypted with the unseal PGP key of the node.",
+				},
 			},
 
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -413,7 +421,11 @@ func (b *SystemBackend) rekeyPaths() []*framework.Path {
 			Fields: map[string]*framework.FieldSchema{
 				"key": {
 					Type:        framework.TypeString,
-					Description: "Specifies a single master key share. This is required unless reset is true.",
+					Description: "Specifies a single master key share. This is required unless reset is true or signed_key is set.",
+				},
+				"signed_key": {
+					Type:        framework.TypeString,
+					Description: "Specifies a single master key share, as a base64-encoded PGP message signed by the key holder and encrypted with the unseal PGP key of the node.",
 				},
 				"reset": {
 					Type:        framework.TypeBool,
@@ -486,6 +498,24 @@ func (b *SystemBackend) auditPaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["audit-hash"][1]),
 		},
 
+		{
+			Pattern: "audit-chain/(?P<path>.+)",
+
+			Fields: map[string]*framework.FieldSchema{
+				"path": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["audit_path"][0]),
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.ReadOperation: b.handleAuditChain,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["audit-chain"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["audit-chain"][1]),
+		},
+
 		{
 			Pattern: "audit$",
 
@@ -644,6 +674,17 @@ func (b *SystemBackend) sealPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["rotate"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["rotate"][1]),
 		},
+
+		{
+			Pattern: "rotate/prune$",
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handleRotatePrune,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["rotate-prune"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["rotate-prune"][1]),
+		},
 	}
 }
 
@@ -1046,6 +1087,45 @@ func (b *SystemBackend) capabilitiesPaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysHelp["capabilities_self"][0]),
 			HelpDescription: strings.TrimSpace(sysHelp["capabilities_self"][1]),
 		},
+
+		{
+			Pattern: "policy-explain$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"token": {
+					Type:        framework.TypeString,
+					Description: "Token whose policies are explained. Defaults to the client token.",
+				},
+				"accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the token whose policies are explained.",
+				},
+				"entity_id": {
+					Type:        framework.TypeString,
+					Description: "ID of the entity whose policies are explained.",
+				},
+				"path": {
+					Type:        framework.TypeString,
+					Description: "Path of the request to explain.",
+				},
+				"operation": {
+					Type:        framework.TypeString,
+					Default:     "read",
+					Description: "Operation of the request to explain: one of create, read, update, delete or list.",
+				},
+				"parameters": {
+					Type:        framework.TypeMap,
+					Description: "Parameters of the request to explain.",
+				},
+			},
+
+			Callbacks: map[logical.Operation]framework.OperationFunc{
+				logical.UpdateOperation: b.handlePolicyExplain,
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["policy-explain"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["policy-explain"][1]),
+		},
 	}
 }
 
@@ -1273,6 +1353,34 @@ func (b *SystemBackend) monitorPath() *framework.Path {
 	}
 }
 
+func (b *SystemBackend) eventsSubscribePath() *framework.Path {
+	return &framework.Path{
+		Pattern: "events/subscribe",
+		Fields: map[string]*framework.FieldSchema{
+			"types": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Types of the events to subscribe to, e.g. \"secret.*\". Defaults to all the types.",
+				Query:       true,
+			},
+			"paths": {
+				Type:        framework.TypeCommaStringSlice,
+				Description: "Paths of the events to subscribe to, e.g. \"secret/data/app/*\". Defaults to all the paths.",
+				Query:       true,
+			},
+			"last_event_id": {
+				Type:        framework.TypeString,
+				Description: "ID of the last event received, to resume a subscription. Defaults to the value of the Last-Event-ID header.",
+				Query:       true,
+			},
+		},
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.handleEventsSubscribe,
+		},
+		HelpSynopsis:    strings.TrimSpace(sysHelp["events-subscribe"][0]),
+		HelpDescription: strings.TrimSpace(sysHelp["events-subscribe"][1]),
+	}
+}
+
 func (b *SystemBackend) hostInfoPath() *framework.Path {
 	return &framework.Path{
 		Pattern: "host-info/?",
@@ -1325,6 +1433,10 @@ func (b *SystemBackend) authPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_response_keys"][0]),
 				},
+				"audit_field_selectors": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["tune_audit_field_selectors"][0]),
+				},
 				"options": {
 					Type:        framework.TypeKVPairs,
 					Description: strings.TrimSpace(sysHelp["tune_mount_options"][0]),
@@ -1486,6 +1598,106 @@ func (b *SystemBackend) policyPaths() []*framework.Path {
 			HelpDescription: strings.TrimSpace(sysHelp["policy-list"][1]),
 		},
 
+		{
+			Pattern: "policies/acl/(?P<name>.+)/versions/?$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["policy-name"][0]),
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesACLVersionsList,
+					Summary:  "List the versions kept in the history of the named ACL policy.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["policy-versions"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["policy-versions"][1]),
+		},
+
+		{
+			Pattern: "policies/acl/(?P<name>.+)/versions/(?P<version>\\d+)$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["policy-name"][0]),
+				},
+				"version": {
+					Type:        framework.TypeInt,
+					Description: "The version of the policy.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesACLVersionRead,
+					Summary:  "Retrieve a version of the named ACL policy.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["policy-versions"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["policy-versions"][1]),
+		},
+
+		{
+			Pattern: "policies/acl/(?P<name>.+)/diff$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["policy-name"][0]),
+				},
+				"from": {
+					Type:        framework.TypeInt,
+					Description: "The version to compare from. Defaults to the version preceding 'to'.",
+				},
+				"to": {
+					Type:        framework.TypeInt,
+					Description: "The version to compare to. Defaults to the current version.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesACLDiff,
+					Summary:  "Compare two versions of the named ACL policy.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["policy-diff"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["policy-diff"][1]),
+		},
+
+		{
+			Pattern: "policies/acl/(?P<name>.+)/rollback$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: strings.TrimSpace(sysHelp["policy-name"][0]),
+				},
+				"version": {
+					Type:        framework.TypeInt,
+					Description: "The version of the policy to restore.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handlePoliciesACLRollback,
+					Summary:  "Restore a previous version of the named ACL policy.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysHelp["policy-rollback"][0]),
+			HelpDescription: strings.TrimSpace(sysHelp["policy-rollback"][1]),
+		},
+
 		{
 			Pattern: "policies/acl/(?P<name>.+)",
 
@@ -1679,6 +1891,10 @@ func (b *SystemBackend) mountPaths() []*framework.Path {
 					Type:        framework.TypeCommaStringSlice,
 					Description: strings.TrimSpace(sysHelp["tune_audit_non_hmac_response_keys"][0]),
 				},
+				"audit_field_selectors": {
+					Type:        framework.TypeCommaStringSlice,
+					Description: strings.TrimSpace(sysHelp["tune_audit_field_selectors"][0]),
+				},
 				"options": {
 					Type:        framework.TypeKVPairs,
 					Description: strings.TrimSpace(sysHelp["tune_mount_options"][0]),
@@ -148,6 +148,18 @@ func (b *SystemBackend) raftStoragePaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-force"][0]),
 			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-force"][1]),
 		},
+		{
+			Pattern: "storage/raft/snapshot-restore-subtree",
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotRestoreSubtree(),
+					Summary:  "Restores the keys of a mount, or of the mounts of a namespace, from the provided snapshot.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-restore-subtree"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-restore-subtree"][1]),
+		},
 		{
 			Pattern: "storage/raft/autopilot/state",
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -188,6 +200,10 @@ func (b *SystemBackend) raftStoragePaths() []*framework.Path {
 					Type:        framework.TypeDurationSecond,
 					Description: "Minimum amount of time a server must be in a stable, healthy state before it can be added to the cluster.",
 				},
+				"disable_upgrade_migration": {
+					Type:        framework.TypeBool,
+					Description: "Disables the automated upgrades, which promote the servers running a newer Vault version once there are enough of them, transfer leadership to one of them and demote the older servers.",
+				},
 			},
 
 			Operations: map[logical.Operation]framework.OperationHandler{
@@ -202,6 +218,133 @@ func (b *SystemBackend) raftStoragePaths() []*framework.Path {
 			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-autopilot-configuration"][0]),
 			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-autopilot-configuration"][1]),
 		},
+		{
+			Pattern: "storage/raft/snapshot-auto/config/?$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigList(),
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config-list"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config-list"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/config/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the snapshot configuration.",
+				},
+				"interval": {
+					Type:        framework.TypeDurationSecond,
+					Description: "Time between snapshots.",
+				},
+				"retain": {
+					Type:        framework.TypeInt,
+					Default:     1,
+					Description: "Number of snapshots to keep. The oldest snapshots beyond it are removed.",
+				},
+				"path_prefix": {
+					Type:        framework.TypeString,
+					Description: "For local storage, the directory of the active node the snapshots are saved to. For aws-s3 storage, the prefix of the keys of the snapshots in the bucket.",
+				},
+				"file_prefix": {
+					Type:        framework.TypeString,
+					Default:     "vault-snapshot",
+					Description: "Prefix of the file names of the snapshots.",
+				},
+				"storage_type": {
+					Type:          framework.TypeString,
+					Description:   `Where the snapshots are saved: "local" or "aws-s3".`,
+					AllowedValues: []interface{}{raftAutoSnapshotStorageLocal, raftAutoSnapshotStorageS3},
+				},
+				"local_max_space": {
+					Type:        framework.TypeInt,
+					Description: "For local storage, the maximum number of bytes the snapshots can use. Snapshots fail once it would be exceeded. Unlimited if unset.",
+				},
+				"aws_s3_bucket": {
+					Type:        framework.TypeString,
+					Description: "Bucket the snapshots are saved to.",
+				},
+				"aws_s3_region": {
+					Type:        framework.TypeString,
+					Description: `Region of the bucket. Defaults to "us-east-1".`,
+				},
+				"aws_s3_endpoint": {
+					Type:        framework.TypeString,
+					Description: "Endpoint of a storage compatible with the S3 API, instead of AWS.",
+				},
+				"aws_s3_force_path_style": {
+					Type:        framework.TypeBool,
+					Description: "Use path-style addressing of the bucket, as most storages compatible with the S3 API require.",
+				},
+				"aws_s3_disable_tls": {
+					Type:        framework.TypeBool,
+					Description: "Use HTTP instead of HTTPS to connect to the endpoint.",
+				},
+				"aws_access_key_id": {
+					Type:        framework.TypeString,
+					Description: "Access key ID. If unset, the credentials are read from the environment of the active node.",
+				},
+				"aws_secret_access_key": {
+					Type:        framework.TypeString,
+					Description: "Secret access key.",
+				},
+				"aws_session_token": {
+					Type:        framework.TypeString,
+					Description: "Session token.",
+				},
+				"aws_s3_enable_kms": {
+					Type:        framework.TypeBool,
+					Description: "Encrypt the snapshots with AWS KMS.",
+				},
+				"aws_s3_kms_key": {
+					Type:        framework.TypeString,
+					Description: "ID of the KMS key used to encrypt the snapshots when aws_s3_enable_kms is set. Defaults to the AWS managed key.",
+				},
+				"aws_s3_server_side_encryption": {
+					Type:        framework.TypeBool,
+					Description: "Encrypt the snapshots with AES256 server-side encryption.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigRead(),
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigUpdate(),
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoConfigDelete(),
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-config"][1]),
+		},
+		{
+			Pattern: "storage/raft/snapshot-auto/status/" + framework.GenericNameRegex("name"),
+
+			Fields: map[string]*framework.FieldSchema{
+				"name": {
+					Type:        framework.TypeString,
+					Description: "Name of the snapshot configuration.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageRaftSnapshotAutoStatusRead(),
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-status"][0]),
+			HelpDescription: strings.TrimSpace(sysRaftHelp["raft-snapshot-auto-status"][1]),
+		},
 	}
 }
 
@@ -356,13 +499,16 @@ func (b *SystemBackend) handleRaftBootstrapAnswerWrite() framework.OperationFunc
 		var desiredSuffrage string
 		switch nonVoter {
 		case true:
-			desiredSuffrage = "voter"
-		default:
 			desiredSuffrage = "non-voter"
+		default:
+			desiredSuffrage = "voter"
 		}
 
 		if b.Core.raftFollowerStates != nil {
-			b.Core.raftFollowerStates.Update(serverID, 0, 0, desiredSuffrage)
+			b.Core.raftFollowerStates.Update(&raft.EchoRequestUpdate{
+				NodeID:          serverID,
+				DesiredSuffrage: desiredSuffrage,
+			})
 		}
 
 		peers, err := raftBackend.Peers(ctx)
@@ -401,6 +547,78 @@ func (b *SystemBackend) handleStorageRaftSnapshotRead() framework.OperationFunc
 	}
 }
 
+func (b *SystemBackend) handleStorageRaftSnapshotRestoreSubtree() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		raftStorage, ok := b.Core.underlyingPhysical.(*raft.RaftBackend)
+		if !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+		if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
+			return nil, errors.New("no reader for request")
+		}
+
+		// The body is the snapshot, so the mount or namespace to restore is
+		// given in the query
+		query := req.HTTPRequest.URL.Query()
+		mountPath := query.Get("mount")
+		_, nsSet := query["namespace"]
+		nsPath := query.Get("namespace")
+		if (mountPath == "") == !nsSet {
+			return logical.ErrorResponse("exactly one of mount or namespace must be set"), logical.ErrInvalidRequest
+		}
+
+		var ns *namespace.Namespace
+		if nsSet {
+			nsPath = namespace.Canonicalize(nsPath)
+			if nsPath == "root/" {
+				nsPath = ""
+			}
+			ns = b.Core.namespaceByPath(nsPath)
+			if ns == nil || ns.Path != nsPath {
+				return logical.ErrorResponse(fmt.Sprintf("namespace %q not found", nsPath)), logical.ErrInvalidRequest
+			}
+		} else {
+			mountPath = sanitizePath(mountPath)
+		}
+
+		snapFile, cleanup, _, err := raftStorage.WriteSnapshotToTemp(req.HTTPRequest.Body, b.Core.seal.GetAccess())
+		switch {
+		case err == nil:
+		case strings.Contains(err.Error(), "failed to open the sealed hashes"):
+			return logical.ErrorResponse("could not verify hash file, possibly the snapshot is using a different set of unseal keys or autoseal key"), logical.ErrInvalidRequest
+		default:
+			b.Core.logger.Error("raft snapshot subtree restore: failed to write snapshot", "error", err)
+			return nil, err
+		}
+		defer cleanup()
+
+		restores, warnings, err := b.Core.restoreRaftSnapshotSubtree(ctx, snapFile, mountPath, ns)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		mounts := make([]map[string]interface{}, 0, len(restores))
+		for _, restore := range restores {
+			mounts = append(mounts, map[string]interface{}{
+				"path":          restore.Path,
+				"type":          restore.Type,
+				"keys_restored": restore.KeysRestored,
+				"keys_deleted":  restore.KeysDeleted,
+			})
+		}
+
+		resp := &logical.Response{
+			Data: map[string]interface{}{
+				"mounts": mounts,
+			},
+		}
+		for _, warning := range warnings {
+			resp.AddWarning(warning)
+		}
+		return resp, nil
+	}
+}
+
 func (b *SystemBackend) handleStorageRaftAutopilotState() framework.OperationFunc {
 	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
 		raftBackend := b.Core.getRaftBackend()
@@ -425,6 +643,9 @@ func (b *SystemBackend) handleStorageRaftAutopilotState() framework.OperationFun
 				"leader":            state.Leader,
 				"voters":            state.Voters,
 				"non_voters":        state.NonVoters,
+				"read_replicas":     state.ReadReplicas,
+				"redundancy_zones":  state.RedundancyZones,
+				"upgrade_info":      state.Upgrade,
 			},
 		}, nil
 	}
@@ -450,6 +671,7 @@ func (b *SystemBackend) handleStorageRaftAutopilotConfigRead() framework.Operati
 				"max_trailing_logs":                  config.MaxTrailingLogs,
 				"min_quorum":                         config.MinQuorum,
 				"server_stabilization_time":          config.ServerStabilizationTime.String(),
+				"disable_upgrade_migration":          config.DisableUpgradeMigration,
 			},
 		}, nil
 	}
@@ -506,6 +728,11 @@ func (b *SystemBackend) handleStorageRaftAutopilotConfigUpdate() framework.Opera
 			config.ServerStabilizationTime = time.Duration(serverStabilizationTime.(int)) * time.Second
 			persist = true
 		}
+		disableUpgradeMigration, ok := d.GetOk("disable_upgrade_migration")
+		if ok {
+			config.DisableUpgradeMigration = disableUpgradeMigration.(bool)
+			persist = true
+		}
 
 		effectiveConf := raftBackend.AutopilotConfig()
 		effectiveConf.Merge(config)
@@ -645,6 +872,220 @@ func (b *SystemBackend) handleStorageRaftSnapshotWrite(force bool) framework.Ope
 	}
 }
 
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigList() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		names, err := b.Core.barrier.List(ctx, raftAutoSnapshotConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		return logical.ListResponse(names), nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		config, err := b.Core.loadRaftAutoSnapshotConfig(ctx, d.Get("name").(string))
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, nil
+		}
+
+		// The secret access key and session token are not returned
+		data := map[string]interface{}{
+			"name":         config.Name,
+			"interval":     int64(config.Interval.Seconds()),
+			"retain":       config.Retain,
+			"path_prefix":  config.PathPrefix,
+			"file_prefix":  config.FilePrefix,
+			"storage_type": config.StorageType,
+		}
+		if config.StorageType == raftAutoSnapshotStorageLocal {
+			data["local_max_space"] = config.LocalMaxSpace
+		}
+		if config.StorageType == raftAutoSnapshotStorageS3 {
+			data["aws_s3_bucket"] = config.AWSS3Bucket
+			data["aws_s3_region"] = config.AWSS3Region
+			data["aws_s3_endpoint"] = config.AWSS3Endpoint
+			data["aws_s3_force_path_style"] = config.AWSS3ForcePathStyle
+			data["aws_s3_disable_tls"] = config.AWSS3DisableTLS
+			data["aws_access_key_id"] = config.AWSAccessKeyID
+			data["aws_s3_enable_kms"] = config.AWSS3EnableKMS
+			data["aws_s3_kms_key"] = config.AWSS3KMSKey
+			data["aws_s3_server_side_encryption"] = config.AWSS3ServerSideEncryption
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigUpdate() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		name := d.Get("name").(string)
+		config, err := b.Core.loadRaftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			config = &raftAutoSnapshotConfig{
+				Name:       name,
+				Retain:     d.Get("retain").(int),
+				FilePrefix: d.Get("file_prefix").(string),
+			}
+		}
+
+		if interval, ok := d.GetOk("interval"); ok {
+			config.Interval = time.Duration(interval.(int)) * time.Second
+		}
+		if retain, ok := d.GetOk("retain"); ok {
+			config.Retain = retain.(int)
+		}
+		if filePrefix, ok := d.GetOk("file_prefix"); ok {
+			config.FilePrefix = filePrefix.(string)
+		}
+		for field, value := range map[string]*string{
+			"path_prefix":           &config.PathPrefix,
+			"storage_type":          &config.StorageType,
+			"aws_s3_bucket":         &config.AWSS3Bucket,
+			"aws_s3_region":         &config.AWSS3Region,
+			"aws_s3_endpoint":       &config.AWSS3Endpoint,
+			"aws_access_key_id":     &config.AWSAccessKeyID,
+			"aws_secret_access_key": &config.AWSSecretAccessKey,
+			"aws_session_token":     &config.AWSSessionToken,
+			"aws_s3_kms_key":        &config.AWSS3KMSKey,
+		} {
+			if v, ok := d.GetOk(field); ok {
+				*value = v.(string)
+			}
+		}
+		if forcePathStyle, ok := d.GetOk("aws_s3_force_path_style"); ok {
+			config.AWSS3ForcePathStyle = forcePathStyle.(bool)
+		}
+		if disableTLS, ok := d.GetOk("aws_s3_disable_tls"); ok {
+			config.AWSS3DisableTLS = disableTLS.(bool)
+		}
+		if enableKMS, ok := d.GetOk("aws_s3_enable_kms"); ok {
+			config.AWSS3EnableKMS = enableKMS.(bool)
+		}
+		if sse, ok := d.GetOk("aws_s3_server_side_encryption"); ok {
+			config.AWSS3ServerSideEncryption = sse.(bool)
+		}
+		if maxSpace, ok := d.GetOk("local_max_space"); ok {
+			config.LocalMaxSpace = int64(maxSpace.(int))
+		}
+
+		if err := config.validate(); err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		if config.StorageType == raftAutoSnapshotStorageLocal {
+			if _, err := raftSnapshotLocalDir(b.Core.raftSnapshotDirectory, config.PathPrefix); err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+		}
+
+		entry, err := logical.StorageEntryJSON(raftAutoSnapshotConfigPath+name, config)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Core.barrier.Put(ctx, entry); err != nil {
+			return nil, err
+		}
+
+		if b.Core.raftAutoSnapshots != nil {
+			b.Core.raftAutoSnapshots.update(config)
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoConfigDelete() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		name := d.Get("name").(string)
+		if b.Core.raftAutoSnapshots != nil {
+			b.Core.raftAutoSnapshots.remove(name)
+		}
+
+		if err := b.Core.barrier.Delete(ctx, raftAutoSnapshotConfigPath+name); err != nil {
+			return nil, err
+		}
+		if err := b.Core.barrier.Delete(ctx, raftAutoSnapshotStatusPath+name); err != nil {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+}
+
+func (b *SystemBackend) handleStorageRaftSnapshotAutoStatusRead() framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		if _, ok := b.Core.underlyingPhysical.(*raft.RaftBackend); !ok {
+			return logical.ErrorResponse("raft storage is not in use"), logical.ErrInvalidRequest
+		}
+
+		name := d.Get("name").(string)
+		config, err := b.Core.loadRaftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, nil
+		}
+
+		status, err := b.Core.loadRaftAutoSnapshotStatus(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		data := map[string]interface{}{
+			"consecutive_errors":  status.ConsecutiveErrors,
+			"last_snapshot_error": status.LastSnapshotError,
+			"last_snapshot_url":   status.LastSnapshotURL,
+			"last_success_url":    status.LastSuccessURL,
+		}
+		for field, t := range map[string]time.Time{
+			"last_snapshot_start": status.LastSnapshotStart,
+			"last_snapshot_end":   status.LastSnapshotEnd,
+			"last_success_time":   status.LastSuccessTime,
+		} {
+			if t.IsZero() {
+				data[field] = ""
+			} else {
+				data[field] = t.Format(time.RFC3339Nano)
+			}
+		}
+		data["next_snapshot_start"] = ""
+		if b.Core.raftAutoSnapshots != nil {
+			if next := b.Core.raftAutoSnapshots.nextStart(name); !next.IsZero() {
+				data["next_snapshot_start"] = next.UTC().Format(time.RFC3339Nano)
+			}
+		}
+
+		return &logical.Response{
+			Data: data,
+		}, nil
+	}
+}
+
 var sysRaftHelp = map[string][2]string{
 	"raft-bootstrap-challenge": {
 		"Creates a challenge for the new peer to be joined to the raft cluster.",
@@ -671,6 +1112,15 @@ var sysRaftHelp = map[string][2]string{
 		"Force restore a raft cluster snapshot",
 		"",
 	},
+	"raft-snapshot-restore-subtree": {
+		"Restores the keys of a mount, or of the mounts of a namespace, from a raft cluster snapshot.",
+		`
+The body is the snapshot, and the mount or namespace to restore is given with
+the "mount" or "namespace" query parameter. The mounts must exist both in the
+snapshot and currently. Their keys are replaced with the keys of the snapshot,
+and they are reloaded. Leases and tokens are not restored.
+		`,
+	},
 	"raft-autopilot-state": {
 		"Returns the state of the raft cluster under integrated storage as seen by autopilot.",
 		"",
@@ -679,4 +1129,20 @@ var sysRaftHelp = map[string][2]string{
 		"Returns autopilot configuration.",
 		"",
 	},
+	"raft-snapshot-auto-config-list": {
+		"Lists the configurations of scheduled raft snapshots.",
+		"",
+	},
+	"raft-snapshot-auto-config": {
+		"Configures scheduled raft snapshots.",
+		`
+The active node takes a snapshot of the raft cluster every interval and saves
+it to a directory of the active node or to an S3 bucket, keeping the number of
+snapshots set by retain.
+		`,
+	},
+	"raft-snapshot-auto-status": {
+		"Returns the status of scheduled raft snapshots.",
+		"",
+	},
 }
@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// storageMigrationPaths returns the paths used to migrate the storage of a
+// running server to another backend.
+func (b *SystemBackend) storageMigrationPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "storage/migration$",
+
+			Fields: map[string]*framework.FieldSchema{
+				"type": {
+					Type:        framework.TypeString,
+					Description: "The type of the destination storage backend.",
+				},
+				"config": {
+					Type:        framework.TypeKVPairs,
+					Description: "The configuration of the destination storage backend.",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageMigrationRead,
+					Summary:  "Returns the progress of the online storage migration.",
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageMigrationStart,
+					Summary:  "Starts migrating the storage to another backend.",
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.handleStorageMigrationCancel,
+					Summary:  "Cancels the online storage migration in progress.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysStorageMigrationHelp["storage-migration"][0]),
+			HelpDescription: strings.TrimSpace(sysStorageMigrationHelp["storage-migration"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleStorageMigrationRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	status := b.Core.currentStorageMigrationStatus()
+	if status == nil {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"status": StorageMigrationStatusIdle,
+			},
+		}, nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"status":           status.Status,
+			"destination_type": status.DestinationType,
+			"start_time":       status.StartTime.Format(time.RFC3339Nano),
+			"keys_copied":      status.KeysCopied,
+		},
+	}
+	if !status.CompleteTime.IsZero() {
+		resp.Data["complete_time"] = status.CompleteTime.Format(time.RFC3339Nano)
+	}
+	if status.Error != "" {
+		resp.Data["error"] = status.Error
+	}
+	return resp, nil
+}
+
+func (b *SystemBackend) handleStorageMigrationStart(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	backendType := d.Get("type").(string)
+	if backendType == "" {
+		return logical.ErrorResponse("missing destination storage type"), logical.ErrInvalidRequest
+	}
+	conf := d.Get("config").(map[string]string)
+
+	if err := b.Core.startStorageMigration(ctx, backendType, conf); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	return nil, nil
+}
+
+func (b *SystemBackend) handleStorageMigrationCancel(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	b.Core.cancelStorageMigration()
+	return nil, nil
+}
+
+var sysStorageMigrationHelp = map[string][2]string{
+	"storage-migration": {
+		"Migrates the storage of the running server to another backend.",
+		`
+		Starting a migration makes the active node write to both the current and
+		the destination storage backends, while copying the existing entries to
+		the destination in the background. Once everything is copied, the node
+		switches to the destination storage and marks the old storage as
+		migrated, which prevents servers from starting with it. The storage
+		configuration of all the nodes must then be updated to the destination.
+
+		Reading this path returns the progress of the migration, and deleting it
+		cancels the migration in progress, leaving the current storage in use.
+		`,
+	},
+}
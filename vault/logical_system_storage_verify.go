@@ -0,0 +1,99 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// storageVerifyPaths returns the paths used to verify the integrity of the
+// storage entries.
+func (b *SystemBackend) storageVerifyPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "storage/verify$",
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleStorageVerify,
+					Summary:  "Starts verifying that every storage entry can be decrypted.",
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleStorageVerifyStatus,
+					Summary:  "Returns the progress of the storage verification.",
+				},
+			},
+
+			HelpSynopsis:    strings.TrimSpace(sysStorageVerifyHelp["storage-verify"][0]),
+			HelpDescription: strings.TrimSpace(sysStorageVerifyHelp["storage-verify"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleStorageVerify(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	status, started := b.Core.startStorageVerify()
+	resp := storageVerifyResponse(status)
+	if !started {
+		resp.AddWarning("Storage verification is already in progress, returning its status.")
+	}
+	return resp, nil
+}
+
+func (b *SystemBackend) handleStorageVerifyStatus(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	status := b.Core.currentStorageVerifyStatus()
+	if status == nil {
+		return nil, nil
+	}
+	return storageVerifyResponse(status), nil
+}
+
+func storageVerifyResponse(status *storageVerifyStatus) *logical.Response {
+	problems := make([]map[string]interface{}, 0, len(status.Report.Problems))
+	for _, problem := range status.Report.Problems {
+		p := map[string]interface{}{
+			"path":    problem.Path,
+			"problem": problem.Problem,
+		}
+		if problem.Error != "" {
+			p["error"] = problem.Error
+		}
+		problems = append(problems, p)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"status":          status.Status(),
+			"start_time":      status.StartTime.Format(time.RFC3339Nano),
+			"entries_checked": status.Report.EntriesChecked,
+			"entries_skipped": status.Report.EntriesSkipped,
+			"problems":        problems,
+		},
+	}
+	if status.Complete() {
+		resp.Data["complete_time"] = status.CompleteTime.Format(time.RFC3339Nano)
+	}
+	if status.Error != "" {
+		resp.Data["error"] = status.Error
+	}
+	return resp
+}
+
+var sysStorageVerifyHelp = map[string][2]string{
+	"storage-verify": {
+		"Verifies the integrity of the storage entries.",
+		`
+		Writing to this endpoint starts walking every entry of the storage backend
+		in the background, and attempts to decrypt it with the keyring. Reading it
+		returns the progress and the problems found so far. Entries too short to
+		hold an encrypted value are reported as truncated, and entries that fail
+		to decrypt as undecryptable. Entries of secrets engines, auth methods and
+		audit devices that are not in the mount tables anymore are reported as
+		orphaned. Entries stored outside of the barrier, like the seal
+		configuration, are skipped. The verification stops if the active node is
+		sealed or steps down.
+		`,
+	},
+}
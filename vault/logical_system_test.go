@@ -17,6 +17,7 @@ import (
 
 	"github.com/fatih/structs"
 	"github.com/go-test/deep"
+	"github.com/hashicorp/errwrap"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/builtinplugins"
@@ -38,6 +39,7 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"remount",
 		"audit",
 		"audit/*",
+		"audit-chain/*",
 		"raw",
 		"raw/*",
 		"replication/primary/secondary-token",
@@ -47,6 +49,7 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"replication/dr/reindex",
 		"replication/performance/reindex",
 		"rotate",
+		"rotate/prune",
 		"config/cors",
 		"config/auditing/*",
 		"config/ui/headers/*",
@@ -57,6 +60,8 @@ func TestSystemBackend_RootPaths(t *testing.T) {
 		"leases/revoke-force/*",
 		"leases/lookup/*",
 		"storage/raft/snapshot-auto/config/*",
+		"storage/migration",
+		"storage/verify",
 	}
 
 	b := testSystemBackend(t)
@@ -668,6 +673,116 @@ func TestSystemBackend_CapabilitiesAccessor_BC(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_PolicyExplain(t *testing.T) {
+	core, b, rootToken := testCoreSystemBackend(t)
+
+	for name, raw := range map[string]string{
+		"writer": `
+path "secret/*" {
+	capabilities = ["read", "update"]
+}
+path "secret/locked" {
+	capabilities = ["deny"]
+}`,
+		"reader": `
+path "secret/*" {
+	capabilities = ["read", "list"]
+}
+path "secret/app/*" {
+	capabilities = ["read"]
+}`,
+	} {
+		policy, err := ParseACLPolicy(namespace.RootNamespace, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy.Name = name
+		if err := core.policyStore.SetPolicy(namespace.RootContext(nil), policy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	testMakeServiceTokenViaBackend(t, core.tokenStore, rootToken, "tokenid", "", []string{"writer", "reader"})
+
+	explain := func(data map[string]interface{}) map[string]interface{} {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, "policy-explain")
+		req.Data = data
+		req.Data["token"] = "tokenid"
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+		}
+		return resp.Data
+	}
+
+	// Both policies contribute to the most specific rule
+	data := explain(map[string]interface{}{"path": "secret/foo", "operation": "update"})
+	if !data["allowed"].(bool) || data["rule"] != "secret/*" {
+		t.Fatalf("bad: %#v", data)
+	}
+	expectedGranted := map[string][]string{
+		"read":   {"reader", "writer"},
+		"update": {"writer"},
+		"list":   {"reader"},
+	}
+	if diff := deep.Equal(data["granted_by"], expectedGranted); diff != nil {
+		t.Fatal(diff)
+	}
+	if data["reason"] != `the "update" capability is granted on path rule "secret/*" by policy "writer"` {
+		t.Fatalf("bad: %q", data["reason"])
+	}
+
+	// The more specific rule of reader shadows the rule of writer
+	data = explain(map[string]interface{}{"path": "secret/app/config", "operation": "update"})
+	if data["allowed"].(bool) || data["rule"] != "secret/app/*" {
+		t.Fatalf("bad: %#v", data)
+	}
+	if data["reason"] != `no policy grants the "update" capability on path rule "secret/app/*"` {
+		t.Fatalf("bad: %q", data["reason"])
+	}
+	expectedPolicies := []map[string]interface{}{
+		{"name": "default", "namespace": "", "rule": "", "capabilities": []string(nil), "effective": false},
+		{"name": "reader", "namespace": "", "rule": "secret/app/*", "capabilities": []string{"read"}, "effective": true},
+		{"name": "writer", "namespace": "", "rule": "secret/*", "capabilities": []string{"read", "update"}, "effective": false},
+	}
+	if diff := deep.Equal(data["policies"], expectedPolicies); diff != nil {
+		t.Fatal(diff)
+	}
+
+	// Explicit deny
+	data = explain(map[string]interface{}{"path": "secret/locked"})
+	if data["allowed"].(bool) || data["reason"] != `path rule "secret/locked" is explicitly denied by policy "writer"` {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// No matching rule
+	data = explain(map[string]interface{}{"path": "transit/keys/foo"})
+	if data["allowed"].(bool) || data["reason"] != `no policy contains a path rule matching "transit/keys/foo"` {
+		t.Fatalf("bad: %#v", data)
+	}
+
+	// Root tokens are always allowed
+	req := logical.TestRequest(t, logical.UpdateOperation, "policy-explain")
+	req.ClientToken = rootToken
+	req.Data["path"] = "transit/keys/foo"
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	if !resp.Data["allowed"].(bool) || !reflect.DeepEqual(resp.Data["capabilities"], []string{"root"}) {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Unsupported operations are rejected
+	req = logical.TestRequest(t, logical.UpdateOperation, "policy-explain")
+	req.Data["path"] = "secret/foo"
+	req.Data["operation"] = "sudo"
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: resp: %#v\nerr: %v", resp, err)
+	}
+}
+
 func TestSystemBackend_remount(t *testing.T) {
 	b := testSystemBackend(t)
 
@@ -1681,6 +1796,220 @@ func TestSystemBackend_policyList(t *testing.T) {
 	}
 }
 
+func TestSystemBackend_policyVersions(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	b := c.systemBackend
+
+	write := func(rules, accessor string) {
+		t.Helper()
+		req := logical.TestRequest(t, logical.UpdateOperation, "policies/acl/foo")
+		req.Data["policy"] = rules
+		req.ClientTokenAccessor = accessor
+		resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err: %v %#v", err, resp)
+		}
+	}
+
+	v1 := "path \"foo/\" {\n  capabilities = [\"read\"]\n}\n"
+	v2 := "path \"foo/\" {\n  capabilities = [\"read\", \"update\"]\n}\n"
+	v3 := "path \"foo/\" {\n  capabilities = [\"deny\"]\n}\n"
+	write(v1, "accessor1")
+	write(v2, "accessor2")
+	write(v3, "accessor3")
+
+	// Writing the same contents again does not add a version
+	write(v3, "accessor3")
+
+	// List the versions
+	req := logical.TestRequest(t, logical.ListOperation, "policies/acl/foo/versions")
+	resp, err := b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	if !reflect.DeepEqual(resp.Data["keys"], []string{"1", "2", "3"}) || resp.Data["current_version"] != 3 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	info := resp.Data["key_info"].(map[string]interface{})["2"].(map[string]interface{})
+	if info["author_accessor"] != "accessor2" || info["created_time"].(time.Time).IsZero() {
+		t.Fatalf("bad: %#v", info)
+	}
+
+	// Read a previous version
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/acl/foo/versions/1")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	if resp.Data["policy"] != v1 || resp.Data["version"] != 1 || resp.Data["author_accessor"] != "accessor1" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Diff the current version against the previous one
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/acl/foo/diff")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	expDiff := `--- foo (version 2)
++++ foo (version 3)
+@@ -1,3 +1,3 @@
+ path "foo/" {
+-  capabilities = ["read", "update"]
++  capabilities = ["deny"]
+ }
+`
+	if resp.Data["from_version"] != 2 || resp.Data["to_version"] != 3 || resp.Data["diff"] != expDiff {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// Roll back to the first version
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/acl/foo/rollback")
+	req.Data["version"] = 1
+	req.ClientToken = root
+	req.ClientTokenAccessor = "accessor4"
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/acl/foo")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.Data["policy"] != v1 {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/acl/foo/versions/4")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.Data["policy"] != v1 || resp.Data["author_accessor"] != "accessor4" {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	// Rolling back to the current contents does not add a version
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/acl/foo/rollback")
+	req.Data["version"] = 4
+	req.ClientToken = root
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	req = logical.TestRequest(t, logical.ReadOperation, "policies/acl/foo/versions/5")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	// Rolling back to an unknown version fails
+	req = logical.TestRequest(t, logical.UpdateOperation, "policies/acl/foo/rollback")
+	req.Data["version"] = 10
+	req.ClientToken = root
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error: %v %#v", err, resp)
+	}
+
+	// The history is bounded
+	for i := 0; i < policyHistoryMaxVersions; i++ {
+		write(fmt.Sprintf("path \"foo/%d\" {\n  capabilities = [\"read\"]\n}\n", i), "")
+	}
+	req = logical.TestRequest(t, logical.ListOperation, "policies/acl/foo/versions")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp == nil || resp.IsError() {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	keys := resp.Data["keys"].([]string)
+	if len(keys) != policyHistoryMaxVersions || keys[0] != "5" || resp.Data["current_version"] != 24 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	// The history is deleted along with the policy
+	req = logical.TestRequest(t, logical.DeleteOperation, "policies/acl/foo")
+	if _, err := b.HandleRequest(namespace.RootContext(nil), req); err != nil {
+		t.Fatal(err)
+	}
+	req = logical.TestRequest(t, logical.ListOperation, "policies/acl/foo/versions")
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || resp != nil {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+
+	// Policies cannot be named like the history endpoints, which would shadow
+	// them
+	for _, name := range []string{"bar/versions", "bar/versions/1", "bar/diff", "bar/rollback"} {
+		req = logical.TestRequest(t, logical.UpdateOperation, "policy/"+name)
+		req.Data["policy"] = v1
+		resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected error: %v %#v", name, err, resp)
+		}
+	}
+	req = logical.TestRequest(t, logical.UpdateOperation, "policy/bar/version")
+	req.Data["policy"] = v1
+	resp, err = b.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+}
+
+func TestSystemBackend_policyRollbackACL(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	request := func(token string, op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		t.Helper()
+		req := logical.TestRequest(t, op, path)
+		req.ClientToken = token
+		req.Data = data
+		return c.HandleRequest(ctx, req)
+	}
+
+	v1 := "path \"foo/\" {\n  capabilities = [\"read\"]\n}\n"
+	v2 := "path \"foo/\" {\n  capabilities = [\"deny\"]\n}\n"
+	for _, rules := range []string{v1, v2} {
+		if resp, err := request(root, logical.UpdateOperation, "sys/policies/acl/foo", map[string]interface{}{"policy": rules}); err != nil || resp.IsError() {
+			t.Fatalf("err: %v %#v", err, resp)
+		}
+	}
+
+	// Only the tokens allowed to update the policy can roll it back
+	for name, rules := range map[string]string{
+		"rollback-only": `path "sys/policies/acl/foo/rollback" { capabilities = ["update"] }`,
+		"denied": `
+path "sys/policies/acl/*" { capabilities = ["update"] }
+path "sys/policies/acl/foo" { capabilities = ["deny"] }`,
+		"allowed": `
+path "sys/policies/acl/foo" { capabilities = ["update"] }
+path "sys/policies/acl/foo/rollback" { capabilities = ["update"] }`,
+	} {
+		if resp, err := request(root, logical.UpdateOperation, "sys/policies/acl/"+name, map[string]interface{}{"policy": rules}); err != nil || resp.IsError() {
+			t.Fatalf("err: %v %#v", err, resp)
+		}
+	}
+	token := func(policy string) string {
+		t.Helper()
+		resp, err := request(root, logical.UpdateOperation, "auth/token/create", map[string]interface{}{"policies": []string{policy}})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("err: %v %#v", err, resp)
+		}
+		return resp.Auth.ClientToken
+	}
+
+	for _, policy := range []string{"rollback-only", "denied"} {
+		resp, err := request(token(policy), logical.UpdateOperation, "sys/policies/acl/foo/rollback", map[string]interface{}{"version": 1})
+		if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+			t.Fatalf("%s: expected permission denied, got: %v %#v", policy, err, resp)
+		}
+	}
+	resp, err := request(token("allowed"), logical.UpdateOperation, "sys/policies/acl/foo/rollback", map[string]interface{}{"version": 1})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+	resp, err = request(root, logical.ReadOperation, "sys/policies/acl/foo", nil)
+	if err != nil || resp == nil || resp.Data["policy"] != v1 {
+		t.Fatalf("err: %v %#v", err, resp)
+	}
+}
+
 func TestSystemBackend_policyCRUD(t *testing.T) {
 	b := testSystemBackend(t)
 
@@ -2039,10 +2368,12 @@ func TestSystemBackend_keyStatus(t *testing.T) {
 	}
 
 	exp := map[string]interface{}{
-		"term": 1,
+		"term":          1,
+		"keyring_terms": []uint32{1},
 	}
 	delete(resp.Data, "install_time")
 	delete(resp.Data, "encryptions")
+	delete(resp.Data, "rewrap")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -2110,10 +2441,12 @@ func TestSystemBackend_rotate(t *testing.T) {
 	}
 
 	exp := map[string]interface{}{
-		"term": 2,
+		"term":          2,
+		"keyring_terms": []uint32{1, 2},
 	}
 	delete(resp.Data, "install_time")
 	delete(resp.Data, "encryptions")
+	delete(resp.Data, "rewrap")
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("got: %#v expect: %#v", resp.Data, exp)
 	}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/armon/go-metrics"
 	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/builtin/plugin"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
@@ -299,6 +300,7 @@ type MountConfig struct {
 	ForceNoCache              bool                  `json:"force_no_cache,omitempty" structs:"force_no_cache" mapstructure:"force_no_cache"`          // Override for global default
 	AuditNonHMACRequestKeys   []string              `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
 	AuditNonHMACResponseKeys  []string              `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	AuditFieldSelectors       []string              `json:"audit_field_selectors,omitempty" structs:"audit_field_selectors" mapstructure:"audit_field_selectors"`
 	ListingVisibility         ListingVisibilityType `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
 	PassthroughRequestHeaders []string              `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
 	AllowedResponseHeaders    []string              `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
@@ -317,6 +319,7 @@ type APIMountConfig struct {
 	ForceNoCache              bool                  `json:"force_no_cache" structs:"force_no_cache" mapstructure:"force_no_cache"`
 	AuditNonHMACRequestKeys   []string              `json:"audit_non_hmac_request_keys,omitempty" structs:"audit_non_hmac_request_keys" mapstructure:"audit_non_hmac_request_keys"`
 	AuditNonHMACResponseKeys  []string              `json:"audit_non_hmac_response_keys,omitempty" structs:"audit_non_hmac_response_keys" mapstructure:"audit_non_hmac_response_keys"`
+	AuditFieldSelectors       []string              `json:"audit_field_selectors,omitempty" structs:"audit_field_selectors" mapstructure:"audit_field_selectors"`
 	ListingVisibility         ListingVisibilityType `json:"listing_visibility,omitempty" structs:"listing_visibility" mapstructure:"listing_visibility"`
 	PassthroughRequestHeaders []string              `json:"passthrough_request_headers,omitempty" structs:"passthrough_request_headers" mapstructure:"passthrough_request_headers"`
 	AllowedResponseHeaders    []string              `json:"allowed_response_headers,omitempty" structs:"allowed_response_headers" mapstructure:"allowed_response_headers"`
@@ -367,6 +370,21 @@ func (e *MountEntry) SyncCache() {
 		e.synthesizedConfigCache.Store("audit_non_hmac_response_keys", e.Config.AuditNonHMACResponseKeys)
 	}
 
+	if len(e.Config.AuditFieldSelectors) == 0 {
+		e.synthesizedConfigCache.Delete("audit_field_selectors")
+		e.synthesizedConfigCache.Delete("audit_field_selectors_parsed")
+	} else {
+		e.synthesizedConfigCache.Store("audit_field_selectors", e.Config.AuditFieldSelectors)
+
+		// The selectors are validated when the mount is tuned, and are parsed
+		// once here rather than for every audit entry
+		if selectors, err := audit.ParseFieldSelectors(e.Config.AuditFieldSelectors); err == nil {
+			e.synthesizedConfigCache.Store("audit_field_selectors_parsed", selectors)
+		} else {
+			e.synthesizedConfigCache.Delete("audit_field_selectors_parsed")
+		}
+	}
+
 	if len(e.Config.PassthroughRequestHeaders) == 0 {
 		e.synthesizedConfigCache.Delete("passthrough_request_headers")
 	} else {
@@ -449,6 +467,7 @@ func (c *Core) mount(ctx context.Context, entry *MountEntry) error {
 		return err
 	}
 
+	c.publishMountEvent(EventMountEnabled, entry)
 	return nil
 }
 
@@ -619,6 +638,8 @@ func (c *Core) unmount(ctx context.Context, path string) error {
 		}
 	}
 
+	entry := c.router.MatchingMountEntry(ctx, path)
+
 	// Unmount mount internally
 	if err := c.unmountInternal(ctx, path, MountTableUpdateStorage); err != nil {
 		return err
@@ -629,6 +650,10 @@ func (c *Core) unmount(ctx context.Context, path string) error {
 		// Even we failed to evaluate filtered paths, the unmount operation was still successful
 		c.logger.Error("failed to evaluate filtered paths", "error", err)
 	}
+
+	if entry != nil {
+		c.publishMountEvent(EventMountDisabled, entry)
+	}
 	return nil
 }
 
@@ -767,6 +792,7 @@ func (c *Core) removeMountEntry(ctx context.Context, path string, updateStorage
 	}
 
 	c.mounts = newTable
+	c.metricSink.RemoveMountAccessorLabel(entry.Accessor)
 	return nil
 }
 
@@ -1349,6 +1375,7 @@ func (c *Core) unloadMounts(ctx context.Context) error {
 	c.mounts = nil
 	c.router.reset()
 	c.systemBarrierView = nil
+	c.metricSink.ResetMountAccessorLabels()
 	return nil
 }
 
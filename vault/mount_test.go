@@ -952,3 +952,28 @@ func TestCore_MountInitialize(t *testing.T) {
 		}
 	}
 }
+
+func TestMountEntry_SyncCache_AuditFieldSelectors(t *testing.T) {
+	entry := &MountEntry{
+		Config: MountConfig{
+			AuditFieldSelectors: []string{"/request/data/user=plaintext"},
+		},
+	}
+	entry.SyncCache()
+
+	// The selectors are parsed once, for every audit entry of the mount
+	raw, ok := entry.synthesizedConfigCache.Load("audit_field_selectors_parsed")
+	if !ok {
+		t.Fatal("expected parsed selectors")
+	}
+	selectors := raw.([]*audit.FieldSelector)
+	if len(selectors) != 1 || selectors[0].Pointer != "/request/data/user" || selectors[0].Action != audit.FieldActionPlaintext {
+		t.Fatalf("bad: %#v", selectors)
+	}
+
+	entry.Config.AuditFieldSelectors = nil
+	entry.SyncCache()
+	if _, ok := entry.synthesizedConfigCache.Load("audit_field_selectors_parsed"); ok {
+		t.Fatal("expected the parsed selectors to be removed")
+	}
+}
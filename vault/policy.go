@@ -119,13 +119,14 @@ type PathRules struct {
 
 	// These keys are used at the top level to make the HCL nicer; we store in
 	// the ACLPermissions object though
-	MinWrappingTTLHCL     interface{}              `hcl:"min_wrapping_ttl"`
-	MaxWrappingTTLHCL     interface{}              `hcl:"max_wrapping_ttl"`
-	AllowedParametersHCL  map[string][]interface{} `hcl:"allowed_parameters"`
-	DeniedParametersHCL   map[string][]interface{} `hcl:"denied_parameters"`
-	RequiredParametersHCL []string                 `hcl:"required_parameters"`
-	MFAMethodsHCL         []string                 `hcl:"mfa_methods"`
-	ControlGroupHCL       *ControlGroupHCL         `hcl:"control_group"`
+	MinWrappingTTLHCL     interface{}                    `hcl:"min_wrapping_ttl"`
+	MaxWrappingTTLHCL     interface{}                    `hcl:"max_wrapping_ttl"`
+	AllowedParametersHCL  map[string][]interface{}       `hcl:"allowed_parameters"`
+	DeniedParametersHCL   map[string][]interface{}       `hcl:"denied_parameters"`
+	RequiredParametersHCL []string                       `hcl:"required_parameters"`
+	MFAMethodsHCL         []string                       `hcl:"mfa_methods"`
+	ControlGroupHCL       *ControlGroupHCL               `hcl:"control_group"`
+	ConditionsHCL         map[string]*PolicyConditionHCL `hcl:"condition"`
 }
 
 type ControlGroupHCL struct {
@@ -158,6 +159,14 @@ type ACLPermissions struct {
 	RequiredParameters []string
 	MFAMethods         []string
 	ControlGroup       *ControlGroup
+
+	// Conditions are the conditions of a parsed path rule. Once the rules are
+	// merged into an ACL, they are held by ConditionalGrants instead, and
+	// UnconditionalCapabilitiesBitmap holds the capabilities of the rules
+	// without conditions.
+	Conditions                      []*PolicyCondition
+	ConditionalGrants               []*ConditionalGrant
+	UnconditionalCapabilitiesBitmap uint32
 }
 
 func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
@@ -214,6 +223,15 @@ func (p *ACLPermissions) Clone() (*ACLPermissions, error) {
 		ret.ControlGroup = clonedControlGroup.(*ControlGroup)
 	}
 
+	// Conditions are immutable once parsed, so they can be shared
+	if len(p.Conditions) > 0 {
+		ret.Conditions = append([]*PolicyCondition(nil), p.Conditions...)
+	}
+	if len(p.ConditionalGrants) > 0 {
+		ret.ConditionalGrants = append([]*ConditionalGrant(nil), p.ConditionalGrants...)
+	}
+	ret.UnconditionalCapabilitiesBitmap = p.UnconditionalCapabilitiesBitmap
+
 	return ret, nil
 }
 
@@ -315,6 +333,7 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 			"max_wrapping_ttl",
 			"mfa_methods",
 			"control_group",
+			"condition",
 		}
 		if err := hclutil.CheckHCLKeys(item.Val, valid); err != nil {
 			return multierror.Prefix(err, fmt.Sprintf("path %q:", key))
@@ -465,6 +484,13 @@ func parsePaths(result *Policy, list *ast.ObjectList, performTemplating bool, en
 		if len(pc.RequiredParametersHCL) > 0 {
 			pc.Permissions.RequiredParameters = pc.RequiredParametersHCL[:]
 		}
+		if len(pc.ConditionsHCL) > 0 {
+			conditions, err := parsePolicyConditions(key, pc.ConditionsHCL)
+			if err != nil {
+				return fmt.Errorf("path %q: %w", key, err)
+			}
+			pc.Permissions.Conditions = conditions
+		}
 
 	PathFinished:
 		paths = append(paths, &pc)
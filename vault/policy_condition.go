@@ -0,0 +1,767 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// PolicyConditionHCL is the HCL representation of a condition block within a
+// path rule.
+type PolicyConditionHCL struct {
+	Expression string `hcl:"expression"`
+}
+
+// PolicyCondition is a named boolean expression attached to a path rule. The
+// capabilities granted by the rule are only usable when every condition
+// evaluates to true for the request being authorized.
+type PolicyCondition struct {
+	Name       string
+	Expression string
+
+	// Policy and Path identify where the condition was defined so that
+	// denials can be attributed. Policy is filled in when building the ACL
+	// since the policy name is not known at parse time.
+	Policy string
+	Path   string
+
+	expr conditionNode
+}
+
+// errPolicyConditionNotSatisfied is the error returned to clients whose
+// request was denied by a policy condition
+var errPolicyConditionNotSatisfied = errors.New("policy condition not satisfied")
+
+// ConditionalGrant holds the capabilities granted by a path rule with
+// conditions. They are only usable when all of the conditions hold.
+type ConditionalGrant struct {
+	CapabilitiesBitmap uint32
+	Conditions         []*PolicyCondition
+}
+
+// evaluate returns the denial error of the first condition of the grant that
+// does not hold for the given input, or nil if they all hold.
+func (g *ConditionalGrant) evaluate(in *conditionInput) error {
+	for _, condition := range g.Conditions {
+		ok, err := condition.evaluate(in)
+		if err != nil || !ok {
+			return condition.denialError(err)
+		}
+	}
+	return nil
+}
+
+// conditionInput holds everything an expression can reference about the
+// request being authorized.
+type conditionInput struct {
+	now    time.Time
+	req    *logical.Request
+	entity *identity.Entity
+	groups []*identity.Group
+
+	// values, if set, holds the value of every selector and replaces the
+	// fields above. It is used by expressions evaluated outside of policies,
+	// such as audit device filters.
+	values map[string]interface{}
+}
+
+// conditionSelectors are the fully qualified selectors that can be used in an
+// expression. conditionSelectorPrefixes are selectors that are followed by an
+// arbitrary map key.
+var (
+	conditionSelectors = map[string]struct{}{
+		"time.hour":             {},
+		"time.minute":           {},
+		"time.weekday":          {},
+		"time.unix":             {},
+		"request.operation":     {},
+		"request.path":          {},
+		"request.remote_addr":   {},
+		"identity.entity.id":    {},
+		"identity.entity.name":  {},
+		"identity.groups.ids":   {},
+		"identity.groups.names": {},
+		"token.display_name":    {},
+		"token.policies":        {},
+		"token.mfa_validated":   {},
+	}
+
+	conditionSelectorPrefixes = []string{
+		"identity.entity.metadata.",
+		"token.metadata.",
+	}
+)
+
+// parsePolicyConditions compiles the condition blocks of a path rule. They
+// are sorted by name so that evaluation, and therefore the reported denial
+// reason, is deterministic.
+func parsePolicyConditions(path string, raw map[string]*PolicyConditionHCL) ([]*PolicyCondition, error) {
+	names := make([]string, 0, len(raw))
+	for name := range raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	conditions := make([]*PolicyCondition, 0, len(names))
+	for _, name := range names {
+		c := raw[name]
+		if c == nil || strings.TrimSpace(c.Expression) == "" {
+			return nil, fmt.Errorf("condition %q: missing expression", name)
+		}
+
+		expr, err := parseConditionExpression(c.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: %w", name, err)
+		}
+
+		conditions = append(conditions, &PolicyCondition{
+			Name:       name,
+			Expression: c.Expression,
+			Path:       path,
+			expr:       expr,
+		})
+	}
+
+	return conditions, nil
+}
+
+// evaluate returns whether the condition holds for the given input. An error
+// is returned if the expression could not be evaluated, e.g. because of
+// mismatched types; callers must treat it as the condition not holding.
+func (c *PolicyCondition) evaluate(in *conditionInput) (bool, error) {
+	v, err := c.expr.eval(in)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %v rather than a boolean", v)
+	}
+	return b, nil
+}
+
+// denialError returns the error reported to the client when the condition
+// prevents the operation.
+func (c *PolicyCondition) denialError(evalErr error) error {
+	where := fmt.Sprintf("condition %q on path %q", c.Name, c.Path)
+	if c.Policy != "" {
+		where = fmt.Sprintf("condition %q of policy %q on path %q", c.Name, c.Policy, c.Path)
+	}
+	if evalErr != nil {
+		return fmt.Errorf("%s could not be evaluated: %v", where, evalErr)
+	}
+	return fmt.Errorf("%s was not satisfied", where)
+}
+
+func (in *conditionInput) lookup(selector string) interface{} {
+	if in.values != nil {
+		return in.values[selector]
+	}
+
+	switch selector {
+	case "time.hour":
+		return float64(in.now.UTC().Hour())
+	case "time.minute":
+		return float64(in.now.UTC().Minute())
+	case "time.weekday":
+		return strings.ToLower(in.now.UTC().Weekday().String())
+	case "time.unix":
+		return float64(in.now.Unix())
+	}
+
+	if in.req != nil {
+		switch selector {
+		case "request.operation":
+			return string(in.req.Operation)
+		case "request.path":
+			return in.req.Path
+		case "request.remote_addr":
+			if in.req.Connection == nil {
+				return nil
+			}
+			return in.req.Connection.RemoteAddr
+		}
+
+		if te := in.req.TokenEntry(); te != nil {
+			switch {
+			case selector == "token.display_name":
+				return te.DisplayName
+			case selector == "token.policies":
+				return te.Policies
+			case selector == "token.mfa_validated":
+				return te.MFAValidated
+			case strings.HasPrefix(selector, "token.metadata."):
+				if v, ok := te.Meta[strings.TrimPrefix(selector, "token.metadata.")]; ok {
+					return v
+				}
+				return nil
+			}
+		}
+	}
+
+	if in.entity != nil {
+		switch {
+		case selector == "identity.entity.id":
+			return in.entity.ID
+		case selector == "identity.entity.name":
+			return in.entity.Name
+		case strings.HasPrefix(selector, "identity.entity.metadata."):
+			if v, ok := in.entity.Metadata[strings.TrimPrefix(selector, "identity.entity.metadata.")]; ok {
+				return v
+			}
+			return nil
+		case selector == "identity.groups.ids":
+			ids := make([]string, 0, len(in.groups))
+			for _, g := range in.groups {
+				ids = append(ids, g.ID)
+			}
+			return ids
+		case selector == "identity.groups.names":
+			names := make([]string, 0, len(in.groups))
+			for _, g := range in.groups {
+				names = append(names, g.Name)
+			}
+			return names
+		}
+	}
+
+	return nil
+}
+
+// conditionNode is a node of a compiled condition expression. Values are one
+// of nil, bool, float64, string or a slice of those.
+type conditionNode interface {
+	eval(in *conditionInput) (interface{}, error)
+}
+
+type conditionLiteral struct {
+	value interface{}
+}
+
+func (n *conditionLiteral) eval(*conditionInput) (interface{}, error) {
+	return n.value, nil
+}
+
+type conditionSelector struct {
+	selector string
+}
+
+func (n *conditionSelector) eval(in *conditionInput) (interface{}, error) {
+	return in.lookup(n.selector), nil
+}
+
+type conditionList struct {
+	elems []conditionNode
+}
+
+func (n *conditionList) eval(in *conditionInput) (interface{}, error) {
+	ret := make([]interface{}, 0, len(n.elems))
+	for _, e := range n.elems {
+		v, err := e.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+type conditionNot struct {
+	operand conditionNode
+}
+
+func (n *conditionNot) eval(in *conditionInput) (interface{}, error) {
+	v, err := n.operand.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operand of '!' is not a boolean: %v", v)
+	}
+	return !b, nil
+}
+
+type conditionLogical struct {
+	and         bool
+	left, right conditionNode
+}
+
+func (n *conditionLogical) eval(in *conditionInput) (interface{}, error) {
+	op := "||"
+	if n.and {
+		op = "&&"
+	}
+
+	l, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	lb, ok := l.(bool)
+	if !ok {
+		return nil, fmt.Errorf("left operand of %q is not a boolean: %v", op, l)
+	}
+
+	// Short-circuit
+	if lb != n.and {
+		return lb, nil
+	}
+
+	r, err := n.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	rb, ok := r.(bool)
+	if !ok {
+		return nil, fmt.Errorf("right operand of %q is not a boolean: %v", op, r)
+	}
+	return rb, nil
+}
+
+type conditionCompare struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n *conditionCompare) eval(in *conditionInput) (interface{}, error) {
+	l, err := n.left.eval(in)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return conditionValuesEqual(l, r), nil
+	case "!=":
+		return !conditionValuesEqual(l, r), nil
+	case "in":
+		list := reflect.ValueOf(r)
+		if r == nil {
+			return false, nil
+		}
+		if list.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("right operand of 'in' is not a list: %v", r)
+		}
+		for i := 0; i < list.Len(); i++ {
+			if conditionValuesEqual(l, list.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	lf, lok := conditionNumber(l)
+	rf, rok := conditionNumber(r)
+	if !lok || !rok {
+		return nil, fmt.Errorf("operands of %q must be numbers, got %v and %v", n.op, l, r)
+	}
+	switch n.op {
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	}
+
+	return nil, fmt.Errorf("unknown operator %q", n.op)
+}
+
+// conditionNumber converts v to a number. Numeric strings, such as metadata
+// values, are accepted so they can be compared to number literals.
+func conditionNumber(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func conditionValuesEqual(l, r interface{}) bool {
+	_, lnum := l.(float64)
+	_, rnum := r.(float64)
+	if lnum || rnum {
+		lf, lok := conditionNumber(l)
+		rf, rok := conditionNumber(r)
+		return lok && rok && lf == rf
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+type conditionCall struct {
+	name string
+	args []conditionNode
+}
+
+// conditionFuncs are the functions that can be called from an expression,
+// along with the number of arguments they take.
+var conditionFuncs = map[string]int{
+	"cidr_match": 2,
+	"has_prefix": 2,
+	"has_suffix": 2,
+	"lower":      1,
+}
+
+func (n *conditionCall) eval(in *conditionInput) (interface{}, error) {
+	args := make([]string, 0, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			// Missing values never match
+			if n.name == "lower" {
+				return nil, nil
+			}
+			return false, nil
+		}
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("argument %d of %s() is not a string: %v", i+1, n.name, v)
+		}
+		args = append(args, s)
+	}
+
+	switch n.name {
+	case "cidr_match":
+		host := args[0]
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return false, nil
+		}
+		_, cidr, err := net.ParseCIDR(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", args[1], err)
+		}
+		return cidr.Contains(ip), nil
+	case "has_prefix":
+		return strings.HasPrefix(args[0], args[1]), nil
+	case "has_suffix":
+		return strings.HasSuffix(args[0], args[1]), nil
+	case "lower":
+		return strings.ToLower(args[0]), nil
+	}
+
+	return nil, fmt.Errorf("unknown function %q", n.name)
+}
+
+// parseConditionExpression compiles an expression. The grammar is:
+//
+//	expr    = or
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | compare
+//	compare = primary [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) primary ]
+//	primary = number | string | "true" | "false" | selector
+//	        | ident "(" [ expr { "," expr } ] ")"
+//	        | "[" [ expr { "," expr } ] "]" | "(" expr ")"
+func parseConditionExpression(s string) (conditionNode, error) {
+	return parseExpression(s, conditionSelectors, conditionSelectorPrefixes)
+}
+
+// parseExpression compiles an expression that may only reference the given
+// selectors, or selectors made of one of the given prefixes and a key.
+func parseExpression(s string, selectors map[string]struct{}, selectorPrefixes []string) (conditionNode, error) {
+	tokens, err := lexCondition(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &conditionParser{
+		tokens:           tokens,
+		selectors:        selectors,
+		selectorPrefixes: selectorPrefixes,
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return node, nil
+}
+
+type conditionTokenKind int
+
+const (
+	conditionTokenIdent conditionTokenKind = iota
+	conditionTokenString
+	conditionTokenNumber
+	conditionTokenOp
+)
+
+type conditionToken struct {
+	kind conditionTokenKind
+	text string
+}
+
+func lexCondition(s string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenString, text: s[i : j+1]})
+			i = j + 1
+
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenNumber, text: s[i:j]})
+			i = j
+
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_' || s[j] == '.' || s[j] == '-') {
+				j++
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenIdent, text: s[i:j]})
+			i = j
+
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, conditionToken{kind: conditionTokenOp, text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+
+	selectors        map[string]struct{}
+	selectorPrefixes []string
+}
+
+func (p *conditionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *conditionParser) peek() conditionToken {
+	if p.done() {
+		return conditionToken{kind: conditionTokenOp, text: "end of expression"}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) acceptOp(op string) bool {
+	if !p.done() && p.tokens[p.pos].kind == conditionTokenOp && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *conditionParser) expectOp(op string) error {
+	if !p.acceptOp(op) {
+		return fmt.Errorf("expected %q, found %q", op, p.peek().text)
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &conditionLogical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptOp("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &conditionLogical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	if p.acceptOp("!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionNot{operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *conditionParser) parseCompare() (conditionNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.kind == conditionTokenOp && (tok.text == "==" || tok.text == "!=" || tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">="),
+		tok.kind == conditionTokenIdent && tok.text == "in":
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &conditionCompare{op: tok.text, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *conditionParser) parseList(end string) ([]conditionNode, error) {
+	var elems []conditionNode
+	if p.acceptOp(end) {
+		return elems, nil
+	}
+	for {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, e)
+		if p.acceptOp(end) {
+			return elems, nil
+		}
+		if err := p.expectOp(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *conditionParser) parsePrimary() (conditionNode, error) {
+	if p.done() {
+		return nil, errors.New("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	switch tok.kind {
+	case conditionTokenString:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s: %w", tok.text, err)
+		}
+		return &conditionLiteral{value: s}, nil
+
+	case conditionTokenNumber:
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return &conditionLiteral{value: f}, nil
+
+	case conditionTokenIdent:
+		switch tok.text {
+		case "true":
+			return &conditionLiteral{value: true}, nil
+		case "false":
+			return &conditionLiteral{value: false}, nil
+		}
+
+		if p.acceptOp("(") {
+			arity, ok := conditionFuncs[tok.text]
+			if !ok {
+				return nil, fmt.Errorf("unknown function %q", tok.text)
+			}
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			if len(args) != arity {
+				return nil, fmt.Errorf("%s() takes %d arguments, got %d", tok.text, arity, len(args))
+			}
+			return &conditionCall{name: tok.text, args: args}, nil
+		}
+
+		if _, ok := p.selectors[tok.text]; ok {
+			return &conditionSelector{selector: tok.text}, nil
+		}
+		for _, prefix := range p.selectorPrefixes {
+			if strings.HasPrefix(tok.text, prefix) && len(tok.text) > len(prefix) {
+				return &conditionSelector{selector: tok.text}, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown selector %q", tok.text)
+
+	case conditionTokenOp:
+		switch tok.text {
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			elems, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &conditionList{elems: elems}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %q", tok.text)
+}
@@ -0,0 +1,139 @@
+package vault
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestPolicyCondition_Evaluate(t *testing.T) {
+	// A Tuesday afternoon
+	now := time.Date(2021, 5, 18, 14, 30, 0, 0, time.UTC)
+
+	req := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "secret/foo",
+		Connection: &logical.Connection{
+			RemoteAddr: "10.1.2.3",
+		},
+	}
+	req.SetTokenEntry(&logical.TokenEntry{
+		DisplayName: "token-ci",
+		Policies:    []string{"default", "ci"},
+		Meta: map[string]string{
+			"pipeline": "deploy",
+		},
+	})
+
+	in := &conditionInput{
+		now: now,
+		req: req,
+		entity: &identity.Entity{
+			ID:   "entity-id",
+			Name: "alice",
+			Metadata: map[string]string{
+				"team":  "ops",
+				"level": "3",
+			},
+		},
+		groups: []*identity.Group{
+			{ID: "group-id", Name: "admins"},
+		},
+	}
+
+	cases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`true`, true},
+		{`time.hour >= 9 && time.hour < 17`, true},
+		{`time.hour >= 18 || time.hour < 6`, false},
+		{`time.weekday in ["saturday", "sunday"]`, false},
+		{`!(time.weekday in ["saturday", "sunday"])`, true},
+		{`cidr_match(request.remote_addr, "10.0.0.0/8")`, true},
+		{`cidr_match(request.remote_addr, "192.168.0.0/16")`, false},
+		{`request.operation == "read"`, true},
+		{`has_prefix(request.path, "secret/")`, true},
+		{`identity.entity.name == "alice"`, true},
+		{`identity.entity.metadata.team == "ops"`, true},
+		{`identity.entity.metadata.level >= 2`, true},
+		{`identity.entity.metadata.level == 3`, true},
+		{`identity.entity.metadata.missing == "ops"`, false},
+		{`"admins" in identity.groups.names`, true},
+		{`"ci" in token.policies`, true},
+		{`token.metadata.pipeline != "deploy"`, false},
+		{`lower(token.display_name) == "token-ci"`, true},
+		{`token.mfa_validated`, false},
+	}
+
+	for _, tc := range cases {
+		conditions, err := parsePolicyConditions("secret/*", map[string]*PolicyConditionHCL{
+			"test": {Expression: tc.expr},
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to parse: %v", tc.expr, err)
+		}
+		actual, err := conditions[0].evaluate(in)
+		if err != nil {
+			t.Fatalf("%s: failed to evaluate: %v", tc.expr, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("%s: expected %t, got %t", tc.expr, tc.expected, actual)
+		}
+	}
+}
+
+func TestPolicyCondition_EvaluateErrors(t *testing.T) {
+	in := &conditionInput{
+		now: time.Now(),
+		req: &logical.Request{Path: "secret/foo"},
+	}
+
+	cases := map[string]string{
+		`request.path > 1`:     `operands of ">" must be numbers`,
+		`request.path`:         "rather than a boolean",
+		`request.path && true`: `left operand of "&&" is not a boolean`,
+		`"a" in request.path`:  "right operand of 'in' is not a list",
+	}
+
+	for expr, expected := range cases {
+		node, err := parseConditionExpression(expr)
+		if err != nil {
+			t.Fatalf("%s: failed to parse: %v", expr, err)
+		}
+		c := &PolicyCondition{Name: "test", Path: "secret/*", expr: node}
+		ok, err := c.evaluate(in)
+		if ok || err == nil {
+			t.Fatalf("%s: expected evaluation error", expr)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%s: expected error containing %q, got %q", expr, expected, err)
+		}
+	}
+}
+
+func TestPolicyCondition_ParseErrors(t *testing.T) {
+	cases := map[string]string{
+		`time.hour >=`:                    "unexpected end of expression",
+		`time.day == 1`:                   `unknown selector "time.day"`,
+		`ip_match(request.remote_addr)`:   `unknown function "ip_match"`,
+		`cidr_match(request.remote_addr)`: "cidr_match() takes 2 arguments",
+		`request.path == "foo`:            "unterminated string",
+		`(time.hour > 1`:                  `expected ")"`,
+		`time.hour $ 1`:                   "unexpected character",
+		`time.hour > 1 time.hour`:         `unexpected "time.hour"`,
+	}
+
+	for expr, expected := range cases {
+		_, err := parseConditionExpression(expr)
+		if err == nil {
+			t.Fatalf("%s: expected error", expr)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("%s: expected error containing %q, got %q", expr, expected, err)
+		}
+	}
+}
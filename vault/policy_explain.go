@@ -0,0 +1,260 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/identity"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// PolicyExplanation describes how a set of policies decides a request: the
+// path rule that applies to it, the capabilities that rule grants and the
+// policies it was merged from.
+type PolicyExplanation struct {
+	Allowed bool
+
+	// Rule is the path of the most specific rule matching the request, as
+	// written in the policies. It is empty if no rule matches.
+	Rule         string
+	Capabilities []string
+
+	// GrantedBy maps each capability of the rule to the policies granting
+	// it, DeniedBy lists the policies explicitly denying the rule.
+	GrantedBy map[string][]string
+	DeniedBy  []string
+
+	Reason   string
+	Policies []*PolicyRuleExplanation
+}
+
+// PolicyRuleExplanation describes the rule of a single policy matching the
+// request.
+type PolicyRuleExplanation struct {
+	Policy       string
+	Namespace    string
+	Rule         string
+	Capabilities []string
+
+	// Effective is set if the rule is the one applied to the request. A rule
+	// that matches the path less specifically than the rule of another policy
+	// is shadowed by it and has no effect.
+	Effective bool
+}
+
+// explainedPolicy is a policy along with an ACL built from it alone.
+type explainedPolicy struct {
+	name      string
+	namespace string
+	acl       *ACL
+}
+
+// explainPolicies explains the decision for the request of an ACL built from
+// the given policies, keyed by namespace ID as in PolicyStore.ACL. The ACL is
+// constructed in policyNS.
+func (c *Core) explainPolicies(ctx context.Context, policyNS *namespace.Namespace, entity *identity.Entity, policyNames map[string][]string, req *logical.Request) (*PolicyExplanation, error) {
+	aclCtx := namespace.ContextWithNamespace(ctx, policyNS)
+	acl, err := c.policyStore.ACL(aclCtx, entity, policyNames)
+	if err != nil {
+		return nil, err
+	}
+
+	nsIDs := make([]string, 0, len(policyNames))
+	for nsID := range policyNames {
+		nsIDs = append(nsIDs, nsID)
+	}
+	sort.Strings(nsIDs)
+
+	var policies []*explainedPolicy
+	for _, nsID := range nsIDs {
+		ns, err := NamespaceByID(ctx, nsID, c)
+		if err != nil {
+			return nil, err
+		}
+		if ns == nil {
+			return nil, namespace.ErrNoNamespace
+		}
+
+		for _, name := range strutil.RemoveDuplicatesStable(policyNames[nsID], false) {
+			policyACL, err := c.policyStore.ACL(aclCtx, entity, map[string][]string{nsID: {name}})
+			if err != nil {
+				return nil, err
+			}
+			policies = append(policies, &explainedPolicy{
+				name:      name,
+				namespace: ns.Path,
+				acl:       policyACL,
+			})
+		}
+	}
+
+	return explainACL(ctx, acl, policies, req), nil
+}
+
+// ExplainPolicies explains the decision for the request of an ACL built from
+// the given policies alone, without consulting a policy store. Templated
+// policies are rendered for the given entity and groups, which are also
+// available to policy conditions. This allows testing policies offline.
+func ExplainPolicies(ctx context.Context, policies []*Policy, entity *identity.Entity, groups []*identity.Group, req *logical.Request) (*PolicyExplanation, error) {
+	rendered := make([]*Policy, 0, len(policies))
+	for _, policy := range policies {
+		if policy.Type == PolicyTypeACL && policy.Templated {
+			p, err := parseACLPolicyWithTemplating(policy.namespace, policy.Raw, true, entity, groups)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing templated policy %q: %w", policy.Name, err)
+			}
+			p.Name = policy.Name
+			policy = p
+		}
+		rendered = append(rendered, policy)
+	}
+
+	newACL := func(policies ...*Policy) (*ACL, error) {
+		acl, err := NewACL(ctx, policies)
+		if err != nil {
+			return nil, fmt.Errorf("failed to construct ACL: %w", err)
+		}
+		if acl.hasConditions {
+			acl.entity = entity
+			acl.groups = groups
+		}
+		return acl, nil
+	}
+
+	acl, err := newACL(rendered...)
+	if err != nil {
+		return nil, err
+	}
+
+	explained := make([]*explainedPolicy, 0, len(rendered))
+	for _, policy := range rendered {
+		policyACL, err := newACL(policy)
+		if err != nil {
+			return nil, err
+		}
+		var nsPath string
+		if policy.namespace != nil {
+			nsPath = policy.namespace.Path
+		}
+		explained = append(explained, &explainedPolicy{
+			name:      policy.Name,
+			namespace: nsPath,
+			acl:       policyACL,
+		})
+	}
+
+	return explainACL(ctx, acl, explained, req), nil
+}
+
+// explainACL explains the decision of acl for the request. The policies acl
+// was built from are used to attribute the matching rule to them.
+func explainACL(ctx context.Context, acl *ACL, policies []*explainedPolicy, req *logical.Request) *PolicyExplanation {
+	ret := &PolicyExplanation{
+		GrantedBy: make(map[string][]string),
+	}
+
+	res := acl.AllowOperation(ctx, req, false)
+	ret.Allowed = res.Allowed
+	switch {
+	case res.IsRoot:
+		ret.Capabilities = []string{RootCapability}
+		ret.Reason = "the root policy grants every capability on every path"
+		return ret
+	case req.Operation == logical.HelpOperation:
+		ret.Reason = "help requests are always allowed"
+		return ret
+	}
+
+	permissions, rule, err := acl.matchingPermissions(ctx, req)
+	if err != nil {
+		ret.Reason = fmt.Sprintf("error matching path: %v", err)
+		return ret
+	}
+	ret.Rule = rule
+	if permissions == nil {
+		ret.Capabilities = []string{DenyCapability}
+	} else {
+		ret.Capabilities = capabilitiesFromBitmap(permissions.CapabilitiesBitmap)
+	}
+
+	for _, p := range policies {
+		pe := &PolicyRuleExplanation{
+			Policy:    p.name,
+			Namespace: p.namespace,
+		}
+		ret.Policies = append(ret.Policies, pe)
+
+		policyPermissions, policyRule, err := p.acl.matchingPermissions(ctx, req)
+		if err != nil || policyPermissions == nil {
+			continue
+		}
+		pe.Rule = policyRule
+		pe.Capabilities = capabilitiesFromBitmap(policyPermissions.CapabilitiesBitmap)
+		pe.Effective = policyRule == rule
+		if !pe.Effective {
+			continue
+		}
+
+		if policyPermissions.CapabilitiesBitmap&DenyCapabilityInt > 0 {
+			ret.DeniedBy = append(ret.DeniedBy, p.name)
+			continue
+		}
+		for _, capability := range pe.Capabilities {
+			ret.GrantedBy[capability] = append(ret.GrantedBy[capability], p.name)
+		}
+	}
+
+	required := operationCapability(req.Operation)
+	switch {
+	case ret.Allowed:
+		ret.Reason = fmt.Sprintf("the %q capability is granted on path rule %q by %s", required, rule, quotedPolicies(ret.GrantedBy[required]))
+	case permissions == nil:
+		ret.Reason = fmt.Sprintf("no policy contains a path rule matching %q", req.Path)
+	case permissions.CapabilitiesBitmap&DenyCapabilityInt > 0:
+		ret.Reason = fmt.Sprintf("path rule %q is explicitly denied by %s", rule, quotedPolicies(ret.DeniedBy))
+	case res.ConditionError != nil:
+		ret.Reason = res.ConditionError.Error()
+	case required == "":
+		ret.Reason = fmt.Sprintf("operation %q is not governed by capabilities", req.Operation)
+	case len(ret.GrantedBy[required]) == 0:
+		ret.Reason = fmt.Sprintf("no policy grants the %q capability on path rule %q", required, rule)
+	default:
+		ret.Reason = fmt.Sprintf("the request does not satisfy the parameter or response wrapping constraints of path rule %q", rule)
+	}
+
+	return ret
+}
+
+// operationCapability returns the capability required to perform the given
+// operation, or an empty string if no capability grants it.
+func operationCapability(op logical.Operation) string {
+	switch op {
+	case logical.ReadOperation:
+		return ReadCapability
+	case logical.ListOperation:
+		return ListCapability
+	case logical.CreateOperation:
+		return CreateCapability
+	case logical.DeleteOperation:
+		return DeleteCapability
+	case logical.UpdateOperation, logical.RevokeOperation, logical.RenewOperation, logical.RollbackOperation:
+		return UpdateCapability
+	default:
+		return ""
+	}
+}
+
+func quotedPolicies(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, fmt.Sprintf("%q", name))
+	}
+	if len(quoted) == 1 {
+		return "policy " + quoted[0]
+	}
+	return "policies " + strings.Join(quoted, ", ")
+}
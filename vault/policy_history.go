@@ -0,0 +1,208 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// policyHistoryMaxVersions is the number of versions of each ACL policy that
+// are kept, including the current one
+const policyHistoryMaxVersions = 20
+
+// policyHistoryPathRe matches the ACL policy names that would be shadowed by
+// the history endpoints under sys/policies/acl/<name>/
+var policyHistoryPathRe = regexp.MustCompile(`/(versions(/\d*)?|diff|rollback)$`)
+
+// PolicyVersion is a version of an ACL policy kept in its history
+type PolicyVersion struct {
+	Version        int       `json:"version"`
+	Raw            string    `json:"raw"`
+	AuthorAccessor string    `json:"author_accessor"`
+	CreatedTime    time.Time `json:"created_time"`
+}
+
+// recordPolicyVersion appends the new contents of the ACL policy to its
+// history, dropping the oldest versions beyond policyHistoryMaxVersions. If
+// the policy predates history tracking, its previous contents are recorded
+// first so that they can be rolled back to. Writes that do not change the
+// contents are not recorded, so that they do not push the actual changes out
+// of the history. It must be called with the modify lock held.
+func (ps *PolicyStore) recordPolicyVersion(ctx context.Context, p *Policy, previous *logical.StorageEntry, authorAccessor string) error {
+	view := ps.getACLHistoryView(p.namespace)
+
+	versions, err := ps.policyVersionNumbers(ctx, view, p.Name)
+	if err != nil {
+		return err
+	}
+
+	putVersion := func(version *PolicyVersion) error {
+		entry, err := logical.StorageEntryJSON(policyVersionKey(p.Name, version.Version), version)
+		if err != nil {
+			return err
+		}
+		if err := view.Put(ctx, entry); err != nil {
+			return err
+		}
+		versions = append(versions, version.Version)
+		return nil
+	}
+
+	var latest *PolicyVersion
+	switch {
+	case len(versions) > 0:
+		latest, err = ps.getPolicyVersion(ctx, view, p.Name, versions[len(versions)-1])
+		if err != nil {
+			return err
+		}
+	case previous != nil:
+		var previousEntry PolicyEntry
+		if err := previous.DecodeJSON(&previousEntry); err != nil {
+			return fmt.Errorf("failed to parse existing policy: %w", err)
+		}
+		latest = &PolicyVersion{
+			Version: 1,
+			Raw:     previousEntry.Raw,
+		}
+		if err := putVersion(latest); err != nil {
+			return err
+		}
+	}
+	if latest != nil && latest.Raw == p.Raw {
+		return nil
+	}
+
+	next := 1
+	if len(versions) > 0 {
+		next = versions[len(versions)-1] + 1
+	}
+	if err := putVersion(&PolicyVersion{
+		Version:        next,
+		Raw:            p.Raw,
+		AuthorAccessor: authorAccessor,
+		CreatedTime:    time.Now().UTC(),
+	}); err != nil {
+		return err
+	}
+
+	for len(versions) > policyHistoryMaxVersions {
+		if err := view.Delete(ctx, policyVersionKey(p.Name, versions[0])); err != nil {
+			return err
+		}
+		versions = versions[1:]
+	}
+
+	return nil
+}
+
+// ListPolicyVersions returns the versions kept in the history of the named
+// ACL policy, oldest first
+func (ps *PolicyStore) ListPolicyVersions(ctx context.Context, name string) ([]*PolicyVersion, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.modifyLock.RLock()
+	defer ps.modifyLock.RUnlock()
+
+	view := ps.getACLHistoryView(ns)
+	name = ps.sanitizeName(name)
+	versions, err := ps.policyVersionNumbers(ctx, view, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*PolicyVersion, 0, len(versions))
+	for _, version := range versions {
+		pv, err := ps.getPolicyVersion(ctx, view, name, version)
+		if err != nil {
+			return nil, err
+		}
+		if pv != nil {
+			ret = append(ret, pv)
+		}
+	}
+
+	return ret, nil
+}
+
+// GetPolicyVersion returns the given version of the named ACL policy, or nil
+// if it is not in the history of the policy
+func (ps *PolicyStore) GetPolicyVersion(ctx context.Context, name string, version int) (*PolicyVersion, error) {
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ps.modifyLock.RLock()
+	defer ps.modifyLock.RUnlock()
+
+	return ps.getPolicyVersion(ctx, ps.getACLHistoryView(ns), ps.sanitizeName(name), version)
+}
+
+func (ps *PolicyStore) getPolicyVersion(ctx context.Context, view *BarrierView, name string, version int) (*PolicyVersion, error) {
+	entry, err := view.Get(ctx, policyVersionKey(name, version))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy version: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var pv PolicyVersion
+	if err := entry.DecodeJSON(&pv); err != nil {
+		return nil, fmt.Errorf("failed to parse policy version: %w", err)
+	}
+	return &pv, nil
+}
+
+// deletePolicyHistory removes every version of the named ACL policy. It must
+// be called with the modify lock held.
+func (ps *PolicyStore) deletePolicyHistory(ctx context.Context, ns *namespace.Namespace, name string) error {
+	view := ps.getACLHistoryView(ns)
+	versions, err := ps.policyVersionNumbers(ctx, view, name)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if err := view.Delete(ctx, policyVersionKey(name, version)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// policyVersionNumbers returns the versions in the history of the named ACL
+// policy in ascending order
+func (ps *PolicyStore) policyVersionNumbers(ctx context.Context, view *BarrierView, name string) ([]int, error) {
+	keys, err := view.List(ctx, name+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list policy versions: %w", err)
+	}
+
+	versions := make([]int, 0, len(keys))
+	for _, key := range keys {
+		version, err := strconv.Atoi(key)
+		if err != nil {
+			// Policy names may contain slashes, skip the history of other
+			// policies nested under this name
+			continue
+		}
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	return versions, nil
+}
+
+func policyVersionKey(name string, version int) string {
+	return fmt.Sprintf("%s/%d", name, version)
+}
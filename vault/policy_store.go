@@ -26,6 +26,10 @@ const (
 	policyRGPSubPath = "policy-rgp/"
 	policyEGPSubPath = "policy-egp/"
 
+	// policyACLHistorySubPath is the sub-path under which previous versions of
+	// ACL policies are kept
+	policyACLHistorySubPath = "policy-history/"
+
 	// policyCacheSize is the number of policies that are kept cached
 	policyCacheSize = 1024
 
@@ -170,10 +174,11 @@ var (
 type PolicyStore struct {
 	entPolicyStore
 
-	core    *Core
-	aclView *BarrierView
-	rgpView *BarrierView
-	egpView *BarrierView
+	core           *Core
+	aclView        *BarrierView
+	rgpView        *BarrierView
+	egpView        *BarrierView
+	aclHistoryView *BarrierView
 
 	tokenPoliciesLRU *lru.TwoQueueCache
 	egpLRU           *lru.TwoQueueCache
@@ -204,12 +209,13 @@ type PolicyEntry struct {
 // using a given view. It used used to durable store and manage named policy.
 func NewPolicyStore(ctx context.Context, core *Core, baseView *BarrierView, system logical.SystemView, logger log.Logger) (*PolicyStore, error) {
 	ps := &PolicyStore{
-		aclView:    baseView.SubView(policyACLSubPath),
-		rgpView:    baseView.SubView(policyRGPSubPath),
-		egpView:    baseView.SubView(policyEGPSubPath),
-		modifyLock: new(sync.RWMutex),
-		logger:     logger,
-		core:       core,
+		aclView:        baseView.SubView(policyACLSubPath),
+		rgpView:        baseView.SubView(policyRGPSubPath),
+		egpView:        baseView.SubView(policyEGPSubPath),
+		aclHistoryView: baseView.SubView(policyACLHistorySubPath),
+		modifyLock:     new(sync.RWMutex),
+		logger:         logger,
+		core:           core,
 	}
 
 	ps.extraInit()
@@ -333,6 +339,12 @@ func (ps *PolicyStore) invalidate(ctx context.Context, name string, policyType P
 
 // SetPolicy is used to create or update the given policy
 func (ps *PolicyStore) SetPolicy(ctx context.Context, p *Policy) error {
+	return ps.SetPolicyWithAuthor(ctx, p, "")
+}
+
+// SetPolicyWithAuthor is used to create or update the given policy, recording
+// the accessor of the token making the change in the history of ACL policies
+func (ps *PolicyStore) SetPolicyWithAuthor(ctx context.Context, p *Policy, authorAccessor string) error {
 	defer metrics.MeasureSince([]string{"policy", "set_policy"}, time.Now())
 	if p == nil {
 		return fmt.Errorf("nil policy passed in for storage")
@@ -346,10 +358,15 @@ func (ps *PolicyStore) SetPolicy(ctx context.Context, p *Policy) error {
 		return fmt.Errorf("cannot update %q policy", p.Name)
 	}
 
-	return ps.setPolicyInternal(ctx, p)
+	if err := ps.setPolicyInternal(ctx, p, authorAccessor); err != nil {
+		return err
+	}
+
+	ps.core.publishPolicyEvent(p.namespace, EventPolicyWritten, p.Name, p.Type)
+	return nil
 }
 
-func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
+func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy, authorAccessor string) error {
 	ps.modifyLock.Lock()
 	defer ps.modifyLock.Unlock()
 
@@ -389,10 +406,19 @@ func (ps *PolicyStore) setPolicyInternal(ctx context.Context, p *Policy) error {
 			return fmt.Errorf("cannot reuse policy names between ACLs and RGPs")
 		}
 
+		existing, err := view.Get(ctx, entry.Key)
+		if err != nil {
+			return fmt.Errorf("failed looking up existing policy: %w", err)
+		}
+
 		if err := view.Put(ctx, entry); err != nil {
 			return fmt.Errorf("failed to persist policy: %w", err)
 		}
 
+		if err := ps.recordPolicyVersion(ctx, p, existing, authorAccessor); err != nil {
+			return fmt.Errorf("failed to record policy version: %w", err)
+		}
+
 		ps.policyTypeMap.Store(index, PolicyTypeACL)
 
 		if ps.tokenPoliciesLRU != nil {
@@ -638,7 +664,14 @@ func (ps *PolicyStore) ListPolicies(ctx context.Context, policyType PolicyType)
 
 // DeletePolicy is used to delete the named policy
 func (ps *PolicyStore) DeletePolicy(ctx context.Context, name string, policyType PolicyType) error {
-	return ps.switchedDeletePolicy(ctx, name, policyType, true, false)
+	if err := ps.switchedDeletePolicy(ctx, name, policyType, true, false); err != nil {
+		return err
+	}
+
+	if ns, err := namespace.FromContext(ctx); err == nil {
+		ps.core.publishPolicyEvent(ns, EventPolicyDeleted, ps.sanitizeName(name), policyType)
+	}
+	return nil
 }
 
 // deletePolicyForce is used to delete the named policy and force it even if
@@ -688,6 +721,9 @@ func (ps *PolicyStore) switchedDeletePolicy(ctx context.Context, name string, po
 			if err != nil {
 				return fmt.Errorf("failed to delete policy: %w", err)
 			}
+			if err := ps.deletePolicyHistory(ctx, ns, name); err != nil {
+				return fmt.Errorf("failed to delete policy history: %w", err)
+			}
 		}
 
 		if ps.tokenPoliciesLRU != nil {
@@ -801,6 +837,19 @@ func (ps *PolicyStore) ACL(ctx context.Context, entity *identity.Entity, policyN
 		return nil, fmt.Errorf("failed to construct ACL: %w", err)
 	}
 
+	// Policy conditions may reference the identity of the caller
+	if acl.hasConditions && entity != nil {
+		if !fetchedGroups {
+			directGroups, inheritedGroups, err := ps.core.identityStore.groupsByEntityID(entity.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch group memberships: %w", err)
+			}
+			groups = append(directGroups, inheritedGroups...)
+		}
+		acl.entity = entity
+		acl.groups = groups
+	}
+
 	return acl, nil
 }
 
@@ -840,7 +889,7 @@ func (ps *PolicyStore) loadACLPolicyInternal(ctx context.Context, policyName, po
 
 	policy.Name = policyName
 	policy.Type = PolicyTypeACL
-	return ps.setPolicyInternal(ctx, policy)
+	return ps.setPolicyInternal(ctx, policy, "")
 }
 
 func (ps *PolicyStore) sanitizeName(name string) string {
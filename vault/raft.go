@@ -193,6 +193,10 @@ func (c *Core) setupRaftActiveNode(ctx context.Context) error {
 		return err
 	}
 
+	if err := c.startRaftAutoSnapshots(ctx); err != nil {
+		return err
+	}
+
 	return c.startPeriodicRaftTLSRotate(ctx)
 }
 
@@ -209,6 +213,7 @@ func (c *Core) stopRaftActiveNode() {
 	}
 
 	c.pendingRaftPeers = nil
+	c.stopRaftAutoSnapshots()
 	c.stopPeriodicRaftTLSRotate()
 }
 
@@ -349,7 +354,10 @@ func (c *Core) raftTLSRotatePhased(ctx context.Context, logger hclog.Logger, raf
 	}
 	for _, server := range raftConfig.Servers {
 		if server.NodeID != raftBackend.NodeID() {
-			followerStates.Update(server.NodeID, 0, 0, "voter")
+			followerStates.Update(&raft.EchoRequestUpdate{
+				NodeID:          server.NodeID,
+				DesiredSuffrage: "voter",
+			})
 		}
 	}
 
@@ -719,6 +727,8 @@ func (c *Core) JoinRaftCluster(ctx context.Context, leaderInfos []*raft.LeaderJo
 		return false, errors.New("raft backend not in use")
 	}
 
+	// Nodes configured as non-voters always join as non-voters
+	nonVoter = nonVoter || raftBackend.NonVoter()
 	if err := raftBackend.SetDesiredSuffrage(nonVoter); err != nil {
 		c.logger.Error("failed to set desired suffrage for this node", "error", err)
 		return false, nil
@@ -873,7 +883,7 @@ func (c *Core) JoinRaftCluster(ctx context.Context, leaderInfos []*raft.LeaderJo
 				return err
 			}
 
-			if sealConfig.Type != c.seal.BarrierType() {
+			if !sealTypesCompatible(sealConfig.Type, c.seal.BarrierType()) {
 				return fmt.Errorf("mismatching seal types between raft leader (%s) and follower (%s)", sealConfig.Type, c.seal.BarrierType())
 			}
 
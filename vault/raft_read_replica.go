@@ -0,0 +1,345 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault/quotas"
+)
+
+const (
+	// raftReadReplicaSetupRetryInterval is how long a read replica waits
+	// before trying again after failing to load its local state
+	raftReadReplicaSetupRetryInterval = 5 * time.Second
+)
+
+var (
+	// raftReadReplicaLocalMountTypes are the mount types whose read and list
+	// operations have no side effects, so that a read replica can serve them
+	// from its own copy of the data. Requests to any other mount are
+	// forwarded to the active node.
+	raftReadReplicaLocalMountTypes = []string{
+		"kv",
+		"cubbyhole",
+		"system",
+		"identity",
+		"token",
+	}
+
+	// raftReadReplicaReloadKeys are the storage keys whose change requires a
+	// read replica to load its mounts and everything derived from them again
+	raftReadReplicaReloadKeys = []string{
+		coreMountConfigPath,
+		coreLocalMountConfigPath,
+		coreAuthConfigPath,
+		coreLocalAuthConfigPath,
+		coreAuditConfigPath,
+		coreLocalAuditConfigPath,
+		systemBarrierPrefix + "config/cors",
+		systemBarrierPrefix + auditedHeadersSubPath + auditedHeadersEntry,
+	}
+)
+
+// raftReadReplicaInvalidations collects the keys applied by the raft FSM of a
+// read replica until they are processed. The FSM must never block on it.
+type raftReadReplicaInvalidations struct {
+	l        sync.Mutex
+	keys     []string
+	reload   bool
+	notifyCh chan struct{}
+}
+
+func (r *raftReadReplicaInvalidations) add(keys []string) {
+	r.l.Lock()
+	if keys == nil {
+		// A snapshot was installed, anything could have changed
+		r.reload = true
+	} else {
+		r.keys = append(r.keys, keys...)
+	}
+	r.l.Unlock()
+
+	select {
+	case r.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *raftReadReplicaInvalidations) take() (keys []string, reload bool) {
+	r.l.Lock()
+	defer r.l.Unlock()
+
+	keys, reload = r.keys, r.reload
+	r.keys, r.reload = nil, false
+	for _, key := range keys {
+		if strutil.StrListContains(raftReadReplicaReloadKeys, key) {
+			reload = true
+		}
+	}
+	return keys, reload
+}
+
+// isRaftReadReplica returns true if this node uses raft for storage and joined
+// the cluster as a non-voter. Such nodes are never promoted by autopilot and
+// serve reads as a standby.
+func (c *Core) isRaftReadReplica() bool {
+	raftBackend, ok := c.underlyingPhysical.(*raft.RaftBackend)
+	return ok && raftBackend.DesiredSuffrage() == "non-voter"
+}
+
+// runRaftReadReplica is a long running routine used by standby nodes that are
+// raft read replicas. It loads the mounts, policies and the rest of the state
+// needed to serve requests, and keeps it up to date with the writes the node
+// receives through the raft log, until stopCh is closed.
+//
+// runStandby is stopped while the state lock is held, so the state is torn
+// down without taking the lock when stopCh is closed.
+func (c *Core) runRaftReadReplica(stopCh chan struct{}) {
+	raftBackend := c.getRaftBackend()
+	invalidations := &raftReadReplicaInvalidations{
+		notifyCh: make(chan struct{}, 1),
+	}
+	raftBackend.SetInvalidateCallback(invalidations.add)
+	defer raftBackend.SetInvalidateCallback(nil)
+
+	defer func() {
+		if c.perfStandby {
+			c.teardownRaftReadReplica()
+			c.logger.Info("read replica stopped")
+		}
+	}()
+
+	setup := true
+	for {
+		if setup {
+			if stopped := grabLockOrStop(c.stateLock.Lock, c.stateLock.Unlock, stopCh); stopped {
+				return
+			}
+			// Anything queued so far is covered by loading the state again
+			invalidations.take()
+			if c.perfStandby {
+				c.teardownRaftReadReplica()
+			}
+			err := c.setupRaftReadReplica()
+			c.stateLock.Unlock()
+			if err != nil {
+				c.logger.Error("failed to set up read replica", "error", err)
+				select {
+				case <-stopCh:
+					return
+				case <-time.After(raftReadReplicaSetupRetryInterval):
+				}
+				continue
+			}
+			c.logger.Info("read replica serving requests")
+			setup = false
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-invalidations.notifyCh:
+		}
+
+		keys, reload := invalidations.take()
+		if reload {
+			setup = true
+			continue
+		}
+
+		if stopped := grabLockOrStop(c.stateLock.RLock, c.stateLock.RUnlock, stopCh); stopped {
+			return
+		}
+		ctx := namespace.RootContext(c.activeContext)
+		for _, key := range keys {
+			c.invalidateRaftReadReplicaKey(ctx, key)
+		}
+		c.stateLock.RUnlock()
+	}
+}
+
+// setupRaftReadReplica loads the state a read replica needs to serve
+// requests. It mirrors the active node's unseal steps, leaving out everything
+// that writes to storage. The state lock must be held for writing.
+func (c *Core) setupRaftReadReplica() (retErr error) {
+	ctx, ctxCancel := context.WithCancel(namespace.RootContext(nil))
+	c.activeContext = ctx
+	c.activeContextCancelFunc.Store(ctxCancel)
+	c.perfStandby = true
+	c.postUnsealFuncs = nil
+
+	defer func() {
+		if retErr != nil {
+			c.teardownRaftReadReplica()
+		}
+	}()
+
+	if err := c.setupPluginCatalog(ctx); err != nil {
+		return err
+	}
+	if err := c.loadMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupMounts(ctx); err != nil {
+		return err
+	}
+	if err := c.setupPolicyStore(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCORSConfig(ctx); err != nil {
+		return err
+	}
+	if err := c.loadCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupCredentials(ctx); err != nil {
+		return err
+	}
+	if err := c.setupQuotas(ctx, true); err != nil {
+		return err
+	}
+	c.setupRaftReadReplicaExpiration()
+	if err := c.loadAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.setupAudits(ctx); err != nil {
+		return err
+	}
+	if err := c.loadIdentityStoreArtifacts(ctx); err != nil {
+		return err
+	}
+	if err := loadMFAConfigs(ctx, c); err != nil {
+		return err
+	}
+	if err := c.setupAuditedHeadersConfig(ctx); err != nil {
+		return err
+	}
+
+	for _, v := range c.postUnsealFuncs {
+		v()
+	}
+	c.postUnsealFuncs = nil
+
+	return nil
+}
+
+// setupRaftReadReplicaExpiration creates an expiration manager that can look
+// up leases but never restores or expires them; that is the active node's job.
+func (c *Core) setupRaftReadReplicaExpiration() {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+
+	expLogger := c.baseLogger.Named("expiration")
+	c.AddLogger(expLogger)
+	mgr := NewExpirationManager(c, c.systemBarrierView.SubView(expirationSubPath), expireLeaseStrategyFairsharing, expLogger)
+	atomic.StoreInt32(mgr.restoreMode, 0)
+	c.expiration = mgr
+
+	c.tokenStore.SetExpirationManager(mgr)
+}
+
+// teardownRaftReadReplica reverses setupRaftReadReplica. The caller must
+// either hold the state lock for writing or be stopping runStandby.
+func (c *Core) teardownRaftReadReplica() {
+	var errs []error
+	if err := c.teardownAudits(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.stopExpiration(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.teardownCredentials(context.Background()); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.teardownPolicyStore(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.unloadMounts(context.Background()); err != nil {
+		errs = append(errs, err)
+	}
+	if c.quotaManager != nil {
+		if err := c.quotaManager.Reset(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, err := range errs {
+		c.logger.Error("error tearing down read replica", "error", err)
+	}
+
+	if cancel, ok := c.activeContextCancelFunc.Load().(context.CancelFunc); ok && cancel != nil {
+		cancel()
+	}
+	c.postUnsealFuncs = nil
+	c.perfStandby = false
+}
+
+// invalidateRaftReadReplicaKey informs the component that caches the given
+// storage key that it has changed. The state lock must be held.
+func (c *Core) invalidateRaftReadReplicaKey(ctx context.Context, key string) {
+	switch {
+	case strings.HasPrefix(key, systemBarrierPrefix+policyACLSubPath):
+		if c.policyStore != nil {
+			c.policyStore.invalidate(ctx, strings.TrimPrefix(key, systemBarrierPrefix+policyACLSubPath), PolicyTypeACL)
+		}
+		return
+
+	case strings.HasPrefix(key, systemBarrierPrefix+quotas.StoragePrefix):
+		quotaKey := strings.TrimPrefix(key, systemBarrierPrefix+quotas.StoragePrefix)
+		if c.quotaManager != nil && (quotaKey == "config" || strings.Contains(quotaKey, "/")) {
+			c.quotaManager.Invalidate(quotaKey)
+		}
+		return
+	}
+
+	ns, mountPath, prefix, found := c.router.MatchingAPIPrefixByStoragePath(ctx, key)
+	if !found {
+		return
+	}
+	nsCtx := namespace.ContextWithNamespace(ctx, ns)
+	backend := c.router.MatchingBackend(nsCtx, mountPath)
+	if backend == nil {
+		return
+	}
+	backend.InvalidateKey(nsCtx, strings.TrimPrefix(key, prefix))
+}
+
+// raftReadReplicaShouldForward returns true if a request received by a read
+// replica has to be handled by the active node: anything that may write to
+// storage, create leases or wrap responses.
+func (c *Core) raftReadReplicaShouldForward(ctx context.Context, req *logical.Request) bool {
+	switch req.Operation {
+	case logical.ReadOperation, logical.ListOperation, logical.HelpOperation:
+	default:
+		return true
+	}
+	if req.WrapInfo != nil || c.router.LoginPath(ctx, req.Path) {
+		return true
+	}
+
+	// Let the active node record the use of tokens whose usage is due to be
+	// persisted, so that idle expiry keeps working for tokens only used here
+	if te := req.TokenEntry(); te != nil && te.Type != logical.TokenTypeBatch &&
+		time.Since(time.Unix(te.LastUsedTime, 0)) >= tokenUsageSampleInterval {
+		return true
+	}
+
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil {
+		return false
+	}
+	mountType := entry.Type
+	if mountType == "plugin" {
+		mountType = entry.Config.PluginName
+	}
+	if mountType == "kv" && entry.Options["leased_passthrough"] == "true" {
+		return true
+	}
+	return !strutil.StrListContains(raftReadReplicaLocalMountTypes, mountType)
+}
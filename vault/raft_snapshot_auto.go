@@ -0,0 +1,392 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	raftAutoSnapshotConfigPath = "core/raft/snapshot-auto/config/"
+	raftAutoSnapshotStatusPath = "core/raft/snapshot-auto/status/"
+
+	raftAutoSnapshotStorageLocal = "local"
+	raftAutoSnapshotStorageS3    = "aws-s3"
+
+	// raftAutoSnapshotTimeFormat is the format of the timestamp in the name of
+	// snapshots. It sorts lexically in chronological order.
+	raftAutoSnapshotTimeFormat = "20060102T150405.000Z"
+)
+
+// raftAutoSnapshotConfig is a schedule of raft snapshots and the storage they
+// are saved to
+type raftAutoSnapshotConfig struct {
+	Name        string        `json:"name"`
+	Interval    time.Duration `json:"interval"`
+	Retain      int           `json:"retain"`
+	PathPrefix  string        `json:"path_prefix"`
+	FilePrefix  string        `json:"file_prefix"`
+	StorageType string        `json:"storage_type"`
+
+	LocalMaxSpace int64 `json:"local_max_space,omitempty"`
+
+	AWSS3Bucket         string `json:"aws_s3_bucket,omitempty"`
+	AWSS3Region         string `json:"aws_s3_region,omitempty"`
+	AWSS3Endpoint       string `json:"aws_s3_endpoint,omitempty"`
+	AWSS3ForcePathStyle bool   `json:"aws_s3_force_path_style,omitempty"`
+	AWSS3DisableTLS     bool   `json:"aws_s3_disable_tls,omitempty"`
+	AWSAccessKeyID      string `json:"aws_access_key_id,omitempty"`
+	AWSSecretAccessKey  string `json:"aws_secret_access_key,omitempty"`
+	AWSSessionToken     string `json:"aws_session_token,omitempty"`
+
+	AWSS3EnableKMS            bool   `json:"aws_s3_enable_kms,omitempty"`
+	AWSS3KMSKey               string `json:"aws_s3_kms_key,omitempty"`
+	AWSS3ServerSideEncryption bool   `json:"aws_s3_server_side_encryption,omitempty"`
+}
+
+func (c *raftAutoSnapshotConfig) validate() error {
+	switch {
+	case c.Interval <= 0:
+		return errors.New("interval must be set")
+	case c.Retain < 1:
+		return errors.New("retain must be at least 1")
+	case c.FilePrefix == "" || strings.Contains(c.FilePrefix, "/"):
+		return errors.New("file_prefix must be set and cannot contain a slash")
+	}
+
+	switch c.StorageType {
+	case raftAutoSnapshotStorageLocal:
+		if c.PathPrefix == "" {
+			return errors.New("path_prefix must be set for local storage")
+		}
+		if c.LocalMaxSpace < 0 {
+			return errors.New("local_max_space cannot be negative")
+		}
+	case raftAutoSnapshotStorageS3:
+		if c.AWSS3Bucket == "" {
+			return errors.New("aws_s3_bucket must be set for aws-s3 storage")
+		}
+		if c.AWSS3EnableKMS && c.AWSS3ServerSideEncryption {
+			return errors.New("aws_s3_enable_kms and aws_s3_server_side_encryption are mutually exclusive")
+		}
+	default:
+		return fmt.Errorf("unknown storage_type %q", c.StorageType)
+	}
+	return nil
+}
+
+// raftAutoSnapshotStatus is the outcome of the snapshots of a configuration
+type raftAutoSnapshotStatus struct {
+	ConsecutiveErrors int       `json:"consecutive_errors"`
+	LastSnapshotStart time.Time `json:"last_snapshot_start"`
+	LastSnapshotEnd   time.Time `json:"last_snapshot_end"`
+	LastSnapshotError string    `json:"last_snapshot_error,omitempty"`
+	LastSnapshotURL   string    `json:"last_snapshot_url,omitempty"`
+	LastSuccessTime   time.Time `json:"last_success_time"`
+	LastSuccessURL    string    `json:"last_success_url,omitempty"`
+}
+
+// raftAutoSnapshots runs the snapshot configurations on the active node
+type raftAutoSnapshots struct {
+	core   *Core
+	logger log.Logger
+	ctx    context.Context
+
+	lock    sync.Mutex
+	runners map[string]*raftAutoSnapshotRunner
+	wg      sync.WaitGroup
+}
+
+type raftAutoSnapshotRunner struct {
+	config *raftAutoSnapshotConfig
+	stopCh chan struct{}
+
+	lock      sync.Mutex
+	nextStart time.Time
+}
+
+func (r *raftAutoSnapshotRunner) next() time.Time {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.nextStart
+}
+
+// startRaftAutoSnapshots starts running the snapshot configurations. This is
+// a no-op unless raft is the storage backend.
+func (c *Core) startRaftAutoSnapshots(ctx context.Context) error {
+	if _, ok := c.underlyingPhysical.(*raft.RaftBackend); !ok {
+		return nil
+	}
+
+	s := &raftAutoSnapshots{
+		core:    c,
+		logger:  c.logger.Named("raft-snapshot-auto"),
+		ctx:     c.activeContext,
+		runners: make(map[string]*raftAutoSnapshotRunner),
+	}
+
+	names, err := c.barrier.List(ctx, raftAutoSnapshotConfigPath)
+	if err != nil {
+		return fmt.Errorf("failed to list raft snapshot configurations: %w", err)
+	}
+	for _, name := range names {
+		config, err := c.loadRaftAutoSnapshotConfig(ctx, name)
+		if err != nil {
+			return err
+		}
+		if config != nil {
+			s.update(config)
+		}
+	}
+
+	c.raftAutoSnapshots = s
+	return nil
+}
+
+func (c *Core) stopRaftAutoSnapshots() {
+	if c.raftAutoSnapshots == nil {
+		return
+	}
+
+	c.raftAutoSnapshots.stop()
+	c.raftAutoSnapshots = nil
+}
+
+func (c *Core) loadRaftAutoSnapshotConfig(ctx context.Context, name string) (*raftAutoSnapshotConfig, error) {
+	entry, err := c.barrier.Get(ctx, raftAutoSnapshotConfigPath+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raft snapshot configuration: %w", err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var config raftAutoSnapshotConfig
+	if err := entry.DecodeJSON(&config); err != nil {
+		return nil, fmt.Errorf("failed to decode raft snapshot configuration: %w", err)
+	}
+	return &config, nil
+}
+
+func (c *Core) loadRaftAutoSnapshotStatus(ctx context.Context, name string) (*raftAutoSnapshotStatus, error) {
+	entry, err := c.barrier.Get(ctx, raftAutoSnapshotStatusPath+name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read raft snapshot status: %w", err)
+	}
+
+	var status raftAutoSnapshotStatus
+	if entry != nil {
+		if err := entry.DecodeJSON(&status); err != nil {
+			return nil, fmt.Errorf("failed to decode raft snapshot status: %w", err)
+		}
+	}
+	return &status, nil
+}
+
+// update starts running the configuration, replacing its previous version
+func (s *raftAutoSnapshots) update(config *raftAutoSnapshotConfig) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if r, ok := s.runners[config.Name]; ok {
+		close(r.stopCh)
+	}
+
+	r := &raftAutoSnapshotRunner{
+		config: config,
+		stopCh: make(chan struct{}),
+	}
+	s.runners[config.Name] = r
+
+	s.wg.Add(1)
+	go s.run(r)
+}
+
+// remove stops running the configuration
+func (s *raftAutoSnapshots) remove(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if r, ok := s.runners[name]; ok {
+		close(r.stopCh)
+		delete(s.runners, name)
+	}
+}
+
+// nextStart returns when the next snapshot of the configuration is due
+func (s *raftAutoSnapshots) nextStart(name string) time.Time {
+	s.lock.Lock()
+	r, ok := s.runners[name]
+	s.lock.Unlock()
+
+	if !ok {
+		return time.Time{}
+	}
+	return r.next()
+}
+
+func (s *raftAutoSnapshots) stop() {
+	s.lock.Lock()
+	for name, r := range s.runners {
+		close(r.stopCh)
+		delete(s.runners, name)
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+}
+
+// run takes a snapshot every interval. The schedule is based on the start of
+// the last snapshot, so that it carries over when the active node changes.
+func (s *raftAutoSnapshots) run(r *raftAutoSnapshotRunner) {
+	defer s.wg.Done()
+
+	logger := s.logger.With("name", r.config.Name)
+	for {
+		status, err := s.core.loadRaftAutoSnapshotStatus(s.ctx, r.config.Name)
+		if err != nil {
+			logger.Error("failed to load status", "error", err)
+			status = &raftAutoSnapshotStatus{}
+		}
+
+		next := time.Now()
+		if !status.LastSnapshotStart.IsZero() {
+			if due := status.LastSnapshotStart.Add(r.config.Interval); due.After(next) {
+				next = due
+			}
+		}
+		r.lock.Lock()
+		r.nextStart = next
+		r.lock.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-r.stopCh:
+			timer.Stop()
+			return
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		status.LastSnapshotStart = time.Now().UTC()
+		url, err := s.snapshot(s.ctx, r.config, status.LastSnapshotStart)
+		status.LastSnapshotEnd = time.Now().UTC()
+		status.LastSnapshotURL = url
+		if err != nil {
+			logger.Error("failed to take snapshot", "error", err)
+			status.ConsecutiveErrors++
+			status.LastSnapshotError = err.Error()
+		} else {
+			logger.Debug("took snapshot", "url", url)
+			status.ConsecutiveErrors = 0
+			status.LastSnapshotError = ""
+			status.LastSuccessTime = status.LastSnapshotEnd
+			status.LastSuccessURL = url
+		}
+
+		// Do not record the outcome of a snapshot whose configuration changed
+		// or was removed in the meantime
+		select {
+		case <-r.stopCh:
+			return
+		default:
+		}
+
+		entry, err := logical.StorageEntryJSON(raftAutoSnapshotStatusPath+r.config.Name, status)
+		if err == nil {
+			err = s.core.barrier.Put(s.ctx, entry)
+		}
+		if err != nil {
+			logger.Error("failed to persist status", "error", err)
+		}
+	}
+}
+
+// snapshot takes a snapshot, saves it to the storage of the configuration and
+// removes the snapshots beyond the retained count. It returns the URL of the
+// snapshot.
+func (s *raftAutoSnapshots) snapshot(ctx context.Context, config *raftAutoSnapshotConfig, start time.Time) (string, error) {
+	raftBackend, ok := s.core.underlyingPhysical.(*raft.RaftBackend)
+	if !ok {
+		return "", errors.New("raft storage is not in use")
+	}
+
+	target, err := newRaftSnapshotTarget(config, s.core.raftSnapshotDirectory, s.logger)
+	if err != nil {
+		return "", err
+	}
+
+	// Buffer the snapshot to a temporary file so that it can be uploaded with
+	// its size known
+	f, err := ioutil.TempFile("", "vault-raft-snapshot")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+	}()
+
+	if err := raftBackend.Snapshot(f, s.core.seal.GetAccess()); err != nil {
+		return "", fmt.Errorf("failed to take snapshot: %w", err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s.snap", config.FilePrefix, start.Format(raftAutoSnapshotTimeFormat))
+	url, err := target.Put(ctx, name, f)
+	if err != nil {
+		return "", fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	if err := pruneRaftSnapshots(ctx, target, config); err != nil {
+		return url, fmt.Errorf("failed to remove old snapshots: %w", err)
+	}
+	return url, nil
+}
+
+// pruneRaftSnapshots removes the oldest snapshots of the configuration beyond
+// the retained count
+func pruneRaftSnapshots(ctx context.Context, target raftSnapshotTarget, config *raftAutoSnapshotConfig) error {
+	names, err := target.List(ctx, config.FilePrefix+"-")
+	if err != nil {
+		return err
+	}
+
+	snapshots := raftSnapshotNames(names, config.FilePrefix)
+	for len(snapshots) > config.Retain {
+		if err := target.Delete(ctx, snapshots[0]); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+	return nil
+}
+
+// raftSnapshotNames returns the names of the snapshots with the file prefix
+// among names, from the oldest to the newest
+func raftSnapshotNames(names []string, filePrefix string) []string {
+	var snapshots []string
+	for _, name := range names {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, filePrefix+"-"), ".snap")
+		if _, err := time.Parse(raftAutoSnapshotTimeFormat, stamp); err != nil || !strings.HasSuffix(name, ".snap") {
+			continue
+		}
+		snapshots = append(snapshots, name)
+	}
+	sort.Strings(snapshots)
+	return snapshots
+}
@@ -0,0 +1,291 @@
+package vault
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/plugin/pb"
+)
+
+// raftSubtreeRestore is the restore of the keys of a mount from a snapshot
+type raftSubtreeRestore struct {
+	Path         string `json:"path"`
+	Type         string `json:"type"`
+	KeysRestored int    `json:"keys_restored"`
+	KeysDeleted  int    `json:"keys_deleted"`
+
+	// snapshotPrefix is the storage prefix of the mount in the snapshot, and
+	// prefix its current one. They differ if the mount was enabled again
+	// since the snapshot.
+	snapshotPrefix string
+	prefix         string
+	keys           map[string]struct{}
+}
+
+// errRaftSubtreeRestoreInProgress is returned for the writes to a mount while
+// it is restored from a snapshot
+var errRaftSubtreeRestoreInProgress = errors.New("cannot write to the mount while it is restored from a snapshot")
+
+// raftSnapshotData is the FSM data of a snapshot, which is read several times
+type raftSnapshotData interface {
+	io.Reader
+	io.Seeker
+}
+
+// restoreRaftSnapshotSubtree restores the keys of a mount, or of all the
+// mounts of a namespace, from the FSM data of a snapshot. Exactly one of
+// mountPath and ns must be set. The mounts must exist both in the snapshot and
+// currently, and the snapshot must have been taken with a keyring whose terms
+// are still in the current keyring: snapshots taken before their terms were
+// removed with sys/rotate/prune cannot be restored.
+//
+// All the keys of the mounts are decrypted with the barrier before any is
+// written, so that such a snapshot fails before the mounts are changed. They
+// are then written again under the current storage prefix of the mounts, the
+// keys missing from the snapshot are deleted, and the mounts are reloaded.
+// The barrier does not support transactions, so the mounts are read-only
+// until they are reloaded instead. Leases and tokens are not restored.
+func (c *Core) restoreRaftSnapshotSubtree(ctx context.Context, data raftSnapshotData, mountPath string, ns *namespace.Namespace) ([]*raftSubtreeRestore, []string, error) {
+	snapshotMounts, err := c.raftSnapshotMountEntries(ctx, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var restores []*raftSubtreeRestore
+	var warnings []string
+	currentMounts := c.raftSubtreeMountEntries()
+	switch {
+	case mountPath != "":
+		current, ok := currentMounts[mountPath]
+		if !ok {
+			return nil, nil, fmt.Errorf("no mount at %q", mountPath)
+		}
+		if strutil.StrListContains(singletonMounts, current.Type) {
+			return nil, nil, fmt.Errorf("mounts of type %q cannot be restored", current.Type)
+		}
+		restore, err := newRaftSubtreeRestore(mountPath, current, snapshotMounts[mountPath])
+		if err != nil {
+			return nil, nil, err
+		}
+		restores = append(restores, restore)
+
+	case ns != nil:
+		var paths []string
+		for path, current := range currentMounts {
+			if current.NamespaceID == ns.ID && !strutil.StrListContains(singletonMounts, current.Type) {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			restore, err := newRaftSubtreeRestore(path, currentMounts[path], snapshotMounts[path])
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("%s; it was not restored", err))
+				continue
+			}
+			restores = append(restores, restore)
+		}
+
+		for path, entry := range snapshotMounts {
+			if _, ok := currentMounts[path]; !ok && entry.NamespaceID == ns.ID && !strutil.StrListContains(singletonMounts, entry.Type) {
+				warnings = append(warnings, fmt.Sprintf("mount %q of the snapshot does not exist; enable it to restore it", path))
+			}
+		}
+		sort.Strings(warnings)
+
+	default:
+		return nil, nil, errors.New("no mount or namespace to restore")
+	}
+
+	if len(restores) == 0 {
+		return nil, warnings, nil
+	}
+
+	// Decrypt all the keys before writing any, so that a snapshot that cannot
+	// be decrypted leaves the mounts untouched
+	err = c.readRaftSnapshotSubtrees(ctx, data, restores, func(restore *raftSubtreeRestore, key string, value []byte) error {
+		restore.keys[key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Block the writes to the mounts until they are reloaded, so that keys
+	// written during the restore are neither mixed with the restored ones nor
+	// served from stale state
+	for _, restore := range restores {
+		view, ok := c.router.MatchingStorageByAPIPath(namespace.RootContext(ctx), restore.Path).(*BarrierView)
+		if !ok {
+			return nil, nil, fmt.Errorf("no storage for mount %q", restore.Path)
+		}
+		origReadOnlyErr := view.getReadOnlyErr()
+		view.setReadOnlyErr(errRaftSubtreeRestoreInProgress)
+		defer view.setReadOnlyErr(origReadOnlyErr)
+	}
+
+	err = c.readRaftSnapshotSubtrees(ctx, data, restores, func(restore *raftSubtreeRestore, key string, value []byte) error {
+		restore.KeysRestored++
+		return c.barrier.Put(ctx, &logical.StorageEntry{
+			Key:   restore.prefix + key,
+			Value: value,
+		})
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to restore keys: %w", err)
+	}
+
+	var reloadPaths []string
+	for _, restore := range restores {
+		keys, err := logical.CollectKeys(ctx, NewBarrierView(c.barrier, restore.prefix))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, key := range keys {
+			if _, ok := restore.keys[key]; ok {
+				continue
+			}
+			if err := c.barrier.Delete(ctx, restore.prefix+key); err != nil {
+				return nil, nil, fmt.Errorf("failed to delete keys: %w", err)
+			}
+			restore.KeysDeleted++
+		}
+
+		reloadPaths = append(reloadPaths, restore.Path)
+		c.logger.Info("restored mount from snapshot", "path", restore.Path, "keys_restored", restore.KeysRestored, "keys_deleted", restore.KeysDeleted)
+	}
+
+	// Reload the mounts so that they do not serve stale cached state
+	if err := c.reloadMatchingPluginMounts(ctx, reloadPaths); err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to reload the restored mounts: %s", err))
+	}
+
+	return restores, warnings, nil
+}
+
+func newRaftSubtreeRestore(path string, current, snapshot *MountEntry) (*raftSubtreeRestore, error) {
+	switch {
+	case snapshot == nil:
+		return nil, fmt.Errorf("mount %q does not exist in the snapshot", path)
+	case snapshot.Type != current.Type:
+		return nil, fmt.Errorf("mount %q is of type %q in the snapshot but of type %q currently", path, snapshot.Type, current.Type)
+	}
+
+	return &raftSubtreeRestore{
+		Path:           path,
+		Type:           current.Type,
+		snapshotPrefix: snapshot.ViewPath(),
+		prefix:         current.ViewPath(),
+		keys:           make(map[string]struct{}),
+	}, nil
+}
+
+// raftSubtreeMountEntries returns the current secrets and auth mounts by
+// their route path
+func (c *Core) raftSubtreeMountEntries() map[string]*MountEntry {
+	entries := make(map[string]*MountEntry)
+
+	c.mountsLock.RLock()
+	for _, entry := range c.mounts.Entries {
+		entries[entry.Namespace().Path+entry.Path] = entry
+	}
+	c.mountsLock.RUnlock()
+
+	c.authLock.RLock()
+	for _, entry := range c.auth.Entries {
+		entries[entry.Namespace().Path+credentialRoutePrefix+entry.Path] = entry
+	}
+	c.authLock.RUnlock()
+
+	return entries
+}
+
+// raftSnapshotMountEntries returns the secrets and auth mounts of the snapshot
+// by their route path
+func (c *Core) raftSnapshotMountEntries(ctx context.Context, data raftSnapshotData) (map[string]*MountEntry, error) {
+	tables := map[string]string{
+		coreMountConfigPath:      "",
+		coreLocalMountConfigPath: "",
+		coreAuthConfigPath:       credentialRoutePrefix,
+		coreLocalAuthConfigPath:  credentialRoutePrefix,
+	}
+
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*MountEntry)
+	err := raft.ReadSnapshot(data, func(entry *pb.StorageEntry) error {
+		routePrefix, ok := tables[entry.Key]
+		if !ok {
+			return nil
+		}
+
+		plaintext, err := c.decryptRaftSnapshotValue(ctx, entry.Key, entry.Value)
+		if err != nil {
+			return err
+		}
+		table, err := c.decodeMountTable(ctx, plaintext)
+		if err != nil {
+			return fmt.Errorf("failed to decode the mount table %q of the snapshot: %w", entry.Key, err)
+		}
+		for _, mountEntry := range table.Entries {
+			entries[mountEntry.Namespace().Path+routePrefix+mountEntry.Path] = mountEntry
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the mounts of the snapshot: %w", err)
+	}
+	return entries, nil
+}
+
+// readRaftSnapshotSubtrees calls fn with the decrypted keys of the snapshot
+// that are under the storage prefix of the restores. The key is relative to
+// the prefix.
+func (c *Core) readRaftSnapshotSubtrees(ctx context.Context, data raftSnapshotData, restores []*raftSubtreeRestore, fn func(*raftSubtreeRestore, string, []byte) error) error {
+	if _, err := data.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return raft.ReadSnapshot(data, func(entry *pb.StorageEntry) error {
+		for _, restore := range restores {
+			if !strings.HasPrefix(entry.Key, restore.snapshotPrefix) {
+				continue
+			}
+
+			plaintext, err := c.decryptRaftSnapshotValue(ctx, entry.Key, entry.Value)
+			if err != nil {
+				return err
+			}
+			return fn(restore, strings.TrimPrefix(entry.Key, restore.snapshotPrefix), plaintext)
+		}
+		return nil
+	})
+}
+
+func (c *Core) decryptRaftSnapshotValue(ctx context.Context, key string, value []byte) ([]byte, error) {
+	if len(value) < 4 {
+		return nil, fmt.Errorf("failed to decrypt %q of the snapshot: invalid value", key)
+	}
+
+	plaintext, err := c.barrier.Decrypt(ctx, key, value)
+	if err != nil {
+		term := binary.BigEndian.Uint32(value[:4])
+		if keyring, kerr := c.barrier.Keyring(); kerr == nil && term < keyring.ActiveTerm() && keyring.TermKey(term) == nil {
+			return nil, fmt.Errorf("failed to decrypt %q of the snapshot: its key term %d is no longer in the keyring, snapshots taken before the term was removed with sys/rotate/prune cannot be restored", key, term)
+		}
+		return nil, fmt.Errorf("failed to decrypt %q of the snapshot, it may have been taken with another keyring: %w", key, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,310 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/go-cleanhttp"
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/helper/awsutil"
+)
+
+// raftSnapshotTarget is the storage scheduled raft snapshots are saved to
+type raftSnapshotTarget interface {
+	// Put saves the snapshot read from r under the name and returns its URL
+	Put(ctx context.Context, name string, r io.ReadSeeker) (string, error)
+
+	// List returns the names of the saved snapshots starting with the prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+
+	// Delete removes the snapshot with the name
+	Delete(ctx context.Context, name string) error
+}
+
+// newRaftSnapshotTarget returns the storage of the configuration. Local
+// snapshots are saved within localDir.
+func newRaftSnapshotTarget(config *raftAutoSnapshotConfig, localDir string, logger log.Logger) (raftSnapshotTarget, error) {
+	switch config.StorageType {
+	case raftAutoSnapshotStorageLocal:
+		dir, err := raftSnapshotLocalDir(localDir, config.PathPrefix)
+		if err != nil {
+			return nil, err
+		}
+		return &raftSnapshotLocalTarget{
+			dir:        dir,
+			filePrefix: config.FilePrefix,
+			retain:     config.Retain,
+			maxSpace:   config.LocalMaxSpace,
+		}, nil
+	case raftAutoSnapshotStorageS3:
+		return newRaftSnapshotS3Target(config, logger)
+	default:
+		return nil, fmt.Errorf("unknown storage_type %q", config.StorageType)
+	}
+}
+
+// raftSnapshotLocalDir returns the directory the local snapshots with the
+// path prefix are saved to. It must be within the directory allowed by the
+// server configuration, so that API callers cannot write anywhere else.
+func raftSnapshotLocalDir(base, pathPrefix string) (string, error) {
+	if base == "" {
+		return "", errors.New("local storage requires raft_snapshot_directory to be set in the server configuration")
+	}
+
+	dir := pathPrefix
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	rel, err := filepath.Rel(base, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path_prefix must be within the raft snapshot directory %q", base)
+	}
+	return dir, nil
+}
+
+// raftSnapshotLocalTarget saves snapshots to a directory of the active node.
+// If maxSpace is set, the snapshots with filePrefix cannot use more than
+// maxSpace bytes once pruned to the retained count.
+type raftSnapshotLocalTarget struct {
+	dir        string
+	filePrefix string
+	retain     int
+	maxSpace   int64
+}
+
+func (t *raftSnapshotLocalTarget) Put(_ context.Context, name string, r io.ReadSeeker) (string, error) {
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return "", err
+	}
+
+	if t.maxSpace > 0 {
+		size, err := r.Seek(0, io.SeekEnd)
+		if err != nil {
+			return "", err
+		}
+		if _, err := r.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+
+		used, err := t.usedSpace()
+		if err != nil {
+			return "", err
+		}
+		if used+size > t.maxSpace {
+			return "", fmt.Errorf("snapshot of %d bytes exceeds local_max_space, %d of %d bytes are used", size, used, t.maxSpace)
+		}
+	}
+
+	// Write to a temporary file first so that a partial snapshot is never
+	// left under its final name
+	f, err := ioutil.TempFile(t.dir, name+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(t.dir, name)
+	if err := os.Rename(f.Name(), dst); err != nil {
+		return "", err
+	}
+	return "file://" + filepath.ToSlash(dst), nil
+}
+
+// usedSpace returns the size of the saved snapshots that are kept along with
+// a new one. The oldest snapshots beyond the retained count are removed once
+// the new one is saved, so their space is considered free.
+func (t *raftSnapshotLocalTarget) usedSpace() (int64, error) {
+	entries, err := ioutil.ReadDir(t.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	sizes := make(map[string]int64, len(entries))
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			sizes[entry.Name()] = entry.Size()
+			names = append(names, entry.Name())
+		}
+	}
+
+	snapshots := raftSnapshotNames(names, t.filePrefix)
+	if kept := t.retain - 1; len(snapshots) > kept {
+		snapshots = snapshots[len(snapshots)-kept:]
+	}
+
+	var used int64
+	for _, name := range snapshots {
+		used += sizes[name]
+	}
+	return used, nil
+}
+
+func (t *raftSnapshotLocalTarget) List(_ context.Context, prefix string) ([]string, error) {
+	entries, err := ioutil.ReadDir(t.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+func (t *raftSnapshotLocalTarget) Delete(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(t.dir, name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// raftSnapshotS3Target saves snapshots to an S3 bucket, or to any storage
+// compatible with the S3 API given its endpoint
+type raftSnapshotS3Target struct {
+	client *s3.S3
+	bucket string
+	prefix string
+
+	serverSideEncryption string
+	kmsKey               string
+}
+
+func newRaftSnapshotS3Target(config *raftAutoSnapshotConfig, logger log.Logger) (*raftSnapshotS3Target, error) {
+	credsConfig := &awsutil.CredentialsConfig{
+		AccessKey:    config.AWSAccessKeyID,
+		SecretKey:    config.AWSSecretAccessKey,
+		SessionToken: config.AWSSessionToken,
+		Logger:       logger,
+	}
+	creds, err := credsConfig.GenerateCredentialChain()
+	if err != nil {
+		return nil, err
+	}
+
+	region := config.AWSS3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	awsConfig := &aws.Config{
+		Credentials: creds,
+		HTTPClient: &http.Client{
+			Transport: cleanhttp.DefaultPooledTransport(),
+		},
+		Region:           aws.String(region),
+		S3ForcePathStyle: aws.Bool(config.AWSS3ForcePathStyle),
+		DisableSSL:       aws.Bool(config.AWSS3DisableTLS),
+	}
+	if config.AWSS3Endpoint != "" {
+		awsConfig.Endpoint = aws.String(config.AWSS3Endpoint)
+	}
+
+	sess, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &raftSnapshotS3Target{
+		client: s3.New(sess),
+		bucket: config.AWSS3Bucket,
+		prefix: strings.Trim(config.PathPrefix, "/"),
+	}
+	switch {
+	case config.AWSS3EnableKMS:
+		target.serverSideEncryption = s3.ServerSideEncryptionAwsKms
+		target.kmsKey = config.AWSS3KMSKey
+	case config.AWSS3ServerSideEncryption:
+		target.serverSideEncryption = s3.ServerSideEncryptionAes256
+	}
+	return target, nil
+}
+
+func (t *raftSnapshotS3Target) key(name string) string {
+	if t.prefix == "" {
+		return name
+	}
+	return path.Join(t.prefix, name)
+}
+
+func (t *raftSnapshotS3Target) Put(ctx context.Context, name string, r io.ReadSeeker) (string, error) {
+	key := t.key(name)
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	}
+	if t.serverSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(t.serverSideEncryption)
+	}
+	if t.kmsKey != "" {
+		input.SSEKMSKeyId = aws.String(t.kmsKey)
+	}
+
+	if _, err := t.client.PutObjectWithContext(ctx, input); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", t.bucket, key), nil
+}
+
+func (t *raftSnapshotS3Target) List(ctx context.Context, prefix string) ([]string, error) {
+	keyPrefix := t.key(prefix)
+
+	var names []string
+	err := t.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(t.bucket),
+		Prefix: aws.String(keyPrefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, obj := range page.Contents {
+			name := strings.TrimPrefix(aws.StringValue(obj.Key), strings.TrimSuffix(keyPrefix, prefix))
+			if !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+func (t *raftSnapshotS3Target) Delete(ctx context.Context, name string) error {
+	_, err := t.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(t.key(name)),
+	})
+	return err
+}
@@ -0,0 +1,98 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRaftSnapshotLocalDir(t *testing.T) {
+	base := filepath.Join(os.TempDir(), "snapshots")
+
+	cases := map[string]string{
+		"":                                "",
+		"daily":                           "daily",
+		"daily/../hourly":                 "hourly",
+		base:                              "",
+		filepath.Join(base, "daily"):      "daily",
+		"..":                              "-",
+		"../snapshots-other":              "-",
+		filepath.Join(base, "..", "etc"):  "-",
+		filepath.Dir(base):                "-",
+		filepath.Join(base, "../../root"): "-",
+	}
+	for pathPrefix, expected := range cases {
+		dir, err := raftSnapshotLocalDir(base, pathPrefix)
+		if expected == "-" {
+			if err == nil {
+				t.Fatalf("%q: expected an error, got %q", pathPrefix, dir)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: err: %v", pathPrefix, err)
+		}
+		if dir != filepath.Join(base, expected) {
+			t.Fatalf("%q: bad: %q", pathPrefix, dir)
+		}
+	}
+
+	if _, err := raftSnapshotLocalDir("", "daily"); err == nil {
+		t.Fatal("expected an error without a raft snapshot directory")
+	}
+}
+
+func TestRaftSnapshotLocalTarget_MaxSpace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vault-raft-snapshot-target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	config := &raftAutoSnapshotConfig{
+		Retain:      2,
+		FilePrefix:  "test",
+		StorageType: raftAutoSnapshotStorageLocal,
+	}
+	target := &raftSnapshotLocalTarget{
+		dir:        dir,
+		filePrefix: config.FilePrefix,
+		retain:     config.Retain,
+		maxSpace:   25,
+	}
+	snapshot := bytes.Repeat([]byte("a"), 10)
+
+	// The snapshots to be pruned once the next one is saved do not count
+	// towards the space allowance, so the schedule keeps going
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("test-%s.snap", start.Add(time.Duration(i)*time.Second).Format(raftAutoSnapshotTimeFormat))
+		if _, err := target.Put(ctx, name, bytes.NewReader(snapshot)); err != nil {
+			t.Fatalf("snapshot %d: err: %v", i, err)
+		}
+		if err := pruneRaftSnapshots(ctx, target, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := target.List(ctx, "test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Fatalf("expected 2 snapshots, got: %v", names)
+	}
+
+	// A snapshot that does not fit along with the retained ones fails
+	name := fmt.Sprintf("test-%s.snap", start.Add(time.Minute).Format(raftAutoSnapshotTimeFormat))
+	_, err = target.Put(ctx, name, bytes.NewReader(bytes.Repeat([]byte("a"), 16)))
+	if err == nil || !strings.Contains(err.Error(), "exceeds local_max_space") {
+		t.Fatalf("expected an error, got: %v", err)
+	}
+}
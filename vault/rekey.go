@@ -189,6 +189,9 @@ func (c *Core) BarrierRekeyInit(config *SealConfig) logical.HTTPCodedError {
 		if len(config.PGPKeys) > 0 {
 			return logical.CodedError(http.StatusBadRequest, "PGP key encryption not supported when using stored keys")
 		}
+		if config.SignedUnseal {
+			return logical.CodedError(http.StatusBadRequest, "signed unseal not supported when using stored keys")
+		}
 		if config.Backup {
 			return logical.CodedError(http.StatusBadRequest, "key backup not supported when using stored keys")
 		}
@@ -246,6 +249,9 @@ func (c *Core) RecoveryRekeyInit(config *SealConfig) logical.HTTPCodedError {
 	if config.StoredShares > 0 {
 		return logical.CodedError(http.StatusBadRequest, "stored shares not supported by recovery key")
 	}
+	if config.SignedUnseal {
+		return logical.CodedError(http.StatusBadRequest, "signed unseal not supported by recovery key")
+	}
 
 	// Check if the seal configuration is valid
 	if err := config.Validate(); err != nil {
@@ -75,7 +75,14 @@ func (s *forwardedRequestRPCServer) Echo(ctx context.Context, in *EchoRequest) (
 	}
 
 	if in.RaftAppliedIndex > 0 && len(in.RaftNodeID) > 0 && s.raftFollowerStates != nil {
-		s.raftFollowerStates.Update(in.RaftNodeID, in.RaftAppliedIndex, in.RaftTerm, in.RaftDesiredSuffrage)
+		s.raftFollowerStates.Update(&raft.EchoRequestUpdate{
+			NodeID:          in.RaftNodeID,
+			AppliedIndex:    in.RaftAppliedIndex,
+			Term:            in.RaftTerm,
+			DesiredSuffrage: in.RaftDesiredSuffrage,
+			UpgradeVersion:  in.RaftUpgradeVersion,
+			RedundancyZone:  in.RaftRedundancyZone,
+		})
 	}
 
 	reply := &EchoReply{
@@ -117,6 +124,8 @@ func (c *forwardingClient) startHeartbeat() {
 				req.RaftNodeID = raftBackend.NodeID()
 				req.RaftTerm = raftBackend.Term()
 				req.RaftDesiredSuffrage = raftBackend.DesiredSuffrage()
+				req.RaftUpgradeVersion = raftBackend.EffectiveVersion()
+				req.RaftRedundancyZone = raftBackend.RedundancyZone()
 			}
 
 			ctx, cancel := context.WithTimeout(c.echoContext, 2*time.Second)
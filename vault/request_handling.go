@@ -12,9 +12,11 @@ import (
 	"github.com/hashicorp/errwrap"
 	multierror "github.com/hashicorp/go-multierror"
 	sockaddr "github.com/hashicorp/go-sockaddr"
+	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/helper/identity"
 	"github.com/hashicorp/vault/helper/metricsutil"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/helper/tracing"
 	"github.com/hashicorp/vault/internalshared/configutil"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/consts"
@@ -25,6 +27,8 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/wrapping"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/vault/quotas"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	uberAtomic "go.uber.org/atomic"
 )
 
@@ -229,9 +233,19 @@ func (c *Core) fetchACLTokenEntryAndEntity(ctx context.Context, req *logical.Req
 	return acl, te, entity, identityPolicies, nil
 }
 
-func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool) (*logical.Auth, *logical.TokenEntry, error) {
+func (c *Core) checkToken(ctx context.Context, req *logical.Request, unauth bool) (_ *logical.Auth, _ *logical.TokenEntry, retErr error) {
 	defer metrics.MeasureSince([]string{"core", "check_token"}, time.Now())
 
+	ctx, span := tracing.StartSpan(ctx, "acl.check_token", trace.WithAttributes(
+		attribute.String("vault.operation", string(req.Operation)),
+	))
+	defer func() { tracing.EndSpan(span, retErr) }()
+	if span.IsRecording() {
+		// The request path is not recorded, as it may hold sensitive data
+		// which the audit log only records HMAC-ed
+		span.SetAttributes(attribute.String("vault.mount_point", c.router.MatchingMount(ctx, req.Path)))
+	}
+
 	var acl *ACL
 	var te *logical.TokenEntry
 	var entity *identity.Entity
@@ -423,7 +437,18 @@ func (c *Core) switchedLockHandleRequest(httpCtx context.Context, req *logical.R
 	}
 	ctx = namespace.ContextWithNamespace(ctx, ns)
 
+	// Raft read replicas only serve requests without side effects
+	if c.perfStandby && c.raftReadReplicaShouldForward(ctx, req) {
+		cancel()
+		return nil, logical.ErrPerfStandbyPleaseForward
+	}
+
 	resp, err = c.handleCancelableRequest(ctx, ns, req)
+	if err != nil && c.perfStandby && errwrap.Contains(err, logical.ErrReadOnly.Error()) {
+		// The request turned out to need a write, which only the active node
+		// can do
+		resp, err = nil, logical.ErrPerfStandbyPleaseForward
+	}
 
 	req.SetTokenEntry(nil)
 	cancel()
@@ -460,11 +485,14 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 	walState := &logical.WALState{}
 	ctx = logical.IndexStateContext(ctx, walState)
 	var auth *logical.Auth
+	start := time.Now()
 	if c.router.LoginPath(ctx, req.Path) {
 		resp, auth, err = c.handleLoginRequest(ctx, req)
 	} else {
 		resp, auth, err = c.handleRequest(ctx, req)
 	}
+	duration := time.Since(start)
+	errClass := requestErrorClass(req, resp, err)
 
 	// Ensure we don't leak internal data
 	if resp != nil {
@@ -531,12 +559,21 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 
 	var nonHMACReqDataKeys []string
 	var nonHMACRespDataKeys []string
+	var auditFieldSelectors []*audit.FieldSelector
 	entry := c.router.MatchingMountEntry(ctx, req.Path)
 	if entry != nil {
+		c.emitMountRequestMetrics(entry, req, duration, errClass)
+		if errClass == "" {
+			c.publishSecretEvent(entry, req)
+		}
+
 		// Get and set ignored HMAC'd value. Reset those back to empty afterwards.
 		if rawVals, ok := entry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
 			nonHMACReqDataKeys = rawVals.([]string)
 		}
+		if rawVals, ok := entry.synthesizedConfigCache.Load("audit_field_selectors_parsed"); ok {
+			auditFieldSelectors = rawVals.([]*audit.FieldSelector)
+		}
 
 		// Get and set ignored HMAC'd value. Reset those back to empty afterwards.
 		if auditResp != nil {
@@ -558,6 +595,7 @@ func (c *Core) handleCancelableRequest(ctx context.Context, ns *namespace.Namesp
 				OuterErr:            err,
 				NonHMACReqDataKeys:  nonHMACReqDataKeys,
 				NonHMACRespDataKeys: nonHMACRespDataKeys,
+				AuditFieldSelectors: auditFieldSelectors,
 			}
 			if auditErr := c.auditBroker.LogResponse(ctx, logInput, c.auditedHeaders); auditErr != nil {
 				c.logger.Error("failed to audit response", "request_path", req.Path, "error", auditErr)
@@ -607,6 +645,7 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	defer metrics.MeasureSince([]string{"core", "handle_request"}, time.Now())
 
 	var nonHMACReqDataKeys []string
+	var auditFieldSelectors []*audit.FieldSelector
 	entry := c.router.MatchingMountEntry(ctx, req.Path)
 	if entry != nil {
 		// Set here so the audit log has it even if authorization fails
@@ -615,6 +654,9 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 		if rawVals, ok := entry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
 			nonHMACReqDataKeys = rawVals.([]string)
 		}
+		if rawVals, ok := entry.synthesizedConfigCache.Load("audit_field_selectors_parsed"); ok {
+			auditFieldSelectors = rawVals.([]*audit.FieldSelector)
+		}
 	}
 
 	ns, err := namespace.FromContext(ctx)
@@ -704,10 +746,11 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 
 		if !isControlGroupRun(req) {
 			logInput := &logical.LogInput{
-				Auth:               auth,
-				Request:            req,
-				OuterErr:           ctErr,
-				NonHMACReqDataKeys: nonHMACReqDataKeys,
+				Auth:                auth,
+				Request:             req,
+				OuterErr:            ctErr,
+				NonHMACReqDataKeys:  nonHMACReqDataKeys,
+				AuditFieldSelectors: auditFieldSelectors,
 			}
 			if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
 				c.logger.Error("failed to audit request", "path", req.Path, "error", err)
@@ -726,9 +769,10 @@ func (c *Core) handleRequest(ctx context.Context, req *logical.Request) (retResp
 	// Create an audit trail of the request
 	if !isControlGroupRun(req) {
 		logInput := &logical.LogInput{
-			Auth:               auth,
-			Request:            req,
-			NonHMACReqDataKeys: nonHMACReqDataKeys,
+			Auth:                auth,
+			Request:             req,
+			NonHMACReqDataKeys:  nonHMACReqDataKeys,
+			AuditFieldSelectors: auditFieldSelectors,
 		}
 		if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
 			c.logger.Error("failed to audit request", "path", req.Path, "error", err)
@@ -1002,6 +1046,7 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 	req.Unauthenticated = true
 
 	var nonHMACReqDataKeys []string
+	var auditFieldSelectors []*audit.FieldSelector
 	entry := c.router.MatchingMountEntry(ctx, req.Path)
 	if entry != nil {
 		// Set here so the audit log has it even if authorization fails
@@ -1010,6 +1055,9 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		if rawVals, ok := entry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
 			nonHMACReqDataKeys = rawVals.([]string)
 		}
+		if rawVals, ok := entry.synthesizedConfigCache.Load("audit_field_selectors_parsed"); ok {
+			auditFieldSelectors = rawVals.([]*audit.FieldSelector)
+		}
 	}
 
 	// Do an unauth check. This will cause EGP policies to be checked
@@ -1031,10 +1079,11 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		}
 
 		logInput := &logical.LogInput{
-			Auth:               auth,
-			Request:            req,
-			OuterErr:           ctErr,
-			NonHMACReqDataKeys: nonHMACReqDataKeys,
+			Auth:                auth,
+			Request:             req,
+			OuterErr:            ctErr,
+			NonHMACReqDataKeys:  nonHMACReqDataKeys,
+			AuditFieldSelectors: auditFieldSelectors,
 		}
 		if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
 			c.logger.Error("failed to audit request", "path", req.Path, "error", err)
@@ -1056,9 +1105,10 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		// Create an audit trail of the request. Attach auth if it was returned,
 		// e.g. if a token was provided.
 		logInput := &logical.LogInput{
-			Auth:               auth,
-			Request:            req,
-			NonHMACReqDataKeys: nonHMACReqDataKeys,
+			Auth:                auth,
+			Request:             req,
+			NonHMACReqDataKeys:  nonHMACReqDataKeys,
+			AuditFieldSelectors: auditFieldSelectors,
 		}
 		if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
 			c.logger.Error("failed to audit request", "path", req.Path, "error", err)
@@ -1333,6 +1383,7 @@ func (c *Core) RegisterAuth(ctx context.Context, tokenTTL time.Duration, path st
 		ExplicitMaxTTL: auth.ExplicitMaxTTL,
 		Period:         auth.Period,
 		Type:           auth.TokenType,
+		MFAValidated:   auth.MFAValidated,
 	}
 
 	if te.TTL == 0 && (len(te.Policies) != 1 || te.Policies[0] != "root") {
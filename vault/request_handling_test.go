@@ -1,12 +1,14 @@
 package vault
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/armon/go-metrics"
 	"github.com/go-test/deep"
+	"github.com/hashicorp/errwrap"
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/builtin/credential/approle"
 	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
@@ -283,6 +285,7 @@ func TestRequestHandling_Login_PeriodicToken(t *testing.T) {
 		"renewable":        true,
 		"ttl":              int64(5),
 		"type":             "service",
+		"use_count":        int64(0),
 	}
 
 	if diff := deep.Equal(resp.Data, exp); diff != nil {
@@ -467,3 +470,127 @@ func TestRequestHandling_SecretLeaseMetric(t *testing.T) {
 		},
 	)
 }
+
+func TestRequestHandling_PolicyConditionMFA(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	policy, err := ParseACLPolicy(namespace.RootNamespace, `
+name = "mfa"
+path "secret/*" {
+	capabilities = ["read"]
+	condition "mfa" {
+		expression = "token.mfa_validated"
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.policyStore.SetPolicy(ctx, policy); err != nil {
+		t.Fatal(err)
+	}
+
+	// MFA credentials supplied with the request are not verified, so they do
+	// not satisfy the condition
+	outAuth := new(logical.Auth)
+	testMakeTokenViaCore(t, core, root, "client", "", "", []string{"mfa"}, false, outAuth)
+	req := &logical.Request{
+		Path:        "secret/foo",
+		ClientToken: "client",
+		Operation:   logical.ReadOperation,
+		MFACreds: logical.MFACreds{
+			"duo": []string{"123456"},
+		},
+	}
+	_, err = core.HandleRequest(ctx, req)
+	if err == nil || !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+
+	// Tokens issued by a login verified with a second factor satisfy it
+	noop := &NoopBackend{
+		Login: []string{"login"},
+		Response: &logical.Response{
+			Auth: &logical.Auth{
+				Policies:     []string{"mfa"},
+				MFAValidated: true,
+			},
+		},
+		BackendType: logical.TypeCredential,
+	}
+	core.credentialBackends["noop"] = func(context.Context, *logical.BackendConfig) (logical.Backend, error) {
+		return noop, nil
+	}
+	enableReq := logical.TestRequest(t, logical.UpdateOperation, "sys/auth/noop")
+	enableReq.Data["type"] = "noop"
+	enableReq.ClientToken = root
+	if _, err := core.HandleRequest(ctx, enableReq); err != nil {
+		t.Fatal(err)
+	}
+	loginResp, err := core.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "auth/noop/login",
+	})
+	if err != nil || loginResp == nil || loginResp.Auth == nil {
+		t.Fatalf("err: %v %#v", err, loginResp)
+	}
+
+	req.ClientToken = loginResp.Auth.ClientToken
+	req.MFACreds = nil
+	if _, err := core.HandleRequest(ctx, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestRequestHandling_PolicyConditionDenied(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+
+	policy, err := ParseACLPolicy(namespace.RootNamespace, `
+name = "conditional"
+path "secret/*" {
+	capabilities = ["read"]
+	condition "internal" {
+		expression = "cidr_match(request.remote_addr, \"10.0.0.0/8\")"
+	}
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := core.policyStore.SetPolicy(namespace.RootContext(nil), policy); err != nil {
+		t.Fatal(err)
+	}
+
+	outAuth := new(logical.Auth)
+	testMakeTokenViaCore(t, core, root, "client", "", "", []string{"conditional"}, false, outAuth)
+
+	req := &logical.Request{
+		Path:        "secret/foo",
+		ClientToken: "client",
+		Operation:   logical.ReadOperation,
+		Connection: &logical.Connection{
+			RemoteAddr: "192.168.0.1",
+		},
+	}
+	_, err = core.HandleRequest(namespace.RootContext(nil), req)
+	if err == nil {
+		t.Fatal("expected permission denied")
+	}
+	if !errwrap.Contains(err, logical.ErrPermissionDenied.Error()) {
+		t.Fatalf("expected permission denied, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), errPolicyConditionNotSatisfied.Error()) {
+		t.Fatalf("expected denial reason, got: %v", err)
+	}
+	// The policies of the token are not disclosed to the client
+	if strings.Contains(err.Error(), "conditional") || strings.Contains(err.Error(), "secret/*") {
+		t.Fatalf("unexpected policy details in error: %v", err)
+	}
+
+	req.Connection.RemoteAddr = "10.0.0.1"
+	_, err = core.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
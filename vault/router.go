@@ -12,10 +12,13 @@ import (
 	radix "github.com/armon/go-radix"
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/helper/tracing"
 	"github.com/hashicorp/vault/sdk/helper/consts"
 	"github.com/hashicorp/vault/sdk/helper/salt"
 	"github.com/hashicorp/vault/sdk/helper/strutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var deniedPassthroughRequestHeaders = []string{
@@ -138,6 +141,7 @@ func (r *Router) Mount(backend logical.Backend, prefix string, mountEntry *Mount
 	}
 	re.rootPaths.Store(pathsToRadix(paths.Root))
 	re.loginPaths.Store(pathsToRadix(paths.Unauthenticated))
+	storageView.setSealWrap(mountEntry.SealWrap, paths.SealWrapStorage)
 
 	switch {
 	case prefix == "":
@@ -683,7 +687,15 @@ func (r *Router) routeCommon(ctx context.Context, req *logical.Request, existenc
 		ok, exists, err := re.backend.HandleExistenceCheck(ctx, req)
 		return nil, ok, exists, err
 	} else {
-		resp, err := re.backend.HandleRequest(ctx, req)
+		// The request path is not recorded, as it may hold sensitive data
+		// which the audit log only records HMAC-ed
+		spanCtx, span := tracing.StartSpan(ctx, "backend.HandleRequest", trace.WithAttributes(
+			attribute.String("vault.operation", string(req.Operation)),
+			attribute.String("vault.mount_point", req.MountPoint),
+			attribute.String("vault.mount_type", re.mountEntry.Type),
+		))
+		resp, err := re.backend.HandleRequest(spanCtx, req)
+		tracing.EndSpan(span, err)
 		if resp != nil {
 			if len(allowedResponseHeaders) > 0 {
 				resp.Headers = filteredHeaders(resp.Headers, allowedResponseHeaders, nil)
@@ -312,6 +312,11 @@ type SealConfig struct {
 	// is unauthenticated.
 	Nonce string `json:"nonce" mapstructure:"nonce"`
 
+	// SignedUnseal requires the unseal keys to be submitted as PGP messages
+	// signed by the holders of PGPKeys, and encrypted with the unseal key of
+	// the server. The unseal keys are never entered in plaintext.
+	SignedUnseal bool `json:"signed_unseal,omitempty" mapstructure:"signed_unseal"`
+
 	// Backup indicates whether or not a backup of PGP-encrypted unseal keys
 	// should be stored at coreUnsealKeysBackupPath after successful rekeying.
 	Backup bool `json:"backup" mapstructure:"backup"`
@@ -362,6 +367,9 @@ func (s *SealConfig) Validate() error {
 	if s.StoredShares > 1 {
 		return fmt.Errorf("stored keys cannot be larger than 1")
 	}
+	if s.SignedUnseal && len(s.PGPKeys) == 0 {
+		return fmt.Errorf("signed unseal requires PGP keys")
+	}
 	if len(s.PGPKeys) > 0 && len(s.PGPKeys) != s.SecretShares {
 		return fmt.Errorf("count mismatch between number of provided PGP keys and number of shares")
 	}
@@ -388,6 +396,7 @@ func (s *SealConfig) Clone() *SealConfig {
 		Nonce:                s.Nonce,
 		Backup:               s.Backup,
 		StoredShares:         s.StoredShares,
+		SignedUnseal:         s.SignedUnseal,
 		VerificationRequired: s.VerificationRequired,
 		VerificationNonce:    s.VerificationNonce,
 	}
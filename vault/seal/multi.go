@@ -0,0 +1,266 @@
+package seal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	proto "github.com/golang/protobuf/proto"
+	"github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-multierror"
+)
+
+const (
+	// MultiSealType is the type of the seal combining the seals of several
+	// enabled seal stanzas
+	MultiSealType = "multiseal"
+
+	// MultiSealMechanism is the mechanism of the values encrypted by a
+	// MultiWrapper, whose data encryption key is wrapped by each of the seals
+	MultiSealMechanism uint64 = 0x4d554c5449
+)
+
+// SealWrapper is one of the seals of a MultiWrapper
+type SealWrapper struct {
+	wrapping.Wrapper
+
+	// Name identifies the seal in the values it encrypted, so that the seal
+	// can be reconfigured without changing its name
+	Name string
+
+	// Priority orders the seals, the seals with the lowest priority are tried
+	// first to decrypt
+	Priority int
+}
+
+// multiSealWrappedKey is the data encryption key of a value, wrapped by one of
+// the seals
+type multiSealWrappedKey struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Blob []byte `json:"blob"`
+}
+
+// MultiWrapper is a Wrapper that wraps the data encryption key of each value
+// with all of its seals, so that the value can be decrypted with any of them.
+// When a seal is unavailable, the values are encrypted with the other seals.
+type MultiWrapper struct {
+	wrappers []*SealWrapper
+	envelope *Envelope
+	logger   hclog.Logger
+}
+
+// Ensure that we are implementing Wrapper
+var _ wrapping.Wrapper = (*MultiWrapper)(nil)
+
+// NewMultiWrapper creates a new wrapper combining the given seals
+func NewMultiWrapper(logger hclog.Logger, wrappers ...*SealWrapper) *MultiWrapper {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	sorted := make([]*SealWrapper, len(wrappers))
+	copy(sorted, wrappers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return &MultiWrapper{
+		wrappers: sorted,
+		envelope: NewEnvelope(),
+		logger:   logger,
+	}
+}
+
+// Wrappers returns the seals, ordered by priority
+func (m *MultiWrapper) Wrappers() []*SealWrapper {
+	return m.wrappers
+}
+
+// Init initializes the seals. Only the seals that fail to initialize are
+// unavailable, unless all of them fail.
+func (m *MultiWrapper) Init(ctx context.Context) error {
+	var errs *multierror.Error
+	for _, w := range m.wrappers {
+		if err := w.Init(ctx); err != nil {
+			m.logger.Warn("failed to initialize seal", "name", w.Name, "error", err)
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", w.Name, err))
+		}
+	}
+	if errs != nil && len(errs.Errors) == len(m.wrappers) {
+		return fmt.Errorf("failed to initialize all the seals: %w", errs)
+	}
+	return nil
+}
+
+// Finalize finalizes all the seals
+func (m *MultiWrapper) Finalize(ctx context.Context) error {
+	var errs *multierror.Error
+	for _, w := range m.wrappers {
+		if err := w.Finalize(ctx); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", w.Name, err))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// Type returns the type for this particular Wrapper implementation
+func (m *MultiWrapper) Type() string {
+	return MultiSealType
+}
+
+// KeyID returns the names and the key IDs of all the seals. It changes when a
+// seal is added or removed, or when the key of one of the seals is rotated.
+func (m *MultiWrapper) KeyID() string {
+	return multiSealKeyID(m.wrappers)
+}
+
+// HMACKeyID returns the last known HMAC key id
+func (m *MultiWrapper) HMACKeyID() string {
+	return ""
+}
+
+// Encrypt envelope encrypts the plaintext, and wraps the data encryption key
+// with each of the seals. The seals that fail are left out, and the KeyID of
+// the value reflects it so that it is encrypted again when the keys are
+// upgraded.
+func (m *MultiWrapper) Encrypt(ctx context.Context, plaintext, aad []byte) (*wrapping.EncryptedBlobInfo, error) {
+	env, err := m.envelope.Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data: %w", err)
+	}
+
+	var errs *multierror.Error
+	var encrypted []*SealWrapper
+	var keys []*multiSealWrappedKey
+	for _, w := range m.wrappers {
+		blob, err := w.Encrypt(ctx, env.Key, nil)
+		if err == nil {
+			var raw []byte
+			raw, err = proto.Marshal(blob)
+			if err == nil {
+				keys = append(keys, &multiSealWrappedKey{
+					Name: w.Name,
+					Type: w.Type(),
+					Blob: raw,
+				})
+				encrypted = append(encrypted, w)
+				continue
+			}
+		}
+		m.logger.Warn("failed to encrypt with seal", "name", w.Name, "error", err)
+		errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", w.Name, err))
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("failed to encrypt with all the seals: %w", errs)
+	}
+
+	wrappedKey, err := json.Marshal(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode wrapped keys: %w", err)
+	}
+
+	return &wrapping.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &wrapping.KeyInfo{
+			Mechanism:  MultiSealMechanism,
+			KeyID:      multiSealKeyID(encrypted),
+			WrappedKey: wrappedKey,
+		},
+	}, nil
+}
+
+// Decrypt unwraps the data encryption key with the first seal, in order of
+// priority, that is able to. Values encrypted by a single seal, before it was
+// combined with other seals, are decrypted by the first seal able to.
+func (m *MultiWrapper) Decrypt(ctx context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+	if IsMultiSealBlob(in) {
+		return decryptMultiSeal(ctx, m.envelope, m.logger, m.wrappers, in, aad)
+	}
+
+	var errs *multierror.Error
+	for _, w := range m.wrappers {
+		pt, err := w.Decrypt(ctx, in, aad)
+		if err == nil {
+			return pt, nil
+		}
+		errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", w.Name, err))
+	}
+	return nil, fmt.Errorf("failed to decrypt with all the seals: %w", errs)
+}
+
+// IsMultiSealBlob returns whether the value was encrypted by a MultiWrapper
+func IsMultiSealBlob(in *wrapping.EncryptedBlobInfo) bool {
+	return in != nil && in.KeyInfo != nil && in.KeyInfo.Mechanism == MultiSealMechanism
+}
+
+// decryptMultiSeal decrypts a value encrypted by a MultiWrapper. The data
+// encryption key is unwrapped by the seal with the same name; seals with an
+// empty name, i.e. a single configured seal, try all the keys wrapped by a
+// seal of the same type.
+func decryptMultiSeal(ctx context.Context, envelope *Envelope, logger hclog.Logger, wrappers []*SealWrapper, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	var keys []*multiSealWrappedKey
+	if err := json.Unmarshal(in.KeyInfo.WrappedKey, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode wrapped keys: %w", err)
+	}
+
+	var errs *multierror.Error
+	for _, w := range wrappers {
+		for _, key := range keys {
+			if key.Type != w.Type() || (w.Name != "" && key.Name != w.Name) {
+				continue
+			}
+
+			blob := new(wrapping.EncryptedBlobInfo)
+			if err := proto.Unmarshal(key.Blob, blob); err != nil {
+				return nil, fmt.Errorf("failed to decode wrapped key of seal %q: %w", key.Name, err)
+			}
+			dek, err := w.Decrypt(ctx, blob, nil)
+			if err != nil {
+				logger.Warn("failed to decrypt with seal", "name", key.Name, "error", err)
+				errs = multierror.Append(errs, fmt.Errorf("seal %q: %w", key.Name, err))
+				continue
+			}
+
+			// The data encryption key is correct, any failure from now on
+			// does not depend on the seal
+			pt, err := envelope.Decrypt(&wrapping.EnvelopeInfo{
+				Key:        dek,
+				IV:         in.IV,
+				Ciphertext: in.Ciphertext,
+			}, aad)
+			if err != nil {
+				return nil, fmt.Errorf("error decrypting data: %w", err)
+			}
+			return pt, nil
+		}
+	}
+
+	if errs != nil {
+		return nil, fmt.Errorf("failed to decrypt with all the seals: %w", errs)
+	}
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, key.Name)
+	}
+	return nil, fmt.Errorf("none of the seals the value was encrypted with are configured, it was encrypted with %s", strings.Join(names, ", "))
+}
+
+// multiSealKeyID returns the names and key IDs of the seals, sorted by name
+func multiSealKeyID(wrappers []*SealWrapper) string {
+	ids := make([]string, 0, len(wrappers))
+	for _, w := range wrappers {
+		ids = append(ids, w.Name+":"+w.KeyID())
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,112 @@
+package seal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+)
+
+// unavailableWrapper is a wrapper whose KMS cannot be reached
+type unavailableWrapper struct {
+	wrapping.Wrapper
+}
+
+func (unavailableWrapper) Encrypt(context.Context, []byte, []byte) (*wrapping.EncryptedBlobInfo, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (unavailableWrapper) Decrypt(context.Context, *wrapping.EncryptedBlobInfo, []byte) ([]byte, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestMultiWrapper(t *testing.T) {
+	ctx := context.Background()
+	east := &SealWrapper{Wrapper: wrapping.NewTestEnvelopeWrapper([]byte("east")), Name: "east", Priority: 1}
+	west := &SealWrapper{Wrapper: wrapping.NewTestEnvelopeWrapper([]byte("west")), Name: "west", Priority: 2}
+	plaintext := []byte("barrier keys")
+	aad := []byte("aad")
+
+	multi := NewMultiWrapper(nil, west, east)
+	if multi.Type() != MultiSealType {
+		t.Fatalf("bad type: %s", multi.Type())
+	}
+	if names := []string{multi.Wrappers()[0].Name, multi.Wrappers()[1].Name}; names[0] != "east" || names[1] != "west" {
+		t.Fatalf("seals not ordered by priority: %v", names)
+	}
+
+	blob, err := multi.Encrypt(ctx, plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsMultiSealBlob(blob) {
+		t.Fatal("expected a multi seal blob")
+	}
+	if blob.KeyInfo.KeyID != multi.KeyID() {
+		t.Fatalf("bad key ID: %s, expected %s", blob.KeyInfo.KeyID, multi.KeyID())
+	}
+
+	decrypt := func(t *testing.T, w wrapping.Wrapper, blob *wrapping.EncryptedBlobInfo) {
+		t.Helper()
+		pt, err := w.Decrypt(ctx, blob, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pt, plaintext) {
+			t.Fatalf("bad plaintext: %q", pt)
+		}
+	}
+
+	t.Run("any seal", func(t *testing.T) {
+		decrypt(t, multi, blob)
+		decrypt(t, NewMultiWrapper(nil, &SealWrapper{Wrapper: unavailableWrapper{Wrapper: east.Wrapper}, Name: "east"}, west), blob)
+		decrypt(t, NewMultiWrapper(nil, west), blob)
+	})
+
+	t.Run("single seal", func(t *testing.T) {
+		decrypt(t, &Access{Wrapper: west.Wrapper}, blob)
+
+		// The value is encrypted by a single seal before it is combined
+		single, err := (&Access{Wrapper: west.Wrapper}).Encrypt(ctx, plaintext, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypt(t, multi, single)
+	})
+
+	t.Run("removed seals", func(t *testing.T) {
+		other := &SealWrapper{Wrapper: wrapping.NewTestEnvelopeWrapper([]byte("other")), Name: "other"}
+		if _, err := NewMultiWrapper(nil, other).Decrypt(ctx, blob, aad); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		if _, err := multi.Decrypt(ctx, blob, []byte("other aad")); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("unavailable seal", func(t *testing.T) {
+		degraded := NewMultiWrapper(nil, &SealWrapper{Wrapper: unavailableWrapper{Wrapper: east.Wrapper}, Name: "east", Priority: 1}, west)
+		blob, err := degraded.Encrypt(ctx, plaintext, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The value is encrypted again once the seal is available
+		if blob.KeyInfo.KeyID == multi.KeyID() {
+			t.Fatal("expected the key ID to leave out the unavailable seal")
+		}
+		decrypt(t, multi, blob)
+		if _, err := NewMultiWrapper(nil, east).Decrypt(ctx, blob, aad); err == nil {
+			t.Fatal("expected error")
+		}
+
+		unavailable := NewMultiWrapper(nil, &SealWrapper{Wrapper: unavailableWrapper{Wrapper: east.Wrapper}, Name: "east"})
+		if _, err := unavailable.Encrypt(ctx, plaintext, aad); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
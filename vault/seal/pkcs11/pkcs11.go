@@ -0,0 +1,711 @@
+package pkcs11
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/vault/sdk/helper/parseutil"
+	p11 "github.com/miekg/pkcs11"
+)
+
+// These constants contain the accepted env vars, which take precedence over
+// the values of the seal configuration
+const (
+	EnvHSMLib         = "VAULT_HSM_LIB"
+	EnvHSMSlot        = "VAULT_HSM_SLOT"
+	EnvHSMTokenLabel  = "VAULT_HSM_TOKEN_LABEL"
+	EnvHSMPin         = "VAULT_HSM_PIN"
+	EnvHSMKeyLabel    = "VAULT_HSM_KEY_LABEL"
+	EnvHSMMechanism   = "VAULT_HSM_MECHANISM"
+	EnvHSMRSAOAEPHash = "VAULT_HSM_RSA_OAEP_HASH"
+	EnvHSMRSAKeyBits  = "VAULT_HSM_RSA_KEY_BITS"
+	EnvHSMGenerateKey = "VAULT_HSM_GENERATE_KEY"
+	EnvHSMMaxParallel = "VAULT_HSM_MAX_PARALLEL"
+)
+
+const (
+	// MechanismAESGCM encrypts the data encryption key with an AES key using
+	// CKM_AES_GCM
+	MechanismAESGCM = p11.CKM_AES_GCM
+
+	// MechanismRSAOAEP encrypts the data encryption key with the public key
+	// of an RSA key pair using CKM_RSA_PKCS_OAEP
+	MechanismRSAOAEP = p11.CKM_RSA_PKCS_OAEP
+
+	gcmNonceSize = 12
+	gcmTagBits   = 128
+
+	defaultRSAKeyBits  = 2048
+	defaultRSAOAEPHash = "sha256"
+	defaultMaxParallel = 1
+)
+
+var oaepHashes = map[string]struct {
+	hash uint
+	mgf  uint
+}{
+	"sha1":   {p11.CKM_SHA_1, p11.CKG_MGF1_SHA1},
+	"sha224": {p11.CKM_SHA224, p11.CKG_MGF1_SHA224},
+	"sha256": {p11.CKM_SHA256, p11.CKG_MGF1_SHA256},
+	"sha384": {p11.CKM_SHA384, p11.CKG_MGF1_SHA384},
+	"sha512": {p11.CKM_SHA512, p11.CKG_MGF1_SHA512},
+}
+
+// Wrapper is a Wrapper that encrypts the data encryption keys with a key
+// stored in an HSM, accessed through a PKCS#11 library
+type Wrapper struct {
+	lib         string
+	slot        *uint
+	tokenLabel  string
+	pin         string
+	keyLabel    string
+	mechanism   uint
+	oaepHash    string
+	generateKey bool
+	rsaKeyBits  int
+	maxParallel int
+
+	// l protects the connection to the token. The sessions cannot be used
+	// concurrently, so each operation takes one from the pool, which is nil
+	// until connected.
+	l      sync.RWMutex
+	ctx    *p11.Ctx
+	slotID uint
+	pool   *sessionPool
+
+	// encryptKey and decryptKey are the same object for AES keys, and the
+	// public and private keys of the pair for RSA keys
+	encryptKey p11.ObjectHandle
+	decryptKey p11.ObjectHandle
+
+	logger hclog.Logger
+}
+
+// sessionPool holds the sessions opened by a connection to the token. It is
+// closed when the connection is lost or finalized, and the sessions still in
+// use are closed when they are released.
+type sessionPool struct {
+	sessions chan p11.SessionHandle
+	closed   chan struct{}
+}
+
+func newSessionPool(sessions []p11.SessionHandle) *sessionPool {
+	pool := &sessionPool{
+		sessions: make(chan p11.SessionHandle, len(sessions)),
+		closed:   make(chan struct{}),
+	}
+	for _, session := range sessions {
+		pool.sessions <- session
+	}
+	return pool
+}
+
+// Ensure that we are implementing Wrapper
+var _ wrapping.Wrapper = (*Wrapper)(nil)
+
+// NewWrapper creates a new PKCS#11 wrapper with the provided options
+func NewWrapper(opts *wrapping.WrapperOptions) *Wrapper {
+	if opts == nil {
+		opts = new(wrapping.WrapperOptions)
+	}
+	logger := opts.Logger
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	return &Wrapper{
+		logger: logger,
+	}
+}
+
+// SetConfig sets the fields on the Wrapper object based on values from the
+// config parameter, opens a session with the token and looks up the key,
+// generating it if requested.
+//
+// Environment variables take precedence over the values from the
+// configuration.
+func (w *Wrapper) SetConfig(config map[string]string) (map[string]string, error) {
+	if config == nil {
+		config = map[string]string{}
+	}
+	get := func(env, key string) string {
+		if v := os.Getenv(env); v != "" {
+			return v
+		}
+		return config[key]
+	}
+
+	w.lib = get(EnvHSMLib, "lib")
+	if w.lib == "" {
+		return nil, errors.New("'lib' not found for PKCS#11 wrapper configuration")
+	}
+
+	w.slot = nil
+	if slot := get(EnvHSMSlot, "slot"); slot != "" {
+		id, err := strconv.ParseUint(slot, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'slot' for PKCS#11 wrapper configuration: %w", err)
+		}
+		slotID := uint(id)
+		w.slot = &slotID
+	}
+	w.tokenLabel = get(EnvHSMTokenLabel, "token_label")
+	switch {
+	case w.slot == nil && w.tokenLabel == "":
+		return nil, errors.New("one of 'slot' or 'token_label' is required for PKCS#11 wrapper configuration")
+	case w.slot != nil && w.tokenLabel != "":
+		return nil, errors.New("only one of 'slot' or 'token_label' can be set for PKCS#11 wrapper configuration")
+	}
+
+	w.pin = get(EnvHSMPin, "pin")
+	if w.pin == "" {
+		return nil, errors.New("'pin' not found for PKCS#11 wrapper configuration")
+	}
+
+	w.keyLabel = get(EnvHSMKeyLabel, "key_label")
+	if w.keyLabel == "" {
+		return nil, errors.New("'key_label' not found for PKCS#11 wrapper configuration")
+	}
+
+	w.mechanism = MechanismAESGCM
+	if mechanism := get(EnvHSMMechanism, "mechanism"); mechanism != "" {
+		m, err := strconv.ParseUint(mechanism, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'mechanism' for PKCS#11 wrapper configuration: %w", err)
+		}
+		w.mechanism = uint(m)
+	}
+	switch w.mechanism {
+	case MechanismAESGCM:
+	case MechanismRSAOAEP:
+		w.oaepHash = strings.ToLower(get(EnvHSMRSAOAEPHash, "rsa_oaep_hash"))
+		if w.oaepHash == "" {
+			w.oaepHash = defaultRSAOAEPHash
+		}
+		if _, ok := oaepHashes[w.oaepHash]; !ok {
+			return nil, fmt.Errorf("unsupported 'rsa_oaep_hash' %q for PKCS#11 wrapper configuration", w.oaepHash)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported 'mechanism' 0x%04x for PKCS#11 wrapper configuration, "+
+			"must be 0x%04x (CKM_AES_GCM) or 0x%04x (CKM_RSA_PKCS_OAEP)", w.mechanism, MechanismAESGCM, MechanismRSAOAEP)
+	}
+
+	w.generateKey = false
+	if generateKey := get(EnvHSMGenerateKey, "generate_key"); generateKey != "" {
+		var err error
+		w.generateKey, err = parseutil.ParseBool(generateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'generate_key' for PKCS#11 wrapper configuration: %w", err)
+		}
+	}
+
+	w.rsaKeyBits = defaultRSAKeyBits
+	if bits := get(EnvHSMRSAKeyBits, "rsa_key_bits"); bits != "" {
+		var err error
+		w.rsaKeyBits, err = strconv.Atoi(bits)
+		if err != nil || w.rsaKeyBits < 2048 {
+			return nil, errors.New("invalid 'rsa_key_bits' for PKCS#11 wrapper configuration, must be at least 2048")
+		}
+	}
+
+	w.maxParallel = defaultMaxParallel
+	if maxParallel := get(EnvHSMMaxParallel, "max_parallel"); maxParallel != "" {
+		var err error
+		w.maxParallel, err = strconv.Atoi(maxParallel)
+		if err != nil || w.maxParallel < 1 {
+			return nil, errors.New("invalid 'max_parallel' for PKCS#11 wrapper configuration, must be at least 1")
+		}
+	}
+
+	w.l.Lock()
+	defer w.l.Unlock()
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	// Map that holds non-sensitive configuration info
+	wrappingInfo := map[string]string{
+		"lib":          w.lib,
+		"slot":         strconv.FormatUint(uint64(w.slotID), 10),
+		"key_label":    w.keyLabel,
+		"mechanism":    fmt.Sprintf("0x%04x", w.mechanism),
+		"max_parallel": strconv.Itoa(w.maxParallel),
+	}
+	if w.tokenLabel != "" {
+		wrappingInfo["token_label"] = w.tokenLabel
+	}
+	if w.mechanism == MechanismRSAOAEP {
+		wrappingInfo["rsa_oaep_hash"] = w.oaepHash
+	}
+	return wrappingInfo, nil
+}
+
+// connect loads the library, opens the pool of sessions, logs into the token
+// and looks up the key. It must be called with the lock held.
+func (w *Wrapper) connect() error {
+	if w.ctx == nil {
+		ctx := p11.New(w.lib)
+		if ctx == nil {
+			return fmt.Errorf("failed to load PKCS#11 library %q", w.lib)
+		}
+		if err := ctx.Initialize(); err != nil && !isError(err, p11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+			ctx.Destroy()
+			return fmt.Errorf("failed to initialize PKCS#11 library: %w", err)
+		}
+		w.ctx = ctx
+	}
+
+	slotID, err := w.findSlot()
+	if err != nil {
+		return err
+	}
+	w.slotID = slotID
+
+	sessions := make([]p11.SessionHandle, 0, w.maxParallel)
+	closeSessions := func() {
+		for _, session := range sessions {
+			w.ctx.CloseSession(session)
+		}
+	}
+	for i := 0; i < w.maxParallel; i++ {
+		session, err := w.ctx.OpenSession(slotID, p11.CKF_SERIAL_SESSION|p11.CKF_RW_SESSION)
+		if err != nil {
+			closeSessions()
+			return fmt.Errorf("failed to open PKCS#11 session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	// The login state is shared by all the sessions of the application, and
+	// the handles of the token objects are valid in all of them
+	if err := w.ctx.Login(sessions[0], p11.CKU_USER, w.pin); err != nil && !isError(err, p11.CKR_USER_ALREADY_LOGGED_IN) {
+		closeSessions()
+		return fmt.Errorf("failed to log into PKCS#11 token: %w", err)
+	}
+	if err := w.loadKey(sessions[0]); err != nil {
+		closeSessions()
+		return err
+	}
+
+	w.pool = newSessionPool(sessions)
+	return nil
+}
+
+// disconnect closes the pool and the idle sessions, logging out of the token
+// first if requested. It must be called with the lock held.
+func (w *Wrapper) disconnect(logout bool) {
+	if w.pool == nil {
+		return
+	}
+	close(w.pool.closed)
+drain:
+	for {
+		select {
+		case session := <-w.pool.sessions:
+			if logout {
+				w.ctx.Logout(session)
+				logout = false
+			}
+			w.ctx.CloseSession(session)
+		default:
+			break drain
+		}
+	}
+	w.pool = nil
+}
+
+// findSlot returns the configured slot, or the slot holding the token with
+// the configured label
+func (w *Wrapper) findSlot() (uint, error) {
+	if w.slot != nil {
+		return *w.slot, nil
+	}
+
+	slots, err := w.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := w.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read PKCS#11 token information of slot %d: %w", slot, err)
+		}
+		if strings.TrimRight(info.Label, " \x00") == w.tokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("no PKCS#11 token found with label %q", w.tokenLabel)
+}
+
+// loadKey looks up the key handles, and generates the key if it is missing
+// and key generation is enabled
+func (w *Wrapper) loadKey(session p11.SessionHandle) error {
+	var encryptClass, decryptClass, keyType uint
+	switch w.mechanism {
+	case MechanismAESGCM:
+		encryptClass, decryptClass, keyType = p11.CKO_SECRET_KEY, p11.CKO_SECRET_KEY, p11.CKK_AES
+	case MechanismRSAOAEP:
+		encryptClass, decryptClass, keyType = p11.CKO_PUBLIC_KEY, p11.CKO_PRIVATE_KEY, p11.CKK_RSA
+	}
+
+	encryptKey, found, err := w.findKey(session, encryptClass, keyType)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if !w.generateKey {
+			return fmt.Errorf("no PKCS#11 key found with label %q", w.keyLabel)
+		}
+		return w.createKey(session)
+	}
+	decryptKey, found, err := w.findKey(session, decryptClass, keyType)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("no PKCS#11 private key found with label %q", w.keyLabel)
+	}
+
+	w.encryptKey, w.decryptKey = encryptKey, decryptKey
+	return nil
+}
+
+func (w *Wrapper) findKey(session p11.SessionHandle, class, keyType uint) (p11.ObjectHandle, bool, error) {
+	template := []*p11.Attribute{
+		p11.NewAttribute(p11.CKA_CLASS, class),
+		p11.NewAttribute(p11.CKA_KEY_TYPE, keyType),
+		p11.NewAttribute(p11.CKA_LABEL, w.keyLabel),
+	}
+	if err := w.ctx.FindObjectsInit(session, template); err != nil {
+		return 0, false, fmt.Errorf("failed to look up PKCS#11 key: %w", err)
+	}
+	objects, _, err := w.ctx.FindObjects(session, 2)
+	if finalErr := w.ctx.FindObjectsFinal(session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to look up PKCS#11 key: %w", err)
+	}
+
+	switch len(objects) {
+	case 0:
+		return 0, false, nil
+	case 1:
+		return objects[0], true, nil
+	default:
+		return 0, false, fmt.Errorf("multiple PKCS#11 keys found with label %q", w.keyLabel)
+	}
+}
+
+// createKey generates a non-extractable key in the token
+func (w *Wrapper) createKey(session p11.SessionHandle) error {
+	w.logger.Info("generating PKCS#11 key", "key_label", w.keyLabel, "mechanism", fmt.Sprintf("0x%04x", w.mechanism))
+
+	switch w.mechanism {
+	case MechanismAESGCM:
+		key, err := w.ctx.GenerateKey(session,
+			[]*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_KEY_GEN, nil)},
+			[]*p11.Attribute{
+				p11.NewAttribute(p11.CKA_CLASS, p11.CKO_SECRET_KEY),
+				p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_AES),
+				p11.NewAttribute(p11.CKA_VALUE_LEN, 32),
+				p11.NewAttribute(p11.CKA_LABEL, w.keyLabel),
+				p11.NewAttribute(p11.CKA_TOKEN, true),
+				p11.NewAttribute(p11.CKA_PRIVATE, true),
+				p11.NewAttribute(p11.CKA_SENSITIVE, true),
+				p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+				p11.NewAttribute(p11.CKA_ENCRYPT, true),
+				p11.NewAttribute(p11.CKA_DECRYPT, true),
+			})
+		if err != nil {
+			return fmt.Errorf("failed to generate PKCS#11 key: %w", err)
+		}
+		w.encryptKey, w.decryptKey = key, key
+
+	case MechanismRSAOAEP:
+		public, private, err := w.ctx.GenerateKeyPair(session,
+			[]*p11.Mechanism{p11.NewMechanism(p11.CKM_RSA_PKCS_KEY_PAIR_GEN, nil)},
+			[]*p11.Attribute{
+				p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PUBLIC_KEY),
+				p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_RSA),
+				p11.NewAttribute(p11.CKA_LABEL, w.keyLabel),
+				p11.NewAttribute(p11.CKA_TOKEN, true),
+				p11.NewAttribute(p11.CKA_ENCRYPT, true),
+				p11.NewAttribute(p11.CKA_MODULUS_BITS, w.rsaKeyBits),
+				p11.NewAttribute(p11.CKA_PUBLIC_EXPONENT, []byte{1, 0, 1}),
+			},
+			[]*p11.Attribute{
+				p11.NewAttribute(p11.CKA_CLASS, p11.CKO_PRIVATE_KEY),
+				p11.NewAttribute(p11.CKA_KEY_TYPE, p11.CKK_RSA),
+				p11.NewAttribute(p11.CKA_LABEL, w.keyLabel),
+				p11.NewAttribute(p11.CKA_TOKEN, true),
+				p11.NewAttribute(p11.CKA_PRIVATE, true),
+				p11.NewAttribute(p11.CKA_SENSITIVE, true),
+				p11.NewAttribute(p11.CKA_EXTRACTABLE, false),
+				p11.NewAttribute(p11.CKA_DECRYPT, true),
+			})
+		if err != nil {
+			return fmt.Errorf("failed to generate PKCS#11 key pair: %w", err)
+		}
+		w.encryptKey, w.decryptKey = public, private
+	}
+	return nil
+}
+
+// withSession runs f with a session taken from the pool, reconnecting once if
+// the session was closed, e.g. because the HSM was restarted
+func (w *Wrapper) withSession(f func(session p11.SessionHandle) error) error {
+	pool, session, err := w.acquireSession()
+	if err != nil {
+		return err
+	}
+	err = f(session)
+	if !isError(err, p11.CKR_SESSION_HANDLE_INVALID, p11.CKR_SESSION_CLOSED, p11.CKR_DEVICE_REMOVED, p11.CKR_TOKEN_NOT_PRESENT) {
+		w.releaseSession(pool, session)
+		return err
+	}
+
+	w.logger.Warn("PKCS#11 session lost, reconnecting", "error", err)
+	w.releaseSession(pool, session)
+	if err := w.reconnect(pool); err != nil {
+		return err
+	}
+
+	pool, session, err = w.acquireSession()
+	if err != nil {
+		return err
+	}
+	defer w.releaseSession(pool, session)
+	return f(session)
+}
+
+// acquireSession connects if needed, and waits for a session of the pool to
+// be available
+func (w *Wrapper) acquireSession() (*sessionPool, p11.SessionHandle, error) {
+	for {
+		w.l.RLock()
+		pool := w.pool
+		w.l.RUnlock()
+
+		if pool == nil {
+			w.l.Lock()
+			if w.pool == nil {
+				if err := w.connect(); err != nil {
+					w.l.Unlock()
+					return nil, 0, err
+				}
+			}
+			pool = w.pool
+			w.l.Unlock()
+		}
+
+		select {
+		case session := <-pool.sessions:
+			return pool, session, nil
+		case <-pool.closed:
+			// The connection was lost while waiting, retry with the new one
+		}
+	}
+}
+
+// releaseSession returns the session to its pool, or closes it if the pool
+// was closed in the meantime
+func (w *Wrapper) releaseSession(pool *sessionPool, session p11.SessionHandle) {
+	w.l.RLock()
+	defer w.l.RUnlock()
+
+	if w.pool == pool {
+		pool.sessions <- session
+		return
+	}
+	if w.ctx != nil {
+		w.ctx.CloseSession(session)
+	}
+}
+
+// reconnect replaces the given pool with a new connection, unless another
+// operation already did
+func (w *Wrapper) reconnect(pool *sessionPool) error {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	if w.pool != pool {
+		return nil
+	}
+	w.disconnect(false)
+	return w.connect()
+}
+
+// Init is called during core.Initialize. No-op at the moment.
+func (w *Wrapper) Init(_ context.Context) error {
+	return nil
+}
+
+// Finalize is called during shutdown, and closes the sessions with the token
+func (w *Wrapper) Finalize(_ context.Context) error {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	if w.ctx == nil {
+		return nil
+	}
+	w.disconnect(true)
+	err := w.ctx.Finalize()
+	w.ctx.Destroy()
+	w.ctx = nil
+	return err
+}
+
+// Type returns the wrapping type for this particular Wrapper implementation
+func (w *Wrapper) Type() string {
+	return wrapping.PKCS11
+}
+
+// KeyID returns the label of the key
+func (w *Wrapper) KeyID() string {
+	return w.keyLabel
+}
+
+// HMACKeyID returns the last known HMAC key id
+func (w *Wrapper) HMACKeyID() string {
+	return ""
+}
+
+// Encrypt encrypts the plaintext with a data encryption key, which is
+// encrypted with the key stored in the HSM
+func (w *Wrapper) Encrypt(_ context.Context, plaintext, aad []byte) (*wrapping.EncryptedBlobInfo, error) {
+	if plaintext == nil {
+		return nil, errors.New("given plaintext for encryption is nil")
+	}
+
+	env, err := wrapping.NewEnvelope(nil).Encrypt(plaintext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("error wrapping data: %w", err)
+	}
+
+	var wrappedKey []byte
+	err = w.withSession(func(session p11.SessionHandle) error {
+		var err error
+		switch w.mechanism {
+		case MechanismAESGCM:
+			nonce := make([]byte, gcmNonceSize)
+			if _, err := rand.Read(nonce); err != nil {
+				return err
+			}
+			params := p11.NewGCMParams(nonce, nil, gcmTagBits)
+			defer params.Free()
+			if err := w.ctx.EncryptInit(session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_GCM, params)}, w.encryptKey); err != nil {
+				return err
+			}
+			ciphertext, err := w.ctx.Encrypt(session, env.Key)
+			if err != nil {
+				return err
+			}
+			// Some HSMs generate their own nonce
+			if iv := params.IV(); len(iv) == gcmNonceSize {
+				nonce = iv
+			}
+			wrappedKey = append(nonce, ciphertext...)
+
+		case MechanismRSAOAEP:
+			if err := w.ctx.EncryptInit(session, []*p11.Mechanism{w.oaepMechanism()}, w.encryptKey); err != nil {
+				return err
+			}
+			wrappedKey, err = w.ctx.Encrypt(session, env.Key)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error encrypting data encryption key: %w", err)
+	}
+
+	return &wrapping.EncryptedBlobInfo{
+		Ciphertext: env.Ciphertext,
+		IV:         env.IV,
+		KeyInfo: &wrapping.KeyInfo{
+			Mechanism:  uint64(w.mechanism),
+			KeyID:      w.keyLabel,
+			WrappedKey: wrappedKey,
+		},
+	}, nil
+}
+
+// Decrypt decrypts the data encryption key with the key stored in the HSM,
+// and then the ciphertext
+func (w *Wrapper) Decrypt(_ context.Context, in *wrapping.EncryptedBlobInfo, aad []byte) ([]byte, error) {
+	if in == nil {
+		return nil, errors.New("given input for decryption is nil")
+	}
+	if in.KeyInfo == nil {
+		return nil, errors.New("key info is nil")
+	}
+	if uint(in.KeyInfo.Mechanism) != w.mechanism {
+		return nil, fmt.Errorf("data was encrypted with mechanism 0x%04x, but the configured mechanism is 0x%04x", in.KeyInfo.Mechanism, w.mechanism)
+	}
+	if in.KeyInfo.KeyID != "" && in.KeyInfo.KeyID != w.keyLabel {
+		return nil, fmt.Errorf("data was encrypted with key %q, but the configured key is %q", in.KeyInfo.KeyID, w.keyLabel)
+	}
+
+	var key []byte
+	err := w.withSession(func(session p11.SessionHandle) error {
+		var err error
+		switch w.mechanism {
+		case MechanismAESGCM:
+			if len(in.KeyInfo.WrappedKey) <= gcmNonceSize {
+				return errors.New("wrapped key is too short")
+			}
+			params := p11.NewGCMParams(in.KeyInfo.WrappedKey[:gcmNonceSize], nil, gcmTagBits)
+			defer params.Free()
+			if err := w.ctx.DecryptInit(session, []*p11.Mechanism{p11.NewMechanism(p11.CKM_AES_GCM, params)}, w.decryptKey); err != nil {
+				return err
+			}
+			key, err = w.ctx.Decrypt(session, in.KeyInfo.WrappedKey[gcmNonceSize:])
+
+		case MechanismRSAOAEP:
+			if err := w.ctx.DecryptInit(session, []*p11.Mechanism{w.oaepMechanism()}, w.decryptKey); err != nil {
+				return err
+			}
+			key, err = w.ctx.Decrypt(session, in.KeyInfo.WrappedKey)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data encryption key: %w", err)
+	}
+
+	plaintext, err := wrapping.NewEnvelope(nil).Decrypt(&wrapping.EnvelopeInfo{
+		Key:        key,
+		IV:         in.IV,
+		Ciphertext: in.Ciphertext,
+	}, aad)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting data: %w", err)
+	}
+	return plaintext, nil
+}
+
+func (w *Wrapper) oaepMechanism() *p11.Mechanism {
+	hash := oaepHashes[w.oaepHash]
+	return p11.NewMechanism(p11.CKM_RSA_PKCS_OAEP, p11.NewOAEPParams(hash.hash, hash.mgf, p11.CKZ_DATA_SPECIFIED, nil))
+}
+
+// isError returns whether err is one of the given PKCS#11 return values
+func isError(err error, codes ...uint) bool {
+	var p11Err p11.Error
+	if !errors.As(err, &p11Err) {
+		return false
+	}
+	for _, code := range codes {
+		if uint(p11Err) == code {
+			return true
+		}
+	}
+	return false
+}
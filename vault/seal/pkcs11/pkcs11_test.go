@@ -0,0 +1,261 @@
+package pkcs11
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	p11 "github.com/miekg/pkcs11"
+)
+
+func TestWrapper_SetConfig_Invalid(t *testing.T) {
+	for _, env := range []string{EnvHSMLib, EnvHSMSlot, EnvHSMTokenLabel, EnvHSMPin, EnvHSMKeyLabel, EnvHSMMechanism, EnvHSMMaxParallel} {
+		if os.Getenv(env) != "" {
+			t.Skipf("%s is set", env)
+		}
+	}
+
+	valid := map[string]string{
+		"lib":       "/usr/lib/softhsm/libsofthsm2.so",
+		"slot":      "0",
+		"pin":       "1234",
+		"key_label": "vault",
+	}
+	cases := map[string]struct {
+		config map[string]string
+		err    string
+	}{
+		"missing lib": {
+			map[string]string{"lib": ""},
+			"'lib' not found",
+		},
+		"missing slot": {
+			map[string]string{"slot": ""},
+			"one of 'slot' or 'token_label' is required",
+		},
+		"slot and token label": {
+			map[string]string{"token_label": "vault"},
+			"only one of 'slot' or 'token_label'",
+		},
+		"invalid slot": {
+			map[string]string{"slot": "first"},
+			"invalid 'slot'",
+		},
+		"missing pin": {
+			map[string]string{"pin": ""},
+			"'pin' not found",
+		},
+		"missing key label": {
+			map[string]string{"key_label": ""},
+			"'key_label' not found",
+		},
+		"unsupported mechanism": {
+			map[string]string{"mechanism": "0x1082"},
+			"unsupported 'mechanism' 0x1082",
+		},
+		"unsupported oaep hash": {
+			map[string]string{"mechanism": "0x0009", "rsa_oaep_hash": "md5"},
+			"unsupported 'rsa_oaep_hash'",
+		},
+		"invalid rsa key bits": {
+			map[string]string{"mechanism": "0x0009", "rsa_key_bits": "1024"},
+			"invalid 'rsa_key_bits'",
+		},
+		"invalid max parallel": {
+			map[string]string{"max_parallel": "0"},
+			"invalid 'max_parallel'",
+		},
+		"missing library": {
+			map[string]string{"lib": "/nonexistent/libpkcs11.so"},
+			"failed to load PKCS#11 library",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := make(map[string]string)
+			for k, v := range valid {
+				config[k] = v
+			}
+			for k, v := range tc.config {
+				config[k] = v
+			}
+			_, err := NewWrapper(nil).SetConfig(config)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error containing %q, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestWrapper_sessionPool(t *testing.T) {
+	w := NewWrapper(nil)
+	w.pool = newSessionPool([]p11.SessionHandle{1, 2})
+
+	acquire := func() (*sessionPool, p11.SessionHandle) {
+		t.Helper()
+		pool, session, err := w.acquireSession()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pool, session
+	}
+	waitSession := func(ch chan p11.SessionHandle, expected p11.SessionHandle) {
+		t.Helper()
+		select {
+		case session := <-ch:
+			if session != expected {
+				t.Fatalf("got session %d, expected %d", session, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a session")
+		}
+	}
+
+	// All the sessions can be used concurrently
+	pool, first := acquire()
+	_, second := acquire()
+	if first == second {
+		t.Fatalf("got session %d twice", first)
+	}
+
+	// Once they are all in use, the next operation waits for one
+	ch := make(chan p11.SessionHandle)
+	go func() {
+		_, session, _ := w.acquireSession()
+		ch <- session
+	}()
+	select {
+	case session := <-ch:
+		t.Fatalf("got session %d while all are in use", session)
+	case <-time.After(50 * time.Millisecond):
+	}
+	w.releaseSession(pool, first)
+	waitSession(ch, first)
+
+	// The operations waiting when the connection is replaced take a session
+	// of the new pool, and the sessions of the old one are not reused
+	go func() {
+		_, session, _ := w.acquireSession()
+		ch <- session
+	}()
+	w.l.Lock()
+	close(w.pool.closed)
+	w.pool = newSessionPool([]p11.SessionHandle{3})
+	w.l.Unlock()
+	waitSession(ch, 3)
+
+	w.releaseSession(pool, second)
+	if len(pool.sessions) != 0 {
+		t.Fatal("session released to a closed pool")
+	}
+}
+
+// TestWrapper_SoftHSM runs against a token of SoftHSM, or any other PKCS#11
+// library, configured with the VAULT_HSM_LIB, VAULT_HSM_TOKEN_LABEL and
+// VAULT_HSM_PIN environment variables, e.g.:
+//
+//	softhsm2-util --init-token --free --label vault --pin 1234 --so-pin 1234
+//	VAULT_HSM_LIB=/usr/lib/softhsm/libsofthsm2.so VAULT_HSM_TOKEN_LABEL=vault VAULT_HSM_PIN=1234 go test
+func TestWrapper_SoftHSM(t *testing.T) {
+	if os.Getenv(EnvHSMLib) == "" || os.Getenv(EnvHSMTokenLabel) == "" || os.Getenv(EnvHSMPin) == "" {
+		t.Skipf("%s, %s and %s must be set", EnvHSMLib, EnvHSMTokenLabel, EnvHSMPin)
+	}
+
+	for name, mechanism := range map[string]string{
+		"aes-gcm":  "0x1087",
+		"rsa-oaep": "0x0009",
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			keyLabel, err := uuid.GenerateUUID()
+			if err != nil {
+				t.Fatal(err)
+			}
+			config := map[string]string{
+				"key_label":    keyLabel,
+				"mechanism":    mechanism,
+				"max_parallel": "4",
+			}
+
+			// The key must exist unless it is generated
+			if _, err := NewWrapper(nil).SetConfig(config); err == nil || !strings.Contains(err.Error(), "no PKCS#11 key found") {
+				t.Fatalf("expected missing key error, got: %v", err)
+			}
+
+			config["generate_key"] = "true"
+			w := NewWrapper(nil)
+			info, err := w.SetConfig(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Finalize(ctx)
+			if info["key_label"] != keyLabel || info["mechanism"] != mechanism {
+				t.Fatalf("bad: %#v", info)
+			}
+
+			plaintext := []byte("vault master key")
+			blob, err := w.Encrypt(ctx, plaintext, []byte("aad"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if blob.KeyInfo.KeyID != keyLabel {
+				t.Fatalf("bad: %#v", blob.KeyInfo)
+			}
+			decrypted, err := w.Decrypt(ctx, blob, []byte("aad"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decrypted, plaintext) {
+				t.Fatalf("bad: %q", decrypted)
+			}
+
+			// The sessions of the pool are used concurrently
+			var wg sync.WaitGroup
+			errCh := make(chan error, 8)
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					blob, err := w.Encrypt(ctx, plaintext, nil)
+					if err == nil {
+						_, err = w.Decrypt(ctx, blob, nil)
+					}
+					errCh <- err
+				}()
+			}
+			wg.Wait()
+			close(errCh)
+			for err := range errCh {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Another wrapper finds the generated key
+			delete(config, "generate_key")
+			w2 := NewWrapper(nil)
+			if _, err := w2.SetConfig(config); err != nil {
+				t.Fatal(err)
+			}
+			defer w2.Finalize(ctx)
+			decrypted, err = w2.Decrypt(ctx, blob, []byte("aad"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decrypted, plaintext) {
+				t.Fatalf("bad: %q", decrypted)
+			}
+
+			// Tampered wrapped keys fail to decrypt
+			blob.KeyInfo.WrappedKey[len(blob.KeyInfo.WrappedKey)-1]++
+			if _, err := w.Decrypt(ctx, blob, []byte("aad")); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/go-hclog"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
 )
 
@@ -84,5 +85,11 @@ func (a *Access) Decrypt(ctx context.Context, data *wrapping.EncryptedBlobInfo,
 	metrics.IncrCounter([]string{"seal", "decrypt"}, 1)
 	metrics.IncrCounter([]string{"seal", a.Wrapper.Type(), "decrypt"}, 1)
 
+	// A single seal decrypts the values encrypted while it was combined with
+	// other seals with its own wrapped data encryption key
+	if _, ok := a.Wrapper.(*MultiWrapper); !ok && IsMultiSealBlob(data) {
+		return decryptMultiSeal(ctx, NewEnvelope(), hclog.NewNullLogger(), []*SealWrapper{{Wrapper: a.Wrapper}}, data, aad)
+	}
+
 	return a.Wrapper.Decrypt(ctx, data, aad)
 }
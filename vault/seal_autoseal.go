@@ -97,18 +97,19 @@ func (d *autoSeal) GetStoredKeys(ctx context.Context) ([][]byte, error) {
 	return readStoredKeys(ctx, d.core.physical, d.Access)
 }
 
-func (d *autoSeal) upgradeStoredKeys(ctx context.Context) error {
+// upgradeStoredKeys returns whether the stored keys were encrypted again
+func (d *autoSeal) upgradeStoredKeys(ctx context.Context) (bool, error) {
 	pe, err := d.core.physical.Get(ctx, StoredBarrierKeysPath)
 	if err != nil {
-		return fmt.Errorf("failed to fetch stored keys: %w", err)
+		return false, fmt.Errorf("failed to fetch stored keys: %w", err)
 	}
 	if pe == nil {
-		return fmt.Errorf("no stored keys found")
+		return false, fmt.Errorf("no stored keys found")
 	}
 
 	blobInfo := &wrapping.EncryptedBlobInfo{}
 	if err := proto.Unmarshal(pe.Value, blobInfo); err != nil {
-		return fmt.Errorf("failed to proto decode stored keys: %w", err)
+		return false, fmt.Errorf("failed to proto decode stored keys: %w", err)
 	}
 
 	if blobInfo.KeyInfo != nil && blobInfo.KeyInfo.KeyID != d.Access.KeyID() {
@@ -116,20 +117,21 @@ func (d *autoSeal) upgradeStoredKeys(ctx context.Context) error {
 
 		pt, err := d.Decrypt(ctx, blobInfo, nil)
 		if err != nil {
-			return fmt.Errorf("failed to decrypt encrypted stored keys: %w", err)
+			return false, fmt.Errorf("failed to decrypt encrypted stored keys: %w", err)
 		}
 
 		// Decode the barrier entry
 		var keys [][]byte
 		if err := json.Unmarshal(pt, &keys); err != nil {
-			return fmt.Errorf("failed to decode stored keys: %w", err)
+			return false, fmt.Errorf("failed to decode stored keys: %w", err)
 		}
 
 		if err := d.SetStoredKeys(ctx, keys); err != nil {
-			return fmt.Errorf("failed to save upgraded stored keys: %w", err)
+			return false, fmt.Errorf("failed to save upgraded stored keys: %w", err)
 		}
+		return true, nil
 	}
-	return nil
+	return false, nil
 }
 
 // UpgradeKeys re-encrypts and saves the stored keys and the recovery key
@@ -146,12 +148,59 @@ func (d *autoSeal) UpgradeKeys(ctx context.Context) error {
 	if err := d.upgradeRecoveryKey(ctx); err != nil {
 		return err
 	}
-	if err := d.upgradeStoredKeys(ctx); err != nil {
+	upgraded, err := d.upgradeStoredKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if err := d.upgradeBarrierConfig(ctx); err != nil {
+		return err
+	}
+
+	// When seals were added to or removed from a multi seal, the seal wrapped
+	// entries are also encrypted again, so that the seals they were encrypted
+	// with can be removed.
+	if _, ok := d.Access.Wrapper.(*seal.MultiWrapper); ok && upgraded && d.core.sealUnwrapper != nil {
+		if wrap, _ := d.core.sealWrapSeals(); wrap != nil {
+			d.core.rewrapSealWrappedEntries(ctx)
+		}
+	}
+	return nil
+}
+
+// upgradeBarrierConfig saves the barrier config with the current seal type
+// when seals were added to or removed from a multi seal. It must be called
+// once the stored keys have been encrypted with the current seal.
+func (d *autoSeal) upgradeBarrierConfig(ctx context.Context) error {
+	conf, err := d.BarrierConfig(ctx)
+	if err != nil {
 		return err
 	}
+	if conf == nil || conf.Type == d.BarrierType() {
+		return nil
+	}
+
+	d.logger.Info("upgrading barrier seal type", "from", conf.Type, "to", d.BarrierType())
+	if err := d.SetBarrierConfig(ctx, conf); err != nil {
+		return fmt.Errorf("failed to save upgraded barrier seal configuration: %w", err)
+	}
 	return nil
 }
 
+// sealTypesCompatible returns whether the keys stored by a seal of the stored
+// type can be read by a seal of the loaded type without a seal migration.
+// This is the case when seals are added to or removed from a multi seal, as
+// long as one of the seals is kept.
+func sealTypesCompatible(stored, loaded string) bool {
+	switch {
+	case stored == loaded:
+		return true
+	case stored == wrapping.Shamir || loaded == wrapping.Shamir:
+		return false
+	default:
+		return stored == seal.MultiSealType || loaded == seal.MultiSealType
+	}
+}
+
 func (d *autoSeal) BarrierConfig(ctx context.Context) (*SealConfig, error) {
 	if d.barrierConfig.Load().(*SealConfig) != nil {
 		return d.barrierConfig.Load().(*SealConfig).Clone(), nil
@@ -192,7 +241,7 @@ func (d *autoSeal) BarrierConfig(ctx context.Context) (*SealConfig, error) {
 
 	barrierTypeUpgradeCheck(d.BarrierType(), conf)
 
-	if conf.Type != d.BarrierType() {
+	if !sealTypesCompatible(conf.Type, d.BarrierType()) {
 		d.logger.Error("barrier seal type does not match loaded type", "seal_type", conf.Type, "loaded_type", d.BarrierType())
 		return nil, fmt.Errorf("barrier seal type of %q does not match loaded type of %q", conf.Type, d.BarrierType())
 	}
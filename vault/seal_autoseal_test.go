@@ -7,8 +7,11 @@ import (
 	"testing"
 
 	proto "github.com/golang/protobuf/proto"
+	log "github.com/hashicorp/go-hclog"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/vault/sdk/helper/logging"
 	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/physical/inmem"
 	"github.com/hashicorp/vault/vault/seal"
 )
 
@@ -157,3 +160,101 @@ func TestAutoSeal_UpgradeKeys(t *testing.T) {
 	}
 	check()
 }
+
+func TestAutoSeal_MultiSeal(t *testing.T) {
+	core, _, _ := TestCoreUnsealed(t)
+	phys, err := inmem.NewInmem(nil, logging.NewVaultLogger(log.Trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	core.physical = phys
+	ctx := context.Background()
+
+	wrappers := map[string]wrapping.Wrapper{
+		"a": wrapping.NewTestEnvelopeWrapper([]byte("a")),
+		"b": wrapping.NewTestEnvelopeWrapper([]byte("b")),
+		"c": wrapping.NewTestEnvelopeWrapper([]byte("c")),
+	}
+	newSeal := func(names ...string) *autoSeal {
+		wrapper := wrappers[names[0]]
+		if len(names) > 1 {
+			var sealWrappers []*seal.SealWrapper
+			for i, name := range names {
+				sealWrappers = append(sealWrappers, &seal.SealWrapper{
+					Wrapper:  wrappers[name],
+					Name:     name,
+					Priority: i,
+				})
+			}
+			wrapper = seal.NewMultiWrapper(nil, sealWrappers...)
+		}
+		s := NewAutoSeal(&seal.Access{Wrapper: wrapper})
+		s.SetCore(core)
+		return s
+	}
+
+	// Initialize with a single seal
+	current := newSeal("a")
+	if err := current.SetBarrierConfig(ctx, &SealConfig{SecretShares: 1, SecretThreshold: 1, StoredShares: 1}); err != nil {
+		t.Fatal(err)
+	}
+	inkeys := [][]byte{[]byte("grist"), []byte("house")}
+	if err := current.SetStoredKeys(ctx, inkeys); err != nil {
+		t.Fatal(err)
+	}
+	inRecoveryKey := []byte("falernum")
+	if err := current.SetRecoveryKey(ctx, inRecoveryKey); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(t *testing.T, s *autoSeal, barrierType string) {
+		t.Helper()
+		conf, err := s.BarrierConfig(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if conf.Type != barrierType {
+			t.Fatalf("bad barrier type: %s, expected %s", conf.Type, barrierType)
+		}
+		outkeys, err := s.GetStoredKeys(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(inkeys, outkeys) {
+			t.Fatalf("incorrect stored keys: want %v, got %v", inkeys, outkeys)
+		}
+		outRecoveryKey, err := s.RecoveryKey(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(inRecoveryKey, outRecoveryKey) {
+			t.Fatalf("incorrect recovery key: want %q, got %q", inRecoveryKey, outRecoveryKey)
+		}
+	}
+
+	// Seals are added and removed one at a time, the stored keys can be read
+	// before they are upgraded
+	barrierType := wrapping.Test
+	for _, step := range []struct {
+		seals       []string
+		barrierType string
+	}{
+		{[]string{"a", "b"}, seal.MultiSealType},
+		{[]string{"b"}, wrapping.Test},
+		{[]string{"b", "c"}, seal.MultiSealType},
+		{[]string{"c"}, wrapping.Test},
+	} {
+		current = newSeal(step.seals...)
+		check(t, current, barrierType)
+		if err := current.UpgradeKeys(ctx); err != nil {
+			t.Fatal(err)
+		}
+		barrierType = step.barrierType
+		check(t, newSeal(step.seals...), barrierType)
+	}
+
+	// The removed seals can no longer decrypt the stored keys
+	if _, err := newSeal("a", "b").GetStoredKeys(ctx); err == nil {
+		t.Fatal("expected error")
+	}
+}
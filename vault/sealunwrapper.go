@@ -3,24 +3,59 @@
 package vault
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"strings"
 	"sync/atomic"
 
 	proto "github.com/golang/protobuf/proto"
 	log "github.com/hashicorp/go-hclog"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/physical"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
 )
 
-// NewSealUnwrapper creates a new seal unwrapper
-func NewSealUnwrapper(underlying physical.Backend, logger log.Logger) physical.Backend {
+// sealWrapTypes are the seals that seal wrap the entries flagged for it. The
+// entries are written unwrapped with the other seals.
+var sealWrapTypes = map[string]bool{
+	wrapping.PKCS11: true,
+}
+
+// sealWrapSupported returns whether the seal wraps the entries flagged for it.
+// A multi seal does if all of its seals do.
+func sealWrapSupported(access *vaultseal.Access) bool {
+	multi, ok := access.Wrapper.(*vaultseal.MultiWrapper)
+	if !ok {
+		return sealWrapTypes[access.Type()]
+	}
+	for _, w := range multi.Wrappers() {
+		if !sealWrapTypes[w.Type()] {
+			return false
+		}
+	}
+	return true
+}
+
+// SealWrapSealsFunc returns the seal used to seal wrap new entries, which is
+// nil when seal wrapping is not available, and the seals that can unwrap the
+// existing entries.
+type SealWrapSealsFunc func() (wrap *vaultseal.Access, unwrap []*vaultseal.Access)
+
+// NewSealUnwrapper creates a new seal unwrapper. Entries are seal wrapped
+// with the seals returned by sealWrapSeals, which may be nil.
+func NewSealUnwrapper(underlying physical.Backend, logger log.Logger, sealWrapSeals SealWrapSealsFunc) physical.Backend {
+	if sealWrapSeals == nil {
+		sealWrapSeals = func() (*vaultseal.Access, []*vaultseal.Access) { return nil, nil }
+	}
 	ret := &sealUnwrapper{
-		underlying:   underlying,
-		logger:       logger,
-		locks:        locksutil.CreateLocks(),
-		allowUnwraps: new(uint32),
+		underlying:    underlying,
+		logger:        logger,
+		locks:         locksutil.CreateLocks(),
+		allowUnwraps:  new(uint32),
+		sealWrapSeals: sealWrapSeals,
 	}
 
 	if underTxn, ok := underlying.(physical.Transactional); ok {
@@ -33,16 +68,87 @@ func NewSealUnwrapper(underlying physical.Backend, logger log.Logger) physical.B
 	return ret
 }
 
+// sealWrapSeals returns the seals used to seal wrap entries. The seal migrated
+// from can still unwrap the existing entries while they are wrapped again with
+// the current seal.
+func (c *Core) sealWrapSeals() (*vaultseal.Access, []*vaultseal.Access) {
+	var wrap *vaultseal.Access
+	var unwrap []*vaultseal.Access
+	if c.seal != nil && c.seal.SealWrapable() {
+		access := c.seal.GetAccess()
+		if sealWrapSupported(access) && !c.disableSealWrap {
+			wrap = access
+		}
+		unwrap = append(unwrap, access)
+	}
+	if c.migrationInfo != nil && c.migrationInfo.seal != nil && c.migrationInfo.seal.SealWrapable() {
+		unwrap = append(unwrap, c.migrationInfo.seal.GetAccess())
+	}
+	return wrap, unwrap
+}
+
+// postSealMigration wraps again with the new seal the entries wrapped by the
+// seal migrated from. Entries are also wrapped again when they are read, but
+// the old seal may be removed from the configuration before all of them are.
+func (c *Core) postSealMigration(ctx context.Context) error {
+	if c.migrationInfo == nil || c.migrationInfo.seal == nil || !c.migrationInfo.seal.SealWrapable() {
+		return nil
+	}
+
+	c.rewrapSealWrappedEntries(ctx)
+	return nil
+}
+
+// rewrapSealWrappedEntries reads all the entries in storage in the
+// background, which wraps again the seal wrapped entries that were not
+// wrapped with the current seal.
+func (c *Core) rewrapSealWrappedEntries(ctx context.Context) {
+	unwrapper := c.sealUnwrapper
+	logger := c.logger
+	go func() {
+		logger.Info("wrapping seal wrapped entries with the new seal")
+		var walk func(prefix string) error
+		walk = func(prefix string) error {
+			keys, err := unwrapper.List(ctx, prefix)
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if strings.HasSuffix(key, "/") {
+					if err := walk(prefix + key); err != nil {
+						return err
+					}
+					continue
+				}
+				// Reading the entry wraps it again with the current seal
+				if _, err := unwrapper.Get(ctx, prefix+key); err != nil {
+					logger.Warn("failed to read storage entry", "key", prefix+key, "error", err)
+				}
+			}
+			return nil
+		}
+		if err := walk(""); err != nil {
+			logger.Error("failed to wrap seal wrapped entries with the new seal", "error", err)
+			return
+		}
+		logger.Info("seal wrapped entries wrapped with the new seal")
+	}()
+}
+
 var (
 	_ physical.Backend       = (*sealUnwrapper)(nil)
 	_ physical.Transactional = (*transactionalSealUnwrapper)(nil)
 )
 
 type sealUnwrapper struct {
-	underlying   physical.Backend
-	logger       log.Logger
-	locks        []*locksutil.LockEntry
-	allowUnwraps *uint32
+	underlying    physical.Backend
+	logger        log.Logger
+	locks         []*locksutil.LockEntry
+	allowUnwraps  *uint32
+	sealWrapSeals SealWrapSealsFunc
 }
 
 // transactionalSealUnwrapper is a seal unwrapper that wraps a physical that is transactional
@@ -59,9 +165,91 @@ func (d *sealUnwrapper) Put(ctx context.Context, entry *physical.Entry) error {
 	locksutil.LockForKey(d.locks, entry.Key).Lock()
 	defer locksutil.LockForKey(d.locks, entry.Key).Unlock()
 
+	entry, err := d.sealWrap(ctx, entry)
+	if err != nil {
+		return err
+	}
 	return d.underlying.Put(ctx, entry)
 }
 
+// sealWrap encrypts the value of entries flagged for seal wrapping with the
+// seal, when it supports it
+func (d *sealUnwrapper) sealWrap(ctx context.Context, entry *physical.Entry) (*physical.Entry, error) {
+	if !entry.SealWrap {
+		return entry, nil
+	}
+	access, _ := d.sealWrapSeals()
+	if access == nil {
+		return entry, nil
+	}
+
+	blob, err := access.Encrypt(ctx, entry.Value, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal wrap storage entry %q: %w", entry.Key, err)
+	}
+	blob.Wrapped = true
+	value, err := proto.Marshal(blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode seal wrapped storage entry %q: %w", entry.Key, err)
+	}
+
+	return &physical.Entry{
+		Key:      entry.Key,
+		Value:    append(value, 's'),
+		SealWrap: true,
+	}, nil
+}
+
+// sealUnwrap decrypts a seal wrapped entry. It returns whether the entry
+// should be written again, because it was wrapped by another seal than the
+// current one, e.g. the seal migrated from, or because seal wrapping is now
+// disabled.
+func (d *sealUnwrapper) sealUnwrap(ctx context.Context, entry *physical.Entry, se *wrapping.EncryptedBlobInfo) (*physical.Entry, bool, error) {
+	access, unwrapSeals := d.sealWrapSeals()
+	if len(unwrapSeals) == 0 {
+		return nil, false, fmt.Errorf("cannot decode sealwrapped storage entry %q", entry.Key)
+	}
+
+	var retErr *multierror.Error
+	for _, unwrapSeal := range unwrapSeals {
+		value, err := unwrapSeal.Decrypt(ctx, se, nil)
+		if err != nil {
+			retErr = multierror.Append(retErr, err)
+			continue
+		}
+
+		// Entries are also wrapped again when seals were added to or removed
+		// from a multi seal since they were wrapped
+		rewrap := unwrapSeal != access
+		if _, ok := unwrapSeal.Wrapper.(*vaultseal.MultiWrapper); ok && !rewrap && se.KeyInfo != nil {
+			rewrap = se.KeyInfo.KeyID != unwrapSeal.KeyID()
+		}
+
+		return &physical.Entry{
+			Key:      entry.Key,
+			Value:    value,
+			SealWrap: true,
+		}, rewrap, nil
+	}
+	return nil, false, fmt.Errorf("cannot decode sealwrapped storage entry %q: %w", entry.Key, retErr.ErrorOrNil())
+}
+
+// rewrap writes an unwrapped entry again with the current seal, unless it was
+// changed since it was read. The lock of the key must be held.
+func (d *sealUnwrapper) rewrap(ctx context.Context, read, unwrapped *physical.Entry) {
+	current, err := d.underlying.Get(ctx, read.Key)
+	if err == nil && current != nil && bytes.Equal(current.Value, read.Value) {
+		var entry *physical.Entry
+		entry, err = d.sealWrap(ctx, unwrapped)
+		if err == nil {
+			err = d.underlying.Put(ctx, entry)
+		}
+	}
+	if err != nil {
+		d.logger.Warn("failed to seal wrap storage entry with the current seal", "key", read.Key, "error", err)
+	}
+}
+
 func (d *sealUnwrapper) Get(ctx context.Context, key string) (*physical.Entry, error) {
 	entry, err := d.underlying.Get(ctx, key)
 	if err != nil {
@@ -85,9 +273,17 @@ func (d *sealUnwrapper) Get(ctx context.Context, key string) (*physical.Entry, e
 	if !performUnwrap {
 		return entry, nil
 	}
-	// It's actually encrypted and we can't read it
+	// It's actually encrypted with the seal
 	if se.Wrapped {
-		return nil, fmt.Errorf("cannot decode sealwrapped storage entry %q", entry.Key)
+		unwrapped, rewrap, err := d.sealUnwrap(ctx, entry, se)
+		if err != nil || !rewrap || atomic.LoadUint32(d.allowUnwraps) != 1 {
+			return unwrapped, err
+		}
+
+		locksutil.LockForKey(d.locks, key).Lock()
+		defer locksutil.LockForKey(d.locks, key).Unlock()
+		d.rewrap(ctx, entry, unwrapped)
+		return unwrapped, nil
 	}
 	if atomic.LoadUint32(d.allowUnwraps) != 1 {
 		return &physical.Entry{
@@ -125,7 +321,11 @@ func (d *sealUnwrapper) Get(ctx context.Context, key string) (*physical.Entry, e
 		return entry, nil
 	}
 	if se.Wrapped {
-		return nil, fmt.Errorf("cannot decode sealwrapped storage entry %q", entry.Key)
+		unwrapped, rewrap, err := d.sealUnwrap(ctx, entry, se)
+		if err == nil && rewrap {
+			d.rewrap(ctx, entry, unwrapped)
+		}
+		return unwrapped, err
 	}
 
 	entry = &physical.Entry{
@@ -162,7 +362,22 @@ func (d *transactionalSealUnwrapper) Transaction(ctx context.Context, txns []*ph
 		defer l.Unlock()
 	}
 
-	if err := d.Transactional.Transaction(ctx, txns); err != nil {
+	wrapped := make([]*physical.TxnEntry, 0, len(txns))
+	for _, curr := range txns {
+		if curr.Operation == physical.PutOperation {
+			entry, err := d.sealWrap(ctx, curr.Entry)
+			if err != nil {
+				return err
+			}
+			curr = &physical.TxnEntry{
+				Operation: curr.Operation,
+				Entry:     entry,
+			}
+		}
+		wrapped = append(wrapped, curr)
+	}
+
+	if err := d.Transactional.Transaction(ctx, wrapped); err != nil {
 		return err
 	}
 
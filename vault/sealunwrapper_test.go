@@ -11,8 +11,12 @@ import (
 	proto "github.com/golang/protobuf/proto"
 	log "github.com/hashicorp/go-hclog"
 	wrapping "github.com/hashicorp/go-kms-wrapping"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/hashicorp/vault/sdk/physical"
 	"github.com/hashicorp/vault/sdk/physical/inmem"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
 )
 
 func TestSealUnwrapper(t *testing.T) {
@@ -106,3 +110,148 @@ func performTestSealUnwrapper(t *testing.T, phys physical.Backend, logger log.Lo
 	checkValue(cluster.Cores[1].Core, true)
 	checkValue(cluster.Cores[0].Core, false)
 }
+
+func TestSealUnwrapper_SealWrap(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewVaultLogger(log.Trace)
+	phys, err := inmem.NewTransactionalInmem(nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Envelope test wrappers fail to decrypt with the wrong secret
+	oldSeal := &vaultseal.Access{Wrapper: wrapping.NewTestEnvelopeWrapper([]byte("old")), OverriddenType: wrapping.PKCS11}
+	newSeal := &vaultseal.Access{Wrapper: wrapping.NewTestEnvelopeWrapper([]byte("new")), OverriddenType: wrapping.PKCS11}
+	var wrap *vaultseal.Access
+	var unwrap []*vaultseal.Access
+	b := NewSealUnwrapper(phys, logger, func() (*vaultseal.Access, []*vaultseal.Access) {
+		return wrap, unwrap
+	})
+	su := b.(*transactionalSealUnwrapper)
+	su.runUnwraps()
+
+	isWrapped := func(key string) bool {
+		t.Helper()
+		entry, err := phys.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		se := &wrapping.EncryptedBlobInfo{}
+		return entry.Value[len(entry.Value)-1] == 's' &&
+			proto.Unmarshal(entry.Value[:len(entry.Value)-1], se) == nil && se.Wrapped
+	}
+	checkValue := func(key string) {
+		t.Helper()
+		entry, err := b.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || !bytes.Equal(entry.Value, []byte("value")) {
+			t.Fatalf("bad: %#v", entry)
+		}
+	}
+
+	// Without a seal wrap capable seal, entries are written as is
+	if err := b.Put(ctx, &physical.Entry{Key: "plain", Value: []byte("value"), SealWrap: true}); err != nil {
+		t.Fatal(err)
+	}
+	if isWrapped("plain") {
+		t.Fatal("should not be seal wrapped")
+	}
+
+	wrap, unwrap = oldSeal, []*vaultseal.Access{oldSeal}
+	if err := b.Put(ctx, &physical.Entry{Key: "wrapped", Value: []byte("value"), SealWrap: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := su.Transaction(ctx, []*physical.TxnEntry{
+		{Operation: physical.PutOperation, Entry: &physical.Entry{Key: "txn", Value: []byte("value"), SealWrap: true}},
+		{Operation: physical.PutOperation, Entry: &physical.Entry{Key: "unflagged", Value: []byte("value")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for key, wrapped := range map[string]bool{"wrapped": true, "txn": true, "unflagged": false} {
+		if isWrapped(key) != wrapped {
+			t.Fatalf("%s: expected seal wrapped to be %t", key, wrapped)
+		}
+		checkValue(key)
+	}
+
+	// The entries cannot be read without the seal
+	wrap, unwrap = nil, nil
+	if _, err := b.Get(ctx, "wrapped"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// After a seal migration, the entries are unwrapped with the old seal, and
+	// wrapped again with the new one when read
+	wrap, unwrap = newSeal, []*vaultseal.Access{newSeal, oldSeal}
+	checkValue("wrapped")
+	wrap, unwrap = newSeal, []*vaultseal.Access{newSeal}
+	checkValue("wrapped")
+	if _, err := b.Get(ctx, "txn"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// When seal wrapping is disabled, the entries are written unwrapped
+	wrap = nil
+	checkValue("wrapped")
+	if isWrapped("wrapped") {
+		t.Fatal("should not be seal wrapped")
+	}
+}
+
+func TestCore_SealWrap(t *testing.T) {
+	c, _, _, root := TestCoreUnsealedWithConfigSealOpts(t,
+		&SealConfig{StoredShares: 1, SecretShares: 1, SecretThreshold: 1},
+		&SealConfig{StoredShares: 1, SecretShares: 1, SecretThreshold: 1},
+		&vaultseal.TestSealOpts{StoredKeys: vaultseal.StoredKeysSupportedGeneric, Name: wrapping.PKCS11})
+	ctx := namespace.RootContext(nil)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := c.HandleRequest(ctx, &logical.Request{
+			Operation:   op,
+			Path:        path,
+			ClientToken: root,
+			Data:        data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("bad: %v %v", resp, err)
+		}
+		return resp
+	}
+	isWrapped := func(mount, key string) bool {
+		t.Helper()
+		me := c.router.MatchingMountEntry(ctx, mount)
+		if me == nil {
+			t.Fatalf("missing mount %q", mount)
+		}
+		entry, err := c.underlyingPhysical.Get(ctx, backendBarrierPrefix+me.UUID+"/"+key)
+		if err != nil || entry == nil {
+			t.Fatalf("bad: %v %v", entry, err)
+		}
+		se := &wrapping.EncryptedBlobInfo{}
+		return entry.Value[len(entry.Value)-1] == 's' &&
+			proto.Unmarshal(entry.Value[:len(entry.Value)-1], se) == nil && se.Wrapped
+	}
+
+	request(logical.UpdateOperation, "sys/mounts/wrapped", map[string]interface{}{"type": "kv", "seal_wrap": true})
+	request(logical.UpdateOperation, "sys/mounts/plain", map[string]interface{}{"type": "kv"})
+	for _, mount := range []string{"wrapped/", "plain/"} {
+		request(logical.UpdateOperation, mount+"foo", map[string]interface{}{"bar": "baz"})
+	}
+
+	if !isWrapped("wrapped/", "foo") {
+		t.Fatal("expected the entry to be seal wrapped")
+	}
+	if isWrapped("plain/", "foo") {
+		t.Fatal("should not be seal wrapped")
+	}
+
+	// Seal wrapped entries are unwrapped when read, bypassing the cache
+	c.physicalCache.Purge(ctx)
+	resp := request(logical.ReadOperation, "wrapped/foo", nil)
+	if resp == nil || resp.Data["bar"] != "baz" {
+		t.Fatalf("bad: %#v", resp)
+	}
+}
@@ -0,0 +1,446 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/physical/raft"
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+)
+
+const (
+	// storageMigrationLockPath is the key used by the offline migration to
+	// prevent servers from starting during a migration. The online migration
+	// writes it to the old storage once it has switched to the new one.
+	storageMigrationLockPath = "core/migration"
+
+	StorageMigrationStatusIdle       = "idle"
+	StorageMigrationStatusInProgress = "in-progress"
+	StorageMigrationStatusComplete   = "complete"
+	StorageMigrationStatusFailed     = "failed"
+	StorageMigrationStatusCanceled   = "canceled"
+)
+
+var errStorageMigrationStopped = errors.New("storage migration stopped")
+
+// storageMigrationMarker is written to the old storage once the online
+// migration switched to the new one. It is compatible with the lock written
+// by the offline migration, so that servers still using the old storage
+// refuse to start.
+type storageMigrationMarker struct {
+	Start       time.Time `json:"start"`
+	Destination string    `json:"destination,omitempty"`
+}
+
+// storageMigrationStatus is the progress of an online storage migration
+type storageMigrationStatus struct {
+	Status          string
+	DestinationType string
+	StartTime       time.Time
+	CompleteTime    time.Time
+	KeysCopied      int64
+	Error           string
+}
+
+// storageMigrator is a physical backend wrapper that allows switching the
+// storage of a running server. While a migration is in progress, the writes
+// go to both the current and the destination backends, and the existing
+// entries are copied in the background. Once everything is copied, the
+// destination becomes the current backend.
+type storageMigrator struct {
+	logger log.Logger
+	locks  []*locksutil.LockEntry
+
+	// l guards the backends. Writes hold it for reading so that the switch to
+	// the destination waits for them to complete.
+	l           sync.RWMutex
+	current     physical.Backend
+	destination physical.Backend
+
+	errLock      sync.Mutex
+	dualWriteErr error
+}
+
+// transactionalStorageMigrator is a storage migrator that wraps a physical
+// backend that is transactional
+type transactionalStorageMigrator struct {
+	*storageMigrator
+}
+
+var (
+	_ physical.Backend       = (*storageMigrator)(nil)
+	_ physical.Transactional = (*transactionalStorageMigrator)(nil)
+)
+
+func newStorageMigrator(underlying physical.Backend, logger log.Logger) *storageMigrator {
+	return &storageMigrator{
+		logger:  logger,
+		locks:   locksutil.CreateLocks(),
+		current: underlying,
+	}
+}
+
+// backend returns the migrator as a physical backend, which is transactional
+// if the underlying backend is
+func (m *storageMigrator) backend() physical.Backend {
+	if _, ok := m.current.(physical.Transactional); ok {
+		return &transactionalStorageMigrator{storageMigrator: m}
+	}
+	return m
+}
+
+func (m *storageMigrator) Get(ctx context.Context, key string) (*physical.Entry, error) {
+	m.l.RLock()
+	current := m.current
+	m.l.RUnlock()
+
+	return current.Get(ctx, key)
+}
+
+func (m *storageMigrator) List(ctx context.Context, prefix string) ([]string, error) {
+	m.l.RLock()
+	current := m.current
+	m.l.RUnlock()
+
+	return current.List(ctx, prefix)
+}
+
+func (m *storageMigrator) Put(ctx context.Context, entry *physical.Entry) error {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	if m.destination == nil {
+		return m.current.Put(ctx, entry)
+	}
+
+	lock := locksutil.LockForKey(m.locks, entry.Key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := m.current.Put(ctx, entry); err != nil {
+		return err
+	}
+	if err := m.destination.Put(ctx, entry); err != nil {
+		m.setDualWriteError(fmt.Errorf("failed to write %q to the destination: %w", entry.Key, err))
+	}
+	return nil
+}
+
+func (m *storageMigrator) Delete(ctx context.Context, key string) error {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	if m.destination == nil {
+		return m.current.Delete(ctx, key)
+	}
+
+	lock := locksutil.LockForKey(m.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := m.current.Delete(ctx, key); err != nil {
+		return err
+	}
+	if err := m.destination.Delete(ctx, key); err != nil {
+		m.setDualWriteError(fmt.Errorf("failed to delete %q from the destination: %w", key, err))
+	}
+	return nil
+}
+
+func (m *transactionalStorageMigrator) Transaction(ctx context.Context, txns []*physical.TxnEntry) error {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	if m.destination == nil {
+		return m.current.(physical.Transactional).Transaction(ctx, txns)
+	}
+
+	keys := make([]string, 0, len(txns))
+	for _, txn := range txns {
+		keys = append(keys, txn.Entry.Key)
+	}
+	for _, lock := range locksutil.LocksForKeys(m.locks, keys) {
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	if err := m.current.(physical.Transactional).Transaction(ctx, txns); err != nil {
+		return err
+	}
+	if err := m.destination.(physical.Transactional).Transaction(ctx, txns); err != nil {
+		m.setDualWriteError(fmt.Errorf("failed to apply transaction to the destination: %w", err))
+	}
+	return nil
+}
+
+func (m *storageMigrator) setDualWriteError(err error) {
+	m.errLock.Lock()
+	defer m.errLock.Unlock()
+	if m.dualWriteErr == nil {
+		m.logger.Error("storage migration write failed", "error", err)
+		m.dualWriteErr = err
+	}
+}
+
+func (m *storageMigrator) dualWriteError() error {
+	m.errLock.Lock()
+	defer m.errLock.Unlock()
+	return m.dualWriteErr
+}
+
+// startDualWrite starts writing to the destination in addition to the
+// current backend
+func (m *storageMigrator) startDualWrite(destination physical.Backend) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.destination != nil {
+		return errors.New("storage migration already in progress")
+	}
+	if _, ok := m.current.(physical.Transactional); ok {
+		if _, ok := destination.(physical.Transactional); !ok {
+			return errors.New("destination storage must support transactions like the current storage")
+		}
+	}
+
+	m.errLock.Lock()
+	m.dualWriteErr = nil
+	m.errLock.Unlock()
+	m.destination = destination
+	return nil
+}
+
+// stopDualWrite abandons the destination
+func (m *storageMigrator) stopDualWrite() {
+	m.l.Lock()
+	defer m.l.Unlock()
+	m.destination = nil
+}
+
+// copyKey copies the entry at the given key from the current backend to the
+// destination, and reports whether there was one
+func (m *storageMigrator) copyKey(ctx context.Context, key string) (bool, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	if m.destination == nil {
+		return false, errStorageMigrationStopped
+	}
+
+	lock := locksutil.LockForKey(m.locks, key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := m.current.Get(ctx, key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %q: %w", key, err)
+	}
+	if entry == nil {
+		return false, nil
+	}
+	if err := m.destination.Put(ctx, entry); err != nil {
+		return false, fmt.Errorf("failed to write %q to the destination: %w", key, err)
+	}
+	return true, nil
+}
+
+// cutover writes the marker to the current backend and makes the destination
+// the current backend. No write can happen in between.
+func (m *storageMigrator) cutover(ctx context.Context, marker *physical.Entry) error {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if m.destination == nil {
+		return errStorageMigrationStopped
+	}
+	if err := m.dualWriteError(); err != nil {
+		return err
+	}
+
+	if err := m.current.Put(ctx, marker); err != nil {
+		return fmt.Errorf("failed to write the migration marker: %w", err)
+	}
+	m.current = m.destination
+	m.destination = nil
+	return nil
+}
+
+// checkStorageMigrated prevents a node from becoming active using a storage
+// that was migrated online to another one, as it is no longer up to date.
+func (c *Core) checkStorageMigrated(ctx context.Context) error {
+	entry, err := c.storageMigrator.Get(ctx, storageMigrationLockPath)
+	if err != nil {
+		return fmt.Errorf("failed to check storage migration: %w", err)
+	}
+	if entry == nil {
+		return nil
+	}
+
+	var marker storageMigrationMarker
+	if err := jsonutil.DecodeJSON(entry.Value, &marker); err != nil {
+		return fmt.Errorf("failed to decode storage migration marker: %w", err)
+	}
+	if marker.Destination == "" {
+		return nil
+	}
+	return fmt.Errorf("storage was migrated to %q, update the storage configuration", marker.Destination)
+}
+
+// startStorageMigration starts migrating the storage to a new backend of the
+// given type
+func (c *Core) startStorageMigration(ctx context.Context, backendType string, conf map[string]string) error {
+	c.storageMigrationLock.Lock()
+	defer c.storageMigrationLock.Unlock()
+
+	if status := c.storageMigrationStatus; status != nil && status.Status == StorageMigrationStatusInProgress {
+		return errors.New("storage migration already in progress")
+	}
+	if _, ok := c.underlyingPhysical.(*raft.RaftBackend); ok || backendType == "raft" {
+		return errors.New("integrated storage cannot be migrated online, use 'vault operator migrate' instead")
+	}
+	// The active node keeps holding the HA lock in the old storage after the
+	// cutover, so a standby restarted with the new storage configuration
+	// would find the lock free and become active as well
+	if ha, ok := c.ha.(physical.Backend); ok && ha == c.underlyingPhysical {
+		return errors.New("storage used for high availability cannot be migrated online, configure a separate 'ha_storage' or use 'vault operator migrate' instead")
+	}
+
+	factory, ok := c.physicalBackends[backendType]
+	if !ok {
+		return fmt.Errorf("unknown storage type %q", backendType)
+	}
+	destination, err := factory(conf, c.baseLogger.Named("storage."+backendType))
+	if err != nil {
+		return fmt.Errorf("failed to create the destination storage: %w", err)
+	}
+
+	// Keys that are only in the destination would be left behind
+	keys, err := destination.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list the destination storage: %w", err)
+	}
+	if len(keys) > 0 {
+		return errors.New("destination storage is not empty")
+	}
+
+	if err := c.storageMigrator.startDualWrite(destination); err != nil {
+		return err
+	}
+
+	status := &storageMigrationStatus{
+		Status:          StorageMigrationStatusInProgress,
+		DestinationType: backendType,
+		StartTime:       time.Now(),
+	}
+	c.storageMigrationStatus = status
+
+	migrationCtx, cancel := context.WithCancel(c.activeContext)
+	c.storageMigrationCancel = cancel
+	go c.runStorageMigration(migrationCtx, status)
+
+	c.logger.Info("storage migration started", "destination", backendType)
+	return nil
+}
+
+func (c *Core) runStorageMigration(ctx context.Context, status *storageMigrationStatus) {
+	err := c.copyStorage(ctx, status)
+	if err == nil {
+		var entry *logical.StorageEntry
+		entry, err = logical.StorageEntryJSON(storageMigrationLockPath, &storageMigrationMarker{
+			Start:       status.StartTime,
+			Destination: status.DestinationType,
+		})
+		if err == nil {
+			err = c.storageMigrator.cutover(ctx, &physical.Entry{
+				Key:   entry.Key,
+				Value: entry.Value,
+			})
+		}
+	}
+	if err != nil {
+		c.storageMigrator.stopDualWrite()
+	}
+
+	c.storageMigrationLock.Lock()
+	defer c.storageMigrationLock.Unlock()
+	status.CompleteTime = time.Now()
+	switch {
+	case err == nil:
+		status.Status = StorageMigrationStatusComplete
+		c.logger.Info("storage migration complete", "destination", status.DestinationType, "keys", status.KeysCopied)
+	case ctx.Err() != nil:
+		status.Status = StorageMigrationStatusCanceled
+		c.logger.Info("storage migration canceled", "destination", status.DestinationType)
+	default:
+		status.Status = StorageMigrationStatusFailed
+		status.Error = err.Error()
+		c.logger.Error("storage migration failed", "destination", status.DestinationType, "error", err)
+	}
+
+	if c.storageMigrationCancel != nil {
+		c.storageMigrationCancel()
+		c.storageMigrationCancel = nil
+	}
+}
+
+// copyStorage copies all the existing entries to the destination
+func (c *Core) copyStorage(ctx context.Context, status *storageMigrationStatus) error {
+	copyCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var copyErr error
+	err := logical.ScanView(copyCtx, c.storageMigrator, func(path string) {
+		if path == storageMigrationLockPath || path == CoreLockPath {
+			return
+		}
+		if err := c.storageMigrator.dualWriteError(); err != nil {
+			copyErr = err
+			cancel()
+			return
+		}
+
+		copied, err := c.storageMigrator.copyKey(copyCtx, path)
+		if err != nil {
+			copyErr = err
+			cancel()
+			return
+		}
+		if copied {
+			c.storageMigrationLock.Lock()
+			status.KeysCopied++
+			c.storageMigrationLock.Unlock()
+		}
+	})
+	if copyErr != nil {
+		return copyErr
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// cancelStorageMigration stops the migration in progress, if any
+func (c *Core) cancelStorageMigration() {
+	c.storageMigrationLock.Lock()
+	cancel := c.storageMigrationCancel
+	c.storageMigrationCancel = nil
+	c.storageMigrationLock.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// currentStorageMigrationStatus returns a copy of the status of the last
+// online storage migration, or nil
+func (c *Core) currentStorageMigrationStatus() *storageMigrationStatus {
+	c.storageMigrationLock.Lock()
+	defer c.storageMigrationLock.Unlock()
+	if c.storageMigrationStatus == nil {
+		return nil
+	}
+	status := *c.storageMigrationStatus
+	return &status
+}
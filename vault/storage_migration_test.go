@@ -0,0 +1,306 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/sdk/physical"
+	"github.com/hashicorp/vault/sdk/physical/inmem"
+)
+
+func TestStorageMigrator_DualWrite(t *testing.T) {
+	ctx := context.Background()
+	source, _ := inmem.NewTransactionalInmem(nil, logger)
+	destination, _ := inmem.NewTransactionalInmem(nil, logger)
+
+	m := newStorageMigrator(source, logger)
+	b := m.backend()
+	if _, ok := b.(physical.Transactional); !ok {
+		t.Fatal("expected a transactional migrator")
+	}
+
+	if err := b.Put(ctx, &physical.Entry{Key: "existing", Value: []byte("1")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	nonTxn, _ := inmem.NewInmem(nil, logger)
+	if err := m.startDualWrite(nonTxn); err == nil {
+		t.Fatal("expected error with a non transactional destination")
+	}
+	if err := m.startDualWrite(destination); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Writes go to both backends
+	if err := b.Put(ctx, &physical.Entry{Key: "foo", Value: []byte("bar")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.(physical.Transactional).Transaction(ctx, []*physical.TxnEntry{
+		{Operation: physical.PutOperation, Entry: &physical.Entry{Key: "txn", Value: []byte("1")}},
+		{Operation: physical.DeleteOperation, Entry: &physical.Entry{Key: "foo"}},
+	}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, backend := range []physical.Backend{source, destination} {
+		if entry, _ := backend.Get(ctx, "foo"); entry != nil {
+			t.Fatalf("bad: %#v", entry)
+		}
+		if entry, _ := backend.Get(ctx, "txn"); entry == nil {
+			t.Fatal("missing transaction entry")
+		}
+	}
+
+	// The existing entries are copied
+	if entry, _ := destination.Get(ctx, "existing"); entry != nil {
+		t.Fatalf("bad: %#v", entry)
+	}
+	copied, err := m.copyKey(ctx, "existing")
+	if err != nil || !copied {
+		t.Fatalf("bad: %v %v", copied, err)
+	}
+	if entry, _ := destination.Get(ctx, "existing"); entry == nil || string(entry.Value) != "1" {
+		t.Fatalf("bad: %#v", entry)
+	}
+
+	// After the cutover, only the destination is used
+	if err := m.cutover(ctx, &physical.Entry{Key: storageMigrationLockPath, Value: []byte("{}")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.Put(ctx, &physical.Entry{Key: "after", Value: []byte("1")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry, _ := source.Get(ctx, "after"); entry != nil {
+		t.Fatalf("bad: %#v", entry)
+	}
+	if entry, _ := b.Get(ctx, "after"); entry == nil {
+		t.Fatal("missing entry")
+	}
+	if entry, _ := source.Get(ctx, storageMigrationLockPath); entry == nil {
+		t.Fatal("missing migration marker")
+	}
+	if entry, _ := b.Get(ctx, storageMigrationLockPath); entry != nil {
+		t.Fatalf("bad: %#v", entry)
+	}
+}
+
+func testWaitStorageMigration(t *testing.T, c *Core) *storageMigrationStatus {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		status := c.currentStorageMigrationStatus()
+		if status != nil && status.Status != StorageMigrationStatusInProgress {
+			return status
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("storage migration did not complete")
+	return nil
+}
+
+func TestCore_StorageMigration(t *testing.T) {
+	var destination physical.Backend
+	c, _, root := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		PhysicalBackends: map[string]physical.Factory{
+			"inmem": func(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+				var err error
+				destination, err = inmem.NewInmem(conf, logger)
+				if err == nil && conf["prefill"] != "" {
+					err = destination.Put(context.Background(), &physical.Entry{Key: "prefill"})
+				}
+				return destination, err
+			},
+		},
+	})
+	ctx := namespace.RootContext(nil)
+	source := c.underlyingPhysical
+
+	for i := 0; i < 100; i++ {
+		if err := c.barrier.Put(ctx, &logical.StorageEntry{
+			Key:   fmt.Sprintf("test/migration/%d", i),
+			Value: []byte("before"),
+		}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	migrate := func(data map[string]interface{}) (*logical.Response, error) {
+		return c.HandleRequest(ctx, &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "sys/storage/migration",
+			ClientToken: root,
+			Data:        data,
+		})
+	}
+
+	resp, err := c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/migration",
+		ClientToken: root,
+	})
+	if err != nil || resp.Data["status"] != StorageMigrationStatusIdle {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+
+	resp, err = migrate(map[string]interface{}{"type": "missing"})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error, got: %v", resp)
+	}
+	resp, err = migrate(map[string]interface{}{"type": "inmem", "config": map[string]interface{}{"prefill": "true"}})
+	if err == nil || !resp.IsError() {
+		t.Fatalf("expected error for a non empty destination, got: %v", resp)
+	}
+
+	// Keep writing while the migration runs
+	var wg sync.WaitGroup
+	stopCh := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stopCh:
+				return
+			default:
+			}
+			if err := c.barrier.Put(ctx, &logical.StorageEntry{
+				Key:   fmt.Sprintf("test/concurrent/%d", i%100),
+				Value: []byte(fmt.Sprintf("during-%d", i)),
+			}); err != nil {
+				t.Errorf("err: %v", err)
+				return
+			}
+		}
+	}()
+
+	resp, err = migrate(map[string]interface{}{"type": "inmem"})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	status := testWaitStorageMigration(t, c)
+	close(stopCh)
+	wg.Wait()
+	if status.Status != StorageMigrationStatusComplete || status.KeysCopied < 100 {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// Every entry of the old storage made it to the new one. The concurrent
+	// writes that happened after the cutover only went to the new storage.
+	keys, err := logical.CollectKeys(ctx, source)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, key := range keys {
+		if key == storageMigrationLockPath || key == CoreLockPath {
+			continue
+		}
+		before, err := source.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		after, err := destination.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if after == nil {
+			t.Fatalf("entry %q was not migrated", key)
+		}
+		if strings.HasPrefix(key, "test/migration/") && !bytes.Equal(before.Value, after.Value) {
+			t.Fatalf("entry %q was not migrated", key)
+		}
+	}
+
+	// The old storage is marked as migrated, and no longer written to
+	marker, err := source.Get(ctx, storageMigrationLockPath)
+	if err != nil || marker == nil || !bytes.Contains(marker.Value, []byte(`"destination":"inmem"`)) {
+		t.Fatalf("bad: %v %v", marker, err)
+	}
+	if err := c.barrier.Put(ctx, &logical.StorageEntry{Key: "test/after", Value: []byte("after")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry, _ := source.Get(ctx, "test/after"); entry != nil {
+		t.Fatalf("bad: %#v", entry)
+	}
+	entry, err := c.barrier.Get(ctx, "test/after")
+	if err != nil || entry == nil {
+		t.Fatalf("bad: %v %v", entry, err)
+	}
+
+	resp, err = c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/migration",
+		ClientToken: root,
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if resp.Data["status"] != StorageMigrationStatusComplete || resp.Data["destination_type"] != "inmem" {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+}
+
+func TestCore_StorageMigration_Failure(t *testing.T) {
+	c, _, root := TestCoreUnsealedWithConfig(t, &CoreConfig{
+		PhysicalBackends: map[string]physical.Factory{
+			"broken": func(conf map[string]string, logger log.Logger) (physical.Backend, error) {
+				b, err := inmem.NewInmem(conf, logger)
+				if err != nil {
+					return nil, err
+				}
+				return &brokenPutBackend{Backend: b}, nil
+			},
+		},
+	})
+	ctx := namespace.RootContext(nil)
+
+	resp, err := c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/storage/migration",
+		ClientToken: root,
+		Data:        map[string]interface{}{"type": "broken"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	status := testWaitStorageMigration(t, c)
+	if status.Status != StorageMigrationStatusFailed || status.Error == "" {
+		t.Fatalf("bad: %#v", status)
+	}
+
+	// The current storage is still in use
+	if err := c.barrier.Put(ctx, &logical.StorageEntry{Key: "test/after", Value: []byte("after")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if entry, _ := c.underlyingPhysical.Get(ctx, "test/after"); entry == nil {
+		t.Fatal("missing entry")
+	}
+}
+
+func TestCore_StorageMigration_HA(t *testing.T) {
+	// The storage being migrated cannot hold the HA lock
+	haBackend, _ := inmem.NewInmemHA(nil, logger)
+	c := &Core{
+		underlyingPhysical: haBackend,
+		ha:                 haBackend.(physical.HABackend),
+	}
+	err := c.startStorageMigration(namespace.RootContext(nil), "inmem", nil)
+	if err == nil || !strings.Contains(err.Error(), "ha_storage") {
+		t.Fatalf("expected an error, got: %v", err)
+	}
+}
+
+// brokenPutBackend is a backend on which every write fails
+type brokenPutBackend struct {
+	physical.Backend
+}
+
+func (b *brokenPutBackend) Put(context.Context, *physical.Entry) error {
+	return fmt.Errorf("broken")
+}
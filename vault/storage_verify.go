@@ -0,0 +1,359 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/jsonutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/shamir"
+	vaultseal "github.com/hashicorp/vault/vault/seal"
+)
+
+const (
+	// StorageVerifyTruncated is reported for entries that are too short to
+	// hold an encrypted value
+	StorageVerifyTruncated = "truncated"
+
+	// StorageVerifyUndecryptable is reported for entries that cannot be
+	// decrypted with the keyring
+	StorageVerifyUndecryptable = "undecryptable"
+
+	// StorageVerifyOrphaned is reported for entries of a secrets engine, auth
+	// method or audit device that is not in the mount tables
+	StorageVerifyOrphaned = "orphaned"
+
+	// storageVerifyBatchSize is the number of entries checked before the
+	// background verification publishes its progress and pauses for
+	// storageVerifyBatchInterval, so that it does not starve the storage
+	// backend
+	storageVerifyBatchSize     = 256
+	storageVerifyBatchInterval = 250 * time.Millisecond
+
+	storageVerifyStatusInProgress = "in-progress"
+	storageVerifyStatusComplete   = "complete"
+	storageVerifyStatusFailed     = "failed"
+)
+
+// storageVerifyPlaintextPaths are the entries written outside of the barrier,
+// which cannot be decrypted with the keyring
+var storageVerifyPlaintextPaths = map[string]bool{
+	barrierSealConfigPath:            true,
+	recoverySealConfigPath:           true,
+	recoverySealConfigPlaintextPath:  true,
+	recoveryKeyPath:                  true,
+	StoredBarrierKeysPath:            true,
+	hsmStoredIVPath:                  true,
+	coreBarrierUnsealKeysBackupPath:  true,
+	coreRecoveryUnsealKeysBackupPath: true,
+	CoreLockPath:                     true,
+	storageMigrationLockPath:         true,
+}
+
+// StorageVerifyReport is the result of the verification of all the entries
+// in storage
+type StorageVerifyReport struct {
+	EntriesChecked int64                   `json:"entries_checked"`
+	EntriesSkipped int64                   `json:"entries_skipped"`
+	Problems       []*StorageVerifyProblem `json:"problems"`
+}
+
+// StorageVerifyProblem is a corrupted or orphaned entry
+type StorageVerifyProblem struct {
+	Path    string `json:"path"`
+	Problem string `json:"problem"`
+	Error   string `json:"error,omitempty"`
+}
+
+// storageVerifyStatus is the progress of the background verification of the
+// storage entries
+type storageVerifyStatus struct {
+	StartTime    time.Time
+	CompleteTime time.Time
+	Error        string
+	Report       StorageVerifyReport
+}
+
+// Complete returns whether the verification has stopped, either because all
+// the entries have been checked or because it failed
+func (s *storageVerifyStatus) Complete() bool {
+	return !s.CompleteTime.IsZero()
+}
+
+// Status returns one of the storageVerifyStatus constants
+func (s *storageVerifyStatus) Status() string {
+	switch {
+	case !s.Complete():
+		return storageVerifyStatusInProgress
+	case s.Error != "":
+		return storageVerifyStatusFailed
+	default:
+		return storageVerifyStatusComplete
+	}
+}
+
+// startStorageVerify starts verifying the storage in the background, unless a
+// verification is already running. It returns the status of the running
+// verification, and whether it was started by this call. The verification is
+// stopped when the core is sealed or steps down.
+func (c *Core) startStorageVerify() (*storageVerifyStatus, bool) {
+	c.storageVerifyLock.Lock()
+	defer c.storageVerifyLock.Unlock()
+
+	if c.storageVerifyStatus != nil && !c.storageVerifyStatus.Complete() {
+		s := *c.storageVerifyStatus
+		return &s, false
+	}
+
+	status := &storageVerifyStatus{
+		StartTime: time.Now(),
+		Report: StorageVerifyReport{
+			Problems: []*StorageVerifyProblem{},
+		},
+	}
+	c.storageVerifyStatus = status
+
+	var ctx context.Context
+	ctx, c.storageVerifyCancel = context.WithCancel(c.activeContext)
+	go c.runStorageVerify(ctx, status)
+
+	s := *status
+	return &s, true
+}
+
+// runStorageVerify verifies the storage, and records the progress in the given
+// status. The status is only modified while holding storageVerifyLock.
+func (c *Core) runStorageVerify(ctx context.Context, status *storageVerifyStatus) {
+	c.logger.Info("verifying storage entries")
+	report, err := c.verifyStorage(ctx, func(report *StorageVerifyReport) {
+		c.storageVerifyLock.Lock()
+		status.Report = *report
+		c.storageVerifyLock.Unlock()
+
+		select {
+		case <-time.After(storageVerifyBatchInterval):
+		case <-ctx.Done():
+		}
+	})
+
+	c.storageVerifyLock.Lock()
+	defer c.storageVerifyLock.Unlock()
+	status.CompleteTime = time.Now()
+	if err != nil {
+		status.Error = err.Error()
+		lf := c.logger.Error
+		if ctx.Err() != nil {
+			lf = c.logger.Debug
+		}
+		lf("error verifying storage entries", "error", err)
+		return
+	}
+	status.Report = *report
+	c.logger.Info("storage verification complete", "checked", report.EntriesChecked,
+		"skipped", report.EntriesSkipped, "problems", len(report.Problems))
+}
+
+// currentStorageVerifyStatus returns the progress of the running
+// verification, or the result of the last one. It returns nil if no
+// verification was started since the core became active.
+func (c *Core) currentStorageVerifyStatus() *storageVerifyStatus {
+	c.storageVerifyLock.RLock()
+	defer c.storageVerifyLock.RUnlock()
+	if c.storageVerifyStatus == nil {
+		return nil
+	}
+	s := *c.storageVerifyStatus
+	return &s
+}
+
+// stopStorageVerify stops the running verification and forgets the result of
+// the last one
+func (c *Core) stopStorageVerify() {
+	c.storageVerifyLock.Lock()
+	defer c.storageVerifyLock.Unlock()
+	if c.storageVerifyCancel != nil {
+		c.storageVerifyCancel()
+		c.storageVerifyCancel = nil
+	}
+	c.storageVerifyStatus = nil
+}
+
+// verifyStorage checks that every entry in storage can be decrypted, and that
+// the entries of the mounts belong to a mount that still exists. The barrier
+// must be unsealed. If progress is set, it is called with the partial report
+// every storageVerifyBatchSize entries.
+func (c *Core) verifyStorage(ctx context.Context, progress func(*StorageVerifyReport)) (*StorageVerifyReport, error) {
+	// Without the mount tables, the orphaned entries cannot be told apart. The
+	// tables are still verified, and reported if they are corrupted.
+	mountPrefixes, err := c.storageVerifyMountPrefixes(ctx)
+	if err != nil {
+		c.logger.Warn("failed to load the mount tables, orphaned entries will not be reported", "error", err)
+	}
+
+	report := &StorageVerifyReport{
+		Problems: []*StorageVerifyProblem{},
+	}
+	var walkErr error
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	err = logical.ScanView(walkCtx, c.barrier, func(path string) {
+		if storageVerifyPlaintextPaths[path] {
+			report.EntriesSkipped++
+			return
+		}
+		report.EntriesChecked++
+		if progress != nil && report.EntriesChecked%storageVerifyBatchSize == 0 {
+			// The problems are only appended to, so the partial report can
+			// share them as long as its slice cannot grow into them
+			partial := *report
+			partial.Problems = report.Problems[:len(report.Problems):len(report.Problems)]
+			progress(&partial)
+		}
+
+		err := c.barrier.Verify(walkCtx, path)
+		switch {
+		case err == nil:
+		case errors.Is(err, ErrBarrierEntryTruncated):
+			report.Problems = append(report.Problems, &StorageVerifyProblem{
+				Path:    path,
+				Problem: StorageVerifyTruncated,
+			})
+			return
+		case errors.Is(err, ErrBarrierEntryUndecryptable):
+			report.Problems = append(report.Problems, &StorageVerifyProblem{
+				Path:    path,
+				Problem: StorageVerifyUndecryptable,
+				Error:   err.Error(),
+			})
+			return
+		default:
+			walkErr = fmt.Errorf("failed to read %q: %w", path, err)
+			cancel()
+			return
+		}
+
+		if mountPrefixes != nil && storageVerifyOrphaned(mountPrefixes, path) {
+			report.Problems = append(report.Problems, &StorageVerifyProblem{
+				Path:    path,
+				Problem: StorageVerifyOrphaned,
+			})
+		}
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// storageVerifyOrphaned returns whether the entry at the given path is stored
+// in the view of a mount that is not in the mount tables
+func storageVerifyOrphaned(mountPrefixes map[string]bool, path string) bool {
+	for _, prefix := range []string{backendBarrierPrefix, credentialBarrierPrefix, auditBarrierPrefix} {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		i := strings.Index(path[len(prefix):], "/")
+		if i == -1 {
+			return true
+		}
+		return !mountPrefixes[path[:len(prefix)+i+1]]
+	}
+	return false
+}
+
+// storageVerifyMountPrefixes reads the mount tables from storage, and returns
+// the storage prefixes of the views of all the mounts
+func (c *Core) storageVerifyMountPrefixes(ctx context.Context) (map[string]bool, error) {
+	prefixes := make(map[string]bool)
+	for tablePath, prefix := range map[string]string{
+		coreMountConfigPath:      backendBarrierPrefix,
+		coreLocalMountConfigPath: backendBarrierPrefix,
+		coreAuthConfigPath:       credentialBarrierPrefix,
+		coreLocalAuthConfigPath:  credentialBarrierPrefix,
+		coreAuditConfigPath:      auditBarrierPrefix,
+		coreLocalAuditConfigPath: auditBarrierPrefix,
+	} {
+		raw, err := c.barrier.Get(ctx, tablePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %q: %w", tablePath, err)
+		}
+		if raw == nil {
+			continue
+		}
+
+		table := new(MountTable)
+		if err := jsonutil.DecodeJSON(raw.Value, table); err != nil {
+			return nil, fmt.Errorf("failed to decode %q: %w", tablePath, err)
+		}
+		for _, entry := range table.Entries {
+			prefixes[prefix+entry.UUID+"/"] = true
+		}
+	}
+	return prefixes, nil
+}
+
+// VerifyStorageOffline unlocks the barrier without unsealing the core, and
+// verifies all the entries in storage. It is meant to check the storage of a
+// server that is not running, so nothing is written to storage. The unseal
+// keys are only needed with a Shamir seal.
+func (c *Core) VerifyStorageOffline(ctx context.Context, unsealKeys [][]byte) (*StorageVerifyReport, error) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	if !c.Sealed() {
+		return nil, errors.New("vault is already unsealed")
+	}
+
+	var masterKey []byte
+	switch c.seal.StoredKeysSupported() {
+	case vaultseal.StoredKeysSupportedGeneric:
+		storedKeys, err := c.seal.GetStoredKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve stored keys: %w", err)
+		}
+		if len(storedKeys) != 1 {
+			return nil, fmt.Errorf("expected exactly one stored key, got %d", len(storedKeys))
+		}
+		masterKey = storedKeys[0]
+
+	default:
+		config, err := c.seal.BarrierConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if config == nil {
+			return nil, ErrNotInit
+		}
+		if len(unsealKeys) == 0 || len(unsealKeys) < config.SecretThreshold {
+			return nil, fmt.Errorf("%d unseal keys are required, got %d", config.SecretThreshold, len(unsealKeys))
+		}
+
+		unsealKey := unsealKeys[0]
+		if config.SecretThreshold > 1 {
+			unsealKey, err = shamir.Combine(unsealKeys)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compute combined key: %w", err)
+			}
+		}
+		masterKey, err = c.unsealKeyToMasterKeyPreUnseal(ctx, c.seal, unsealKey)
+		if err != nil {
+			return nil, err
+		}
+		if masterKey == nil {
+			return nil, errors.New("unable to retrieve stored keys")
+		}
+	}
+
+	if err := c.barrier.Unseal(ctx, masterKey); err != nil {
+		return nil, fmt.Errorf("failed to unseal the barrier: %w", err)
+	}
+	defer c.barrier.Seal()
+
+	return c.verifyStorage(ctx, nil)
+}
@@ -0,0 +1,171 @@
+package vault
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	uuid "github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestCore_StorageVerify(t *testing.T) {
+	c, keys, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	// Data of a mounted secrets engine is not reported
+	resp, err := c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "sys/mounts/kv",
+		ClientToken: root,
+		Data:        map[string]interface{}{"type": "kv"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+	resp, err = c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.UpdateOperation,
+		Path:        "kv/foo",
+		ClientToken: root,
+		Data:        map[string]interface{}{"bar": "baz"},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+
+	verify := func() *logical.Response {
+		t.Helper()
+		resp, err := c.HandleRequest(ctx, &logical.Request{
+			Operation:   logical.UpdateOperation,
+			Path:        "sys/storage/verify",
+			ClientToken: root,
+		})
+		if err != nil || resp == nil || resp.IsError() {
+			t.Fatalf("bad: %v %v", resp, err)
+		}
+
+		// The verification runs in the background until it completes
+		deadline := time.Now().Add(10 * time.Second)
+		for resp.Data["status"] == storageVerifyStatusInProgress {
+			if time.Now().After(deadline) {
+				t.Fatalf("storage verification did not complete: %#v", resp.Data)
+			}
+			time.Sleep(10 * time.Millisecond)
+			resp, err = c.HandleRequest(ctx, &logical.Request{
+				Operation:   logical.ReadOperation,
+				Path:        "sys/storage/verify",
+				ClientToken: root,
+			})
+			if err != nil || resp == nil || resp.IsError() {
+				t.Fatalf("bad: %v %v", resp, err)
+			}
+		}
+		if resp.Data["status"] != storageVerifyStatusComplete {
+			t.Fatalf("bad: %#v", resp.Data)
+		}
+		return resp
+	}
+
+	// Nothing is reported before the first verification
+	resp, err = c.HandleRequest(ctx, &logical.Request{
+		Operation:   logical.ReadOperation,
+		Path:        "sys/storage/verify",
+		ClientToken: root,
+	})
+	if err != nil || resp != nil {
+		t.Fatalf("bad: %v %v", resp, err)
+	}
+
+	resp = verify()
+	if problems := resp.Data["problems"].([]map[string]interface{}); len(problems) != 0 {
+		t.Fatalf("bad: %#v", problems)
+	}
+	if resp.Data["entries_checked"].(int64) == 0 || resp.Data["entries_skipped"].(int64) == 0 {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+
+	for _, key := range []string{"test/truncated", "test/undecryptable"} {
+		if err := c.barrier.Put(ctx, &logical.StorageEntry{Key: key, Value: []byte("test")}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	pe, err := c.physical.Get(ctx, "test/truncated")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pe.Value = pe.Value[:8]
+	if err := c.physical.Put(ctx, pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pe, err = c.physical.Get(ctx, "test/undecryptable")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pe.Value[len(pe.Value)-1]++
+	if err := c.physical.Put(ctx, pe); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	orphanedUUID, _ := uuid.GenerateUUID()
+	orphanedPath := backendBarrierPrefix + orphanedUUID + "/foo"
+	if err := c.barrier.Put(ctx, &logical.StorageEntry{Key: orphanedPath, Value: []byte("test")}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := []string{
+		"test/truncated " + StorageVerifyTruncated,
+		"test/undecryptable " + StorageVerifyUndecryptable,
+		orphanedPath + " " + StorageVerifyOrphaned,
+	}
+	sort.Strings(expected)
+	collect := func(problems []*StorageVerifyProblem) []string {
+		var out []string
+		for _, problem := range problems {
+			out = append(out, problem.Path+" "+problem.Problem)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	resp = verify()
+	var problems []*StorageVerifyProblem
+	for _, problem := range resp.Data["problems"].([]map[string]interface{}) {
+		problems = append(problems, &StorageVerifyProblem{
+			Path:    problem["path"].(string),
+			Problem: problem["problem"].(string),
+		})
+	}
+	if actual := collect(problems); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %v", actual)
+	}
+
+	// Offline verification requires the core to be sealed, and the unseal keys
+	if _, err := c.VerifyStorageOffline(context.Background(), keys); err == nil {
+		t.Fatal("expected error while unsealed")
+	}
+	if err := c.Seal(root); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if status := c.currentStorageVerifyStatus(); status != nil {
+		t.Fatalf("status should be reset when sealing, got: %#v", status)
+	}
+	if _, err := c.VerifyStorageOffline(context.Background(), keys[:1]); err == nil {
+		t.Fatal("expected error without enough unseal keys")
+	}
+	report, err := c.VerifyStorageOffline(context.Background(), keys)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if actual := collect(report.Problems); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %v", actual)
+	}
+	if !c.Sealed() {
+		t.Fatal("should still be sealed")
+	}
+	if _, err := c.barrier.Get(context.Background(), "test/foo"); err != ErrBarrierSealed {
+		t.Fatalf("barrier should be sealed again, got: %v", err)
+	}
+}
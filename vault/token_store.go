@@ -69,6 +69,11 @@ var (
 	// any namespace information
 	TokenLength = 24
 
+	// tokenUsageSampleInterval is the minimum amount of time between two
+	// persisted updates of a token's usage statistics. Uses in between are
+	// only counted in memory and folded into the next persisted update.
+	tokenUsageSampleInterval = 1 * time.Minute
+
 	// displayNameSanitize is used to sanitize a display name given to a token.
 	displayNameSanitize = regexp.MustCompile("[^a-zA-Z0-9-]")
 
@@ -430,6 +435,11 @@ func (ts *TokenStore) paths() []*framework.Path {
 				Type:        framework.TypeCommaStringSlice,
 				Description: "String or JSON list of allowed entity aliases. If set, specifies the entity aliases which are allowed to be used during token generation. This field supports globbing.",
 			},
+
+			"max_idle_ttl": {
+				Type:        framework.TypeDurationSecond,
+				Description: tokenMaxIdleTTLHelp,
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -503,6 +513,10 @@ type TokenStore struct {
 	// failed. Revocation needs to handle these states accordingly.
 	tokensPendingDeletion *sync.Map
 
+	// tokenUsage stores the *pendingTokenUsage of the tokens that were used
+	// since their usage statistics were last persisted, keyed by token ID.
+	tokenUsage *sync.Map
+
 	cubbyholeDestroyer func(context.Context, *TokenStore, *logical.TokenEntry) error
 
 	logger log.Logger
@@ -537,6 +551,7 @@ func NewTokenStore(ctx context.Context, logger log.Logger, core *Core, config *l
 		logger:                logger,
 		tokenLocks:            locksutil.CreateLocks(),
 		tokensPendingDeletion: &sync.Map{},
+		tokenUsage:            &sync.Map{},
 		saltLock:              sync.RWMutex{},
 		tidyLock:              new(uint32),
 		quitContext:           core.activeContext,
@@ -647,6 +662,10 @@ type tsRoleEntry struct {
 
 	// The set of allowed entity aliases used during token creation
 	AllowedEntityAliases []string `json:"allowed_entity_aliases" mapstructure:"allowed_entity_aliases" structs:"allowed_entity_aliases"`
+
+	// If non-zero, tokens created using this role will be revoked once they
+	// have not been used for this duration
+	MaxIdleTTL time.Duration `json:"max_idle_ttl" mapstructure:"max_idle_ttl" structs:"max_idle_ttl"`
 }
 
 type accessorEntry struct {
@@ -1034,10 +1053,15 @@ func (ts *TokenStore) UseToken(ctx context.Context, te *logical.TokenEntry) (*lo
 		return nil, fmt.Errorf("invalid token entry provided for use count decrementing")
 	}
 
+	flushUsage := ts.trackTokenUse(te)
+
 	// This case won't be hit with a token with restricted uses because we go
 	// from 1 to -1. So it's a nice optimization to check this without a read
 	// lock.
 	if te.NumUses == 0 {
+		if flushUsage {
+			return ts.flushTokenUsage(ctx, te)
+		}
 		return te, nil
 	}
 
@@ -1076,6 +1100,10 @@ func (ts *TokenStore) UseToken(ctx context.Context, te *logical.TokenEntry) (*lo
 		te.NumUses--
 	}
 
+	// We are writing the entry anyways, so take the opportunity to persist
+	// any pending usage statistics
+	ts.applyTokenUsage(te)
+
 	err = ts.store(ctx, te)
 	if err != nil {
 		return nil, err
@@ -1084,6 +1112,104 @@ func (ts *TokenStore) UseToken(ctx context.Context, te *logical.TokenEntry) (*lo
 	return te, nil
 }
 
+// pendingTokenUsage holds the usage statistics of a token that have not yet
+// been persisted to its token entry
+type pendingTokenUsage struct {
+	count    int64
+	lastUsed int64
+}
+
+// trackTokenUse counts a use of the given token in memory. It returns true if
+// the persisted usage statistics of the token are older than the sample
+// interval and should be flushed.
+func (ts *TokenStore) trackTokenUse(te *logical.TokenEntry) bool {
+	// Batch tokens are never persisted, so there is nothing to track
+	if te.Type == logical.TokenTypeBatch || te.ID == "" {
+		return false
+	}
+
+	raw, _ := ts.tokenUsage.LoadOrStore(te.ID, new(pendingTokenUsage))
+	usage := raw.(*pendingTokenUsage)
+	atomic.AddInt64(&usage.count, 1)
+	atomic.StoreInt64(&usage.lastUsed, time.Now().Unix())
+
+	return time.Since(time.Unix(te.LastUsedTime, 0)) >= tokenUsageSampleInterval
+}
+
+// applyTokenUsage moves the uses counted in memory for the given token into
+// the entry and marks it as used now. The caller is responsible for
+// persisting the entry.
+func (ts *TokenStore) applyTokenUsage(te *logical.TokenEntry) {
+	if raw, ok := ts.tokenUsage.Load(te.ID); ok {
+		// The entry is dropped so that the map only holds the tokens used
+		// since their last flush
+		ts.tokenUsage.Delete(te.ID)
+		te.UseCount += atomic.SwapInt64(&raw.(*pendingTokenUsage).count, 0)
+	}
+	te.LastUsedTime = time.Now().Unix()
+}
+
+// pendingTokenUses returns the number of uses of the given token that have
+// not yet been persisted.
+func (ts *TokenStore) pendingTokenUses(id string) int64 {
+	if raw, ok := ts.tokenUsage.Load(id); ok {
+		return atomic.LoadInt64(&raw.(*pendingTokenUsage).count)
+	}
+	return 0
+}
+
+// flushTokenUsage persists the usage statistics of the given token. Failing
+// to do so is not fatal to the request, since the uses stay counted in memory
+// and are retried on the next flush.
+func (ts *TokenStore) flushTokenUsage(ctx context.Context, te *logical.TokenEntry) (*logical.TokenEntry, error) {
+	lock := locksutil.LockForKey(ts.tokenLocks, te.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	entry, err := ts.lookupInternal(ctx, te.ID, false, false)
+	if err != nil {
+		ts.logger.Warn("failed to refresh entry while recording token usage", "error", err)
+		return te, nil
+	}
+	if entry == nil {
+		return te, nil
+	}
+
+	// Another request may have flushed while we were waiting on the lock
+	if time.Since(time.Unix(entry.LastUsedTime, 0)) < tokenUsageSampleInterval {
+		return entry, nil
+	}
+
+	ts.applyTokenUsage(entry)
+	if err := ts.store(ctx, entry); err != nil {
+		ts.logger.Warn("failed to persist token usage", "error", err)
+		return te, nil
+	}
+
+	return entry, nil
+}
+
+// tokenIdleExpired returns true if the token has a maximum idle TTL and has
+// not been used within it. The uses that were not persisted yet are taken
+// into account.
+func (ts *TokenStore) tokenIdleExpired(te *logical.TokenEntry) bool {
+	if te.MaxIdleTTL == 0 {
+		return false
+	}
+
+	lastUsed := te.LastUsedTime
+	if lastUsed == 0 {
+		lastUsed = te.CreationTime
+	}
+	if raw, ok := ts.tokenUsage.Load(te.ID); ok {
+		if pending := atomic.LoadInt64(&raw.(*pendingTokenUsage).lastUsed); pending > lastUsed {
+			lastUsed = pending
+		}
+	}
+
+	return time.Since(time.Unix(lastUsed, 0)) > te.MaxIdleTTL
+}
+
 func (ts *TokenStore) UseTokenByID(ctx context.Context, id string) (*logical.TokenEntry, error) {
 	te, err := ts.Lookup(ctx, id)
 	if err != nil {
@@ -1342,6 +1468,24 @@ func (ts *TokenStore) lookupInternal(ctx context.Context, id string, salted, tai
 			return nil, err
 		}
 
+	// Tokens that have gone unused for longer than their maximum idle TTL are
+	// revoked, and their children orphaned since they may still be in use
+	case !tainted && ts.tokenIdleExpired(entry):
+		ts.logger.Info("revoking idle token", "accessor", entry.Accessor, "max_idle_ttl", entry.MaxIdleTTL)
+
+		tokenNS, err := NamespaceByID(ctx, entry.NamespaceID, ts.core)
+		if err != nil {
+			return nil, err
+		}
+		if tokenNS == nil {
+			return nil, namespace.ErrNoNamespace
+		}
+
+		if err := ts.revokeOrphan(namespace.ContextWithNamespace(ts.quitContext, tokenNS), entry.ID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
 	// Only return if we're not past lease expiration (or if tainted is true),
 	// otherwise assume expmgr is working on revocation
 	default:
@@ -1431,6 +1575,11 @@ func (ts *TokenStore) revokeInternal(ctx context.Context, saltedID string, skipO
 			}
 		}
 
+		// Usage that hasn't been persisted yet is no longer of interest
+		if ret == nil {
+			ts.tokenUsage.Delete(entry.ID)
+		}
+
 		// Check on ret again and update the sync.Map accordingly
 		if ret != nil {
 			// If we failed on any of the calls within, we store the state as false
@@ -1916,7 +2065,8 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 				deletedCountAccessorEmptyToken,
 				deletedCountAccessorInvalidToken,
 				deletedCountInvalidTokenInAccessor,
-				deletedCountInvalidCubbyholeKey int64
+				deletedCountInvalidCubbyholeKey,
+				revokedCountIdleToken int64
 
 			validCubbyholeKeys := make(map[string]bool)
 
@@ -1971,6 +2121,7 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 					// for this token should not exist as well.
 
 					ts.logger.Info("deleting token with nil entry referenced by accessor", "salted_accessor", saltedAccessor)
+					ts.tokenUsage.Delete(accessorEntry.TokenID)
 
 					// RevokeByToken expects a '*logical.TokenEntry'. For the
 					// purposes of tidying, it is sufficient if the token
@@ -1999,6 +2150,17 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 						continue
 					}
 					deletedCountAccessorInvalidToken++
+				case ts.tokenIdleExpired(te):
+					// Tokens that have gone unused for longer than their
+					// maximum idle TTL are revoked along with their leases,
+					// and their children orphaned
+					ts.logger.Info("revoking idle token", "salted_accessor", saltedAccessor, "max_idle_ttl", te.MaxIdleTTL)
+
+					if err := ts.revokeOrphan(quitCtx, te.ID); err != nil {
+						tidyErrors = multierror.Append(tidyErrors, fmt.Errorf("failed to revoke idle token: %w", err))
+						continue
+					}
+					revokedCountIdleToken++
 				default:
 					// Cache the cubbyhole storage key when the token is valid
 					switch {
@@ -2047,6 +2209,7 @@ func (ts *TokenStore) handleTidy(ctx context.Context, req *logical.Request, data
 			ts.logger.Info("number of revoked tokens which were invalid but present in accessors", "count", deletedCountInvalidTokenInAccessor)
 			ts.logger.Info("number of deleted accessors which had invalid tokens", "count", deletedCountAccessorInvalidToken)
 			ts.logger.Info("number of deleted cubbyhole keys that were invalid", "count", deletedCountInvalidCubbyholeKey)
+			ts.logger.Info("number of revoked tokens which were idle", "count", revokedCountIdleToken)
 
 			return tidyErrors.ErrorOrNil()
 		}
@@ -2439,6 +2602,10 @@ func (ts *TokenStore) handleCreateCommon(ctx context.Context, req *logical.Reque
 		if role.PathSuffix != "" {
 			te.Path = fmt.Sprintf("%s/%s", te.Path, role.PathSuffix)
 		}
+
+		if te.Type != logical.TokenTypeBatch {
+			te.MaxIdleTTL = role.MaxIdleTTL
+		}
 	}
 
 	// Attach the given display name if any
@@ -2981,6 +3148,16 @@ func (ts *TokenStore) handleLookup(ctx context.Context, req *logical.Request, da
 		resp.Data["bound_cidrs"] = out.BoundCIDRs
 	}
 
+	if out.Type != logical.TokenTypeBatch {
+		resp.Data["use_count"] = out.UseCount + ts.pendingTokenUses(out.ID)
+		if out.LastUsedTime != 0 {
+			resp.Data["last_used_time"] = out.LastUsedTime
+		}
+		if out.MaxIdleTTL != 0 {
+			resp.Data["max_idle_ttl"] = int64(out.MaxIdleTTL.Seconds())
+		}
+	}
+
 	tokenNS, err := NamespaceByID(ctx, out.NamespaceID, ts.core)
 	if err != nil {
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
@@ -3192,6 +3369,7 @@ func (ts *TokenStore) tokenStoreRoleRead(ctx context.Context, req *logical.Reque
 			"renewable":              role.Renewable,
 			"token_type":             role.TokenType.String(),
 			"allowed_entity_aliases": role.AllowedEntityAliases,
+			"max_idle_ttl":           int64(role.MaxIdleTTL.Seconds()),
 		},
 	}
 
@@ -3432,6 +3610,22 @@ func (ts *TokenStore) tokenStoreRoleCreateUpdate(ctx context.Context, req *logic
 		entry.AllowedEntityAliases = strutil.RemoveDuplicates(allowedEntityAliasesRaw.([]string), true)
 	}
 
+	maxIdleTTLRaw, ok := data.GetOk("max_idle_ttl")
+	if ok {
+		entry.MaxIdleTTL = time.Second * time.Duration(maxIdleTTLRaw.(int))
+	}
+	if entry.MaxIdleTTL != 0 {
+		// Uses are persisted at most once per sample interval, so the idle
+		// TTL leaves room for uses that were not persisted before a leader
+		// change
+		if entry.MaxIdleTTL < 2*tokenUsageSampleInterval {
+			return logical.ErrorResponse(fmt.Sprintf("'max_idle_ttl' must be at least %d seconds", int64(2*tokenUsageSampleInterval.Seconds()))), nil
+		}
+		if entry.TokenType == logical.TokenTypeBatch {
+			return logical.ErrorResponse("'max_idle_ttl' cannot be set when role is set to generate batch tokens"), nil
+		}
+	}
+
 	ns, err := namespace.FromContext(ctx)
 	if err != nil {
 		return nil, err
@@ -3748,9 +3942,10 @@ conditions have occurred.
 `
 	tokenTidyDesc = `
 This endpoint performs cleanup tasks that can be run to clean up token and
-lease entries after certain error conditions. Usually running this is not
-necessary, and is only required if upgrade notes or support personnel suggest
-it.
+lease entries after certain error conditions. It also revokes tokens that have
+exceeded the maximum idle TTL of the role they were created against. Usually
+running this is not necessary, and is only required if upgrade notes or support
+personnel suggest it.
 `
 	tokenBackendHelp = `The token credential backend is always enabled and builtin to Vault.
 Client tokens are used to identify a client and to allow Vault to associate policies and ACLs
@@ -3798,6 +3993,12 @@ no effect on the token being renewed.`
 	tokenRenewableHelp = `Tokens created via this role will be
 renewable or not according to this value.
 Defaults to "true".`
+	tokenMaxIdleTTLHelp = `If set, tokens created via this role
+are revoked once they have not been used to
+authenticate a request for this duration. Use
+is sampled once a minute, so this must be at least
+two minutes. This takes an integer number of seconds,
+or a string duration (e.g. "720h").`
 	tokenListAccessorsHelp = `List token accessors, which can then be
 be used to iterate and discover their properties
 or revoke them. Because this can be used to
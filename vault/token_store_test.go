@@ -1256,6 +1256,72 @@ func TestTokenStore_UseToken(t *testing.T) {
 	}
 }
 
+func TestTokenStore_UseToken_Usage(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	testMakeServiceTokenViaBackend(t, ts, root, "tokenid", "60s", []string{"foo"})
+	ent, err := ts.Lookup(namespace.RootContext(nil), "tokenid")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ent.LastUsedTime != 0 || ent.UseCount != 0 {
+		t.Fatalf("expected unused token, got: %#v", ent)
+	}
+
+	// The first use is persisted since the token was never used before, the
+	// following ones fall within the sample interval and are kept in memory
+	for i := 0; i < 3; i++ {
+		if _, err := ts.UseToken(namespace.RootContext(nil), ent); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ent, err = ts.Lookup(namespace.RootContext(nil), "tokenid")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if ent.LastUsedTime == 0 {
+		t.Fatalf("expected last used time to be set")
+	}
+	if ent.UseCount != 1 {
+		t.Fatalf("expected one persisted use, got %d", ent.UseCount)
+	}
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "lookup-accessor")
+	req.Data = map[string]interface{}{
+		"accessor": ent.Accessor,
+	}
+	resp, err := ts.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	if resp.Data["use_count"].(int64) != 3 {
+		t.Fatalf("expected use count of 3, got %v", resp.Data["use_count"])
+	}
+	if resp.Data["last_used_time"].(int64) != ent.LastUsedTime {
+		t.Fatalf("bad: last used time: %v", resp.Data["last_used_time"])
+	}
+
+	// Once the sample interval has elapsed, the pending uses get persisted
+	ent.LastUsedTime = time.Now().Add(-2 * tokenUsageSampleInterval).Unix()
+	if err := ts.store(namespace.RootContext(nil), ent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.UseToken(namespace.RootContext(nil), ent); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ent, err = ts.Lookup(namespace.RootContext(nil), "tokenid")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if ent.UseCount != 4 {
+		t.Fatalf("expected four persisted uses, got %d", ent.UseCount)
+	}
+	if time.Since(time.Unix(ent.LastUsedTime, 0)) > tokenUsageSampleInterval {
+		t.Fatalf("expected last used time to be updated")
+	}
+}
+
 func TestTokenStore_Revoke(t *testing.T) {
 	c, _, _ := TestCoreUnsealed(t)
 	ts := c.tokenStore
@@ -2420,12 +2486,17 @@ func testTokenStoreHandleRequestLookup(t *testing.T, batch, periodic bool) {
 		"expire_time":      nil,
 		"entity_id":        "",
 		"type":             "service",
+		"use_count":        int64(1),
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
 		t.Fatalf("creation time was zero")
 	}
 	delete(resp.Data, "creation_time")
+	if resp.Data["last_used_time"].(int64) == 0 {
+		t.Fatalf("last used time was zero")
+	}
+	delete(resp.Data, "last_used_time")
 
 	if !reflect.DeepEqual(resp.Data, exp) {
 		t.Fatalf("bad: expected:%#v\nactual:%#v", exp, resp.Data)
@@ -2478,6 +2549,9 @@ func testTokenStoreHandleRequestLookup(t *testing.T, batch, periodic bool) {
 	if periodic {
 		exp["period"] = int64(3600)
 	}
+	if !batch {
+		exp["use_count"] = int64(0)
+	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
 		t.Fatalf("creation time was zero")
@@ -2565,6 +2639,7 @@ func TestTokenStore_HandleRequest_LookupSelf(t *testing.T) {
 		"explicit_max_ttl": int64(0),
 		"entity_id":        "",
 		"type":             "service",
+		"use_count":        int64(0),
 	}
 
 	if resp.Data["creation_time"].(int64) == 0 {
@@ -3180,6 +3255,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"token_type":             "default-service",
 		"token_num_uses":         123,
 		"allowed_entity_aliases": []string(nil),
+		"max_idle_ttl":           int64(0),
 	}
 
 	if resp.Data["bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "0.0.0.0/0" {
@@ -3240,6 +3316,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"renewable":              false,
 		"token_type":             "default-service",
 		"allowed_entity_aliases": []string(nil),
+		"max_idle_ttl":           int64(0),
 	}
 
 	if resp.Data["bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "0.0.0.0/0" {
@@ -3290,6 +3367,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"renewable":              false,
 		"token_type":             "default-service",
 		"allowed_entity_aliases": []string(nil),
+		"max_idle_ttl":           int64(0),
 	}
 
 	if resp.Data["bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "0.0.0.0/0" {
@@ -3340,6 +3418,7 @@ func TestTokenStore_RoleCRUD(t *testing.T) {
 		"renewable":              false,
 		"token_type":             "default-service",
 		"allowed_entity_aliases": []string(nil),
+		"max_idle_ttl":           int64(0),
 	}
 
 	if diff := deep.Equal(expected, resp.Data); diff != nil {
@@ -3710,6 +3789,108 @@ func TestTokenStore_RolePathSuffix(t *testing.T) {
 	}
 }
 
+func TestTokenStore_RoleMaxIdleTTL(t *testing.T) {
+	c, _, root := TestCoreUnsealed(t)
+	ts := c.tokenStore
+
+	// Note: these requests are sent to Core since Core handles registration
+	// with the expiration manager and we need the storage to be consistent
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "auth/token/roles/test")
+	req.ClientToken = root
+	req.Data = map[string]interface{}{
+		"max_idle_ttl": "10s",
+	}
+	resp, err := c.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a max idle TTL below the sample interval, got: %#v", resp)
+	}
+
+	// The idle TTL must cover two sample intervals
+	req.Data["max_idle_ttl"] = "90s"
+	resp, err = c.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for a max idle TTL below two sample intervals, got: %#v", resp)
+	}
+
+	req.Data["max_idle_ttl"] = "1h"
+	resp, err = c.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+
+	req.Path = "auth/token/create/test"
+	req.Data = map[string]interface{}{
+		"policies": []string{"foo"},
+	}
+	resp, err = c.HandleRequest(namespace.RootContext(nil), req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v\nresp: %#v", err, resp)
+	}
+	token := resp.Auth.ClientToken
+
+	// The idle token has a child that is still in use
+	testMakeTokenDirectly(t, ts, &logical.TokenEntry{
+		ID:       "child",
+		Parent:   token,
+		Path:     "auth/token/create",
+		Policies: []string{"foo"},
+		TTL:      time.Hour,
+	})
+
+	out, err := ts.Lookup(namespace.RootContext(nil), token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out.MaxIdleTTL != time.Hour {
+		t.Fatalf("expected max idle TTL of 1h, got %s", out.MaxIdleTTL)
+	}
+
+	// Backdate the persisted last use past the idle window. The uses that
+	// were not persisted yet still count.
+	out.LastUsedTime = time.Now().Add(-2 * time.Hour).Unix()
+	if err := ts.store(namespace.RootContext(nil), out); err != nil {
+		t.Fatal(err)
+	}
+	ts.trackTokenUse(out)
+
+	out, err = ts.Lookup(namespace.RootContext(nil), token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out == nil {
+		t.Fatal("expected token with recent uses not to be revoked")
+	}
+
+	// Without recent uses, the next lookup revokes the token
+	ts.tokenUsage.Delete(out.ID)
+	out, err = ts.Lookup(namespace.RootContext(nil), token)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if out != nil {
+		t.Fatalf("expected idle token to be revoked, got: %#v", out)
+	}
+
+	// And its child is orphaned rather than revoked
+	child, err := ts.Lookup(namespace.RootContext(nil), "child")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if child == nil {
+		t.Fatal("expected the child of the idle token not to be revoked")
+	}
+	if child.Parent != "" {
+		t.Fatalf("expected the child of the idle token to be orphaned, got parent %q", child.Parent)
+	}
+}
+
 func TestTokenStore_RolePeriod(t *testing.T) {
 	core, _, root := TestCoreUnsealed(t)
 
@@ -4150,6 +4331,7 @@ func TestTokenStore_RoleTokenFields(t *testing.T) {
 			"renewable":              false,
 			"token_type":             "batch",
 			"allowed_entity_aliases": []string(nil),
+			"max_idle_ttl":           int64(0),
 		}
 
 		if resp.Data["bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "127.0.0.1" {
@@ -4203,6 +4385,7 @@ func TestTokenStore_RoleTokenFields(t *testing.T) {
 			"renewable":              false,
 			"token_type":             "default-service",
 			"allowed_entity_aliases": []string(nil),
+			"max_idle_ttl":           int64(0),
 		}
 
 		if resp.Data["bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "127.0.0.1" {
@@ -4255,6 +4438,7 @@ func TestTokenStore_RoleTokenFields(t *testing.T) {
 			"renewable":              false,
 			"token_type":             "default-service",
 			"allowed_entity_aliases": []string(nil),
+			"max_idle_ttl":           int64(0),
 		}
 
 		if resp.Data["token_bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "127.0.0.1" {
@@ -4309,6 +4493,7 @@ func TestTokenStore_RoleTokenFields(t *testing.T) {
 			"renewable":              false,
 			"token_type":             "service",
 			"allowed_entity_aliases": []string(nil),
+			"max_idle_ttl":           int64(0),
 		}
 
 		if resp.Data["token_bound_cidrs"].([]*sockaddr.SockAddrMarshaler)[0].String() != "127.0.0.1" {
@@ -0,0 +1,135 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/vault/helper/pgpkeys"
+	"github.com/keybase/go-crypto/openpgp"
+	"github.com/keybase/go-crypto/openpgp/packet"
+)
+
+// UnsealPGPKey returns the base64-encoded public PGP key that signed unseal
+// keys must be encrypted with. The key pair is generated the first time it is
+// requested while the node is sealed, and is only kept in memory until the
+// unseal keys have all been provided.
+func (c *Core) UnsealPGPKey() (string, error) {
+	c.unsealPGPKeyLock.Lock()
+	defer c.unsealPGPKeyLock.Unlock()
+
+	if c.unsealPGPKey == nil {
+		entity, err := openpgp.NewEntity("Vault unseal", "ephemeral unseal key", "", &packet.Config{RSABits: 2048})
+		if err != nil {
+			return "", fmt.Errorf("failed to generate unseal PGP key: %w", err)
+		}
+		// The self-signatures of a new entity are only computed when its
+		// private key is serialized
+		if err := entity.SerializePrivate(ioutil.Discard, nil); err != nil {
+			return "", fmt.Errorf("failed to sign unseal PGP key: %w", err)
+		}
+		c.unsealPGPKey = entity
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := c.unsealPGPKey.Serialize(buf); err != nil {
+		return "", fmt.Errorf("failed to serialize unseal PGP key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// resetUnsealPGPKey discards the unseal key pair, so that the unseal keys
+// submitted with it cannot be replayed.
+func (c *Core) resetUnsealPGPKey() {
+	c.unsealPGPKeyLock.Lock()
+	defer c.unsealPGPKeyLock.Unlock()
+	c.unsealPGPKey = nil
+}
+
+// UnsealSigned is used to provide one of the key parts to unseal the Vault,
+// as a PGP message signed by the key holder and encrypted with the key
+// returned by UnsealPGPKey.
+func (c *Core) UnsealSigned(signedKey []byte) (bool, error) {
+	err := c.unsealFragment(nil, signedKey, false)
+	return !c.Sealed(), err
+}
+
+// UnsealSignedMigrate is like UnsealSigned, for the migration seal.
+func (c *Core) UnsealSignedMigrate(signedKey []byte) (bool, error) {
+	err := c.unsealFragment(nil, signedKey, true)
+	return !c.Sealed(), err
+}
+
+// UnsealHolders returns the fingerprints of the PGP keys of the key holders
+// who provided unseal keys, either for the unseal in progress or, once
+// unsealed, for the last unseal.
+func (c *Core) UnsealHolders() []string {
+	c.stateLock.RLock()
+	defer c.stateLock.RUnlock()
+	if c.unlockInfo != nil {
+		return c.unlockInfo.Holders
+	}
+	return c.unsealHolders
+}
+
+// verifySignedUnsealKey decrypts the signed unseal key, and verifies that it
+// was signed by one of the key holders of the seal configuration. It returns
+// the unseal key and the fingerprint of the key holder. The stateLock must be
+// held.
+func (c *Core) verifySignedUnsealKey(config *SealConfig, signedKey []byte) ([]byte, string, error) {
+	c.unsealPGPKeyLock.Lock()
+	privKey := c.unsealPGPKey
+	c.unsealPGPKeyLock.Unlock()
+	if privKey == nil {
+		return nil, "", fmt.Errorf("no unseal PGP key has been generated, fetch it from the seal status")
+	}
+
+	holders, err := pgpkeys.GetEntities(config.PGPKeys)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse the PGP keys of the key holders: %w", err)
+	}
+
+	encoded, holder, err := pgpkeys.DecryptAndVerify(signedKey, privKey, holders)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The key holders receive hex-encoded unseal keys, but accept base64 as
+	// well like unencrypted unseal keys
+	encoded = bytes.TrimSpace(encoded)
+	min, max := c.BarrierKeyLength()
+	key, err := hex.DecodeString(string(encoded))
+	if err != nil || len(key) < min || len(key) > max {
+		key, err = base64.StdEncoding.DecodeString(string(encoded))
+		if err != nil {
+			return nil, "", fmt.Errorf("signed unseal key must be a valid hex or base64 string")
+		}
+	}
+	return key, holder, nil
+}
+
+// checkHolderShares returns an error if the key holder with the given
+// fingerprint already provided as many unseal keys as they hold.
+func checkHolderShares(config *SealConfig, holders []string, holder string) error {
+	entities, err := pgpkeys.GetEntities(config.PGPKeys)
+	if err != nil {
+		return err
+	}
+	var shares, provided int
+	for _, entity := range entities {
+		if fmt.Sprintf("%x", entity.PrimaryKey.Fingerprint) == holder {
+			shares++
+		}
+	}
+	for _, h := range holders {
+		if h == holder {
+			provided++
+		}
+	}
+	if provided >= shares {
+		return &ErrInvalidKey{fmt.Sprintf("key holder %s already provided their unseal keys", holder)}
+	}
+	return nil
+}
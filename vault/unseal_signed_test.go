@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/helper/pgpkeys"
+	"github.com/keybase/go-crypto/openpgp"
+	"github.com/keybase/go-crypto/openpgp/packet"
+)
+
+func testPGPEntity(t *testing.T, privKey string) *openpgp.Entity {
+	t.Helper()
+	data, err := base64.StdEncoding.DecodeString(privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, err := openpgp.ReadEntity(packet.NewReader(bytes.NewBuffer(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entity
+}
+
+func TestCore_UnsealSigned(t *testing.T) {
+	c := TestCore(t)
+
+	// The first key holder holds two of the shares
+	res, err := c.Initialize(namespace.RootContext(nil), &InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    3,
+			SecretThreshold: 3,
+			PGPKeys:         []string{pgpkeys.TestPubKey1, pgpkeys.TestPubKey2, pgpkeys.TestPubKey1},
+			SignedUnseal:    true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprints, err := pgpkeys.GetFingerprints([]string{pgpkeys.TestPubKey1, pgpkeys.TestPubKey2}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	holder1 := testPGPEntity(t, pgpkeys.TestPrivKey1)
+	holder2 := testPGPEntity(t, pgpkeys.TestPrivKey2)
+	other := testPGPEntity(t, pgpkeys.TestPrivKey3)
+	privKeys := []string{pgpkeys.TestPrivKey1, pgpkeys.TestPrivKey2, pgpkeys.TestPrivKey1}
+
+	shares := make([][]byte, len(res.SecretShares))
+	for i, encrypted := range res.SecretShares {
+		share, err := pgpkeys.DecryptBytes(base64.StdEncoding.EncodeToString(encrypted), privKeys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		shares[i] = share.Bytes()
+	}
+
+	status, err := c.GetSealStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.SignedUnseal || status.UnsealPGPKey == "" {
+		t.Fatalf("expected the unseal PGP key in the seal status: %#v", status)
+	}
+	unsealPGPKey := status.UnsealPGPKey
+	sign := func(i int, signer *openpgp.Entity) []byte {
+		t.Helper()
+		signed, err := pgpkeys.SignAndEncrypt(shares[i], unsealPGPKey, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	// Unsigned unseal keys are refused
+	if _, err := TestCoreUnseal(c, shares[0]); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Unseal keys must be signed by a key holder
+	if _, err := c.UnsealSigned(sign(0, other)); err == nil {
+		t.Fatal("expected error")
+	}
+	if prog, _ := c.SecretProgress(); prog != 0 {
+		t.Fatalf("bad progress: %d", prog)
+	}
+
+	if unsealed, err := c.UnsealSigned(sign(0, holder1)); err != nil || unsealed {
+		t.Fatalf("unsealed: %t, err: %v", unsealed, err)
+	}
+	if unsealed, err := c.UnsealSigned(sign(1, holder2)); err != nil || unsealed {
+		t.Fatalf("unsealed: %t, err: %v", unsealed, err)
+	}
+
+	// Each key holder only provides as many keys as they hold
+	if _, err := c.UnsealSigned(sign(2, holder2)); err == nil {
+		t.Fatal("expected error")
+	}
+	if prog, _ := c.SecretProgress(); prog != 2 {
+		t.Fatalf("bad progress: %d", prog)
+	}
+	expected := []string{fingerprints[0], fingerprints[1]}
+	if holders := c.UnsealHolders(); !reflect.DeepEqual(holders, expected) {
+		t.Fatalf("bad holders: %v, expected %v", holders, expected)
+	}
+
+	if unsealed, err := c.UnsealSigned(sign(2, holder1)); err != nil || !unsealed {
+		t.Fatalf("unsealed: %t, err: %v", unsealed, err)
+	}
+	expected = []string{fingerprints[0], fingerprints[1], fingerprints[0]}
+	if holders := c.UnsealHolders(); !reflect.DeepEqual(holders, expected) {
+		t.Fatalf("bad holders: %v, expected %v", holders, expected)
+	}
+
+	// The unseal PGP key is discarded once unsealed
+	status, err = c.GetSealStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.UnsealPGPKey != "" {
+		t.Fatal("expected the unseal PGP key to be discarded")
+	}
+
+	// A new unseal PGP key is generated once sealed
+	if err := c.Seal(res.RootToken); err != nil {
+		t.Fatal(err)
+	}
+	sealedStatus, err := c.GetSealStatus(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealedStatus.UnsealPGPKey == "" || sealedStatus.UnsealPGPKey == unsealPGPKey {
+		t.Fatal("expected a new unseal PGP key")
+	}
+	// Unseal keys encrypted with the previous key cannot be replayed
+	if _, err := c.UnsealSigned(sign(0, holder1)); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCore_UnsealSigned_NotEnabled(t *testing.T) {
+	c := TestCore(t)
+	if _, err := c.Initialize(namespace.RootContext(nil), &InitParams{
+		BarrierConfig: &SealConfig{
+			SecretShares:    1,
+			SecretThreshold: 1,
+			PGPKeys:         []string{pgpkeys.TestPubKey1},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UnsealSigned([]byte("signed")); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if err := (&SealConfig{SecretShares: 1, SecretThreshold: 1, SignedUnseal: true}).Validate(); err == nil {
+		t.Fatal("expected signed unseal without PGP keys to be invalid")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 )
 
+// forwardWrapRequest sends responses that have to be wrapped on a raft read
+// replica to the active node, which owns the cubbyhole tokens
 func forwardWrapRequest(context.Context, *Core, *logical.Request, *logical.Response, *logical.Auth) (*logical.Response, error) {
-	return nil, nil
+	return nil, logical.ErrPerfStandbyPleaseForward
 }